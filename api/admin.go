@@ -0,0 +1,287 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/vocdoni/davinci-node/log"
+	"github.com/vocdoni/davinci-node/storage"
+	"github.com/vocdoni/davinci-node/types"
+)
+
+const (
+	defaultAdminQueueItemsLimit = 100  // Queue items returned by default by the admin queue endpoint
+	maxAdminQueueItemsLimit     = 1000 // Maximum queue items returned by the admin queue endpoint
+)
+
+// SequencingController pauses and resumes the sequencing of the processes,
+// and sets their batch policies. It is implemented by the sequencer, which
+// is set with SetSequencingController once it is running.
+type SequencingController interface {
+	PauseProcessID(processID types.ProcessID) bool
+	ResumeProcessID(processID types.ProcessID) bool
+	PausedProcessIDs() []types.ProcessID
+	SetProcessBatchPolicy(processID types.ProcessID, policy string) error
+	ProcessBatchPolicy(processID types.ProcessID) (string, bool)
+}
+
+// SetSequencingController sets the controller used by the admin endpoints to
+// pause and resume the sequencing of the processes.
+func (a *API) SetSequencingController(sc SequencingController) {
+	a.sequencingMu.Lock()
+	defer a.sequencingMu.Unlock()
+	a.sequencing = sc
+}
+
+// sequencingController returns the sequencing controller, or writes an
+// error and returns false if it is not set.
+func (a *API) sequencingController(w http.ResponseWriter) (SequencingController, bool) {
+	a.sequencingMu.RLock()
+	defer a.sequencingMu.RUnlock()
+	if a.sequencing == nil {
+		ErrSequencerNotAvailable.Write(w)
+		return nil, false
+	}
+	return a.sequencing, true
+}
+
+// adminAuth wraps an admin handler to require the admin bearer token in the
+// Authorization header.
+func (a *API) adminAuth(next http.HandlerFunc) http.HandlerFunc {
+	return bearerAuth(a.adminToken, "admin", next)
+}
+
+// adminQueues returns the size of every queue of the sequencer
+// GET /admin/queues
+func (a *API) adminQueues(w http.ResponseWriter, r *http.Request) {
+	sizes, err := a.storage.QueueSizes()
+	if err != nil {
+		ErrGenericInternalServerError.Withf("could not get queue sizes: %v", err).Write(w)
+		return
+	}
+	httpWriteJSON(w, &AdminQueuesResponse{Queues: sizes})
+}
+
+// adminQueueItems returns the items of a queue, optionally filtered by process
+// GET /admin/queues/{queue}?processId={processId}&limit={limit}
+func (a *API) adminQueueItems(w http.ResponseWriter, r *http.Request) {
+	var processID *types.ProcessID
+	if strProcessID := r.URL.Query().Get(AdminQueueProcessQueryParam); strProcessID != "" {
+		pid, err := types.HexStringToProcessID(strProcessID)
+		if err != nil {
+			ErrMalformedProcessID.Withf("could not parse process ID: %v", err).Write(w)
+			return
+		}
+		processID = &pid
+	}
+	limit := defaultAdminQueueItemsLimit
+	if strLimit := r.URL.Query().Get(AdminQueueLimitQueryParam); strLimit != "" {
+		var err error
+		limit, err = strconv.Atoi(strLimit)
+		if err != nil || limit < 1 || limit > maxAdminQueueItemsLimit {
+			ErrMalformedParam.Withf("limit must be between 1 and %d", maxAdminQueueItemsLimit).Write(w)
+			return
+		}
+	}
+	items, err := a.storage.QueueItems(storage.Queue(chi.URLParam(r, AdminQueueURLParam)), processID, limit)
+	if err != nil {
+		writeAdminQueueError(w, err)
+		return
+	}
+	httpWriteJSON(w, &AdminQueueItemsResponse{Items: items})
+}
+
+// adminQueueItem returns an item of a queue
+// GET /admin/queues/{queue}/{key}
+func (a *API) adminQueueItem(w http.ResponseWriter, r *http.Request) {
+	key, ok := adminQueueKey(w, r)
+	if !ok {
+		return
+	}
+	item, err := a.storage.QueueItem(storage.Queue(chi.URLParam(r, AdminQueueURLParam)), key)
+	if err != nil {
+		writeAdminQueueError(w, err)
+		return
+	}
+	httpWriteJSON(w, item)
+}
+
+// adminReleaseQueueItem releases the reservation of a queue item, so the
+// sequencer picks it up again
+// POST /admin/queues/{queue}/{key}/release
+func (a *API) adminReleaseQueueItem(w http.ResponseWriter, r *http.Request) {
+	key, ok := adminQueueKey(w, r)
+	if !ok {
+		return
+	}
+	queue := storage.Queue(chi.URLParam(r, AdminQueueURLParam))
+	if err := a.storage.ReleaseReservation(queue, key); err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			ErrQueueItemNotReserved.Write(w)
+			return
+		}
+		writeAdminQueueError(w, err)
+		return
+	}
+	log.Infow("admin released queue item reservation", "queue", queue, "key", key.String())
+	httpWriteOK(w)
+}
+
+// adminFailQueueItem marks an aggregated or state transition batch as failed
+// POST /admin/queues/{queue}/{key}/fail
+func (a *API) adminFailQueueItem(w http.ResponseWriter, r *http.Request) {
+	key, ok := adminQueueKey(w, r)
+	if !ok {
+		return
+	}
+	queue := storage.Queue(chi.URLParam(r, AdminQueueURLParam))
+	if err := a.storage.MarkBatchFailed(queue, key); err != nil {
+		writeAdminQueueError(w, err)
+		return
+	}
+	log.Infow("admin marked batch as failed", "queue", queue, "key", key.String())
+	httpWriteOK(w)
+}
+
+// adminCleanupProcess removes the queued ballots and batches of a process and
+// marks its undone votes as timeout
+// POST /admin/processes/{processId}/cleanup
+func (a *API) adminCleanupProcess(w http.ResponseWriter, r *http.Request) {
+	processID, err := types.HexStringToProcessID(chi.URLParam(r, ProcessURLParam))
+	if err != nil {
+		ErrMalformedProcessID.Withf("could not parse process ID: %v", err).Write(w)
+		return
+	}
+	timedOut, err := a.storage.CleanupProcess(processID)
+	if err != nil {
+		ErrGenericInternalServerError.Withf("could not clean up process: %v", err).Write(w)
+		return
+	}
+	log.Infow("admin cleaned up process", "processID", processID.String(), "timedOutVotes", timedOut)
+	httpWriteJSON(w, &AdminCleanupResponse{TimedOutVotes: timedOut})
+}
+
+// adminPauseProcess pauses the sequencing of a process
+// POST /admin/processes/{processId}/pause
+func (a *API) adminPauseProcess(w http.ResponseWriter, r *http.Request) {
+	processID, err := types.HexStringToProcessID(chi.URLParam(r, ProcessURLParam))
+	if err != nil {
+		ErrMalformedProcessID.Withf("could not parse process ID: %v", err).Write(w)
+		return
+	}
+	sc, ok := a.sequencingController(w)
+	if !ok {
+		return
+	}
+	if !sc.PauseProcessID(processID) {
+		ErrProcessNotRegistered.Write(w)
+		return
+	}
+	httpWriteOK(w)
+}
+
+// adminResumeProcess resumes the sequencing of a paused process
+// POST /admin/processes/{processId}/resume
+func (a *API) adminResumeProcess(w http.ResponseWriter, r *http.Request) {
+	processID, err := types.HexStringToProcessID(chi.URLParam(r, ProcessURLParam))
+	if err != nil {
+		ErrMalformedProcessID.Withf("could not parse process ID: %v", err).Write(w)
+		return
+	}
+	sc, ok := a.sequencingController(w)
+	if !ok {
+		return
+	}
+	if !sc.ResumeProcessID(processID) {
+		ErrProcessNotPaused.Write(w)
+		return
+	}
+	httpWriteOK(w)
+}
+
+// adminPausedProcesses returns the processes whose sequencing is paused
+// GET /admin/processes/paused
+func (a *API) adminPausedProcesses(w http.ResponseWriter, r *http.Request) {
+	sc, ok := a.sequencingController(w)
+	if !ok {
+		return
+	}
+	httpWriteJSON(w, &AdminPausedProcessesResponse{Processes: sc.PausedProcessIDs()})
+}
+
+// adminProcessBatchPolicy returns the batch policy of a process
+// GET /admin/processes/{processId}/batchPolicy
+func (a *API) adminProcessBatchPolicy(w http.ResponseWriter, r *http.Request) {
+	processID, err := types.HexStringToProcessID(chi.URLParam(r, ProcessURLParam))
+	if err != nil {
+		ErrMalformedProcessID.Withf("could not parse process ID: %v", err).Write(w)
+		return
+	}
+	sc, ok := a.sequencingController(w)
+	if !ok {
+		return
+	}
+	policy, registered := sc.ProcessBatchPolicy(processID)
+	if !registered {
+		ErrProcessNotRegistered.Write(w)
+		return
+	}
+	httpWriteJSON(w, &AdminBatchPolicy{Policy: policy})
+}
+
+// adminSetProcessBatchPolicy sets the batch policy of a process, an empty
+// policy restores the default one
+// POST /admin/processes/{processId}/batchPolicy
+func (a *API) adminSetProcessBatchPolicy(w http.ResponseWriter, r *http.Request) {
+	processID, err := types.HexStringToProcessID(chi.URLParam(r, ProcessURLParam))
+	if err != nil {
+		ErrMalformedProcessID.Withf("could not parse process ID: %v", err).Write(w)
+		return
+	}
+	req := &AdminBatchPolicy{}
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		ErrMalformedBody.Withf("could not decode request body: %v", err).Write(w)
+		return
+	}
+	sc, ok := a.sequencingController(w)
+	if !ok {
+		return
+	}
+	if _, registered := sc.ProcessBatchPolicy(processID); !registered {
+		ErrProcessNotRegistered.Write(w)
+		return
+	}
+	if err := sc.SetProcessBatchPolicy(processID, req.Policy); err != nil {
+		ErrInvalidBatchPolicy.WithErr(err).Write(w)
+		return
+	}
+	policy, _ := sc.ProcessBatchPolicy(processID)
+	log.Infow("admin set process batch policy", "processID", processID.String(), "policy", policy)
+	httpWriteJSON(w, &AdminBatchPolicy{Policy: policy})
+}
+
+// adminQueueKey parses the hex key of the queue item of the request. It
+// writes an error and returns false if the key is not valid.
+func adminQueueKey(w http.ResponseWriter, r *http.Request) (types.HexBytes, bool) {
+	key, err := types.HexStringToHexBytes(chi.URLParam(r, AdminQueueKeyURLParam))
+	if err != nil || len(key) == 0 {
+		ErrMalformedParam.With("invalid queue item key").Write(w)
+		return nil, false
+	}
+	return key, true
+}
+
+// writeAdminQueueError writes the API error matching a queue storage error.
+func writeAdminQueueError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, storage.ErrUnknownQueue):
+		ErrUnknownQueue.WithErr(err).Write(w)
+	case errors.Is(err, storage.ErrNotFound):
+		ErrQueueItemNotFound.Write(w)
+	default:
+		ErrGenericInternalServerError.WithErr(err).Write(w)
+	}
+}
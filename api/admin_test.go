@@ -0,0 +1,194 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/go-chi/chi/v5"
+	"github.com/vocdoni/davinci-node/db"
+	"github.com/vocdoni/davinci-node/db/metadb"
+	"github.com/vocdoni/davinci-node/internal/testutil"
+	"github.com/vocdoni/davinci-node/storage"
+	"github.com/vocdoni/davinci-node/types"
+)
+
+// testSequencingController is a SequencingController that only knows the
+// registered process IDs provided.
+type testSequencingController struct {
+	registered []types.ProcessID
+	paused     []types.ProcessID
+	policies   map[types.ProcessID]string
+}
+
+func (sc *testSequencingController) PauseProcessID(processID types.ProcessID) bool {
+	if !slices.Contains(sc.registered, processID) {
+		return false
+	}
+	sc.paused = append(sc.paused, processID)
+	return true
+}
+
+func (sc *testSequencingController) ResumeProcessID(processID types.ProcessID) bool {
+	i := slices.Index(sc.paused, processID)
+	if i < 0 {
+		return false
+	}
+	sc.paused = slices.Delete(sc.paused, i, i+1)
+	return true
+}
+
+func (sc *testSequencingController) PausedProcessIDs() []types.ProcessID {
+	return sc.paused
+}
+
+func (sc *testSequencingController) SetProcessBatchPolicy(processID types.ProcessID, policy string) error {
+	if strings.Contains(policy, "invalid") {
+		return errors.New("invalid policy")
+	}
+	if sc.policies == nil {
+		sc.policies = make(map[types.ProcessID]string)
+	}
+	sc.policies[processID] = policy
+	return nil
+}
+
+func (sc *testSequencingController) ProcessBatchPolicy(processID types.ProcessID) (string, bool) {
+	if !slices.Contains(sc.registered, processID) {
+		return "", false
+	}
+	return sc.policies[processID], true
+}
+
+func TestAdminEndpoints(t *testing.T) {
+	c := qt.New(t)
+
+	testDB, err := metadb.New(db.TypePebble, filepath.Join(t.TempDir(), "db"))
+	c.Assert(err, qt.IsNil)
+	store := storage.New(testDB)
+	defer store.Close()
+
+	const token = "secret"
+	api := &API{storage: store, adminToken: token}
+	router := chi.NewRouter()
+	router.Get(AdminQueuesEndpoint, api.adminAuth(api.adminQueues))
+	router.Get(AdminQueueEndpoint, api.adminAuth(api.adminQueueItems))
+	router.Get(AdminQueueItemEndpoint, api.adminAuth(api.adminQueueItem))
+	router.Post(AdminQueueItemReleaseEndpoint, api.adminAuth(api.adminReleaseQueueItem))
+	router.Post(AdminQueueItemFailEndpoint, api.adminAuth(api.adminFailQueueItem))
+	router.Get(AdminPausedProcessesEndpoint, api.adminAuth(api.adminPausedProcesses))
+	router.Post(AdminProcessCleanupEndpoint, api.adminAuth(api.adminCleanupProcess))
+	router.Post(AdminProcessPauseEndpoint, api.adminAuth(api.adminPauseProcess))
+	router.Post(AdminProcessResumeEndpoint, api.adminAuth(api.adminResumeProcess))
+	router.Get(AdminProcessBatchPolicyEndpoint, api.adminAuth(api.adminProcessBatchPolicy))
+	router.Post(AdminProcessBatchPolicyEndpoint, api.adminAuth(api.adminSetProcessBatchPolicy))
+
+	authToken := token
+	request := func(method, path string, out any) (int, int) {
+		req := httptest.NewRequest(method, path, nil)
+		req.Header.Set("Authorization", "Bearer "+authToken)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		if rr.Code != http.StatusOK {
+			apiErr := Error{}
+			c.Assert(json.Unmarshal(rr.Body.Bytes(), &apiErr), qt.IsNil)
+			return rr.Code, apiErr.Code
+		}
+		if out != nil {
+			c.Assert(json.Unmarshal(rr.Body.Bytes(), out), qt.IsNil)
+		}
+		return rr.Code, 0
+	}
+	queuePath := func(endpoint string, queue storage.Queue, key string) string {
+		path := EndpointWithParam(endpoint, AdminQueueURLParam, string(queue))
+		return EndpointWithParam(path, AdminQueueKeyURLParam, key)
+	}
+	processPath := func(endpoint string, processID types.ProcessID) string {
+		return EndpointWithParam(endpoint, ProcessURLParam, processID.String())
+	}
+
+	// the admin endpoints require the token
+	authToken = "wrong"
+	status, _ := request(http.MethodGet, AdminQueuesEndpoint, nil)
+	c.Assert(status, qt.Equals, http.StatusForbidden)
+	authToken = token
+
+	queues := &AdminQueuesResponse{}
+	status, _ = request(http.MethodGet, AdminQueuesEndpoint, queues)
+	c.Assert(status, qt.Equals, http.StatusOK)
+	c.Assert(queues.Queues, qt.HasLen, len(storage.Queues))
+
+	items := &AdminQueueItemsResponse{}
+	status, _ = request(http.MethodGet, EndpointWithParam(AdminQueueEndpoint, AdminQueueURLParam, string(storage.QueuePending)), items)
+	c.Assert(status, qt.Equals, http.StatusOK)
+	c.Assert(items.Items, qt.HasLen, 0)
+
+	_, code := request(http.MethodGet, EndpointWithParam(AdminQueueEndpoint, AdminQueueURLParam, "unknown"), nil)
+	c.Assert(code, qt.Equals, ErrUnknownQueue.Code)
+	_, code = request(http.MethodGet, queuePath(AdminQueueItemEndpoint, storage.QueueAggregated, "zz"), nil)
+	c.Assert(code, qt.Equals, ErrMalformedParam.Code)
+	_, code = request(http.MethodGet, queuePath(AdminQueueItemEndpoint, storage.QueueAggregated, "abcd"), nil)
+	c.Assert(code, qt.Equals, ErrQueueItemNotFound.Code)
+	_, code = request(http.MethodPost, queuePath(AdminQueueItemReleaseEndpoint, storage.QueueAggregated, "abcd"), nil)
+	c.Assert(code, qt.Equals, ErrQueueItemNotReserved.Code)
+	_, code = request(http.MethodPost, queuePath(AdminQueueItemFailEndpoint, storage.QueueVerified, "abcd"), nil)
+	c.Assert(code, qt.Equals, ErrUnknownQueue.Code)
+	_, code = request(http.MethodPost, queuePath(AdminQueueItemFailEndpoint, storage.QueueStateTransitions, "abcd"), nil)
+	c.Assert(code, qt.Equals, ErrQueueItemNotFound.Code)
+
+	processID := testutil.DeterministicProcessID(1)
+	cleanup := &AdminCleanupResponse{}
+	status, _ = request(http.MethodPost, processPath(AdminProcessCleanupEndpoint, processID), cleanup)
+	c.Assert(status, qt.Equals, http.StatusOK)
+	c.Assert(cleanup.TimedOutVotes, qt.Equals, 0)
+
+	// the processes can not be paused until the sequencer is running
+	_, code = request(http.MethodPost, processPath(AdminProcessPauseEndpoint, processID), nil)
+	c.Assert(code, qt.Equals, ErrSequencerNotAvailable.Code)
+	api.SetSequencingController(&testSequencingController{registered: []types.ProcessID{processID}})
+
+	_, code = request(http.MethodPost, processPath(AdminProcessPauseEndpoint, testutil.DeterministicProcessID(2)), nil)
+	c.Assert(code, qt.Equals, ErrProcessNotRegistered.Code)
+	status, _ = request(http.MethodPost, processPath(AdminProcessPauseEndpoint, processID), nil)
+	c.Assert(status, qt.Equals, http.StatusOK)
+	paused := &AdminPausedProcessesResponse{}
+	status, _ = request(http.MethodGet, AdminPausedProcessesEndpoint, paused)
+	c.Assert(status, qt.Equals, http.StatusOK)
+	c.Assert(paused.Processes, qt.DeepEquals, []types.ProcessID{processID})
+	status, _ = request(http.MethodPost, processPath(AdminProcessResumeEndpoint, processID), nil)
+	c.Assert(status, qt.Equals, http.StatusOK)
+	_, code = request(http.MethodPost, processPath(AdminProcessResumeEndpoint, processID), nil)
+	c.Assert(code, qt.Equals, ErrProcessNotPaused.Code)
+
+	// set the batch policy of a process
+	setPolicy := func(processID types.ProcessID, policy string) (int, int) {
+		body, err := json.Marshal(&AdminBatchPolicy{Policy: policy})
+		c.Assert(err, qt.IsNil)
+		req := httptest.NewRequest(http.MethodPost, processPath(AdminProcessBatchPolicyEndpoint, processID), bytes.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+authToken)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		apiErr := Error{}
+		if rr.Code != http.StatusOK {
+			c.Assert(json.Unmarshal(rr.Body.Bytes(), &apiErr), qt.IsNil)
+		}
+		return rr.Code, apiErr.Code
+	}
+	_, code = setPolicy(testutil.DeterministicProcessID(2), "minFill=0.5")
+	c.Assert(code, qt.Equals, ErrProcessNotRegistered.Code)
+	_, code = setPolicy(processID, "invalid")
+	c.Assert(code, qt.Equals, ErrInvalidBatchPolicy.Code)
+	status, _ = setPolicy(processID, "minFill=0.5")
+	c.Assert(status, qt.Equals, http.StatusOK)
+	policy := &AdminBatchPolicy{}
+	status, _ = request(http.MethodGet, processPath(AdminProcessBatchPolicyEndpoint, processID), policy)
+	c.Assert(status, qt.Equals, http.StatusOK)
+	c.Assert(policy.Policy, qt.Equals, "minFill=0.5")
+}
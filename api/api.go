@@ -7,6 +7,7 @@ import (
 	"os"
 	"path"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/ethereum/go-ethereum/common"
@@ -18,8 +19,10 @@ import (
 	"github.com/vocdoni/davinci-node/crypto/signatures/ethereum"
 	"github.com/vocdoni/davinci-node/log"
 	"github.com/vocdoni/davinci-node/metadata"
+	"github.com/vocdoni/davinci-node/metrics"
 	stg "github.com/vocdoni/davinci-node/storage"
 	"github.com/vocdoni/davinci-node/web3"
+	"github.com/vocdoni/davinci-node/webhooks"
 	"github.com/vocdoni/davinci-node/workers"
 )
 
@@ -42,9 +45,23 @@ type APIConfig struct {
 	SequencerWorkersSeed       string                  // Seed for workers authentication over current sequencer
 	WorkersAuthtokenExpiration time.Duration           // Expiration time for worker authentication tokens
 	WorkerJobTimeout           time.Duration           // Worker job timeout
+	WorkerProofJobTimeout      time.Duration           // Worker aggregation and state transition job timeout
 	WorkerBanRules             *workers.WorkerBanRules // Custom ban rules for workers
 	// Metadata configuration
 	PinataConfig metadata.PinataMetadataProviderConfig // Pinata configuration
+	// Webhooks configuration
+	Webhooks      *webhooks.Manager // Optional: enables the webhook subscription endpoints
+	WebhooksToken string            // Bearer token required by the webhook subscription endpoints
+	// DKG configuration
+	DKGToken string // Optional: enables the creation of DKG sessions, which requires this bearer token
+	// Admin configuration
+	AdminToken string // Optional: enables the admin endpoints, which require this bearer token
+	// Census builder configuration
+	CensusToken string // Optional: enables the census builder endpoints, which require this bearer token
+	// Rate limiting configuration
+	RateLimits *RateLimitConfig // Optional: enables the rate limiting of the requests
+	// Observer configuration
+	ReadOnly bool // Optional: rejects the requests that write to the node, for observer nodes
 }
 
 // API type represents the API HTTP server with JWT authentication capabilities.
@@ -60,8 +77,20 @@ type API struct {
 	voteVerifier               *circuits.CircuitRuntime // VoteVerifier circuit
 	workersAuthtokenExpiration time.Duration            // Expiration time for worker authentication tokens
 	workersJobTimeout          time.Duration            // The time that the sequencer waits for a worker job
+	workersProofJobTimeout     time.Duration            // The time that the sequencer waits for a worker proof job
 	workersBanRules            *workers.WorkerBanRules  // Rules for banning workers based on job failures
 	jobsManager                *workers.JobsManager     // Manages worker jobs and timeouts
+	eventStreams               chan struct{}            // Semaphore to limit the open event streams
+	webhooks                   *webhooks.Manager        // Webhook subscriptions manager
+	webhooksToken              string                   // Bearer token of the webhook subscription endpoints
+	dkgToken                   string                   // Bearer token required to create DKG sessions
+	adminToken                 string                   // Bearer token required by the admin endpoints
+	censusToken                string                   // Bearer token required by the census builder endpoints
+	censusBuilderMu            sync.Mutex               // Serializes the changes of the working censuses
+	sequencing                 SequencingController     // Pauses and resumes the sequencing of the processes
+	sequencingMu               sync.RWMutex             // Protects the sequencing controller
+	rateLimiter                *rateLimiter             // Rate limits the requests, nil if disabled
+	readOnly                   bool                     // Rejects the requests that write to the node
 	parentCtx                  context.Context          // Context to stop the API server
 }
 
@@ -77,6 +106,9 @@ func New(ctx context.Context, conf *APIConfig) (*API, error) {
 	if conf.Runtimes == nil {
 		return nil, fmt.Errorf("missing runtime router")
 	}
+	if conf.Webhooks != nil && conf.WebhooksToken == "" {
+		return nil, fmt.Errorf("missing webhooks token")
+	}
 
 	runtimeInfos, err := apiRuntimeData(conf.Runtimes)
 	if err != nil {
@@ -100,7 +132,15 @@ func New(ctx context.Context, conf *APIConfig) (*API, error) {
 		runtimes:                   conf.Runtimes,
 		networksInfo:               runtimeInfos,
 		workersJobTimeout:          conf.WorkerJobTimeout,
+		workersProofJobTimeout:     conf.WorkerProofJobTimeout,
 		workersAuthtokenExpiration: conf.WorkersAuthtokenExpiration,
+		eventStreams:               make(chan struct{}, maxEventStreams),
+		webhooks:                   conf.Webhooks,
+		webhooksToken:              conf.WebhooksToken,
+		dkgToken:                   conf.DKGToken,
+		adminToken:                 conf.AdminToken,
+		censusToken:                conf.CensusToken,
+		readOnly:                   conf.ReadOnly,
 		parentCtx:                  ctx,
 	}
 
@@ -109,6 +149,20 @@ func New(ctx context.Context, conf *APIConfig) (*API, error) {
 		a.workersBanRules = conf.WorkerBanRules
 	}
 
+	// Report the storage queue depths in the metrics endpoint
+	metrics.SetQueueSource(conf.Storage)
+
+	// Rate limit the requests if configured
+	if conf.RateLimits != nil {
+		a.rateLimiter = newRateLimiter(conf.RateLimits)
+		a.rateLimiter.start(ctx)
+	}
+
+	// Discard the working censuses that are never published
+	if a.censusToken != "" {
+		a.startWorkingCensusPurge(ctx)
+	}
+
 	// Initialize router
 	a.initRouter()
 
@@ -148,6 +202,10 @@ func (a *API) registerHandlers() {
 	log.Infow("register handler", "endpoint", HostLoadEndpoint, "method", "GET")
 	a.router.Get(HostLoadEndpoint, a.hostLoad)
 
+	// prometheus metrics endpoint
+	log.Infow("register handler", "endpoint", MetricsEndpoint, "method", "GET")
+	a.router.Method(http.MethodGet, MetricsEndpoint, metrics.Handler())
+
 	// static file serving
 	log.Infow("register static handler", "endpoint", "/app/*", "method", "GET")
 	a.router.Get(StaticFilesEndpoint, staticHandler)
@@ -163,6 +221,30 @@ func (a *API) registerHandlers() {
 	a.router.Get(CensusParticipantsEndpoint, a.processParticipants)
 	log.Infow("register handler", "endpoint", NewEncryptionKeysEndpoint, "method", "POST")
 	a.router.Post(NewEncryptionKeysEndpoint, a.processEncryptionKeys)
+	log.Infow("register handler", "endpoint", ResultsDecryptionEndpoint, "method", "GET")
+	a.router.Get(ResultsDecryptionEndpoint, a.resultsDecryption)
+
+	// dkg endpoints - the sessions can only be created if a token is set
+	if a.dkgToken != "" {
+		log.Infow("register handler", "endpoint", DKGSessionsEndpoint, "method", "POST")
+		a.router.Post(DKGSessionsEndpoint, a.dkgAuth(a.newDKGSession))
+	}
+	log.Infow("register handler", "endpoint", DKGSessionEndpoint, "method", "GET")
+	a.router.Get(DKGSessionEndpoint, a.dkgSession)
+	log.Infow("register handler", "endpoint", DKGCommitmentsEndpoint, "method", "POST")
+	a.router.Post(DKGCommitmentsEndpoint, a.dkgCommitments)
+	log.Infow("register handler", "endpoint", DKGSharesEndpoint, "method", "POST")
+	a.router.Post(DKGSharesEndpoint, a.dkgShares)
+	log.Infow("register handler", "endpoint", DKGTrusteeSharesEndpoint, "method", "GET")
+	a.router.Get(DKGTrusteeSharesEndpoint, a.dkgTrusteeShares)
+	log.Infow("register handler", "endpoint", DKGVerificationEndpoint, "method", "POST")
+	a.router.Post(DKGVerificationEndpoint, a.dkgVerification)
+	log.Infow("register handler", "endpoint", ThresholdDecryptionEndpoint, "method", "GET")
+	a.router.Get(ThresholdDecryptionEndpoint, a.thresholdDecryption)
+	log.Infow("register handler", "endpoint", PartialDecryptionsEndpoint, "method", "POST")
+	a.router.Post(PartialDecryptionsEndpoint, a.partialDecryptions)
+	log.Infow("register handler", "endpoint", DecryptionResponsesEndpoint, "method", "POST")
+	a.router.Post(DecryptionResponsesEndpoint, a.decryptionResponses)
 
 	// metadata endpoints
 	log.Infow("register handler", "endpoint", MetadataSetEndpoint, "method", "POST")
@@ -175,10 +257,76 @@ func (a *API) registerHandlers() {
 	a.router.Post(VotesEndpoint, a.newVote)
 	log.Infow("register handler", "endpoint", VoteStatusEndpoint, "method", "GET")
 	a.router.Get(VoteStatusEndpoint, a.voteStatus)
+	log.Infow("register handler", "endpoint", VoteReceiptEndpoint, "method", "GET")
+	a.router.Get(VoteReceiptEndpoint, a.voteReceipt)
+	log.Infow("register handler", "endpoint", SpoilBallotEndpoint, "method", "POST")
+	a.router.Post(SpoilBallotEndpoint, a.spoilBallot)
+	log.Infow("register handler", "endpoint", SpoiledBallotEndpoint, "method", "GET")
+	a.router.Get(SpoiledBallotEndpoint, a.spoiledBallot)
 	log.Infow("register handler", "endpoint", VoteByAddressEndpoint, "method", "GET")
 	a.router.Get(VoteByAddressEndpoint, a.voteByAddress)
 	log.Infow("register handler", "endpoint", BallotByIndexEndpoint, "method", "GET")
 	a.router.Get(BallotByIndexEndpoint, a.ballotByIndex)
+	log.Infow("register handler", "endpoint", VoteStatusStreamEndpoint, "method", "GET")
+	a.router.Get(VoteStatusStreamEndpoint, a.voteStatusStream)
+	log.Infow("register handler", "endpoint", ProcessVoteStatusStreamEndpoint, "method", "GET")
+	a.router.Get(ProcessVoteStatusStreamEndpoint, a.processVoteStatusStream)
+
+	// webhooks endpoints - only available if webhooks are enabled
+	if a.webhooks != nil {
+		log.Infow("register handler", "endpoint", WebhooksEndpoint, "method", "GET")
+		a.router.Get(WebhooksEndpoint, a.webhooksAuth(a.webhookList))
+		log.Infow("register handler", "endpoint", WebhooksEndpoint, "method", "POST")
+		a.router.Post(WebhooksEndpoint, a.webhooksAuth(a.newWebhook))
+		log.Infow("register handler", "endpoint", WebhookEndpoint, "method", "DELETE")
+		a.router.Delete(WebhookEndpoint, a.webhooksAuth(a.deleteWebhook))
+		log.Infow("register handler", "endpoint", WebhookDeliveriesEndpoint, "method", "GET")
+		a.router.Get(WebhookDeliveriesEndpoint, a.webhooksAuth(a.webhookDeliveries))
+	}
+
+	// admin endpoints - only available if an admin token is set
+	if a.adminToken != "" {
+		log.Infow("register handler", "endpoint", AdminQueuesEndpoint, "method", "GET")
+		a.router.Get(AdminQueuesEndpoint, a.adminAuth(a.adminQueues))
+		log.Infow("register handler", "endpoint", AdminQueueEndpoint, "method", "GET")
+		a.router.Get(AdminQueueEndpoint, a.adminAuth(a.adminQueueItems))
+		log.Infow("register handler", "endpoint", AdminQueueItemEndpoint, "method", "GET")
+		a.router.Get(AdminQueueItemEndpoint, a.adminAuth(a.adminQueueItem))
+		log.Infow("register handler", "endpoint", AdminQueueItemReleaseEndpoint, "method", "POST")
+		a.router.Post(AdminQueueItemReleaseEndpoint, a.adminAuth(a.adminReleaseQueueItem))
+		log.Infow("register handler", "endpoint", AdminQueueItemFailEndpoint, "method", "POST")
+		a.router.Post(AdminQueueItemFailEndpoint, a.adminAuth(a.adminFailQueueItem))
+		log.Infow("register handler", "endpoint", AdminPausedProcessesEndpoint, "method", "GET")
+		a.router.Get(AdminPausedProcessesEndpoint, a.adminAuth(a.adminPausedProcesses))
+		log.Infow("register handler", "endpoint", AdminProcessCleanupEndpoint, "method", "POST")
+		a.router.Post(AdminProcessCleanupEndpoint, a.adminAuth(a.adminCleanupProcess))
+		log.Infow("register handler", "endpoint", AdminProcessPauseEndpoint, "method", "POST")
+		a.router.Post(AdminProcessPauseEndpoint, a.adminAuth(a.adminPauseProcess))
+		log.Infow("register handler", "endpoint", AdminProcessResumeEndpoint, "method", "POST")
+		a.router.Post(AdminProcessResumeEndpoint, a.adminAuth(a.adminResumeProcess))
+		log.Infow("register handler", "endpoint", AdminProcessBatchPolicyEndpoint, "method", "GET")
+		a.router.Get(AdminProcessBatchPolicyEndpoint, a.adminAuth(a.adminProcessBatchPolicy))
+		log.Infow("register handler", "endpoint", AdminProcessBatchPolicyEndpoint, "method", "POST")
+		a.router.Post(AdminProcessBatchPolicyEndpoint, a.adminAuth(a.adminSetProcessBatchPolicy))
+	}
+
+	// census builder endpoints - only available if a census token is set
+	if a.censusToken != "" {
+		log.Infow("register handler", "endpoint", CensusesEndpoint, "method", "POST")
+		a.router.Post(CensusesEndpoint, a.censusAuth(a.newCensus))
+		log.Infow("register handler", "endpoint", CensusEndpoint, "method", "GET")
+		a.router.Get(CensusEndpoint, a.censusAuth(a.censusInfo))
+		log.Infow("register handler", "endpoint", CensusEndpoint, "method", "DELETE")
+		a.router.Delete(CensusEndpoint, a.censusAuth(a.deleteCensus))
+		log.Infow("register handler", "endpoint", CensusBuilderParticipantsEndpoint, "method", "POST")
+		a.router.Post(CensusBuilderParticipantsEndpoint, a.censusAuth(a.addCensusParticipants))
+		log.Infow("register handler", "endpoint", CensusBuilderParticipantsEndpoint, "method", "DELETE")
+		a.router.Delete(CensusBuilderParticipantsEndpoint, a.censusAuth(a.removeCensusParticipants))
+		log.Infow("register handler", "endpoint", CensusPublishEndpoint, "method", "POST")
+		a.router.Post(CensusPublishEndpoint, a.censusAuth(a.publishCensus))
+	}
+	log.Infow("register handler", "endpoint", PublishedCensusEndpoint, "method", "GET")
+	a.router.Get(PublishedCensusEndpoint, a.publishedCensus)
 
 	// sequencer workers stats endpoint - available even without worker mode
 	log.Infow("register handler", "endpoint", SequencerWorkersEndpoint, "method", "GET")
@@ -197,9 +345,16 @@ func (a *API) initRouter() {
 	}).Handler)
 	a.router.Use(loggingMiddleware(maxRequestBodyLog))
 	a.router.Use(middleware.Recoverer)
-	a.router.Use(middleware.Throttle(100))
-	a.router.Use(middleware.ThrottleBacklog(5000, 40000, 60*time.Second))
-	a.router.Use(middleware.Timeout(45 * time.Second))
+	if a.rateLimiter != nil {
+		a.router.Use(a.rateLimiter.middleware)
+	}
+	if a.readOnly {
+		a.router.Use(readOnlyMiddleware)
+	}
+	streams := eventStreamRoutes()
+	a.router.Use(skipEventStreamsMiddleware(streams, middleware.Throttle(100)))
+	a.router.Use(skipEventStreamsMiddleware(streams, middleware.ThrottleBacklog(5000, 40000, 60*time.Second)))
+	a.router.Use(skipEventStreamsMiddleware(streams, middleware.Timeout(45*time.Second)))
 	// Add middleware to skip unknown process ID versions
 	a.router.Use(skipUnknownProcessIDMiddleware(a.runtimes))
 
@@ -0,0 +1,373 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/vocdoni/davinci-node/census"
+	"github.com/vocdoni/davinci-node/census/censusdb"
+	"github.com/vocdoni/davinci-node/log"
+	"github.com/vocdoni/davinci-node/types"
+)
+
+const (
+	maxCensusBatchSize         = 10000                  // Maximum participants added or removed by a census builder request
+	maxCensusBatchBodyBytes    = 4 << 20                // Maximum size of the census builder requests, 4 MiB
+	workingCensusMaxAge        = 24 * time.Hour         // Working censuses not published within this time are discarded
+	workingCensusPurgeInterval = time.Hour              // Interval between the purges of the expired working censuses
+	publishedCensusContentType = "application/x-ndjson" // Content type of the published census dumps, in JSON Lines
+)
+
+// censusAuth wraps a census builder handler to require the census bearer
+// token in the Authorization header.
+func (a *API) censusAuth(next http.HandlerFunc) http.HandlerFunc {
+	return bearerAuth(a.censusToken, "census", next)
+}
+
+// startWorkingCensusPurge discards periodically the working censuses that
+// were not published within workingCensusMaxAge, until the context is done.
+func (a *API) startWorkingCensusPurge(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(workingCensusPurgeInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				a.censusBuilderMu.Lock()
+				if _, err := a.storage.CensusDB().PurgeWorkingCensuses(workingCensusMaxAge); err != nil {
+					log.Warnw("failed to purge working censuses", "error", err.Error())
+				}
+				a.censusBuilderMu.Unlock()
+			}
+		}
+	}()
+}
+
+// newCensus creates a new empty working census
+// POST /censuses
+func (a *API) newCensus(w http.ResponseWriter, r *http.Request) {
+	censusID := uuid.New()
+	if _, err := a.storage.CensusDB().New(censusID); err != nil {
+		ErrGenericInternalServerError.Withf("could not create census: %v", err).Write(w)
+		return
+	}
+	log.Infow("working census created", "censusId", censusID.String())
+	httpWriteJSON(w, &CensusResponse{CensusID: censusID})
+}
+
+// censusInfo returns the root and the size of a working census
+// GET /censuses/{censusId}
+func (a *API) censusInfo(w http.ResponseWriter, r *http.Request) {
+	ref, ok := a.workingCensus(w, r)
+	if !ok {
+		return
+	}
+	httpWriteJSON(w, workingCensusResponse(ref))
+}
+
+// deleteCensus discards a working census
+// DELETE /censuses/{censusId}
+func (a *API) deleteCensus(w http.ResponseWriter, r *http.Request) {
+	a.censusBuilderMu.Lock()
+	defer a.censusBuilderMu.Unlock()
+	ref, ok := a.workingCensus(w, r)
+	if !ok {
+		return
+	}
+	if err := a.storage.CensusDB().CleanupWorkingCensus(ref.ID); err != nil {
+		ErrGenericInternalServerError.Withf("could not delete census: %v", err).Write(w)
+		return
+	}
+	httpWriteOK(w)
+}
+
+// addCensusParticipants adds a batch of participants to a working census.
+// The addresses must not be in the census yet.
+// POST /censuses/{censusId}/participants
+func (a *API) addCensusParticipants(w http.ResponseWriter, r *http.Request) {
+	req := &CensusParticipantsRequest{}
+	if !decodeCensusBatch(w, r, req) {
+		return
+	}
+	if len(req.Participants) == 0 || len(req.Participants) > maxCensusBatchSize {
+		ErrInvalidCensusParticipant.Withf("the batch must have between 1 and %d participants", maxCensusBatchSize).Write(w)
+		return
+	}
+
+	a.censusBuilderMu.Lock()
+	defer a.censusBuilderMu.Unlock()
+	ref, ok := a.workingCensus(w, r)
+	if !ok {
+		return
+	}
+	keys := make([]types.HexBytes, 0, len(req.Participants))
+	values := make([]types.HexBytes, 0, len(req.Participants))
+	batch := make(map[common.Address]struct{}, len(req.Participants))
+	for i, participant := range req.Participants {
+		if len(participant.Key) != common.AddressLength {
+			ErrInvalidCensusParticipant.Withf("participant %d: key must be a %d bytes address", i, common.AddressLength).Write(w)
+			return
+		}
+		weight := big.NewInt(1)
+		if participant.Weight != nil {
+			weight = participant.Weight.MathBigInt()
+		}
+		if weight.Sign() <= 0 || weight.BitLen() > census.MaxWeightBits {
+			ErrInvalidCensusParticipant.Withf("participant %d: weight must be positive and fit in %d bits", i, census.MaxWeightBits).Write(w)
+			return
+		}
+		address := common.BytesToAddress(participant.Key)
+		if _, duplicated := batch[address]; duplicated || ref.Tree().Has(address) {
+			ErrInvalidCensusParticipant.Withf("participant %d: address %s is already in the census", i, address.Hex()).Write(w)
+			return
+		}
+		batch[address] = struct{}{}
+		keys = append(keys, address.Bytes())
+		values = append(values, weight.Bytes())
+	}
+	if _, err := ref.InsertBatch(keys, values); err != nil {
+		ErrGenericInternalServerError.Withf("could not add participants: %v", err).Write(w)
+		return
+	}
+	httpWriteJSON(w, workingCensusResponse(ref))
+}
+
+// removeCensusParticipants removes a batch of participants from a working
+// census. The census is rebuilt without them, so their leaves do not remain
+// empty in the tree.
+// DELETE /censuses/{censusId}/participants
+func (a *API) removeCensusParticipants(w http.ResponseWriter, r *http.Request) {
+	req := &CensusKeysRequest{}
+	if !decodeCensusBatch(w, r, req) {
+		return
+	}
+	if len(req.Keys) == 0 || len(req.Keys) > maxCensusBatchSize {
+		ErrInvalidCensusParticipant.Withf("the batch must have between 1 and %d keys", maxCensusBatchSize).Write(w)
+		return
+	}
+
+	a.censusBuilderMu.Lock()
+	defer a.censusBuilderMu.Unlock()
+	ref, ok := a.workingCensus(w, r)
+	if !ok {
+		return
+	}
+	removed := make(map[common.Address]struct{}, len(req.Keys))
+	for i, key := range req.Keys {
+		if len(key) != common.AddressLength {
+			ErrInvalidCensusParticipant.Withf("key %d must be a %d bytes address", i, common.AddressLength).Write(w)
+			return
+		}
+		address := common.BytesToAddress(key)
+		if !ref.Tree().Has(address) {
+			ErrInvalidCensusParticipant.Withf("key %d: address %s is not in the census", i, address.Hex()).Write(w)
+			return
+		}
+		removed[address] = struct{}{}
+	}
+	if err := a.rebuildWorkingCensus(ref, removed); err != nil {
+		ErrGenericInternalServerError.Withf("could not remove participants: %v", err).Write(w)
+		return
+	}
+	// the census is reloaded, since it is recreated if it is left empty
+	ref, err := a.storage.CensusDB().Load(ref.ID)
+	if err != nil {
+		ErrGenericInternalServerError.Withf("could not load census: %v", err).Write(w)
+		return
+	}
+	httpWriteJSON(w, workingCensusResponse(ref))
+}
+
+// publishCensus publishes a working census by its root, so it can be used by
+// the processes, and discards the working census. The response includes the
+// URI that serves the dump of the census.
+// POST /censuses/{censusId}/publish
+func (a *API) publishCensus(w http.ResponseWriter, r *http.Request) {
+	a.censusBuilderMu.Lock()
+	defer a.censusBuilderMu.Unlock()
+	ref, ok := a.workingCensus(w, r)
+	if !ok {
+		return
+	}
+	size := ref.Size()
+	if size == 0 {
+		ErrEmptyCensus.Write(w)
+		return
+	}
+	root := ref.Root()
+	censusDB := a.storage.CensusDB()
+	published, err := censusDB.NewByRoot(root)
+	switch {
+	case errors.Is(err, censusdb.ErrCensusAlreadyExists):
+		// the same census is already published or imported, the working
+		// census is not needed anymore
+		if err := censusDB.CleanupWorkingCensus(ref.ID); err != nil {
+			ErrGenericInternalServerError.Withf("could not discard census: %v", err).Write(w)
+			return
+		}
+	case err != nil:
+		ErrGenericInternalServerError.Withf("could not publish census: %v", err).Write(w)
+		return
+	default:
+		if err := censusDB.PublishCensus(ref.ID, published); err != nil {
+			if err := censusDB.DelByRoot(root); err != nil {
+				log.Warnw("failed to delete unpublished census", "root", root.String(), "error", err.Error())
+			}
+			ErrGenericInternalServerError.Withf("could not publish census: %v", err).Write(w)
+			return
+		}
+	}
+	log.Infow("census published", "censusId", ref.ID.String(), "root", root.String(), "size", size)
+	httpWriteJSON(w, &PublishedCensusResponse{
+		Root: root,
+		Size: size,
+		URI:  publishedCensusURI(r, root),
+	})
+}
+
+// publishedCensus streams the dump of a census by its root, in the JSON
+// Lines format accepted by the census importers.
+// GET /censuses/published/{censusRoot}
+func (a *API) publishedCensus(w http.ResponseWriter, r *http.Request) {
+	root, err := types.HexStringToHexBytes(chi.URLParam(r, CensusRootURLParam))
+	if err != nil || len(root) == 0 {
+		ErrMalformedParam.Withf("could not parse census root: %v", err).Write(w)
+		return
+	}
+	censusDB := a.storage.CensusDB()
+	if !censusDB.ExistsByRoot(root) {
+		ErrCensusNotFound.Write(w)
+		return
+	}
+	ref, err := censusDB.LoadByRoot(root)
+	if err != nil {
+		ErrGenericInternalServerError.Withf("could not load census: %v", err).Write(w)
+		return
+	}
+	w.Header().Set("Content-Type", publishedCensusContentType)
+	w.WriteHeader(http.StatusOK)
+	if _, err := io.Copy(w, ref.Tree().Dump()); err != nil {
+		log.Warnw("failed to write census dump", "root", root.String(), "error", err.Error())
+	}
+}
+
+// workingCensus loads the working census of the census ID URL parameter, or
+// writes an error and returns false if it does not exist.
+func (a *API) workingCensus(w http.ResponseWriter, r *http.Request) (*censusdb.CensusRef, bool) {
+	censusID, err := uuid.Parse(chi.URLParam(r, CensusURLParam))
+	if err != nil {
+		ErrInvalidCensusID.Withf("could not parse census ID: %v", err).Write(w)
+		return nil, false
+	}
+	ref, err := a.storage.CensusDB().Load(censusID)
+	if err != nil {
+		if errors.Is(err, censusdb.ErrCensusNotFound) {
+			ErrCensusNotFound.Write(w)
+			return nil, false
+		}
+		ErrGenericInternalServerError.Withf("could not load census: %v", err).Write(w)
+		return nil, false
+	}
+	return ref, true
+}
+
+// rebuildWorkingCensus replaces the tree of a working census with a new one
+// without the addresses removed. The new tree is built in a temporary
+// working census, and moved to the working census provided.
+func (a *API) rebuildWorkingCensus(ref *censusdb.CensusRef, removed map[common.Address]struct{}) error {
+	dump, err := ref.Tree().DumpAll()
+	if err != nil {
+		return fmt.Errorf("could not dump census: %w", err)
+	}
+	keys := make([]types.HexBytes, 0, len(dump.Participants))
+	values := make([]types.HexBytes, 0, len(dump.Participants))
+	for _, participant := range dump.Participants {
+		if _, ok := removed[participant.Address]; ok || participant.Weight.Sign() == 0 {
+			continue
+		}
+		keys = append(keys, participant.Address.Bytes())
+		values = append(values, participant.Weight.Bytes())
+	}
+
+	censusDB := a.storage.CensusDB()
+	if len(keys) == 0 {
+		if err := censusDB.CleanupWorkingCensus(ref.ID); err != nil {
+			return fmt.Errorf("could not clean up census: %w", err)
+		}
+		if _, err := censusDB.New(ref.ID); err != nil {
+			return fmt.Errorf("could not recreate census: %w", err)
+		}
+		return nil
+	}
+	tmpID := uuid.New()
+	tmpRef, err := censusDB.New(tmpID)
+	if err != nil {
+		return fmt.Errorf("could not create temporary census: %w", err)
+	}
+	if _, err := tmpRef.InsertBatch(keys, values); err != nil {
+		if err := censusDB.CleanupWorkingCensus(tmpID); err != nil {
+			log.Warnw("failed to clean up temporary census", "censusId", tmpID.String(), "error", err.Error())
+		}
+		return fmt.Errorf("could not insert participants: %w", err)
+	}
+	if err := censusDB.PublishCensus(tmpID, ref); err != nil {
+		if err := censusDB.CleanupWorkingCensus(tmpID); err != nil {
+			log.Warnw("failed to clean up temporary census", "censusId", tmpID.String(), "error", err.Error())
+		}
+		return fmt.Errorf("could not replace census: %w", err)
+	}
+	return nil
+}
+
+// decodeCensusBatch decodes the body of a census builder request into req,
+// or writes an error and returns false if it is too large or malformed.
+func decodeCensusBatch(w http.ResponseWriter, r *http.Request, req any) bool {
+	r.Body = http.MaxBytesReader(w, r.Body, maxCensusBatchBodyBytes)
+	defer func() { _ = r.Body.Close() }()
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			ErrRequestBodyTooLarge.Withf("census batch exceeds %d bytes", maxCensusBatchBodyBytes).Write(w)
+			return false
+		}
+		ErrMalformedBody.Withf("could not decode request body: %v", err).Write(w)
+		return false
+	}
+	return true
+}
+
+// workingCensusResponse returns the response with the state of a working
+// census.
+func workingCensusResponse(ref *censusdb.CensusRef) *CensusResponse {
+	res := &CensusResponse{CensusID: ref.ID, Size: ref.Size()}
+	if res.Size > 0 {
+		res.Root = ref.Root()
+	}
+	return res
+}
+
+// publishedCensusURI returns the URI of the published census endpoint of a
+// census root, on the host of the request provided.
+func publishedCensusURI(r *http.Request, root types.HexBytes) string {
+	scheme := "http"
+	if r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+	return (&url.URL{
+		Scheme: scheme,
+		Host:   r.Host,
+		Path:   EndpointWithParam(PublishedCensusEndpoint, CensusRootURLParam, root.String()),
+	}).String()
+}
@@ -0,0 +1,201 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/vocdoni/davinci-node/db"
+	"github.com/vocdoni/davinci-node/db/metadb"
+	"github.com/vocdoni/davinci-node/internal/testutil"
+	"github.com/vocdoni/davinci-node/storage"
+	"github.com/vocdoni/davinci-node/types"
+)
+
+// testNewStorage returns a new storage on a temporary directory.
+func testNewStorage(c *qt.C) *storage.Storage {
+	testDB, err := metadb.New(db.TypePebble, filepath.Join(c.TempDir(), "db"))
+	c.Assert(err, qt.IsNil)
+	store := storage.New(testDB)
+	c.Cleanup(store.Close)
+	return store
+}
+
+func TestCensusBuilderEndpoints(t *testing.T) {
+	c := qt.New(t)
+
+	store := testNewStorage(c)
+	const token = "secret"
+	api := &API{storage: store, censusToken: token}
+	router := chi.NewRouter()
+	router.Post(CensusesEndpoint, api.censusAuth(api.newCensus))
+	router.Get(CensusEndpoint, api.censusAuth(api.censusInfo))
+	router.Delete(CensusEndpoint, api.censusAuth(api.deleteCensus))
+	router.Post(CensusBuilderParticipantsEndpoint, api.censusAuth(api.addCensusParticipants))
+	router.Delete(CensusBuilderParticipantsEndpoint, api.censusAuth(api.removeCensusParticipants))
+	router.Post(CensusPublishEndpoint, api.censusAuth(api.publishCensus))
+	router.Get(PublishedCensusEndpoint, api.publishedCensus)
+
+	authToken := token
+	request := func(method, path string, in, out any) (int, int) {
+		var body io.Reader
+		if in != nil {
+			data, err := json.Marshal(in)
+			c.Assert(err, qt.IsNil)
+			body = bytes.NewReader(data)
+		}
+		req := httptest.NewRequest(method, path, body)
+		req.Header.Set("Authorization", "Bearer "+authToken)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		if rr.Code != http.StatusOK {
+			apiErr := Error{}
+			c.Assert(json.Unmarshal(rr.Body.Bytes(), &apiErr), qt.IsNil)
+			return rr.Code, apiErr.Code
+		}
+		if out != nil {
+			c.Assert(json.Unmarshal(rr.Body.Bytes(), out), qt.IsNil)
+		}
+		return rr.Code, 0
+	}
+	censusPath := func(endpoint string, censusID uuid.UUID) string {
+		return EndpointWithParam(endpoint, CensusURLParam, censusID.String())
+	}
+	// expectedRoot returns the root of a census of the participants provided.
+	expectedRoot := func(participants ...CensusParticipant) types.HexBytes {
+		ref, err := store.CensusDB().New(uuid.New())
+		c.Assert(err, qt.IsNil)
+		defer func() { c.Assert(store.CensusDB().CleanupWorkingCensus(ref.ID), qt.IsNil) }()
+		for _, p := range participants {
+			weight := types.NewInt(1)
+			if p.Weight != nil {
+				weight = p.Weight
+			}
+			c.Assert(ref.Insert(p.Key, weight.Bytes()), qt.IsNil)
+		}
+		return ref.Root()
+	}
+
+	// the census builder endpoints require the token
+	authToken = "wrong"
+	status, _ := request(http.MethodPost, CensusesEndpoint, nil, nil)
+	c.Assert(status, qt.Equals, http.StatusForbidden)
+	authToken = token
+
+	created := &CensusResponse{}
+	status, _ = request(http.MethodPost, CensusesEndpoint, nil, created)
+	c.Assert(status, qt.Equals, http.StatusOK)
+	info := &CensusResponse{}
+	status, _ = request(http.MethodGet, censusPath(CensusEndpoint, created.CensusID), nil, info)
+	c.Assert(status, qt.Equals, http.StatusOK)
+	c.Assert(info.Size, qt.Equals, 0)
+	c.Assert(info.Root, qt.HasLen, 0)
+	_, code := request(http.MethodGet, EndpointWithParam(CensusEndpoint, CensusURLParam, "invalid"), nil, nil)
+	c.Assert(code, qt.Equals, ErrInvalidCensusID.Code)
+	_, code = request(http.MethodGet, censusPath(CensusEndpoint, uuid.New()), nil, nil)
+	c.Assert(code, qt.Equals, ErrCensusNotFound.Code)
+	_, code = request(http.MethodPost, censusPath(CensusPublishEndpoint, created.CensusID), nil, nil)
+	c.Assert(code, qt.Equals, ErrEmptyCensus.Code)
+
+	// add the participants, the weight is 1 by default
+	participants := []CensusParticipant{
+		{Key: testutil.RandomAddress().Bytes(), Weight: types.NewInt(3)},
+		{Key: testutil.RandomAddress().Bytes()},
+		{Key: testutil.RandomAddress().Bytes(), Weight: types.NewInt(7)},
+	}
+	participantsPath := censusPath(CensusBuilderParticipantsEndpoint, created.CensusID)
+	status, _ = request(http.MethodPost, participantsPath, &CensusParticipantsRequest{Participants: participants}, info)
+	c.Assert(status, qt.Equals, http.StatusOK)
+	c.Assert(info.Size, qt.Equals, len(participants))
+	c.Assert(info.Root, qt.DeepEquals, expectedRoot(participants...))
+
+	for _, invalid := range []CensusParticipant{
+		participants[0],
+		{Key: types.HexBytes{0x01, 0x02}},
+		{Key: testutil.RandomAddress().Bytes(), Weight: types.NewInt(0)},
+		{Key: testutil.RandomAddress().Bytes(), Weight: new(types.BigInt).SetBytes(bytes.Repeat([]byte{0xff}, 12))},
+	} {
+		_, code = request(http.MethodPost, participantsPath, &CensusParticipantsRequest{
+			Participants: []CensusParticipant{invalid},
+		}, nil)
+		c.Assert(code, qt.Equals, ErrInvalidCensusParticipant.Code)
+	}
+	duplicated := CensusParticipant{Key: testutil.RandomAddress().Bytes()}
+	_, code = request(http.MethodPost, participantsPath, &CensusParticipantsRequest{
+		Participants: []CensusParticipant{duplicated, duplicated},
+	}, nil)
+	c.Assert(code, qt.Equals, ErrInvalidCensusParticipant.Code)
+	_, code = request(http.MethodPost, participantsPath, &CensusParticipantsRequest{}, nil)
+	c.Assert(code, qt.Equals, ErrInvalidCensusParticipant.Code)
+
+	// remove a participant, the census is rebuilt without it
+	status, _ = request(http.MethodDelete, participantsPath, &CensusKeysRequest{Keys: []types.HexBytes{participants[1].Key}}, info)
+	c.Assert(status, qt.Equals, http.StatusOK)
+	c.Assert(info.Size, qt.Equals, 2)
+	c.Assert(info.Root, qt.DeepEquals, expectedRoot(participants[0], participants[2]))
+	_, code = request(http.MethodDelete, participantsPath, &CensusKeysRequest{Keys: []types.HexBytes{participants[1].Key}}, nil)
+	c.Assert(code, qt.Equals, ErrInvalidCensusParticipant.Code)
+
+	// publish the census and download its dump
+	published := &PublishedCensusResponse{}
+	status, _ = request(http.MethodPost, censusPath(CensusPublishEndpoint, created.CensusID), nil, published)
+	c.Assert(status, qt.Equals, http.StatusOK)
+	c.Assert(published.Root, qt.DeepEquals, info.Root)
+	c.Assert(published.Size, qt.Equals, 2)
+	c.Assert(store.CensusDB().ExistsByRoot(published.Root), qt.IsTrue)
+	_, code = request(http.MethodGet, censusPath(CensusEndpoint, created.CensusID), nil, nil)
+	c.Assert(code, qt.Equals, ErrCensusNotFound.Code)
+
+	uri, err := url.Parse(published.URI)
+	c.Assert(err, qt.IsNil)
+	c.Assert(uri.Host, qt.Equals, "example.com")
+	authToken = ""
+	req := httptest.NewRequest(http.MethodGet, uri.Path, nil)
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	c.Assert(rr.Code, qt.Equals, http.StatusOK)
+	c.Assert(rr.Header().Get("Content-Type"), qt.Equals, publishedCensusContentType)
+	imported, err := testNewStorage(c).CensusDB().Import(published.Root, rr.Body)
+	c.Assert(err, qt.IsNil)
+	c.Assert(imported.Size(), qt.Equals, 2)
+	_, code = request(http.MethodGet, EndpointWithParam(PublishedCensusEndpoint, CensusRootURLParam, "0x0102"), nil, nil)
+	c.Assert(code, qt.Equals, ErrCensusNotFound.Code)
+	authToken = token
+
+	// publishing the same census again discards the working census
+	status, _ = request(http.MethodPost, CensusesEndpoint, nil, created)
+	c.Assert(status, qt.Equals, http.StatusOK)
+	participantsPath = censusPath(CensusBuilderParticipantsEndpoint, created.CensusID)
+	status, _ = request(http.MethodPost, participantsPath, &CensusParticipantsRequest{
+		Participants: []CensusParticipant{participants[0], participants[2]},
+	}, nil)
+	c.Assert(status, qt.Equals, http.StatusOK)
+	status, _ = request(http.MethodPost, censusPath(CensusPublishEndpoint, created.CensusID), nil, published)
+	c.Assert(status, qt.Equals, http.StatusOK)
+	c.Assert(published.Root, qt.DeepEquals, info.Root)
+	_, code = request(http.MethodGet, censusPath(CensusEndpoint, created.CensusID), nil, nil)
+	c.Assert(code, qt.Equals, ErrCensusNotFound.Code)
+
+	// removing every participant leaves the census empty, and it can be
+	// deleted
+	status, _ = request(http.MethodPost, CensusesEndpoint, nil, created)
+	c.Assert(status, qt.Equals, http.StatusOK)
+	participantsPath = censusPath(CensusBuilderParticipantsEndpoint, created.CensusID)
+	status, _ = request(http.MethodPost, participantsPath, &CensusParticipantsRequest{Participants: participants[:1]}, nil)
+	c.Assert(status, qt.Equals, http.StatusOK)
+	status, _ = request(http.MethodDelete, participantsPath, &CensusKeysRequest{Keys: []types.HexBytes{participants[0].Key}}, info)
+	c.Assert(status, qt.Equals, http.StatusOK)
+	c.Assert(info.Size, qt.Equals, 0)
+	status, _ = request(http.MethodDelete, censusPath(CensusEndpoint, created.CensusID), nil, nil)
+	c.Assert(status, qt.Equals, http.StatusOK)
+	_, code = request(http.MethodGet, censusPath(CensusEndpoint, created.CensusID), nil, nil)
+	c.Assert(code, qt.Equals, ErrCensusNotFound.Code)
+}
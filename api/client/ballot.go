@@ -0,0 +1,174 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/iden3/go-rapidsnark/prover"
+	"github.com/iden3/go-rapidsnark/witness"
+	"github.com/vocdoni/davinci-node/api"
+	"github.com/vocdoni/davinci-node/circuits/ballotproof"
+	"github.com/vocdoni/davinci-node/crypto"
+	"github.com/vocdoni/davinci-node/crypto/signatures/ethereum"
+	"github.com/vocdoni/davinci-node/spec/params"
+	"github.com/vocdoni/davinci-node/types"
+	"github.com/vocdoni/davinci-node/util/circomgnark"
+)
+
+// BuildVote builds a vote ready to be submitted for the process provided
+// with the ballot fields provided. It encrypts the fields with the process
+// encryption key, generates the ballot proof and signs the vote ID with the
+// signer provided. The census proof is optional, if it is nil the weight of
+// the voter is requested to the sequencer, which generates the census proof
+// by itself. Otherwise, the weight of the census proof is used.
+func (c *HTTPclient) BuildVote(
+	ctx context.Context,
+	processID types.ProcessID,
+	signer *ethereum.Signer,
+	fields []*types.BigInt,
+	censusProof *types.CensusProof,
+) (*api.Vote, error) {
+	vote, _, err := c.buildVote(ctx, processID, signer, fields, censusProof)
+	return vote, err
+}
+
+// BuildSpoilableVote builds a vote as BuildVote, and also returns the
+// spoiled ballot that reveals its randomness. Before submitting the vote, the
+// voter can audit its ballot (a Benaloh challenge) spoiling it with
+// SpoilBallot instead, which discards the vote, so a new one must be built.
+func (c *HTTPclient) BuildSpoilableVote(
+	ctx context.Context,
+	processID types.ProcessID,
+	signer *ethereum.Signer,
+	fields []*types.BigInt,
+	censusProof *types.CensusProof,
+) (*api.Vote, *ballotproof.SpoiledBallot, error) {
+	return c.buildVote(ctx, processID, signer, fields, censusProof)
+}
+
+// buildVote builds the vote of BuildVote and the spoiled ballot that reveals
+// the randomness of its ballot.
+func (c *HTTPclient) buildVote(
+	ctx context.Context,
+	processID types.ProcessID,
+	signer *ethereum.Signer,
+	fields []*types.BigInt,
+	censusProof *types.CensusProof,
+) (*api.Vote, *ballotproof.SpoiledBallot, error) {
+	if len(fields) > params.FieldsPerBallot {
+		return nil, nil, fmt.Errorf("too many ballot fields: %d, max %d", len(fields), params.FieldsPerBallot)
+	}
+	process, err := c.Process(ctx, processID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get process %s: %w", processID.String(), err)
+	}
+	if process.EncryptionKey == nil {
+		return nil, nil, fmt.Errorf("process %s has no encryption key", processID.String())
+	}
+	address := signer.Address()
+	proof := types.CensusProof{}
+	if censusProof != nil {
+		proof = *censusProof
+	}
+	if proof.Weight == nil {
+		participant, err := c.Participant(ctx, processID, address)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to get voter weight: %w", err)
+		}
+		proof.Weight = participant.Weight
+	}
+
+	// Encrypt the ballot and compose the inputs of the ballot proof
+	ballotInputs := &ballotproof.BallotProofInputs{
+		ProcessID:     processID,
+		Address:       address.Bytes(),
+		EncryptionKey: []*types.BigInt{process.EncryptionKey.X, process.EncryptionKey.Y},
+		BallotMode:    process.BallotMode,
+		Weight:        proof.Weight,
+		FieldValues:   fields,
+	}
+	inputs, err := ballotproof.GenerateBallotProofInputs(ballotInputs)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to generate ballot proof inputs: %w", err)
+	}
+	ballotProof, err := ProveBallot(inputs)
+	if err != nil {
+		return nil, nil, err
+	}
+	signature, err := signer.Sign(crypto.PadToSign(inputs.VoteID.Bytes()))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to sign vote: %w", err)
+	}
+	return &api.Vote{
+		ProcessID:        inputs.ProcessID,
+		Address:          inputs.Address,
+		Ballot:           inputs.Ballot,
+		BallotProof:      ballotProof,
+		BallotInputsHash: inputs.BallotInputsHash,
+		Signature:        signature.Bytes(),
+		VoteID:           inputs.VoteID,
+		CensusProof:      proof,
+	}, ballotproof.NewSpoiledBallot(ballotInputs, inputs), nil
+}
+
+// CastVote builds the vote with BuildVote and submits it to the sequencer.
+// It returns the vote ID, which can be used to follow the status of the
+// vote with VoteStatus or WaitVoteStatus.
+func (c *HTTPclient) CastVote(
+	ctx context.Context,
+	processID types.ProcessID,
+	signer *ethereum.Signer,
+	fields []*types.BigInt,
+	censusProof *types.CensusProof,
+) (types.VoteID, error) {
+	vote, err := c.BuildVote(ctx, processID, signer, fields, censusProof)
+	if err != nil {
+		return 0, err
+	}
+	return c.SubmitVote(ctx, vote)
+}
+
+// HasVoted reports whether the address provided has already voted in the
+// process.
+func (c *HTTPclient) HasVoted(ctx context.Context, processID types.ProcessID, address common.Address) (bool, error) {
+	if _, err := c.VoteByAddress(ctx, processID, address); err != nil {
+		if errors.Is(err, api.ErrResourceNotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// ProveBallot generates the ballot proof of the inputs provided using the
+// Circom circuit embedded in the ballotproof package.
+func ProveBallot(inputs *ballotproof.BallotProofInputsResult) (*circomgnark.CircomProof, error) {
+	encodedInputs, err := json.Marshal(inputs.CircomInputs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode circom inputs: %w", err)
+	}
+	parsedInputs, err := witness.ParseInputs(encodedInputs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse circom inputs: %w", err)
+	}
+	calc, err := witness.NewCircom2WitnessCalculator(ballotproof.CircomCircuitWasm, true)
+	if err != nil {
+		return nil, fmt.Errorf("failed to instance witness calculator: %w", err)
+	}
+	w, err := calc.CalculateWTNSBin(parsedInputs, true)
+	if err != nil {
+		return nil, fmt.Errorf("failed to calculate witness: %w", err)
+	}
+	rawProof, pubSignals, err := prover.Groth16ProverRaw(ballotproof.CircomProvingKey, w)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate ballot proof: %w", err)
+	}
+	proof, _, err := circomgnark.UnmarshalCircom(rawProof, pubSignals)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode ballot proof: %w", err)
+	}
+	return proof, nil
+}
@@ -2,6 +2,7 @@ package client
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -29,6 +30,9 @@ const (
 	DefaultRetries = 3
 	// DefaultTimeout is the default timeout for the HTTP client
 	DefaultTimeout = 10 * time.Second
+	// DefaultRetryDelay is the delay before the first retry, it is doubled
+	// on every attempt
+	DefaultRetryDelay = 500 * time.Millisecond
 )
 
 // HTTPclient is the Vocdoni API HTTP client.
@@ -102,6 +106,15 @@ func (c *HTTPclient) SetTimeout(d time.Duration) {
 // Supports query parameters via `params` slice. If the slice is not empty, it should contain pairs of strings;
 // the first element of each pair is the key, and the second element is the value.
 func (c *HTTPclient) Request(method string, jsonBody any, params []string, urlPath ...string) ([]byte, int, error) {
+	return c.RequestWithContext(context.Background(), method, jsonBody, params, urlPath...)
+}
+
+// RequestWithContext performs the same request as Request, but it can be
+// cancelled with the context provided. The request is retried if the
+// connection fails or the server is temporarily unavailable (HTTP 502, 503
+// or 504), waiting DefaultRetryDelay before the first retry and doubling it
+// on every attempt.
+func (c *HTTPclient) RequestWithContext(ctx context.Context, method string, jsonBody any, params []string, urlPath ...string) ([]byte, int, error) {
 	var (
 		body []byte
 		err  error
@@ -157,30 +170,46 @@ func (c *HTTPclient) Request(method string, jsonBody any, params []string, urlPa
 		}(),
 	)
 
-	var resp *http.Response
-	for i := 1; i <= c.retries; i++ {
+	retries := max(c.retries, 1)
+	delay := DefaultRetryDelay
+	for i := 1; ; i++ {
 		// Create a fresh request each attempt
 		var reqBody io.ReadCloser
 		if body != nil {
 			reqBody = io.NopCloser(bytes.NewReader(body))
 		}
-		req, err := http.NewRequest(method, u.String(), reqBody)
+		req, err := http.NewRequestWithContext(ctx, method, u.String(), reqBody)
 		if err != nil {
 			return nil, 0, fmt.Errorf("failed to create request: %w", err)
 		}
-		req.Header = headers
+		req.Header = headers.Clone()
 
-		resp, err = c.c.Do(req)
-		if err != nil {
-			log.Warnw("http request failed", "error", err.Error(), "attempt", i, "retries", c.retries)
-			time.Sleep(500 * time.Millisecond)
-			continue
+		data, status, err := c.do(req)
+		if err == nil && !retryableStatus(status) {
+			return data, status, nil
 		}
-
-		// Successfully got a response, break out of the retry loop
-		break
+		if i >= retries || ctx.Err() != nil {
+			if err != nil {
+				return nil, status, fmt.Errorf("request failed after %d attempts: %w", i, err)
+			}
+			return data, status, nil
+		}
+		log.Warnw("http request failed", "error", err, "status", status, "attempt", i, "retries", retries)
+		select {
+		case <-ctx.Done():
+			return nil, 0, ctx.Err()
+		case <-time.After(delay):
+		}
+		delay *= 2
 	}
+}
 
+// do sends the request provided and reads the response body.
+func (c *HTTPclient) do(req *http.Request) ([]byte, int, error) {
+	resp, err := c.c.Do(req)
+	if err != nil {
+		return nil, 0, err
+	}
 	defer func() {
 		if err := resp.Body.Close(); err != nil {
 			log.Warnw("failed to close response body", "error", err)
@@ -190,6 +219,15 @@ func (c *HTTPclient) Request(method string, jsonBody any, params []string, urlPa
 	if err != nil {
 		return nil, resp.StatusCode, fmt.Errorf("failed to read response body: %w", err)
 	}
-
 	return data, resp.StatusCode, nil
 }
+
+// retryableStatus reports whether the status code provided means that the
+// server is temporarily unable to handle the request.
+func retryableStatus(status int) bool {
+	switch status {
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,211 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/vocdoni/davinci-node/api"
+	"github.com/vocdoni/davinci-node/types"
+)
+
+// call performs a request to the endpoint provided and decodes the JSON
+// response into out, if it is not nil. If the server returns an error, it
+// is decoded and returned as an api.Error, so it can be compared with the
+// errors defined in the api package using errors.Is.
+func (c *HTTPclient) call(ctx context.Context, method string, body, out any, params []string, endpoint string) error {
+	data, status, err := c.RequestWithContext(ctx, method, body, params, endpoint)
+	if err != nil {
+		return err
+	}
+	if status != http.StatusOK {
+		return decodeError(status, data)
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
+
+// decodeError decodes the error returned by the API. If the body is not an
+// API error, a generic error with the status code and the body is returned.
+func decodeError(status int, data []byte) error {
+	apiErr := api.Error{}
+	if err := json.Unmarshal(data, &apiErr); err != nil || apiErr.Code == 0 {
+		return fmt.Errorf("%s: %d (%s)", errCodeNot200, status, data)
+	}
+	apiErr.HTTPstatus = status
+	return apiErr
+}
+
+// Ping checks that the sequencer API is up.
+func (c *HTTPclient) Ping(ctx context.Context) error {
+	return c.call(ctx, HTTPGET, nil, nil, nil, api.PingEndpoint)
+}
+
+// Info returns the information needed to generate the ballot proofs and
+// the networks supported by the sequencer.
+func (c *HTTPclient) Info(ctx context.Context) (*api.SequencerInfo, error) {
+	info := &api.SequencerInfo{}
+	if err := c.call(ctx, HTTPGET, nil, info, nil, api.InfoEndpoint); err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+// HostLoad returns the load metrics of the sequencer host.
+func (c *HTTPclient) HostLoad(ctx context.Context) (*api.HostLoadResponse, error) {
+	load := &api.HostLoadResponse{}
+	if err := c.call(ctx, HTTPGET, nil, load, nil, api.HostLoadEndpoint); err != nil {
+		return nil, err
+	}
+	return load, nil
+}
+
+// Workers returns the statistics of the workers connected to the sequencer.
+func (c *HTTPclient) Workers(ctx context.Context) ([]api.WorkerInfo, error) {
+	resp := &api.WorkersListResponse{}
+	if err := c.call(ctx, HTTPGET, nil, resp, nil, api.SequencerWorkersEndpoint); err != nil {
+		return nil, err
+	}
+	return resp.Workers, nil
+}
+
+// ProcessFilter contains the optional filters of the process list. The zero
+// values are ignored.
+type ProcessFilter struct {
+	Cursor         string
+	Limit          int
+	ChainID        uint64
+	Status         string
+	OrganizationID *common.Address
+	CensusOrigin   string
+	HasResults     *bool
+	From           time.Time
+	To             time.Time
+}
+
+// params returns the query params of the filter.
+func (f *ProcessFilter) params() []string {
+	if f == nil {
+		return nil
+	}
+	params := []string{}
+	add := func(key, value string) {
+		if value != "" {
+			params = append(params, key, value)
+		}
+	}
+	add(api.ProcessListCursorQueryParam, f.Cursor)
+	if f.Limit > 0 {
+		add(api.ProcessListLimitQueryParam, strconv.Itoa(f.Limit))
+	}
+	if f.ChainID > 0 {
+		add(api.ProcessListChainIDQueryParam, strconv.FormatUint(f.ChainID, 10))
+	}
+	add(api.ProcessListStatusQueryParam, f.Status)
+	if f.OrganizationID != nil {
+		add(api.ProcessListOrganizationQueryParam, f.OrganizationID.Hex())
+	}
+	add(api.ProcessListCensusOriginQueryParam, f.CensusOrigin)
+	if f.HasResults != nil {
+		add(api.ProcessListHasResultsQueryParam, strconv.FormatBool(*f.HasResults))
+	}
+	if !f.From.IsZero() {
+		add(api.ProcessListFromQueryParam, strconv.FormatInt(f.From.Unix(), 10))
+	}
+	if !f.To.IsZero() {
+		add(api.ProcessListToQueryParam, strconv.FormatInt(f.To.Unix(), 10))
+	}
+	return params
+}
+
+// Processes returns a page of the processes that match the filter provided,
+// which can be nil. The NextCursor of the list must be set as the Cursor of
+// the filter to get the next page.
+func (c *HTTPclient) Processes(ctx context.Context, filter *ProcessFilter) (*api.ProcessList, error) {
+	list := &api.ProcessList{}
+	if err := c.call(ctx, HTTPGET, nil, list, filter.params(), api.ProcessesEndpoint); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// Process returns the process with the ID provided.
+func (c *HTTPclient) Process(ctx context.Context, processID types.ProcessID) (*api.ProcessResponse, error) {
+	process := &api.ProcessResponse{}
+	endpoint := api.EndpointWithParam(api.ProcessEndpoint, api.ProcessURLParam, processID.String())
+	if err := c.call(ctx, HTTPGET, nil, process, nil, endpoint); err != nil {
+		return nil, err
+	}
+	return process, nil
+}
+
+// NewEncryptionKeys requests the sequencer to generate the encryption keys
+// of a new process.
+func (c *HTTPclient) NewEncryptionKeys(ctx context.Context) (*types.ProcessEncryptionKeysResponse, error) {
+	keys := &types.ProcessEncryptionKeysResponse{}
+	if err := c.call(ctx, HTTPPOST, nil, keys, nil, api.NewEncryptionKeysEndpoint); err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// Participants returns the participants of the census of a process.
+func (c *HTTPclient) Participants(ctx context.Context, processID types.ProcessID) ([]api.CensusParticipant, error) {
+	resp := map[string][]api.CensusParticipant{}
+	endpoint := api.EndpointWithParam(api.CensusParticipantsEndpoint, api.ProcessURLParam, processID.String())
+	if err := c.call(ctx, HTTPGET, nil, &resp, nil, endpoint); err != nil {
+		return nil, err
+	}
+	return resp["participants"], nil
+}
+
+// Participant returns the participant of the census of a process with the
+// address provided.
+func (c *HTTPclient) Participant(ctx context.Context, processID types.ProcessID, address common.Address) (*api.CensusParticipant, error) {
+	participant := &api.CensusParticipant{}
+	endpoint := api.EndpointWithParam(api.CensusParticipantEndpoint, api.ProcessURLParam, processID.String())
+	endpoint = api.EndpointWithParam(endpoint, api.AddressURLParam, address.Hex())
+	if err := c.call(ctx, HTTPGET, nil, participant, nil, endpoint); err != nil {
+		return nil, err
+	}
+	return participant, nil
+}
+
+// ResultsDecryption returns the decryption of the results of a process, with
+// the proof of correct decryption of every accumulator field.
+func (c *HTTPclient) ResultsDecryption(ctx context.Context, processID types.ProcessID) (*types.ResultsDecryption, error) {
+	rd := &types.ResultsDecryption{}
+	endpoint := api.EndpointWithParam(api.ResultsDecryptionEndpoint, api.ProcessURLParam, processID.String())
+	if err := c.call(ctx, HTTPGET, nil, rd, nil, endpoint); err != nil {
+		return nil, err
+	}
+	return rd, nil
+}
+
+// SetMetadata stores the process metadata provided and returns its hash.
+func (c *HTTPclient) SetMetadata(ctx context.Context, metadata *types.Metadata) (types.HexBytes, error) {
+	resp := &api.SetMetadataResponse{}
+	if err := c.call(ctx, HTTPPOST, metadata, resp, nil, api.MetadataSetEndpoint); err != nil {
+		return nil, err
+	}
+	return resp.Hash, nil
+}
+
+// Metadata returns the process metadata with the hash provided.
+func (c *HTTPclient) Metadata(ctx context.Context, hash types.HexBytes) (*types.Metadata, error) {
+	metadata := &types.Metadata{}
+	endpoint := api.EndpointWithParam(api.MetadataGetEndpoint, api.MetadataHashParam, hash.String())
+	if err := c.call(ctx, HTTPGET, nil, metadata, nil, endpoint); err != nil {
+		return nil, err
+	}
+	return metadata, nil
+}
@@ -0,0 +1,141 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/go-chi/chi/v5"
+	"github.com/vocdoni/davinci-node/api"
+	"github.com/vocdoni/davinci-node/internal/testutil"
+	"github.com/vocdoni/davinci-node/types"
+)
+
+func newTestClient(c *qt.C, router http.Handler) *HTTPclient {
+	server := httptest.NewServer(router)
+	c.Cleanup(server.Close)
+	hostURL, err := url.Parse(server.URL)
+	c.Assert(err, qt.IsNil)
+	return &HTTPclient{
+		c:       &http.Client{Timeout: DefaultTimeout},
+		host:    hostURL,
+		retries: DefaultRetries,
+	}
+}
+
+func TestClientTypedResponses(t *testing.T) {
+	c := qt.New(t)
+
+	processID := testutil.DeterministicProcessID(1)
+	router := chi.NewRouter()
+	router.Get(api.ProcessEndpoint, func(w http.ResponseWriter, r *http.Request) {
+		if chi.URLParam(r, api.ProcessURLParam) != processID.String() {
+			api.ErrProcessNotFound.Write(w)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"id":"` + processID.String() + `","isAcceptingVotes":true}`))
+	})
+	router.Get(api.ProcessesEndpoint, func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		if query.Get(api.ProcessListStatusQueryParam) != "ready" ||
+			query.Get(api.ProcessListLimitQueryParam) != "5" ||
+			query.Get(api.ProcessListHasResultsQueryParam) != "false" {
+			api.ErrMalformedParam.Withf("unexpected query: %s", r.URL.RawQuery).Write(w)
+			return
+		}
+		_, _ = w.Write([]byte(`{"processes":["` + processID.String() + `"],"summaries":[],"nextCursor":"next"}`))
+	})
+
+	cli := newTestClient(c, router)
+	ctx := context.Background()
+
+	process, err := cli.Process(ctx, processID)
+	c.Assert(err, qt.IsNil)
+	c.Assert(process.ID.String(), qt.Equals, processID.String())
+	c.Assert(process.IsAcceptingVotes, qt.IsTrue)
+
+	// the API errors are decoded and can be compared with the defined ones
+	_, err = cli.Process(ctx, testutil.DeterministicProcessID(2))
+	c.Assert(errors.Is(err, api.ErrProcessNotFound), qt.IsTrue)
+	c.Assert(errors.Is(err, api.ErrResourceNotFound), qt.IsFalse)
+	var apiErr api.Error
+	c.Assert(errors.As(err, &apiErr), qt.IsTrue)
+	c.Assert(apiErr.HTTPstatus, qt.Equals, http.StatusNotFound)
+
+	hasResults := false
+	list, err := cli.Processes(ctx, &ProcessFilter{Status: "ready", Limit: 5, HasResults: &hasResults})
+	c.Assert(err, qt.IsNil)
+	c.Assert(list.Processes, qt.HasLen, 1)
+	c.Assert(list.NextCursor, qt.Equals, "next")
+
+	// an unknown route is not an API error
+	_, err = cli.Info(ctx)
+	c.Assert(err, qt.ErrorMatches, errCodeNot200+": 404.*")
+}
+
+func TestClientRetries(t *testing.T) {
+	c := qt.New(t)
+
+	var calls atomic.Int32
+	router := chi.NewRouter()
+	router.Get(api.PingEndpoint, func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) < DefaultRetries {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+	router.Get(api.InfoEndpoint, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+	cli := newTestClient(c, router)
+
+	// the request is retried while the server is unavailable
+	c.Assert(cli.Ping(context.Background()), qt.IsNil)
+	c.Assert(calls.Load(), qt.Equals, int32(DefaultRetries))
+
+	// the retries stop when the context is done
+	ctx, cancel := context.WithTimeout(context.Background(), DefaultRetryDelay/2)
+	defer cancel()
+	start := time.Now()
+	_, err := cli.Info(ctx)
+	c.Assert(errors.Is(err, context.DeadlineExceeded), qt.IsTrue)
+	c.Assert(time.Since(start) < DefaultRetryDelay, qt.IsTrue)
+}
+
+func TestClientWaitVoteStatus(t *testing.T) {
+	c := qt.New(t)
+
+	prevInterval := VoteStatusPollInterval
+	VoteStatusPollInterval = 10 * time.Millisecond
+	defer func() { VoteStatusPollInterval = prevInterval }()
+
+	statuses := []string{"pending", "verified", "processed", "settled"}
+	var calls atomic.Int32
+	router := chi.NewRouter()
+	router.Get(api.VoteStatusEndpoint, func(w http.ResponseWriter, r *http.Request) {
+		if chi.URLParam(r, api.VoteIDURLParam) == types.VoteID(2).String() {
+			_, _ = w.Write([]byte(`{"status":"error"}`))
+			return
+		}
+		status := statuses[min(int(calls.Add(1))-1, len(statuses)-1)]
+		_, _ = w.Write([]byte(`{"status":"` + status + `"}`))
+	})
+	cli := newTestClient(c, router)
+	processID := testutil.DeterministicProcessID(1)
+
+	status, err := cli.WaitVoteStatus(context.Background(), processID, 1, "processed", "settled")
+	c.Assert(err, qt.IsNil)
+	c.Assert(status, qt.Equals, "processed")
+
+	status, err = cli.WaitVoteStatus(context.Background(), processID, 2, "settled")
+	c.Assert(err, qt.ErrorMatches, "vote .* reached status error")
+	c.Assert(status, qt.Equals, "error")
+}
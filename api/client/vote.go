@@ -0,0 +1,134 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/vocdoni/davinci-node/api"
+	"github.com/vocdoni/davinci-node/crypto/elgamal"
+	"github.com/vocdoni/davinci-node/storage"
+	"github.com/vocdoni/davinci-node/types"
+)
+
+// VoteStatusPollInterval is the time between the vote status requests of
+// WaitVoteStatus.
+var VoteStatusPollInterval = 5 * time.Second
+
+// SubmitVote sends the vote provided to the sequencer and returns its vote
+// ID.
+func (c *HTTPclient) SubmitVote(ctx context.Context, vote *api.Vote) (types.VoteID, error) {
+	if err := c.call(ctx, HTTPPOST, vote, nil, nil, api.VotesEndpoint); err != nil {
+		return 0, err
+	}
+	return vote.VoteID, nil
+}
+
+// VoteStatus returns the name of the current status of a vote, one of the
+// names returned by storage.VoteIDStatusName.
+func (c *HTTPclient) VoteStatus(ctx context.Context, processID types.ProcessID, voteID types.VoteID) (string, error) {
+	resp := &api.VoteStatusResponse{}
+	endpoint := api.EndpointWithParam(api.VoteStatusEndpoint, api.ProcessURLParam, processID.String())
+	endpoint = api.EndpointWithParam(endpoint, api.VoteIDURLParam, voteID.String())
+	if err := c.call(ctx, HTTPGET, nil, resp, nil, endpoint); err != nil {
+		return "", err
+	}
+	return resp.Status, nil
+}
+
+// VoteReceipt returns the receipt of a settled vote, with the inclusion
+// proofs of its ballot and vote ID against the state root settled on chain.
+func (c *HTTPclient) VoteReceipt(ctx context.Context, processID types.ProcessID, voteID types.VoteID) (*api.VoteReceiptResponse, error) {
+	receipt := &api.VoteReceiptResponse{}
+	endpoint := api.EndpointWithParam(api.VoteReceiptEndpoint, api.ProcessURLParam, processID.String())
+	endpoint = api.EndpointWithParam(endpoint, api.VoteIDURLParam, voteID.String())
+	if err := c.call(ctx, HTTPGET, nil, receipt, nil, endpoint); err != nil {
+		return nil, err
+	}
+	return receipt, nil
+}
+
+// SpoilBallot spoils the ballot of a vote built with BuildSpoilableVote, to
+// audit its encryption, and returns the spoiled ballot recorded by the
+// sequencer, which reports whether the ballot encrypts the field values
+// revealed. The vote can not be submitted anymore. The signature is the one
+// of the vote ID, as in the vote.
+func (c *HTTPclient) SpoilBallot(ctx context.Context, req *api.SpoilBallotRequest) (*storage.SpoiledBallot, error) {
+	spoiled := &storage.SpoiledBallot{}
+	if err := c.call(ctx, HTTPPOST, req, spoiled, nil, api.SpoilBallotEndpoint); err != nil {
+		return nil, err
+	}
+	return spoiled, nil
+}
+
+// SpoiledBallot returns the spoiled ballot of a vote, as recorded by the
+// sequencer.
+func (c *HTTPclient) SpoiledBallot(ctx context.Context, processID types.ProcessID, voteID types.VoteID) (*storage.SpoiledBallot, error) {
+	spoiled := &storage.SpoiledBallot{}
+	endpoint := api.EndpointWithParam(api.SpoiledBallotEndpoint, api.ProcessURLParam, processID.String())
+	endpoint = api.EndpointWithParam(endpoint, api.VoteIDURLParam, voteID.String())
+	if err := c.call(ctx, HTTPGET, nil, spoiled, nil, endpoint); err != nil {
+		return nil, err
+	}
+	return spoiled, nil
+}
+
+// WaitVoteStatus polls the status of a vote until it reaches one of the
+// statuses provided, and returns it. It fails if the vote reaches the error
+// or timeout status and they are not expected, or the context is done.
+func (c *HTTPclient) WaitVoteStatus(
+	ctx context.Context,
+	processID types.ProcessID,
+	voteID types.VoteID,
+	statuses ...string,
+) (string, error) {
+	failed := []string{
+		storage.VoteIDStatusName(storage.VoteIDStatusError),
+		storage.VoteIDStatusName(storage.VoteIDStatusTimeout),
+	}
+	ticker := time.NewTicker(VoteStatusPollInterval)
+	defer ticker.Stop()
+	for {
+		status, err := c.VoteStatus(ctx, processID, voteID)
+		if err != nil {
+			return "", err
+		}
+		if slices.Contains(statuses, status) {
+			return status, nil
+		}
+		if slices.Contains(failed, status) {
+			return status, fmt.Errorf("vote %s reached status %s", voteID.String(), status)
+		}
+		select {
+		case <-ctx.Done():
+			return status, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// VoteByAddress returns the encrypted ballot cast by the address provided
+// in a process.
+func (c *HTTPclient) VoteByAddress(ctx context.Context, processID types.ProcessID, address common.Address) (*elgamal.Ballot, error) {
+	ballot := &elgamal.Ballot{}
+	endpoint := api.EndpointWithParam(api.VoteByAddressEndpoint, api.ProcessURLParam, processID.String())
+	endpoint = api.EndpointWithParam(endpoint, api.AddressURLParam, address.Hex())
+	if err := c.call(ctx, HTTPGET, nil, ballot, nil, endpoint); err != nil {
+		return nil, err
+	}
+	return ballot, nil
+}
+
+// BallotByIndex returns the encrypted ballot stored in the state of a
+// process with the ballot index provided.
+func (c *HTTPclient) BallotByIndex(ctx context.Context, processID types.ProcessID, index types.BallotIndex) (*elgamal.Ballot, error) {
+	ballot := &elgamal.Ballot{}
+	endpoint := api.EndpointWithParam(api.BallotByIndexEndpoint, api.ProcessURLParam, processID.String())
+	endpoint = api.EndpointWithParam(endpoint, api.BallotIndexURLParam, index.String())
+	if err := c.call(ctx, HTTPGET, nil, ballot, nil, endpoint); err != nil {
+		return nil, err
+	}
+	return ballot, nil
+}
@@ -0,0 +1,165 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/vocdoni/davinci-node/log"
+	"github.com/vocdoni/davinci-node/storage"
+	"github.com/vocdoni/davinci-node/trustee"
+	"github.com/vocdoni/davinci-node/types"
+)
+
+// thresholdDecryption returns the threshold decryption of the results of a
+// process, including the ciphertexts to decrypt and, once the quorum is
+// reached, the challenges of the combined decryption proofs
+// GET /processes/{processId}/decryption
+func (a *API) thresholdDecryption(w http.ResponseWriter, r *http.Request) {
+	td, ok := a.thresholdDecryptionFromRequest(w, r)
+	if !ok {
+		return
+	}
+	httpWriteJSON(w, td)
+}
+
+// resultsDecryption returns the decryption of the results accumulator of a
+// process, with the proof of correct decryption of every ciphertext
+// GET /processes/{processId}/results/decryption
+func (a *API) resultsDecryption(w http.ResponseWriter, r *http.Request) {
+	processID, err := types.HexStringToProcessID(chi.URLParam(r, ProcessURLParam))
+	if err != nil {
+		ErrMalformedProcessID.Withf("could not parse process ID: %v", err).Write(w)
+		return
+	}
+	rd, err := a.storage.ResultsDecryption(processID)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			ErrNoResultsDecryption.Write(w)
+			return
+		}
+		ErrGenericInternalServerError.Withf("could not retrieve results decryption: %v", err).Write(w)
+		return
+	}
+	httpWriteJSON(w, rd)
+}
+
+// partialDecryptions stores the partial decryptions of a trustee
+// POST /processes/{processId}/decryption/partials
+func (a *API) partialDecryptions(w http.ResponseWriter, r *http.Request) {
+	td, ok := a.thresholdDecryptionFromRequest(w, r)
+	if !ok {
+		return
+	}
+	req := &PartialDecryptionsRequest{}
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		ErrMalformedBody.Withf("could not decode request body: %v", err).Write(w)
+		return
+	}
+	if !a.verifyDecryptionSignature(w, td, trustee.RoundPartialDecryptions, req.TrusteeIndex, req.Partials, req.Signature) {
+		return
+	}
+	if err := a.storage.SetPartialDecryptions(td.ProcessID, req.TrusteeIndex, req.Partials); err != nil {
+		writeDecryptionStorageError(w, err)
+		return
+	}
+	log.Infow("partial decryptions received",
+		"processID", td.ProcessID.String(),
+		"trustee", req.TrusteeIndex)
+	httpWriteOK(w)
+}
+
+// decryptionResponses stores the responses of a quorum trustee to the
+// challenges of the combined decryption proofs
+// POST /processes/{processId}/decryption/responses
+func (a *API) decryptionResponses(w http.ResponseWriter, r *http.Request) {
+	td, ok := a.thresholdDecryptionFromRequest(w, r)
+	if !ok {
+		return
+	}
+	req := &DecryptionResponsesRequest{}
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		ErrMalformedBody.Withf("could not decode request body: %v", err).Write(w)
+		return
+	}
+	if !a.verifyDecryptionSignature(w, td, trustee.RoundDecryptionResponses, req.TrusteeIndex, req.Responses, req.Signature) {
+		return
+	}
+	if err := a.storage.SetDecryptionResponses(td.ProcessID, req.TrusteeIndex, req.Responses); err != nil {
+		writeDecryptionStorageError(w, err)
+		return
+	}
+	log.Infow("decryption responses received",
+		"processID", td.ProcessID.String(),
+		"trustee", req.TrusteeIndex)
+	httpWriteOK(w)
+}
+
+// thresholdDecryptionFromRequest loads the threshold decryption of the
+// process referenced by the URL of the request provided. If it fails, it
+// writes the error to the response writer and returns false.
+func (a *API) thresholdDecryptionFromRequest(w http.ResponseWriter, r *http.Request) (*types.ThresholdDecryption, bool) {
+	processID, err := types.HexStringToProcessID(chi.URLParam(r, ProcessURLParam))
+	if err != nil {
+		ErrMalformedProcessID.Withf("could not parse process ID: %v", err).Write(w)
+		return nil, false
+	}
+	td, err := a.storage.ThresholdDecryption(processID)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			ErrDecryptionNotFound.Write(w)
+			return nil, false
+		}
+		ErrGenericInternalServerError.Withf("could not retrieve threshold decryption: %v", err).Write(w)
+		return nil, false
+	}
+	return td, true
+}
+
+// verifyDecryptionSignature checks that the payload provided has been signed
+// by the trustee with the index provided for the threshold decryption
+// provided. If it is not, it writes the error to the response writer and
+// returns false.
+func (a *API) verifyDecryptionSignature(
+	w http.ResponseWriter,
+	td *types.ThresholdDecryption,
+	round trustee.Round,
+	trusteeIndex int,
+	payload any,
+	signature types.HexBytes,
+) bool {
+	session, err := a.storage.DKGSession(td.SessionID)
+	if err != nil {
+		ErrGenericInternalServerError.Withf("could not retrieve DKG session: %v", err).Write(w)
+		return false
+	}
+	t, ok := session.Trustee(trusteeIndex)
+	if !ok {
+		ErrInvalidDKGSubmission.Withf("unknown trustee %d", trusteeIndex).Write(w)
+		return false
+	}
+	if err := trustee.VerifyDecryptionSignature(signature, t.Address, session.ID, td.ProcessID,
+		round, trusteeIndex, payload); err != nil {
+		ErrInvalidSignature.WithErr(err).Write(w)
+		return false
+	}
+	return true
+}
+
+// writeDecryptionStorageError writes the API error that matches the threshold
+// decryption storage error provided.
+func writeDecryptionStorageError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, storage.ErrNotFound):
+		ErrDecryptionNotFound.Write(w)
+	case errors.Is(err, storage.ErrThresholdDecryptionRound),
+		errors.Is(err, storage.ErrThresholdDecryptionExcluded),
+		errors.Is(err, storage.ErrDKGAlreadySubmitted),
+		errors.Is(err, storage.ErrDKGUnknownTrustee),
+		errors.Is(err, storage.ErrDKGInvalidSubmission):
+		ErrInvalidDKGSubmission.WithErr(err).Write(w)
+	default:
+		ErrGenericInternalServerError.WithErr(err).Write(w)
+	}
+}
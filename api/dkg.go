@@ -0,0 +1,205 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/vocdoni/davinci-node/log"
+	"github.com/vocdoni/davinci-node/storage"
+	"github.com/vocdoni/davinci-node/trustee"
+	"github.com/vocdoni/davinci-node/types"
+)
+
+// newDKGSession creates a new DKG session between the trustees provided
+// POST /dkg
+func (a *API) newDKGSession(w http.ResponseWriter, r *http.Request) {
+	req := &NewDKGSessionRequest{}
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		ErrMalformedBody.Withf("could not decode request body: %v", err).Write(w)
+		return
+	}
+	session, err := a.storage.NewDKGSession(req.Threshold, req.Trustees)
+	if err != nil {
+		if errors.Is(err, storage.ErrDKGInvalidSession) {
+			ErrMalformedBody.WithErr(err).Write(w)
+			return
+		}
+		ErrGenericInternalServerError.Withf("could not create DKG session: %v", err).Write(w)
+		return
+	}
+	httpWriteJSON(w, session)
+}
+
+// dkgSession returns the public information of a DKG session
+// GET /dkg/{sessionId}
+func (a *API) dkgSession(w http.ResponseWriter, r *http.Request) {
+	session, ok := a.dkgSessionFromRequest(w, r)
+	if !ok {
+		return
+	}
+	httpWriteJSON(w, session)
+}
+
+// dkgCommitments stores the polynomial commitments of a trustee
+// POST /dkg/{sessionId}/commitments
+func (a *API) dkgCommitments(w http.ResponseWriter, r *http.Request) {
+	session, ok := a.dkgSessionFromRequest(w, r)
+	if !ok {
+		return
+	}
+	req := &DKGCommitmentsRequest{}
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		ErrMalformedBody.Withf("could not decode request body: %v", err).Write(w)
+		return
+	}
+	if !a.verifyTrusteeSignature(w, session, trustee.RoundCommitments, req.TrusteeIndex, req.Commitments, req.Signature) {
+		return
+	}
+	if err := a.storage.SetDKGCommitments(session.ID, req.TrusteeIndex, req.Commitments); err != nil {
+		writeDKGStorageError(w, err)
+		return
+	}
+	log.Infow("DKG commitments received",
+		"sessionID", session.ID.String(),
+		"trustee", req.TrusteeIndex)
+	httpWriteOK(w)
+}
+
+// dkgShares stores the encrypted shares sent by a trustee
+// POST /dkg/{sessionId}/shares
+func (a *API) dkgShares(w http.ResponseWriter, r *http.Request) {
+	session, ok := a.dkgSessionFromRequest(w, r)
+	if !ok {
+		return
+	}
+	req := &DKGSharesRequest{}
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		ErrMalformedBody.Withf("could not decode request body: %v", err).Write(w)
+		return
+	}
+	if !a.verifyTrusteeSignature(w, session, trustee.RoundShares, req.TrusteeIndex, req.Shares, req.Signature) {
+		return
+	}
+	if err := a.storage.SetDKGShares(session.ID, req.TrusteeIndex, req.Shares); err != nil {
+		writeDKGStorageError(w, err)
+		return
+	}
+	log.Infow("DKG shares received",
+		"sessionID", session.ID.String(),
+		"trustee", req.TrusteeIndex)
+	httpWriteOK(w)
+}
+
+// dkgVerification stores the result of the verification of the shares
+// received by a trustee. Any complaint makes the session fail.
+// POST /dkg/{sessionId}/verification
+func (a *API) dkgVerification(w http.ResponseWriter, r *http.Request) {
+	session, ok := a.dkgSessionFromRequest(w, r)
+	if !ok {
+		return
+	}
+	req := &DKGVerificationRequest{}
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		ErrMalformedBody.Withf("could not decode request body: %v", err).Write(w)
+		return
+	}
+	if !a.verifyTrusteeSignature(w, session, trustee.RoundVerification, req.TrusteeIndex, req.Complaints, req.Signature) {
+		return
+	}
+	if err := a.storage.SetDKGVerification(session.ID, req.TrusteeIndex, req.Complaints); err != nil {
+		writeDKGStorageError(w, err)
+		return
+	}
+	log.Infow("DKG verification received",
+		"sessionID", session.ID.String(),
+		"trustee", req.TrusteeIndex,
+		"complaints", len(req.Complaints))
+	httpWriteOK(w)
+}
+
+// dkgTrusteeShares returns the encrypted shares addressed to a trustee
+// GET /dkg/{sessionId}/shares/{trusteeIndex}
+func (a *API) dkgTrusteeShares(w http.ResponseWriter, r *http.Request) {
+	session, ok := a.dkgSessionFromRequest(w, r)
+	if !ok {
+		return
+	}
+	index, err := strconv.Atoi(chi.URLParam(r, DKGTrusteeURLParam))
+	if err != nil {
+		ErrMalformedParam.Withf("invalid trustee index: %v", err).Write(w)
+		return
+	}
+	if _, ok := session.Trustee(index); !ok {
+		ErrResourceNotFound.Withf("trustee %d not found", index).Write(w)
+		return
+	}
+	httpWriteJSON(w, &DKGSharesResponse{Shares: session.SharesFor(index)})
+}
+
+// dkgAuth wraps the handler provided to require the DKG bearer token.
+func (a *API) dkgAuth(next http.HandlerFunc) http.HandlerFunc {
+	return bearerAuth(a.dkgToken, "DKG", next)
+}
+
+// dkgSessionFromRequest loads the DKG session referenced by the URL of the
+// request provided. If it fails, it writes the error to the response
+// writer and returns false.
+func (a *API) dkgSessionFromRequest(w http.ResponseWriter, r *http.Request) (*types.DKGSession, bool) {
+	sessionID, err := types.HexStringToHexBytes(chi.URLParam(r, DKGSessionURLParam))
+	if err != nil {
+		ErrMalformedParam.Withf("invalid DKG session ID: %v", err).Write(w)
+		return nil, false
+	}
+	session, err := a.storage.DKGSession(sessionID)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			ErrDKGSessionNotFound.Write(w)
+			return nil, false
+		}
+		ErrGenericInternalServerError.Withf("could not retrieve DKG session: %v", err).Write(w)
+		return nil, false
+	}
+	return session, true
+}
+
+// verifyTrusteeSignature checks that the payload provided has been signed by
+// the trustee with the index provided. If it is not, it writes the error to
+// the response writer and returns false.
+func (a *API) verifyTrusteeSignature(
+	w http.ResponseWriter,
+	session *types.DKGSession,
+	round trustee.Round,
+	trusteeIndex int,
+	payload any,
+	signature types.HexBytes,
+) bool {
+	t, ok := session.Trustee(trusteeIndex)
+	if !ok {
+		ErrInvalidDKGSubmission.Withf("unknown trustee %d", trusteeIndex).Write(w)
+		return false
+	}
+	if err := trustee.VerifySignature(signature, t.Address, session.ID, round, trusteeIndex, payload); err != nil {
+		ErrInvalidSignature.WithErr(err).Write(w)
+		return false
+	}
+	return true
+}
+
+// writeDKGStorageError writes the API error that matches the DKG storage
+// error provided.
+func writeDKGStorageError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, storage.ErrNotFound):
+		ErrDKGSessionNotFound.Write(w)
+	case errors.Is(err, storage.ErrDKGUnexpectedRound),
+		errors.Is(err, storage.ErrDKGAlreadySubmitted),
+		errors.Is(err, storage.ErrDKGUnknownTrustee),
+		errors.Is(err, storage.ErrDKGInvalidSubmission):
+		ErrInvalidDKGSubmission.WithErr(err).Write(w)
+	default:
+		ErrGenericInternalServerError.WithErr(err).Write(w)
+	}
+}
@@ -0,0 +1,159 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/go-chi/chi/v5"
+	"github.com/vocdoni/davinci-node/crypto/signatures/ethereum"
+	"github.com/vocdoni/davinci-node/db"
+	"github.com/vocdoni/davinci-node/db/metadb"
+	"github.com/vocdoni/davinci-node/storage"
+	"github.com/vocdoni/davinci-node/trustee"
+	"github.com/vocdoni/davinci-node/types"
+)
+
+func TestDKGSession(t *testing.T) {
+	c := qt.New(t)
+
+	testDB, err := metadb.New(db.TypePebble, filepath.Join(t.TempDir(), "db"))
+	c.Assert(err, qt.IsNil)
+	store := storage.New(testDB)
+	defer store.Close()
+
+	const token = "secret"
+	api := &API{storage: store, dkgToken: token}
+	router := chi.NewRouter()
+	router.Post(DKGSessionsEndpoint, api.dkgAuth(api.newDKGSession))
+	router.Get(DKGSessionEndpoint, api.dkgSession)
+	router.Post(DKGCommitmentsEndpoint, api.dkgCommitments)
+	router.Post(DKGSharesEndpoint, api.dkgShares)
+	router.Get(DKGTrusteeSharesEndpoint, api.dkgTrusteeShares)
+	router.Post(DKGVerificationEndpoint, api.dkgVerification)
+	router.Post(NewEncryptionKeysEndpoint, api.processEncryptionKeys)
+
+	authToken := token
+	request := func(method, path string, body any, out any) int {
+		var data []byte
+		if body != nil {
+			var err error
+			data, err = json.Marshal(body)
+			c.Assert(err, qt.IsNil)
+		}
+		req := httptest.NewRequest(method, path, bytes.NewReader(data))
+		req.Header.Set("Authorization", "Bearer "+authToken)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		if out != nil && rr.Code == http.StatusOK {
+			c.Assert(json.Unmarshal(rr.Body.Bytes(), out), qt.IsNil)
+		}
+		return rr.Code
+	}
+	sessionPath := func(endpoint string, session *types.DKGSession, params ...string) string {
+		path := EndpointWithParam(endpoint, DKGSessionURLParam, session.ID.String())
+		if len(params) == 2 {
+			path = EndpointWithParam(path, params[0], params[1])
+		}
+		return path
+	}
+
+	// create the trustees and the session
+	const n, threshold = 3, 2
+	trustees := make([]*trustee.Trustee, n)
+	infos := make([]types.DKGTrustee, n)
+	for i := range trustees {
+		signer, err := ethereum.NewSigner()
+		c.Assert(err, qt.IsNil)
+		trustees[i], err = trustee.New(signer, nil)
+		c.Assert(err, qt.IsNil)
+		infos[i] = trustees[i].Info()
+	}
+	newSession := &NewDKGSessionRequest{Threshold: threshold, Trustees: infos}
+	// the session can not be created without the token
+	authToken = ""
+	c.Assert(request(http.MethodPost, DKGSessionsEndpoint, newSession, nil), qt.Equals, http.StatusForbidden)
+	authToken = "wrong"
+	c.Assert(request(http.MethodPost, DKGSessionsEndpoint, newSession, nil), qt.Equals, http.StatusForbidden)
+	authToken = token
+
+	session := &types.DKGSession{}
+	c.Assert(request(http.MethodPost, DKGSessionsEndpoint,
+		newSession, session), qt.Equals, http.StatusOK)
+	c.Assert(session.Trustees, qt.HasLen, n)
+	c.Assert(session.Status, qt.Equals, types.DKGSessionStatusCommitments)
+
+	// the key is not available until the session finishes
+	keysPath := NewEncryptionKeysEndpoint + "?" + DKGSessionQueryParam + "=" + session.ID.String()
+	c.Assert(request(http.MethodPost, keysPath, nil, nil), qt.Equals, http.StatusBadRequest)
+
+	// commitments round
+	for i, tr := range trustees {
+		c.Assert(tr.Join(session), qt.IsNil)
+		commitments, err := tr.Commitments()
+		c.Assert(err, qt.IsNil)
+		signature, err := trustee.Sign(tr.Signer(), session.ID, trustee.RoundCommitments, tr.Index(), commitments)
+		c.Assert(err, qt.IsNil)
+
+		if i == 0 {
+			// a submission signed for another trustee is rejected
+			code := request(http.MethodPost, sessionPath(DKGCommitmentsEndpoint, session),
+				&DKGCommitmentsRequest{TrusteeIndex: 2, Commitments: commitments, Signature: signature}, nil)
+			c.Assert(code, qt.Equals, http.StatusBadRequest)
+		}
+		code := request(http.MethodPost, sessionPath(DKGCommitmentsEndpoint, session),
+			&DKGCommitmentsRequest{TrusteeIndex: tr.Index(), Commitments: commitments, Signature: signature}, nil)
+		c.Assert(code, qt.Equals, http.StatusOK)
+	}
+	c.Assert(request(http.MethodGet, sessionPath(DKGSessionEndpoint, session), nil, session), qt.Equals, http.StatusOK)
+	c.Assert(session.Status, qt.Equals, types.DKGSessionStatusShares)
+	c.Assert(session.PublicKey, qt.IsNotNil)
+
+	// shares round
+	for _, tr := range trustees {
+		shares, err := tr.EncryptedShares(session)
+		c.Assert(err, qt.IsNil)
+		signature, err := trustee.Sign(tr.Signer(), session.ID, trustee.RoundShares, tr.Index(), shares)
+		c.Assert(err, qt.IsNil)
+		code := request(http.MethodPost, sessionPath(DKGSharesEndpoint, session),
+			&DKGSharesRequest{TrusteeIndex: tr.Index(), Shares: shares, Signature: signature}, nil)
+		c.Assert(code, qt.Equals, http.StatusOK)
+	}
+	c.Assert(request(http.MethodGet, sessionPath(DKGSessionEndpoint, session), nil, session), qt.Equals, http.StatusOK)
+	c.Assert(session.Status, qt.Equals, types.DKGSessionStatusVerification)
+
+	// the key is not available until every trustee verifies its shares
+	c.Assert(request(http.MethodPost, keysPath, nil, nil), qt.Equals, http.StatusBadRequest)
+
+	// verification round, every trustee derives its private share and the
+	// same public key
+	for _, tr := range trustees {
+		resp := &DKGSharesResponse{}
+		path := sessionPath(DKGTrusteeSharesEndpoint, session, DKGTrusteeURLParam, fmt.Sprint(tr.Index()))
+		c.Assert(request(http.MethodGet, path, nil, resp), qt.Equals, http.StatusOK)
+		c.Assert(resp.Shares, qt.HasLen, n-1)
+		complaints, err := tr.VerifyShares(session, resp.Shares)
+		c.Assert(err, qt.IsNil)
+		c.Assert(complaints, qt.HasLen, 0)
+		c.Assert(tr.PrivateShare(), qt.IsNotNil)
+
+		signature, err := trustee.Sign(tr.Signer(), session.ID, trustee.RoundVerification, tr.Index(), complaints)
+		c.Assert(err, qt.IsNil)
+		code := request(http.MethodPost, sessionPath(DKGVerificationEndpoint, session),
+			&DKGVerificationRequest{TrusteeIndex: tr.Index(), Complaints: complaints, Signature: signature}, nil)
+		c.Assert(code, qt.Equals, http.StatusOK)
+	}
+	c.Assert(request(http.MethodGet, sessionPath(DKGSessionEndpoint, session), nil, session), qt.Equals, http.StatusOK)
+	c.Assert(session.Status, qt.Equals, types.DKGSessionStatusReady)
+
+	// the process encryption key is the session public key
+	keys := &types.ProcessEncryptionKeysResponse{}
+	c.Assert(request(http.MethodPost, keysPath, nil, keys), qt.Equals, http.StatusOK)
+	c.Assert(keys.EncryptionPubKey[0].String(), qt.Equals, session.PublicKey.X.String())
+	c.Assert(keys.EncryptionPubKey[1].String(), qt.Equals, session.PublicKey.Y.String())
+}
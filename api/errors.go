@@ -2,6 +2,7 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 
@@ -37,11 +38,34 @@ func (e Error) MarshalJSON() ([]byte, error) {
 		})
 }
 
+// UnmarshalJSON decodes the JSON produced by MarshalJSON. Field HTTPstatus
+// is not part of the JSON, so it must be set by the caller.
+func (e *Error) UnmarshalJSON(data []byte) error {
+	var aux struct {
+		Err  string `json:"error"`
+		Code int    `json:"code"`
+	}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+	e.Err = errors.New(aux.Err)
+	e.Code = aux.Code
+	return nil
+}
+
 // Error returns the Message contained inside the APIerror
 func (e Error) Error() string {
 	return e.Err.Error()
 }
 
+// Is reports whether the target is an Error with the same code, so the
+// errors decoded from the API responses can be compared with the defined
+// ones using errors.Is.
+func (e Error) Is(target error) bool {
+	t, ok := target.(Error)
+	return ok && t.Code == e.Code
+}
+
 // Write serializes a JSON msg using APIerror.Message and APIerror.Code
 // and passes that to ctx.Send()
 func (e Error) Write(w http.ResponseWriter) {
@@ -49,6 +49,26 @@ var (
 	ErrInvalidCurveType         = Error{Code: 40030, HTTPstatus: http.StatusBadRequest, Err: fmt.Errorf("invalid curve type")}
 	ErrRequestBodyTooLarge      = Error{Code: 40031, HTTPstatus: http.StatusRequestEntityTooLarge, Err: fmt.Errorf("request body too large")}
 	ErrInvalidChainID           = Error{Code: 40032, HTTPstatus: http.StatusBadRequest, Err: fmt.Errorf("chain ID not supported or invalid")}
+	ErrDKGSessionNotFound       = Error{Code: 40033, HTTPstatus: http.StatusNotFound, Err: fmt.Errorf("DKG session not found")}
+	ErrInvalidDKGSubmission     = Error{Code: 40034, HTTPstatus: http.StatusBadRequest, Err: fmt.Errorf("invalid DKG submission")}
+	ErrDKGSessionNotReady       = Error{Code: 40035, HTTPstatus: http.StatusBadRequest, Err: fmt.Errorf("DKG session not ready")}
+	ErrDecryptionNotFound       = Error{Code: 40036, HTTPstatus: http.StatusNotFound, Err: fmt.Errorf("threshold decryption not found")}
+	ErrWebhookNotFound          = Error{Code: 40037, HTTPstatus: http.StatusNotFound, Err: fmt.Errorf("webhook subscription not found")}
+	ErrInvalidWebhook           = Error{Code: 40038, HTTPstatus: http.StatusBadRequest, Err: fmt.Errorf("invalid webhook subscription")}
+	ErrUnknownQueue             = Error{Code: 40039, HTTPstatus: http.StatusBadRequest, Err: fmt.Errorf("unknown queue")}
+	ErrQueueItemNotFound        = Error{Code: 40040, HTTPstatus: http.StatusNotFound, Err: fmt.Errorf("queue item not found")}
+	ErrQueueItemNotReserved     = Error{Code: 40041, HTTPstatus: http.StatusBadRequest, Err: fmt.Errorf("queue item is not reserved")}
+	ErrProcessNotRegistered     = Error{Code: 40042, HTTPstatus: http.StatusNotFound, Err: fmt.Errorf("process not registered for sequencing")}
+	ErrProcessNotPaused         = Error{Code: 40043, HTTPstatus: http.StatusBadRequest, Err: fmt.Errorf("process sequencing is not paused")}
+	ErrInvalidWorkerProof       = Error{Code: 40044, HTTPstatus: http.StatusBadRequest, Err: fmt.Errorf("invalid worker proof")}
+	ErrRateLimited              = Error{Code: 40045, HTTPstatus: http.StatusTooManyRequests, Err: fmt.Errorf("rate limit exceeded")}
+	ErrInvalidBatchPolicy       = Error{Code: 40046, HTTPstatus: http.StatusBadRequest, Err: fmt.Errorf("invalid batch policy")}
+	ErrReadOnlyNode             = Error{Code: 40047, HTTPstatus: http.StatusForbidden, Err: fmt.Errorf("read-only observer node")}
+	ErrNoResultsDecryption      = Error{Code: 40048, HTTPstatus: http.StatusNotFound, Err: fmt.Errorf("results decryption not found")}
+	ErrBallotSpoiled            = Error{Code: 40049, HTTPstatus: http.StatusBadRequest, Err: fmt.Errorf("ballot is spoiled")}
+	ErrInvalidSpoiledBallot     = Error{Code: 40050, HTTPstatus: http.StatusBadRequest, Err: fmt.Errorf("invalid spoiled ballot")}
+	ErrInvalidCensusParticipant = Error{Code: 40051, HTTPstatus: http.StatusBadRequest, Err: fmt.Errorf("invalid census participant")}
+	ErrEmptyCensus              = Error{Code: 40052, HTTPstatus: http.StatusBadRequest, Err: fmt.Errorf("census has no participants")}
 	// Worker errors
 	ErrWorkerNotAvailable     = Error{Code: 40022, HTTPstatus: http.StatusBadRequest, Err: fmt.Errorf("worker not available")}
 	ErrMalformedWorkerInfo    = Error{Code: 40023, HTTPstatus: http.StatusBadRequest, Err: fmt.Errorf("malformed worker info")}
@@ -59,4 +79,6 @@ var (
 
 	ErrMarshalingServerJSONFailed = Error{Code: 50001, HTTPstatus: http.StatusInternalServerError, Err: fmt.Errorf("marshaling (server-side) JSON failed")}
 	ErrGenericInternalServerError = Error{Code: 50002, HTTPstatus: http.StatusInternalServerError, Err: fmt.Errorf("internal server error")}
+	ErrTooManyEventStreams        = Error{Code: 50003, HTTPstatus: http.StatusServiceUnavailable, Err: fmt.Errorf("too many open event streams")}
+	ErrSequencerNotAvailable      = Error{Code: 50004, HTTPstatus: http.StatusServiceUnavailable, Err: fmt.Errorf("sequencer not available")}
 )
@@ -24,6 +24,7 @@ func (a *API) info(w http.ResponseWriter, r *http.Request) {
 		VerificationKeyURL:  config.BallotProofVerificationKeyURL,
 		VerificationKeyHash: config.BallotProofVerificationKeyHash,
 		Networks:            networks,
+		ReadOnly:            a.readOnly,
 	}
 	// if the sequencer has a signer, include the sequencer address
 	if a.sequencerSigner != nil {
@@ -55,6 +56,9 @@ func (a *API) hostLoad(w http.ResponseWriter, _ *http.Request) {
 			_ = json.Unmarshal([]byte(kv.Value.String()), &resp.HostDiskUsedPercent)
 		}
 	})
+	if a.rateLimiter != nil {
+		resp.RateLimits = a.rateLimiter.stats()
+	}
 
 	jsonResponse, err := json.Marshal(resp)
 	if err != nil {
@@ -2,6 +2,7 @@ package api
 
 import (
 	"bytes"
+	"crypto/subtle"
 	"io"
 	"net/http"
 	"regexp"
@@ -74,6 +75,12 @@ func (rw *responseWriter) Write(b []byte) (int, error) {
 	return rw.ResponseWriter.Write(b)
 }
 
+// Unwrap returns the original http.ResponseWriter, so http.ResponseController
+// can reach its optional interfaces, like http.Flusher.
+func (rw *responseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
+
 // loggingMiddleware provides request/response logging for debugging
 func loggingMiddleware(maxBodyLog int) func(http.Handler) http.Handler {
 	config := LoggingConfig{
@@ -181,3 +188,57 @@ func skipUnknownProcessIDMiddleware(runtimes *web3.RuntimeRouter) func(http.Hand
 		})
 	}
 }
+
+// eventStreamRoutes returns a router that only matches the event stream
+// endpoints, to find them before the request is routed.
+func eventStreamRoutes() *chi.Mux {
+	routes := chi.NewRouter()
+	routes.Get(VoteStatusStreamEndpoint, http.NotFound)
+	routes.Get(ProcessVoteStatusStreamEndpoint, http.NotFound)
+	return routes
+}
+
+// skipEventStreamsMiddleware applies the middleware provided to every request
+// except to the event stream endpoints matched by the routes provided, which
+// are long-lived and must not be throttled nor timed out as regular requests.
+func skipEventStreamsMiddleware(streams *chi.Mux, mw func(http.Handler) http.Handler) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		wrapped := mw(next)
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if streams.Match(chi.NewRouteContext(), r.Method, r.URL.Path) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			wrapped.ServeHTTP(w, r)
+		})
+	}
+}
+
+// readOnlyMiddleware rejects the requests that would write to the node, so
+// only the safe methods are served. The webhook subscriptions are the
+// exception, since they only configure the notifications sent by the node.
+func readOnlyMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet, r.Method == http.MethodHead, r.Method == http.MethodOptions:
+		case strings.HasPrefix(r.URL.Path, WebhooksEndpoint):
+		default:
+			ErrReadOnlyNode.Withf("%s %s", r.Method, r.URL.Path).Write(w)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// bearerAuth wraps a handler to require the bearer token provided in the
+// Authorization header. The name identifies the token in the error returned.
+func bearerAuth(token, name string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		reqToken, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(reqToken), []byte(token)) != 1 {
+			ErrUnauthorized.Withf("invalid %s token", name).Write(w)
+			return
+		}
+		next(w, r)
+	}
+}
@@ -295,3 +295,69 @@ func BenchmarkLoggingMiddleware(b *testing.B) {
 		}
 	})
 }
+
+func TestSkipEventStreamsMiddleware(t *testing.T) {
+	c := qt.New(t)
+
+	// The wrapped middleware rejects every request it handles
+	reject := func(http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		})
+	}
+	router := chi.NewRouter()
+	router.Use(skipEventStreamsMiddleware(eventStreamRoutes(), reject))
+	router.NotFound(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	pid := "0x" + strings.Repeat("ab", 32)
+	testCases := []struct {
+		method   string
+		path     string
+		wantCode int
+	}{
+		{http.MethodGet, "/votes/" + pid + "/stream", http.StatusOK},
+		{http.MethodGet, "/votes/" + pid + "/voteId/0x01/stream", http.StatusOK},
+		{http.MethodPost, "/votes/" + pid + "/stream", http.StatusServiceUnavailable},
+		{http.MethodGet, "/processes/" + pid + "/stream", http.StatusServiceUnavailable},
+		{http.MethodGet, "/votes/" + pid + "/address/0x01/stream", http.StatusServiceUnavailable},
+		{http.MethodGet, "/stream", http.StatusServiceUnavailable},
+	}
+	for _, tc := range testCases {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(tc.method, tc.path, nil))
+		c.Assert(rec.Code, qt.Equals, tc.wantCode, qt.Commentf("%s %s", tc.method, tc.path))
+	}
+}
+
+func TestReadOnlyMiddleware(t *testing.T) {
+	c := qt.New(t)
+
+	router := chi.NewRouter()
+	router.Use(readOnlyMiddleware)
+	ok := func(w http.ResponseWriter, _ *http.Request) { w.WriteHeader(http.StatusOK) }
+	router.Get(ProcessesEndpoint, ok)
+	router.Post(VotesEndpoint, ok)
+	router.Post(NewEncryptionKeysEndpoint, ok)
+	router.Post(WebhooksEndpoint, ok)
+
+	testCases := []struct {
+		method   string
+		path     string
+		wantCode int
+	}{
+		{http.MethodGet, ProcessesEndpoint, http.StatusOK},
+		{http.MethodPost, VotesEndpoint, http.StatusForbidden},
+		{http.MethodPost, NewEncryptionKeysEndpoint, http.StatusForbidden},
+		{http.MethodPost, WebhooksEndpoint, http.StatusOK},
+	}
+	for _, tc := range testCases {
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, httptest.NewRequest(tc.method, tc.path, nil))
+		c.Assert(rr.Code, qt.Equals, tc.wantCode, qt.Commentf("%s %s", tc.method, tc.path))
+		if tc.wantCode == http.StatusForbidden {
+			c.Assert(rr.Body.String(), qt.Contains, ErrReadOnlyNode.Err.Error())
+		}
+	}
+}
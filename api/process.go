@@ -4,13 +4,17 @@ import (
 	"encoding/hex"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
 	"math/big"
 	"net/http"
+	"net/url"
 	"strconv"
+	"time"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/go-chi/chi/v5"
+	"github.com/vocdoni/davinci-node/crypto/ecc"
 	"github.com/vocdoni/davinci-node/log"
 	"github.com/vocdoni/davinci-node/metadata"
 	"github.com/vocdoni/davinci-node/storage"
@@ -20,14 +24,36 @@ import (
 
 const maxMetadataBodyBytes = 1 << 20 // 1MiB
 
-// processEncryptionKeys creates a new encryption key
+// processEncryptionKeys creates a new encryption key, or returns the public
+// key derived by a finished DKG session if the dkgSession query param is
+// provided
 // POST /processes/keys
 func (a *API) processEncryptionKeys(w http.ResponseWriter, r *http.Request) {
-	// Fetch or create the elgamal key from storage
-	publicKey, _, err := a.storage.GenerateProcessEncryptionKeys()
-	if err != nil {
-		ErrGenericInternalServerError.Withf("could not fetch or generate encryption keys: %v", err).Write(w)
-		return
+	var publicKey ecc.Point
+	if strSessionID := r.URL.Query().Get(DKGSessionQueryParam); strSessionID != "" {
+		sessionID, err := types.HexStringToHexBytes(strSessionID)
+		if err != nil {
+			ErrMalformedParam.Withf("invalid DKG session ID: %v", err).Write(w)
+			return
+		}
+		if publicKey, err = a.storage.DKGSessionPublicKey(sessionID); err != nil {
+			switch {
+			case errors.Is(err, storage.ErrNotFound):
+				ErrDKGSessionNotFound.Write(w)
+			case errors.Is(err, storage.ErrDKGSessionNotFinished):
+				ErrDKGSessionNotReady.WithErr(err).Write(w)
+			default:
+				ErrGenericInternalServerError.Withf("could not fetch DKG public key: %v", err).Write(w)
+			}
+			return
+		}
+	} else {
+		// Fetch or create the elgamal key from storage
+		var err error
+		if publicKey, _, err = a.storage.GenerateProcessEncryptionKeys(); err != nil {
+			ErrGenericInternalServerError.Withf("could not fetch or generate encryption keys: %v", err).Write(w)
+			return
+		}
 	}
 
 	// Create the process response
@@ -71,36 +97,108 @@ func (a *API) process(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
-// processList retrieves the list of voting processes
+// processList retrieves a page of the voting processes summaries, optionally
+// filtered by chain ID, status, organization, census origin, time window and
+// results availability. The nextCursor returned must be provided in the
+// cursor query param to get the next page.
 // GET /processes
 func (a *API) processList(w http.ResponseWriter, r *http.Request) {
-	// Retrieve the list of processes
-	processes, err := a.storage.ListProcesses()
+	filter, err := a.processFilterFromQuery(r.URL.Query())
 	if err != nil {
-		ErrGenericInternalServerError.Withf("could not retrieve processes: %v", err).Write(w)
+		var apiErr Error
+		if errors.As(err, &apiErr) {
+			apiErr.Write(w)
+			return
+		}
+		ErrMalformedParam.WithErr(err).Write(w)
 		return
 	}
-	// Try to get the chainID from the request query in an uint64
-	chainID, _ := strconv.ParseUint(r.URL.Query().Get("chainId"), 10, 64)
-	if chainID == 0 {
-		// If no chainID is specified, write the response with all processes
-		httpWriteJSON(w, &ProcessList{Processes: processes})
+	summaries, nextCursor, err := a.storage.ListProcessSummaries(filter)
+	if err != nil {
+		if errors.Is(err, storage.ErrInvalidCursor) {
+			ErrMalformedParam.WithErr(err).Write(w)
+			return
+		}
+		ErrGenericInternalServerError.Withf("could not retrieve processes: %v", err).Write(w)
 		return
 	}
-	// If a chain ID is specified, get the version for that chain ID
-	version, ok := a.runtimes.VersionForChainID(chainID)
-	if !ok {
-		ErrInvalidChainID.Write(w)
-		return
+	list := &ProcessList{
+		Processes:  make([]types.ProcessID, 0, len(summaries)),
+		Summaries:  make([]*ProcessSummary, 0, len(summaries)),
+		NextCursor: nextCursor,
 	}
-	var filteredProcesses []types.ProcessID
-	for _, p := range processes {
-		if p.Version() == version {
-			filteredProcesses = append(filteredProcesses, p)
+	for _, summary := range summaries {
+		list.Processes = append(list.Processes, summary.ID)
+		list.Summaries = append(list.Summaries, NewProcessSummary(summary))
+	}
+	httpWriteJSON(w, list)
+}
+
+// processFilterFromQuery builds the process list filter from the query params
+// of the request.
+func (a *API) processFilterFromQuery(query url.Values) (*storage.ProcessFilter, error) {
+	filter := &storage.ProcessFilter{Cursor: query.Get(ProcessListCursorQueryParam)}
+	if strLimit := query.Get(ProcessListLimitQueryParam); strLimit != "" {
+		limit, err := strconv.Atoi(strLimit)
+		if err != nil || limit < 1 || limit > storage.MaxProcessListLimit {
+			return nil, fmt.Errorf("limit must be between 1 and %d", storage.MaxProcessListLimit)
+		}
+		filter.Limit = limit
+	}
+	// Invalid chain IDs are discarded, but unsupported ones are rejected
+	if chainID, _ := strconv.ParseUint(query.Get(ProcessListChainIDQueryParam), 10, 64); chainID != 0 {
+		version, ok := a.runtimes.VersionForChainID(chainID)
+		if !ok {
+			return nil, ErrInvalidChainID
+		}
+		filter.ProcessIDVersion = &version
+	}
+	if strStatus := query.Get(ProcessListStatusQueryParam); strStatus != "" {
+		status, ok := types.ProcessStatusFromString(strStatus)
+		if !ok {
+			return nil, fmt.Errorf("unknown status: %s", strStatus)
+		}
+		filter.Status = &status
+	}
+	if strOrg := query.Get(ProcessListOrganizationQueryParam); strOrg != "" {
+		if !common.IsHexAddress(strOrg) {
+			return nil, fmt.Errorf("invalid organization ID: %s", strOrg)
 		}
+		org := common.HexToAddress(strOrg)
+		filter.OrganizationID = &org
+	}
+	if strOrigin := query.Get(ProcessListCensusOriginQueryParam); strOrigin != "" {
+		origin := types.CensusOriginFromString(strOrigin)
+		if !origin.Valid() {
+			return nil, fmt.Errorf("unknown census origin: %s", strOrigin)
+		}
+		filter.CensusOrigin = &origin
+	}
+	if strHasResults := query.Get(ProcessListHasResultsQueryParam); strHasResults != "" {
+		hasResults, err := strconv.ParseBool(strHasResults)
+		if err != nil {
+			return nil, fmt.Errorf("invalid hasResults value: %s", strHasResults)
+		}
+		filter.HasResults = &hasResults
+	}
+	for param, t := range map[string]*time.Time{
+		ProcessListFromQueryParam: &filter.From,
+		ProcessListToQueryParam:   &filter.To,
+	} {
+		strTime := query.Get(param)
+		if strTime == "" {
+			continue
+		}
+		unix, err := strconv.ParseInt(strTime, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s timestamp: %s", param, strTime)
+		}
+		*t = time.Unix(unix, 0)
+	}
+	if !filter.From.IsZero() && !filter.To.IsZero() && !filter.From.Before(filter.To) {
+		return nil, fmt.Errorf("from must be before to")
 	}
-	// Write the response with the filtered processes
-	httpWriteJSON(w, &ProcessList{Processes: filteredProcesses})
+	return filter, nil
 }
 
 // setMetadata sets the metadata for a voting process
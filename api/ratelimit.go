@@ -0,0 +1,337 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/vocdoni/davinci-node/log"
+	"github.com/vocdoni/davinci-node/types"
+	"golang.org/x/time/rate"
+)
+
+// rateLimitPruneInterval is the interval at which the buckets that are full
+// again are removed, so the memory used does not grow with every client.
+const rateLimitPruneInterval = time.Minute
+
+// RateLimit is the budget of a token bucket: up to Requests requests,
+// refilled evenly along Period. A zero RateLimit does not limit anything.
+type RateLimit struct {
+	Requests int
+	Period   time.Duration
+}
+
+// Enabled returns whether the rate limit limits anything.
+func (rl RateLimit) Enabled() bool {
+	return rl.Requests > 0 && rl.Period > 0
+}
+
+// perSecond returns the refill rate of the token bucket. It is computed as
+// a float, since dividing the period by the requests would truncate to zero,
+// which is an infinite rate, when the requests exceed the nanoseconds of the
+// period.
+func (rl RateLimit) perSecond() rate.Limit {
+	return rate.Limit(float64(rl.Requests) / rl.Period.Seconds())
+}
+
+// String returns the rate limit in the format parsed by ParseRateLimit.
+func (rl RateLimit) String() string {
+	if !rl.Enabled() {
+		return "0"
+	}
+	return fmt.Sprintf("%d/%s", rl.Requests, rl.Period)
+}
+
+// ParseRateLimit parses a rate limit in the format <requests>/<period>, for
+// example 60/1m. An empty string or 0 disables the rate limit.
+func ParseRateLimit(s string) (RateLimit, error) {
+	s = strings.TrimSpace(s)
+	if s == "" || s == "0" {
+		return RateLimit{}, nil
+	}
+	strRequests, strPeriod, ok := strings.Cut(s, "/")
+	if !ok {
+		return RateLimit{}, fmt.Errorf("invalid rate limit %q, expected <requests>/<period>", s)
+	}
+	requests, err := strconv.Atoi(strRequests)
+	if err != nil || requests < 0 {
+		return RateLimit{}, fmt.Errorf("invalid rate limit requests %q", strRequests)
+	}
+	period, err := time.ParseDuration(strPeriod)
+	if err != nil || period <= 0 {
+		return RateLimit{}, fmt.Errorf("invalid rate limit period %q", strPeriod)
+	}
+	return RateLimit{Requests: requests, Period: period}, nil
+}
+
+// RateLimitConfig defines the budgets of the API rate limiters. The routes
+// with their own budget do not consume the per IP budget.
+type RateLimitConfig struct {
+	Global         RateLimit            // Budget shared by all the clients
+	PerIP          RateLimit            // Budget of each client IP for the routes without their own budget
+	Routes         map[string]RateLimit // Budget of each client IP for some routes, keyed by RateLimitRoute
+	PerVoter       RateLimit            // Budget of each voter address in each process to submit votes
+	TrustedProxies int                  // Number of trusted reverse proxies in front of the API that append the client IP to X-Forwarded-For
+}
+
+// RateLimitRoute returns the key of the route with the method and path
+// provided in RateLimitConfig.Routes. Only the routes without URL params
+// can have their own budget.
+func RateLimitRoute(method, path string) string {
+	return method + " " + path
+}
+
+// DefaultRateLimitConfig returns the default rate limits of the API, with
+// smaller budgets for the routes that generate proofs or keys on every
+// request.
+func DefaultRateLimitConfig() *RateLimitConfig {
+	return &RateLimitConfig{
+		Global: RateLimit{Requests: 1000, Period: time.Second},
+		PerIP:  RateLimit{Requests: 50, Period: time.Second},
+		Routes: map[string]RateLimit{
+			RateLimitRoute(http.MethodPost, VotesEndpoint):             {Requests: 60, Period: time.Minute},
+			RateLimitRoute(http.MethodPost, NewEncryptionKeysEndpoint): {Requests: 20, Period: time.Hour},
+		},
+		PerVoter: RateLimit{Requests: 5, Period: time.Minute},
+	}
+}
+
+// keyedLimiter is a set of token buckets with the same budget, one for each
+// key.
+type keyedLimiter struct {
+	limit    RateLimit
+	mu       sync.Mutex
+	buckets  map[string]*rate.Limiter
+	allowed  atomic.Uint64
+	rejected atomic.Uint64
+}
+
+// newKeyedLimiter returns a keyed limiter with the budget provided, or nil
+// if the budget does not limit anything.
+func newKeyedLimiter(limit RateLimit) *keyedLimiter {
+	if !limit.Enabled() {
+		return nil
+	}
+	return &keyedLimiter{
+		limit:   limit,
+		buckets: make(map[string]*rate.Limiter),
+	}
+}
+
+// allow takes a token from the bucket of the key provided. If the bucket is
+// empty, it returns false and the time until the next token is available.
+// A nil limiter allows every request.
+func (kl *keyedLimiter) allow(key string, now time.Time) (time.Duration, bool) {
+	_, delay, ok := kl.reserve(key, now)
+	return delay, ok
+}
+
+// reserve takes a token from the bucket of the key provided, like allow, and
+// returns the reservation of the token, so it can be given back with cancel
+// if the request is rejected afterwards. A nil limiter allows every request
+// and returns a nil reservation.
+func (kl *keyedLimiter) reserve(key string, now time.Time) (*rate.Reservation, time.Duration, bool) {
+	if kl == nil {
+		return nil, 0, true
+	}
+	kl.mu.Lock()
+	bucket, ok := kl.buckets[key]
+	if !ok {
+		bucket = rate.NewLimiter(kl.limit.perSecond(), kl.limit.Requests)
+		kl.buckets[key] = bucket
+	}
+	kl.mu.Unlock()
+
+	reservation := bucket.ReserveN(now, 1)
+	if delay := reservation.DelayFrom(now); delay > 0 {
+		reservation.CancelAt(now)
+		kl.rejected.Add(1)
+		return nil, delay, false
+	}
+	kl.allowed.Add(1)
+	return reservation, 0, true
+}
+
+// cancel gives back the token of the reservation provided, taken by reserve
+// for a request that has been rejected by another limiter.
+func (kl *keyedLimiter) cancel(reservation *rate.Reservation, now time.Time) {
+	if kl == nil || reservation == nil {
+		return
+	}
+	reservation.CancelAt(now)
+	kl.allowed.Add(^uint64(0))
+}
+
+// prune removes the buckets that are full again, which behave as the new
+// ones.
+func (kl *keyedLimiter) prune(now time.Time) {
+	if kl == nil {
+		return
+	}
+	kl.mu.Lock()
+	defer kl.mu.Unlock()
+	for key, bucket := range kl.buckets {
+		if bucket.TokensAt(now) >= float64(bucket.Burst()) {
+			delete(kl.buckets, key)
+		}
+	}
+}
+
+// stats returns the counters of the limiter, or nil if it is disabled.
+func (kl *keyedLimiter) stats() *RateLimiterStats {
+	if kl == nil {
+		return nil
+	}
+	kl.mu.Lock()
+	tracked := len(kl.buckets)
+	kl.mu.Unlock()
+	return &RateLimiterStats{
+		Limit:    kl.limit.String(),
+		Allowed:  kl.allowed.Load(),
+		Rejected: kl.rejected.Load(),
+		Tracked:  tracked,
+	}
+}
+
+// rateLimiter limits the requests to the API with a global budget, a budget
+// for each client IP and route, and a budget for each voter address in each
+// process.
+type rateLimiter struct {
+	trustedProxies int
+	global         *keyedLimiter
+	perIP          *keyedLimiter
+	routes         map[string]*keyedLimiter
+	perVoter       *keyedLimiter
+}
+
+// newRateLimiter returns a rate limiter with the budgets provided.
+func newRateLimiter(conf *RateLimitConfig) *rateLimiter {
+	rl := &rateLimiter{
+		trustedProxies: conf.TrustedProxies,
+		global:         newKeyedLimiter(conf.Global),
+		perIP:          newKeyedLimiter(conf.PerIP),
+		routes:         make(map[string]*keyedLimiter, len(conf.Routes)),
+		perVoter:       newKeyedLimiter(conf.PerVoter),
+	}
+	for route, limit := range conf.Routes {
+		// a disabled route budget is kept, so the route does not fall back to
+		// the per IP budget
+		rl.routes[route] = newKeyedLimiter(limit)
+	}
+	return rl
+}
+
+// start prunes the buckets periodically until the context is done.
+func (rl *rateLimiter) start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(rateLimitPruneInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				rl.global.prune(now)
+				rl.perIP.prune(now)
+				for _, kl := range rl.routes {
+					kl.prune(now)
+				}
+				rl.perVoter.prune(now)
+			}
+		}
+	}()
+}
+
+// middleware rejects the requests that exceed the global budget, or the
+// budget of their client IP for the route requested. The global budget is
+// checked first, so the requests rejected by it do not consume the budget of
+// the client, and its token is given back if the client budget rejects the
+// request.
+func (rl *rateLimiter) middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		now := time.Now()
+		globalReservation, delay, ok := rl.global.reserve("", now)
+		if !ok {
+			log.Debugw("global rate limit reached", "method", r.Method, "path", r.URL.Path)
+			writeRateLimited(w, delay)
+			return
+		}
+		ip := clientIP(r, rl.trustedProxies)
+		ipLimiter, ok := rl.routes[RateLimitRoute(r.Method, r.URL.Path)]
+		if !ok {
+			ipLimiter = rl.perIP
+		}
+		if delay, ok := ipLimiter.allow(ip, now); !ok {
+			rl.global.cancel(globalReservation, now)
+			log.Debugw("client rate limited", "ip", ip, "method", r.Method, "path", r.URL.Path)
+			writeRateLimited(w, delay)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// allowVoter takes a token from the budget of the voter address in the
+// process provided. If the budget is exhausted, it returns false and the
+// time until the voter can submit a vote again.
+func (rl *rateLimiter) allowVoter(processID types.ProcessID, address types.HexBytes) (time.Duration, bool) {
+	if rl == nil {
+		return 0, true
+	}
+	return rl.perVoter.allow(processID.String()+"/"+address.String(), time.Now())
+}
+
+// stats returns the counters of the rate limiters.
+func (rl *rateLimiter) stats() *RateLimitStats {
+	stats := &RateLimitStats{
+		Global:   rl.global.stats(),
+		PerIP:    rl.perIP.stats(),
+		PerVoter: rl.perVoter.stats(),
+		Routes:   make(map[string]*RateLimiterStats, len(rl.routes)),
+	}
+	for route, kl := range rl.routes {
+		if routeStats := kl.stats(); routeStats != nil {
+			stats.Routes[route] = routeStats
+		}
+	}
+	return stats
+}
+
+// writeRateLimited writes the rate limited error, with the seconds to wait
+// before retrying in the Retry-After header.
+func writeRateLimited(w http.ResponseWriter, delay time.Duration) {
+	w.Header().Set("Retry-After", strconv.Itoa(max(1, int(math.Ceil(delay.Seconds())))))
+	ErrRateLimited.Write(w)
+}
+
+// clientIP returns the IP of the client of the request provided. Behind the
+// number of trusted proxies provided, the client IP is the entry appended to
+// the X-Forwarded-For header by the outermost trusted proxy, that is, the
+// trustedProxies-th entry from the right, since the entries on its left can
+// be set by the client. Without trusted proxies, or if the header does not
+// have a valid IP in that entry, it falls back to the remote address.
+func clientIP(r *http.Request, trustedProxies int) string {
+	if trustedProxies > 0 {
+		var forwarded []string
+		for _, header := range r.Header.Values("X-Forwarded-For") {
+			forwarded = append(forwarded, strings.Split(header, ",")...)
+		}
+		if len(forwarded) >= trustedProxies {
+			if ip := net.ParseIP(strings.TrimSpace(forwarded[len(forwarded)-trustedProxies])); ip != nil {
+				return ip.String()
+			}
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
@@ -0,0 +1,182 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/vocdoni/davinci-node/internal/testutil"
+	"github.com/vocdoni/davinci-node/types"
+	"golang.org/x/time/rate"
+)
+
+func TestParseRateLimit(t *testing.T) {
+	c := qt.New(t)
+
+	rl, err := ParseRateLimit("60/1m")
+	c.Assert(err, qt.IsNil)
+	c.Assert(rl, qt.Equals, RateLimit{Requests: 60, Period: time.Minute})
+	c.Assert(rl.Enabled(), qt.IsTrue)
+
+	for _, disabled := range []string{"", "0", "0/1s"} {
+		rl, err = ParseRateLimit(disabled)
+		c.Assert(err, qt.IsNil)
+		c.Assert(rl.Enabled(), qt.IsFalse)
+	}
+
+	for _, invalid := range []string{"60", "a/1s", "-1/1s", "10/", "10/-1s"} {
+		_, err = ParseRateLimit(invalid)
+		c.Assert(err, qt.IsNotNil, qt.Commentf("rate limit %q", invalid))
+	}
+}
+
+func TestKeyedLimiter(t *testing.T) {
+	c := qt.New(t)
+	kl := newKeyedLimiter(RateLimit{Requests: 2, Period: time.Minute})
+	now := time.Now()
+
+	for range 2 {
+		_, ok := kl.allow("a", now)
+		c.Assert(ok, qt.IsTrue)
+	}
+	delay, ok := kl.allow("a", now)
+	c.Assert(ok, qt.IsFalse)
+	c.Assert(delay > 0 && delay <= 30*time.Second, qt.IsTrue, qt.Commentf("delay %s", delay))
+
+	// the buckets are independent
+	_, ok = kl.allow("b", now)
+	c.Assert(ok, qt.IsTrue)
+
+	// a rejected request does not consume the budget
+	_, ok = kl.allow("a", now.Add(30*time.Second))
+	c.Assert(ok, qt.IsTrue)
+
+	stats := kl.stats()
+	c.Assert(stats.Allowed, qt.Equals, uint64(4))
+	c.Assert(stats.Rejected, qt.Equals, uint64(1))
+	c.Assert(stats.Tracked, qt.Equals, 2)
+
+	// the rate is not truncated when the requests exceed the nanoseconds of
+	// the period
+	c.Assert(RateLimit{Requests: 3, Period: 2 * time.Nanosecond}.perSecond(), qt.Equals, rate.Limit(1.5e9))
+	c.Assert(RateLimit{Requests: 60, Period: time.Minute}.perSecond(), qt.Equals, rate.Limit(1))
+
+	// the full buckets are pruned
+	kl.prune(now.Add(2 * time.Minute))
+	c.Assert(kl.stats().Tracked, qt.Equals, 0)
+
+	// a disabled limiter allows every request
+	kl = newKeyedLimiter(RateLimit{})
+	c.Assert(kl, qt.IsNil)
+	_, ok = kl.allow("a", now)
+	c.Assert(ok, qt.IsTrue)
+}
+
+func TestRateLimiterMiddleware(t *testing.T) {
+	c := qt.New(t)
+	rl := newRateLimiter(&RateLimitConfig{
+		Global: RateLimit{Requests: 4, Period: time.Hour},
+		PerIP:  RateLimit{Requests: 2, Period: time.Hour},
+		Routes: map[string]RateLimit{
+			RateLimitRoute(http.MethodPost, VotesEndpoint): {Requests: 1, Period: time.Hour},
+		},
+	})
+	handler := rl.middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		httpWriteOK(w)
+	}))
+	request := func(method, path, remoteAddr string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		req.RemoteAddr = remoteAddr
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// the votes route has its own budget
+	c.Assert(request(http.MethodPost, VotesEndpoint, "10.0.0.1:1000").Code, qt.Equals, http.StatusOK)
+	rec := request(http.MethodPost, VotesEndpoint, "10.0.0.1:1001")
+	c.Assert(rec.Code, qt.Equals, http.StatusTooManyRequests)
+	retryAfter, err := strconv.Atoi(rec.Header().Get("Retry-After"))
+	c.Assert(err, qt.IsNil)
+	c.Assert(retryAfter >= 3599 && retryAfter <= 3601, qt.IsTrue, qt.Commentf("retry after %d", retryAfter))
+	var apiErr Error
+	c.Assert(json.NewDecoder(rec.Body).Decode(&apiErr), qt.IsNil)
+	c.Assert(apiErr.Code, qt.Equals, ErrRateLimited.Code)
+
+	// the rest of the routes share the per IP budget
+	c.Assert(request(http.MethodGet, PingEndpoint, "10.0.0.1:1002").Code, qt.Equals, http.StatusOK)
+	c.Assert(request(http.MethodGet, InfoEndpoint, "10.0.0.1:1003").Code, qt.Equals, http.StatusOK)
+	c.Assert(request(http.MethodGet, PingEndpoint, "10.0.0.1:1004").Code, qt.Equals, http.StatusTooManyRequests)
+
+	// other clients have their own budget until the global one is exhausted,
+	// the requests rejected by the client budgets do not consume it
+	c.Assert(request(http.MethodGet, PingEndpoint, "10.0.0.2:1000").Code, qt.Equals, http.StatusOK)
+	c.Assert(request(http.MethodGet, PingEndpoint, "10.0.0.3:1000").Code, qt.Equals, http.StatusTooManyRequests)
+
+	// the requests rejected by the global budget do not consume the client
+	// budget, so the client can still send a request once it is refilled
+	c.Assert(request(http.MethodGet, PingEndpoint, "10.0.0.3:1001").Code, qt.Equals, http.StatusTooManyRequests)
+	rl.global = newKeyedLimiter(RateLimit{Requests: 4, Period: time.Hour})
+	c.Assert(request(http.MethodGet, PingEndpoint, "10.0.0.3:1002").Code, qt.Equals, http.StatusOK)
+	c.Assert(request(http.MethodGet, PingEndpoint, "10.0.0.3:1003").Code, qt.Equals, http.StatusOK)
+
+	stats := rl.stats()
+	c.Assert(stats.Global.Allowed, qt.Equals, uint64(2))
+	c.Assert(stats.PerIP.Rejected, qt.Equals, uint64(1))
+	c.Assert(stats.Routes[RateLimitRoute(http.MethodPost, VotesEndpoint)].Rejected, qt.Equals, uint64(1))
+	c.Assert(stats.PerVoter, qt.IsNil)
+}
+
+func TestRateLimiterVoter(t *testing.T) {
+	c := qt.New(t)
+	rl := newRateLimiter(&RateLimitConfig{
+		PerVoter: RateLimit{Requests: 1, Period: time.Minute},
+	})
+	processID := testutil.RandomProcessID()
+	address := types.HexBytes(testutil.RandomAddress().Bytes())
+
+	_, ok := rl.allowVoter(processID, address)
+	c.Assert(ok, qt.IsTrue)
+	_, ok = rl.allowVoter(processID, address)
+	c.Assert(ok, qt.IsFalse)
+	// the budget is per process
+	_, ok = rl.allowVoter(testutil.RandomProcessID(), address)
+	c.Assert(ok, qt.IsTrue)
+
+	// without rate limiter every vote is allowed
+	var disabled *rateLimiter
+	_, ok = disabled.allowVoter(processID, address)
+	c.Assert(ok, qt.IsTrue)
+}
+
+func TestClientIP(t *testing.T) {
+	c := qt.New(t)
+	req := httptest.NewRequest(http.MethodGet, PingEndpoint, nil)
+	req.RemoteAddr = "10.0.0.1:1234"
+	// the client sets the first entry, the trusted proxies append the rest
+	req.Header.Set("X-Forwarded-For", "198.51.100.1, 203.0.113.7, 10.0.0.2")
+
+	c.Assert(clientIP(req, 0), qt.Equals, "10.0.0.1")
+	c.Assert(clientIP(req, 1), qt.Equals, "10.0.0.2")
+	c.Assert(clientIP(req, 2), qt.Equals, "203.0.113.7")
+	c.Assert(clientIP(req, 3), qt.Equals, "198.51.100.1")
+	// not enough entries for the trusted proxies
+	c.Assert(clientIP(req, 4), qt.Equals, "10.0.0.1")
+
+	// the entries of repeated headers are appended
+	req.Header.Set("X-Forwarded-For", "198.51.100.1")
+	req.Header.Add("X-Forwarded-For", "203.0.113.7")
+	c.Assert(clientIP(req, 1), qt.Equals, "203.0.113.7")
+
+	req.Header.Set("X-Forwarded-For", "203.0.113.7, not-an-ip")
+	c.Assert(clientIP(req, 1), qt.Equals, "10.0.0.1")
+
+	// the X-Real-IP header is not trusted
+	req.Header.Del("X-Forwarded-For")
+	req.Header.Set("X-Real-IP", "203.0.113.8")
+	c.Assert(clientIP(req, 1), qt.Equals, "10.0.0.1")
+}
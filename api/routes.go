@@ -20,6 +20,18 @@ const (
 	CensusParticipantsEndpoint = "/processes/{" + ProcessURLParam + "}/participants"                           // GET: Get all participants info for a process
 	CensusParticipantEndpoint  = "/processes/{" + ProcessURLParam + "}/participants/{" + AddressURLParam + "}" // GET: Get participant info for a process
 	NewEncryptionKeysEndpoint  = "/processes/keys"                                                             // POST: Create new encryption keys for a process
+	ResultsDecryptionEndpoint  = "/processes/{" + ProcessURLParam + "}/results/decryption"                     // GET: Get the decryption proofs of the process results
+
+	// Process list query params
+	ProcessListCursorQueryParam       = "cursor"         // URL query param for the process list pagination cursor
+	ProcessListLimitQueryParam        = "limit"          // URL query param for the process list page size
+	ProcessListChainIDQueryParam      = "chainId"        // URL query param to filter processes by chain ID
+	ProcessListStatusQueryParam       = "status"         // URL query param to filter processes by status name
+	ProcessListOrganizationQueryParam = "organizationId" // URL query param to filter processes by organization
+	ProcessListCensusOriginQueryParam = "censusOrigin"   // URL query param to filter processes by census origin name
+	ProcessListHasResultsQueryParam   = "hasResults"     // URL query param to filter processes with or without results
+	ProcessListFromQueryParam         = "from"           // URL query param for the start of the time window (unix seconds)
+	ProcessListToQueryParam           = "to"             // URL query param for the end of the time window (unix seconds)
 
 	// Vote endpoints
 	VotesEndpoint = "/votes" // POST: Submit a vote
@@ -30,6 +42,14 @@ const (
 	VoteStatusEndpoint    = VotesEndpoint + "/{" + ProcessURLParam + "}/voteId/{" + VoteIDURLParam + "}"      // GET: Check vote status
 	VoteByAddressEndpoint = VotesEndpoint + "/{" + ProcessURLParam + "}/address/{" + AddressURLParam + "}"    // GET: Get vote by address
 	BallotByIndexEndpoint = VotesEndpoint + "/{" + ProcessURLParam + "}/ballot/{" + BallotIndexURLParam + "}" // GET: Get ballot by index
+	VoteReceiptEndpoint   = VoteStatusEndpoint + "/receipt"                                                   // GET: Get the inclusion receipt of a settled vote
+	SpoilBallotEndpoint   = VotesEndpoint + "/spoil"                                                          // POST: Spoil a ballot to audit its encryption
+	SpoiledBallotEndpoint = VoteStatusEndpoint + "/spoiled"                                                   // GET: Get a spoiled ballot
+
+	// Vote status event streams (Server-Sent Events)
+	EventStreamSuffix               = "/stream"                                                        // Suffix of the event stream endpoints
+	VoteStatusStreamEndpoint        = VoteStatusEndpoint + EventStreamSuffix                           // GET: Stream the status transitions of a vote
+	ProcessVoteStatusStreamEndpoint = VotesEndpoint + "/{" + ProcessURLParam + "}" + EventStreamSuffix // GET: Stream the vote status transitions of a process
 
 	// Info endpoint
 	InfoEndpoint = "/info" // GET: Get ballot proof information
@@ -37,22 +57,78 @@ const (
 	// Host load endpoint
 	HostLoadEndpoint = "/info/load" // GET: Get host load metrics
 
+	// Prometheus metrics endpoint
+	MetricsEndpoint = "/metrics" // GET: Get Prometheus metrics of the sequencer pipeline
+
 	// Static file serving endpoint
 	StaticFilesEndpoint = "/app*" // GET: Serve static files from the /webapp directory
 
 	// Worker URL params and endpoints
-	SequencerUUIDURLParam   = "uuid"    // Param for worker UUID
-	WorkerAddressQueryParam = "address" // URL query param for worker address
-	WorkerNameQueryParam    = "name"    // URL query param for worker name
-	WorkerTokenQueryParam   = "token"   // URL query param for worker token
+	SequencerUUIDURLParam   = "uuid"               // Param for worker UUID
+	WorkerAddressQueryParam = "address"            // URL query param for worker address
+	WorkerNameQueryParam    = "name"               // URL query param for worker name
+	WorkerTokenQueryParam   = "token"              // URL query param for worker token
+	WorkerJobsQueryParam    = "jobs"               // URL query param for the comma separated job types taken by the worker
+	WorkerJobTypeHeader     = "X-Davinci-Job-Type" // Response header with the type of a proof job
 
 	WorkersEndpoint         = "/workers/{" + SequencerUUIDURLParam + "}" // Base workers endpoint
 	WorkerTokenDataEndpoint = WorkersEndpoint + "/authData"              // GET: Message to be signed by workers
 	WorkerJobEndpoint       = WorkersEndpoint + "/job"                   // GET: New job for worker POST: Submit job from worker
+	WorkerProofJobEndpoint  = WorkersEndpoint + "/proof"                 // POST: Submit the result of a proof job from worker
 
 	// Sequencer endpoints
 	SequencerWorkersEndpoint = "/sequencer/workers" // GET: List worker statistics
 
+	// DKG endpoints
+	DKGSessionURLParam       = "sessionId"                                           // URL parameter for DKG session ID
+	DKGTrusteeURLParam       = "trusteeIndex"                                        // URL parameter for DKG trustee index
+	DKGSessionQueryParam     = "dkgSession"                                          // URL query param to request a DKG derived encryption key
+	DKGSessionsEndpoint      = "/dkg"                                                // POST: Create a new DKG session (requires the DKG token)
+	DKGSessionEndpoint       = DKGSessionsEndpoint + "/{" + DKGSessionURLParam + "}" // GET: Get DKG session info
+	DKGCommitmentsEndpoint   = DKGSessionEndpoint + "/commitments"                   // POST: Submit trustee commitments
+	DKGSharesEndpoint        = DKGSessionEndpoint + "/shares"                        // POST: Submit trustee encrypted shares
+	DKGTrusteeSharesEndpoint = DKGSharesEndpoint + "/{" + DKGTrusteeURLParam + "}"   // GET: Get encrypted shares addressed to a trustee
+	DKGVerificationEndpoint  = DKGSessionEndpoint + "/verification"                  // POST: Submit the verification of the shares received by a trustee
+
+	// Threshold decryption endpoints
+	ThresholdDecryptionEndpoint = ProcessEndpoint + "/decryption"            // GET: Get the threshold decryption of a process
+	PartialDecryptionsEndpoint  = ThresholdDecryptionEndpoint + "/partials"  // POST: Submit trustee partial decryptions
+	DecryptionResponsesEndpoint = ThresholdDecryptionEndpoint + "/responses" // POST: Submit trustee decryption proof responses
+
+	// Webhook endpoints (require the webhooks bearer token)
+	WebhookURLParam                  = "webhookId"                                     // URL parameter for webhook subscription ID
+	WebhookDeliveriesLimitQueryParam = "limit"                                         // URL query param for the number of deliveries returned
+	WebhooksEndpoint                 = "/webhooks"                                     // GET: List webhook subscriptions, POST: Create webhook subscription
+	WebhookEndpoint                  = WebhooksEndpoint + "/{" + WebhookURLParam + "}" // DELETE: Delete webhook subscription
+	WebhookDeliveriesEndpoint        = WebhookEndpoint + "/deliveries"                 // GET: List the last deliveries of a webhook subscription
+
+	// Admin endpoints (require the admin bearer token)
+	AdminQueueURLParam              = "queue"                                                 // URL parameter for the queue name
+	AdminQueueKeyURLParam           = "key"                                                   // URL parameter for the hex key of a queue item
+	AdminQueueProcessQueryParam     = "processId"                                             // URL query param to filter the queue items by process
+	AdminQueueLimitQueryParam       = "limit"                                                 // URL query param for the number of queue items returned
+	AdminQueuesEndpoint             = "/admin/queues"                                         // GET: Size of the sequencer queues
+	AdminQueueEndpoint              = AdminQueuesEndpoint + "/{" + AdminQueueURLParam + "}"   // GET: List the items of a queue
+	AdminQueueItemEndpoint          = AdminQueueEndpoint + "/{" + AdminQueueKeyURLParam + "}" // GET: Get an item of a queue
+	AdminQueueItemReleaseEndpoint   = AdminQueueItemEndpoint + "/release"                     // POST: Release the reservation of a queue item
+	AdminQueueItemFailEndpoint      = AdminQueueItemEndpoint + "/fail"                        // POST: Mark an aggregated or state transition batch as failed
+	AdminProcessesEndpoint          = "/admin/processes"                                      // Base admin processes endpoint
+	AdminPausedProcessesEndpoint    = AdminProcessesEndpoint + "/paused"                      // GET: List the processes whose sequencing is paused
+	AdminProcessEndpoint            = AdminProcessesEndpoint + "/{" + ProcessURLParam + "}"   // Base admin process endpoint
+	AdminProcessCleanupEndpoint     = AdminProcessEndpoint + "/cleanup"                       // POST: Remove the queued ballots and batches of a process
+	AdminProcessPauseEndpoint       = AdminProcessEndpoint + "/pause"                         // POST: Pause the sequencing of a process
+	AdminProcessResumeEndpoint      = AdminProcessEndpoint + "/resume"                        // POST: Resume the sequencing of a process
+	AdminProcessBatchPolicyEndpoint = AdminProcessEndpoint + "/batchPolicy"                   // GET, POST: Get or set the batch policy of a process
+
+	// Census builder endpoints (require the census bearer token, except the published census)
+	CensusURLParam                    = "censusId"                                                   // URL parameter for the working census ID
+	CensusRootURLParam                = "censusRoot"                                                 // URL parameter for the published census root
+	CensusesEndpoint                  = "/censuses"                                                  // POST: Create a working census
+	CensusEndpoint                    = CensusesEndpoint + "/{" + CensusURLParam + "}"               // GET: Get the root and size of a working census, DELETE: Discard it
+	CensusBuilderParticipantsEndpoint = CensusEndpoint + "/participants"                             // POST: Add a batch of participants, DELETE: Remove a batch of participants
+	CensusPublishEndpoint             = CensusEndpoint + "/publish"                                  // POST: Publish a working census by its root
+	PublishedCensusEndpoint           = CensusesEndpoint + "/published/{" + CensusRootURLParam + "}" // GET: Get the dump of a published census (public)
+
 	// Metadata endpoints
 	MetadataHashParam   = "metadataHash"                                       // URL parameter for metadata hash
 	MetadataSetEndpoint = "/metadata"                                          // POST: Set metadata
@@ -87,4 +163,5 @@ var LogExcludedPrefixes = []string{
 	PingEndpoint,
 	WorkersEndpoint,
 	InfoEndpoint,
+	MetricsEndpoint,
 }
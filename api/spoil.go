@@ -0,0 +1,150 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/go-chi/chi/v5"
+	"github.com/vocdoni/davinci-node/circuits/ballotproof"
+	bjj "github.com/vocdoni/davinci-node/crypto/ecc/bjj_gnark"
+	"github.com/vocdoni/davinci-node/crypto/signatures/ethereum"
+	"github.com/vocdoni/davinci-node/log"
+	"github.com/vocdoni/davinci-node/storage"
+	"github.com/vocdoni/davinci-node/types"
+)
+
+// spoilBallot spoils a ballot built by the client of a voter to audit its
+// encryption (a Benaloh challenge). The ballot is re-encrypted with the
+// randomness and the field values revealed, to check that the client
+// encrypted the choices of the voter, and recorded with the result, but it
+// is never tallied.
+// POST /votes/spoil
+func (a *API) spoilBallot(w http.ResponseWriter, r *http.Request) {
+	// decode the spoiled ballot
+	r.Body = http.MaxBytesReader(w, r.Body, 1<<20) // 1 MiB limit, as /votes
+	req := &SpoilBallotRequest{}
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			ErrRequestBodyTooLarge.Write(w)
+			return
+		}
+		ErrMalformedBody.Withf("could not decode request body: %v", err).Write(w)
+		return
+	}
+	if err := json.Unmarshal(body, req); err != nil {
+		ErrMalformedBody.Withf("could not unmarshal request body: %v", err).Write(w)
+		return
+	}
+
+	// sanity checks
+	if req.Ballot == nil || req.K == nil || req.Address == nil || req.Signature == nil {
+		ErrMalformedBody.Withf("missing required fields").Write(w)
+		return
+	}
+	if len(req.Address) != common.AddressLength {
+		ErrMalformedBody.Withf("address must be %d bytes", common.AddressLength).Write(w)
+		return
+	}
+	req.Address = types.HexBytes(common.BytesToAddress(req.Address).Bytes())
+	if !req.ProcessID.IsValid() {
+		ErrMalformedProcessID.Withf("invalid process ID").Write(w)
+		return
+	}
+	if !a.runtimes.SupportsProcess(req.ProcessID) {
+		http.NotFound(w, r)
+		return
+	}
+	process, err := a.storage.Process(req.ProcessID)
+	if err != nil {
+		ErrProcessNotFound.Withf("could not get process: %v", err).Write(w)
+		return
+	}
+	// the ballots can only be spoiled while they could be cast
+	if ok, err := a.storage.ProcessIsAcceptingVotes(req.ProcessID); !ok {
+		if err != nil {
+			ErrProcessNotAcceptingVotes.WithErr(err).Write(w)
+			return
+		}
+		ErrProcessNotAcceptingVotes.Write(w)
+		return
+	}
+	// only the voter can spoil its ballots
+	signature := new(ethereum.ECDSASignature).SetBytes(req.Signature)
+	if signature == nil {
+		ErrMalformedBody.Withf("could not decode signature").Write(w)
+		return
+	}
+	if ok, _ := signature.VerifyVoteID(req.VoteID, common.BytesToAddress(req.Address)); !ok {
+		ErrInvalidSignature.Write(w)
+		return
+	}
+	if delay, ok := a.rateLimiter.allowVoter(req.ProcessID, req.Address); !ok {
+		writeRateLimited(w, delay)
+		return
+	}
+
+	// re-encrypt the field values with the randomness revealed
+	encryptionKey := new(bjj.BJJ).SetPoint(process.EncryptionKey.X.MathBigInt(), process.EncryptionKey.Y.MathBigInt())
+	verified := true
+	if err := req.Verify(encryptionKey); err != nil {
+		if !errors.Is(err, ballotproof.ErrBallotEncryptionMismatch) {
+			ErrInvalidSpoiledBallot.WithErr(err).Write(w)
+			return
+		}
+		verified = false
+	}
+	spoiled := &storage.SpoiledBallot{
+		ProcessID:   req.ProcessID,
+		VoteID:      req.VoteID,
+		Address:     req.Address,
+		Ballot:      req.Ballot,
+		K:           req.K,
+		FieldValues: req.FieldValues,
+		Verified:    verified,
+		SpoiledAt:   time.Now(),
+	}
+	if err := a.storage.SetSpoiledBallot(spoiled); err != nil {
+		if errors.Is(err, storage.ErroBallotAlreadyExists) {
+			ErrBallotAlreadySubmitted.Write(w)
+			return
+		}
+		ErrGenericInternalServerError.Withf("could not record spoiled ballot: %v", err).Write(w)
+		return
+	}
+	log.Infow("ballot spoiled",
+		"processID", req.ProcessID.String(),
+		"voteID", req.VoteID.String(),
+		"verified", verified)
+	httpWriteJSON(w, spoiled)
+}
+
+// spoiledBallot returns a spoiled ballot, so anyone can audit it
+// GET /votes/{processId}/voteId/{voteId}/spoiled
+func (a *API) spoiledBallot(w http.ResponseWriter, r *http.Request) {
+	processID, err := types.HexStringToProcessID(chi.URLParam(r, ProcessURLParam))
+	if err != nil {
+		ErrMalformedProcessID.Withf("could not decode process ID: %v", err).Write(w)
+		return
+	}
+	voteID, err := types.HexStringToVoteID(chi.URLParam(r, VoteIDURLParam))
+	if err != nil {
+		ErrMalformedBody.Withf("could not decode vote ID: %v", err).Write(w)
+		return
+	}
+	spoiled, err := a.storage.SpoiledBallot(processID, voteID)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			ErrResourceNotFound.Withf("ballot is not spoiled").Write(w)
+			return
+		}
+		ErrGenericInternalServerError.WithErr(err).Write(w)
+		return
+	}
+	httpWriteJSON(w, spoiled)
+}
@@ -1,10 +1,16 @@
 package api
 
 import (
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/google/uuid"
+	"github.com/vocdoni/davinci-node/circuits/ballotproof"
 	"github.com/vocdoni/davinci-node/crypto/elgamal"
 	"github.com/vocdoni/davinci-node/storage"
 	"github.com/vocdoni/davinci-node/types"
 	"github.com/vocdoni/davinci-node/util/circomgnark"
+	"github.com/vocdoni/davinci-node/webhooks"
 )
 
 // CensusParticipant is a participant in a census.
@@ -51,6 +57,7 @@ type SequencerInfo struct {
 	VerificationKeyHash string                          `json:"verificationKeyHash"`
 	Networks            map[uint64]SequencerNetworkInfo `json:"networks"`
 	SequencerAddress    types.HexBytes                  `json:"sequencerAddress"`
+	ReadOnly            bool                            `json:"readOnly,omitempty"`
 }
 
 // VoteResponse is the response returned by the vote submission endpoint.
@@ -63,6 +70,140 @@ type VoteStatusResponse struct {
 	Status string `json:"status"`
 }
 
+// SpoilBallotRequest is the request to spoil a ballot to audit its
+// encryption (a Benaloh challenge). It is signed by the voter as the vote of
+// the ballot, so only the voter can spoil it.
+type SpoilBallotRequest struct {
+	ballotproof.SpoiledBallot
+	Signature types.HexBytes `json:"signature"`
+}
+
+// ArboProof is an inclusion proof of a leaf of the state tree: the key and
+// value hashed through the siblings path lead to the root.
+type ArboProof struct {
+	Root     *types.BigInt   `json:"root"`
+	Siblings []*types.BigInt `json:"siblings"`
+	Key      *types.BigInt   `json:"key"`
+	Value    *types.BigInt   `json:"value"`
+}
+
+// VoteReceiptResponse is the response returned by the vote receipt
+// endpoint. It proves that a vote is included in the state settled on chain:
+// the ballot leaf stored at its ballot index and the inclusion proofs of the
+// leaf and the vote ID against the state root of the process, together with
+// the transaction and blob that carried the vote. If the ballot was
+// overwritten by a later vote of the same voter, the leaf is the one of the
+// overwriting vote.
+type VoteReceiptResponse struct {
+	ProcessID        types.ProcessID   `json:"processId"`
+	VoteID           types.VoteID      `json:"voteId"`
+	BallotIndex      types.BallotIndex `json:"ballotIndex"`
+	Ballot           *elgamal.Ballot   `json:"ballot"`
+	Address          common.Address    `json:"address"`
+	Weight           *types.BigInt     `json:"weight"`
+	StateRoot        *types.BigInt     `json:"stateRoot"`
+	BallotProof      *ArboProof        `json:"ballotProof"`
+	VoteIDProof      *ArboProof        `json:"voteIdProof"`
+	SettledStateRoot *types.BigInt     `json:"settledStateRoot"`
+	TxHash           types.HexBytes    `json:"txHash"`
+	BlobIndex        int               `json:"blobIndex"`
+	Overwritten      bool              `json:"overwritten"`
+	OverwrittenBy    *types.VoteID     `json:"overwrittenBy,omitempty"`
+}
+
+// VoteStatusEvent is the data of the events sent by the vote status streams.
+type VoteStatusEvent struct {
+	ProcessID types.ProcessID `json:"processId"`
+	VoteID    types.VoteID    `json:"voteId"`
+	Status    string          `json:"status"`
+	Timestamp int64           `json:"timestamp"`
+}
+
+// AdminQueuesResponse is the response returned by the admin queues endpoint
+// with the size of every queue of the sequencer.
+type AdminQueuesResponse struct {
+	Queues map[storage.Queue]storage.QueueSize `json:"queues"`
+}
+
+// AdminQueueItemsResponse is the response returned by the admin queue items
+// endpoint.
+type AdminQueueItemsResponse struct {
+	Items []storage.QueueItem `json:"items"`
+}
+
+// AdminCleanupResponse is the response returned by the admin process cleanup
+// endpoint with the number of votes marked as timeout.
+type AdminCleanupResponse struct {
+	TimedOutVotes int `json:"timedOutVotes"`
+}
+
+// AdminPausedProcessesResponse is the response returned by the admin paused
+// processes endpoint.
+type AdminPausedProcessesResponse struct {
+	Processes []types.ProcessID `json:"processes"`
+}
+
+// AdminBatchPolicy is the batch policy of a process, as a comma separated
+// list of key=value options. It is the request and the response of the
+// admin process batch policy endpoints.
+type AdminBatchPolicy struct {
+	Policy string `json:"policy"`
+}
+
+// CensusResponse is the response returned by the census builder endpoints
+// with the state of a working census. The root is empty while the census
+// has no participants.
+type CensusResponse struct {
+	CensusID uuid.UUID      `json:"censusId"`
+	Root     types.HexBytes `json:"root,omitempty"`
+	Size     int            `json:"size"`
+}
+
+// CensusParticipantsRequest is the request to add a batch of participants
+// to a working census. The weight of the participants is 1 if it is not
+// set.
+type CensusParticipantsRequest struct {
+	Participants []CensusParticipant `json:"participants"`
+}
+
+// CensusKeysRequest is the request to remove a batch of participants from a
+// working census, by their keys.
+type CensusKeysRequest struct {
+	Keys []types.HexBytes `json:"keys"`
+}
+
+// PublishedCensusResponse is the response returned by the census publish
+// endpoint. The URI serves the dump of the census, and can be used as the
+// census URI of a process.
+type PublishedCensusResponse struct {
+	Root types.HexBytes `json:"root"`
+	Size int            `json:"size"`
+	URI  string         `json:"uri"`
+}
+
+// WebhookRequest is the request to create a webhook subscription. If the
+// organization ID is empty, the subscription receives the events of every
+// process. If the events list is empty, it receives every event type. If
+// the secret is empty, a random one is generated.
+type WebhookRequest struct {
+	URL            string          `json:"url"`
+	Secret         string          `json:"secret,omitempty"`
+	OrganizationID *common.Address `json:"organizationId,omitempty"`
+	Events         []string        `json:"events,omitempty"`
+}
+
+// WebhookList is the response returned by the webhook subscriptions list
+// endpoint. The secrets of the subscriptions are not included.
+type WebhookList struct {
+	Webhooks []*webhooks.Subscription `json:"webhooks"`
+}
+
+// WebhookDeliveryList is the response returned by the webhook deliveries
+// endpoint, newest first.
+type WebhookDeliveryList struct {
+	Deliveries []*webhooks.Delivery `json:"deliveries"`
+}
+
 // WorkerAuthDataResponse is the response returned by the worker sign
 // message endpoint.
 type WorkerAuthDataResponse struct {
@@ -80,6 +221,34 @@ type WorkerJobResponse struct {
 	FailedCount  int64        `json:"failedCount"`
 }
 
+// WorkerProofJob is an aggregation or state transition proving job sent to
+// a worker. The witness is the binary encoded full witness of the circuit of
+// the job type.
+type WorkerProofJob struct {
+	ID        types.HexBytes  `json:"id"`
+	Type      string          `json:"type"`
+	ProcessID types.ProcessID `json:"processId"`
+	Witness   []byte          `json:"witness"`
+}
+
+// WorkerProofJobResult is the result of a proof job returned by a worker.
+// It contains the binary encoded proof, or the error if the worker could
+// not generate it.
+type WorkerProofJobResult struct {
+	ID    types.HexBytes `json:"id"`
+	Proof []byte         `json:"proof,omitempty"`
+	Error string         `json:"error,omitempty"`
+}
+
+// WorkerProofJobResponse is the response returned by the proof job
+// submission endpoint.
+type WorkerProofJobResponse struct {
+	ID           types.HexBytes `json:"id"`
+	Type         string         `json:"type"`
+	SuccessCount int64          `json:"successCount"`
+	FailedCount  int64          `json:"failedCount"`
+}
+
 // WorkerInfo contains information about a worker node.
 type WorkerInfo struct {
 	Name         string `json:"name"`
@@ -97,9 +266,46 @@ type SetMetadataResponse struct {
 	Hash types.HexBytes `json:"hash"`
 }
 
-// ProcessList is the response returned by the process list endpoint.
+// ProcessList is the response returned by the process list endpoint. The
+// Processes field keeps the list of process IDs returned by previous
+// versions, while Summaries contains the summary of each listed process in
+// the same order. The NextCursor is empty when there are no more processes
+// to list.
 type ProcessList struct {
-	Processes []types.ProcessID `json:"processes"`
+	Processes  []types.ProcessID `json:"processes"`
+	Summaries  []*ProcessSummary `json:"summaries"`
+	NextCursor string            `json:"nextCursor,omitempty"`
+}
+
+// ProcessSummary is the summary of a process returned by the process list
+// endpoint. The status and census origin are returned by name, the same
+// values accepted by the process list filters.
+type ProcessSummary struct {
+	ID             types.ProcessID `json:"id"`
+	Status         string          `json:"status"`
+	OrganizationID common.Address  `json:"organizationId"`
+	CensusOrigin   string          `json:"censusOrigin"`
+	StartTime      time.Time       `json:"startTime"`
+	EndTime        time.Time       `json:"endTime"`
+	MetadataURI    string          `json:"metadataURI"`
+	VotersCount    *types.BigInt   `json:"votersCount"`
+	HasResults     bool            `json:"hasResults"`
+}
+
+// NewProcessSummary builds the API summary of the stored process summary
+// provided.
+func NewProcessSummary(s *storage.ProcessSummary) *ProcessSummary {
+	return &ProcessSummary{
+		ID:             s.ID,
+		Status:         s.Status.String(),
+		OrganizationID: s.OrganizationID,
+		CensusOrigin:   s.CensusOrigin.String(),
+		StartTime:      s.StartTime,
+		EndTime:        s.EndTime,
+		MetadataURI:    s.MetadataURI,
+		VotersCount:    s.VotersCount,
+		HasResults:     s.HasResults,
+	}
 }
 
 type ProcessResponse struct {
@@ -107,12 +313,89 @@ type ProcessResponse struct {
 	IsAcceptingVotes bool `json:"isAcceptingVotes"`
 }
 
+// NewDKGSessionRequest is the request to create a new DKG session between the
+// trustees provided. The index of the trustees is assigned by the sequencer.
+type NewDKGSessionRequest struct {
+	Threshold int                `json:"threshold"`
+	Trustees  []types.DKGTrustee `json:"trustees"`
+}
+
+// DKGCommitmentsRequest is the request sent by a trustee to publish the
+// public coefficients of its secret polynomial. The signature must be
+// produced by the trustee address over the commitments.
+type DKGCommitmentsRequest struct {
+	TrusteeIndex int              `json:"trusteeIndex"`
+	Commitments  []types.DKGPoint `json:"commitments"`
+	Signature    types.HexBytes   `json:"signature"`
+}
+
+// DKGSharesRequest is the request sent by a trustee to deliver the encrypted
+// shares of its secret polynomial to the rest of trustees. The signature
+// must be produced by the trustee address over the shares.
+type DKGSharesRequest struct {
+	TrusteeIndex int                       `json:"trusteeIndex"`
+	Shares       []types.DKGEncryptedShare `json:"shares"`
+	Signature    types.HexBytes            `json:"signature"`
+}
+
+// DKGVerificationRequest is the request sent by a trustee once it has
+// verified the shares addressed to it. Complaints lists the indexes of the
+// trustees whose shares are invalid, it is empty if every share is valid.
+// The signature must be produced by the trustee address over the
+// complaints.
+type DKGVerificationRequest struct {
+	TrusteeIndex int            `json:"trusteeIndex"`
+	Complaints   []int          `json:"complaints"`
+	Signature    types.HexBytes `json:"signature"`
+}
+
+// DKGSharesResponse is the response returned by the trustee shares endpoint.
+type DKGSharesResponse struct {
+	Shares []types.DKGEncryptedShare `json:"shares"`
+}
+
+// PartialDecryptionsRequest is the request sent by a trustee to publish the
+// partial decryptions of the results of a process. The signature must be
+// produced by the trustee address over the partial decryptions.
+type PartialDecryptionsRequest struct {
+	TrusteeIndex int                          `json:"trusteeIndex"`
+	Partials     []types.DKGPartialDecryption `json:"partials"`
+	Signature    types.HexBytes               `json:"signature"`
+}
+
+// DecryptionResponsesRequest is the request sent by a quorum trustee with its
+// responses to the challenges of the combined decryption proofs. The
+// signature must be produced by the trustee address over the responses.
+type DecryptionResponsesRequest struct {
+	TrusteeIndex int             `json:"trusteeIndex"`
+	Responses    []*types.BigInt `json:"responses"`
+	Signature    types.HexBytes  `json:"signature"`
+}
+
 // HostLoadResponse is the exact shape we return to the client.
 type HostLoadResponse struct {
 	MemStats            any                `json:"memStats,omitempty"`
 	HostLoad1           float64            `json:"hostLoad1,omitempty"`
 	HostMemUsedPercent  float64            `json:"hostMemUsedPercent,omitempty"`
 	HostDiskUsedPercent map[string]float64 `json:"hostDiskUsedPercent,omitempty"`
+	RateLimits          *RateLimitStats    `json:"rateLimits,omitempty"`
+}
+
+// RateLimitStats are the counters of the API rate limiters. The disabled
+// limiters are omitted.
+type RateLimitStats struct {
+	Global   *RateLimiterStats            `json:"global,omitempty"`
+	PerIP    *RateLimiterStats            `json:"perIP,omitempty"`
+	Routes   map[string]*RateLimiterStats `json:"routes,omitempty"`
+	PerVoter *RateLimiterStats            `json:"perVoter,omitempty"`
+}
+
+// RateLimiterStats are the counters of a rate limiter since the API started.
+type RateLimiterStats struct {
+	Limit    string `json:"limit"`
+	Allowed  uint64 `json:"allowed"`
+	Rejected uint64 `json:"rejected"`
+	Tracked  int    `json:"tracked"` // Number of clients with a partially consumed budget
 }
 
 // SequencerStatsResponse is the response returned by the sequencer stats endpoint.
@@ -121,3 +404,22 @@ type SequencerStatsResponse struct {
 	ActiveProcesses int `json:"activeProcesses"`
 	PendingVotes    int `json:"pendingVotes"`
 }
+
+// SequencerSettlementResponse is the response returned by the sequencer
+// settlement endpoint, with the processes whose state transitions are held
+// because the fees are above the configured ceilings.
+type SequencerSettlementResponse struct {
+	Processes []*ProcessSettlement `json:"processes"`
+}
+
+// ProcessSettlement is the settlement status of a process whose state
+// transitions are held.
+type ProcessSettlement struct {
+	ProcessID     types.ProcessID `json:"processId"`
+	Reason        string          `json:"reason"`
+	HeldSince     time.Time       `json:"heldSince"`
+	Deadline      time.Time       `json:"deadline"`              // Time from which the transitions are settled whatever the fees
+	ExpectedDelay int64           `json:"expectedDelay"`         // Maximum seconds until the transitions are settled
+	GasTipCap     *types.BigInt   `json:"gasTipCap,omitempty"`   // Last suggested priority fee per gas, in wei
+	BlobBaseFee   *types.BigInt   `json:"blobBaseFee,omitempty"` // Last blob base fee, in wei
+}
@@ -38,7 +38,7 @@ func (a *API) voteStatus(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Get the vote ID status
-	status, err := a.storage.VoteIDStatus(processID, voteID)
+	status, err := a.voteIDStatus(processID, voteID)
 	if err != nil {
 		if errors.Is(err, storage.ErrNotFound) {
 			ErrResourceNotFound.WithErr(err).Write(w)
@@ -60,6 +60,120 @@ func (a *API) voteStatus(w http.ResponseWriter, r *http.Request) {
 	httpWriteJSON(w, response)
 }
 
+// voteIDStatus returns the status of a vote ID. An observer node sequences
+// no votes, so none of them has a status there; instead, the votes are
+// reported as settled once their receipt is indexed by the state sync.
+func (a *API) voteIDStatus(processID types.ProcessID, voteID types.VoteID) (int, error) {
+	status, err := a.storage.VoteIDStatus(processID, voteID)
+	if !errors.Is(err, storage.ErrNotFound) || !a.readOnly {
+		return status, err
+	}
+	if _, rerr := a.storage.VoteReceipt(processID, voteID); rerr != nil {
+		return status, err
+	}
+	return storage.VoteIDStatusSettled, nil
+}
+
+// voteReceipt returns the receipt of a settled vote, with the inclusion
+// proofs of its ballot and vote ID against the state root settled on chain,
+// so the voter can verify independently that the ballot is counted
+// GET /votes/{processId}/voteId/{voteId}/receipt
+func (a *API) voteReceipt(w http.ResponseWriter, r *http.Request) {
+	// Get the processID and voteID from the URL
+	processID, err := types.HexStringToProcessID(chi.URLParam(r, ProcessURLParam))
+	if err != nil {
+		ErrMalformedProcessID.Withf("could not decode process ID: %v", err).Write(w)
+		return
+	}
+
+	voteID, err := types.HexStringToVoteID(chi.URLParam(r, VoteIDURLParam))
+	if err != nil {
+		ErrMalformedBody.Withf("could not decode vote ID: %v", err).Write(w)
+		return
+	}
+
+	// Get the receipt recorded when the vote was settled
+	receipt, err := a.storage.VoteReceipt(processID, voteID)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			ErrResourceNotFound.Withf("vote is not settled yet").Write(w)
+			return
+		}
+		ErrGenericInternalServerError.WithErr(err).Write(w)
+		return
+	}
+
+	// Open the state at the root settled on chain
+	process, err := a.storage.Process(processID)
+	if err != nil {
+		ErrProcessNotFound.WithErr(err).Write(w)
+		return
+	}
+	if process.StateRoot == nil {
+		ErrResourceNotFound.Withf("process has no settled state root").Write(w)
+		return
+	}
+	s, err := state.LoadSnapshotOnRoot(a.storage.StateDB(), processID, process.StateRoot.MathBigInt())
+	if err != nil {
+		ErrGenericInternalServerError.Withf("could not open state: %v", err).Write(w)
+		return
+	}
+	if !s.ContainsVoteID(voteID) {
+		ErrResourceNotFound.Withf("vote is not included in the settled state").Write(w)
+		return
+	}
+
+	// Get the ballot leaf and the inclusion proofs
+	leaf, err := s.BallotLeaf(receipt.BallotIndex)
+	if err != nil {
+		ErrGenericInternalServerError.Withf("could not get ballot leaf: %v", err).Write(w)
+		return
+	}
+	ballotProof, err := s.GenArboProof(receipt.BallotIndex.StateKey())
+	if err != nil {
+		ErrGenericInternalServerError.Withf("could not generate ballot proof: %v", err).Write(w)
+		return
+	}
+	voteIDProof, err := s.GenArboProof(voteID.StateKey())
+	if err != nil {
+		ErrGenericInternalServerError.Withf("could not generate vote ID proof: %v", err).Write(w)
+		return
+	}
+
+	response := VoteReceiptResponse{
+		ProcessID:        processID,
+		VoteID:           voteID,
+		BallotIndex:      receipt.BallotIndex,
+		Ballot:           leaf.Ballot,
+		Address:          common.BigToAddress(leaf.Address),
+		Weight:           types.BigIntConverter(leaf.Weight),
+		StateRoot:        process.StateRoot,
+		BallotProof:      arboProofResponse(ballotProof),
+		VoteIDProof:      arboProofResponse(voteIDProof),
+		SettledStateRoot: receipt.StateRoot,
+		TxHash:           receipt.TxHash,
+		BlobIndex:        receipt.BlobIndex,
+	}
+
+	// Report if a later vote of the same voter overwrote the ballot
+	if lastVoteID, err := a.storage.BallotVoteID(processID, receipt.BallotIndex); err == nil && lastVoteID != voteID {
+		response.Overwritten = true
+		response.OverwrittenBy = &lastVoteID
+	}
+	httpWriteJSON(w, response)
+}
+
+// arboProofResponse converts a state inclusion proof to its API
+// representation.
+func arboProofResponse(proof *state.ArboProof) *ArboProof {
+	return &ArboProof{
+		Root:     types.BigIntConverter(proof.Root),
+		Siblings: types.SliceOf(proof.Siblings, types.BigIntConverter),
+		Key:      types.BigIntConverter(proof.Key),
+		Value:    types.BigIntConverter(proof.Value),
+	}
+}
+
 // voteByAddress retrieves an encrypted ballot by its address for a given
 // processID
 // GET /votes/{processId}/address/{address}
@@ -292,6 +406,19 @@ func (a *API) newVote(w http.ResponseWriter, r *http.Request) {
 		vote.CensusProof.VoterIndex = types.VoterIndex(proof.AddressIndex)
 		voterWeight = new(types.BigInt).SetBigInt(proof.Weight)
 	case process.Census.CensusOrigin.IsCSP():
+		// the state transition circuit cannot prove the votes of every CSP
+		// census origin yet
+		if !csp.StateTransitionSupported(process.Census.CensusOrigin) {
+			ErrInvalidCensusProof.Withf("census origin %s not supported by the state transition circuit",
+				process.Census.CensusOrigin).Write(w)
+			return
+		}
+		// the proof must be signed by the current CSP key of the process,
+		// the proofs signed by a rotated key are not accepted anymore
+		if err := csp.VerifyCensusRoot(&vote.CensusProof, process.Census.CensusRoot); err != nil {
+			ErrInvalidCensusProof.Withf("census proof not signed by the process CSP key").WithErr(err).Write(w)
+			return
+		}
 		if err := csp.VerifyCensusProof(&vote.CensusProof); err != nil {
 			ErrInvalidCensusProof.Withf("census proof verification failed").WithErr(err).Write(w)
 			return
@@ -329,6 +456,12 @@ func (a *API) newVote(w http.ResponseWriter, r *http.Request) {
 		ErrInvalidSignature.Write(w)
 		return
 	}
+	// rate limit the votes of the voter once the signature proves that the
+	// request comes from it, so nobody else can exhaust its budget
+	if delay, ok := a.rateLimiter.allowVoter(vote.ProcessID, vote.Address); !ok {
+		writeRateLimited(w, delay)
+		return
+	}
 
 	// calculate the ballot inputs hash
 	ballotInputsHash, err := ballotproof.BallotInputsHashIden3(
@@ -387,6 +520,9 @@ func (a *API) newVote(w http.ResponseWriter, r *http.Request) {
 		case errors.Is(err, storage.ErrAddressProcessing):
 			ErrAddressAlreadyProcessing.Write(w)
 			return
+		case errors.Is(err, storage.ErrBallotSpoiled):
+			ErrBallotSpoiled.Write(w)
+			return
 		default:
 			ErrGenericInternalServerError.Withf("could not push ballot: %v", err).Write(w)
 			return
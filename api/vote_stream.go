@@ -0,0 +1,218 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/vocdoni/davinci-node/log"
+	"github.com/vocdoni/davinci-node/storage"
+	"github.com/vocdoni/davinci-node/types"
+)
+
+const (
+	// maxEventStreams is the maximum number of event streams open at the
+	// same time, since they are not limited by the throttle middleware.
+	maxEventStreams = 1000
+	// eventStreamKeepAlive is the interval between keep alive comments, to
+	// avoid proxies closing idle streams.
+	eventStreamKeepAlive = 15 * time.Second
+	// voteStatusEventName is the name of the vote status events.
+	voteStatusEventName = "status"
+)
+
+// voteStatusStream streams the status transitions of a vote as Server-Sent
+// Events. The first event contains the current status of the vote, and the
+// stream ends once the vote reaches a final status (settled, error or
+// timeout).
+// GET /votes/{processId}/voteId/{voteId}/stream
+func (a *API) voteStatusStream(w http.ResponseWriter, r *http.Request) {
+	processID, err := types.HexStringToProcessID(chi.URLParam(r, ProcessURLParam))
+	if err != nil {
+		ErrMalformedProcessID.Withf("could not decode process ID: %v", err).Write(w)
+		return
+	}
+	voteID, err := types.HexStringToVoteID(chi.URLParam(r, VoteIDURLParam))
+	if err != nil {
+		ErrMalformedBody.Withf("could not decode vote ID: %v", err).Write(w)
+		return
+	}
+
+	// Subscribe before reading the current status to not miss transitions
+	events, cancel := a.storage.SubscribeVoteIDStatus(processID, &voteID)
+	defer func() { cancel() }()
+	status, err := a.voteIDStatus(processID, voteID)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			ErrResourceNotFound.WithErr(err).Write(w)
+			return
+		}
+		ErrGenericInternalServerError.WithErr(err).Write(w)
+		return
+	}
+
+	rc, release, ok := a.openEventStream(w)
+	if !ok {
+		return
+	}
+	defer release()
+	for {
+		final, err := writeVoteStatusEvent(w, rc, &storage.VoteIDStatusEvent{
+			ProcessID: processID,
+			VoteID:    voteID,
+			Status:    status,
+			Time:      time.Now(),
+		})
+		if err != nil || final {
+			return
+		}
+		if !a.forwardVoteStatusEvents(w, r, rc, events, true) {
+			return
+		}
+		// The subscription was cancelled because the client did not keep
+		// up, subscribe again and send the current status, since some
+		// transitions could be lost
+		cancel()
+		events, cancel = a.storage.SubscribeVoteIDStatus(processID, &voteID)
+		if status, err = a.voteIDStatus(processID, voteID); err != nil {
+			log.Debugw("failed to read vote status", "error", err)
+			return
+		}
+	}
+}
+
+// processVoteStatusStream streams the status transitions of every vote of a
+// process as Server-Sent Events, until the client disconnects. If the client
+// does not consume the events fast enough, the stream is closed.
+// GET /votes/{processId}/stream
+func (a *API) processVoteStatusStream(w http.ResponseWriter, r *http.Request) {
+	processID, err := types.HexStringToProcessID(chi.URLParam(r, ProcessURLParam))
+	if err != nil {
+		ErrMalformedProcessID.Withf("could not decode process ID: %v", err).Write(w)
+		return
+	}
+	if _, err := a.storage.Process(processID); err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			ErrProcessNotFound.WithErr(err).Write(w)
+			return
+		}
+		ErrGenericInternalServerError.WithErr(err).Write(w)
+		return
+	}
+
+	events, cancel := a.storage.SubscribeVoteIDStatus(processID, nil)
+	defer cancel()
+	rc, release, ok := a.openEventStream(w)
+	if !ok {
+		return
+	}
+	defer release()
+	if a.forwardVoteStatusEvents(w, r, rc, events, false) {
+		log.Debugw("vote status stream closed, client too slow", "processID", processID.String())
+	}
+}
+
+// openEventStream writes the headers of an event stream response, if the
+// limit of open streams is not reached. It returns the response controller
+// to flush the events, a function to release the stream slot once the stream
+// ends, and whether the stream was opened.
+func (a *API) openEventStream(w http.ResponseWriter) (*http.ResponseController, func(), bool) {
+	// Limit the number of open streams
+	select {
+	case a.eventStreams <- struct{}{}:
+	default:
+		ErrTooManyEventStreams.Write(w)
+		return nil, nil, false
+	}
+	release := func() { <-a.eventStreams }
+
+	rc := http.NewResponseController(w)
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	if err := rc.Flush(); err != nil {
+		log.Warnw("event stream flushing not supported", "error", err)
+		release()
+		return nil, nil, false
+	}
+	return rc, release, true
+}
+
+// forwardVoteStatusEvents writes the events received from the subscription
+// provided as Server-Sent Events, until the client disconnects, the API
+// stops or, if single is true, an event has a final status. It returns true
+// if it stopped because the subscription was cancelled, since the client did
+// not keep up with the events.
+func (a *API) forwardVoteStatusEvents(
+	w http.ResponseWriter,
+	r *http.Request,
+	rc *http.ResponseController,
+	events <-chan *storage.VoteIDStatusEvent,
+	single bool,
+) bool {
+	keepAlive := time.NewTicker(eventStreamKeepAlive)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return false
+		case <-a.parentCtx.Done():
+			return false
+		case <-keepAlive.C:
+			if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+				return false
+			}
+			if err := rc.Flush(); err != nil {
+				return false
+			}
+		case event, ok := <-events:
+			if !ok {
+				return true
+			}
+			final, err := writeVoteStatusEvent(w, rc, event)
+			if err != nil {
+				log.Debugw("failed to write vote status event", "error", err)
+				return false
+			}
+			// Streams of a single vote end with its final status
+			if final && single {
+				return false
+			}
+		}
+	}
+}
+
+// writeVoteStatusEvent writes a vote status event and flushes it. It returns
+// whether the status of the event is final, so no more transitions of the
+// vote will follow.
+func writeVoteStatusEvent(w http.ResponseWriter, rc *http.ResponseController, event *storage.VoteIDStatusEvent) (bool, error) {
+	status := event.Status
+	final := false
+	switch status {
+	case storage.VoteIDStatusDone:
+		// DONE is an internal status and should not be exposed, return
+		// PROCESSED instead, as the vote status endpoint does. The vote
+		// becomes settled once it is included in the process state.
+		status = storage.VoteIDStatusProcessed
+	case storage.VoteIDStatusSettled, storage.VoteIDStatusError, storage.VoteIDStatusTimeout:
+		final = true
+	}
+	data, err := json.Marshal(&VoteStatusEvent{
+		ProcessID: event.ProcessID,
+		VoteID:    event.VoteID,
+		Status:    storage.VoteIDStatusName(status),
+		Timestamp: event.Time.Unix(),
+	})
+	if err != nil {
+		return final, err
+	}
+	if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", voteStatusEventName, data); err != nil {
+		return final, err
+	}
+	return final, rc.Flush()
+}
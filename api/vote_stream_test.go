@@ -0,0 +1,108 @@
+package api
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/go-chi/chi/v5"
+	"github.com/vocdoni/davinci-node/db/metadb"
+	"github.com/vocdoni/davinci-node/internal/testutil"
+	"github.com/vocdoni/davinci-node/storage"
+	"github.com/vocdoni/davinci-node/types"
+)
+
+// readVoteStatusEvent reads the next vote status event of a stream, skipping
+// the keep alive comments.
+func readVoteStatusEvent(c *qt.C, reader *bufio.Reader) *VoteStatusEvent {
+	for {
+		line, err := reader.ReadString('\n')
+		c.Assert(err, qt.IsNil)
+		data, ok := strings.CutPrefix(strings.TrimSpace(line), "data: ")
+		if !ok {
+			continue
+		}
+		event := &VoteStatusEvent{}
+		c.Assert(json.Unmarshal([]byte(data), event), qt.IsNil)
+		return event
+	}
+}
+
+func TestVoteStatusStream(t *testing.T) {
+	c := qt.New(t)
+
+	store := storage.New(metadb.NewTest(t))
+	defer store.Close()
+	pid := testutil.DeterministicProcessID(1)
+	c.Assert(store.NewProcess(testutil.RandomProcess(pid)), qt.IsNil)
+	voteID := testutil.RandomVoteID()
+
+	api := &API{
+		storage:      store,
+		eventStreams: make(chan struct{}, 4),
+		parentCtx:    context.Background(),
+	}
+	router := chi.NewRouter()
+	router.Get(VoteStatusStreamEndpoint, api.voteStatusStream)
+	router.Get(ProcessVoteStatusStreamEndpoint, api.processVoteStatusStream)
+	srv := httptest.NewServer(router)
+	defer srv.Close()
+
+	// Stream the vote transitions of the process
+	processURL := srv.URL + EndpointWithParam(ProcessVoteStatusStreamEndpoint, ProcessURLParam, pid.String())
+	resp, err := http.Get(processURL)
+	c.Assert(err, qt.IsNil)
+	defer func() { _ = resp.Body.Close() }()
+	c.Assert(resp.StatusCode, qt.Equals, http.StatusOK)
+	c.Assert(resp.Header.Get("Content-Type"), qt.Equals, "text/event-stream")
+
+	// Done votes are streamed with the same status as the vote status
+	// endpoint: processed until they are included in the process state
+	c.Assert(store.MarkVoteIDsDone(pid, []types.VoteID{voteID}), qt.IsNil)
+	event := readVoteStatusEvent(c, bufio.NewReader(resp.Body))
+	c.Assert(event.ProcessID, qt.Equals, pid)
+	c.Assert(event.VoteID, qt.Equals, voteID)
+	c.Assert(event.Status, qt.Equals, "processed")
+	status, err := store.VoteIDStatus(pid, voteID)
+	c.Assert(err, qt.IsNil)
+	c.Assert(status, qt.Equals, storage.VoteIDStatusDone)
+
+	// The single vote stream sends the current status first
+	voteStream := func(voteID types.VoteID) *http.Response {
+		voteURL := EndpointWithParam(VoteStatusStreamEndpoint, ProcessURLParam, pid.String())
+		voteURL = EndpointWithParam(voteURL, VoteIDURLParam, voteID.String())
+		resp, err := http.Get(srv.URL + voteURL)
+		c.Assert(err, qt.IsNil)
+		return resp
+	}
+	voteResp := voteStream(voteID)
+	event = readVoteStatusEvent(c, bufio.NewReader(voteResp.Body))
+	c.Assert(event.VoteID, qt.Equals, voteID)
+	c.Assert(event.Status, qt.Equals, "processed")
+	c.Assert(voteResp.Body.Close(), qt.IsNil)
+
+	// The stream of a vote in a final status only sends its current status
+	failedVoteID := testutil.RandomVoteID()
+	c.Assert(store.RemovePendingBallot(pid, failedVoteID), qt.IsNil)
+	failedResp := voteStream(failedVoteID)
+	defer func() { _ = failedResp.Body.Close() }()
+	reader := bufio.NewReader(failedResp.Body)
+	event = readVoteStatusEvent(c, reader)
+	c.Assert(event.VoteID, qt.Equals, failedVoteID)
+	c.Assert(event.Status, qt.Equals, "error")
+	_, err = reader.ReadString('\n')
+	c.Assert(err, qt.IsNotNil)
+
+	// Unknown votes are not found
+	unknownURL := EndpointWithParam(VoteStatusStreamEndpoint, ProcessURLParam, pid.String())
+	unknownURL = EndpointWithParam(unknownURL, VoteIDURLParam, testutil.RandomVoteID().String())
+	unknownResp, err := http.Get(srv.URL + unknownURL)
+	c.Assert(err, qt.IsNil)
+	defer func() { _ = unknownResp.Body.Close() }()
+	c.Assert(unknownResp.StatusCode, qt.Equals, http.StatusNotFound)
+}
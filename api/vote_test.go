@@ -1,12 +1,24 @@
 package api
 
 import (
+	"context"
+	"encoding/json"
+	"math/big"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
 
+	"github.com/ethereum/go-ethereum/common"
 	qt "github.com/frankban/quicktest"
+	"github.com/go-chi/chi/v5"
+	"github.com/vocdoni/davinci-node/crypto/elgamal"
+	"github.com/vocdoni/davinci-node/db/metadb"
+	"github.com/vocdoni/davinci-node/internal/testutil"
+	"github.com/vocdoni/davinci-node/state"
+	statetest "github.com/vocdoni/davinci-node/state/testutil"
+	"github.com/vocdoni/davinci-node/storage"
+	"github.com/vocdoni/davinci-node/types"
 )
 
 func TestNewVoteInvalidCurveType(t *testing.T) {
@@ -55,3 +67,131 @@ func TestNewVoteBodyTooLarge(t *testing.T) {
 	c.Assert(rr.Code, qt.Equals, http.StatusRequestEntityTooLarge)
 	c.Assert(rr.Body.String(), qt.Contains, "request body too large")
 }
+
+func TestVoteIDStatusObserver(t *testing.T) {
+	c := qt.New(t)
+
+	store := storage.New(metadb.NewTest(t))
+	defer store.Close()
+	pid := testutil.DeterministicProcessID(1)
+	c.Assert(store.NewProcess(testutil.RandomProcess(pid)), qt.IsNil)
+
+	publicKey, _, err := elgamal.GenerateKey(state.Curve)
+	c.Assert(err, qt.IsNil)
+	st, err := state.New(store.StateDB(), pid)
+	c.Assert(err, qt.IsNil)
+	c.Assert(st.Initialize(
+		types.CensusOriginMerkleTreeOffchainStaticV1.BigInt().MathBigInt(),
+		testutil.BallotModePacked(),
+		types.EncryptionKeyFromPoint(publicKey),
+	), qt.IsNil)
+
+	// a vote sequenced by another node has no status
+	vote := statetest.NewVoteForTest(publicKey, 1, 2)
+	batch, err := st.PrepareVotesBatch([]*state.Vote{vote})
+	c.Assert(err, qt.IsNil)
+	root, err := batch.RootAsBigInt()
+	c.Assert(err, qt.IsNil)
+	sidecar := batch.BlobEvalData().TxSidecar()
+	c.Assert(batch.Commit(), qt.IsNil)
+
+	observer := &API{storage: store, readOnly: true}
+	// the vote is not settled until the state sync indexes its receipt
+	_, err = observer.voteIDStatus(pid, vote.VoteID)
+	c.Assert(err, qt.ErrorIs, storage.ErrNotFound)
+
+	c.Assert(store.SetVoteReceipts(pid, root, types.HexBytes{0x01}, sidecar), qt.IsNil)
+	status, err := observer.voteIDStatus(pid, vote.VoteID)
+	c.Assert(err, qt.IsNil)
+	c.Assert(status, qt.Equals, storage.VoteIDStatusSettled)
+
+	_, err = observer.voteIDStatus(pid, testutil.RandomVoteID())
+	c.Assert(err, qt.ErrorIs, storage.ErrNotFound)
+
+	// a sequencer only reports the status of the votes it sequenced
+	sequencer := &API{storage: store}
+	_, err = sequencer.voteIDStatus(pid, vote.VoteID)
+	c.Assert(err, qt.ErrorIs, storage.ErrNotFound)
+}
+
+func TestVoteReceipt(t *testing.T) {
+	c := qt.New(t)
+
+	store := storage.New(metadb.NewTest(t))
+	defer store.Close()
+	pid := testutil.DeterministicProcessID(1)
+	c.Assert(store.NewProcess(testutil.RandomProcess(pid)), qt.IsNil)
+
+	publicKey, _, err := elgamal.GenerateKey(state.Curve)
+	c.Assert(err, qt.IsNil)
+	st, err := state.New(store.StateDB(), pid)
+	c.Assert(err, qt.IsNil)
+	c.Assert(st.Initialize(
+		types.CensusOriginMerkleTreeOffchainStaticV1.BigInt().MathBigInt(),
+		testutil.BallotModePacked(),
+		types.EncryptionKeyFromPoint(publicKey),
+	), qt.IsNil)
+
+	// settles the votes provided on chain, as the sequencer does
+	settle := func(txHash types.HexBytes, votes ...*state.Vote) *big.Int {
+		batch, err := st.PrepareVotesBatch(votes)
+		c.Assert(err, qt.IsNil)
+		root, err := batch.RootAsBigInt()
+		c.Assert(err, qt.IsNil)
+		sidecar := batch.BlobEvalData().TxSidecar()
+		c.Assert(batch.Commit(), qt.IsNil)
+		c.Assert(store.SetVoteReceipts(pid, root, txHash, sidecar), qt.IsNil)
+		c.Assert(store.UpdateProcess(pid, storage.ProcessUpdateCallbackSetStateRoot(
+			(*types.BigInt)(root), types.NewInt(len(votes)), types.NewInt(0),
+		)), qt.IsNil)
+		return root
+	}
+	api := &API{storage: store}
+	receipt := func(voteID types.VoteID) *httptest.ResponseRecorder {
+		endpoint := EndpointWithParam(VoteReceiptEndpoint, ProcessURLParam, pid.String())
+		endpoint = EndpointWithParam(endpoint, VoteIDURLParam, voteID.String())
+		req := httptest.NewRequest(http.MethodGet, endpoint, nil)
+		routeCtx := chi.NewRouteContext()
+		routeCtx.URLParams.Add(ProcessURLParam, pid.String())
+		routeCtx.URLParams.Add(VoteIDURLParam, voteID.String())
+		req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, routeCtx))
+		rr := httptest.NewRecorder()
+		api.voteReceipt(rr, req)
+		return rr
+	}
+
+	vote := statetest.NewVoteForTest(publicKey, 1, 2)
+	c.Assert(receipt(vote.VoteID).Code, qt.Equals, http.StatusNotFound)
+	root := settle(types.HexBytes{0x01}, vote)
+
+	rr := receipt(vote.VoteID)
+	c.Assert(rr.Code, qt.Equals, http.StatusOK)
+	resp := &VoteReceiptResponse{}
+	c.Assert(json.Unmarshal(rr.Body.Bytes(), resp), qt.IsNil)
+	c.Assert(resp.VoteID, qt.Equals, vote.VoteID)
+	c.Assert(resp.BallotIndex, qt.Equals, vote.BallotIndex)
+	c.Assert(resp.Address, qt.Equals, common.BigToAddress(vote.Address))
+	c.Assert(resp.TxHash, qt.DeepEquals, types.HexBytes{0x01})
+	c.Assert(resp.BlobIndex, qt.Equals, 0)
+	c.Assert(resp.StateRoot.MathBigInt().Cmp(root), qt.Equals, 0)
+	c.Assert(resp.SettledStateRoot.MathBigInt().Cmp(root), qt.Equals, 0)
+	c.Assert(resp.BallotProof.Root.MathBigInt().Cmp(root), qt.Equals, 0)
+	c.Assert(resp.BallotProof.Key.MathBigInt().Cmp(vote.BallotIndex.BigInt()), qt.Equals, 0)
+	c.Assert(resp.VoteIDProof.Root.MathBigInt().Cmp(root), qt.Equals, 0)
+	c.Assert(resp.VoteIDProof.Key.MathBigInt().Cmp(vote.VoteID.BigInt()), qt.Equals, 0)
+	c.Assert(resp.Ballot.String(), qt.Equals, vote.ReencryptedBallot.String())
+	c.Assert(resp.Overwritten, qt.IsFalse)
+
+	// the receipt of an overwritten vote reports the vote that overwrote it
+	overwrite := statetest.NewVoteForTest(publicKey, 1, 3)
+	root = settle(types.HexBytes{0x02}, overwrite)
+	rr = receipt(vote.VoteID)
+	c.Assert(rr.Code, qt.Equals, http.StatusOK)
+	resp = &VoteReceiptResponse{}
+	c.Assert(json.Unmarshal(rr.Body.Bytes(), resp), qt.IsNil)
+	c.Assert(resp.TxHash, qt.DeepEquals, types.HexBytes{0x01})
+	c.Assert(resp.StateRoot.MathBigInt().Cmp(root), qt.Equals, 0)
+	c.Assert(resp.Overwritten, qt.IsTrue)
+	c.Assert(*resp.OverwrittenBy, qt.Equals, overwrite.VoteID)
+	c.Assert(resp.Ballot.String(), qt.Equals, overwrite.ReencryptedBallot.String())
+}
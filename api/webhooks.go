@@ -0,0 +1,106 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/vocdoni/davinci-node/log"
+	"github.com/vocdoni/davinci-node/webhooks"
+)
+
+const (
+	defaultWebhookDeliveriesLimit = 50  // Deliveries returned by default by the deliveries endpoint
+	maxWebhookDeliveriesLimit     = 500 // Maximum deliveries returned by the deliveries endpoint
+)
+
+// webhooksAuth wraps a webhook handler to require the webhooks bearer token
+// in the Authorization header.
+func (a *API) webhooksAuth(next http.HandlerFunc) http.HandlerFunc {
+	return bearerAuth(a.webhooksToken, "webhooks", next)
+}
+
+// newWebhook creates a webhook subscription. The response includes the
+// secret used to sign the payloads, which is not returned anymore.
+// POST /webhooks
+func (a *API) newWebhook(w http.ResponseWriter, r *http.Request) {
+	req := &WebhookRequest{}
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		ErrMalformedBody.Withf("could not decode request body: %v", err).Write(w)
+		return
+	}
+	sub, err := a.webhooks.AddSubscription(&webhooks.Subscription{
+		URL:            req.URL,
+		Secret:         req.Secret,
+		OrganizationID: req.OrganizationID,
+		Events:         req.Events,
+	})
+	if err != nil {
+		if errors.Is(err, webhooks.ErrInvalidSubscription) {
+			ErrInvalidWebhook.WithErr(err).Write(w)
+			return
+		}
+		ErrGenericInternalServerError.Withf("could not create webhook subscription: %v", err).Write(w)
+		return
+	}
+	log.Infow("webhook subscription created", "id", sub.ID, "url", sub.URL)
+	httpWriteJSON(w, sub)
+}
+
+// webhookList returns every webhook subscription, without their secrets.
+// GET /webhooks
+func (a *API) webhookList(w http.ResponseWriter, r *http.Request) {
+	subs, err := a.webhooks.Subscriptions()
+	if err != nil {
+		ErrGenericInternalServerError.Withf("could not list webhook subscriptions: %v", err).Write(w)
+		return
+	}
+	for _, sub := range subs {
+		sub.Secret = ""
+	}
+	httpWriteJSON(w, &WebhookList{Webhooks: subs})
+}
+
+// deleteWebhook deletes a webhook subscription with its pending deliveries
+// and its delivery log.
+// DELETE /webhooks/{webhookId}
+func (a *API) deleteWebhook(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, WebhookURLParam)
+	if err := a.webhooks.DeleteSubscription(id); err != nil {
+		writeWebhookError(w, err)
+		return
+	}
+	log.Infow("webhook subscription deleted", "id", id)
+	httpWriteOK(w)
+}
+
+// webhookDeliveries returns the last deliveries of a webhook subscription.
+// GET /webhooks/{webhookId}/deliveries
+func (a *API) webhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	limit := defaultWebhookDeliveriesLimit
+	if strLimit := r.URL.Query().Get(WebhookDeliveriesLimitQueryParam); strLimit != "" {
+		var err error
+		limit, err = strconv.Atoi(strLimit)
+		if err != nil || limit < 1 || limit > maxWebhookDeliveriesLimit {
+			ErrMalformedParam.Withf("limit must be between 1 and %d", maxWebhookDeliveriesLimit).Write(w)
+			return
+		}
+	}
+	deliveries, err := a.webhooks.Deliveries(chi.URLParam(r, WebhookURLParam), limit)
+	if err != nil {
+		writeWebhookError(w, err)
+		return
+	}
+	httpWriteJSON(w, &WebhookDeliveryList{Deliveries: deliveries})
+}
+
+// writeWebhookError writes the API error matching a webhooks manager error.
+func writeWebhookError(w http.ResponseWriter, err error) {
+	if errors.Is(err, webhooks.ErrSubscriptionNotFound) {
+		ErrWebhookNotFound.Write(w)
+		return
+	}
+	ErrGenericInternalServerError.WithErr(err).Write(w)
+}
@@ -0,0 +1,87 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/go-chi/chi/v5"
+	"github.com/vocdoni/davinci-node/db/metadb"
+	"github.com/vocdoni/davinci-node/webhooks"
+)
+
+func TestWebhookEndpoints(t *testing.T) {
+	c := qt.New(t)
+
+	api := &API{
+		webhooks:      webhooks.New(metadb.NewTest(t), webhooks.DefaultConfig),
+		webhooksToken: "token",
+	}
+	router := chi.NewRouter()
+	router.Get(WebhooksEndpoint, api.webhooksAuth(api.webhookList))
+	router.Post(WebhooksEndpoint, api.webhooksAuth(api.newWebhook))
+	router.Delete(WebhookEndpoint, api.webhooksAuth(api.deleteWebhook))
+	router.Get(WebhookDeliveriesEndpoint, api.webhooksAuth(api.webhookDeliveries))
+
+	do := func(method, path, token string, body any) *httptest.ResponseRecorder {
+		var reqBody bytes.Buffer
+		if body != nil {
+			c.Assert(json.NewEncoder(&reqBody).Encode(body), qt.IsNil)
+		}
+		req := httptest.NewRequest(method, path, &reqBody)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// The token is required
+	rec := do(http.MethodGet, WebhooksEndpoint, "", nil)
+	c.Assert(rec.Code, qt.Equals, ErrUnauthorized.HTTPstatus)
+	rec = do(http.MethodGet, WebhooksEndpoint, "wrong", nil)
+	c.Assert(rec.Code, qt.Equals, ErrUnauthorized.HTTPstatus)
+
+	// Invalid subscription
+	rec = do(http.MethodPost, WebhooksEndpoint, "token", &WebhookRequest{URL: "not-a-url"})
+	c.Assert(rec.Code, qt.Equals, ErrInvalidWebhook.HTTPstatus)
+
+	// Create a subscription, the secret is only returned on creation
+	rec = do(http.MethodPost, WebhooksEndpoint, "token", &WebhookRequest{URL: "https://example.com/hook"})
+	c.Assert(rec.Code, qt.Equals, http.StatusOK)
+	sub := &webhooks.Subscription{}
+	c.Assert(json.Unmarshal(rec.Body.Bytes(), sub), qt.IsNil)
+	c.Assert(sub.ID, qt.Not(qt.Equals), "")
+	c.Assert(sub.Secret, qt.Not(qt.Equals), "")
+
+	rec = do(http.MethodGet, WebhooksEndpoint, "token", nil)
+	c.Assert(rec.Code, qt.Equals, http.StatusOK)
+	list := &WebhookList{}
+	c.Assert(json.Unmarshal(rec.Body.Bytes(), list), qt.IsNil)
+	c.Assert(list.Webhooks, qt.HasLen, 1)
+	c.Assert(list.Webhooks[0].ID, qt.Equals, sub.ID)
+	c.Assert(list.Webhooks[0].Secret, qt.Equals, "")
+
+	// Deliveries of the subscription
+	deliveriesURL := EndpointWithParam(WebhookDeliveriesEndpoint, WebhookURLParam, sub.ID)
+	rec = do(http.MethodGet, deliveriesURL, "token", nil)
+	c.Assert(rec.Code, qt.Equals, http.StatusOK)
+	deliveries := &WebhookDeliveryList{}
+	c.Assert(json.Unmarshal(rec.Body.Bytes(), deliveries), qt.IsNil)
+	c.Assert(deliveries.Deliveries, qt.HasLen, 0)
+	rec = do(http.MethodGet, deliveriesURL+"?"+WebhookDeliveriesLimitQueryParam+"=0", "token", nil)
+	c.Assert(rec.Code, qt.Equals, ErrMalformedParam.HTTPstatus)
+
+	// Delete the subscription
+	subURL := EndpointWithParam(WebhookEndpoint, WebhookURLParam, sub.ID)
+	rec = do(http.MethodDelete, subURL, "token", nil)
+	c.Assert(rec.Code, qt.Equals, http.StatusOK)
+	rec = do(http.MethodDelete, subURL, "token", nil)
+	c.Assert(rec.Code, qt.Equals, ErrWebhookNotFound.HTTPstatus)
+	rec = do(http.MethodGet, deliveriesURL, "token", nil)
+	c.Assert(rec.Code, qt.Equals, ErrWebhookNotFound.HTTPstatus)
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"slices"
 	"time"
 
 	"github.com/consensys/gnark/std/algebra/emulated/sw_bn254"
@@ -59,6 +60,8 @@ func (a *API) startWorkersAPI(conf APIConfig) error {
 		a.router.Get(WorkerJobEndpoint, a.workersNewJob)
 		log.Infow("register handler", "endpoint", WorkerJobEndpoint, "method", "POST")
 		a.router.Post(WorkerJobEndpoint, a.workersSubmitJob)
+		log.Infow("register handler", "endpoint", WorkerProofJobEndpoint, "method", "POST")
+		a.router.Post(WorkerProofJobEndpoint, a.workersSubmitProofJob)
 
 		log.Debugw("worker API enabled",
 			"sequencerUUID", a.sequencerUUID.String(),
@@ -68,10 +71,20 @@ func (a *API) startWorkersAPI(conf APIConfig) error {
 	return nil
 }
 
+// JobsManager returns the manager of the worker jobs, used by the sequencer
+// to offload the aggregation and state transition proofs to the workers. It
+// returns nil if the workers API is not enabled.
+func (a *API) JobsManager() *workers.JobsManager {
+	return a.jobsManager
+}
+
 // startWorkersMonitor starts the timeout monitor for worker jobs
 func (a *API) startWorkersMonitor() {
 	// Start the jobs manager with the worker job timeout and the ban rules
 	a.jobsManager = workers.NewJobsManager(a.storage, a.workersJobTimeout, a.workersBanRules)
+	if a.workersProofJobTimeout > 0 {
+		a.jobsManager.ProofJobTimeout = a.workersProofJobTimeout
+	}
 	a.jobsManager.Start(a.parentCtx)
 
 	go func() {
@@ -235,6 +248,15 @@ func (a *API) workersNewJob(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Record the job types advertised by the worker, the workers that do not
+	// advertise them only take ballot jobs
+	jobTypes, err := workers.ParseJobTypes(r.URL.Query().Get(WorkerJobsQueryParam))
+	if err != nil {
+		ErrMalformedWorkerInfo.WithErr(err).Write(w)
+		return
+	}
+	a.jobsManager.SetWorkerJobTypes(workerAddr.Hex(), jobTypes)
+
 	// Check if worker is available
 	if available, err := a.jobsManager.IsWorkerAvailable(workerAddr.Hex()); !available {
 		log.Warnw("worker not available", "worker", workerAddr.Hex())
@@ -246,6 +268,38 @@ func (a *API) workersNewJob(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Offer the proof jobs first, the sequencer is waiting for them
+	proofJob, err := a.jobsManager.NextProofJob(workerAddr.Hex(), jobTypes)
+	if err != nil {
+		log.Warnw("failed to get next proof job for worker",
+			"error", err.Error(),
+			"worker", workerAddr.Hex())
+		ErrGenericInternalServerError.WithErr(err).Write(w)
+		return
+	}
+	if proofJob != nil {
+		data, err := storage.EncodeArtifact(&WorkerProofJob{
+			ID:        proofJob.ID,
+			Type:      string(proofJob.Type),
+			ProcessID: proofJob.ProcessID,
+			Witness:   proofJob.Witness,
+		})
+		if err != nil {
+			log.Warnw("failed to encode proof job for worker",
+				"error", err.Error(),
+				"jobID", proofJob.ID.String())
+			ErrGenericInternalServerError.WithErr(err).Write(w)
+			return
+		}
+		w.Header().Set(WorkerJobTypeHeader, string(proofJob.Type))
+		httpWriteBinary(w, data)
+		return
+	}
+	if !slices.Contains(jobTypes, workers.JobTypeBallot) {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
 	// Get next ballot
 	ballot, voteID, err := a.storage.NextPendingBallot()
 	if err != nil {
@@ -413,3 +467,89 @@ func (a *API) workersSubmitJob(w http.ResponseWriter, r *http.Request) {
 
 	httpWriteJSON(w, response)
 }
+
+// workersSubmitProofJob handles POST /workers/{uuid}/proof
+func (a *API) workersSubmitProofJob(w http.ResponseWriter, r *http.Request) {
+	// Check worker signature
+	workerAddr, apiErr := a.authWorkerFromRequest(r)
+	if apiErr != nil {
+		log.Warnw("failed to verify worker signature", "error", apiErr.Error())
+		apiErr.Write(w)
+		return
+	}
+
+	// Decode the proof job result
+	r.Body = http.MaxBytesReader(w, r.Body, 1<<20) // 1 MiB limit for worker submissions
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			ErrRequestBodyTooLarge.Write(w)
+			return
+		}
+		ErrMalformedBody.WithErr(err).Write(w)
+		return
+	}
+	var result WorkerProofJobResult
+	if err := storage.DecodeArtifact(body, &result); err != nil {
+		log.Warnw("failed to decode proof job result",
+			"error", err.Error())
+		ErrMalformedBody.WithErr(err).Write(w)
+		return
+	}
+	if len(result.ID) == 0 || (result.Error == "" && len(result.Proof) == 0) {
+		ErrMalformedBody.Withf("missing required fields").Write(w)
+		return
+	}
+
+	// Fail the job if the worker could not generate the proof, otherwise
+	// verify the proof and hand it to the sequencer
+	var job *workers.WorkerJob
+	if result.Error != "" {
+		job, err = a.jobsManager.FailProofJob(workerAddr.Hex(), result.ID, errors.New(result.Error))
+	} else {
+		job, err = a.jobsManager.CompleteProofJob(workerAddr.Hex(), result.ID, result.Proof)
+	}
+	if err != nil {
+		log.Warnw("failed to complete proof job",
+			"error", err.Error(),
+			"jobID", result.ID.String(),
+			"worker", workerAddr.Hex())
+		switch {
+		case errors.Is(err, workers.ErrInvalidProof):
+			ErrInvalidWorkerProof.WithErr(err).Write(w)
+		case errors.Is(err, workers.ErrJobNotFound), errors.Is(err, workers.ErrWorkerJobMismatch):
+			ErrResourceNotFound.Withf("job not found or expired").Write(w)
+		default:
+			ErrGenericInternalServerError.WithErr(err).Write(w)
+		}
+		return
+	}
+
+	stats, err := a.jobsManager.WorkerManager.WorkerStats(job.Address)
+	if err != nil {
+		log.Warnw("failed to get worker job count",
+			"error", err.Error(),
+			"worker", job.Address)
+		ErrGenericInternalServerError.WithErr(err).Write(w)
+		return
+	}
+
+	log.Debugw("worker proof job completed",
+		"jobID", result.ID.String(),
+		"type", job.Type,
+		"success", result.Error == "",
+		"workerAddr", job.Address,
+		"workerName", stats.Name,
+		"duration", time.Since(job.Timestamp).String(),
+		"successCount", stats.SuccessCount,
+		"failedCount", stats.FailedCount,
+	)
+
+	httpWriteJSON(w, WorkerProofJobResponse{
+		ID:           result.ID,
+		Type:         string(job.Type),
+		SuccessCount: stats.SuccessCount,
+		FailedCount:  stats.FailedCount,
+	})
+}
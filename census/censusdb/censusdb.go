@@ -344,6 +344,11 @@ func (c *CensusDB) Del(censusID uuid.UUID) error {
 	return c.delCensus(censusID, key)
 }
 
+// DelByRoot removes a root-based census from the database and memory.
+func (c *CensusDB) DelByRoot(root types.HexBytes) error {
+	return c.delCensus(rootToCensusID(root), rootDBPrefix(root))
+}
+
 // DelByScopedAddress removes a chain-scoped address census from the database
 // and memory.
 func (c *CensusDB) DelByScopedAddress(chainID uint64, address common.Address) error {
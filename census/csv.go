@@ -0,0 +1,196 @@
+package census
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/vocdoni/davinci-node/census/censusdb"
+	"github.com/vocdoni/davinci-node/log"
+	"github.com/vocdoni/davinci-node/types"
+)
+
+// CSVImporterConfig holds configuration options for the CSV importer. The
+// URI of each census can override the columns and the delimiter in its
+// fragment, e.g. https://example.com/census.csv#address=wallet&delimiter=;
+type CSVImporterConfig struct {
+	// AddressColumn is the header of the column of the voter addresses.
+	AddressColumn string
+	// WeightColumn is the header of the column of the voter weights.
+	WeightColumn string
+	// Delimiter is the field delimiter. If it is not set, it is a tab for
+	// the .tsv files and a comma for the rest.
+	Delimiter rune
+	// FileDir is the directory from which the file:// URIs can be imported.
+	// If it is empty, the file URIs are not supported.
+	FileDir string
+}
+
+// CSVImporter returns an instance of csvImporter with the provided
+// configuration. If config is nil, it uses the default configuration.
+func CSVImporter(config *CSVImporterConfig) *csvImporter {
+	if config == nil {
+		config = &CSVImporterConfig{}
+	}
+	importer := &csvImporter{
+		addressColumn: config.AddressColumn,
+		weightColumn:  config.WeightColumn,
+		delimiter:     config.Delimiter,
+		fileDir:       config.FileDir,
+	}
+	if importer.addressColumn == "" {
+		importer.addressColumn = DefaultAddressColumn
+	}
+	if importer.weightColumn == "" {
+		importer.weightColumn = DefaultWeightColumn
+	}
+	return importer
+}
+
+// csvImporter is an implementation of the ImporterPlugin interface for
+// importing censuses from CSV files, with a header and a row per voter.
+type csvImporter struct {
+	addressColumn string
+	weightColumn  string
+	delimiter     rune
+	fileDir       string
+}
+
+// ValidURI checks if the provided targetURI is an HTTP, HTTPS or file URL of
+// a .csv or .tsv file.
+func (d *csvImporter) ValidURI(targetURI string) bool {
+	return validTabularURI(targetURI, d.fileDir != "", ".csv", ".tsv")
+}
+
+// ImportCensus streams the rows of the CSV file of the census URI into the
+// census DB, checking that they lead to the census root. The from argument
+// is the number of rows imported by a previous attempt, which are skipped.
+// It returns the number of rows imported, also when it fails, so the import
+// can be resumed.
+func (d *csvImporter) ImportCensus(
+	ctx context.Context,
+	censusDB *censusdb.CensusDB,
+	chainID uint64,
+	census *types.Census,
+	from int,
+) (int, error) {
+	ext, options, err := parseTabularURI(census.CensusURI)
+	if err != nil {
+		return 0, fmt.Errorf("invalid CSV census URI %s: %w", census.CensusURI, err)
+	}
+	delimiter, err := d.csvDelimiter(ext, options.delimiter)
+	if err != nil {
+		return 0, fmt.Errorf("invalid CSV census URI %s: %w", census.CensusURI, err)
+	}
+	addressColumn, weightColumn := d.addressColumn, d.weightColumn
+	if options.addressColumn != "" {
+		addressColumn = options.addressColumn
+	}
+	if options.weightColumn != "" {
+		weightColumn = options.weightColumn
+	}
+	file, err := openTabularFile(ctx, census.CensusURI, d.fileDir)
+	if err != nil {
+		return from, err
+	}
+	defer func() {
+		if err := file.Close(); err != nil {
+			log.Warnw("failed to close CSV census file",
+				"root", census.CensusRoot.String(),
+				"uri", census.CensusURI,
+				"error", err.Error())
+		}
+	}()
+	rows, err := newCSVRowReader(file, delimiter, addressColumn, weightColumn)
+	if err != nil {
+		return 0, fmt.Errorf("invalid CSV census file %s: %w", census.CensusURI, err)
+	}
+	return importCensusRows(ctx, censusDB, chainID, census, from, rows)
+}
+
+// csvDelimiter returns the delimiter of a CSV file, set in its URI, in the
+// importer configuration or, by default, by its extension. The delimiter of
+// the URI can be a single character or "tab".
+func (d *csvImporter) csvDelimiter(ext, option string) (rune, error) {
+	switch {
+	case option == "tab":
+		return '\t', nil
+	case option != "":
+		delimiter, size := utf8.DecodeRuneInString(option)
+		if delimiter == utf8.RuneError || size != len(option) {
+			return 0, fmt.Errorf("invalid delimiter %q", option)
+		}
+		return delimiter, nil
+	case d.delimiter != 0:
+		return d.delimiter, nil
+	case ext == ".tsv":
+		return '\t', nil
+	default:
+		return ',', nil
+	}
+}
+
+// csvRowReader reads the address and weight columns of a CSV file.
+type csvRowReader struct {
+	r       *csv.Reader
+	address int
+	weight  int
+}
+
+// newCSVRowReader reads the header of the CSV file provided, and returns a
+// reader of the columns with the headers provided, compared
+// case-insensitively.
+func newCSVRowReader(r io.Reader, delimiter rune, addressColumn, weightColumn string) (*csvRowReader, error) {
+	reader := csv.NewReader(r)
+	reader.Comma = delimiter
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	reader.ReuseRecord = true
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, fmt.Errorf("empty file")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not read header: %w", err)
+	}
+	if len(header) > 0 {
+		// spreadsheets often start the files with an UTF-8 byte order mark
+		header[0] = strings.TrimPrefix(header[0], "\ufeff")
+	}
+	rows := &csvRowReader{r: reader, address: -1, weight: -1}
+	for i, name := range header {
+		switch {
+		case rows.address < 0 && equalColumnName(name, addressColumn):
+			rows.address = i
+		case rows.weight < 0 && equalColumnName(name, weightColumn):
+			rows.weight = i
+		}
+	}
+	if rows.address < 0 {
+		return nil, fmt.Errorf("missing %q column, found columns: %s", addressColumn, strings.Join(header, ", "))
+	}
+	if rows.weight < 0 {
+		return nil, fmt.Errorf("missing %q column, found columns: %s", weightColumn, strings.Join(header, ", "))
+	}
+	return rows, nil
+}
+
+// Next returns the address and the weight of the next row. It returns io.EOF
+// after the last row.
+func (cr *csvRowReader) Next() (string, string, error) {
+	record, err := cr.r.Read()
+	if err != nil {
+		return "", "", err
+	}
+	var address, weight string
+	if cr.address < len(record) {
+		address = record[cr.address]
+	}
+	if cr.weight < len(record) {
+		weight = record[cr.weight]
+	}
+	return address, weight, nil
+}
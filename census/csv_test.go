@@ -0,0 +1,200 @@
+package census
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/vocdoni/davinci-node/types"
+)
+
+// testMakeCSV returns a CSV file of the rows provided, with the header and
+// the delimiter provided.
+func testMakeCSV(header string, delimiter string, rows []testTabularRow) string {
+	var b strings.Builder
+	b.WriteString(header + "\n")
+	for i, row := range rows {
+		// an extra column to check that it is ignored
+		b.WriteString(strings.Join([]string{row.Address, "voter" + string(rune('a'+i%26)), row.Weight}, delimiter) + "\n")
+	}
+	return b.String()
+}
+
+func TestCSVImporterValidURI(t *testing.T) {
+	c := qt.New(t)
+
+	importer := CSVImporter(nil)
+	c.Assert(importer.ValidURI("https://example.com/census.csv"), qt.IsTrue)
+	c.Assert(importer.ValidURI("http://example.com/census.TSV#delimiter=tab"), qt.IsTrue)
+	c.Assert(importer.ValidURI("https://example.com/census.json"), qt.IsFalse)
+	c.Assert(importer.ValidURI("https://example.com/census.parquet"), qt.IsFalse)
+	c.Assert(importer.ValidURI("file:census.csv"), qt.IsFalse)
+	c.Assert(importer.ValidURI("graphql://example.com/census.csv"), qt.IsFalse)
+
+	importer = CSVImporter(&CSVImporterConfig{FileDir: c.TempDir()})
+	c.Assert(importer.ValidURI("file:census.csv"), qt.IsTrue)
+}
+
+func TestCSVDelimiter(t *testing.T) {
+	c := qt.New(t)
+
+	importer := CSVImporter(nil)
+	for _, tc := range []struct {
+		ext       string
+		option    string
+		delimiter rune
+	}{
+		{".csv", "", ','},
+		{".tsv", "", '\t'},
+		{".csv", "tab", '\t'},
+		{".tsv", ";", ';'},
+	} {
+		delimiter, err := importer.csvDelimiter(tc.ext, tc.option)
+		c.Assert(err, qt.IsNil)
+		c.Assert(delimiter, qt.Equals, tc.delimiter)
+	}
+	_, err := importer.csvDelimiter(".csv", ";;")
+	c.Assert(err, qt.ErrorMatches, "invalid delimiter.*")
+
+	delimiter, err := CSVImporter(&CSVImporterConfig{Delimiter: '|'}).csvDelimiter(".tsv", "")
+	c.Assert(err, qt.IsNil)
+	c.Assert(delimiter, qt.Equals, '|')
+}
+
+func TestCSVRowReader(t *testing.T) {
+	c := qt.New(t)
+
+	c.Run("ColumnsByHeader", func(c *qt.C) {
+		data := "\ufeffWeight, name, Address\n3, alice, 0xabc\n5, bob\n"
+		rows, err := newCSVRowReader(strings.NewReader(data), ',', "address", "weight")
+		c.Assert(err, qt.IsNil)
+
+		address, weight, err := rows.Next()
+		c.Assert(err, qt.IsNil)
+		c.Assert(address, qt.Equals, "0xabc")
+		c.Assert(weight, qt.Equals, "3")
+
+		// the missing fields are empty, to be reported as invalid rows
+		address, weight, err = rows.Next()
+		c.Assert(err, qt.IsNil)
+		c.Assert(address, qt.Equals, "")
+		c.Assert(weight, qt.Equals, "5")
+
+		_, _, err = rows.Next()
+		c.Assert(errors.Is(err, io.EOF), qt.IsTrue)
+	})
+
+	c.Run("MissingColumn", func(c *qt.C) {
+		_, err := newCSVRowReader(strings.NewReader("wallet,weight\n"), ',', "address", "weight")
+		c.Assert(err, qt.ErrorMatches, `missing "address" column, found columns: wallet, weight`)
+	})
+
+	c.Run("EmptyFile", func(c *qt.C) {
+		_, err := newCSVRowReader(strings.NewReader(""), ',', "address", "weight")
+		c.Assert(err, qt.ErrorMatches, "empty file")
+	})
+}
+
+func TestCSVImportCensus(t *testing.T) {
+	c := qt.New(t)
+
+	c.Run("HTTPWithURIOptions", func(c *qt.C) {
+		censusDB := testNewCensusDB(c)
+		rows, root := testMakeTabularCensus(c, 20)
+		data := testMakeCSV("wallet;name;votes", ";", rows)
+
+		var requested string
+		oldTransport := http.DefaultTransport
+		http.DefaultTransport = roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+			requested = r.URL.String()
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Header:     http.Header{"Content-Type": []string{"text/csv"}},
+				Body:       io.NopCloser(strings.NewReader(data)),
+				Request:    r,
+			}, nil
+		})
+		c.Cleanup(func() { http.DefaultTransport = oldTransport })
+
+		imported, err := CSVImporter(nil).ImportCensus(c.Context(), censusDB, 0, &types.Census{
+			CensusURI:  "https://example.invalid/census.csv#address=wallet&weight=votes&delimiter=%3B",
+			CensusRoot: root,
+		}, 0)
+		c.Assert(err, qt.IsNil)
+		c.Assert(imported, qt.Equals, len(rows))
+		c.Assert(requested, qt.Equals, "https://example.invalid/census.csv")
+		c.Assert(censusDB.ExistsByRoot(root), qt.IsTrue)
+	})
+
+	c.Run("DownloadError", func(c *qt.C) {
+		censusDB := testNewCensusDB(c)
+		oldTransport := http.DefaultTransport
+		http.DefaultTransport = roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+			return &http.Response{
+				StatusCode: http.StatusNotFound,
+				Header:     http.Header{},
+				Body:       io.NopCloser(strings.NewReader("not found")),
+				Request:    r,
+			}, nil
+		})
+		c.Cleanup(func() { http.DefaultTransport = oldTransport })
+
+		// the rows imported by the previous attempts are kept
+		imported, err := CSVImporter(nil).ImportCensus(c.Context(), censusDB, 0, &types.Census{
+			CensusURI:  "https://example.invalid/census.csv",
+			CensusRoot: types.HexBytes{0x01},
+		}, 7)
+		c.Assert(err, qt.ErrorMatches, ".*status code 404.*")
+		c.Assert(imported, qt.Equals, 7)
+	})
+
+	c.Run("LocalTSVFile", func(c *qt.C) {
+		censusDB := testNewCensusDB(c)
+		rows, root := testMakeTabularCensus(c, 5)
+		dir := c.TempDir()
+		err := os.WriteFile(filepath.Join(dir, "census.tsv"), []byte(testMakeCSV("address\tname\tweight", "\t", rows)), 0o600)
+		c.Assert(err, qt.IsNil)
+
+		importer := CSVImporter(&CSVImporterConfig{FileDir: dir})
+		imported, err := importer.ImportCensus(c.Context(), censusDB, 0, &types.Census{
+			CensusURI:  "file:census.tsv",
+			CensusRoot: root,
+		}, 0)
+		c.Assert(err, qt.IsNil)
+		c.Assert(imported, qt.Equals, len(rows))
+		c.Assert(censusDB.ExistsByRoot(root), qt.IsTrue)
+
+		_, err = importer.ImportCensus(c.Context(), censusDB, 0, &types.Census{
+			CensusURI:  "file:missing.csv",
+			CensusRoot: root,
+		}, 0)
+		c.Assert(errors.Is(err, os.ErrNotExist), qt.IsTrue)
+	})
+
+	c.Run("InvalidRows", func(c *qt.C) {
+		censusDB := testNewCensusDB(c)
+		rows, root := testMakeTabularCensus(c, 3)
+		rows = append(rows, testTabularRow{Address: rows[0].Address, Weight: "2"})
+		dir := c.TempDir()
+		err := os.WriteFile(filepath.Join(dir, "census.csv"), []byte(testMakeCSV("address,name,weight", ",", rows)), 0o600)
+		c.Assert(err, qt.IsNil)
+
+		_, err = CSVImporter(&CSVImporterConfig{FileDir: dir}).ImportCensus(c.Context(), censusDB, 0, &types.Census{
+			CensusURI:  "file:census.csv",
+			CensusRoot: root,
+		}, 0)
+		var invalidRows *InvalidRowsError
+		c.Assert(errors.As(err, &invalidRows), qt.IsTrue)
+		c.Assert(invalidRows.Rows, qt.DeepEquals, []RowError{{
+			Row:     4,
+			Address: rows[0].Address,
+			Reason:  "duplicated address, first found at row 1",
+		}})
+		c.Assert(censusDB.ExistsByRoot(root), qt.IsFalse)
+	})
+}
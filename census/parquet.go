@@ -0,0 +1,244 @@
+package census
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/vocdoni/davinci-node/census/censusdb"
+	"github.com/vocdoni/davinci-node/log"
+	"github.com/vocdoni/davinci-node/types"
+)
+
+// ParquetImporterConfig holds configuration options for the Parquet
+// importer. The URI of each census can override the columns in its
+// fragment, e.g. https://example.com/census.parquet#address=wallet
+type ParquetImporterConfig struct {
+	// AddressColumn is the name of the column of the voter addresses.
+	AddressColumn string
+	// WeightColumn is the name of the column of the voter weights.
+	WeightColumn string
+	// FileDir is the directory from which the file:// URIs can be imported.
+	// If it is empty, the file URIs are not supported.
+	FileDir string
+}
+
+// ParquetImporter returns an instance of parquetImporter with the provided
+// configuration. If config is nil, it uses the default configuration.
+func ParquetImporter(config *ParquetImporterConfig) *parquetImporter {
+	if config == nil {
+		config = &ParquetImporterConfig{}
+	}
+	importer := &parquetImporter{
+		addressColumn: config.AddressColumn,
+		weightColumn:  config.WeightColumn,
+		fileDir:       config.FileDir,
+	}
+	if importer.addressColumn == "" {
+		importer.addressColumn = DefaultAddressColumn
+	}
+	if importer.weightColumn == "" {
+		importer.weightColumn = DefaultWeightColumn
+	}
+	return importer
+}
+
+// parquetImporter is an implementation of the ImporterPlugin interface for
+// importing censuses from Parquet files, with a row per voter. The address
+// column can hold the hex addresses as strings or the raw 20 bytes, and the
+// weight column integers, decimal strings or decimals without scale.
+type parquetImporter struct {
+	addressColumn string
+	weightColumn  string
+	fileDir       string
+}
+
+// ValidURI checks if the provided targetURI is an HTTP, HTTPS or file URL of
+// a .parquet file.
+func (d *parquetImporter) ValidURI(targetURI string) bool {
+	return validTabularURI(targetURI, d.fileDir != "", ".parquet")
+}
+
+// ImportCensus streams the rows of the Parquet file of the census URI into
+// the census DB, checking that they lead to the census root. The remote
+// files are downloaded to a temporary file first, since the Parquet metadata
+// is at their end. The from argument is the number of rows imported by a
+// previous attempt, which are skipped. It returns the number of rows
+// imported, also when it fails, so the import can be resumed.
+func (d *parquetImporter) ImportCensus(
+	ctx context.Context,
+	censusDB *censusdb.CensusDB,
+	chainID uint64,
+	census *types.Census,
+	from int,
+) (int, error) {
+	_, options, err := parseTabularURI(census.CensusURI)
+	if err != nil {
+		return 0, fmt.Errorf("invalid Parquet census URI %s: %w", census.CensusURI, err)
+	}
+	addressColumn, weightColumn := d.addressColumn, d.weightColumn
+	if options.addressColumn != "" {
+		addressColumn = options.addressColumn
+	}
+	if options.weightColumn != "" {
+		weightColumn = options.weightColumn
+	}
+	file, size, cleanup, err := d.localFile(ctx, census.CensusURI)
+	if err != nil {
+		return from, err
+	}
+	defer cleanup()
+	parquetFile, err := openParquetFile(file, size)
+	if err != nil {
+		return 0, fmt.Errorf("invalid Parquet census file %s: %w", census.CensusURI, err)
+	}
+	rows, err := newParquetRowReader(parquetFile, addressColumn, weightColumn)
+	if err != nil {
+		return 0, fmt.Errorf("invalid Parquet census file %s: %w", census.CensusURI, err)
+	}
+	defer rows.close()
+	return importCensusRows(ctx, censusDB, chainID, census, from, rows)
+}
+
+// localFile opens the Parquet file of the URI provided, downloading it to a
+// temporary file if it is remote. It returns the file, its size and a
+// function that closes it and removes it, if temporary.
+func (d *parquetImporter) localFile(ctx context.Context, targetURI string) (*os.File, int64, func(), error) {
+	source, err := openTabularFile(ctx, targetURI, d.fileDir)
+	if err != nil {
+		return nil, 0, nil, err
+	}
+	closeFile := func(file io.Closer) {
+		if err := file.Close(); err != nil {
+			log.Warnw("failed to close Parquet census file",
+				"uri", targetURI,
+				"error", err.Error())
+		}
+	}
+	file, isLocal := source.(*os.File)
+	if !isLocal {
+		defer closeFile(source)
+		if file, err = os.CreateTemp("", "davinci-census-*.parquet"); err != nil {
+			return nil, 0, nil, fmt.Errorf("failed to create temporary census file: %w", err)
+		}
+		if _, err := io.Copy(file, source); err != nil {
+			closeFile(file)
+			_ = os.Remove(file.Name())
+			return nil, 0, nil, fmt.Errorf("failed to download census file from %s: %w", targetURI, err)
+		}
+	}
+	cleanup := func() {
+		closeFile(file)
+		if !isLocal {
+			if err := os.Remove(file.Name()); err != nil {
+				log.Warnw("failed to remove temporary census file",
+					"path", file.Name(),
+					"error", err.Error())
+			}
+		}
+	}
+	info, err := file.Stat()
+	if err != nil {
+		cleanup()
+		return nil, 0, nil, fmt.Errorf("failed to read census file %s: %w", targetURI, err)
+	}
+	return file, info.Size(), cleanup, nil
+}
+
+// parquetRowReader reads the address and weight columns of a Parquet file.
+type parquetRowReader struct {
+	address *parquetColumnReader
+	weight  *parquetColumnReader
+}
+
+// newParquetRowReader returns a reader of the columns with the names
+// provided, compared case-insensitively, checking that their types can hold
+// addresses and weights.
+func newParquetRowReader(file *parquetFile, addressColumn, weightColumn string) (*parquetRowReader, error) {
+	address := file.column(addressColumn)
+	if address == nil {
+		return nil, fmt.Errorf("missing %q column, found columns: %s", addressColumn, strings.Join(file.columnNames(), ", "))
+	}
+	if address.typ != parquetByteArray && address.typ != parquetFixedLenByteArray {
+		return nil, fmt.Errorf("column %q must hold strings or bytes", address.name)
+	}
+	weight := file.column(weightColumn)
+	if weight == nil {
+		return nil, fmt.Errorf("missing %q column, found columns: %s", weightColumn, strings.Join(file.columnNames(), ", "))
+	}
+	switch {
+	case weight.isDecimal && weight.scale != 0:
+		return nil, fmt.Errorf("column %q must hold integers, it has a decimal scale of %d", weight.name, weight.scale)
+	case weight.typ == parquetInt32, weight.typ == parquetInt64, weight.typ == parquetByteArray:
+	case weight.typ == parquetFixedLenByteArray && weight.isDecimal:
+	default:
+		return nil, fmt.Errorf("column %q must hold integers, decimal strings or decimals", weight.name)
+	}
+	return &parquetRowReader{
+		address: file.newParquetColumnReader(address),
+		weight:  file.newParquetColumnReader(weight),
+	}, nil
+}
+
+// close releases the resources of the column readers.
+func (pr *parquetRowReader) close() {
+	pr.address.close()
+	pr.weight.close()
+}
+
+// Next returns the address and the weight of the next row, formatted as in
+// the CSV files. It returns io.EOF after the last row.
+func (pr *parquetRowReader) Next() (string, string, error) {
+	address, addressErr := pr.address.next()
+	weight, weightErr := pr.weight.next()
+	switch {
+	case errors.Is(addressErr, io.EOF) && errors.Is(weightErr, io.EOF):
+		return "", "", io.EOF
+	case errors.Is(addressErr, io.EOF) || errors.Is(weightErr, io.EOF):
+		return "", "", fmt.Errorf("columns %q and %q have a different number of values",
+			pr.address.column.name, pr.weight.column.name)
+	case addressErr != nil:
+		return "", "", addressErr
+	case weightErr != nil:
+		return "", "", weightErr
+	}
+	return parquetAddress(pr.address.column, address), parquetWeight(pr.weight.column, weight), nil
+}
+
+// parquetAddress formats an address value. The raw addresses, 20 bytes
+// that are not annotated as strings, are hex encoded.
+func parquetAddress(column *parquetColumn, value parquetValue) string {
+	if value.null {
+		return ""
+	}
+	if !column.isString && len(value.bytes) == common.AddressLength {
+		return "0x" + hex.EncodeToString(value.bytes)
+	}
+	return string(value.bytes)
+}
+
+// parquetWeight formats a weight value in base 10. The decimals are big
+// endian two's complement integers.
+func parquetWeight(column *parquetColumn, value parquetValue) string {
+	switch {
+	case value.null:
+		return ""
+	case column.typ == parquetInt32 || column.typ == parquetInt64:
+		return strconv.FormatInt(value.i64, 10)
+	case column.isDecimal:
+		weight := new(big.Int).SetBytes(value.bytes)
+		if len(value.bytes) > 0 && value.bytes[0]&0x80 != 0 {
+			weight.Sub(weight, new(big.Int).Lsh(big.NewInt(1), uint(8*len(value.bytes))))
+		}
+		return weight.String()
+	default:
+		return string(value.bytes)
+	}
+}
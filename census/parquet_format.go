@@ -0,0 +1,1179 @@
+package census
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+
+	"github.com/golang/snappy"
+	"github.com/klauspost/compress/zstd"
+)
+
+// This file implements a minimal reader of Parquet files, enough to stream
+// the top-level columns of the flat tables exported by spreadsheets and data
+// warehouses. It supports the PLAIN and dictionary encodings, data pages v1
+// and v2, and the uncompressed, snappy, gzip and zstd codecs. Nested columns
+// can be present in the file, but can not be read. The files are untrusted,
+// so every size, count and nesting depth read from them is bounded before
+// allocating or recursing, and FuzzParquetReader checks that malformed files
+// fail cleanly.
+
+// parquetMagic is the magic number at the start and the end of the files.
+const parquetMagic = "PAR1"
+
+// Limits of the sizes and counts read from the files, to bound the memory
+// and the stack used by malformed files.
+const (
+	// maxParquetFooterSize is the maximum size of the file metadata.
+	maxParquetFooterSize = 64 << 20
+	// maxParquetPageSize is the maximum size of a page, compressed or not.
+	maxParquetPageSize = 64 << 20
+	// maxParquetPageValues is the maximum number of values of a page,
+	// dictionary pages included.
+	maxParquetPageValues = 1 << 22
+	// maxParquetSchemaDepth is the maximum nesting of the schema groups.
+	maxParquetSchemaDepth = 64
+)
+
+// Physical types of the Parquet columns.
+const (
+	parquetBoolean           int32 = 0
+	parquetInt32             int32 = 1
+	parquetInt64             int32 = 2
+	parquetInt96             int32 = 3
+	parquetFloat             int32 = 4
+	parquetDouble            int32 = 5
+	parquetByteArray         int32 = 6
+	parquetFixedLenByteArray int32 = 7
+)
+
+// Repetition types of the Parquet schema elements.
+const (
+	parquetRequired int32 = 0
+	parquetOptional int32 = 1
+	parquetRepeated int32 = 2
+)
+
+// Converted types of the Parquet schema elements used by the reader.
+const (
+	parquetConvertedUTF8    int32 = 0
+	parquetConvertedDecimal int32 = 5
+)
+
+// Compression codecs of the Parquet column chunks.
+const (
+	parquetUncompressed int32 = 0
+	parquetSnappy       int32 = 1
+	parquetGzip         int32 = 2
+	parquetZstd         int32 = 6
+)
+
+// Page types of the Parquet column chunks.
+const (
+	parquetDataPage       int32 = 0
+	parquetDictionaryPage int32 = 2
+	parquetDataPageV2     int32 = 3
+)
+
+// Encodings of the Parquet pages.
+const (
+	parquetPlain           int32 = 0
+	parquetPlainDictionary int32 = 2
+	parquetRLE             int32 = 3
+	parquetRLEDictionary   int32 = 8
+)
+
+// parquetSchemaElement is an element of the schema of a Parquet file.
+type parquetSchemaElement struct {
+	typ         int32
+	typeLength  int32
+	repetition  int32
+	name        string
+	numChildren int32
+	converted   int32
+	scale       int32
+	isString    bool
+	isDecimal   bool
+}
+
+// parquetColumnChunk is the metadata of a column chunk of a row group.
+type parquetColumnChunk struct {
+	codec                int32
+	numValues            int64
+	totalCompressedSize  int64
+	dataPageOffset       int64
+	dictionaryPageOffset int64
+}
+
+// parquetRowGroup is a row group of a Parquet file, with the metadata of its
+// column chunks in the order of the leaf columns of the schema.
+type parquetRowGroup struct {
+	numRows int64
+	columns []parquetColumnChunk
+}
+
+// parquetColumn is a top-level column of a Parquet file that can be read.
+type parquetColumn struct {
+	name       string
+	index      int // index of the column chunks in the row groups
+	typ        int32
+	typeLength int32
+	optional   bool
+	isString   bool
+	isDecimal  bool
+	scale      int32
+}
+
+// parquetValue is a value of a Parquet column. Integers are returned in
+// i64, and byte arrays in bytes.
+type parquetValue struct {
+	null  bool
+	i64   int64
+	bytes []byte
+}
+
+// parquetFile is a Parquet file opened to read its columns.
+type parquetFile struct {
+	r         io.ReaderAt
+	numRows   int64
+	columns   []*parquetColumn
+	rowGroups []parquetRowGroup
+}
+
+// openParquetFile reads the metadata of the Parquet file of the size provided.
+func openParquetFile(r io.ReaderAt, size int64) (*parquetFile, error) {
+	if size < int64(2*len(parquetMagic)+4) {
+		return nil, fmt.Errorf("file too small to be a parquet file")
+	}
+	header := make([]byte, len(parquetMagic))
+	if _, err := r.ReadAt(header, 0); err != nil {
+		return nil, fmt.Errorf("could not read parquet header: %w", err)
+	}
+	trailer := make([]byte, 4+len(parquetMagic))
+	if _, err := r.ReadAt(trailer, size-int64(len(trailer))); err != nil {
+		return nil, fmt.Errorf("could not read parquet trailer: %w", err)
+	}
+	if string(header) != parquetMagic || string(trailer[4:]) != parquetMagic {
+		return nil, fmt.Errorf("not a parquet file")
+	}
+	footerSize := int64(binary.LittleEndian.Uint32(trailer[:4]))
+	if footerSize > maxParquetFooterSize || footerSize > size-int64(len(header)+len(trailer)) {
+		return nil, fmt.Errorf("invalid parquet footer size %d", footerSize)
+	}
+	footer := make([]byte, footerSize)
+	if _, err := r.ReadAt(footer, size-int64(len(trailer))-footerSize); err != nil {
+		return nil, fmt.Errorf("could not read parquet footer: %w", err)
+	}
+	file := &parquetFile{r: r}
+	schema, err := file.readMetadata(newThriftReader(bytes.NewReader(footer)))
+	if err != nil {
+		return nil, fmt.Errorf("invalid parquet metadata: %w", err)
+	}
+	if err := file.readColumns(schema); err != nil {
+		return nil, fmt.Errorf("invalid parquet schema: %w", err)
+	}
+	return file, nil
+}
+
+// column returns the top-level column with the name provided, compared
+// case-insensitively, or nil if the file has no such column.
+func (f *parquetFile) column(name string) *parquetColumn {
+	for _, column := range f.columns {
+		if equalColumnName(column.name, name) {
+			return column
+		}
+	}
+	return nil
+}
+
+// columnNames returns the names of the top-level columns of the file.
+func (f *parquetFile) columnNames() []string {
+	names := make([]string, 0, len(f.columns))
+	for _, column := range f.columns {
+		names = append(names, column.name)
+	}
+	return names
+}
+
+// readMetadata decodes the file metadata, and returns its schema.
+func (f *parquetFile) readMetadata(r *thriftReader) ([]parquetSchemaElement, error) {
+	var schema []parquetSchemaElement
+	err := r.readStruct(func(id int16, typ byte) error {
+		switch id {
+		case 2:
+			return r.readList(typ, func(typ byte) error {
+				element, err := readParquetSchemaElement(r, typ)
+				schema = append(schema, element)
+				return err
+			})
+		case 3:
+			numRows, err := r.readInt(typ)
+			f.numRows = numRows
+			return err
+		case 4:
+			return r.readList(typ, func(typ byte) error {
+				rowGroup, err := readParquetRowGroup(r, typ)
+				f.rowGroups = append(f.rowGroups, rowGroup)
+				return err
+			})
+		default:
+			return r.skip(typ)
+		}
+	})
+	return schema, err
+}
+
+// readColumns collects the top-level primitive columns of the schema, and
+// the index of their column chunks, which follow the order of every leaf of
+// the schema, nested ones included.
+func (f *parquetFile) readColumns(schema []parquetSchemaElement) error {
+	if len(schema) == 0 {
+		return fmt.Errorf("empty schema")
+	}
+	next, leaves := 1, 0
+	// countLeaves skips the subtree of the element at next, and returns the
+	// number of leaves it has.
+	var countLeaves func(depth int) (int, error)
+	countLeaves = func(depth int) (int, error) {
+		if next >= len(schema) {
+			return 0, fmt.Errorf("schema has less elements than declared")
+		}
+		if depth > maxParquetSchemaDepth {
+			return 0, fmt.Errorf("schema groups nested too deep")
+		}
+		element := schema[next]
+		next++
+		if element.numChildren <= 0 {
+			return 1, nil
+		}
+		total := 0
+		for range element.numChildren {
+			n, err := countLeaves(depth + 1)
+			if err != nil {
+				return 0, err
+			}
+			total += n
+		}
+		return total, nil
+	}
+	for range schema[0].numChildren {
+		if next >= len(schema) {
+			return fmt.Errorf("schema has less elements than declared")
+		}
+		element := schema[next]
+		if element.numChildren > 0 || element.repetition == parquetRepeated {
+			n, err := countLeaves(1)
+			if err != nil {
+				return err
+			}
+			leaves += n
+			continue
+		}
+		next++
+		f.columns = append(f.columns, &parquetColumn{
+			name:       element.name,
+			index:      leaves,
+			typ:        element.typ,
+			typeLength: element.typeLength,
+			optional:   element.repetition == parquetOptional,
+			isString:   element.isString,
+			isDecimal:  element.isDecimal,
+			scale:      element.scale,
+		})
+		leaves++
+	}
+	for i, rowGroup := range f.rowGroups {
+		if len(rowGroup.columns) != leaves {
+			return fmt.Errorf("row group %d has %d columns, expected %d", i, len(rowGroup.columns), leaves)
+		}
+	}
+	return nil
+}
+
+// readParquetSchemaElement decodes a SchemaElement struct.
+func readParquetSchemaElement(r *thriftReader, typ byte) (parquetSchemaElement, error) {
+	element := parquetSchemaElement{converted: -1}
+	if typ != thriftStruct {
+		return element, fmt.Errorf("unexpected thrift type %d for schema element", typ)
+	}
+	err := r.readStruct(func(id int16, typ byte) error {
+		var err error
+		var v int64
+		switch id {
+		case 1:
+			v, err = r.readInt(typ)
+			element.typ = int32(v)
+		case 2:
+			v, err = r.readInt(typ)
+			element.typeLength = int32(v)
+		case 3:
+			v, err = r.readInt(typ)
+			element.repetition = int32(v)
+		case 4:
+			var name []byte
+			name, err = r.readBinary(typ)
+			element.name = string(name)
+		case 5:
+			v, err = r.readInt(typ)
+			element.numChildren = int32(v)
+		case 6:
+			v, err = r.readInt(typ)
+			element.converted = int32(v)
+			element.isString = element.isString || element.converted == parquetConvertedUTF8
+			element.isDecimal = element.isDecimal || element.converted == parquetConvertedDecimal
+		case 7:
+			v, err = r.readInt(typ)
+			element.scale = int32(v)
+		case 10:
+			// LogicalType union: STRING (1) and DECIMAL (5) are the only
+			// ones that change how the values are read
+			return r.readStruct(func(id int16, typ byte) error {
+				switch id {
+				case 1:
+					element.isString = true
+				case 5:
+					element.isDecimal = true
+					return r.readStruct(func(id int16, typ byte) error {
+						if id != 1 {
+							return r.skip(typ)
+						}
+						scale, err := r.readInt(typ)
+						element.scale = int32(scale)
+						return err
+					})
+				}
+				return r.skip(typ)
+			})
+		default:
+			err = r.skip(typ)
+		}
+		return err
+	})
+	return element, err
+}
+
+// readParquetRowGroup decodes a RowGroup struct.
+func readParquetRowGroup(r *thriftReader, typ byte) (parquetRowGroup, error) {
+	rowGroup := parquetRowGroup{}
+	if typ != thriftStruct {
+		return rowGroup, fmt.Errorf("unexpected thrift type %d for row group", typ)
+	}
+	err := r.readStruct(func(id int16, typ byte) error {
+		switch id {
+		case 1:
+			return r.readList(typ, func(typ byte) error {
+				chunk, err := readParquetColumnChunk(r, typ)
+				rowGroup.columns = append(rowGroup.columns, chunk)
+				return err
+			})
+		case 3:
+			numRows, err := r.readInt(typ)
+			rowGroup.numRows = numRows
+			return err
+		default:
+			return r.skip(typ)
+		}
+	})
+	return rowGroup, err
+}
+
+// readParquetColumnChunk decodes a ColumnChunk struct and its
+// ColumnMetaData.
+func readParquetColumnChunk(r *thriftReader, typ byte) (parquetColumnChunk, error) {
+	chunk := parquetColumnChunk{}
+	if typ != thriftStruct {
+		return chunk, fmt.Errorf("unexpected thrift type %d for column chunk", typ)
+	}
+	hasMetadata := false
+	err := r.readStruct(func(id int16, typ byte) error {
+		if id != 3 {
+			return r.skip(typ)
+		}
+		hasMetadata = true
+		return r.readStruct(func(id int16, typ byte) error {
+			var err error
+			var v int64
+			switch id {
+			case 4:
+				v, err = r.readInt(typ)
+				chunk.codec = int32(v)
+			case 5:
+				chunk.numValues, err = r.readInt(typ)
+			case 7:
+				chunk.totalCompressedSize, err = r.readInt(typ)
+			case 9:
+				chunk.dataPageOffset, err = r.readInt(typ)
+			case 11:
+				chunk.dictionaryPageOffset, err = r.readInt(typ)
+			default:
+				err = r.skip(typ)
+			}
+			return err
+		})
+	})
+	if err == nil && !hasMetadata {
+		err = fmt.Errorf("column chunk without metadata, external column chunks are not supported")
+	}
+	return chunk, err
+}
+
+// parquetPageHeader is the header of a page of a column chunk.
+type parquetPageHeader struct {
+	typ              int32
+	uncompressedSize int32
+	compressedSize   int32
+	// data pages
+	hasDataPageHeader   bool
+	hasDataPageV2Header bool
+	numValues           int32
+	encoding            int32
+	definitionEncoding  int32
+	// data pages v2
+	definitionLength int32
+	repetitionLength int32
+	isCompressed     bool
+	// dictionary pages
+	hasDictionaryHeader bool
+	dictionaryNumValues int32
+	dictionaryEncoding  int32
+}
+
+// readParquetPageHeader decodes a PageHeader struct.
+func readParquetPageHeader(r *thriftReader) (*parquetPageHeader, error) {
+	header := &parquetPageHeader{isCompressed: true}
+	err := r.readStruct(func(id int16, typ byte) error {
+		var err error
+		var v int64
+		switch id {
+		case 1:
+			v, err = r.readInt(typ)
+			header.typ = int32(v)
+		case 2:
+			v, err = r.readInt(typ)
+			header.uncompressedSize = int32(v)
+		case 3:
+			v, err = r.readInt(typ)
+			header.compressedSize = int32(v)
+		case 5:
+			header.hasDataPageHeader = true
+			err = r.readStruct(func(id int16, typ byte) error {
+				var err error
+				var v int64
+				switch id {
+				case 1:
+					v, err = r.readInt(typ)
+					header.numValues = int32(v)
+				case 2:
+					v, err = r.readInt(typ)
+					header.encoding = int32(v)
+				case 3:
+					v, err = r.readInt(typ)
+					header.definitionEncoding = int32(v)
+				default:
+					err = r.skip(typ)
+				}
+				return err
+			})
+		case 7:
+			header.hasDictionaryHeader = true
+			err = r.readStruct(func(id int16, typ byte) error {
+				var err error
+				var v int64
+				switch id {
+				case 1:
+					v, err = r.readInt(typ)
+					header.dictionaryNumValues = int32(v)
+				case 2:
+					v, err = r.readInt(typ)
+					header.dictionaryEncoding = int32(v)
+				default:
+					err = r.skip(typ)
+				}
+				return err
+			})
+		case 8:
+			header.hasDataPageV2Header = true
+			err = r.readStruct(func(id int16, typ byte) error {
+				var err error
+				var v int64
+				switch id {
+				case 1:
+					v, err = r.readInt(typ)
+					header.numValues = int32(v)
+				case 4:
+					v, err = r.readInt(typ)
+					header.encoding = int32(v)
+				case 5:
+					v, err = r.readInt(typ)
+					header.definitionLength = int32(v)
+				case 6:
+					v, err = r.readInt(typ)
+					header.repetitionLength = int32(v)
+				case 7:
+					header.isCompressed, err = r.readBool(typ)
+				default:
+					err = r.skip(typ)
+				}
+				return err
+			})
+		default:
+			err = r.skip(typ)
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if header.compressedSize < 0 || header.compressedSize > maxParquetPageSize ||
+		header.uncompressedSize < 0 || header.uncompressedSize > maxParquetPageSize {
+		return nil, fmt.Errorf("invalid page sizes %d and %d", header.compressedSize, header.uncompressedSize)
+	}
+	if header.numValues < 0 || header.numValues > maxParquetPageValues ||
+		header.dictionaryNumValues < 0 || header.dictionaryNumValues > maxParquetPageValues {
+		return nil, fmt.Errorf("invalid number of page values")
+	}
+	return header, nil
+}
+
+// parquetColumnReader reads the values of a column, page by page, across the
+// row groups of the file.
+type parquetColumnReader struct {
+	file     *parquetFile
+	column   *parquetColumn
+	rowGroup int
+
+	// state of the current column chunk
+	chunk      *bufio.Reader
+	chunkSize  int64
+	codec      int32
+	remaining  int64
+	dictionary []parquetValue
+
+	// values of the current page
+	values []parquetValue
+	pos    int
+
+	zstd *zstd.Decoder
+}
+
+// newParquetColumnReader returns a reader of the values of the column
+// provided.
+func (f *parquetFile) newParquetColumnReader(column *parquetColumn) *parquetColumnReader {
+	return &parquetColumnReader{file: f, column: column}
+}
+
+// close releases the resources of the reader.
+func (cr *parquetColumnReader) close() {
+	if cr.zstd != nil {
+		cr.zstd.Close()
+	}
+}
+
+// next returns the next value of the column. It returns io.EOF after the
+// last value.
+func (cr *parquetColumnReader) next() (parquetValue, error) {
+	for cr.pos >= len(cr.values) {
+		if err := cr.readPage(); err != nil {
+			return parquetValue{}, err
+		}
+	}
+	value := cr.values[cr.pos]
+	cr.pos++
+	return value, nil
+}
+
+// readPage decodes the next data page of the column, moving to the next
+// column chunk when the current one has no more values.
+func (cr *parquetColumnReader) readPage() error {
+	for cr.chunk == nil || cr.remaining <= 0 {
+		if cr.rowGroup >= len(cr.file.rowGroups) {
+			return io.EOF
+		}
+		chunk := cr.file.rowGroups[cr.rowGroup].columns[cr.column.index]
+		cr.rowGroup++
+		start := chunk.dataPageOffset
+		if chunk.dictionaryPageOffset > 0 && chunk.dictionaryPageOffset < start {
+			start = chunk.dictionaryPageOffset
+		}
+		if start < 0 || chunk.totalCompressedSize < 0 {
+			return fmt.Errorf("invalid column chunk offsets of column %s", cr.column.name)
+		}
+		cr.chunk = bufio.NewReader(io.NewSectionReader(cr.file.r, start, chunk.totalCompressedSize))
+		cr.chunkSize = chunk.totalCompressedSize
+		cr.codec = chunk.codec
+		cr.remaining = chunk.numValues
+		cr.dictionary = nil
+	}
+	header, err := readParquetPageHeader(newThriftReader(cr.chunk))
+	if err != nil {
+		return fmt.Errorf("could not read page header of column %s: %w", cr.column.name, err)
+	}
+	if int64(header.compressedSize) > cr.chunkSize {
+		return fmt.Errorf("page of column %s is larger than its column chunk", cr.column.name)
+	}
+	page := make([]byte, header.compressedSize)
+	if _, err := io.ReadFull(cr.chunk, page); err != nil {
+		return fmt.Errorf("could not read page of column %s: %w", cr.column.name, err)
+	}
+	cr.values, cr.pos = cr.values[:0], 0
+	switch header.typ {
+	case parquetDictionaryPage:
+		if !header.hasDictionaryHeader {
+			return fmt.Errorf("dictionary page without header in column %s", cr.column.name)
+		}
+		data, err := cr.decompress(page, header.uncompressedSize)
+		if err != nil {
+			return err
+		}
+		if header.dictionaryEncoding != parquetPlain && header.dictionaryEncoding != parquetPlainDictionary {
+			return fmt.Errorf("unsupported dictionary encoding %d in column %s", header.dictionaryEncoding, cr.column.name)
+		}
+		cr.dictionary, err = cr.decodePlain(data, int(header.dictionaryNumValues), nil)
+		return err
+	case parquetDataPage:
+		if !header.hasDataPageHeader {
+			return fmt.Errorf("data page without header in column %s", cr.column.name)
+		}
+		data, err := cr.decompress(page, header.uncompressedSize)
+		if err != nil {
+			return err
+		}
+		var defined []bool
+		if cr.column.optional {
+			if header.definitionEncoding != parquetRLE {
+				return fmt.Errorf("unsupported definition level encoding %d in column %s", header.definitionEncoding, cr.column.name)
+			}
+			if len(data) < 4 {
+				return fmt.Errorf("truncated definition levels in column %s", cr.column.name)
+			}
+			length := binary.LittleEndian.Uint32(data[:4])
+			if uint64(length) > uint64(len(data)-4) {
+				return fmt.Errorf("truncated definition levels in column %s", cr.column.name)
+			}
+			if defined, err = decodeDefinitionLevels(data[4:4+length], int(header.numValues)); err != nil {
+				return fmt.Errorf("invalid definition levels in column %s: %w", cr.column.name, err)
+			}
+			data = data[4+length:]
+		}
+		return cr.decodeValues(header, data, defined)
+	case parquetDataPageV2:
+		if !header.hasDataPageV2Header {
+			return fmt.Errorf("data page v2 without header in column %s", cr.column.name)
+		}
+		levels := int(header.repetitionLength) + int(header.definitionLength)
+		if header.repetitionLength < 0 || header.definitionLength < 0 || levels > len(page) {
+			return fmt.Errorf("invalid level lengths in column %s", cr.column.name)
+		}
+		var defined []bool
+		if cr.column.optional {
+			definitions := page[header.repetitionLength:levels]
+			if defined, err = decodeDefinitionLevels(definitions, int(header.numValues)); err != nil {
+				return fmt.Errorf("invalid definition levels in column %s: %w", cr.column.name, err)
+			}
+		}
+		data := page[levels:]
+		if header.isCompressed {
+			if data, err = cr.decompress(data, header.uncompressedSize-int32(levels)); err != nil {
+				return err
+			}
+		}
+		return cr.decodeValues(header, data, defined)
+	default:
+		// index pages and unknown pages carry no values
+		return nil
+	}
+}
+
+// decodeValues decodes the values of a data page. The defined slice has the
+// definition level of each value of optional columns, and is nil for the
+// required ones.
+func (cr *parquetColumnReader) decodeValues(header *parquetPageHeader, data []byte, defined []bool) error {
+	numValues := int(header.numValues)
+	nonNull := numValues
+	if defined != nil {
+		nonNull = 0
+		for _, d := range defined {
+			if d {
+				nonNull++
+			}
+		}
+	}
+	var values []parquetValue
+	var err error
+	switch header.encoding {
+	case parquetPlain:
+		values, err = cr.decodePlain(data, nonNull, cr.values)
+	case parquetPlainDictionary, parquetRLEDictionary:
+		values, err = cr.decodeDictionary(data, nonNull)
+	default:
+		return fmt.Errorf("unsupported encoding %d in column %s", header.encoding, cr.column.name)
+	}
+	if err != nil {
+		return err
+	}
+	if defined != nil {
+		// spread the values among the rows, leaving the null ones empty
+		spread := make([]parquetValue, numValues)
+		next := 0
+		for i, d := range defined {
+			if !d {
+				spread[i] = parquetValue{null: true}
+				continue
+			}
+			spread[i] = values[next]
+			next++
+		}
+		values = spread
+	}
+	cr.values = values
+	cr.remaining -= int64(numValues)
+	return nil
+}
+
+// decodePlain decodes n values in the PLAIN encoding of the column type,
+// appending them to the values provided.
+func (cr *parquetColumnReader) decodePlain(data []byte, n int, values []parquetValue) ([]parquetValue, error) {
+	if n < 0 {
+		return nil, fmt.Errorf("invalid number of values %d in column %s", n, cr.column.name)
+	}
+	truncated := fmt.Errorf("truncated values in column %s", cr.column.name)
+	switch cr.column.typ {
+	case parquetInt32:
+		if len(data) < 4*n {
+			return nil, truncated
+		}
+		for i := range n {
+			v := int32(binary.LittleEndian.Uint32(data[4*i:]))
+			values = append(values, parquetValue{i64: int64(v)})
+		}
+	case parquetInt64:
+		if len(data) < 8*n {
+			return nil, truncated
+		}
+		for i := range n {
+			v := int64(binary.LittleEndian.Uint64(data[8*i:]))
+			values = append(values, parquetValue{i64: v})
+		}
+	case parquetByteArray:
+		for range n {
+			if len(data) < 4 {
+				return nil, truncated
+			}
+			length := binary.LittleEndian.Uint32(data[:4])
+			if uint64(length) > uint64(len(data)-4) {
+				return nil, truncated
+			}
+			values = append(values, parquetValue{bytes: data[4 : 4+length]})
+			data = data[4+length:]
+		}
+	case parquetFixedLenByteArray:
+		size := int(cr.column.typeLength)
+		if size <= 0 {
+			return nil, fmt.Errorf("invalid fixed length %d of column %s", size, cr.column.name)
+		}
+		if len(data)/size < n {
+			return nil, truncated
+		}
+		for i := range n {
+			values = append(values, parquetValue{bytes: data[size*i : size*(i+1)]})
+		}
+	default:
+		return nil, fmt.Errorf("unsupported type %d of column %s", cr.column.typ, cr.column.name)
+	}
+	return values, nil
+}
+
+// decodeDictionary decodes n dictionary indexes, encoded with the RLE and
+// bit-packing hybrid after a byte with their bit width, and returns their
+// values.
+func (cr *parquetColumnReader) decodeDictionary(data []byte, n int) ([]parquetValue, error) {
+	if cr.dictionary == nil {
+		return nil, fmt.Errorf("dictionary encoded page without dictionary in column %s", cr.column.name)
+	}
+	values := cr.values
+	if n == 0 {
+		return values, nil
+	}
+	if len(data) < 1 {
+		return nil, fmt.Errorf("truncated dictionary indexes in column %s", cr.column.name)
+	}
+	indexes, err := decodeRLEHybrid(data[1:], int(data[0]), n)
+	if err != nil {
+		return nil, fmt.Errorf("invalid dictionary indexes in column %s: %w", cr.column.name, err)
+	}
+	for _, index := range indexes {
+		if index >= uint64(len(cr.dictionary)) {
+			return nil, fmt.Errorf("dictionary index %d out of range in column %s", index, cr.column.name)
+		}
+		values = append(values, cr.dictionary[index])
+	}
+	return values, nil
+}
+
+// decompress decompresses a page with the codec of the column chunk.
+func (cr *parquetColumnReader) decompress(data []byte, uncompressedSize int32) ([]byte, error) {
+	if uncompressedSize < 0 || uncompressedSize > maxParquetPageSize {
+		return nil, fmt.Errorf("invalid uncompressed size %d of page of column %s", uncompressedSize, cr.column.name)
+	}
+	var out []byte
+	var err error
+	switch cr.codec {
+	case parquetUncompressed:
+		return data, nil
+	case parquetSnappy:
+		// the decoded length is checked before snappy allocates it
+		var size int
+		if size, err = snappy.DecodedLen(data); err == nil && size != int(uncompressedSize) {
+			return nil, fmt.Errorf("page of column %s has %d bytes, expected %d", cr.column.name, size, uncompressedSize)
+		}
+		if err == nil {
+			out, err = snappy.Decode(nil, data)
+		}
+	case parquetGzip:
+		var zr *gzip.Reader
+		if zr, err = gzip.NewReader(bytes.NewReader(data)); err == nil {
+			out, err = io.ReadAll(io.LimitReader(zr, int64(uncompressedSize)+1))
+		}
+	case parquetZstd:
+		if cr.zstd == nil {
+			if cr.zstd, err = zstd.NewReader(nil, zstd.WithDecoderMaxMemory(maxParquetPageSize)); err != nil {
+				return nil, err
+			}
+		}
+		out, err = cr.zstd.DecodeAll(data, nil)
+	default:
+		return nil, fmt.Errorf("unsupported compression codec %d in column %s", cr.codec, cr.column.name)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not decompress page of column %s: %w", cr.column.name, err)
+	}
+	if len(out) != int(uncompressedSize) {
+		return nil, fmt.Errorf("page of column %s has %d bytes, expected %d", cr.column.name, len(out), uncompressedSize)
+	}
+	return out, nil
+}
+
+// decodeDefinitionLevels decodes n definition levels of a top-level optional
+// column, whose max level is 1, and reports whether each value is defined.
+func decodeDefinitionLevels(data []byte, n int) ([]bool, error) {
+	levels, err := decodeRLEHybrid(data, 1, n)
+	if err != nil {
+		return nil, err
+	}
+	defined := make([]bool, n)
+	for i, level := range levels {
+		defined[i] = level == 1
+	}
+	return defined, nil
+}
+
+// decodeRLEHybrid decodes n values of the bit width provided, encoded with
+// the RLE and bit-packing hybrid encoding of Parquet.
+func decodeRLEHybrid(data []byte, bitWidth, n int) ([]uint64, error) {
+	if bitWidth < 0 || bitWidth > 32 {
+		return nil, fmt.Errorf("invalid bit width %d", bitWidth)
+	}
+	values := make([]uint64, 0, n)
+	for len(values) < n {
+		header, read := binary.Uvarint(data)
+		if read <= 0 {
+			return nil, fmt.Errorf("truncated run header")
+		}
+		data = data[read:]
+		if header&1 == 0 {
+			// RLE run: the value repeated, in little endian
+			count := header >> 1
+			width := (bitWidth + 7) / 8
+			if count == 0 || len(data) < width {
+				return nil, fmt.Errorf("invalid RLE run")
+			}
+			var value uint64
+			for i := range width {
+				value |= uint64(data[i]) << (8 * i)
+			}
+			data = data[width:]
+			for ; count > 0 && len(values) < n; count-- {
+				values = append(values, value)
+			}
+			continue
+		}
+		// bit-packed run: groups of 8 values, least significant bit first
+		groups := header >> 1
+		if groups == 0 || groups > math.MaxInt32/8 {
+			return nil, fmt.Errorf("invalid bit-packed run")
+		}
+		size := int(groups) * bitWidth
+		if len(data) < size {
+			return nil, fmt.Errorf("truncated bit-packed run")
+		}
+		packed := data[:size]
+		data = data[size:]
+		for i := 0; i < int(groups)*8 && len(values) < n; i++ {
+			var value uint64
+			for bit := range bitWidth {
+				pos := i*bitWidth + bit
+				value |= uint64(packed[pos/8]>>(pos%8)&1) << bit
+			}
+			values = append(values, value)
+		}
+	}
+	return values, nil
+}
+
+// Types of the thrift compact protocol.
+const (
+	thriftStop   byte = 0
+	thriftTrue   byte = 1
+	thriftFalse  byte = 2
+	thriftByte   byte = 3
+	thriftI16    byte = 4
+	thriftI32    byte = 5
+	thriftI64    byte = 6
+	thriftDouble byte = 7
+	thriftBinary byte = 8
+	thriftList   byte = 9
+	thriftSet    byte = 10
+	thriftMap    byte = 11
+	thriftStruct byte = 12
+)
+
+const (
+	// maxThriftDepth is the maximum nesting of the thrift structs, lists and
+	// maps decoded.
+	maxThriftDepth = 64
+	// maxThriftBinary is the maximum size of the thrift binaries decoded.
+	maxThriftBinary = 16 << 20
+	// maxThriftElements is the maximum number of elements of the thrift
+	// lists and maps decoded.
+	maxThriftElements = 1 << 20
+)
+
+// thriftReader decodes the thrift compact protocol, in which the Parquet
+// metadata is encoded.
+type thriftReader struct {
+	r     io.ByteReader
+	depth int
+}
+
+// newThriftReader returns a thrift reader of the data of the reader
+// provided, which must not be read ahead.
+func newThriftReader(r io.ByteReader) *thriftReader {
+	return &thriftReader{r: r}
+}
+
+// readStruct decodes a struct, calling field with the id and type of each of
+// its fields, which must decode or skip the value of the field.
+func (r *thriftReader) readStruct(field func(id int16, typ byte) error) error {
+	if err := r.nest(); err != nil {
+		return err
+	}
+	defer r.unnest()
+	var lastID int16
+	for {
+		b, err := r.r.ReadByte()
+		if err != nil {
+			return unexpectedEOF(err)
+		}
+		typ := b & 0x0f
+		if typ == thriftStop {
+			return nil
+		}
+		id := lastID + int16(b>>4)
+		if b>>4 == 0 {
+			v, err := r.readVarint()
+			if err != nil {
+				return err
+			}
+			id = int16(zigzag(v))
+		}
+		if err := field(id, typ); err != nil {
+			return err
+		}
+		lastID = id
+	}
+}
+
+// readList decodes a list or a set, calling element with the type of each of
+// its elements, which must decode or skip it.
+func (r *thriftReader) readList(typ byte, element func(typ byte) error) error {
+	if typ != thriftList && typ != thriftSet {
+		return fmt.Errorf("unexpected thrift type %d, expected a list", typ)
+	}
+	if err := r.nest(); err != nil {
+		return err
+	}
+	defer r.unnest()
+	b, err := r.r.ReadByte()
+	if err != nil {
+		return unexpectedEOF(err)
+	}
+	size := uint64(b >> 4)
+	if size == 15 {
+		if size, err = r.readVarint(); err != nil {
+			return err
+		}
+	}
+	if size > maxThriftElements {
+		return fmt.Errorf("thrift list too large: %d elements", size)
+	}
+	for range size {
+		if err := element(b & 0x0f); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readInt decodes an integer field of any size.
+func (r *thriftReader) readInt(typ byte) (int64, error) {
+	switch typ {
+	case thriftByte:
+		b, err := r.r.ReadByte()
+		return int64(int8(b)), unexpectedEOF(err)
+	case thriftI16, thriftI32, thriftI64:
+		v, err := r.readVarint()
+		return zigzag(v), err
+	default:
+		return 0, fmt.Errorf("unexpected thrift type %d, expected an integer", typ)
+	}
+}
+
+// readBool decodes a boolean field, whose value is its type.
+func (r *thriftReader) readBool(typ byte) (bool, error) {
+	switch typ {
+	case thriftTrue:
+		return true, nil
+	case thriftFalse:
+		return false, nil
+	default:
+		return false, fmt.Errorf("unexpected thrift type %d, expected a boolean", typ)
+	}
+}
+
+// readBinary decodes a binary or string field.
+func (r *thriftReader) readBinary(typ byte) ([]byte, error) {
+	if typ != thriftBinary {
+		return nil, fmt.Errorf("unexpected thrift type %d, expected a binary", typ)
+	}
+	size, err := r.readVarint()
+	if err != nil {
+		return nil, err
+	}
+	if size > maxThriftBinary {
+		return nil, fmt.Errorf("thrift binary too large: %d bytes", size)
+	}
+	// the binary grows as it is read, so a truncated one does not allocate
+	// its declared size
+	data := make([]byte, 0, min(size, 4096))
+	for range size {
+		b, err := r.r.ReadByte()
+		if err != nil {
+			return nil, unexpectedEOF(err)
+		}
+		data = append(data, b)
+	}
+	return data, nil
+}
+
+// skip skips a value of the type provided.
+func (r *thriftReader) skip(typ byte) error {
+	switch typ {
+	case thriftTrue, thriftFalse:
+		return nil
+	case thriftByte, thriftI16, thriftI32, thriftI64:
+		_, err := r.readInt(typ)
+		return err
+	case thriftDouble:
+		for range 8 {
+			if _, err := r.r.ReadByte(); err != nil {
+				return unexpectedEOF(err)
+			}
+		}
+		return nil
+	case thriftBinary:
+		_, err := r.readBinary(typ)
+		return err
+	case thriftList, thriftSet:
+		return r.readList(typ, func(typ byte) error {
+			if typ == thriftTrue || typ == thriftFalse {
+				// booleans take a byte inside the collections
+				_, err := r.r.ReadByte()
+				return unexpectedEOF(err)
+			}
+			return r.skip(typ)
+		})
+	case thriftMap:
+		if err := r.nest(); err != nil {
+			return err
+		}
+		defer r.unnest()
+		size, err := r.readVarint()
+		if err != nil || size == 0 {
+			return err
+		}
+		if size > maxThriftElements {
+			return fmt.Errorf("thrift map too large: %d elements", size)
+		}
+		types, err := r.r.ReadByte()
+		if err != nil {
+			return unexpectedEOF(err)
+		}
+		for range size {
+			if err := r.skip(types >> 4); err != nil {
+				return err
+			}
+			if err := r.skip(types & 0x0f); err != nil {
+				return err
+			}
+		}
+		return nil
+	case thriftStruct:
+		return r.readStruct(func(_ int16, typ byte) error {
+			return r.skip(typ)
+		})
+	default:
+		return fmt.Errorf("unknown thrift type %d", typ)
+	}
+}
+
+// nest increments the nesting depth of the values decoded, failing if it
+// exceeds maxThriftDepth, to bound the stack used by malformed values. Every
+// call must be followed by a call to unnest.
+func (r *thriftReader) nest() error {
+	r.depth++
+	if r.depth > maxThriftDepth {
+		r.depth--
+		return fmt.Errorf("thrift values nested too deep")
+	}
+	return nil
+}
+
+// unnest decrements the nesting depth of the values decoded.
+func (r *thriftReader) unnest() {
+	r.depth--
+}
+
+// readVarint decodes an unsigned varint.
+func (r *thriftReader) readVarint() (uint64, error) {
+	v, err := binary.ReadUvarint(r.r)
+	return v, unexpectedEOF(err)
+}
+
+// zigzag decodes a zigzag encoded integer.
+func zigzag(v uint64) int64 {
+	return int64(v>>1) ^ -int64(v&1)
+}
+
+// unexpectedEOF converts io.EOF into io.ErrUnexpectedEOF, since the thrift
+// values are never expected to be truncated.
+func unexpectedEOF(err error) error {
+	if errors.Is(err, io.EOF) {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
@@ -0,0 +1,740 @@
+package census
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	qt "github.com/frankban/quicktest"
+	"github.com/golang/snappy"
+	"github.com/klauspost/compress/zstd"
+	"github.com/vocdoni/davinci-node/types"
+)
+
+// testThriftStruct encodes a struct in the thrift compact protocol, with
+// its fields in increasing order of their ids.
+type testThriftStruct struct {
+	buf    []byte
+	lastID int16
+}
+
+func (s *testThriftStruct) field(id int16, typ byte) {
+	s.buf = append(s.buf, byte(id-s.lastID)<<4|typ)
+	s.lastID = id
+}
+
+func (s *testThriftStruct) i32(id int16, v int64) *testThriftStruct {
+	s.field(id, thriftI32)
+	s.buf = binary.AppendUvarint(s.buf, uint64((v<<1)^(v>>63)))
+	return s
+}
+
+func (s *testThriftStruct) i64(id int16, v int64) *testThriftStruct {
+	s.field(id, thriftI64)
+	s.buf = binary.AppendUvarint(s.buf, uint64((v<<1)^(v>>63)))
+	return s
+}
+
+func (s *testThriftStruct) boolean(id int16, v bool) *testThriftStruct {
+	if v {
+		s.field(id, thriftTrue)
+	} else {
+		s.field(id, thriftFalse)
+	}
+	return s
+}
+
+func (s *testThriftStruct) binary(id int16, v []byte) *testThriftStruct {
+	s.field(id, thriftBinary)
+	s.buf = binary.AppendUvarint(s.buf, uint64(len(v)))
+	s.buf = append(s.buf, v...)
+	return s
+}
+
+func (s *testThriftStruct) strct(id int16, v *testThriftStruct) *testThriftStruct {
+	s.field(id, thriftStruct)
+	s.buf = append(s.buf, v.bytes()...)
+	return s
+}
+
+// list encodes a list of elements of the type provided, already encoded.
+func (s *testThriftStruct) list(id int16, typ byte, elements ...[]byte) *testThriftStruct {
+	s.field(id, thriftList)
+	if len(elements) < 15 {
+		s.buf = append(s.buf, byte(len(elements))<<4|typ)
+	} else {
+		s.buf = append(s.buf, 0xf0|typ)
+		s.buf = binary.AppendUvarint(s.buf, uint64(len(elements)))
+	}
+	for _, element := range elements {
+		s.buf = append(s.buf, element...)
+	}
+	return s
+}
+
+func (s *testThriftStruct) bytes() []byte {
+	return append(bytes.Clone(s.buf), thriftStop)
+}
+
+// testParquetColumn describes a leaf column of a test Parquet file.
+type testParquetColumn struct {
+	name       string
+	typ        int32
+	typeLength int32
+	optional   bool
+	// converted is the converted type, or -1 if none
+	converted int32
+	scale     int32
+	// parent is the name of the group of a nested column
+	parent string
+}
+
+// testParquetChunk is a column chunk of a test Parquet file, with its
+// pages already encoded.
+type testParquetChunk struct {
+	codec     int32
+	numValues int64
+	// dictionary is the encoded dictionary page, if any
+	dictionary []byte
+	pages      [][]byte
+}
+
+// testMakeParquet encodes a Parquet file with the columns and row groups
+// provided, each row group with a chunk per column.
+func testMakeParquet(c *qt.C, columns []testParquetColumn, rowGroups ...[]testParquetChunk) []byte {
+	c.Helper()
+
+	// schema: the root, the groups of the nested columns and the leaves
+	schema := [][]byte{}
+	var topLevel int64
+	for i := 0; i < len(columns); i++ {
+		topLevel++
+		if parent := columns[i].parent; parent != "" {
+			children := 0
+			for j := i; j < len(columns) && columns[j].parent == parent; j++ {
+				children++
+			}
+			schema = append(schema, new(testThriftStruct).
+				i32(3, int64(parquetOptional)).
+				binary(4, []byte(parent)).
+				i32(5, int64(children)).bytes())
+			for j := i; j < i+children; j++ {
+				schema = append(schema, testParquetSchemaElement(columns[j]))
+			}
+			i += children - 1
+			continue
+		}
+		schema = append(schema, testParquetSchemaElement(columns[i]))
+	}
+	root := new(testThriftStruct).binary(4, []byte("schema")).i32(5, topLevel).bytes()
+	schema = append([][]byte{root}, schema...)
+
+	file := []byte(parquetMagic)
+	var encodedRowGroups [][]byte
+	var numRows int64
+	for _, chunks := range rowGroups {
+		c.Assert(chunks, qt.HasLen, len(columns))
+		var encodedChunks [][]byte
+		for i, chunk := range chunks {
+			start := int64(len(file))
+			dataOffset := start
+			if chunk.dictionary != nil {
+				file = append(file, chunk.dictionary...)
+				dataOffset = int64(len(file))
+			}
+			for _, page := range chunk.pages {
+				file = append(file, page...)
+			}
+			metadata := new(testThriftStruct).
+				i32(1, int64(columns[i].typ)).
+				list(2, thriftI32, []byte{byte(parquetPlain << 1)}).
+				list(3, thriftBinary, append([]byte{byte(len(columns[i].name))}, columns[i].name...)).
+				i32(4, int64(chunk.codec)).
+				i64(5, chunk.numValues).
+				i64(6, int64(len(file))-start).
+				i64(7, int64(len(file))-start).
+				i64(9, dataOffset)
+			if chunk.dictionary != nil {
+				metadata.i64(11, start)
+			}
+			encodedChunks = append(encodedChunks, new(testThriftStruct).
+				i64(2, start).
+				strct(3, metadata).bytes())
+		}
+		var rows int64
+		if len(chunks) > 0 {
+			rows = chunks[len(chunks)-1].numValues
+		}
+		numRows += rows
+		encodedRowGroups = append(encodedRowGroups, new(testThriftStruct).
+			list(1, thriftStruct, encodedChunks...).
+			i64(2, 0).
+			i64(3, rows).bytes())
+	}
+	footer := new(testThriftStruct).
+		i32(1, 2).
+		list(2, thriftStruct, schema...).
+		i64(3, numRows).
+		list(4, thriftStruct, encodedRowGroups...).
+		binary(6, []byte("davinci-node test")).bytes()
+	file = append(file, footer...)
+	file = binary.LittleEndian.AppendUint32(file, uint32(len(footer)))
+	return append(file, parquetMagic...)
+}
+
+// testParquetSchemaElement encodes the schema element of a leaf column.
+func testParquetSchemaElement(column testParquetColumn) []byte {
+	element := new(testThriftStruct).i32(1, int64(column.typ))
+	if column.typeLength > 0 {
+		element.i32(2, int64(column.typeLength))
+	}
+	repetition := parquetRequired
+	if column.optional {
+		repetition = parquetOptional
+	}
+	element.i32(3, int64(repetition)).binary(4, []byte(column.name))
+	if column.converted >= 0 {
+		element.i32(6, int64(column.converted))
+	}
+	if column.converted == parquetConvertedDecimal {
+		element.i32(7, int64(column.scale)).i32(8, 38)
+	}
+	return element.bytes()
+}
+
+// testCompress compresses data with the codec provided.
+func testCompress(c *qt.C, codec int32, data []byte) []byte {
+	c.Helper()
+	switch codec {
+	case parquetSnappy:
+		return snappy.Encode(nil, data)
+	case parquetGzip:
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		_, err := zw.Write(data)
+		c.Assert(err, qt.IsNil)
+		c.Assert(zw.Close(), qt.IsNil)
+		return buf.Bytes()
+	case parquetZstd:
+		zw, err := zstd.NewWriter(nil)
+		c.Assert(err, qt.IsNil)
+		defer func() { c.Assert(zw.Close(), qt.IsNil) }()
+		return zw.EncodeAll(data, nil)
+	default:
+		return data
+	}
+}
+
+// testDataPage encodes a data page v1. The definition levels are only set
+// for optional columns.
+func testDataPage(c *qt.C, codec int32, numValues int, encoding int32, levels, values []byte) []byte {
+	c.Helper()
+	var body []byte
+	if levels != nil {
+		body = binary.LittleEndian.AppendUint32(body, uint32(len(levels)))
+		body = append(body, levels...)
+	}
+	body = append(body, values...)
+	compressed := testCompress(c, codec, body)
+	header := new(testThriftStruct).
+		i32(1, int64(parquetDataPage)).
+		i32(2, int64(len(body))).
+		i32(3, int64(len(compressed))).
+		strct(5, new(testThriftStruct).
+			i32(1, int64(numValues)).
+			i32(2, int64(encoding)).
+			i32(3, int64(parquetRLE)).
+			i32(4, int64(parquetRLE))).bytes()
+	return append(header, compressed...)
+}
+
+// testDataPageV2 encodes a data page v2, whose levels are not compressed.
+func testDataPageV2(c *qt.C, codec int32, numValues, numNulls int, encoding int32, levels, values []byte) []byte {
+	c.Helper()
+	compressed := testCompress(c, codec, values)
+	header := new(testThriftStruct).
+		i32(1, int64(parquetDataPageV2)).
+		i32(2, int64(len(levels)+len(values))).
+		i32(3, int64(len(levels)+len(compressed))).
+		strct(8, new(testThriftStruct).
+			i32(1, int64(numValues)).
+			i32(2, int64(numNulls)).
+			i32(3, int64(numValues)).
+			i32(4, int64(encoding)).
+			i32(5, int64(len(levels))).
+			i32(6, 0).
+			boolean(7, codec != parquetUncompressed)).bytes()
+	page := append(header, levels...)
+	return append(page, compressed...)
+}
+
+// testDictionaryPage encodes a dictionary page of PLAIN values.
+func testDictionaryPage(c *qt.C, codec int32, numValues int, values []byte) []byte {
+	c.Helper()
+	compressed := testCompress(c, codec, values)
+	header := new(testThriftStruct).
+		i32(1, int64(parquetDictionaryPage)).
+		i32(2, int64(len(values))).
+		i32(3, int64(len(compressed))).
+		strct(7, new(testThriftStruct).
+			i32(1, int64(numValues)).
+			i32(2, int64(parquetPlain))).bytes()
+	return append(header, compressed...)
+}
+
+// testPlainBytes encodes byte arrays in the PLAIN encoding.
+func testPlainBytes(values ...[]byte) []byte {
+	var data []byte
+	for _, value := range values {
+		data = binary.LittleEndian.AppendUint32(data, uint32(len(value)))
+		data = append(data, value...)
+	}
+	return data
+}
+
+// testPlainStrings encodes strings in the PLAIN encoding.
+func testPlainStrings(values ...string) []byte {
+	var data []byte
+	for _, value := range values {
+		data = append(data, testPlainBytes([]byte(value))...)
+	}
+	return data
+}
+
+// testPlainInt64 encodes 64-bit integers in the PLAIN encoding.
+func testPlainInt64(values ...int64) []byte {
+	var data []byte
+	for _, value := range values {
+		data = binary.LittleEndian.AppendUint64(data, uint64(value))
+	}
+	return data
+}
+
+// testPlainInt32 encodes 32-bit integers in the PLAIN encoding.
+func testPlainInt32(values ...int32) []byte {
+	var data []byte
+	for _, value := range values {
+		data = binary.LittleEndian.AppendUint32(data, uint32(value))
+	}
+	return data
+}
+
+// testBitPacked encodes values in a bit-packed run of the RLE and
+// bit-packing hybrid encoding.
+func testBitPacked(bitWidth int, values ...uint64) []byte {
+	groups := (len(values) + 7) / 8
+	data := binary.AppendUvarint(nil, uint64(groups)<<1|1)
+	packed := make([]byte, groups*bitWidth)
+	for i, value := range values {
+		for bit := range bitWidth {
+			if value>>bit&1 == 1 {
+				pos := i*bitWidth + bit
+				packed[pos/8] |= 1 << (pos % 8)
+			}
+		}
+	}
+	return append(data, packed...)
+}
+
+// testRLERun encodes a value repeated in a RLE run of the RLE and
+// bit-packing hybrid encoding.
+func testRLERun(bitWidth, count int, value uint64) []byte {
+	data := binary.AppendUvarint(nil, uint64(count)<<1)
+	for i := range (bitWidth + 7) / 8 {
+		data = append(data, byte(value>>(8*i)))
+	}
+	return data
+}
+
+// testReadParquetRows opens the Parquet file provided and reads every row of
+// its address and weight columns.
+func testReadParquetRows(c *qt.C, data []byte) []testTabularRow {
+	c.Helper()
+	file, err := openParquetFile(bytes.NewReader(data), int64(len(data)))
+	c.Assert(err, qt.IsNil)
+	rows, err := newParquetRowReader(file, DefaultAddressColumn, DefaultWeightColumn)
+	c.Assert(err, qt.IsNil)
+	defer rows.close()
+	var read []testTabularRow
+	for {
+		address, weight, err := rows.Next()
+		if errors.Is(err, io.EOF) {
+			return read
+		}
+		c.Assert(err, qt.IsNil)
+		read = append(read, testTabularRow{Address: address, Weight: weight})
+	}
+}
+
+var (
+	testParquetAddressColumn = testParquetColumn{name: "address", typ: parquetByteArray, converted: parquetConvertedUTF8}
+	testParquetWeightColumn  = testParquetColumn{name: "weight", typ: parquetInt64, converted: -1}
+)
+
+func TestParquetReader(t *testing.T) {
+	c := qt.New(t)
+
+	addresses := []string{"0x01", "0x02", "0x03", "0x04"}
+
+	c.Run("PlainRequired", func(c *qt.C) {
+		data := testMakeParquet(c,
+			[]testParquetColumn{testParquetAddressColumn, testParquetWeightColumn},
+			[]testParquetChunk{
+				{numValues: 4, pages: [][]byte{
+					testDataPage(c, parquetUncompressed, 3, parquetPlain, nil, testPlainStrings(addresses[:3]...)),
+					testDataPage(c, parquetUncompressed, 1, parquetPlain, nil, testPlainStrings(addresses[3])),
+				}},
+				{numValues: 4, pages: [][]byte{
+					testDataPage(c, parquetUncompressed, 4, parquetPlain, nil, testPlainInt64(1, 2, 3, 1<<40)),
+				}},
+			})
+		c.Assert(testReadParquetRows(c, data), qt.DeepEquals, []testTabularRow{
+			{"0x01", "1"}, {"0x02", "2"}, {"0x03", "3"}, {"0x04", "1099511627776"},
+		})
+	})
+
+	c.Run("OptionalSnappyV1AndV2", func(c *qt.C) {
+		address := testParquetAddressColumn
+		address.optional = true
+		weight := testParquetColumn{name: "Weight", typ: parquetByteArray, converted: parquetConvertedUTF8, optional: true}
+		data := testMakeParquet(c,
+			[]testParquetColumn{address, weight},
+			[]testParquetChunk{
+				{codec: parquetSnappy, numValues: 4, pages: [][]byte{
+					testDataPage(c, parquetSnappy, 4, parquetPlain, testBitPacked(1, 1, 0, 1, 1), testPlainStrings("0x01", "0x03", "0x04")),
+				}},
+				{codec: parquetSnappy, numValues: 4, pages: [][]byte{
+					testDataPageV2(c, parquetSnappy, 4, 1, parquetPlain, testBitPacked(1, 1, 1, 1, 0), testPlainStrings("10", "20", "30")),
+				}},
+			})
+		c.Assert(testReadParquetRows(c, data), qt.DeepEquals, []testTabularRow{
+			{"0x01", "10"}, {"", "20"}, {"0x03", "30"}, {"0x04", ""},
+		})
+	})
+
+	c.Run("DictionaryGzip", func(c *qt.C) {
+		weight := testParquetColumn{name: "weight", typ: parquetInt32, converted: -1}
+		indexes := append([]byte{2}, testBitPacked(2, 3, 2, 1, 0)...)
+		data := testMakeParquet(c,
+			[]testParquetColumn{testParquetAddressColumn, weight},
+			[]testParquetChunk{
+				{codec: parquetGzip, numValues: 4,
+					dictionary: testDictionaryPage(c, parquetGzip, 4, testPlainStrings(addresses...)),
+					pages: [][]byte{
+						testDataPage(c, parquetGzip, 4, parquetRLEDictionary, nil, indexes),
+					}},
+				{codec: parquetGzip, numValues: 4,
+					dictionary: testDictionaryPage(c, parquetGzip, 1, testPlainInt32(7)),
+					pages: [][]byte{
+						testDataPage(c, parquetGzip, 4, parquetPlainDictionary, nil, append([]byte{1}, testRLERun(1, 4, 0)...)),
+					}},
+			})
+		c.Assert(testReadParquetRows(c, data), qt.DeepEquals, []testTabularRow{
+			{"0x04", "7"}, {"0x03", "7"}, {"0x02", "7"}, {"0x01", "7"},
+		})
+	})
+
+	c.Run("RawAddressesDecimalsNestedZstd", func(c *qt.C) {
+		columns := []testParquetColumn{
+			{name: "street", typ: parquetByteArray, converted: parquetConvertedUTF8, parent: "location"},
+			{name: "city", typ: parquetByteArray, converted: parquetConvertedUTF8, parent: "location"},
+			{name: "address", typ: parquetFixedLenByteArray, typeLength: common.AddressLength, converted: -1},
+			{name: "weight", typ: parquetFixedLenByteArray, typeLength: 12, converted: parquetConvertedDecimal},
+		}
+		addr1, addr2 := common.HexToAddress("0x01"), common.HexToAddress("0x02")
+		weight := new(big.Int).Lsh(big.NewInt(1), 80).FillBytes(make([]byte, 12))
+		chunk := func(values []byte) testParquetChunk {
+			return testParquetChunk{codec: parquetZstd, numValues: 1, pages: [][]byte{
+				testDataPage(c, parquetZstd, 1, parquetPlain, nil, values),
+			}}
+		}
+		data := testMakeParquet(c, columns,
+			[]testParquetChunk{{}, {}, chunk(addr1.Bytes()), chunk(weight)},
+			[]testParquetChunk{{}, {}, chunk(addr2.Bytes()), chunk(make([]byte, 12))},
+		)
+		c.Assert(testReadParquetRows(c, data), qt.DeepEquals, []testTabularRow{
+			{"0x" + common.Bytes2Hex(addr1.Bytes()), new(big.Int).Lsh(big.NewInt(1), 80).String()},
+			{"0x" + common.Bytes2Hex(addr2.Bytes()), "0"},
+		})
+	})
+
+	c.Run("DifferentLengths", func(c *qt.C) {
+		data := testMakeParquet(c,
+			[]testParquetColumn{testParquetAddressColumn, testParquetWeightColumn},
+			[]testParquetChunk{
+				{numValues: 2, pages: [][]byte{
+					testDataPage(c, parquetUncompressed, 2, parquetPlain, nil, testPlainStrings(addresses[:2]...)),
+				}},
+				{numValues: 1, pages: [][]byte{
+					testDataPage(c, parquetUncompressed, 1, parquetPlain, nil, testPlainInt64(1)),
+				}},
+			})
+		file, err := openParquetFile(bytes.NewReader(data), int64(len(data)))
+		c.Assert(err, qt.IsNil)
+		rows, err := newParquetRowReader(file, DefaultAddressColumn, DefaultWeightColumn)
+		c.Assert(err, qt.IsNil)
+		defer rows.close()
+		_, _, err = rows.Next()
+		c.Assert(err, qt.IsNil)
+		_, _, err = rows.Next()
+		c.Assert(err, qt.ErrorMatches, `columns "address" and "weight" have a different number of values`)
+	})
+
+	c.Run("UnsupportedColumns", func(c *qt.C) {
+		scaled := testParquetColumn{name: "weight", typ: parquetInt64, converted: parquetConvertedDecimal, scale: 2}
+		double := testParquetColumn{name: "weight", typ: parquetDouble, converted: -1}
+		for _, tc := range []struct {
+			columns []testParquetColumn
+			err     string
+		}{
+			{[]testParquetColumn{testParquetWeightColumn}, `missing "address" column, found columns: weight`},
+			{[]testParquetColumn{testParquetAddressColumn}, `missing "weight" column, found columns: address`},
+			{[]testParquetColumn{testParquetAddressColumn, scaled}, `column "weight" must hold integers, it has a decimal scale of 2`},
+			{[]testParquetColumn{testParquetAddressColumn, double}, `column "weight" must hold integers, decimal strings or decimals`},
+		} {
+			data := testMakeParquet(c, tc.columns)
+			file, err := openParquetFile(bytes.NewReader(data), int64(len(data)))
+			c.Assert(err, qt.IsNil)
+			_, err = newParquetRowReader(file, DefaultAddressColumn, DefaultWeightColumn)
+			c.Assert(err, qt.ErrorMatches, tc.err)
+		}
+	})
+
+	c.Run("NotParquet", func(c *qt.C) {
+		data := []byte("address,weight\n0x01,1\n")
+		_, err := openParquetFile(bytes.NewReader(data), int64(len(data)))
+		c.Assert(err, qt.ErrorMatches, "not a parquet file")
+
+		data = append([]byte(parquetMagic), 0xff, 0xff, 0xff, 0x7f)
+		data = append(data, parquetMagic...)
+		_, err = openParquetFile(bytes.NewReader(data), int64(len(data)))
+		c.Assert(err, qt.ErrorMatches, "invalid parquet footer size.*")
+	})
+}
+
+func TestDecodeRLEHybrid(t *testing.T) {
+	c := qt.New(t)
+
+	data := append(testRLERun(3, 3, 5), testBitPacked(3, 0, 1, 2, 3, 4, 5, 6, 7, 1)...)
+	values, err := decodeRLEHybrid(data, 3, 12)
+	c.Assert(err, qt.IsNil)
+	c.Assert(values, qt.DeepEquals, []uint64{5, 5, 5, 0, 1, 2, 3, 4, 5, 6, 7, 1})
+
+	// the padding of the last bit-packed group is ignored
+	values, err = decodeRLEHybrid(testBitPacked(1, 1, 0, 1), 1, 3)
+	c.Assert(err, qt.IsNil)
+	c.Assert(values, qt.DeepEquals, []uint64{1, 0, 1})
+
+	values, err = decodeRLEHybrid(testRLERun(17, 2, 1<<16), 17, 2)
+	c.Assert(err, qt.IsNil)
+	c.Assert(values, qt.DeepEquals, []uint64{1 << 16, 1 << 16})
+
+	_, err = decodeRLEHybrid(testRLERun(3, 3, 5), 3, 4)
+	c.Assert(err, qt.ErrorMatches, "truncated run header")
+	_, err = decodeRLEHybrid(testBitPacked(8, 1, 2)[:3], 8, 2)
+	c.Assert(err, qt.ErrorMatches, "truncated bit-packed run")
+	_, err = decodeRLEHybrid(nil, 33, 1)
+	c.Assert(err, qt.ErrorMatches, "invalid bit width 33")
+}
+
+func TestParquetLimits(t *testing.T) {
+	c := qt.New(t)
+
+	// nested lists and maps count towards the thrift depth
+	nested := bytes.Repeat([]byte{0x19}, maxThriftDepth+1)
+	err := newThriftReader(bytes.NewReader(nested)).skip(thriftList)
+	c.Assert(err, qt.ErrorMatches, "thrift values nested too deep")
+
+	// the declared sizes are checked before reading the elements
+	list := append([]byte{0xfc}, binary.AppendUvarint(nil, maxThriftElements+1)...)
+	err = newThriftReader(bytes.NewReader(list)).skip(thriftList)
+	c.Assert(err, qt.ErrorMatches, "thrift list too large: .*")
+	truncated := binary.AppendUvarint(nil, maxThriftBinary)
+	_, err = newThriftReader(bytes.NewReader(truncated)).readBinary(thriftBinary)
+	c.Assert(err, qt.ErrorIs, io.ErrUnexpectedEOF)
+
+	header := func(numValues, uncompressedSize int64) []byte {
+		return new(testThriftStruct).
+			i32(1, int64(parquetDataPage)).
+			i32(2, uncompressedSize).
+			i32(3, 0).
+			strct(5, new(testThriftStruct).i32(1, numValues)).bytes()
+	}
+	_, err = readParquetPageHeader(newThriftReader(bytes.NewReader(header(maxParquetPageValues+1, 0))))
+	c.Assert(err, qt.ErrorMatches, "invalid number of page values")
+	_, err = readParquetPageHeader(newThriftReader(bytes.NewReader(header(1, maxParquetPageSize+1))))
+	c.Assert(err, qt.ErrorMatches, "invalid page sizes .*")
+
+	// the pages can not be larger than their column chunk, nor decompress to
+	// more bytes than declared
+	oversized := new(testThriftStruct).
+		i32(1, int64(parquetDataPage)).
+		i32(2, 8).
+		i32(3, 1<<20).
+		strct(5, new(testThriftStruct).i32(1, 1).i32(2, int64(parquetPlain))).bytes()
+	data := testMakeParquet(c,
+		[]testParquetColumn{testParquetAddressColumn, testParquetWeightColumn},
+		[]testParquetChunk{
+			{numValues: 1, pages: [][]byte{testDataPage(c, parquetUncompressed, 1, parquetPlain, nil, testPlainStrings("0x01"))}},
+			{numValues: 1, pages: [][]byte{append(oversized, testPlainInt64(1)...)}},
+		})
+	err = testReadParquetError(c, data)
+	c.Assert(err, qt.ErrorMatches, "page of column weight is larger than its column chunk")
+
+	values := testPlainInt64(1)
+	snappyPage := append(new(testThriftStruct).
+		i32(1, int64(parquetDataPage)).
+		i32(2, int64(len(values))-1).
+		i32(3, int64(len(snappy.Encode(nil, values)))).
+		strct(5, new(testThriftStruct).i32(1, 1).i32(2, int64(parquetPlain))).bytes(),
+		snappy.Encode(nil, values)...)
+	data = testMakeParquet(c,
+		[]testParquetColumn{testParquetAddressColumn, testParquetWeightColumn},
+		[]testParquetChunk{
+			{numValues: 1, pages: [][]byte{testDataPage(c, parquetUncompressed, 1, parquetPlain, nil, testPlainStrings("0x01"))}},
+			{codec: parquetSnappy, numValues: 1, pages: [][]byte{snappyPage}},
+		})
+	err = testReadParquetError(c, data)
+	c.Assert(err, qt.ErrorMatches, "page of column weight has 8 bytes, expected 7")
+}
+
+// testReadParquetError reads every row of the Parquet file provided, and
+// returns the error that stopped the reading.
+func testReadParquetError(c *qt.C, data []byte) error {
+	c.Helper()
+	file, err := openParquetFile(bytes.NewReader(data), int64(len(data)))
+	c.Assert(err, qt.IsNil)
+	rows, err := newParquetRowReader(file, DefaultAddressColumn, DefaultWeightColumn)
+	c.Assert(err, qt.IsNil)
+	defer rows.close()
+	for {
+		if _, _, err := rows.Next(); err != nil {
+			return err
+		}
+	}
+}
+
+func FuzzParquetReader(f *testing.F) {
+	c := qt.New(f)
+	optional := testParquetAddressColumn
+	optional.optional = true
+	f.Add(testMakeParquet(c,
+		[]testParquetColumn{testParquetAddressColumn, testParquetWeightColumn},
+		[]testParquetChunk{
+			{numValues: 2, pages: [][]byte{testDataPage(c, parquetUncompressed, 2, parquetPlain, nil, testPlainStrings("0x01", "0x02"))}},
+			{numValues: 2, pages: [][]byte{testDataPage(c, parquetUncompressed, 2, parquetPlain, nil, testPlainInt64(1, 2))}},
+		}))
+	f.Add(testMakeParquet(c,
+		[]testParquetColumn{optional, {name: "weight", typ: parquetInt32, converted: -1}},
+		[]testParquetChunk{
+			{codec: parquetSnappy, numValues: 2, pages: [][]byte{
+				testDataPageV2(c, parquetSnappy, 2, 1, parquetPlain, testBitPacked(1, 1, 0), testPlainStrings("0x01")),
+			}},
+			{codec: parquetGzip, numValues: 2,
+				dictionary: testDictionaryPage(c, parquetGzip, 1, testPlainInt32(7)),
+				pages: [][]byte{
+					testDataPage(c, parquetGzip, 2, parquetRLEDictionary, nil, append([]byte{1}, testRLERun(1, 2, 0)...)),
+				}},
+		}))
+	f.Fuzz(func(t *testing.T, data []byte) {
+		// malformed files must fail without panicking nor exhausting the
+		// memory, and every column must be readable to the end
+		file, err := openParquetFile(bytes.NewReader(data), int64(len(data)))
+		if err != nil {
+			return
+		}
+		for _, column := range file.columns {
+			cr := file.newParquetColumnReader(column)
+			for {
+				if _, err := cr.next(); err != nil {
+					break
+				}
+			}
+			cr.close()
+		}
+	})
+}
+
+func TestParquetImportCensus(t *testing.T) {
+	c := qt.New(t)
+
+	rows, root := testMakeTabularCensus(c, 10)
+	addresses := make([][]byte, 0, len(rows))
+	weights := make([]int64, 0, len(rows))
+	for _, row := range rows {
+		addresses = append(addresses, common.HexToAddress(row.Address).Bytes())
+		weight, ok := new(big.Int).SetString(row.Weight, 10)
+		c.Assert(ok, qt.IsTrue)
+		weights = append(weights, weight.Int64())
+	}
+	// raw addresses in a column with a custom name
+	address := testParquetColumn{name: "wallet", typ: parquetByteArray, converted: -1}
+	data := testMakeParquet(c,
+		[]testParquetColumn{address, testParquetWeightColumn},
+		[]testParquetChunk{
+			{codec: parquetSnappy, numValues: 10, pages: [][]byte{
+				testDataPage(c, parquetSnappy, 10, parquetPlain, nil, testPlainBytes(addresses...)),
+			}},
+			{codec: parquetSnappy, numValues: 10, pages: [][]byte{
+				testDataPage(c, parquetSnappy, 10, parquetPlain, nil, testPlainInt64(weights...)),
+			}},
+		})
+	dir := c.TempDir()
+	c.Assert(os.WriteFile(filepath.Join(dir, "census.parquet"), data, 0o600), qt.IsNil)
+
+	c.Run("LocalFile", func(c *qt.C) {
+		censusDB := testNewCensusDB(c)
+		importer := ParquetImporter(&ParquetImporterConfig{FileDir: dir})
+		c.Assert(importer.ValidURI("file:census.parquet#address=wallet"), qt.IsTrue)
+		c.Assert(ParquetImporter(nil).ValidURI("file:census.parquet"), qt.IsFalse)
+
+		imported, err := importer.ImportCensus(c.Context(), censusDB, 0, &types.Census{
+			CensusURI:  "file:census.parquet#address=wallet",
+			CensusRoot: root,
+		}, 0)
+		c.Assert(err, qt.IsNil)
+		c.Assert(imported, qt.Equals, len(rows))
+		c.Assert(censusDB.ExistsByRoot(root), qt.IsTrue)
+	})
+
+	c.Run("Download", func(c *qt.C) {
+		censusDB := testNewCensusDB(c)
+		oldTransport := http.DefaultTransport
+		http.DefaultTransport = roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Header:     http.Header{},
+				Body:       io.NopCloser(bytes.NewReader(data)),
+				Request:    r,
+			}, nil
+		})
+		c.Cleanup(func() { http.DefaultTransport = oldTransport })
+
+		importer := ParquetImporter(&ParquetImporterConfig{AddressColumn: "wallet"})
+		c.Assert(importer.ValidURI("https://example.invalid/census.parquet"), qt.IsTrue)
+		imported, err := importer.ImportCensus(c.Context(), censusDB, 0, &types.Census{
+			CensusURI:  "https://example.invalid/census.parquet",
+			CensusRoot: root,
+		}, 0)
+		c.Assert(err, qt.IsNil)
+		c.Assert(imported, qt.Equals, len(rows))
+		c.Assert(censusDB.ExistsByRoot(root), qt.IsTrue)
+	})
+
+	c.Run("MissingColumn", func(c *qt.C) {
+		censusDB := testNewCensusDB(c)
+		_, err := ParquetImporter(&ParquetImporterConfig{FileDir: dir}).ImportCensus(c.Context(), censusDB, 0, &types.Census{
+			CensusURI:  "file:census.parquet",
+			CensusRoot: root,
+		}, 0)
+		c.Assert(err, qt.ErrorMatches, `invalid Parquet census file .*: missing "address" column, found columns: wallet, weight`)
+	})
+}
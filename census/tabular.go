@@ -0,0 +1,424 @@
+package census
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/google/uuid"
+	"github.com/vocdoni/davinci-node/census/censusdb"
+	"github.com/vocdoni/davinci-node/log"
+	"github.com/vocdoni/davinci-node/types"
+)
+
+const (
+	// DefaultAddressColumn is the default name of the column of the voter
+	// addresses in the tabular census files (CSV and Parquet).
+	DefaultAddressColumn = "address"
+	// DefaultWeightColumn is the default name of the column of the voter
+	// weights in the tabular census files (CSV and Parquet).
+	DefaultWeightColumn = "weight"
+	// MaxWeightBits is the maximum size of the weights of the census, which
+	// are packed with the addresses in the census tree leaves.
+	MaxWeightBits = 88
+
+	// tabularBatchSize is the number of rows of a tabular census file
+	// inserted at once into the census tree.
+	tabularBatchSize = 1000
+	// maxReportedRowErrors is the maximum number of invalid rows listed by
+	// InvalidRowsError.
+	maxReportedRowErrors = 100
+	// maxReportedValueLen is the maximum length of the values of the invalid
+	// rows listed by InvalidRowsError.
+	maxReportedValueLen = 66
+)
+
+// RowError describes an invalid row of a tabular census file.
+type RowError struct {
+	// Row is the 1-based position of the row in the file, without counting
+	// the header.
+	Row     int    `json:"row"`
+	Address string `json:"address,omitempty"`
+	Reason  string `json:"reason"`
+}
+
+// String returns the description of the invalid row.
+func (e RowError) String() string {
+	if e.Address == "" {
+		return fmt.Sprintf("row %d: %s", e.Row, e.Reason)
+	}
+	return fmt.Sprintf("row %d (%s): %s", e.Row, e.Address, e.Reason)
+}
+
+// InvalidRowsError is returned when a tabular census file has invalid rows,
+// like malformed addresses, invalid weights or duplicated addresses. The
+// file is not imported at all, and Rows lists the first invalid rows so
+// they can be fixed at once.
+type InvalidRowsError struct {
+	URI   string     `json:"uri"`
+	Total int        `json:"total"`
+	Rows  []RowError `json:"rows"`
+}
+
+// Error returns the report of the invalid rows, one per line.
+func (e *InvalidRowsError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "census file %s has %d invalid rows", e.URI, e.Total)
+	for _, row := range e.Rows {
+		b.WriteString("\n  ")
+		b.WriteString(row.String())
+	}
+	if e.Total > len(e.Rows) {
+		fmt.Fprintf(&b, "\n  ... and %d more", e.Total-len(e.Rows))
+	}
+	return b.String()
+}
+
+// add records an invalid row.
+func (e *InvalidRowsError) add(row int, address, reason string) {
+	e.Total++
+	if len(e.Rows) >= maxReportedRowErrors {
+		return
+	}
+	if len(address) > maxReportedValueLen {
+		address = address[:maxReportedValueLen] + "..."
+	}
+	e.Rows = append(e.Rows, RowError{Row: row, Address: address, Reason: reason})
+}
+
+// censusRowReader reads the rows of a tabular census file.
+type censusRowReader interface {
+	// Next returns the address and the weight of the next row, as they are
+	// written in the file. It returns io.EOF after the last row.
+	Next() (address, weight string, err error)
+}
+
+// tabularURIOptions are the options of a tabular census file that can be
+// set in the fragment of its URI as a query string, e.g.
+// https://example.com/census.csv#address=wallet&weight=votes
+type tabularURIOptions struct {
+	addressColumn string
+	weightColumn  string
+	delimiter     string
+}
+
+// parseTabularURI parses the URI of a tabular census file, and returns the
+// extension of its path, in lower case, and the options of its fragment.
+func parseTabularURI(targetURI string) (string, *tabularURIOptions, error) {
+	u, err := url.Parse(targetURI)
+	if err != nil {
+		return "", nil, err
+	}
+	options := &tabularURIOptions{}
+	if u.Fragment != "" {
+		values, err := url.ParseQuery(u.Fragment)
+		if err != nil {
+			return "", nil, fmt.Errorf("invalid URI options: %w", err)
+		}
+		options.addressColumn = values.Get("address")
+		options.weightColumn = values.Get("weight")
+		options.delimiter = values.Get("delimiter")
+	}
+	return strings.ToLower(path.Ext(u.Path)), options, nil
+}
+
+// validTabularURI reports whether the URI provided is an HTTP, HTTPS or, if
+// files are allowed, file URI of a file with one of the extensions provided.
+func validTabularURI(targetURI string, allowFiles bool, extensions ...string) bool {
+	u, err := url.Parse(targetURI)
+	if err != nil {
+		return false
+	}
+	switch u.Scheme {
+	case "http", "https":
+	case "file":
+		if !allowFiles {
+			return false
+		}
+	default:
+		return false
+	}
+	ext, _, err := parseTabularURI(targetURI)
+	if err != nil {
+		return false
+	}
+	for _, valid := range extensions {
+		if ext == valid {
+			return true
+		}
+	}
+	return false
+}
+
+// openTabularFile opens the census file of the URI provided, downloading it
+// if it is an HTTP or HTTPS URI. The file URIs must point to a file inside
+// the directory provided, and are rejected if it is empty.
+func openTabularFile(ctx context.Context, targetURI, fileDir string) (io.ReadCloser, error) {
+	u, err := url.Parse(targetURI)
+	if err != nil {
+		return nil, fmt.Errorf("invalid census file URI %s: %w", targetURI, err)
+	}
+	if u.Scheme == "file" {
+		filePath, err := tabularFilePath(u, fileDir)
+		if err != nil {
+			return nil, err
+		}
+		return os.Open(filePath)
+	}
+	u.Fragment = ""
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create HTTP request for %s: %w", u.String(), err)
+	}
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download census file from %s: %w", u.String(), err)
+	}
+	if res.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(res.Body, 1024))
+		_ = res.Body.Close()
+		return nil, fmt.Errorf("failed to download census file from %s: status code %d, body: %s", u.String(), res.StatusCode, string(body))
+	}
+	return res.Body, nil
+}
+
+// tabularFilePath returns the path of the file of a file URI, checking that
+// it is inside the directory provided.
+func tabularFilePath(u *url.URL, fileDir string) (string, error) {
+	if fileDir == "" {
+		return "", fmt.Errorf("file census URIs are not allowed")
+	}
+	if u.Host != "" && u.Host != "localhost" {
+		return "", fmt.Errorf("file census URI with a remote host %s", u.Host)
+	}
+	dir, err := filepath.Abs(fileDir)
+	if err != nil {
+		return "", fmt.Errorf("invalid census file directory: %w", err)
+	}
+	filePath := u.Path
+	if u.Opaque != "" {
+		// relative paths, e.g. file:census.csv
+		filePath = u.Opaque
+	}
+	filePath = filepath.Clean(filepath.FromSlash(filePath))
+	if !filepath.IsAbs(filePath) {
+		filePath = filepath.Join(dir, filePath)
+	}
+	if rel, err := filepath.Rel(dir, filePath); err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("census file %s is outside of the census file directory", filePath)
+	}
+	return filePath, nil
+}
+
+// equalColumnName reports whether the name of a column matches the name
+// expected, ignoring the case and the surrounding spaces.
+func equalColumnName(name, expected string) bool {
+	return strings.EqualFold(strings.TrimSpace(name), strings.TrimSpace(expected))
+}
+
+// importCensusRows imports the rows of a tabular census file into the census
+// DB, checking that they lead to the census root. The rows are inserted in a
+// working census, that is only published once every row is imported, so an
+// import interrupted can be resumed: the from argument is the number of rows
+// imported by a previous attempt, returned by it along with its error. If the
+// working census does not hold those rows, the import starts over. Every row
+// is validated, and if any is invalid, the working census is discarded and an
+// InvalidRowsError is returned.
+func importCensusRows(
+	ctx context.Context,
+	censusDB *censusdb.CensusDB,
+	chainID uint64,
+	census *types.Census,
+	from int,
+	rows censusRowReader,
+) (int, error) {
+	workingID := tabularWorkingCensusID(census)
+	ref, imported, err := loadWorkingCensus(censusDB, workingID, from)
+	if err != nil {
+		return 0, err
+	}
+	report := &InvalidRowsError{URI: census.CensusURI}
+	discard := func() {
+		if err := censusDB.CleanupWorkingCensus(workingID); err != nil {
+			log.Warnw("failed to clean up working census",
+				"uri", census.CensusURI,
+				"error", err.Error())
+		}
+	}
+
+	var (
+		keys    []types.HexBytes
+		values  []types.HexBytes
+		pending = map[common.Address]int{}
+		row     = 0
+	)
+	flush := func() error {
+		if len(keys) == 0 {
+			return nil
+		}
+		if _, err := ref.InsertBatch(keys, values); err != nil {
+			return fmt.Errorf("failed to insert census rows: %w", err)
+		}
+		imported += len(keys)
+		keys, values = keys[:0], values[:0]
+		clear(pending)
+		return nil
+	}
+	for {
+		if err := ctx.Err(); err != nil {
+			return imported, err
+		}
+		rawAddress, rawWeight, err := rows.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return imported, fmt.Errorf("failed to read census file %s: %w", census.CensusURI, err)
+		}
+		row++
+		// skip the rows imported by a previous attempt
+		if row <= imported {
+			continue
+		}
+		address, weight, reason := parseCensusRow(rawAddress, rawWeight)
+		if reason != "" {
+			report.add(row, rawAddress, reason)
+			continue
+		}
+		if first, ok := pending[address]; ok {
+			report.add(row, rawAddress, fmt.Sprintf("duplicated address, first found at row %d", first))
+			continue
+		}
+		if ref.Tree().Has(address) {
+			first := "a previous row"
+			if proof, err := ref.GenProof(address.Bytes()); err == nil {
+				first = fmt.Sprintf("row %d", int(proof.VoterIndex)+1)
+			}
+			report.add(row, rawAddress, "duplicated address, first found at "+first)
+			continue
+		}
+		pending[address] = row
+		if report.Total > 0 {
+			// the file will not be imported, keep validating its rows
+			continue
+		}
+		keys = append(keys, address.Bytes())
+		values = append(values, weight.Bytes())
+		if len(keys) >= tabularBatchSize {
+			if err := flush(); err != nil {
+				return imported, err
+			}
+		}
+	}
+	if err := flush(); err != nil {
+		return imported, err
+	}
+	if report.Total > 0 {
+		discard()
+		return 0, report
+	}
+	if imported == 0 {
+		discard()
+		return 0, fmt.Errorf("census file %s has no rows", census.CensusURI)
+	}
+	if root := ref.Root(); !root.Equal(census.CensusRoot) {
+		discard()
+		return 0, fmt.Errorf("imported census root mismatch: expected %s, got %s", census.CensusRoot.String(), root.String())
+	}
+	if err := publishWorkingCensus(censusDB, chainID, census, workingID); err != nil {
+		return 0, err
+	}
+	return imported, nil
+}
+
+// parseCensusRow parses the address and weight of a row. If the row is
+// invalid, it returns the reason.
+func parseCensusRow(rawAddress, rawWeight string) (common.Address, *big.Int, string) {
+	rawAddress, rawWeight = strings.TrimSpace(rawAddress), strings.TrimSpace(rawWeight)
+	if rawAddress == "" {
+		return common.Address{}, nil, "missing address"
+	}
+	if !common.IsHexAddress(rawAddress) {
+		return common.Address{}, nil, "invalid address"
+	}
+	if rawWeight == "" {
+		return common.Address{}, nil, "missing weight"
+	}
+	weight, ok := new(big.Int).SetString(rawWeight, 10)
+	if !ok {
+		return common.Address{}, nil, fmt.Sprintf("invalid weight %q", rawWeight)
+	}
+	if weight.Sign() <= 0 {
+		return common.Address{}, nil, fmt.Sprintf("weight %s must be positive", rawWeight)
+	}
+	if weight.BitLen() > MaxWeightBits {
+		return common.Address{}, nil, fmt.Sprintf("weight %s exceeds %d bits", rawWeight, MaxWeightBits)
+	}
+	return common.HexToAddress(rawAddress), weight, ""
+}
+
+// tabularWorkingCensusID returns the ID of the working census in which the
+// census file is imported, derived from the census root.
+func tabularWorkingCensusID(census *types.Census) uuid.UUID {
+	return uuid.NewSHA1(uuid.NameSpaceOID, append([]byte("tabular/"), census.CensusRoot.LeftTrim()...))
+}
+
+// loadWorkingCensus returns the working census of an import, and the number
+// of rows it holds. If it does not hold the rows imported by a previous
+// attempt, it is replaced by a new one.
+func loadWorkingCensus(censusDB *censusdb.CensusDB, workingID uuid.UUID, from int) (*censusdb.CensusRef, int, error) {
+	if censusDB.Exists(workingID) {
+		if from > 0 {
+			ref, err := censusDB.Load(workingID)
+			if err == nil && ref.Size() == from {
+				return ref, from, nil
+			}
+		}
+		if err := censusDB.CleanupWorkingCensus(workingID); err != nil {
+			return nil, 0, fmt.Errorf("failed to clean up working census: %w", err)
+		}
+	}
+	ref, err := censusDB.New(workingID)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to create working census: %w", err)
+	}
+	return ref, 0, nil
+}
+
+// publishWorkingCensus publishes the working census of an import as the
+// census of its root or, for dynamic on-chain censuses, of its chain-scoped
+// contract address.
+func publishWorkingCensus(censusDB *censusdb.CensusDB, chainID uint64, census *types.Census, workingID uuid.UUID) error {
+	var destination *censusdb.CensusRef
+	var err error
+	if census.CensusOrigin == types.CensusOriginMerkleTreeOnchainDynamicV1 {
+		if censusDB.ExistsByScopedAddress(chainID, census.ContractAddress) {
+			if err := censusDB.DelByScopedAddress(chainID, census.ContractAddress); err != nil {
+				return fmt.Errorf("failed to replace scoped census: %w", err)
+			}
+		}
+		destination, err = censusDB.NewByScopedAddress(chainID, census.ContractAddress)
+	} else {
+		destination, err = censusDB.NewByRoot(census.CensusRoot)
+		if errors.Is(err, censusdb.ErrCensusAlreadyExists) {
+			// imported meanwhile by another attempt
+			return censusDB.CleanupWorkingCensus(workingID)
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create census: %w", err)
+	}
+	if err := censusDB.PublishCensus(workingID, destination); err != nil {
+		return fmt.Errorf("failed to publish census: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,302 @@
+package census
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/vocdoni/davinci-node/internal/testutil"
+	"github.com/vocdoni/davinci-node/types"
+	leanimt "github.com/vocdoni/lean-imt-go"
+	leancensus "github.com/vocdoni/lean-imt-go/census"
+)
+
+// testTabularRow is a row of a tabular census file.
+type testTabularRow struct {
+	Address string
+	Weight  string
+}
+
+// testRowReader is a censusRowReader of the rows provided, that fails with
+// err after failAfter rows, if err is set.
+type testRowReader struct {
+	rows      []testTabularRow
+	next      int
+	failAfter int
+	err       error
+}
+
+func (r *testRowReader) Next() (string, string, error) {
+	if r.err != nil && r.next >= r.failAfter {
+		return "", "", r.err
+	}
+	if r.next >= len(r.rows) {
+		return "", "", io.EOF
+	}
+	row := r.rows[r.next]
+	r.next++
+	return row.Address, row.Weight, nil
+}
+
+// testMakeTabularCensus returns n rows with random addresses and the root of
+// the census they lead to.
+func testMakeTabularCensus(c *qt.C, n int) ([]testTabularRow, types.HexBytes) {
+	c.Helper()
+
+	tree, err := leancensus.NewCensusIMT(nil, leanimt.PoseidonHasher)
+	c.Assert(err, qt.IsNil)
+
+	rows := make([]testTabularRow, 0, n)
+	for i := range n {
+		addr := testutil.RandomAddress()
+		weight := big.NewInt(int64(i + 1))
+		c.Assert(tree.Add(addr, weight), qt.IsNil)
+		rows = append(rows, testTabularRow{Address: addr.Hex(), Weight: weight.String()})
+	}
+	root, ok := tree.Root()
+	c.Assert(ok, qt.IsTrue)
+	return rows, types.HexBytes(root.Bytes())
+}
+
+func TestParseCensusRow(t *testing.T) {
+	c := qt.New(t)
+
+	addr := testutil.RandomAddress()
+	tooHeavy := new(big.Int).Lsh(big.NewInt(1), MaxWeightBits).String()
+	maxWeight := new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), MaxWeightBits), big.NewInt(1)).String()
+
+	address, weight, reason := parseCensusRow(" "+strings.ToLower(addr.Hex())+" ", " 42 ")
+	c.Assert(reason, qt.Equals, "")
+	c.Assert(address, qt.Equals, addr)
+	c.Assert(weight.Int64(), qt.Equals, int64(42))
+
+	_, weight, reason = parseCensusRow(addr.Hex(), maxWeight)
+	c.Assert(reason, qt.Equals, "")
+	c.Assert(weight.String(), qt.Equals, maxWeight)
+
+	for _, tc := range []struct {
+		address string
+		weight  string
+		reason  string
+	}{
+		{"", "1", "missing address"},
+		{"0x1234", "1", "invalid address"},
+		{addr.Hex(), "", "missing weight"},
+		{addr.Hex(), "1.5", "invalid weight"},
+		{addr.Hex(), "0x10", "invalid weight"},
+		{addr.Hex(), "0", "must be positive"},
+		{addr.Hex(), "-3", "must be positive"},
+		{addr.Hex(), tooHeavy, "exceeds 88 bits"},
+	} {
+		_, _, reason := parseCensusRow(tc.address, tc.weight)
+		c.Assert(reason, qt.Contains, tc.reason, qt.Commentf("address %q weight %q", tc.address, tc.weight))
+	}
+}
+
+func TestImportCensusRows(t *testing.T) {
+	c := qt.New(t)
+
+	c.Run("Success", func(c *qt.C) {
+		censusDB := testNewCensusDB(c)
+		rows, root := testMakeTabularCensus(c, 10)
+		census := &types.Census{CensusURI: "https://example.invalid/census.csv", CensusRoot: root}
+
+		imported, err := importCensusRows(c.Context(), censusDB, 0, census, 0, &testRowReader{rows: rows})
+		c.Assert(err, qt.IsNil)
+		c.Assert(imported, qt.Equals, 10)
+		c.Assert(censusDB.ExistsByRoot(root), qt.IsTrue)
+		size, err := censusDB.SizeByRoot(root)
+		c.Assert(err, qt.IsNil)
+		c.Assert(size, qt.Equals, 10)
+		c.Assert(censusDB.Exists(tabularWorkingCensusID(census)), qt.IsFalse)
+	})
+
+	c.Run("ResumeFromPreviousAttempt", func(c *qt.C) {
+		censusDB := testNewCensusDB(c)
+		rows, root := testMakeTabularCensus(c, tabularBatchSize+50)
+		census := &types.Census{CensusURI: "https://example.invalid/census.csv", CensusRoot: root}
+
+		// the first attempt fails after the first batch is inserted
+		readErr := fmt.Errorf("connection reset")
+		imported, err := importCensusRows(c.Context(), censusDB, 0, census, 0,
+			&testRowReader{rows: rows, failAfter: tabularBatchSize + 10, err: readErr})
+		c.Assert(errors.Is(err, readErr), qt.IsTrue)
+		c.Assert(imported, qt.Equals, tabularBatchSize)
+		c.Assert(censusDB.ExistsByRoot(root), qt.IsFalse)
+
+		// the rows of the first batch are skipped when resuming
+		imported, err = importCensusRows(c.Context(), censusDB, 0, census, imported, &testRowReader{rows: rows})
+		c.Assert(err, qt.IsNil)
+		c.Assert(imported, qt.Equals, len(rows))
+		c.Assert(censusDB.ExistsByRoot(root), qt.IsTrue)
+	})
+
+	c.Run("StaleResumeStartsOver", func(c *qt.C) {
+		censusDB := testNewCensusDB(c)
+		rows, root := testMakeTabularCensus(c, 5)
+		census := &types.Census{CensusURI: "https://example.invalid/census.csv", CensusRoot: root}
+
+		// the working census holds no rows, so the offset is ignored
+		ref, err := censusDB.New(tabularWorkingCensusID(census))
+		c.Assert(err, qt.IsNil)
+		c.Assert(ref.Size(), qt.Equals, 0)
+
+		imported, err := importCensusRows(c.Context(), censusDB, 0, census, 3, &testRowReader{rows: rows})
+		c.Assert(err, qt.IsNil)
+		c.Assert(imported, qt.Equals, 5)
+		c.Assert(censusDB.ExistsByRoot(root), qt.IsTrue)
+	})
+
+	c.Run("InvalidRows", func(c *qt.C) {
+		censusDB := testNewCensusDB(c)
+		rows, root := testMakeTabularCensus(c, 4)
+		census := &types.Census{CensusURI: "https://example.invalid/census.csv", CensusRoot: root}
+		rows = append(rows,
+			testTabularRow{Address: "0xnotanaddress", Weight: "1"},
+			testTabularRow{Address: strings.ToLower(rows[1].Address), Weight: "7"},
+			testTabularRow{Address: testutil.RandomAddress().Hex(), Weight: "0"},
+		)
+
+		imported, err := importCensusRows(c.Context(), censusDB, 0, census, 0, &testRowReader{rows: rows})
+		c.Assert(imported, qt.Equals, 0)
+		var invalidRows *InvalidRowsError
+		c.Assert(errors.As(err, &invalidRows), qt.IsTrue)
+		c.Assert(invalidRows.URI, qt.Equals, census.CensusURI)
+		c.Assert(invalidRows.Total, qt.Equals, 3)
+		c.Assert(invalidRows.Rows, qt.HasLen, 3)
+		c.Assert(invalidRows.Rows[0].Row, qt.Equals, 5)
+		c.Assert(invalidRows.Rows[0].Reason, qt.Equals, "invalid address")
+		c.Assert(invalidRows.Rows[1].Row, qt.Equals, 6)
+		c.Assert(invalidRows.Rows[1].Reason, qt.Equals, "duplicated address, first found at row 2")
+		c.Assert(invalidRows.Rows[2].Row, qt.Equals, 7)
+		c.Assert(err.Error(), qt.Contains, "has 3 invalid rows")
+		c.Assert(err.Error(), qt.Contains, "row 6 ("+strings.ToLower(rows[1].Address)+")")
+		c.Assert(censusDB.ExistsByRoot(root), qt.IsFalse)
+		c.Assert(censusDB.Exists(tabularWorkingCensusID(census)), qt.IsFalse)
+	})
+
+	c.Run("DuplicatesAcrossBatches", func(c *qt.C) {
+		censusDB := testNewCensusDB(c)
+		rows, root := testMakeTabularCensus(c, tabularBatchSize+1)
+		census := &types.Census{CensusURI: "https://example.invalid/census.csv", CensusRoot: root}
+		rows[tabularBatchSize].Address = rows[0].Address
+
+		_, err := importCensusRows(c.Context(), censusDB, 0, census, 0, &testRowReader{rows: rows})
+		var invalidRows *InvalidRowsError
+		c.Assert(errors.As(err, &invalidRows), qt.IsTrue)
+		c.Assert(invalidRows.Total, qt.Equals, 1)
+		c.Assert(invalidRows.Rows[0].Row, qt.Equals, tabularBatchSize+1)
+		c.Assert(invalidRows.Rows[0].Reason, qt.Equals, "duplicated address, first found at row 1")
+	})
+
+	c.Run("ReportIsBounded", func(c *qt.C) {
+		censusDB := testNewCensusDB(c)
+		census := &types.Census{CensusURI: "https://example.invalid/census.csv", CensusRoot: types.HexBytes{0x01}}
+		rows := make([]testTabularRow, maxReportedRowErrors+5)
+		for i := range rows {
+			rows[i] = testTabularRow{Address: strings.Repeat("z", 100), Weight: "1"}
+		}
+
+		_, err := importCensusRows(c.Context(), censusDB, 0, census, 0, &testRowReader{rows: rows})
+		var invalidRows *InvalidRowsError
+		c.Assert(errors.As(err, &invalidRows), qt.IsTrue)
+		c.Assert(invalidRows.Total, qt.Equals, len(rows))
+		c.Assert(invalidRows.Rows, qt.HasLen, maxReportedRowErrors)
+		c.Assert(invalidRows.Rows[0].Address, qt.HasLen, maxReportedValueLen+len("..."))
+		c.Assert(err.Error(), qt.Contains, "... and 5 more")
+	})
+
+	c.Run("RootMismatch", func(c *qt.C) {
+		censusDB := testNewCensusDB(c)
+		rows, _ := testMakeTabularCensus(c, 3)
+		census := &types.Census{CensusURI: "https://example.invalid/census.csv", CensusRoot: types.HexBytes{0x01}}
+
+		imported, err := importCensusRows(c.Context(), censusDB, 0, census, 0, &testRowReader{rows: rows})
+		c.Assert(imported, qt.Equals, 0)
+		c.Assert(err, qt.ErrorMatches, "imported census root mismatch.*")
+		c.Assert(censusDB.Exists(tabularWorkingCensusID(census)), qt.IsFalse)
+	})
+
+	c.Run("EmptyFile", func(c *qt.C) {
+		censusDB := testNewCensusDB(c)
+		census := &types.Census{CensusURI: "https://example.invalid/census.csv", CensusRoot: types.HexBytes{0x01}}
+
+		_, err := importCensusRows(c.Context(), censusDB, 0, census, 0, &testRowReader{})
+		c.Assert(err, qt.ErrorMatches, ".*has no rows")
+	})
+
+	c.Run("DynamicCensusReplacesScopedCensus", func(c *qt.C) {
+		censusDB := testNewCensusDB(c)
+		contract := testutil.RandomAddress()
+		for range 2 {
+			rows, root := testMakeTabularCensus(c, 3)
+			census := &types.Census{
+				CensusOrigin:    types.CensusOriginMerkleTreeOnchainDynamicV1,
+				CensusURI:       "https://example.invalid/census.csv",
+				CensusRoot:      root,
+				ContractAddress: contract,
+			}
+			_, err := importCensusRows(c.Context(), censusDB, 1, census, 0, &testRowReader{rows: rows})
+			c.Assert(err, qt.IsNil)
+			ref, err := censusDB.LoadByScopedAddress(1, contract)
+			c.Assert(err, qt.IsNil)
+			c.Assert(ref.Root(), qt.DeepEquals, root)
+		}
+	})
+}
+
+func TestTabularURI(t *testing.T) {
+	c := qt.New(t)
+
+	ext, options, err := parseTabularURI("https://example.com/Census.CSV#address=wallet&weight=votes&delimiter=%3B")
+	c.Assert(err, qt.IsNil)
+	c.Assert(ext, qt.Equals, ".csv")
+	c.Assert(options.addressColumn, qt.Equals, "wallet")
+	c.Assert(options.weightColumn, qt.Equals, "votes")
+	c.Assert(options.delimiter, qt.Equals, ";")
+
+	c.Assert(validTabularURI("https://example.com/census.csv?token=1", false, ".csv"), qt.IsTrue)
+	c.Assert(validTabularURI("https://example.com/census.json", false, ".csv"), qt.IsFalse)
+	c.Assert(validTabularURI("ftp://example.com/census.csv", true, ".csv"), qt.IsFalse)
+	c.Assert(validTabularURI("file:///census.csv", false, ".csv"), qt.IsFalse)
+	c.Assert(validTabularURI("file:///census.csv", true, ".csv"), qt.IsTrue)
+}
+
+func TestTabularFilePath(t *testing.T) {
+	c := qt.New(t)
+
+	dir := c.TempDir()
+	for _, tc := range []struct {
+		uri  string
+		path string
+		err  string
+	}{
+		{uri: "file:census.csv", path: filepath.Join(dir, "census.csv")},
+		{uri: "file:sub/census.csv", path: filepath.Join(dir, "sub", "census.csv")},
+		{uri: "file://" + filepath.ToSlash(filepath.Join(dir, "census.csv")), path: filepath.Join(dir, "census.csv")},
+		{uri: "file:../census.csv", err: ".*outside of the census file directory"},
+		{uri: "file:///etc/passwd.csv", err: ".*outside of the census file directory"},
+		{uri: "file://example.com/census.csv", err: ".*remote host.*"},
+	} {
+		u, err := url.Parse(tc.uri)
+		c.Assert(err, qt.IsNil)
+		path, err := tabularFilePath(u, dir)
+		if tc.err != "" {
+			c.Assert(err, qt.ErrorMatches, tc.err, qt.Commentf("uri %s", tc.uri))
+			continue
+		}
+		c.Assert(err, qt.IsNil, qt.Commentf("uri %s", tc.uri))
+		c.Assert(path, qt.Equals, tc.path)
+	}
+
+	u, err := url.Parse("file:census.csv")
+	c.Assert(err, qt.IsNil)
+	_, err = tabularFilePath(u, "")
+	c.Assert(err, qt.ErrorMatches, "file census URIs are not allowed")
+}
@@ -0,0 +1,355 @@
+package census
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"math/big"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	gethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/vocdoni/davinci-node/census/censusdb"
+	"github.com/vocdoni/davinci-node/log"
+	"github.com/vocdoni/davinci-node/types"
+)
+
+const (
+	// ERC20CensusScheme is the scheme of the URIs of the censuses built from
+	// the balances of an ERC-20 token.
+	ERC20CensusScheme = "erc20"
+	// ERC721CensusScheme is the scheme of the URIs of the censuses built from
+	// the balances of an ERC-721 token.
+	ERC721CensusScheme = "erc721"
+	// DefaultTokenBlockRange is the default number of blocks of each query
+	// of the Transfer logs of a token.
+	DefaultTokenBlockRange = 10_000
+)
+
+// transferEventSignature is the topic of the Transfer events of the ERC-20
+// and ERC-721 tokens, which only differ in the tokenId being indexed in the
+// ERC-721 ones.
+var transferEventSignature = crypto.Keccak256Hash([]byte("Transfer(address,address,uint256)"))
+
+// TokenLogsClient filters the logs of a chain. It is implemented by the
+// web3 rpc.Client, which switches between the endpoints of its pool.
+type TokenLogsClient interface {
+	FilterLogs(ctx context.Context, query ethereum.FilterQuery) ([]gethtypes.Log, error)
+}
+
+// TokenImporterConfig holds configuration options for the token importer.
+type TokenImporterConfig struct {
+	// Clients returns the client of the chain with the ID provided. If it is
+	// not set, the token censuses cannot be imported.
+	Clients func(chainID uint64) (TokenLogsClient, error)
+	// BlockRange is the number of blocks of each query of the Transfer
+	// logs. If it is zero, DefaultTokenBlockRange is used.
+	BlockRange uint64
+}
+
+// TokenImporter returns an instance of tokenImporter with the provided
+// configuration. If config is nil, it uses the default configuration.
+func TokenImporter(config *TokenImporterConfig) *tokenImporter {
+	if config == nil {
+		config = &TokenImporterConfig{}
+	}
+	importer := &tokenImporter{
+		clients:    config.Clients,
+		blockRange: config.BlockRange,
+	}
+	if importer.blockRange == 0 {
+		importer.blockRange = DefaultTokenBlockRange
+	}
+	return importer
+}
+
+// tokenImporter is an implementation of the ImporterPlugin interface for
+// importing censuses from the balances of the holders of an ERC-20 or
+// ERC-721 token at a block. The balances are computed from the Transfer logs
+// of the token, so the census URI defines it completely, e.g.
+// erc20://1/0xA0b86991c6218b36c1d19D4a2e9Eb0cE3606eB48?block=19000000
+//
+// The host of the URI is the chain ID and its path the token address. The
+// query accepts the following parameters:
+//   - block: the block of the balances (required).
+//   - from: the first block to scan, which must not be after the deployment
+//     of the token (default 0).
+//   - min: the minimum balance of the holders included (default 1).
+//   - cap: the maximum balance counted for each holder.
+//   - weighting: "linear" (default) or "quadratic", which uses the integer
+//     square root of the balance counted as weight.
+//
+// The holders are inserted into the census ordered by address, so the same
+// URI always leads to the same census root.
+type tokenImporter struct {
+	clients    func(chainID uint64) (TokenLogsClient, error)
+	blockRange uint64
+}
+
+// tokenCensusURI holds the parameters of a token census URI.
+type tokenCensusURI struct {
+	standard   string
+	chainID    uint64
+	token      common.Address
+	fromBlock  uint64
+	block      uint64
+	minBalance *big.Int
+	maxBalance *big.Int
+	quadratic  bool
+}
+
+// parseTokenCensusURI parses the URI of a token census, described in
+// tokenImporter.
+func parseTokenCensusURI(targetURI string) (*tokenCensusURI, error) {
+	u, err := url.Parse(targetURI)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != ERC20CensusScheme && u.Scheme != ERC721CensusScheme {
+		return nil, fmt.Errorf("unsupported scheme %q", u.Scheme)
+	}
+	uri := &tokenCensusURI{standard: u.Scheme, minBalance: big.NewInt(1)}
+	if uri.chainID, err = strconv.ParseUint(u.Host, 10, 64); err != nil || uri.chainID == 0 {
+		return nil, fmt.Errorf("invalid chain ID %q", u.Host)
+	}
+	token := strings.Trim(u.Path, "/")
+	if !common.IsHexAddress(token) {
+		return nil, fmt.Errorf("invalid token address %q", token)
+	}
+	uri.token = common.HexToAddress(token)
+
+	query, err := url.ParseQuery(u.RawQuery)
+	if err != nil {
+		return nil, fmt.Errorf("invalid query: %w", err)
+	}
+	if !query.Has("block") {
+		return nil, fmt.Errorf("missing block")
+	}
+	if uri.block, err = strconv.ParseUint(query.Get("block"), 10, 64); err != nil {
+		return nil, fmt.Errorf("invalid block %q", query.Get("block"))
+	}
+	if query.Has("from") {
+		if uri.fromBlock, err = strconv.ParseUint(query.Get("from"), 10, 64); err != nil {
+			return nil, fmt.Errorf("invalid from block %q", query.Get("from"))
+		}
+		if uri.fromBlock > uri.block {
+			return nil, fmt.Errorf("from block %d is after block %d", uri.fromBlock, uri.block)
+		}
+	}
+	if query.Has("min") {
+		if uri.minBalance, err = parseTokenAmount(query.Get("min")); err != nil {
+			return nil, fmt.Errorf("invalid min balance: %w", err)
+		}
+	}
+	if query.Has("cap") {
+		if uri.maxBalance, err = parseTokenAmount(query.Get("cap")); err != nil {
+			return nil, fmt.Errorf("invalid cap: %w", err)
+		}
+		if uri.maxBalance.Cmp(uri.minBalance) < 0 {
+			return nil, fmt.Errorf("cap %s is lower than the min balance %s", uri.maxBalance, uri.minBalance)
+		}
+	}
+	switch weighting := query.Get("weighting"); weighting {
+	case "", "linear":
+	case "quadratic":
+		uri.quadratic = true
+	default:
+		return nil, fmt.Errorf("unsupported weighting %q", weighting)
+	}
+	return uri, nil
+}
+
+// parseTokenAmount parses a positive amount of tokens, in the smallest unit
+// of the token.
+func parseTokenAmount(raw string) (*big.Int, error) {
+	amount, ok := new(big.Int).SetString(raw, 10)
+	if !ok {
+		return nil, fmt.Errorf("invalid amount %q", raw)
+	}
+	if amount.Sign() <= 0 {
+		return nil, fmt.Errorf("amount %s must be positive", raw)
+	}
+	return amount, nil
+}
+
+// ValidURI checks if the provided targetURI is a valid ERC-20 or ERC-721
+// census URI.
+func (d *tokenImporter) ValidURI(targetURI string) bool {
+	_, err := parseTokenCensusURI(targetURI)
+	return err == nil
+}
+
+// ImportCensus computes the balances of the token holders at the block of
+// the census URI from the Transfer logs of the token, and imports their
+// weights into the census DB, checking that they lead to the census root.
+// The from argument is the number of holders imported by a previous attempt,
+// which are skipped. It returns the number of holders imported, also when it
+// fails, so the import can be resumed.
+func (d *tokenImporter) ImportCensus(
+	ctx context.Context,
+	censusDB *censusdb.CensusDB,
+	chainID uint64,
+	census *types.Census,
+	from int,
+) (int, error) {
+	uri, err := parseTokenCensusURI(census.CensusURI)
+	if err != nil {
+		return 0, fmt.Errorf("invalid token census URI %s: %w", census.CensusURI, err)
+	}
+	if d.clients == nil {
+		return from, fmt.Errorf("no web3 clients configured to import token censuses")
+	}
+	client, err := d.clients(uri.chainID)
+	if err != nil {
+		return from, fmt.Errorf("no web3 client for chain %d: %w", uri.chainID, err)
+	}
+	balances, err := d.tokenBalances(ctx, client, uri)
+	if err != nil {
+		return from, err
+	}
+	holders, err := tokenHolderWeights(balances, uri)
+	if err != nil {
+		return 0, fmt.Errorf("invalid token census %s: %w", census.CensusURI, err)
+	}
+	log.Debugw("token holders computed",
+		"uri", census.CensusURI,
+		"holders", len(holders),
+		"balances", len(balances))
+	return importCensusRows(ctx, censusDB, chainID, census, from, &tokenRowReader{holders: holders})
+}
+
+// tokenBalances returns the balances of every account of the token at the
+// block of the URI provided, computed from the Transfer logs of the token
+// since the from block of the URI. The accounts that have held tokens but
+// have no balance at the block are included with a zero balance.
+func (d *tokenImporter) tokenBalances(
+	ctx context.Context,
+	client TokenLogsClient,
+	uri *tokenCensusURI,
+) (map[common.Address]*big.Int, error) {
+	balances := make(map[common.Address]*big.Int)
+	add := func(account common.Address, amount *big.Int) {
+		if account == (common.Address{}) {
+			// mints and burns
+			return
+		}
+		balance, ok := balances[account]
+		if !ok {
+			balance = new(big.Int)
+			balances[account] = balance
+		}
+		balance.Add(balance, amount)
+	}
+	one, minusOne := big.NewInt(1), big.NewInt(-1)
+	for start := uri.fromBlock; start <= uri.block; start += d.blockRange {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		end := min(start+d.blockRange-1, uri.block)
+		logs, err := client.FilterLogs(ctx, ethereum.FilterQuery{
+			FromBlock: new(big.Int).SetUint64(start),
+			ToBlock:   new(big.Int).SetUint64(end),
+			Addresses: []common.Address{uri.token},
+			Topics:    [][]common.Hash{{transferEventSignature}},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to filter Transfer logs of %s from block %d to %d: %w",
+				uri.token.Hex(), start, end, err)
+		}
+		for _, l := range logs {
+			if l.Removed || l.Address != uri.token {
+				continue
+			}
+			switch {
+			case uri.standard == ERC20CensusScheme && len(l.Topics) == 3 && len(l.Data) == 32:
+				value := new(big.Int).SetBytes(l.Data)
+				add(common.BytesToAddress(l.Topics[1].Bytes()), new(big.Int).Neg(value))
+				add(common.BytesToAddress(l.Topics[2].Bytes()), value)
+			case uri.standard == ERC721CensusScheme && len(l.Topics) == 4 && len(l.Data) == 0:
+				add(common.BytesToAddress(l.Topics[1].Bytes()), minusOne)
+				add(common.BytesToAddress(l.Topics[2].Bytes()), one)
+			default:
+				// the events of the other standard, e.g. an ERC-20 census
+				// URI of an ERC-721 token
+				return nil, fmt.Errorf("unexpected Transfer log of %s in tx %s, the token is not %s",
+					uri.token.Hex(), l.TxHash.Hex(), strings.ToUpper(uri.standard))
+			}
+		}
+		if end == uri.block {
+			break
+		}
+	}
+	for account, balance := range balances {
+		if balance.Sign() < 0 {
+			return nil, fmt.Errorf("negative balance of %s, the Transfer logs must be scanned from the deployment of the token",
+				account.Hex())
+		}
+	}
+	return balances, nil
+}
+
+// tokenHolder is a holder of a token census with its weight.
+type tokenHolder struct {
+	address common.Address
+	weight  *big.Int
+}
+
+// tokenHolderWeights returns the holders with at least the min balance of
+// the URI provided, ordered by address, with their weights. The weight of
+// each holder is its balance, limited to the cap, or the integer square root
+// of it if the weighting is quadratic.
+func tokenHolderWeights(balances map[common.Address]*big.Int, uri *tokenCensusURI) ([]tokenHolder, error) {
+	holders := make([]tokenHolder, 0, len(balances))
+	tooBig := 0
+	for address, balance := range balances {
+		if balance.Cmp(uri.minBalance) < 0 {
+			continue
+		}
+		weight := new(big.Int).Set(balance)
+		if uri.maxBalance != nil && weight.Cmp(uri.maxBalance) > 0 {
+			weight.Set(uri.maxBalance)
+		}
+		if uri.quadratic {
+			weight.Sqrt(weight)
+		}
+		if weight.BitLen() > MaxWeightBits {
+			tooBig++
+		}
+		holders = append(holders, tokenHolder{address: address, weight: weight})
+	}
+	if tooBig > 0 {
+		return nil, fmt.Errorf("%d holders have a weight over %d bits, set a cap or use the quadratic weighting",
+			tooBig, MaxWeightBits)
+	}
+	if len(holders) == 0 {
+		return nil, fmt.Errorf("no holders with the min balance %s", uri.minBalance)
+	}
+	slices.SortFunc(holders, func(a, b tokenHolder) int {
+		return bytes.Compare(a.address.Bytes(), b.address.Bytes())
+	})
+	return holders, nil
+}
+
+// tokenRowReader reads the holders of a token census as the rows of a
+// tabular census file.
+type tokenRowReader struct {
+	holders []tokenHolder
+	next    int
+}
+
+// Next returns the address and the weight of the next holder. It returns
+// io.EOF after the last holder.
+func (tr *tokenRowReader) Next() (string, string, error) {
+	if tr.next >= len(tr.holders) {
+		return "", "", io.EOF
+	}
+	holder := tr.holders[tr.next]
+	tr.next++
+	return holder.address.Hex(), holder.weight.String(), nil
+}
@@ -0,0 +1,266 @@
+package census
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	gethtypes "github.com/ethereum/go-ethereum/core/types"
+	qt "github.com/frankban/quicktest"
+	"github.com/vocdoni/davinci-node/types"
+	leanimt "github.com/vocdoni/lean-imt-go"
+	leancensus "github.com/vocdoni/lean-imt-go/census"
+)
+
+var (
+	testToken = common.HexToAddress("0x00000000000000000000000000000000000000aa")
+	testAlice = common.HexToAddress("0x0000000000000000000000000000000000000003")
+	testBob   = common.HexToAddress("0x0000000000000000000000000000000000000001")
+	testCarol = common.HexToAddress("0x0000000000000000000000000000000000000002")
+)
+
+// testTokenClient is a TokenLogsClient that returns the logs provided within
+// the block range of each query, recording the queries.
+type testTokenClient struct {
+	logs    []gethtypes.Log
+	queries [][2]uint64
+}
+
+func (tc *testTokenClient) FilterLogs(_ context.Context, query ethereum.FilterQuery) ([]gethtypes.Log, error) {
+	from, to := query.FromBlock.Uint64(), query.ToBlock.Uint64()
+	tc.queries = append(tc.queries, [2]uint64{from, to})
+	var logs []gethtypes.Log
+	for _, l := range tc.logs {
+		if l.BlockNumber >= from && l.BlockNumber <= to {
+			logs = append(logs, l)
+		}
+	}
+	return logs, nil
+}
+
+// testERC20Transfer returns the Transfer log of an ERC-20 token.
+func testERC20Transfer(block uint64, from, to common.Address, value int64) gethtypes.Log {
+	return gethtypes.Log{
+		Address:     testToken,
+		BlockNumber: block,
+		Topics:      []common.Hash{transferEventSignature, common.BytesToHash(from.Bytes()), common.BytesToHash(to.Bytes())},
+		Data:        common.BigToHash(big.NewInt(value)).Bytes(),
+	}
+}
+
+// testERC721Transfer returns the Transfer log of an ERC-721 token.
+func testERC721Transfer(block uint64, from, to common.Address, tokenID int64) gethtypes.Log {
+	return gethtypes.Log{
+		Address:     testToken,
+		BlockNumber: block,
+		Topics: []common.Hash{
+			transferEventSignature, common.BytesToHash(from.Bytes()),
+			common.BytesToHash(to.Bytes()), common.BigToHash(big.NewInt(tokenID)),
+		},
+	}
+}
+
+// testTokenCensusRoot returns the root of the census of the holders
+// provided, in that order.
+func testTokenCensusRoot(c *qt.C, holders ...tokenHolder) types.HexBytes {
+	c.Helper()
+	tree, err := leancensus.NewCensusIMT(nil, leanimt.PoseidonHasher)
+	c.Assert(err, qt.IsNil)
+	for _, holder := range holders {
+		c.Assert(tree.Add(holder.address, holder.weight), qt.IsNil)
+	}
+	root, ok := tree.Root()
+	c.Assert(ok, qt.IsTrue)
+	return types.HexBytes(root.Bytes())
+}
+
+func TestParseTokenCensusURI(t *testing.T) {
+	c := qt.New(t)
+
+	uri, err := parseTokenCensusURI(fmt.Sprintf("erc20://10/%s?block=200&from=100&min=5&cap=1000&weighting=quadratic", testToken.Hex()))
+	c.Assert(err, qt.IsNil)
+	c.Assert(uri.standard, qt.Equals, ERC20CensusScheme)
+	c.Assert(uri.chainID, qt.Equals, uint64(10))
+	c.Assert(uri.token, qt.Equals, testToken)
+	c.Assert(uri.fromBlock, qt.Equals, uint64(100))
+	c.Assert(uri.block, qt.Equals, uint64(200))
+	c.Assert(uri.minBalance.Int64(), qt.Equals, int64(5))
+	c.Assert(uri.maxBalance.Int64(), qt.Equals, int64(1000))
+	c.Assert(uri.quadratic, qt.IsTrue)
+
+	uri, err = parseTokenCensusURI(fmt.Sprintf("erc721://1/%s?block=7", testToken.Hex()))
+	c.Assert(err, qt.IsNil)
+	c.Assert(uri.standard, qt.Equals, ERC721CensusScheme)
+	c.Assert(uri.minBalance.Int64(), qt.Equals, int64(1))
+	c.Assert(uri.maxBalance, qt.IsNil)
+	c.Assert(uri.quadratic, qt.IsFalse)
+
+	for _, invalid := range []string{
+		fmt.Sprintf("https://1/%s?block=7", testToken.Hex()),
+		fmt.Sprintf("erc20://mainnet/%s?block=7", testToken.Hex()),
+		"erc20://1/0x1234?block=7",
+		fmt.Sprintf("erc20://1/%s", testToken.Hex()),
+		fmt.Sprintf("erc20://1/%s?block=7&from=8", testToken.Hex()),
+		fmt.Sprintf("erc20://1/%s?block=7&min=0", testToken.Hex()),
+		fmt.Sprintf("erc20://1/%s?block=7&min=10&cap=5", testToken.Hex()),
+		fmt.Sprintf("erc20://1/%s?block=7&weighting=cubic", testToken.Hex()),
+	} {
+		_, err := parseTokenCensusURI(invalid)
+		c.Assert(err, qt.IsNotNil, qt.Commentf("%s", invalid))
+		c.Assert(TokenImporter(nil).ValidURI(invalid), qt.IsFalse)
+	}
+}
+
+func TestTokenBalances(t *testing.T) {
+	c := qt.New(t)
+
+	c.Run("ERC20", func(c *qt.C) {
+		client := &testTokenClient{logs: []gethtypes.Log{
+			testERC20Transfer(1, common.Address{}, testAlice, 100),
+			testERC20Transfer(3, testAlice, testBob, 30),
+			testERC20Transfer(5, testBob, common.Address{}, 10),
+			// after the census block
+			testERC20Transfer(9, testAlice, testCarol, 70),
+		}}
+		uri, err := parseTokenCensusURI(fmt.Sprintf("erc20://1/%s?block=8", testToken.Hex()))
+		c.Assert(err, qt.IsNil)
+		balances, err := TokenImporter(&TokenImporterConfig{BlockRange: 3}).tokenBalances(c.Context(), client, uri)
+		c.Assert(err, qt.IsNil)
+		c.Assert(balances, qt.HasLen, 2)
+		c.Assert(balances[testAlice].Int64(), qt.Equals, int64(70))
+		c.Assert(balances[testBob].Int64(), qt.Equals, int64(20))
+		c.Assert(client.queries, qt.DeepEquals, [][2]uint64{{0, 2}, {3, 5}, {6, 8}})
+	})
+
+	c.Run("ERC721", func(c *qt.C) {
+		client := &testTokenClient{logs: []gethtypes.Log{
+			testERC721Transfer(1, common.Address{}, testAlice, 1),
+			testERC721Transfer(1, common.Address{}, testAlice, 2),
+			testERC721Transfer(2, testAlice, testBob, 1),
+		}}
+		uri, err := parseTokenCensusURI(fmt.Sprintf("erc721://1/%s?block=2", testToken.Hex()))
+		c.Assert(err, qt.IsNil)
+		balances, err := TokenImporter(nil).tokenBalances(c.Context(), client, uri)
+		c.Assert(err, qt.IsNil)
+		c.Assert(balances[testAlice].Int64(), qt.Equals, int64(1))
+		c.Assert(balances[testBob].Int64(), qt.Equals, int64(1))
+	})
+
+	c.Run("WrongStandard", func(c *qt.C) {
+		client := &testTokenClient{logs: []gethtypes.Log{
+			testERC721Transfer(1, common.Address{}, testAlice, 1),
+		}}
+		uri, err := parseTokenCensusURI(fmt.Sprintf("erc20://1/%s?block=2", testToken.Hex()))
+		c.Assert(err, qt.IsNil)
+		_, err = TokenImporter(nil).tokenBalances(c.Context(), client, uri)
+		c.Assert(err, qt.ErrorMatches, ".*the token is not ERC20")
+	})
+
+	c.Run("MissingLogs", func(c *qt.C) {
+		client := &testTokenClient{logs: []gethtypes.Log{
+			testERC20Transfer(1, common.Address{}, testAlice, 100),
+			testERC20Transfer(3, testAlice, testBob, 30),
+		}}
+		uri, err := parseTokenCensusURI(fmt.Sprintf("erc20://1/%s?block=5&from=2", testToken.Hex()))
+		c.Assert(err, qt.IsNil)
+		_, err = TokenImporter(nil).tokenBalances(c.Context(), client, uri)
+		c.Assert(err, qt.ErrorMatches, "negative balance of .*")
+	})
+}
+
+func TestTokenHolderWeights(t *testing.T) {
+	c := qt.New(t)
+
+	balances := map[common.Address]*big.Int{
+		testAlice: big.NewInt(400),
+		testBob:   big.NewInt(9),
+		testCarol: big.NewInt(4),
+		{0x04}:    big.NewInt(0),
+	}
+
+	uri := &tokenCensusURI{minBalance: big.NewInt(1)}
+	holders, err := tokenHolderWeights(balances, uri)
+	c.Assert(err, qt.IsNil)
+	c.Assert(holders, qt.HasLen, 3)
+	// ordered by address
+	c.Assert(holders[0].address, qt.Equals, testBob)
+	c.Assert(holders[1].address, qt.Equals, testCarol)
+	c.Assert(holders[2].address, qt.Equals, testAlice)
+	c.Assert(holders[2].weight.Int64(), qt.Equals, int64(400))
+
+	uri = &tokenCensusURI{minBalance: big.NewInt(5), maxBalance: big.NewInt(100), quadratic: true}
+	holders, err = tokenHolderWeights(balances, uri)
+	c.Assert(err, qt.IsNil)
+	c.Assert(holders, qt.HasLen, 2)
+	c.Assert(holders[0].address, qt.Equals, testBob)
+	c.Assert(holders[0].weight.Int64(), qt.Equals, int64(3))
+	c.Assert(holders[1].address, qt.Equals, testAlice)
+	c.Assert(holders[1].weight.Int64(), qt.Equals, int64(10))
+
+	_, err = tokenHolderWeights(balances, &tokenCensusURI{minBalance: big.NewInt(1000)})
+	c.Assert(err, qt.ErrorMatches, "no holders with the min balance 1000")
+
+	huge := map[common.Address]*big.Int{testAlice: new(big.Int).Lsh(big.NewInt(1), MaxWeightBits)}
+	_, err = tokenHolderWeights(huge, &tokenCensusURI{minBalance: big.NewInt(1)})
+	c.Assert(err, qt.ErrorMatches, "1 holders have a weight over 88 bits.*")
+	holders, err = tokenHolderWeights(huge, &tokenCensusURI{minBalance: big.NewInt(1), quadratic: true})
+	c.Assert(err, qt.IsNil)
+	c.Assert(holders[0].weight.BitLen(), qt.Equals, MaxWeightBits/2+1)
+}
+
+func TestTokenImportCensus(t *testing.T) {
+	c := qt.New(t)
+
+	client := &testTokenClient{logs: []gethtypes.Log{
+		testERC20Transfer(1, common.Address{}, testAlice, 100),
+		testERC20Transfer(2, testAlice, testBob, 36),
+		testERC20Transfer(2, testAlice, testCarol, 1),
+	}}
+	var requestedChainID uint64
+	importer := TokenImporter(&TokenImporterConfig{
+		Clients: func(chainID uint64) (TokenLogsClient, error) {
+			requestedChainID = chainID
+			return client, nil
+		},
+	})
+	uri := fmt.Sprintf("erc20://10/%s?block=2&min=2&weighting=quadratic", testToken.Hex())
+	c.Assert(importer.ValidURI(uri), qt.IsTrue)
+
+	root := testTokenCensusRoot(c,
+		tokenHolder{address: testBob, weight: big.NewInt(6)},
+		tokenHolder{address: testAlice, weight: big.NewInt(7)},
+	)
+
+	c.Run("RootMismatch", func(c *qt.C) {
+		censusDB := testNewCensusDB(c)
+		_, err := importer.ImportCensus(c.Context(), censusDB, 0, &types.Census{
+			CensusURI:  uri,
+			CensusRoot: types.HexBytes{0x01},
+		}, 0)
+		c.Assert(err, qt.ErrorMatches, "imported census root mismatch.*")
+		c.Assert(censusDB.ExistsByRoot(root), qt.IsFalse)
+	})
+
+	c.Run("Imported", func(c *qt.C) {
+		censusDB := testNewCensusDB(c)
+		imported, err := importer.ImportCensus(c.Context(), censusDB, 0, &types.Census{
+			CensusURI:  uri,
+			CensusRoot: root,
+		}, 0)
+		c.Assert(err, qt.IsNil)
+		c.Assert(imported, qt.Equals, 2)
+		c.Assert(requestedChainID, qt.Equals, uint64(10))
+		c.Assert(censusDB.ExistsByRoot(root), qt.IsTrue)
+	})
+
+	c.Run("NoClients", func(c *qt.C) {
+		_, err := TokenImporter(nil).ImportCensus(c.Context(), testNewCensusDB(c), 0, &types.Census{
+			CensusURI:  uri,
+			CensusRoot: root,
+		}, 0)
+		c.Assert(err, qt.ErrorMatches, "no web3 clients configured.*")
+	})
+}
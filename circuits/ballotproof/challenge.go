@@ -0,0 +1,121 @@
+package ballotproof
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/vocdoni/davinci-node/crypto/ecc"
+	bjj "github.com/vocdoni/davinci-node/crypto/ecc/bjj_gnark"
+	"github.com/vocdoni/davinci-node/crypto/elgamal"
+	"github.com/vocdoni/davinci-node/spec/params"
+	"github.com/vocdoni/davinci-node/types"
+)
+
+// ErrBallotEncryptionMismatch is returned when a spoiled ballot is not the
+// encryption of the field values it claims.
+var ErrBallotEncryptionMismatch = errors.New("ballot does not encrypt the field values")
+
+// SpoiledBallot is a Benaloh challenge of an encrypted ballot. Instead of
+// casting the ballot built by its client, the voter spoils it, revealing the
+// randomness k used to encrypt it and the field values that it should
+// encrypt, so anyone can verify that the client encrypted the choices of the
+// voter. Since its randomness is public, the vote of a spoiled ballot can not
+// be cast anymore.
+type SpoiledBallot struct {
+	ProcessID   types.ProcessID `json:"processId"`
+	Address     types.HexBytes  `json:"address"`
+	VoteID      types.VoteID    `json:"voteId"`
+	Ballot      *elgamal.Ballot `json:"ballot"`
+	K           *types.BigInt   `json:"k"`
+	FieldValues []*types.BigInt `json:"fieldValues"`
+}
+
+// NewSpoiledBallot returns the spoiled ballot of the ballot proof inputs
+// provided, generated by GenerateBallotProofInputs, which reveals their
+// randomness.
+func NewSpoiledBallot(inputs *BallotProofInputs, result *BallotProofInputsResult) *SpoiledBallot {
+	return &SpoiledBallot{
+		ProcessID:   result.ProcessID,
+		Address:     result.Address,
+		VoteID:      result.VoteID,
+		Ballot:      result.Ballot,
+		K:           inputs.K,
+		FieldValues: inputs.FieldValues,
+	}
+}
+
+// Verify checks that the vote ID of the spoiled ballot is derived from its
+// randomness, and re-encrypts the field values with it and the encryption
+// key of the process provided to check that they lead to the ballot, in the
+// twisted edwards form generated by GenerateBallotProofInputs. It returns
+// ErrBallotEncryptionMismatch if the ballot encrypts other values, and other
+// errors if the challenge is malformed.
+func (s *SpoiledBallot) Verify(encryptionKey ecc.Point) error {
+	fields, err := s.check()
+	if err != nil {
+		return err
+	}
+	key := new(bjj.BJJ).SetPoint(encryptionKey.Point())
+	expected, err := elgamal.NewBallot(key).Encrypt(fields, key, s.K.MathBigInt())
+	if err != nil {
+		return fmt.Errorf("could not encrypt field values: %w", err)
+	}
+	expectedValues, values := expected.FromRTEtoTE().BigInts(), s.Ballot.BigInts()
+	for i := range expectedValues {
+		if expectedValues[i].Cmp(values[i]) != 0 {
+			return ErrBallotEncryptionMismatch
+		}
+	}
+	return nil
+}
+
+// Decrypt re-derives the field values encrypted in the ballot from its
+// randomness and the encryption key of the process provided, searching each
+// one in the interval [0,maxValue]. Unlike Verify, it does not rely on the
+// field values claimed, so it shows what the ballot really encrypts.
+func (s *SpoiledBallot) Decrypt(encryptionKey ecc.Point, maxValue uint64) ([]*types.BigInt, error) {
+	if _, err := s.check(); err != nil {
+		return nil, err
+	}
+	key := new(bjj.BJJ).SetPoint(encryptionKey.Point())
+	fields, err := s.Ballot.FromTEtoRTE().DecryptWithK(key, s.K.MathBigInt(), maxValue)
+	if err != nil {
+		return nil, err
+	}
+	return types.SliceOf(fields[:], types.BigIntConverter), nil
+}
+
+// check validates the spoiled ballot and its vote ID, and returns its field
+// values padded to params.FieldsPerBallot.
+func (s *SpoiledBallot) check() ([params.FieldsPerBallot]*big.Int, error) {
+	fields := [params.FieldsPerBallot]*big.Int{}
+	if s.Ballot == nil || !s.Ballot.Valid() {
+		return fields, fmt.Errorf("invalid ballot")
+	}
+	if s.K == nil {
+		return fields, fmt.Errorf("missing randomness")
+	}
+	if len(s.FieldValues) > params.FieldsPerBallot {
+		return fields, fmt.Errorf("too many field values: %d, max %d", len(s.FieldValues), params.FieldsPerBallot)
+	}
+	voteID, err := (&BallotProofInputs{
+		ProcessID: s.ProcessID,
+		Address:   s.Address,
+		K:         s.K,
+	}).VoteID()
+	if err != nil {
+		return fields, fmt.Errorf("could not derive vote ID: %w", err)
+	}
+	if voteID != s.VoteID {
+		return fields, fmt.Errorf("vote ID %s is not derived from the randomness provided", s.VoteID.String())
+	}
+	for i := range fields {
+		if i < len(s.FieldValues) && s.FieldValues[i] != nil {
+			fields[i] = s.FieldValues[i].MathBigInt()
+		} else {
+			fields[i] = big.NewInt(0)
+		}
+	}
+	return fields, nil
+}
@@ -0,0 +1,51 @@
+package ballotproof
+
+import (
+	"math/big"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+	bjj "github.com/vocdoni/davinci-node/crypto/ecc/bjj_gnark"
+	"github.com/vocdoni/davinci-node/internal/testutil"
+	"github.com/vocdoni/davinci-node/types"
+)
+
+func TestSpoiledBallot(t *testing.T) {
+	c := qt.New(t)
+
+	_, encryptionKey := testutil.RandomEncryptionKeys()
+	fields := []*types.BigInt{types.NewInt(1), types.NewInt(3), types.NewInt(2)}
+	inputs := &BallotProofInputs{
+		ProcessID:     testutil.RandomProcessID(),
+		Address:       testutil.RandomAddress().Bytes(),
+		EncryptionKey: []*types.BigInt{encryptionKey.X, encryptionKey.Y},
+		BallotMode:    testutil.BallotMode(),
+		Weight:        types.NewInt(testutil.Weight),
+		FieldValues:   fields,
+	}
+	result, err := GenerateBallotProofInputs(inputs)
+	c.Assert(err, qt.IsNil)
+	key := new(bjj.BJJ).SetPoint(encryptionKey.X.MathBigInt(), encryptionKey.Y.MathBigInt())
+
+	// the ballot encrypts the field values with the randomness revealed
+	spoiled := NewSpoiledBallot(inputs, result)
+	c.Assert(spoiled.Verify(key), qt.IsNil)
+	decrypted, err := spoiled.Decrypt(key, 10)
+	c.Assert(err, qt.IsNil)
+	for i, value := range decrypted {
+		expected := big.NewInt(0)
+		if i < len(fields) {
+			expected = fields[i].MathBigInt()
+		}
+		c.Assert(value.MathBigInt().Cmp(expected), qt.Equals, 0)
+	}
+
+	// a client that claims other values is caught
+	spoiled.FieldValues = []*types.BigInt{types.NewInt(3), types.NewInt(3), types.NewInt(2)}
+	c.Assert(spoiled.Verify(key), qt.ErrorIs, ErrBallotEncryptionMismatch)
+
+	// the randomness must be the one committed by the vote ID
+	spoiled.FieldValues = fields
+	spoiled.K = new(types.BigInt).SetBigInt(new(big.Int).Add(inputs.K.MathBigInt(), big.NewInt(1)))
+	c.Assert(spoiled.Verify(key), qt.ErrorMatches, "vote ID .* is not derived from the randomness provided")
+}
@@ -1,30 +1,24 @@
 package main
 
 import (
-	"bytes"
 	"context"
-	"encoding/json"
 	"fmt"
 	"math/big"
-	"net/http"
 	"time"
 
 	"github.com/ethereum/go-ethereum/common"
-	ethcrypto "github.com/ethereum/go-ethereum/crypto"
 	"github.com/vocdoni/davinci-node/api"
 	"github.com/vocdoni/davinci-node/api/client"
 	censustest "github.com/vocdoni/davinci-node/census/test"
-	"github.com/vocdoni/davinci-node/circuits/ballotproof"
 	ballotprooftest "github.com/vocdoni/davinci-node/circuits/test/ballotproof"
-	"github.com/vocdoni/davinci-node/crypto/elgamal"
+	bjj "github.com/vocdoni/davinci-node/crypto/ecc/bjj_gnark"
 	"github.com/vocdoni/davinci-node/crypto/signatures/ethereum"
 	"github.com/vocdoni/davinci-node/log"
 	"github.com/vocdoni/davinci-node/spec"
-	specutil "github.com/vocdoni/davinci-node/spec/util"
 	"github.com/vocdoni/davinci-node/state"
+	"github.com/vocdoni/davinci-node/storage"
 	"github.com/vocdoni/davinci-node/types"
 	"github.com/vocdoni/davinci-node/util"
-	"github.com/vocdoni/davinci-node/util/circomgnark"
 	"github.com/vocdoni/davinci-node/web3"
 )
 
@@ -159,12 +153,12 @@ func (s *CLIServices) initSequencerCLI() error {
 		case <-pingCtx.Done():
 			return fmt.Errorf("timeout reached while connecting to sequencer")
 		default:
-			_, status, err := s.cli.Request(http.MethodGet, nil, nil, api.PingEndpoint)
-			if err == nil && status == http.StatusOK {
+			err := s.cli.Ping(pingCtx)
+			if err == nil {
 				isConnected = true
 				break
 			}
-			log.Warnw("failed to ping sequencer", "status", status, "err", err)
+			log.Warnw("failed to ping sequencer", "err", err)
 			time.Sleep(10 * time.Second)
 		}
 	}
@@ -226,17 +220,9 @@ func (s *CLIServices) CreateProcess(
 	maxVoters *types.BigInt,
 ) (types.ProcessID, *types.EncryptionKey, error) {
 	// Make the request to create the encryption keys
-	body, code, err := s.cli.Request(http.MethodPost, nil, nil, api.NewEncryptionKeysEndpoint)
+	resp, err := s.cli.NewEncryptionKeys(s.ctx)
 	if err != nil {
 		return types.ProcessID{}, nil, fmt.Errorf("failed to create process: %v", err)
-	} else if code != http.StatusOK {
-		return types.ProcessID{}, nil, fmt.Errorf("failed to create process, status code: %d", code)
-	}
-
-	// Decode process response
-	var resp types.ProcessEncryptionKeysResponse
-	if err := json.NewDecoder(bytes.NewReader(body)).Decode(&resp); err != nil {
-		return types.ProcessID{}, nil, fmt.Errorf("failed to decode process response: %v", err)
 	}
 	encryptionKeys := &types.EncryptionKey{
 		X: resp.EncryptionPubKey[0],
@@ -271,12 +257,8 @@ func (s *CLIServices) CreateProcess(
 	for processReady := false; !processReady; {
 		select {
 		case <-time.After(time.Second * 5):
-			pBytes, status, err := s.cli.Request(http.MethodGet, nil, nil, api.EndpointWithParam(api.ProcessEndpoint, api.ProcessURLParam, pid.String()))
-			if err == nil && status == http.StatusOK {
-				proc := &api.ProcessResponse{}
-				if err := json.Unmarshal(pBytes, proc); err != nil {
-					return types.ProcessID{}, nil, fmt.Errorf("failed to unmarshal process response: %v", err)
-				}
+			proc, err := s.cli.Process(processCtx, pid)
+			if err == nil {
 				processReady = proc.IsAcceptingVotes
 			}
 		case <-processCtx.Done():
@@ -300,182 +282,110 @@ func (s *CLIServices) StopProcess(pid types.ProcessID) error {
 }
 
 // ProcessEncKey retrieves the encryption key for the given process ID from
-// the sequencer. It request the process information to the sequencer API and
-// extracts the encryption key from the response. If any error occurs during
-// the request or unmarshalling, it will be returned.
+// the sequencer. If any error occurs during the request, it will be returned.
 // The encryption key can be used to encrypt ballots for the specified process.
 func (s *CLIServices) ProcessEncKey(pid types.ProcessID) (*types.EncryptionKey, error) {
-	// Get the encryption keys from the sequencer
-	processEndpoint := api.EndpointWithParam(api.ProcessEndpoint, api.ProcessURLParam, pid.String())
-	log.Debugw("getting encryption keys",
-		"processID", pid.String(),
-		"endpoint", processEndpoint)
-	processResponse, status, err := s.cli.Request(http.MethodGet, nil, nil, processEndpoint)
+	process, err := s.cli.Process(s.ctx, pid)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get process info from sequencer: %v", err)
-	}
-	if status != http.StatusOK {
-		return nil, fmt.Errorf("failed to get process info from sequencer, status code: %d", status)
+		return nil, fmt.Errorf("failed to get process info from sequencer: %w", err)
 	}
-	var processInfo api.ProcessResponse
-	if err := json.Unmarshal(processResponse, &processInfo); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal process info: %v", err)
-	}
-	return processInfo.EncryptionKey, nil
+	return process.EncryptionKey, nil
 }
 
 // VoterWeight retrieves the voter weight for a given process ID and address
-// from the sequencer. It makes a request to the census participant endpoint
-// of the sequencer API and extracts the weight from the response. If any
-// error occurs during the request or unmarshalling, it will be returned.
+// from the census participant endpoint of the sequencer API. If any error
+// occurs during the request, it will be returned.
 func (s *CLIServices) VoterWeight(pid types.ProcessID, addr common.Address) (*types.BigInt, error) {
-	participantEndpoint := api.EndpointWithParam(api.CensusParticipantEndpoint, api.ProcessURLParam, pid.String())
-	participantEndpoint = api.EndpointWithParam(participantEndpoint, api.AddressURLParam, addr.Hex())
-
-	participantResponse, status, err := s.cli.Request(http.MethodGet, nil, nil, participantEndpoint)
+	participant, err := s.cli.Participant(s.ctx, pid, addr)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get participant info from sequencer: %v", err)
-	}
-	if status != http.StatusOK {
-		return nil, fmt.Errorf("failed to get participant info from sequencer, status code: %d", status)
+		return nil, fmt.Errorf("failed to get participant info from sequencer: %w", err)
 	}
-	var participantInfo api.CensusParticipant
-	if err := json.Unmarshal(participantResponse, &participantInfo); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal participant info: %v", err)
-	}
-	return participantInfo.Weight, nil
+	return participant.Weight, nil
 }
 
 // CreateVote creates a new vote for the given process ID and ballot mode. It
-// generates a random ballot based on the ballot mode and constructs the vote
-// structure with the necessary fields, including the ballot proof and
-// signature. If any error occurs during the process, it will be returned.
+// generates a random ballot based on the ballot mode and builds the vote
+// with the sequencer client, including the ballot proof and signature. If
+// any error occurs during the process, it will be returned.
 func (s *CLIServices) CreateVote(
 	privKey *ethereum.Signer,
 	pid types.ProcessID,
 	bm spec.BallotMode,
 ) (api.Vote, error) {
-	// Fetch the encryption key for the process
-	encKey, err := s.ProcessEncKey(pid)
-	if err != nil {
-		return api.Vote{}, fmt.Errorf("failed to get encryption key for process %s: %v", pid.String(), err)
-	}
-
-	// Get voter address
-	address := ethcrypto.PubkeyToAddress(privKey.PublicKey)
-	k, err := specutil.RandomK()
-	if err != nil {
-		return api.Vote{}, fmt.Errorf("failed to generate random k: %v", err)
-	}
-
-	// Get voter weight
-	weight, err := s.VoterWeight(pid, address)
-	if err != nil {
-		return api.Vote{}, fmt.Errorf("failed to get voter weight: %v", err)
-	}
-
 	// Generate random ballot fields based on the ballot mode
 	randFields := ballotprooftest.GenBallotFieldsForTest(
 		int(bm.NumFields),
 		int(bm.MaxValue),
 		int(bm.MinValue),
 		bm.UniqueValues)
-
-	// Cast fields to types.BigInt
-	fields := []*types.BigInt{}
-	for _, f := range randFields {
-		fields = append(fields, (*types.BigInt)(f))
-	}
-
-	// Compose wasm inputs
-	wasmInputs := &ballotproof.BallotProofInputs{
-		Address:   address.Bytes(),
-		ProcessID: pid,
-		EncryptionKey: []*types.BigInt{
-			(*types.BigInt)(encKey.X),
-			(*types.BigInt)(encKey.Y),
-		},
-		K:           (*types.BigInt)(k),
-		BallotMode:  bm,
-		Weight:      weight,
-		FieldValues: fields,
-	}
-
-	// Generate the inputs for the ballot proof circuit
-	wasmResult, err := ballotproof.GenerateBallotProofInputs(wasmInputs)
+	vote, err := s.cli.BuildVote(s.ctx, pid, privKey, randFields[:], nil)
 	if err != nil {
-		return api.Vote{}, fmt.Errorf("failed to generate ballot proof inputs: %v", err)
+		return api.Vote{}, fmt.Errorf("failed to create vote: %w", err)
 	}
+	return *vote, nil
+}
 
-	// Encode the inputs to json
-	encodedCircomInputs, err := json.Marshal(wasmResult.CircomInputs)
+// SpoilVote creates a new vote for the given process ID and ballot mode as
+// CreateVote, but spoils its ballot instead of submitting it, to audit its
+// encryption. Before sending the spoiled ballot to the sequencer, it decrypts
+// the ballot with its randomness and checks that it encrypts the random
+// ballot fields. It returns the spoiled ballot recorded by the sequencer,
+// which reports whether the sequencer verified the ballot too.
+func (s *CLIServices) SpoilVote(
+	privKey *ethereum.Signer,
+	pid types.ProcessID,
+	bm spec.BallotMode,
+) (*storage.SpoiledBallot, error) {
+	randFields := ballotprooftest.GenBallotFieldsForTest(
+		int(bm.NumFields),
+		int(bm.MaxValue),
+		int(bm.MinValue),
+		bm.UniqueValues)
+	vote, spoiled, err := s.cli.BuildSpoilableVote(s.ctx, pid, privKey, randFields[:], nil)
 	if err != nil {
-		return api.Vote{}, fmt.Errorf("failed to encode circom inputs: %v", err)
+		return nil, fmt.Errorf("failed to create vote: %w", err)
 	}
-
-	// Generate the proof using the circom circuit
-	rawProof, pubInputs, err := ballotprooftest.CompileAndGenerateProofForTest(encodedCircomInputs)
+	encKey, err := s.ProcessEncKey(pid)
 	if err != nil {
-		return api.Vote{}, fmt.Errorf("failed to generate proof: %v", err)
+		return nil, err
 	}
-
-	// Convert the proof to gnark format
-	circomProof, _, err := circomgnark.UnmarshalCircom(rawProof, pubInputs)
+	encryptionKey := new(bjj.BJJ).SetPoint(encKey.X.MathBigInt(), encKey.Y.MathBigInt())
+	if err := spoiled.Verify(encryptionKey); err != nil {
+		return nil, fmt.Errorf("failed to verify ballot encryption: %w", err)
+	}
+	// re-derive the plaintext from the ballot, regardless of the fields
+	// claimed
+	fields, err := spoiled.Decrypt(encryptionKey, bm.MaxValue)
 	if err != nil {
-		return api.Vote{}, fmt.Errorf("failed to convert proof to gnark format: %v", err)
+		return nil, fmt.Errorf("failed to decrypt ballot: %w", err)
 	}
-
-	// Sign the hash of the circuit inputs
-	signature, err := ballotprooftest.SignECDSAForTest(privKey, wasmResult.VoteID)
+	for i, field := range randFields {
+		if field.MathBigInt().Cmp(fields[i].MathBigInt()) != 0 {
+			return nil, fmt.Errorf("ballot field %d is %s, expected %s", i, fields[i].String(), field.String())
+		}
+	}
+	recorded, err := s.cli.SpoilBallot(s.ctx, &api.SpoilBallotRequest{
+		SpoiledBallot: *spoiled,
+		Signature:     vote.Signature,
+	})
 	if err != nil {
-		return api.Vote{}, fmt.Errorf("failed to sign vote: %v", err)
+		return nil, fmt.Errorf("failed to spoil ballot: %w", err)
 	}
-
-	// Return the vote ready to be sent to the sequencer
-	return api.Vote{
-		ProcessID:        wasmResult.ProcessID,
-		Address:          wasmInputs.Address,
-		Ballot:           wasmResult.Ballot,
-		BallotProof:      circomProof,
-		BallotInputsHash: wasmResult.BallotInputsHash,
-		Signature:        signature.Bytes(),
-		VoteID:           wasmResult.VoteID,
-		CensusProof: types.CensusProof{
-			Weight: weight,
-		},
-	}, nil
+	return recorded, nil
 }
 
-// SubmitVote submits the given vote to the sequencer API. It makes a POST
-// request to the votes endpoint with the vote data. If the request is
-// successful, it returns the vote ID. If any error occurs during the request
-// or if the response status code is not OK, it will be returned.
+// SubmitVote submits the given vote to the sequencer API. If the request is
+// successful, it returns the vote ID, otherwise the error is returned.
 func (s *CLIServices) SubmitVote(vote api.Vote) (types.VoteID, error) {
-	// Make the request to cast the vote
-	body, status, err := s.cli.Request(http.MethodPost, vote, nil, api.VotesEndpoint)
+	voteID, err := s.cli.SubmitVote(s.ctx, &vote)
 	if err != nil {
 		return 0, fmt.Errorf("failed to cast vote: %w", err)
-	} else if status != http.StatusOK {
-		return 0, fmt.Errorf("failed to cast vote (status code %d): %s", status, body)
 	}
-	return vote.VoteID, nil
+	return voteID, nil
 }
 
+// HasAddressAlreadyVoted reports whether the given address has already voted
+// in the process.
 func (s *CLIServices) HasAddressAlreadyVoted(pid types.ProcessID, address common.Address) (bool, error) {
-	// get participant from the sequencer
-	voteByAddressProcessEndpoint := api.EndpointWithParam(api.VoteByAddressEndpoint, api.ProcessURLParam, pid.String())
-	voteByAddressEndpoint := api.EndpointWithParam(voteByAddressProcessEndpoint, api.AddressURLParam, address.Hex())
-	voteByAddressBody, statusCode, err := s.cli.Request("GET", nil, nil, voteByAddressEndpoint)
-	if err != nil {
-		return false, fmt.Errorf("failed to request participant: %w", err)
-	}
-	if statusCode != 200 {
-		return false, fmt.Errorf("unexpected status code: %d: %s", statusCode, string(voteByAddressBody))
-	}
-	var voteByAddressResponse *elgamal.Ballot
-	err = json.NewDecoder(bytes.NewReader(voteByAddressBody)).Decode(&voteByAddressResponse)
-	if err != nil {
-		return false, fmt.Errorf("failed to decode already voted response: %w", err)
-	}
-	return voteByAddressResponse != nil, nil
+	return s.cli.HasVoted(s.ctx, pid, address)
 }
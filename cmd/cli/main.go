@@ -39,7 +39,7 @@ var (
 	cURI                             = flag.String("censusURI", "", "census URI to use (if empty, a new census will be created)")
 	votersCount                      = flag.Int("votersCount", 10, "number of voters that will cast a vote (half of them will rewrite it)")
 	web3Network                      = flag.StringP("web3.network", "n", defaultNetwork, fmt.Sprintf("network to use %v", npbindings.AvailableNetworksByName))
-	action                           = flag.String("action", "create", "create|stop|vote")
+	action                           = flag.String("action", "create", "create|stop|vote|spoil")
 	pid                              = flag.String("pid", "", "process ID to perform the action on")
 	voterPrivkey                     = flag.String("voterPrivkey", "", "private key to use for the voter account")
 )
@@ -137,6 +137,26 @@ func main() {
 			return
 		}
 		log.Infow("vote submitted", "voteID", voteID.String())
+	case "spoil":
+		processID, err := types.HexStringToProcessID(*pid)
+		if err != nil {
+			log.Errorw(err, "invalid process ID")
+			return
+		}
+		signer, err := ethereum.NewSignerFromHex(*voterPrivkey)
+		if err != nil {
+			log.Errorw(err, "invalid voter private key")
+			return
+		}
+		// Audit the encryption of a vote spoiling its ballot
+		spoiled, err := cliSrv.SpoilVote(signer, processID, ballotMode)
+		if err != nil {
+			log.Errorw(err, "failed to spoil ballot")
+			return
+		}
+		log.Infow("ballot spoiled",
+			"voteID", spoiled.VoteID.String(),
+			"verified", spoiled.Verified)
 	case "stop":
 		// Stop an existing process
 		processID, err := types.HexStringToProcessID(*pid)
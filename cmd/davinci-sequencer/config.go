@@ -2,6 +2,7 @@ package main
 
 import (
 	"fmt"
+	"net/http"
 	"os"
 	"path/filepath"
 	"strings"
@@ -9,8 +10,13 @@ import (
 
 	flag "github.com/spf13/pflag"
 	"github.com/spf13/viper"
+	"github.com/vocdoni/davinci-node/api"
 	"github.com/vocdoni/davinci-node/internal"
+	"github.com/vocdoni/davinci-node/sequencer"
+	"github.com/vocdoni/davinci-node/service"
 	"github.com/vocdoni/davinci-node/web3"
+	"github.com/vocdoni/davinci-node/webhooks"
+	"github.com/vocdoni/davinci-node/workers"
 )
 
 const (
@@ -24,11 +30,20 @@ const (
 	defaultLogDisableAPI              = false
 	defaultDatadir                    = ".davinci" // Will be prefixed with user's home directory
 	defaultGasMultiplier              = 1.2
+	defaultConfirmations              = 2
 	artifactsTimeout                  = 20 * time.Minute
 	monitorInterval                   = 10 * time.Second
 	defaultWorkersBanTimeout          = 30 * time.Minute
 	defaultWorkersAuthtokenExpiration = 90 * 24 * time.Hour // 90 days
 	defaultWorkerBanFailures          = 3
+	defaultWorkerProofJobTimeout      = 10 * time.Minute
+	defaultKeysFile                   = "keywrap.json" // Will be prefixed with the datadir
+	defaultRateLimitGlobal            = "1000/1s"
+	defaultRateLimitIP                = "50/1s"
+	defaultRateLimitVotes             = "60/1m"
+	defaultRateLimitKeys              = "20/1h"
+	defaultRateLimitVoter             = "5/1m"
+	defaultSettlementEndMargin        = 30 * time.Minute
 )
 
 // Version is the build version, set at build time with -ldflags
@@ -39,11 +54,17 @@ type Config struct {
 	Web3         web3.Web3Config
 	API          APIConfig
 	Batch        BatchConfig
+	Settlement   SettlementConfig
+	Census       CensusConfig
 	Log          LogConfig
 	Worker       WorkerConfig
 	Metadata     MetadataConfig
+	Keys         KeysConfig
+	Webhooks     WebhooksConfig
+	Process      ProcessConfig
 	Datadir      string
 	ForceCleanup bool `mapstructure:"forceCleanup"` // Force cleanup of all pending items at startup
+	Observer     bool `mapstructure:"observer"`     // Run a read-only observer node, without private key
 }
 
 // APIConfig holds the API-specific configuration
@@ -54,11 +75,94 @@ type APIConfig struct {
 	WorkersAuthtokenExpiration time.Duration `mapstructure:"workersAuthtokenExpiration"` // Expiration time for worker authentication tokens
 	WorkersBanTimeout          time.Duration `mapstructure:"workersBanTimeout"`          // Timeout for worker ban
 	WorkersFailuresToGetBanned int           `mapstructure:"workersFailuresToGetBanned"` // Number of failed jobs to get banned
+	DKGToken                   string        `mapstructure:"dkgToken"`                   // Bearer token required to create DKG sessions (empty disables it)
+	AdminToken                 string        `mapstructure:"adminToken"`                 // Bearer token required by the admin endpoints (empty disables them)
+	CensusToken                string        `mapstructure:"censusToken"`                // Bearer token required by the census builder endpoints (empty disables them)
+	RateLimitGlobal            string        `mapstructure:"rateLimitGlobal"`            // Requests per period of all the clients (0 disables it)
+	RateLimitIP                string        `mapstructure:"rateLimitIP"`                // Requests per period of each client IP (0 disables it)
+	RateLimitVotes             string        `mapstructure:"rateLimitVotes"`             // Votes per period of each client IP (0 disables it)
+	RateLimitKeys              string        `mapstructure:"rateLimitKeys"`              // Encryption keys per period of each client IP (0 disables it)
+	RateLimitVoter             string        `mapstructure:"rateLimitVoter"`             // Votes per period of each voter in each process (0 disables it)
+	TrustedProxies             int           `mapstructure:"trustedProxies"`             // Number of trusted reverse proxies that append the client IP to X-Forwarded-For
+}
+
+// RateLimits returns the rate limits of the API requests, or nil if all of
+// them are disabled.
+func (c *APIConfig) RateLimits() (*api.RateLimitConfig, error) {
+	conf := &api.RateLimitConfig{
+		Routes:         make(map[string]api.RateLimit),
+		TrustedProxies: c.TrustedProxies,
+	}
+	if c.TrustedProxies < 0 {
+		return nil, fmt.Errorf("invalid number of trusted proxies: %d", c.TrustedProxies)
+	}
+	var err error
+	if conf.Global, err = api.ParseRateLimit(c.RateLimitGlobal); err != nil {
+		return nil, fmt.Errorf("invalid global rate limit: %w", err)
+	}
+	if conf.PerIP, err = api.ParseRateLimit(c.RateLimitIP); err != nil {
+		return nil, fmt.Errorf("invalid IP rate limit: %w", err)
+	}
+	votes, err := api.ParseRateLimit(c.RateLimitVotes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid votes rate limit: %w", err)
+	}
+	conf.Routes[api.RateLimitRoute(http.MethodPost, api.VotesEndpoint)] = votes
+	keys, err := api.ParseRateLimit(c.RateLimitKeys)
+	if err != nil {
+		return nil, fmt.Errorf("invalid encryption keys rate limit: %w", err)
+	}
+	conf.Routes[api.RateLimitRoute(http.MethodPost, api.NewEncryptionKeysEndpoint)] = keys
+	if conf.PerVoter, err = api.ParseRateLimit(c.RateLimitVoter); err != nil {
+		return nil, fmt.Errorf("invalid voter rate limit: %w", err)
+	}
+	if !conf.Global.Enabled() && !conf.PerIP.Enabled() && !votes.Enabled() &&
+		!keys.Enabled() && !conf.PerVoter.Enabled() {
+		return nil, nil
+	}
+	return conf, nil
 }
 
 // BatchConfig holds batch processing configuration
 type BatchConfig struct {
-	Time time.Duration `mapstructure:"time"` // Maximum time window to wait for batch processing
+	Time   time.Duration `mapstructure:"time"`   // Maximum time window to wait for batch processing
+	Policy string        `mapstructure:"policy"` // Default batch policy of the processes (empty for the time window)
+}
+
+// SettlementConfig holds the fee ceilings of the on-chain state transitions
+type SettlementConfig struct {
+	MaxGasTipCap   string        `mapstructure:"maxGasTipCap"`   // Maximum priority fee per gas to settle, in wei or gwei (empty to disable)
+	MaxBlobBaseFee string        `mapstructure:"maxBlobBaseFee"` // Maximum blob base fee to settle, in wei or gwei (empty to disable)
+	EndMargin      time.Duration `mapstructure:"endMargin"`      // Time before the process end from which the fees are ignored
+}
+
+// CensusConfig holds the census import configuration
+type CensusConfig struct {
+	FileDir string `mapstructure:"fileDir"` // Directory from which the censuses can be imported with file:// URIs (empty disables them)
+}
+
+// Sequencer returns the settlement config of the sequencer, or nil if no
+// fee ceiling is configured.
+func (c *SettlementConfig) Sequencer() (*sequencer.SettlementConfig, error) {
+	if c.MaxGasTipCap == "" && c.MaxBlobBaseFee == "" {
+		return nil, nil
+	}
+	if c.EndMargin < 0 {
+		return nil, fmt.Errorf("settlement end margin must not be negative, got: %s", c.EndMargin)
+	}
+	conf := &sequencer.SettlementConfig{EndMargin: c.EndMargin}
+	var err error
+	if c.MaxGasTipCap != "" {
+		if conf.MaxGasTipCap, err = sequencer.ParseWei(c.MaxGasTipCap); err != nil {
+			return nil, fmt.Errorf("invalid settlement max gas tip cap: %w", err)
+		}
+	}
+	if c.MaxBlobBaseFee != "" {
+		if conf.MaxBlobBaseFee, err = sequencer.ParseWei(c.MaxBlobBaseFee); err != nil {
+			return nil, fmt.Errorf("invalid settlement max blob base fee: %w", err)
+		}
+	}
+	return conf, nil
 }
 
 // LogConfig holds logging configuration
@@ -71,6 +175,8 @@ type LogConfig struct {
 // WorkerConfig holds worker-related configuration
 type WorkerConfig struct {
 	Timeout      time.Duration `mapstructure:"timeout"`      // Timeout for worker jobs
+	ProofTimeout time.Duration `mapstructure:"proofTimeout"` // Timeout for worker aggregation and state transition jobs
+	Jobs         string        `mapstructure:"jobs"`         // Comma separated job types taken by the worker
 	Address      string        `mapstructure:"address"`      // Ethereum address for the worker (auto-generated if empty)
 	Name         string        `mapstructure:"name"`         // Name of the worker for identification
 	Authtoken    string        `mapstructure:"authtoken"`    // Worker authentication token
@@ -85,6 +191,32 @@ type MetadataConfig struct {
 	PinataGatewayToken string `mapstructure:"pinataGatewayToken"` // Pinata gateway token
 }
 
+// KeysConfig holds the configuration of the key-encryption key used to wrap
+// the process private keys at rest
+type KeysConfig struct {
+	Passphrase         string `mapstructure:"passphrase"`         // Passphrase to derive the key-encryption key (empty keeps the keys in the clear)
+	File               string `mapstructure:"file"`               // Key file with the key derivation parameters (defaults to datadir/keywrap.json)
+	PreviousPassphrase string `mapstructure:"previousPassphrase"` // Passphrase of the previous key-encryption key, to rotate it
+	PreviousFile       string `mapstructure:"previousFile"`       // Key file of the previous key-encryption key, to rotate it
+}
+
+// WebhooksConfig holds the process lifecycle webhooks configuration
+type WebhooksConfig struct {
+	Token       string `mapstructure:"token"`       // Bearer token of the webhook subscription endpoints (empty disables webhooks)
+	MaxAttempts int    `mapstructure:"maxAttempts"` // Delivery attempts before a webhook delivery is marked as failed
+	// Allow webhook URLs targeting loopback, link-local or private addresses
+	AllowPrivateNetworks bool `mapstructure:"allowPrivateNetworks"`
+}
+
+// ProcessConfig holds the configuration to export or import a process
+// instead of running the sequencer
+type ProcessConfig struct {
+	Export     string `mapstructure:"export"`     // Process ID to export to the process file
+	Import     string `mapstructure:"import"`     // Process export file to import
+	File       string `mapstructure:"file"`       // File to write the process export to
+	Passphrase string `mapstructure:"passphrase"` // Passphrase that wraps the process private key in the export
+}
+
 // loadConfig loads configuration from flags, environment variables, and defaults
 func loadConfig() (*Config, error) {
 	cfg := &Config{}
@@ -104,23 +236,42 @@ func loadConfig() (*Config, error) {
 	flag.StringSliceP("web3.rpc", "r", []string{defaultRPC}, "web3 rpc endpoint(s), comma-separated")
 	flag.StringSlice("web3.bapi", []string{defaultConsensusAPI}, "consensus api endpoints(s), comma-separated")
 	flag.Float64("web3.gasMultiplier", defaultGasMultiplier, "gas price multiplier for transactions (1.0 = default, 2.0 = double gas prices)")
+	flag.Uint64("web3.confirmations", defaultConfirmations, "blocks to wait on top of a block before applying its process events")
+	flag.Uint64("web3.reorgDepth", service.DefaultReorgDepth, "blocks after which the applied state transitions are final and not checked for reorgs anymore (0 disables the reorg detection)")
 	flag.StringSlice("web3.processRegistryContract", nil, "'chainID:0xaddress' of the process registry smart contract, if defined, it will be included in the available networks if a valid RPC endpoint is provided")
 	// sequencer API
 	flag.StringP("api.host", "h", defaultAPIHost, "API host")
 	flag.IntP("api.port", "p", defaultAPIPort, "API port")
 	flag.DurationP("batch.time", "b", defaultBatchTime, "sequencer batch max time window (i.e 10m or 1h)")
+	flag.String("batch.policy", "", "default batch policy as key=value options (i.e minFill=0.5,maxGasPrice=30gwei,deadline=10m), the window defaults to batch.time")
+	flag.String("settlement.maxGasTipCap", "", "hold the state transitions while the priority fee per gas is above this value, in wei or gwei (i.e 2gwei)")
+	flag.String("settlement.maxBlobBaseFee", "", "hold the state transitions while the blob base fee is above this value, in wei or gwei (i.e 10gwei)")
+	flag.Duration("settlement.endMargin", defaultSettlementEndMargin, "time before the process end from which the state transitions are settled whatever the fees")
+	flag.String("census.fileDir", "", "directory from which the CSV and Parquet censuses can be imported with file:// URIs (empty disables them)")
 	flag.StringP("log.level", "l", defaultLogLevel, "log level (debug, info, warn, error, fatal)")
 	flag.StringP("log.output", "o", defaultLogOutput, "log output (stdout, stderr or filepath)")
 	flag.Bool("log.disableAPI", defaultLogDisableAPI, "disable API logging middleware")
 	flag.StringP("datadir", "d", defaultDatadirPath, "data directory for database and storage files")
 	flag.Bool("forceCleanup", false, "force cleanup of all pending verified votes, aggregated batches and state transitions at startup")
+	flag.Bool("observer", false, "run a read-only observer node without private key, which follows every process and reconstructs its state from the blobs to audit it")
 	// sequencer workers api flags
 	flag.String("api.workersSeed", "", "enable master worker endpoint with URL seed for authentication")
 	flag.Duration("api.workersBanTimeout", defaultWorkersBanTimeout, "timeout for worker ban in seconds")
 	flag.Duration("api.workersAuthtokenExpiration", defaultWorkersAuthtokenExpiration, "timeout for worker authentication token expiration")
 	flag.Int("api.workersFailuresToGetBanned", defaultWorkerBanFailures, "number of failed jobs to get banned")
+	flag.String("api.dkgToken", "", "bearer token required to create DKG sessions through the API (empty disables the creation of DKG sessions)")
+	flag.String("api.adminToken", "", "bearer token required by the admin API endpoints (empty disables the admin API)")
+	flag.String("api.censusToken", "", "bearer token required by the census builder API endpoints (empty disables the census builder API)")
+	flag.String("api.rateLimitGlobal", defaultRateLimitGlobal, "requests per period of all the API clients, as <requests>/<period> (0 disables it)")
+	flag.String("api.rateLimitIP", defaultRateLimitIP, "requests per period of each API client IP, as <requests>/<period> (0 disables it)")
+	flag.String("api.rateLimitVotes", defaultRateLimitVotes, "votes per period of each API client IP, as <requests>/<period> (0 disables it)")
+	flag.String("api.rateLimitKeys", defaultRateLimitKeys, "encryption keys per period of each API client IP, as <requests>/<period> (0 disables it)")
+	flag.String("api.rateLimitVoter", defaultRateLimitVoter, "votes per period of each voter address in each process, as <requests>/<period> (0 disables it)")
+	flag.Int("api.trustedProxies", 0, "number of trusted reverse proxies in front of the API, the client IP is the X-Forwarded-For entry appended by the outermost one (0 uses the connection IP)")
 	// worker mode flags
 	flag.Duration("worker.timeout", 1*time.Minute, "worker job timeout duration")
+	flag.Duration("worker.proofTimeout", defaultWorkerProofJobTimeout, "worker aggregation and state transition job timeout duration")
+	flag.String("worker.jobs", string(workers.JobTypeBallot), "comma separated job types taken by the worker (ballot, aggregateBatch, stateTransition)")
 	flag.StringP("worker.address", "a", "", "worker Ethereum address")
 	flag.String("worker.name", "", "worker name for identification")
 	flag.StringP("worker.authtoken", "t", "", "worker authentication token (required for running in worker mode)")
@@ -130,6 +281,20 @@ func loadConfig() (*Config, error) {
 	flag.String("metadata.pinataHostnameJWT", "", "pinata hostname JWT")
 	flag.String("metadata.pinataGatewayURL", "https://gateway.pinata.cloud/ipfs", "pinata gateway URL")
 	flag.String("metadata.pinataGatewayToken", "", "pinata gateway token")
+	// process private keys encryption at rest
+	flag.String("keys.passphrase", "", "passphrase to derive the key-encryption key that wraps the process private keys at rest (empty keeps them in the clear)")
+	flag.String("keys.file", "", "key file with the key-encryption key derivation parameters, created if missing (defaults to <datadir>/"+defaultKeysFile+")")
+	flag.String("keys.previousPassphrase", "", "passphrase of the previous key-encryption key, the keys wrapped with it are rewrapped with the current one")
+	flag.String("keys.previousFile", "", "key file of the previous key-encryption key (required with --keys.previousPassphrase)")
+	// process lifecycle webhooks
+	flag.String("webhooks.token", "", "bearer token required to manage the webhook subscriptions through the API (empty disables webhooks)")
+	flag.Int("webhooks.maxAttempts", webhooks.DefaultConfig.MaxAttempts, "delivery attempts before a webhook delivery is marked as failed")
+	flag.Bool("webhooks.allowPrivateNetworks", false, "allow webhook URLs targeting loopback, link-local or private addresses")
+	// process export and import
+	flag.String("process.export", "", "export the process with this ID to the file provided with --process.file and exit")
+	flag.String("process.file", "", "file to write the process export to")
+	flag.String("process.import", "", "import the process export file provided, checking its state root on chain, and exit")
+	flag.String("process.passphrase", "", "passphrase to wrap the process private key in the export, required to export and import a process")
 
 	// Configure usage information
 	flag.Usage = func() {
@@ -146,7 +311,13 @@ func loadConfig() (*Config, error) {
 		fmt.Fprintf(os.Stderr, "  # Start with custom RPC endpoints\n")
 		fmt.Fprintf(os.Stderr, "  davinci-sequencer --web3.privkey=0x123... --web3.rpc=https://rpc1.com,https://rpc2.com\n\n")
 		fmt.Fprintf(os.Stderr, "  # Start in multinetwork mode with structured runtime configs\n")
-		fmt.Fprintf(os.Stderr, "  davinci-sequencer --web3.privkey=0x123... --web3.rpc=https://network1.rpc.com,https://network2.rpc.com --web3.capi=https://network1.beaconapi.com,https://network2.beaconapi.com\n\n\n")
+		fmt.Fprintf(os.Stderr, "  davinci-sequencer --web3.privkey=0x123... --web3.rpc=https://network1.rpc.com,https://network2.rpc.com --web3.capi=https://network1.beaconapi.com,https://network2.beaconapi.com\n\n")
+		fmt.Fprintf(os.Stderr, "  # Start a read-only observer node to audit the processes\n")
+		fmt.Fprintf(os.Stderr, "  davinci-sequencer --observer\n\n")
+		fmt.Fprintf(os.Stderr, "  # Export a process to move it to another sequencer\n")
+		fmt.Fprintf(os.Stderr, "  davinci-sequencer --web3.privkey=0x123... --process.export=0xabc... --process.file=process.json.gz --process.passphrase=...\n\n")
+		fmt.Fprintf(os.Stderr, "  # Import a process exported by another sequencer\n")
+		fmt.Fprintf(os.Stderr, "  davinci-sequencer --web3.privkey=0x123... --process.import=process.json.gz --process.passphrase=...\n\n\n")
 	}
 
 	// Parse flags
@@ -173,7 +344,17 @@ func loadConfig() (*Config, error) {
 // validateConfig validates the loaded configuration
 func validateConfig(cfg *Config) error {
 	// Validate required fields
-	if cfg.Web3.PrivKey == "" {
+	if cfg.Observer {
+		if cfg.Web3.PrivKey != "" {
+			return fmt.Errorf("observer nodes run without private key, remove the --web3.privkey flag")
+		}
+		if cfg.Process.Export != "" || cfg.Process.Import != "" {
+			return fmt.Errorf("processes can not be exported nor imported by observer nodes")
+		}
+		if cfg.API.SequencerWorkersSeed != "" {
+			return fmt.Errorf("observer nodes have no workers, remove the --api.workersSeed flag")
+		}
+	} else if cfg.Web3.PrivKey == "" {
 		return fmt.Errorf("private key is required (use --privkey flag or DAVINCI_WEB3_PRIVKEY environment variable)")
 	}
 
@@ -185,5 +366,40 @@ func validateConfig(cfg *Config) error {
 		return fmt.Errorf("gas multiplier too high (max 100), got: %f", cfg.Web3.GasMultiplier)
 	}
 
+	// Validate process export and import
+	if (cfg.Process.Export != "" || cfg.Process.Import != "") && cfg.Process.Passphrase == "" {
+		return fmt.Errorf("the process export passphrase is required (use --process.passphrase flag or DAVINCI_PROCESS_PASSPHRASE environment variable)")
+	}
+
+	// Validate key-encryption key rotation
+	if cfg.Keys.PreviousPassphrase != "" {
+		if cfg.Keys.Passphrase == "" {
+			return fmt.Errorf("a new keys passphrase is required to rotate the previous one (use --keys.passphrase flag)")
+		}
+		if cfg.Keys.PreviousFile == "" {
+			return fmt.Errorf("the previous key file is required to rotate the keys passphrase (use --keys.previousFile flag)")
+		}
+	}
+
+	// Validate batch policy
+	if _, err := sequencer.ParseBatchPolicy(cfg.Batch.Policy, cfg.Batch.Time); err != nil {
+		return fmt.Errorf("invalid batch policy: %w", err)
+	}
+
+	// Validate settlement fee ceilings
+	if _, err := cfg.Settlement.Sequencer(); err != nil {
+		return err
+	}
+
+	// Validate API rate limits
+	if _, err := cfg.API.RateLimits(); err != nil {
+		return err
+	}
+
+	// Validate webhooks
+	if cfg.Webhooks.MaxAttempts < 1 {
+		return fmt.Errorf("webhooks max attempts must be greater than 0, got: %d", cfg.Webhooks.MaxAttempts)
+	}
+
 	return nil
 }
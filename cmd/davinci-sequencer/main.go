@@ -8,6 +8,7 @@ import (
 	"path"
 	"syscall"
 
+	"github.com/vocdoni/davinci-node/crypto/keywrap"
 	"github.com/vocdoni/davinci-node/db"
 	"github.com/vocdoni/davinci-node/db/metadb"
 	"github.com/vocdoni/davinci-node/log"
@@ -17,6 +18,7 @@ import (
 	"github.com/vocdoni/davinci-node/storage"
 	"github.com/vocdoni/davinci-node/web3"
 	"github.com/vocdoni/davinci-node/web3/txmanager"
+	"github.com/vocdoni/davinci-node/webhooks"
 	"github.com/vocdoni/davinci-node/workers"
 )
 
@@ -24,6 +26,7 @@ import (
 type Services struct {
 	TxManagers       []*txmanager.TxManager
 	Storage          *storage.Storage
+	Webhooks         *webhooks.Manager
 	StateSync        *service.StateSync
 	CensusDownloader *service.CensusDownloader
 	ProcessMons      []*service.ProcessMonitor
@@ -59,6 +62,14 @@ func main() {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
+	// Export or import a process and exit if requested
+	if cfg.Process.Export != "" || cfg.Process.Import != "" {
+		if err := runProcessTool(ctx, cfg); err != nil {
+			log.Fatalf("Process export/import failed: %v", err)
+		}
+		return
+	}
+
 	// Setup services
 	services, err := setupServices(ctx, cfg)
 	if err != nil {
@@ -89,6 +100,11 @@ func runWorkerMode(cfg *Config) {
 	if cfg.Worker.SequencerURL == "" {
 		log.Fatalf("valid worker sequencer URL is required (use --worker.sequencerURL flag)")
 	}
+	// Check the job types taken by the worker
+	jobTypes, err := workers.ParseJobTypes(cfg.Worker.Jobs)
+	if err != nil {
+		log.Fatalf("invalid worker job types (use --worker.jobs flag): %v", err)
+	}
 
 	// Initialize storage database (only for local process tracking)
 	log.Infow("initializing storage", "datadir", cfg.Datadir, "type", db.TypePebble)
@@ -104,7 +120,7 @@ func runWorkerMode(cfg *Config) {
 	artifactsCtx, cancel := context.WithTimeout(context.Background(), artifactsTimeout)
 	defer cancel()
 	log.Infow("preparing zkSNARK circuit worker artifacts", "timeout", artifactsTimeout, "artifactsDir", artifactsDir)
-	if err := service.DownloadWorkerArtifacts(artifactsCtx, artifactsDir); err != nil {
+	if err := service.DownloadWorkerArtifacts(artifactsCtx, artifactsDir, jobTypes); err != nil {
 		log.Fatalf("failed to download artifacts: %v", err)
 	}
 
@@ -115,6 +131,7 @@ func runWorkerMode(cfg *Config) {
 		cfg.Worker.Address,
 		cfg.Worker.Authtoken,
 		cfg.Worker.Name,
+		jobTypes,
 	)
 	if err != nil {
 		log.Fatalf("failed to create worker: %v", err)
@@ -142,6 +159,50 @@ func runWorkerMode(cfg *Config) {
 	}
 }
 
+// setupKeyWrapper configures the key wrapper used to encrypt the process
+// private keys at rest, wrapping the keys stored in the clear and rewrapping
+// the keys wrapped with the previous key-encryption key, if provided.
+func setupKeyWrapper(cfg *Config, stg *storage.Storage) error {
+	if cfg.Keys.Passphrase == "" {
+		// Refuse to start if some keys were wrapped, they could not be used
+		// to decrypt the results
+		missing, err := stg.MissingKeyWrappers()
+		if err != nil {
+			return fmt.Errorf("failed to check wrapped keys: %w", err)
+		}
+		if len(missing) > 0 {
+			return fmt.Errorf("stored process private keys are wrapped with %v, the keys passphrase is required (use --keys.passphrase flag)", missing)
+		}
+		log.Warn("no keys passphrase provided, process private keys are stored in the clear (use --keys.passphrase flag)")
+		return nil
+	}
+	keysFile := cfg.Keys.File
+	if keysFile == "" {
+		keysFile = path.Join(cfg.Datadir, defaultKeysFile)
+	}
+	current, err := keywrap.NewLocalKeyWrapper(keysFile, cfg.Keys.Passphrase)
+	if err != nil {
+		return fmt.Errorf("failed to load key file %s: %w", keysFile, err)
+	}
+	var previous []keywrap.KeyWrapper
+	if cfg.Keys.PreviousPassphrase != "" {
+		kw, err := keywrap.NewLocalKeyWrapper(cfg.Keys.PreviousFile, cfg.Keys.PreviousPassphrase)
+		if err != nil {
+			return fmt.Errorf("failed to load previous key file %s: %w", cfg.Keys.PreviousFile, err)
+		}
+		previous = append(previous, kw)
+	}
+	rewrapped, err := stg.SetKeyWrapper(current, previous...)
+	if err != nil {
+		return fmt.Errorf("failed to set key wrapper: %w", err)
+	}
+	log.Infow("process private keys encrypted at rest",
+		"keyWrapper", current.ID(),
+		"keyFile", keysFile,
+		"rewrapped", rewrapped)
+	return nil
+}
+
 // setupServices initializes and starts all required services
 func setupServices(ctx context.Context, cfg *Config) (services *Services, err error) {
 	services = &Services{}
@@ -151,13 +212,17 @@ func setupServices(ctx context.Context, cfg *Config) (services *Services, err er
 		}
 	}()
 
-	// Download circuit artifacts
-	artifactsDir := path.Join(cfg.Datadir, "artifacts")
-	artifactsCtx, cancel := context.WithTimeout(context.Background(), artifactsTimeout)
-	defer cancel()
-	log.Infow("preparing zkSNARK circuit full sequencer artifacts", "timeout", artifactsTimeout, "artifactsDir", artifactsDir)
-	if err := service.DownloadArtifacts(artifactsCtx, artifactsDir); err != nil {
-		return nil, fmt.Errorf("failed to download artifacts: %w", err)
+	// Download circuit artifacts, observer nodes do not generate proofs
+	if cfg.Observer {
+		log.Info("starting in observer mode, the API is read-only and no votes are sequenced")
+	} else {
+		artifactsDir := path.Join(cfg.Datadir, "artifacts")
+		artifactsCtx, cancel := context.WithTimeout(context.Background(), artifactsTimeout)
+		defer cancel()
+		log.Infow("preparing zkSNARK circuit full sequencer artifacts", "timeout", artifactsTimeout, "artifactsDir", artifactsDir)
+		if err := service.DownloadArtifacts(artifactsCtx, artifactsDir); err != nil {
+			return nil, fmt.Errorf("failed to download artifacts: %w", err)
+		}
 	}
 
 	// Initialize storage database
@@ -167,6 +232,11 @@ func setupServices(ctx context.Context, cfg *Config) (services *Services, err er
 		return nil, fmt.Errorf("failed to initialize storage: %w", err)
 	}
 	services.Storage = storage.New(storagedb)
+	if !cfg.Observer {
+		if err := setupKeyWrapper(cfg, services.Storage); err != nil {
+			return nil, err
+		}
+	}
 
 	// Force cleanup if requested
 	if cfg.ForceCleanup {
@@ -177,7 +247,18 @@ func setupServices(ctx context.Context, cfg *Config) (services *Services, err er
 		log.Info("force cleanup completed successfully")
 	}
 
-	runtimes, err := cfg.Web3.InitRuntimes(ctx)
+	// Start the process lifecycle webhooks if enabled
+	if cfg.Webhooks.Token != "" {
+		log.Infow("starting process lifecycle webhooks", "maxAttempts", cfg.Webhooks.MaxAttempts)
+		webhooksConfig := webhooks.DefaultConfig
+		webhooksConfig.MaxAttempts = cfg.Webhooks.MaxAttempts
+		webhooksConfig.AllowPrivateNetworks = cfg.Webhooks.AllowPrivateNetworks
+		services.Webhooks = webhooks.New(storagedb, webhooksConfig)
+		services.Storage.AddProcessEventListener(services.Webhooks.Notify)
+		services.Webhooks.Start(ctx)
+	}
+
+	runtimes, err := cfg.Web3.InitRuntimes(ctx, storagedb)
 	if err != nil {
 		return nil, fmt.Errorf("failed to initialize runtimes: %w", err)
 	}
@@ -186,6 +267,7 @@ func setupServices(ctx context.Context, cfg *Config) (services *Services, err er
 			"chainID", runtime.ChainID,
 			"account", runtime.Contracts.AccountAddress().Hex(),
 			"gasMultiplier", runtime.Contracts.GasMultiplier,
+			"confirmations", cfg.Web3.Confirmations,
 			"availableEndpoints", runtime.AvailableEndpoints(),
 			"processRegistry", runtime.Contracts.ContractsAddresses.ProcessRegistry.Hex(),
 			"consensusAPI", runtime.Contracts.Web3ConsensusAPIEndpoint,
@@ -200,7 +282,10 @@ func setupServices(ctx context.Context, cfg *Config) (services *Services, err er
 
 	// Start census downloader
 	log.Info("starting census downloader")
-	services.CensusDownloader = service.NewCensusDownloader(runtimeRouter, services.Storage, service.DefaultCensusDownloaderConfig)
+	downloaderConfig := service.DefaultCensusDownloaderConfig
+	downloaderConfig.FileDir = cfg.Census.FileDir
+	downloaderConfig.ChainClient = runtimeRouter.ClientForChainID
+	services.CensusDownloader = service.NewCensusDownloader(runtimeRouter, services.Storage, downloaderConfig)
 	if err := services.CensusDownloader.Start(ctx); err != nil {
 		return nil, fmt.Errorf("failed to start census downloader: %w", err)
 	}
@@ -224,6 +309,7 @@ func setupServices(ctx context.Context, cfg *Config) (services *Services, err er
 			services.StateSync,
 			monitorInterval,
 		)
+		processMon.SetReorgDepth(cfg.Web3.ReorgDepth)
 		if err := processMon.Start(ctx); err != nil {
 			return nil, fmt.Errorf("failed to start process monitor for %d: %w", runtime.ChainID, err)
 		}
@@ -256,16 +342,71 @@ func setupServices(ctx context.Context, cfg *Config) (services *Services, err er
 				FailuresToGetBanned: cfg.API.WorkersFailuresToGetBanned,
 			},
 		)
+		services.API.SetWorkerProofJobTimeout(cfg.Worker.ProofTimeout)
 	}
 
+	// Enable the creation of DKG sessions if a token is set
+	if cfg.API.DKGToken != "" {
+		services.API.SetDKGToken(cfg.API.DKGToken)
+	}
+
+	// Enable the admin endpoints if a token is set
+	if cfg.API.AdminToken != "" {
+		services.API.SetAdminToken(cfg.API.AdminToken)
+	}
+
+	// Enable the census builder endpoints if a token is set
+	if cfg.API.CensusToken != "" {
+		services.API.SetCensusToken(cfg.API.CensusToken)
+	}
+
+	// Rate limit the API requests unless every limit is disabled
+	rateLimits, err := cfg.API.RateLimits()
+	if err != nil {
+		return nil, fmt.Errorf("invalid API rate limits: %w", err)
+	}
+	if rateLimits != nil {
+		services.API.SetRateLimits(rateLimits)
+	}
+
+	// Enable the webhook subscription endpoints if webhooks are enabled
+	if services.Webhooks != nil {
+		services.API.SetWebhooks(services.Webhooks, cfg.Webhooks.Token)
+	}
+
+	// Reject the requests that write to the node in observer mode
+	services.API.SetReadOnly(cfg.Observer)
+
 	// Start API service
 	if err := services.API.Start(ctx); err != nil {
 		return nil, fmt.Errorf("failed to start API service: %w", err)
 	}
 
+	// Observer nodes only follow the processes and serve their state
+	if cfg.Observer {
+		log.Info("davinci-node is running in observer mode, following every process")
+		return services, nil
+	}
+
 	// Start sequencer service
-	log.Infow("starting sequencer service", "batchTimeWindow", cfg.Batch.Time.String())
+	log.Infow("starting sequencer service", "batchTimeWindow", cfg.Batch.Time.String(), "batchPolicy", cfg.Batch.Policy)
 	services.Sequencer = service.NewSequencer(services.Storage, runtimeRouter, cfg.Batch.Time, services.API.API)
+	batchPolicy, err := sequencer.ParseBatchPolicy(cfg.Batch.Policy, cfg.Batch.Time)
+	if err != nil {
+		return nil, fmt.Errorf("invalid batch policy: %w", err)
+	}
+	services.Sequencer.Sequencer.SetDefaultBatchPolicy(batchPolicy)
+	settlement, err := cfg.Settlement.Sequencer()
+	if err != nil {
+		return nil, err
+	}
+	if settlement != nil {
+		log.Infow("state transitions held by fees",
+			"maxGasTipCap", cfg.Settlement.MaxGasTipCap,
+			"maxBlobBaseFee", cfg.Settlement.MaxBlobBaseFee,
+			"endMargin", cfg.Settlement.EndMargin.String())
+		services.Sequencer.Sequencer.SetSettlementConfig(settlement)
+	}
 	if err := services.Sequencer.Start(ctx); err != nil {
 		return nil, fmt.Errorf("failed to start sequencer service: %w", err)
 	}
@@ -302,6 +443,9 @@ func shutdownServices(services *Services) {
 			txManager.Stop()
 		}
 	}
+	if services.Webhooks != nil {
+		services.Webhooks.Stop()
+	}
 	if services.Storage != nil {
 		services.Storage.Close()
 	}
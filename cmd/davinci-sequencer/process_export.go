@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/vocdoni/davinci-node/db"
+	"github.com/vocdoni/davinci-node/db/metadb"
+	"github.com/vocdoni/davinci-node/log"
+	"github.com/vocdoni/davinci-node/storage"
+	"github.com/vocdoni/davinci-node/types"
+	"github.com/vocdoni/davinci-node/web3"
+)
+
+// runProcessTool exports or imports a process as requested by the process
+// flags, without starting the sequencer services.
+func runProcessTool(ctx context.Context, cfg *Config) error {
+	if cfg.Process.Export != "" && cfg.Process.Import != "" {
+		return fmt.Errorf("a process can not be exported and imported at the same time")
+	}
+
+	log.Infow("initializing storage", "datadir", cfg.Datadir, "type", db.TypePebble)
+	storagedb, err := metadb.New(db.TypePebble, cfg.Datadir)
+	if err != nil {
+		return fmt.Errorf("failed to initialize storage: %w", err)
+	}
+	stg := storage.New(storagedb)
+	defer stg.Close()
+	if err := setupKeyWrapper(cfg, stg); err != nil {
+		return err
+	}
+
+	// The runtimes are only used to read the chain, so their pending
+	// transactions are not restored
+	runtimes, err := cfg.Web3.InitRuntimes(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to initialize runtimes: %w", err)
+	}
+	defer func() {
+		for _, runtime := range runtimes {
+			runtime.TxManager.Stop()
+		}
+	}()
+	runtimeRouter, err := web3.NewRuntimeRouter(runtimes...)
+	if err != nil {
+		return fmt.Errorf("failed to create runtime router: %w", err)
+	}
+
+	if cfg.Process.Export != "" {
+		return exportProcess(cfg, stg, runtimeRouter)
+	}
+	return importProcess(cfg, stg, runtimeRouter)
+}
+
+// exportProcess writes the export of the process provided with the
+// process.export flag to the file provided with the process.file flag. The
+// process private key is wrapped with the passphrase provided with the
+// process.passphrase flag, and the file is only readable by its owner.
+func exportProcess(cfg *Config, stg *storage.Storage, runtimeRouter *web3.RuntimeRouter) error {
+	if cfg.Process.File == "" {
+		return fmt.Errorf("the export file is required (use --process.file flag)")
+	}
+	processID, err := types.HexStringToProcessID(cfg.Process.Export)
+	if err != nil {
+		return fmt.Errorf("invalid process ID: %w", err)
+	}
+	runtime, err := runtimeRouter.RuntimeForProcess(processID)
+	if err != nil {
+		return fmt.Errorf("failed to find the process network: %w", err)
+	}
+	export, err := stg.ExportProcess(processID, runtime.ChainID, cfg.Process.Passphrase)
+	if err != nil {
+		return fmt.Errorf("failed to export process: %w", err)
+	}
+
+	f, err := os.OpenFile(cfg.Process.File, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to create export file: %w", err)
+	}
+	if err := export.Write(f); err != nil {
+		_ = f.Close()
+		return fmt.Errorf("failed to write export file: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to write export file: %w", err)
+	}
+	log.Infow("process exported",
+		"processID", processID.String(),
+		"stateRoot", export.StateRoot.String(),
+		"file", cfg.Process.File)
+	return nil
+}
+
+// importProcess imports the process export file provided with the
+// process.import flag, after checking its state root against the on-chain
+// state root of the process. The process private key is unwrapped with the
+// passphrase provided with the process.passphrase flag.
+func importProcess(cfg *Config, stg *storage.Storage, runtimeRouter *web3.RuntimeRouter) error {
+	f, err := os.Open(cfg.Process.Import)
+	if err != nil {
+		return fmt.Errorf("failed to open export file: %w", err)
+	}
+	defer func() {
+		if err := f.Close(); err != nil {
+			log.Warnw("failed to close export file", "error", err.Error())
+		}
+	}()
+	export, err := storage.ReadProcessExport(f)
+	if err != nil {
+		return err
+	}
+	runtime, err := runtimeRouter.RuntimeForProcess(export.ProcessID)
+	if err != nil {
+		return fmt.Errorf("failed to find the process network: %w", err)
+	}
+	onchain, err := runtime.Contracts.Process(export.ProcessID)
+	if err != nil {
+		return fmt.Errorf("failed to get the on-chain process: %w", err)
+	}
+	if err := stg.ImportProcess(export, cfg.Process.Passphrase, runtime.ChainID, onchain); err != nil {
+		return fmt.Errorf("failed to import process: %w", err)
+	}
+	return nil
+}
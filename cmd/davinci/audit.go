@@ -0,0 +1,198 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/vocdoni/davinci-node/crypto/ecc"
+	"github.com/vocdoni/davinci-node/crypto/elgamal"
+	"github.com/vocdoni/davinci-node/crypto/signatures/ethereum"
+	"github.com/vocdoni/davinci-node/state"
+	"github.com/vocdoni/davinci-node/types"
+	"github.com/vocdoni/davinci-node/web3"
+)
+
+// AuditReport is the result of the independent verification of a process.
+// It is signed by the auditor, so it can be shared and attributed.
+type AuditReport struct {
+	ProcessID        types.ProcessID       `json:"processId"`
+	ChainID          uint64                `json:"chainId"`
+	ProcessRegistry  common.Address        `json:"processRegistry"`
+	CreationBlock    uint64                `json:"creationBlock"`
+	Block            uint64                `json:"block"`
+	InitialStateRoot *types.BigInt         `json:"initialStateRoot"`
+	FinalStateRoot   *types.BigInt         `json:"finalStateRoot"`
+	Transitions      []*TransitionAudit    `json:"transitions"`
+	EncryptedResults []types.DKGCiphertext `json:"encryptedResults,omitempty"`
+	Results          []*types.BigInt       `json:"results,omitempty"`
+	ResultsVerified  bool                  `json:"resultsVerified"`
+	Valid            bool                  `json:"valid"`
+	Errors           []string              `json:"errors,omitempty"`
+	VerifiedAt       time.Time             `json:"verifiedAt"`
+	Auditor          common.Address        `json:"auditor"`
+	Signature        types.HexBytes        `json:"signature,omitempty"`
+}
+
+// TransitionAudit is the verification of a state transition settled on
+// chain, replayed from the blobs of its transaction.
+type TransitionAudit struct {
+	BlockNumber  uint64        `json:"blockNumber"`
+	TxHash       common.Hash   `json:"txHash"`
+	OldStateRoot *types.BigInt `json:"oldStateRoot"`
+	NewStateRoot *types.BigInt `json:"newStateRoot"`
+	VotersCount  *types.BigInt `json:"votersCount"`
+	Blobs        int           `json:"blobs"`
+	Verified     bool          `json:"verified"`
+}
+
+// fail records an error that invalidates the report.
+func (r *AuditReport) fail(err error) {
+	r.Valid = false
+	r.Errors = append(r.Errors, err.Error())
+}
+
+// signedPayload returns the JSON of the report without its signature, which
+// is the message signed by the auditor.
+func (r *AuditReport) signedPayload() ([]byte, error) {
+	unsigned := *r
+	unsigned.Signature = nil
+	return json.Marshal(&unsigned)
+}
+
+// Sign sets the auditor of the report to the address of the signer and
+// signs it.
+func (r *AuditReport) Sign(signer *ethereum.Signer) error {
+	r.Auditor = signer.Address()
+	payload, err := r.signedPayload()
+	if err != nil {
+		return fmt.Errorf("could not encode audit report: %w", err)
+	}
+	signature, err := signer.Sign(payload)
+	if err != nil {
+		return fmt.Errorf("could not sign audit report: %w", err)
+	}
+	r.Signature = signature.Bytes()
+	return nil
+}
+
+// replayStateTransitions applies to the state provided the blobs of every
+// state transition, checking that each one starts on the current root and
+// leads to the new state root settled on chain. It returns the audit of the
+// transitions replayed, stopping at the first one that fails.
+func replayStateTransitions(
+	ctx context.Context,
+	st *state.State,
+	transitions []*types.StateRootChange,
+	fetcher web3.BlobFetcher,
+) ([]*TransitionAudit, error) {
+	audits := make([]*TransitionAudit, 0, len(transitions))
+	for i, transition := range transitions {
+		audit := &TransitionAudit{
+			BlockNumber:  transition.BlockNumber,
+			OldStateRoot: transition.OldStateRoot,
+			NewStateRoot: transition.NewStateRoot,
+			VotersCount:  transition.NewVotersCount,
+		}
+		if transition.TxHash != nil {
+			audit.TxHash = *transition.TxHash
+		}
+		audits = append(audits, audit)
+
+		root, err := st.RootAsBigInt()
+		if err != nil {
+			return audits, fmt.Errorf("could not get state root: %w", err)
+		}
+		if root.Cmp(transition.OldStateRoot.MathBigInt()) != 0 {
+			return audits, fmt.Errorf("state transition %d starts on root %s, expected %s",
+				i, transition.OldStateRoot.String(), root.String())
+		}
+		if transition.TxHash == nil {
+			return audits, fmt.Errorf("state transition %d has no transaction", i)
+		}
+		blobs, err := fetcher.BlobsByTxHash(ctx, *transition.TxHash)
+		if err != nil {
+			return audits, fmt.Errorf("could not fetch blobs of state transition %d (tx %s): %w",
+				i, transition.TxHash.String(), err)
+		}
+		sidecar := &types.BlobTxSidecar{Blobs: make([]*types.Blob, 0, len(blobs))}
+		for _, blobSidecar := range blobs {
+			if blobSidecar != nil && blobSidecar.Blob != nil {
+				sidecar.Blobs = append(sidecar.Blobs, blobSidecar.Blob)
+			}
+		}
+		if len(sidecar.Blobs) == 0 {
+			return audits, fmt.Errorf("no blobs found for state transition %d (tx %s)", i, transition.TxHash.String())
+		}
+		audit.Blobs = len(sidecar.Blobs)
+		if err := st.ApplyBlobSidecarFromRoot(
+			transition.OldStateRoot.MathBigInt(),
+			transition.NewStateRoot.MathBigInt(),
+			sidecar,
+		); err != nil {
+			return audits, fmt.Errorf("could not apply blobs of state transition %d (tx %s): %w",
+				i, transition.TxHash.String(), err)
+		}
+		if root, err = st.RootAsBigInt(); err != nil {
+			return audits, fmt.Errorf("could not get state root: %w", err)
+		}
+		if root.Cmp(transition.NewStateRoot.MathBigInt()) != 0 {
+			return audits, fmt.Errorf("state transition %d leads to root %s, expected %s",
+				i, root.String(), transition.NewStateRoot.String())
+		}
+		audit.Verified = true
+	}
+	return audits, nil
+}
+
+// verifyResultsDecryption checks that the results provided are the
+// decryption of the accumulator recomputed from the state, verifying the
+// published decryption proof of every accumulator field against the
+// encryption key of the process.
+func verifyResultsDecryption(
+	publicKey ecc.Point,
+	accumulator *elgamal.Ballot,
+	results []*types.BigInt,
+	decryption *types.ResultsDecryption,
+) error {
+	fields := len(accumulator.Ciphertexts)
+	if len(results) != fields {
+		return fmt.Errorf("expected %d results, got %d", fields, len(results))
+	}
+	if len(decryption.Results) != fields || len(decryption.Proofs) != fields {
+		return fmt.Errorf("expected %d decryption proofs, got %d proofs for %d results",
+			fields, len(decryption.Proofs), len(decryption.Results))
+	}
+	for i, ct := range accumulator.Ciphertexts {
+		if results[i] == nil || !results[i].Equal(decryption.Results[i]) {
+			return fmt.Errorf("result %d is %s, but the decryption proof is for %s",
+				i, results[i].String(), decryption.Results[i].String())
+		}
+		proof := decryption.Proofs[i]
+		if !proof.A1.Valid() || !proof.A2.Valid() || proof.Z == nil {
+			return fmt.Errorf("malformed decryption proof of result %d", i)
+		}
+		if err := elgamal.VerifyDecryptionProof(publicKey, ct.C1, ct.C2, results[i].MathBigInt(), &elgamal.DecryptionProof{
+			A1: proof.A1.ToPoint(publicKey),
+			A2: proof.A2.ToPoint(publicKey),
+			Z:  proof.Z.MathBigInt(),
+		}); err != nil {
+			return fmt.Errorf("invalid decryption proof of result %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// encryptedResults returns the ciphertexts of the accumulator provided.
+func encryptedResults(accumulator *elgamal.Ballot) []types.DKGCiphertext {
+	ciphertexts := make([]types.DKGCiphertext, 0, len(accumulator.Ciphertexts))
+	for _, ct := range accumulator.Ciphertexts {
+		ciphertexts = append(ciphertexts, types.DKGCiphertext{
+			C1: types.DKGPointFromPoint(ct.C1),
+			C2: types.DKGPointFromPoint(ct.C2),
+		})
+	}
+	return ciphertexts
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	qt "github.com/frankban/quicktest"
+	"github.com/vocdoni/davinci-node/crypto/elgamal"
+	"github.com/vocdoni/davinci-node/crypto/signatures/ethereum"
+	"github.com/vocdoni/davinci-node/internal/testutil"
+	"github.com/vocdoni/davinci-node/state"
+	statetest "github.com/vocdoni/davinci-node/state/testutil"
+	"github.com/vocdoni/davinci-node/types"
+)
+
+// testBlobFetcher returns the blobs of the transactions it knows.
+type testBlobFetcher map[common.Hash]*types.BlobTxSidecar
+
+func (f testBlobFetcher) BlobsByTxHash(_ context.Context, txHash common.Hash) ([]*types.BlobSidecar, error) {
+	sidecar, ok := f[txHash]
+	if !ok {
+		return nil, fmt.Errorf("unknown tx %s", txHash.String())
+	}
+	blobs := make([]*types.BlobSidecar, 0, len(sidecar.Blobs))
+	for i, blob := range sidecar.Blobs {
+		blobs = append(blobs, &types.BlobSidecar{Index: uint64(i), Blob: blob})
+	}
+	return blobs, nil
+}
+
+func TestReplayAndVerifyResults(t *testing.T) {
+	c := qt.New(t)
+
+	processID := testutil.RandomProcessID()
+	publicKey, privateKey, err := elgamal.GenerateKey(state.Curve)
+	c.Assert(err, qt.IsNil)
+	encryptionKey := types.EncryptionKeyFromPoint(publicKey)
+
+	// settle two state transitions on the state of the sequencer
+	sequencerState := statetest.NewStateForTest(t, processID, testutil.BallotModePacked(),
+		types.CensusOriginMerkleTreeOffchainStaticV1, encryptionKey)
+	fetcher := testBlobFetcher{}
+	var transitions []*types.StateRootChange
+	for i := range 2 {
+		rootBefore, err := sequencerState.RootAsBigInt()
+		c.Assert(err, qt.IsNil)
+		batch, err := sequencerState.PrepareVotesBatch([]*state.Vote{statetest.NewVoteForTest(publicKey, uint64(i), 3)})
+		c.Assert(err, qt.IsNil)
+		rootAfter, err := batch.RootAsBigInt()
+		c.Assert(err, qt.IsNil)
+		sidecar := batch.BlobEvalData().TxSidecar()
+		c.Assert(batch.Commit(), qt.IsNil)
+
+		txHash := common.BigToHash(big.NewInt(int64(i + 1)))
+		fetcher[txHash] = sidecar
+		transitions = append(transitions, &types.StateRootChange{
+			OldStateRoot:   types.BigIntConverter(rootBefore),
+			NewStateRoot:   types.BigIntConverter(rootAfter),
+			NewVotersCount: types.BigIntConverter(big.NewInt(int64(i + 1))),
+			TxHash:         &txHash,
+			BlockNumber:    uint64(100 + i),
+		})
+	}
+
+	// the auditor replays them from the blobs
+	newAuditorState := func() *state.State {
+		return statetest.NewStateForTest(t, processID, testutil.BallotModePacked(),
+			types.CensusOriginMerkleTreeOffchainStaticV1, encryptionKey)
+	}
+	auditorState := newAuditorState()
+	audits, err := replayStateTransitions(context.Background(), auditorState, transitions, fetcher)
+	c.Assert(err, qt.IsNil)
+	c.Assert(audits, qt.HasLen, 2)
+	for _, audit := range audits {
+		c.Assert(audit.Verified, qt.IsTrue)
+		c.Assert(audit.Blobs > 0, qt.IsTrue)
+	}
+	root, err := auditorState.RootAsBigInt()
+	c.Assert(err, qt.IsNil)
+	c.Assert(transitions[1].NewStateRoot.MathBigInt().Cmp(root), qt.Equals, 0)
+
+	// a transition that does not lead to the root settled on chain fails
+	tampered := *transitions[1]
+	tampered.NewStateRoot = transitions[0].NewStateRoot
+	audits, err = replayStateTransitions(context.Background(), newAuditorState(),
+		[]*types.StateRootChange{transitions[0], &tampered}, fetcher)
+	c.Assert(err, qt.IsNotNil)
+	c.Assert(audits, qt.HasLen, 2)
+	c.Assert(audits[0].Verified, qt.IsTrue)
+	c.Assert(audits[1].Verified, qt.IsFalse)
+
+	// decrypt the accumulator as the sequencer does and publish the proofs
+	accumulator, err := auditorState.Results()
+	c.Assert(err, qt.IsNil)
+	decryption := &types.ResultsDecryption{ProcessID: processID}
+	results := make([]*types.BigInt, 0, len(accumulator.Ciphertexts))
+	for _, ct := range accumulator.Ciphertexts {
+		_, result, err := elgamal.Decrypt(publicKey, privateKey, ct.C1, ct.C2, 1000)
+		c.Assert(err, qt.IsNil)
+		proof, err := elgamal.BuildDecryptionProof(privateKey, publicKey, ct.C1, ct.C2, result)
+		c.Assert(err, qt.IsNil)
+		results = append(results, types.BigIntConverter(result))
+		decryption.Results = append(decryption.Results, types.BigIntConverter(result))
+		decryption.Proofs = append(decryption.Proofs, types.DKGProof{
+			A1: types.DKGPointFromPoint(proof.A1),
+			A2: types.DKGPointFromPoint(proof.A2),
+			Z:  types.BigIntConverter(proof.Z),
+		})
+	}
+	c.Assert(verifyResultsDecryption(publicKey, accumulator, results, decryption), qt.IsNil)
+
+	// results that are not the decryption of the accumulator are rejected,
+	// even if the sequencer publishes them
+	results[1] = types.BigIntConverter(new(big.Int).Add(results[1].MathBigInt(), big.NewInt(1)))
+	c.Assert(verifyResultsDecryption(publicKey, accumulator, results, decryption), qt.ErrorMatches, "result 1 is .*")
+	decryption.Results[1] = results[1]
+	c.Assert(verifyResultsDecryption(publicKey, accumulator, results, decryption),
+		qt.ErrorMatches, "invalid decryption proof of result 1.*")
+}
+
+func TestAuditReportSign(t *testing.T) {
+	c := qt.New(t)
+
+	signer, err := ethereum.NewSigner()
+	c.Assert(err, qt.IsNil)
+	report := &AuditReport{
+		ProcessID:  testutil.RandomProcessID(),
+		Valid:      true,
+		VerifiedAt: time.Now().UTC(),
+	}
+	c.Assert(report.Sign(signer), qt.IsNil)
+	c.Assert(report.Auditor, qt.Equals, signer.Address())
+
+	payload, err := report.signedPayload()
+	c.Assert(err, qt.IsNil)
+	signature, err := ethereum.BytesToSignature(report.Signature)
+	c.Assert(err, qt.IsNil)
+	valid, _ := signature.Verify(payload, signer.Address())
+	c.Assert(valid, qt.IsTrue)
+
+	// any change in the report invalidates the signature
+	report.Valid = false
+	payload, err = report.signedPayload()
+	c.Assert(err, qt.IsNil)
+	valid, _ = signature.Verify(payload, signer.Address())
+	c.Assert(valid, qt.IsFalse)
+}
@@ -0,0 +1,32 @@
+// Command davinci groups the tools to audit the davinci protocol from the
+// command line.
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+const usage = `Usage: davinci <command> [flags]
+
+Commands:
+  verify    independently verify the state transitions and the results of a process
+
+Run 'davinci <command> -h' to see the flags of a command.
+`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+	switch os.Args[1] {
+	case "verify":
+		os.Exit(runVerify(os.Args[2:]))
+	case "help", "-h", "--help":
+		fmt.Fprint(os.Stdout, usage)
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n%s", os.Args[1], usage)
+		os.Exit(2)
+	}
+}
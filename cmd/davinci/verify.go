@@ -0,0 +1,239 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	flag "github.com/spf13/pflag"
+	"github.com/vocdoni/arbo/memdb"
+	"github.com/vocdoni/davinci-node/api/client"
+	"github.com/vocdoni/davinci-node/crypto/signatures/ethereum"
+	"github.com/vocdoni/davinci-node/log"
+	"github.com/vocdoni/davinci-node/state"
+	"github.com/vocdoni/davinci-node/storage"
+	"github.com/vocdoni/davinci-node/types"
+	"github.com/vocdoni/davinci-node/util"
+	"github.com/vocdoni/davinci-node/web3"
+)
+
+const (
+	defaultVerifyRPC       = "https://ethereum-sepolia-rpc.publicnode.com"
+	defaultVerifyBeaconAPI = "https://ethereum-sepolia-beacon-api.publicnode.com"
+	defaultVerifyTimeout   = time.Hour
+)
+
+// runVerify runs the verify command with the arguments provided and returns
+// the exit code: 0 if the process is valid, 1 if it is not and 2 if it could
+// not be verified.
+func runVerify(args []string) int {
+	fs := flag.NewFlagSet("verify", flag.ContinueOnError)
+	rpcs := fs.StringSliceP("web3.rpc", "r", []string{defaultVerifyRPC}, "web3 rpc endpoint(s), comma-separated")
+	beaconAPI := fs.String("web3.bapi", defaultVerifyBeaconAPI, "consensus api endpoint to download the blobs of the state transitions")
+	registry := fs.String("web3.processRegistryContract", "", "address of the process registry smart contract, by default the one deployed on the chain of the rpc")
+	pid := fs.StringP("process", "p", "", "ID of the process to verify (required)")
+	fromBlock := fs.Uint64("fromBlock", 0, "lowest block where the creation of the process is searched")
+	sequencer := fs.StringP("sequencer", "s", "", "sequencer API URL to download the published decryption proofs of the results from")
+	privKey := fs.StringP("privkey", "k", "", "private key to sign the audit report (required)")
+	output := fs.StringP("output", "o", "", "file to write the signed audit report to, stdout by default")
+	logLevel := fs.StringP("log.level", "l", "info", "log level (debug, info, warn, error, fatal)")
+	timeout := fs.Duration("timeout", defaultVerifyTimeout, "timeout to download the history of the process and verify it")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: davinci verify [flags]\n\n%s", fs.FlagUsages())
+	}
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 2
+	}
+	// the report is written to stdout, so the logs go to stderr
+	log.Init(*logLevel, "stderr", nil)
+
+	processID, err := types.HexStringToProcessID(*pid)
+	if err != nil {
+		log.Errorw(err, "invalid process ID")
+		return 2
+	}
+	if *privKey == "" {
+		log.Errorw(fmt.Errorf("missing private key"), "the audit report must be signed")
+		return 2
+	}
+	signer, err := ethereum.NewSignerFromHex(util.TrimHex(*privKey))
+	if err != nil {
+		log.Errorw(err, "invalid private key")
+		return 2
+	}
+	var addresses *web3.Addresses
+	if *registry != "" {
+		if !common.IsHexAddress(*registry) {
+			log.Errorw(fmt.Errorf("invalid address %q", *registry), "invalid process registry contract")
+			return 2
+		}
+		addresses = &web3.Addresses{ProcessRegistry: common.HexToAddress(*registry)}
+	}
+	contracts, err := web3.New(*rpcs, *beaconAPI, 1.0)
+	if err != nil {
+		log.Errorw(err, "could not initialize web3 client")
+		return 2
+	}
+	if err := contracts.LoadContracts(addresses); err != nil {
+		log.Errorw(err, "could not load contracts")
+		return 2
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+	report, err := verifyProcess(ctx, contracts, processID, *fromBlock, *sequencer)
+	if err != nil {
+		log.Errorw(err, "could not verify process")
+		return 2
+	}
+	if err := report.Sign(signer); err != nil {
+		log.Errorw(err, "could not sign audit report")
+		return 2
+	}
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		log.Errorw(err, "could not encode audit report")
+		return 2
+	}
+	data = append(data, '\n')
+	if *output == "" {
+		_, err = os.Stdout.Write(data)
+	} else {
+		err = os.WriteFile(*output, data, 0o644)
+	}
+	if err != nil {
+		log.Errorw(err, "could not write audit report")
+		return 2
+	}
+	if !report.Valid {
+		log.Warnw("process verification failed", "processID", processID.String(), "errors", report.Errors)
+		return 1
+	}
+	log.Infow("process verified", "processID", processID.String(), "resultsVerified", report.ResultsVerified)
+	return 0
+}
+
+// verifyProcess downloads the history of the process from the chain and
+// replays it to verify every state transition and, if the process has
+// results, their decryption proofs published by the sequencer provided. The
+// checks that fail are recorded in the report, while the errors returned
+// are the ones that prevent verifying the process.
+func verifyProcess(
+	ctx context.Context,
+	contracts *web3.Contracts,
+	processID types.ProcessID,
+	fromBlock uint64,
+	sequencerURL string,
+) (*AuditReport, error) {
+	block := contracts.CurrentBlock()
+	report := &AuditReport{
+		ProcessID:       processID,
+		ChainID:         contracts.ChainID,
+		ProcessRegistry: contracts.ContractsAddresses.ProcessRegistry,
+		Block:           block,
+		Transitions:     []*TransitionAudit{},
+		Valid:           true,
+		VerifiedAt:      time.Now().UTC(),
+	}
+
+	log.Infow("downloading process history", "processID", processID.String(), "fromBlock", fromBlock, "toBlock", block)
+	creationBlock, transitions, err := contracts.ProcessStateTransitions(ctx, processID, fromBlock, block)
+	if err != nil {
+		return nil, err
+	}
+	report.CreationBlock = creationBlock
+	initial, err := contracts.ProcessAtBlock(processID, creationBlock)
+	if err != nil {
+		return nil, fmt.Errorf("could not get process at creation block: %w", err)
+	}
+	process, err := contracts.ProcessAtBlock(processID, block)
+	if err != nil {
+		return nil, fmt.Errorf("could not get process at block %d: %w", block, err)
+	}
+	report.InitialStateRoot = initial.StateRoot
+	report.FinalStateRoot = process.StateRoot
+	if initial.EncryptionKey == nil || initial.Census == nil {
+		return nil, fmt.Errorf("process has no encryption key or census")
+	}
+
+	// Rebuild the initial state from the parameters of the process
+	st, err := state.New(memdb.New(), processID)
+	if err != nil {
+		return nil, fmt.Errorf("could not create state: %w", err)
+	}
+	packedBallotMode, err := initial.BallotMode.Pack()
+	if err != nil {
+		return nil, fmt.Errorf("could not pack ballot mode: %w", err)
+	}
+	if err := st.Initialize(
+		initial.Census.CensusOrigin.BigInt().MathBigInt(),
+		packedBallotMode,
+		*initial.EncryptionKey,
+	); err != nil {
+		return nil, fmt.Errorf("could not initialize state: %w", err)
+	}
+	root, err := st.RootAsBigInt()
+	if err != nil {
+		return nil, fmt.Errorf("could not get initial state root: %w", err)
+	}
+	if !initial.StateRoot.Equal((*types.BigInt)(root)) {
+		report.fail(fmt.Errorf("initial state root is %s, but the process parameters lead to %s",
+			initial.StateRoot.String(), root.String()))
+		return report, nil
+	}
+
+	// Replay the state transitions
+	log.Infow("replaying state transitions", "processID", processID.String(), "transitions", len(transitions))
+	report.Transitions, err = replayStateTransitions(ctx, st, transitions, contracts)
+	if err != nil {
+		report.fail(err)
+		return report, nil
+	}
+	if root, err = st.RootAsBigInt(); err != nil {
+		return nil, fmt.Errorf("could not get final state root: %w", err)
+	}
+	if !process.StateRoot.Equal((*types.BigInt)(root)) {
+		report.fail(fmt.Errorf("final state root is %s, but the state transitions lead to %s",
+			process.StateRoot.String(), root.String()))
+		return report, nil
+	}
+	accumulator, err := st.Results()
+	if err != nil {
+		return nil, fmt.Errorf("could not get encrypted results: %w", err)
+	}
+	report.EncryptedResults = encryptedResults(accumulator)
+
+	// Verify the decryption of the results, if any
+	if len(process.Result) == 0 {
+		log.Infow("process has no results yet, skipping their verification", "processID", processID.String())
+		return report, nil
+	}
+	report.Results = process.Result
+	if sequencerURL == "" {
+		report.fail(fmt.Errorf("process has results, but no sequencer was provided to download their decryption proofs"))
+		return report, nil
+	}
+	cli, err := client.New(sequencerURL)
+	if err != nil {
+		return nil, fmt.Errorf("could not connect to sequencer: %w", err)
+	}
+	decryption, err := cli.ResultsDecryption(ctx, processID)
+	if err != nil {
+		report.fail(fmt.Errorf("could not download results decryption: %w", err))
+		return report, nil
+	}
+	publicKey := storage.ProcessEncryptionKeyToPoint(initial.EncryptionKey)
+	if err := verifyResultsDecryption(publicKey, accumulator, process.Result, decryption); err != nil {
+		report.fail(err)
+		return report, nil
+	}
+	report.ResultsVerified = true
+	return report, nil
+}
@@ -5,8 +5,9 @@
 // census proofs generated by the CSP.
 //
 // The CSP interface defines methods to set a seed, retrieve census origin and
-// root, generate and verify census proofs. The package currently supports the
-// EdDSA BN254 origin for census proofs.
+// root, generate and verify census proofs. The package currently supports
+// EdDSA over BabyJubJub, ECDSA over secp256k1 and EdDSA over the BLS12-377
+// twisted Edwards curve origins for census proofs.
 //
 //   - The `New` function creates a new CSP based on the provided origin and
 //     seed.
@@ -18,6 +19,8 @@ import (
 	"fmt"
 
 	"github.com/ethereum/go-ethereum/common"
+	ethcrypto "github.com/ethereum/go-ethereum/crypto"
+	"github.com/vocdoni/davinci-node/crypto/csp/ecdsa"
 	"github.com/vocdoni/davinci-node/crypto/csp/eddsa"
 	"github.com/vocdoni/davinci-node/types"
 )
@@ -50,6 +53,16 @@ func New(origin types.CensusOrigin, seed []byte) (CSP, error) {
 			return eddsa.NewBabyJubJubKeyFromSeed(eddsa.DefaultHashFn, seed)
 		}
 		return eddsa.NewBabyJubJubKey(eddsa.DefaultHashFn)
+	case types.CensusOriginCSPECDSASecp256k1V1:
+		if len(seed) > 0 {
+			return ecdsa.NewSecp256k1KeyFromSeed(eddsa.DefaultHashFn, seed)
+		}
+		return ecdsa.NewSecp256k1Key(eddsa.DefaultHashFn)
+	case types.CensusOriginCSPEdDSABLS12377V1:
+		if len(seed) > 0 {
+			return eddsa.NewBLS12377KeyFromSeed(eddsa.DefaultHashFn, seed)
+		}
+		return eddsa.NewBLS12377Key(eddsa.DefaultHashFn)
 	default:
 		return nil, fmt.Errorf("unsupported census origin: %s", origin)
 	}
@@ -67,8 +80,76 @@ func VerifyCensusProof(proof *types.CensusProof) error {
 		if err != nil {
 			return fmt.Errorf("failed to create EdDSA BN254 CSP: %w", err)
 		}
+	case types.CensusOriginCSPECDSASecp256k1V1:
+		var err error
+		csp, err = ecdsa.NewSecp256k1Key(eddsa.DefaultHashFn)
+		if err != nil {
+			return fmt.Errorf("failed to create ECDSA secp256k1 CSP: %w", err)
+		}
+	case types.CensusOriginCSPEdDSABLS12377V1:
+		var err error
+		csp, err = eddsa.NewBLS12377Key(eddsa.DefaultHashFn)
+		if err != nil {
+			return fmt.Errorf("failed to create EdDSA BLS12-377 CSP: %w", err)
+		}
 	default:
 		return fmt.Errorf("unsupported census origin: %s", proof.CensusOrigin)
 	}
 	return csp.VerifyProof(proof)
 }
+
+// VerifyCensusRoot checks that the CSP census proof provided is signed by the
+// key of the census root provided, that is, the census root of the process.
+// The root is derived from the public key of the proof, the same way the
+// circuits do, since the root included in the proof is provided by the voter
+// and it is not bound to the public key that signed the proof. It is used to
+// reject the proofs signed by a rotated CSP key. It returns an error if the
+// public key cannot be decoded or if the roots do not match.
+func VerifyCensusRoot(proof *types.CensusProof, censusRoot types.HexBytes) error {
+	if proof == nil {
+		return fmt.Errorf("proof is nil")
+	}
+	var root types.HexBytes
+	switch proof.CensusOrigin {
+	case types.CensusOriginCSPEdDSABabyJubJubV1:
+		pubKey, err := eddsa.DecompressPublicKey(proof.PublicKey)
+		if err != nil {
+			return fmt.Errorf("error getting public key from census proof: %w", err)
+		}
+		if root, err = eddsa.BabyJubJubCensusRoot(eddsa.DefaultHashFn, pubKey); err != nil {
+			return err
+		}
+	case types.CensusOriginCSPECDSASecp256k1V1:
+		pubKey, err := ethcrypto.DecompressPubkey(proof.PublicKey)
+		if err != nil {
+			return fmt.Errorf("error getting public key from census proof: %w", err)
+		}
+		if root, err = ecdsa.CensusRoot(eddsa.DefaultHashFn, pubKey); err != nil {
+			return err
+		}
+	case types.CensusOriginCSPEdDSABLS12377V1:
+		pubKey, err := eddsa.DecodeBLS12377PublicKey(proof.PublicKey)
+		if err != nil {
+			return fmt.Errorf("error getting public key from census proof: %w", err)
+		}
+		if root, err = eddsa.BLS12377CensusRoot(eddsa.DefaultHashFn, pubKey); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unsupported census origin: %s", proof.CensusOrigin)
+	}
+	if root.BigInt().MathBigInt().Cmp(censusRoot.BigInt().MathBigInt()) != 0 {
+		return fmt.Errorf("census proof public key does not match the census root %s", censusRoot.String())
+	}
+	return nil
+}
+
+// StateTransitionSupported returns true if the census proofs of the CSP
+// census origin provided can be verified by the state transition circuit.
+// The circuit only verifies EdDSA over BabyJubJub proofs (see CSPProof). The
+// secp256k1 ECDSA and BLS12-377 EdDSA proofs have their own gadgets (see
+// Secp256k1CSPProof and BLS12377CSPProof) that require a separate circuit
+// version, so the votes of processes with those origins are not accepted yet.
+func StateTransitionSupported(origin types.CensusOrigin) bool {
+	return origin == types.CensusOriginCSPEdDSABabyJubJubV1
+}
@@ -0,0 +1,45 @@
+package csp
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	qt "github.com/frankban/quicktest"
+	"github.com/vocdoni/davinci-node/internal/testutil"
+	"github.com/vocdoni/davinci-node/types"
+)
+
+func TestVerifyCensusRoot(t *testing.T) {
+	c := qt.New(t)
+
+	for _, origin := range []types.CensusOrigin{
+		types.CensusOriginCSPEdDSABabyJubJubV1,
+		types.CensusOriginCSPECDSASecp256k1V1,
+		types.CensusOriginCSPEdDSABLS12377V1,
+	} {
+		c.Run(origin.String(), func(c *qt.C) {
+			oldCSP, err := New(origin, []byte("old key"))
+			c.Assert(err, qt.IsNil)
+			newCSP, err := New(origin, []byte("new key"))
+			c.Assert(err, qt.IsNil)
+			newRoot := newCSP.CensusRoot().Root
+
+			processID := testutil.RandomProcessID()
+			address := common.BytesToAddress([]byte{0x01})
+			weight := new(types.BigInt).SetUint64(testutil.Weight)
+
+			proof, err := newCSP.GenerateProof(processID, address, weight)
+			c.Assert(err, qt.IsNil)
+			c.Assert(VerifyCensusRoot(proof, newRoot), qt.IsNil)
+			c.Assert(VerifyCensusRoot(proof, newRoot.BigInt().Bytes()), qt.IsNil)
+
+			// a proof signed by the revoked key is rejected, even if it
+			// claims the new root
+			revoked, err := oldCSP.GenerateProof(processID, address, weight)
+			c.Assert(err, qt.IsNil)
+			c.Assert(VerifyCensusProof(revoked), qt.IsNil)
+			revoked.Root = newRoot
+			c.Assert(VerifyCensusRoot(revoked, newRoot), qt.ErrorMatches, "census proof public key does not match.*")
+		})
+	}
+}
@@ -0,0 +1,199 @@
+// Package ecdsa implements a CSP that certifies voters with ECDSA signatures
+// over the secp256k1 curve.
+package ecdsa
+
+import (
+	"crypto/ecdsa"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	ethcrypto "github.com/ethereum/go-ethereum/crypto"
+	"github.com/vocdoni/davinci-node/crypto/csp/eddsa"
+	"github.com/vocdoni/davinci-node/types"
+)
+
+// SignatureLength is the length in bytes of a secp256k1 CSP signature, the
+// R and S values of the signature. Signatures with a trailing recovery byte
+// are also accepted.
+const SignatureLength = 64
+
+// Secp256k1ECDSA struct implements the CSP interface for ECDSA signatures
+// over the secp256k1 curve. The CSP signs the raw signature message, without
+// any prefix, so any secp256k1 key service can issue valid proofs.
+type Secp256k1ECDSA struct {
+	hashFn  eddsa.Hash
+	privKey *ecdsa.PrivateKey
+	indexFn types.CSPIndexFn
+}
+
+// NewSecp256k1KeyFromSeed creates a new Secp256k1ECDSA using the hash
+// function provided and the provided seed. It implements the CSP interface
+// and can be used to generate and verify proofs for voters. If something goes
+// wrong during the key generation, it returns an error.
+func NewSecp256k1KeyFromSeed(hashFn eddsa.Hash, seed []byte) (*Secp256k1ECDSA, error) {
+	// Ensure seed is not empty
+	if len(seed) == 0 {
+		return nil, fmt.Errorf("seed cannot be empty")
+	}
+	// Derive the private key from the hashed seed
+	privKey, err := ethcrypto.ToECDSA(ethcrypto.Keccak256(seed))
+	if err != nil {
+		return nil, fmt.Errorf("error generating private key: %w", err)
+	}
+	return &Secp256k1ECDSA{
+		hashFn:  hashFn,
+		privKey: privKey,
+		indexFn: eddsa.DefaultCSPIndexFn,
+	}, nil
+}
+
+// NewSecp256k1Key creates a new random Secp256k1ECDSA using the hash function
+// provided. It implements the CSP interface and can be used to generate and
+// verify proofs for voters. If something goes wrong during the key
+// generation, it returns an error.
+func NewSecp256k1Key(hashFn eddsa.Hash) (*Secp256k1ECDSA, error) {
+	privKey, err := ethcrypto.GenerateKey()
+	if err != nil {
+		return nil, fmt.Errorf("error generating private key: %w", err)
+	}
+	return &Secp256k1ECDSA{
+		hashFn:  hashFn,
+		privKey: privKey,
+		indexFn: eddsa.DefaultCSPIndexFn,
+	}, nil
+}
+
+// SetIndexFn sets the index function for the Secp256k1ECDSA instance.
+func (c *Secp256k1ECDSA) SetIndexFn(indexFn types.CSPIndexFn) {
+	c.indexFn = indexFn
+}
+
+// CensusOrigin returns the origin of the credential service providers. It
+// returns the type of the CSP, which is ECDSA over secp256k1 in this case.
+func (c *Secp256k1ECDSA) CensusOrigin() types.CensusOrigin {
+	return types.CensusOriginCSPECDSASecp256k1V1
+}
+
+// PublicKey returns the compressed public key of the ECDSA instance.
+func (c *Secp256k1ECDSA) PublicKey() types.HexBytes {
+	return ethcrypto.CompressPubkey(&c.privKey.PublicKey)
+}
+
+// CensusRoot returns the census root computed from the public key of the
+// ECDSA instance. If the public key can not be converted to a census root,
+// it returns nil.
+func (c *Secp256k1ECDSA) CensusRoot() *types.CensusRoot {
+	censusRoot, err := CensusRoot(c.hashFn, &c.privKey.PublicKey)
+	if err != nil {
+		return nil
+	}
+	return &types.CensusRoot{
+		Root: censusRoot,
+	}
+}
+
+// GenerateProof generates a census proof for the given process ID, address
+// and weight. It signs the message composed by the voter index, the process
+// ID, the address and the weight using the private key of the CSP. It returns
+// a CensusProof struct that includes the hash of the public key as the root,
+// the compressed public key and the signature. It returns an error if the
+// inputs provided are not valid or something fails during signature process.
+func (c *Secp256k1ECDSA) GenerateProof(
+	processID types.ProcessID,
+	address common.Address,
+	weight *types.BigInt,
+) (*types.CensusProof, error) {
+	voterIndex := c.indexFn(processID, address, weight)
+	// Compose the message and sign it
+	message, err := eddsa.SignatureMessage(c.hashFn, voterIndex, processID, address.Bytes(), weight)
+	if err != nil {
+		return nil, fmt.Errorf("error composing signature message: %w", err)
+	}
+	signature, err := ethcrypto.Sign(message.LeftPad(32), c.privKey)
+	if err != nil {
+		return nil, fmt.Errorf("error signing message: %w", err)
+	}
+	// Get the root for the current private key
+	censusRoot, err := CensusRoot(c.hashFn, &c.privKey.PublicKey)
+	if err != nil {
+		return nil, fmt.Errorf("error computing census root: %w", err)
+	}
+	return &types.CensusProof{
+		CensusOrigin: c.CensusOrigin(),
+		Root:         censusRoot,
+		Address:      address.Bytes(),
+		Weight:       weight,
+		VoterIndex:   voterIndex,
+		ProcessID:    processID,
+		PublicKey:    c.PublicKey(),
+		Signature:    signature[:SignatureLength],
+	}, nil
+}
+
+// VerifyProof method verifies the proof provided. It also verifies that the
+// census origin of the proof matches with the ECDSA instance one. It returns
+// an error if the proof provided is nil, the census origins do not match or
+// something fails during signature verification process.
+func (c *Secp256k1ECDSA) VerifyProof(proof *types.CensusProof) error {
+	// Proof inputs checks
+	if proof == nil {
+		return fmt.Errorf("proof is nil")
+	}
+	if !proof.ProcessID.IsValid() {
+		return fmt.Errorf("process ID is nil")
+	}
+	if proof.CensusOrigin != c.CensusOrigin() {
+		return fmt.Errorf("proof origin mismatch: expected %s, got %s", c.CensusOrigin(), proof.CensusOrigin)
+	}
+	// Get the public key and the signature from the proof
+	pubKey, err := ethcrypto.DecompressPubkey(proof.PublicKey)
+	if err != nil {
+		return fmt.Errorf("error getting public key from census proof: %w", err)
+	}
+	signature, err := DecodeSignature(proof.Signature)
+	if err != nil {
+		return err
+	}
+	// Recompute the signature message
+	message, err := eddsa.SignatureMessage(c.hashFn, proof.VoterIndex, proof.ProcessID, proof.Address, proof.Weight)
+	if err != nil {
+		return fmt.Errorf("error composing signature message: %w", err)
+	}
+	// Verify the signature using the public key and the message, it rejects
+	// malleable signatures with high S values
+	if !ethcrypto.VerifySignature(ethcrypto.FromECDSAPub(pubKey), message.LeftPad(32), signature) {
+		return fmt.Errorf("signature verification failed for address %s", proof.Address.String())
+	}
+	return nil
+}
+
+// CensusRoot encodes the public key provided as a census root by hashing the
+// 128 bits halves of its coords with the hash function provided, since the
+// secp256k1 coords do not fit into a single field element.
+func CensusRoot(hashFn eddsa.Hash, pubKey *ecdsa.PublicKey) (types.HexBytes, error) {
+	xHi, xLo := splitCoord(pubKey.X)
+	yHi, yLo := splitCoord(pubKey.Y)
+	// Reset the hash function before using it
+	hashFn.Reset()
+	hashedPubKey, err := hashFn.BigIntsSum([]*big.Int{xHi, xLo, yHi, yLo})
+	if err != nil {
+		return nil, fmt.Errorf("error hashing public key: %w", err)
+	}
+	return types.NormalizedCensusRoot(hashedPubKey.Bytes()), nil
+}
+
+// DecodeSignature returns the R and S values of the signature provided,
+// dropping the recovery byte if it is included.
+func DecodeSignature(signature types.HexBytes) (types.HexBytes, error) {
+	if len(signature) != SignatureLength && len(signature) != SignatureLength+1 {
+		return nil, fmt.Errorf("invalid signature length: %d", len(signature))
+	}
+	return signature[:SignatureLength], nil
+}
+
+// splitCoord returns the high and low 128 bits halves of the coord provided.
+func splitCoord(coord *big.Int) (*big.Int, *big.Int) {
+	mask := new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 128), big.NewInt(1))
+	return new(big.Int).Rsh(coord, 128), new(big.Int).And(coord, mask)
+}
@@ -0,0 +1,83 @@
+package ecdsa
+
+import (
+	"testing"
+
+	ethcrypto "github.com/ethereum/go-ethereum/crypto"
+	qt "github.com/frankban/quicktest"
+	"github.com/vocdoni/davinci-node/crypto/csp/eddsa"
+	"github.com/vocdoni/davinci-node/internal/testutil"
+	"github.com/vocdoni/davinci-node/types"
+)
+
+func TestSecp256k1GenerateVerifyProof(t *testing.T) {
+	c := qt.New(t)
+
+	processID := testutil.RandomProcessID()
+	userAddress := testutil.RandomAddress()
+	userWeight := types.NewInt(testutil.Weight)
+
+	csp, err := NewSecp256k1Key(eddsa.DefaultHashFn)
+	c.Assert(err, qt.IsNil)
+
+	t.Run("valid proof generation and verification", func(t *testing.T) {
+		proof, err := csp.GenerateProof(processID, userAddress, userWeight)
+		c.Assert(err, qt.IsNil)
+		c.Assert(proof.CensusOrigin, qt.Equals, types.CensusOriginCSPECDSASecp256k1V1)
+		c.Assert(proof.Root, qt.DeepEquals, csp.CensusRoot().Root)
+		c.Assert(proof.Signature, qt.HasLen, SignatureLength)
+		c.Assert(csp.VerifyProof(proof), qt.IsNil)
+	})
+
+	t.Run("signature signed by an external key service", func(t *testing.T) {
+		proof, err := csp.GenerateProof(processID, userAddress, userWeight)
+		c.Assert(err, qt.IsNil)
+
+		// the signature of the raw message, including the recovery byte
+		message, err := eddsa.SignatureMessage(eddsa.DefaultHashFn, proof.VoterIndex, processID, userAddress.Bytes(), userWeight)
+		c.Assert(err, qt.IsNil)
+		proof.Signature, err = ethcrypto.Sign(message.LeftPad(32), csp.privKey)
+		c.Assert(err, qt.IsNil)
+		c.Assert(csp.VerifyProof(proof), qt.IsNil)
+	})
+
+	t.Run("invalid proof pubkey", func(t *testing.T) {
+		proof, err := csp.GenerateProof(processID, userAddress, userWeight)
+		c.Assert(err, qt.IsNil)
+
+		other, err := NewSecp256k1Key(eddsa.DefaultHashFn)
+		c.Assert(err, qt.IsNil)
+		proof.PublicKey = other.PublicKey()
+		c.Assert(csp.VerifyProof(proof), qt.IsNotNil)
+	})
+
+	t.Run("invalid proof address", func(t *testing.T) {
+		proof, err := csp.GenerateProof(processID, userAddress, userWeight)
+		c.Assert(err, qt.IsNil)
+
+		proof.Address = testutil.RandomAddress().Bytes()
+		c.Assert(csp.VerifyProof(proof), qt.IsNotNil)
+	})
+
+	t.Run("invalid proof signature", func(t *testing.T) {
+		proof, err := csp.GenerateProof(processID, userAddress, userWeight)
+		c.Assert(err, qt.IsNil)
+
+		proof.Signature = proof.Signature[:SignatureLength-1]
+		c.Assert(csp.VerifyProof(proof), qt.IsNotNil)
+	})
+}
+
+func TestSecp256k1KeyFromSeed(t *testing.T) {
+	c := qt.New(t)
+
+	csp1, err := NewSecp256k1KeyFromSeed(eddsa.DefaultHashFn, []byte("seed"))
+	c.Assert(err, qt.IsNil)
+	csp2, err := NewSecp256k1KeyFromSeed(eddsa.DefaultHashFn, []byte("seed"))
+	c.Assert(err, qt.IsNil)
+	c.Assert(csp1.PublicKey(), qt.DeepEquals, csp2.PublicKey())
+	c.Assert(csp1.CensusRoot().Root, qt.HasLen, types.CensusRootLength)
+
+	_, err = NewSecp256k1KeyFromSeed(eddsa.DefaultHashFn, nil)
+	c.Assert(err, qt.IsNotNil)
+}
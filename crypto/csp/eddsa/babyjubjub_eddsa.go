@@ -170,17 +170,20 @@ func (c *BabyJubJubEdDSA) VerifyProof(proof *types.CensusProof) error {
 	return nil
 }
 
-// root function encodes the public key provided as a census root by hashing
-// its coords accordingly to the curve provided. It uses the poseidon hash
-// function for that curve to calculate the hash of the public key coords.
-// It returns an error if the public key provided is invalid for the desired
-// curve or if the curve is not supported.
+// root function encodes the public key of the current private key as a
+// census root (see BabyJubJubCensusRoot).
 func (c *BabyJubJubEdDSA) root() (types.HexBytes, error) {
-	pubKey := c.privKey.Public()
+	return BabyJubJubCensusRoot(c.hashFn, c.privKey.Public())
+}
+
+// BabyJubJubCensusRoot encodes the public key provided as a census root by
+// hashing its coords with the hash function provided, the same way the
+// circuits do. It returns an error if the hash of the coords fails.
+func BabyJubJubCensusRoot(hashFn Hash, pubKey *babyjub.PublicKey) (types.HexBytes, error) {
 	// Reset the hash function before using it
-	c.hashFn.Reset()
+	hashFn.Reset()
 	// Hash the public key using the poseidon hash function
-	hashedPubKey, err := c.hashFn.BigIntsSum([]*big.Int{pubKey.X, pubKey.Y})
+	hashedPubKey, err := hashFn.BigIntsSum([]*big.Int{pubKey.X, pubKey.Y})
 	if err != nil {
 		return nil, fmt.Errorf("error hashing public key: %w", err)
 	}
@@ -196,32 +199,7 @@ func (c *BabyJubJubEdDSA) signatureMessage(
 	address types.HexBytes,
 	weight *types.BigInt,
 ) (types.HexBytes, error) {
-	// Inputs validation
-	if !processID.IsValid() {
-		return nil, fmt.Errorf("invalid process ID")
-	}
-	if common.BytesToAddress(address) == (common.Address{}) {
-		return nil, fmt.Errorf("invalid address")
-	}
-	if weight == nil || weight.LessThanOrEqual(new(types.BigInt).SetInt(0)) ||
-		!weight.IsInField(bn254.ID.ScalarField()) {
-		return nil, fmt.Errorf("invalid weight")
-	}
-	// Reset the hash function before using it
-	c.hashFn.Reset()
-	// Hash the process ID and address to create a message suitable for signing
-	// using the poseidon hash function. Ensure that the process ID and address
-	// are converted to field elements for the curve.
-	message, err := c.hashFn.BigIntsSum([]*big.Int{
-		voterIndex.BigInt().MathBigInt(),
-		processID.BigInt().MathBigInt(),
-		address.BigInt().MathBigInt(),
-		weight.MathBigInt(),
-	})
-	if err != nil {
-		return nil, fmt.Errorf("error hashing signature message: %w", err)
-	}
-	return message.Bytes(), nil
+	return SignatureMessage(c.hashFn, voterIndex, processID, address, weight)
 }
 
 // sign method signs a message with the current EdDSA private key. It returns
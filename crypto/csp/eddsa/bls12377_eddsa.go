@@ -0,0 +1,218 @@
+package eddsa
+
+import (
+	"bytes"
+	"crypto/rand"
+	"fmt"
+	"math/big"
+
+	"github.com/consensys/gnark-crypto/ecc/bls12-377/fr"
+	"github.com/consensys/gnark-crypto/ecc/bls12-377/fr/mimc"
+	blseddsa "github.com/consensys/gnark-crypto/ecc/bls12-377/twistededwards/eddsa"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/vocdoni/davinci-node/types"
+)
+
+// BLS12377EdDSA struct implements the CSP interface for EdDSA signatures over
+// the twisted Edwards curve defined on the BLS12-377 scalar field, as
+// implemented by gnark-crypto. The signatures use the MiMC hash over the
+// BLS12-377 scalar field, so any gnark-crypto signer can issue valid proofs.
+type BLS12377EdDSA struct {
+	hashFn  Hash
+	privKey *blseddsa.PrivateKey
+	indexFn types.CSPIndexFn
+}
+
+// NewBLS12377KeyFromSeed creates a new BLS12377EdDSA using the hash function
+// provided and the provided seed. It implements the CSP interface and can be
+// used to generate and verify proofs for voters. If something goes wrong
+// during the key generation, it returns an error.
+func NewBLS12377KeyFromSeed(hashFn Hash, seed []byte) (*BLS12377EdDSA, error) {
+	// Ensure seed is not empty
+	if len(seed) == 0 {
+		return nil, fmt.Errorf("seed cannot be empty")
+	}
+	// Reset the hash function before using it
+	hashFn.Reset()
+	// Compute the hash of the seed
+	if _, err := hashFn.Write(seed); err != nil {
+		return nil, fmt.Errorf("error hashing seed: %w", err)
+	}
+	// Derive the private key from the hashed seed
+	var rawSeed [32]byte
+	copy(rawSeed[:], hashFn.Sum(nil))
+	privKey, err := blseddsa.GenerateKey(bytes.NewReader(rawSeed[:]))
+	if err != nil {
+		return nil, fmt.Errorf("error generating private key: %w", err)
+	}
+	return &BLS12377EdDSA{
+		hashFn:  hashFn,
+		privKey: privKey,
+		indexFn: DefaultCSPIndexFn,
+	}, nil
+}
+
+// NewBLS12377Key creates a new random BLS12377EdDSA using the hash function
+// provided. It implements the CSP interface and can be used to generate and
+// verify proofs for voters. If something goes wrong during the key
+// generation, it returns an error.
+func NewBLS12377Key(hashFn Hash) (*BLS12377EdDSA, error) {
+	privKey, err := blseddsa.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("error generating private key: %w", err)
+	}
+	return &BLS12377EdDSA{
+		hashFn:  hashFn,
+		privKey: privKey,
+		indexFn: DefaultCSPIndexFn,
+	}, nil
+}
+
+// SetIndexFn sets the index function for the BLS12377EdDSA instance.
+func (c *BLS12377EdDSA) SetIndexFn(indexFn types.CSPIndexFn) {
+	c.indexFn = indexFn
+}
+
+// CensusOrigin returns the origin of the credential service providers. It
+// returns the type of the CSP, which is EdDSA over BLS12-377 in this case.
+func (c *BLS12377EdDSA) CensusOrigin() types.CensusOrigin {
+	return types.CensusOriginCSPEdDSABLS12377V1
+}
+
+// PublicKey returns the compressed public key of the EdDSA instance.
+func (c *BLS12377EdDSA) PublicKey() types.HexBytes {
+	return c.privKey.PublicKey.Bytes()
+}
+
+// CensusRoot returns the census root computed from the public key of the
+// EdDSA instance. It uses the X and Y coordinates of the public key's point
+// to compute the hash. If the public key can not be converted to a census
+// root, it returns nil.
+func (c *BLS12377EdDSA) CensusRoot() *types.CensusRoot {
+	censusRoot, err := BLS12377CensusRoot(c.hashFn, &c.privKey.PublicKey)
+	if err != nil {
+		return nil
+	}
+	return &types.CensusRoot{
+		Root: censusRoot,
+	}
+}
+
+// GenerateProof generates a census proof for the given process ID, address
+// and weight. It signs the message composed by the voter index, the process
+// ID, the address and the weight using the private key of the CSP. It returns
+// a CensusProof struct that includes the hash of the public key as the root,
+// the compressed public key and the signature. It returns an error if the
+// inputs provided are not valid or something fails during signature process.
+func (c *BLS12377EdDSA) GenerateProof(
+	processID types.ProcessID,
+	address common.Address,
+	weight *types.BigInt,
+) (*types.CensusProof, error) {
+	voterIndex := c.indexFn(processID, address, weight)
+	// Compose the message and sign it
+	message, err := SignatureMessage(c.hashFn, voterIndex, processID, address.Bytes(), weight)
+	if err != nil {
+		return nil, fmt.Errorf("error composing signature message: %w", err)
+	}
+	signature, err := c.privKey.Sign(BLS12377Message(message), mimc.NewMiMC())
+	if err != nil {
+		return nil, fmt.Errorf("error signing message: %w", err)
+	}
+	// Get the root for the current private key
+	censusRoot, err := BLS12377CensusRoot(c.hashFn, &c.privKey.PublicKey)
+	if err != nil {
+		return nil, fmt.Errorf("error computing census root: %w", err)
+	}
+	return &types.CensusProof{
+		CensusOrigin: c.CensusOrigin(),
+		Root:         censusRoot,
+		Address:      address.Bytes(),
+		Weight:       weight,
+		VoterIndex:   voterIndex,
+		ProcessID:    processID,
+		PublicKey:    c.PublicKey(),
+		Signature:    signature,
+	}, nil
+}
+
+// VerifyProof method verifies the proof provided. It also verifies that the
+// census origin of the proof matches with the EdDSA instance one. It returns
+// an error if the proof provided is nil, the census origins do not match or
+// something fails during signature verification process.
+func (c *BLS12377EdDSA) VerifyProof(proof *types.CensusProof) error {
+	// Proof inputs checks
+	if proof == nil {
+		return fmt.Errorf("proof is nil")
+	}
+	if !proof.ProcessID.IsValid() {
+		return fmt.Errorf("process ID is nil")
+	}
+	if proof.CensusOrigin != c.CensusOrigin() {
+		return fmt.Errorf("proof origin mismatch: expected %s, got %s", c.CensusOrigin(), proof.CensusOrigin)
+	}
+	// Get the public key from the proof
+	pubKey, err := DecodeBLS12377PublicKey(proof.PublicKey)
+	if err != nil {
+		return fmt.Errorf("error getting public key from census proof: %w", err)
+	}
+	// Recompute the signature message
+	message, err := SignatureMessage(c.hashFn, proof.VoterIndex, proof.ProcessID, proof.Address, proof.Weight)
+	if err != nil {
+		return fmt.Errorf("error composing signature message: %w", err)
+	}
+	// Verify the signature using the public key and the message
+	verified, err := pubKey.Verify(proof.Signature, BLS12377Message(message), mimc.NewMiMC())
+	if err != nil {
+		return fmt.Errorf("error verifying signature: %w", err)
+	}
+	if !verified {
+		return fmt.Errorf("signature verification failed for address %s", proof.Address.String())
+	}
+	return nil
+}
+
+// BLS12377CensusRoot encodes the public key provided as a census root by
+// hashing its coords with the hash function provided.
+func BLS12377CensusRoot(hashFn Hash, pubKey *blseddsa.PublicKey) (types.HexBytes, error) {
+	// Reset the hash function before using it
+	hashFn.Reset()
+	hashedPubKey, err := hashFn.BigIntsSum([]*big.Int{
+		pubKey.A.X.BigInt(new(big.Int)),
+		pubKey.A.Y.BigInt(new(big.Int)),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error hashing public key: %w", err)
+	}
+	return types.NormalizedCensusRoot(hashedPubKey.Bytes()), nil
+}
+
+// BLS12377Message returns the bytes signed by a BLS12-377 EdDSA CSP for the
+// signature message provided. The message is reduced to the BLS12-377 scalar
+// field, so it can be written to the MiMC hash of the signature challenge.
+func BLS12377Message(message types.HexBytes) []byte {
+	var element fr.Element
+	element.SetBigInt(message.BigInt().MathBigInt())
+	b := element.Bytes()
+	return b[:]
+}
+
+// DecodeBLS12377PublicKey decodes a compressed BLS12-377 EdDSA public key,
+// checking that it is a point on the curve.
+func DecodeBLS12377PublicKey(b types.HexBytes) (*blseddsa.PublicKey, error) {
+	pubKey := &blseddsa.PublicKey{}
+	if _, err := pubKey.SetBytes(b); err != nil {
+		return nil, fmt.Errorf("error decoding public key: %w", err)
+	}
+	return pubKey, nil
+}
+
+// DecodeBLS12377Signature decodes a BLS12-377 EdDSA signature, checking that
+// its R point is on the curve and its S scalar is lower than the curve order.
+func DecodeBLS12377Signature(b types.HexBytes) (*blseddsa.Signature, error) {
+	signature := &blseddsa.Signature{}
+	if _, err := signature.SetBytes(b); err != nil {
+		return nil, fmt.Errorf("error decoding signature: %w", err)
+	}
+	return signature, nil
+}
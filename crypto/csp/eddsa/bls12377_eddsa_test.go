@@ -0,0 +1,73 @@
+package eddsa
+
+import (
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/vocdoni/davinci-node/internal/testutil"
+	"github.com/vocdoni/davinci-node/types"
+	"github.com/vocdoni/davinci-node/util"
+)
+
+func TestBLS12377GenerateVerifyProof(t *testing.T) {
+	c := qt.New(t)
+
+	processID := testutil.RandomProcessID()
+	userAddress := testutil.RandomAddress()
+	userWeight := types.NewInt(testutil.Weight)
+
+	csp, err := NewBLS12377Key(DefaultHashFn)
+	c.Assert(err, qt.IsNil)
+
+	t.Run("valid proof generation and verification", func(t *testing.T) {
+		proof, err := csp.GenerateProof(processID, userAddress, userWeight)
+		c.Assert(err, qt.IsNil)
+		c.Assert(proof.CensusOrigin, qt.Equals, types.CensusOriginCSPEdDSABLS12377V1)
+		c.Assert(proof.Root, qt.DeepEquals, csp.CensusRoot().Root)
+		c.Assert(csp.VerifyProof(proof), qt.IsNil)
+	})
+
+	t.Run("invalid proof pubkey", func(t *testing.T) {
+		proof, err := csp.GenerateProof(processID, userAddress, userWeight)
+		c.Assert(err, qt.IsNil)
+
+		other, err := NewBLS12377Key(DefaultHashFn)
+		c.Assert(err, qt.IsNil)
+		proof.PublicKey = other.PublicKey()
+		c.Assert(csp.VerifyProof(proof), qt.IsNotNil)
+	})
+
+	t.Run("invalid proof weight", func(t *testing.T) {
+		proof, err := csp.GenerateProof(processID, userAddress, userWeight)
+		c.Assert(err, qt.IsNil)
+
+		proof.Weight = types.NewInt(testutil.Weight + 1)
+		c.Assert(csp.VerifyProof(proof), qt.IsNotNil)
+	})
+
+	t.Run("invalid proof signature", func(t *testing.T) {
+		proof, err := csp.GenerateProof(processID, userAddress, userWeight)
+		c.Assert(err, qt.IsNil)
+
+		proof.Signature = util.RandomBytes(64)
+		c.Assert(csp.VerifyProof(proof), qt.IsNotNil)
+	})
+}
+
+func TestBLS12377KeyFromSeed(t *testing.T) {
+	c := qt.New(t)
+
+	csp1, err := NewBLS12377KeyFromSeed(DefaultHashFn, []byte("seed"))
+	c.Assert(err, qt.IsNil)
+	csp2, err := NewBLS12377KeyFromSeed(DefaultHashFn, []byte("seed"))
+	c.Assert(err, qt.IsNil)
+	c.Assert(csp1.PublicKey(), qt.DeepEquals, csp2.PublicKey())
+	c.Assert(csp1.CensusRoot().Root, qt.HasLen, types.CensusRootLength)
+
+	csp3, err := NewBLS12377KeyFromSeed(DefaultHashFn, []byte("other seed"))
+	c.Assert(err, qt.IsNil)
+	c.Assert(csp1.PublicKey(), qt.Not(qt.DeepEquals), csp3.PublicKey())
+
+	_, err = NewBLS12377KeyFromSeed(DefaultHashFn, nil)
+	c.Assert(err, qt.IsNotNil)
+}
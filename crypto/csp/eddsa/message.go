@@ -0,0 +1,49 @@
+package eddsa
+
+import (
+	"fmt"
+	"math/big"
+
+	bn254 "github.com/consensys/gnark-crypto/ecc/bn254"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/vocdoni/davinci-node/types"
+)
+
+// SignatureMessage creates the message that a CSP signs to certify a voter,
+// using the hash function provided to hash the voterIndex, process ID, the
+// address and the weight. Every CSP signature scheme signs this same message,
+// so the circuit computes it once for all of them.
+func SignatureMessage(
+	hashFn Hash,
+	voterIndex types.VoterIndex,
+	processID types.ProcessID,
+	address types.HexBytes,
+	weight *types.BigInt,
+) (types.HexBytes, error) {
+	// Inputs validation
+	if !processID.IsValid() {
+		return nil, fmt.Errorf("invalid process ID")
+	}
+	if common.BytesToAddress(address) == (common.Address{}) {
+		return nil, fmt.Errorf("invalid address")
+	}
+	if weight == nil || weight.LessThanOrEqual(new(types.BigInt).SetInt(0)) ||
+		!weight.IsInField(bn254.ID.ScalarField()) {
+		return nil, fmt.Errorf("invalid weight")
+	}
+	// Reset the hash function before using it
+	hashFn.Reset()
+	// Hash the process ID and address to create a message suitable for signing
+	// using the poseidon hash function. Ensure that the process ID and address
+	// are converted to field elements for the curve.
+	message, err := hashFn.BigIntsSum([]*big.Int{
+		voterIndex.BigInt().MathBigInt(),
+		processID.BigInt().MathBigInt(),
+		address.BigInt().MathBigInt(),
+		weight.MathBigInt(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error hashing signature message: %w", err)
+	}
+	return message.Bytes(), nil
+}
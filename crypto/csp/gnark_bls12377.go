@@ -0,0 +1,300 @@
+package csp
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/consensys/gnark-crypto/ecc/bls12-377/fr/mimc"
+	bls12377te "github.com/consensys/gnark-crypto/ecc/bls12-377/twistededwards"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/std/algebra/native/twistededwards"
+	"github.com/consensys/gnark/std/math/bits"
+	"github.com/consensys/gnark/std/math/emulated"
+	"github.com/consensys/gnark/std/math/emulated/emparams"
+
+	"github.com/vocdoni/davinci-node/circuits"
+	cspeddsa "github.com/vocdoni/davinci-node/crypto/csp/eddsa"
+	"github.com/vocdoni/davinci-node/types"
+	"github.com/vocdoni/gnark-crypto-primitives/ecc/bn254/eddsa"
+	"github.com/vocdoni/gnark-crypto-primitives/hash"
+	"github.com/vocdoni/gnark-crypto-primitives/hash/native"
+)
+
+// BLS12377CSPProof struct represents a proof generated by a BLS12-377 EdDSA
+// CredentialServiceProvider (CensusOriginCSPEdDSABLS12377V1) to be used in
+// the circuits. The coordinates of the BLS12-377 twisted Edwards points fit
+// in the native field, so they are native variables. It is a standalone
+// gadget: the state transition circuit only verifies BabyJubJub CSP proofs
+// (see CSPProof), so it must be used by a separate circuit version.
+type BLS12377CSPProof struct {
+	Signature  eddsa.Signature
+	PublicKey  eddsa.PublicKey
+	VoterIndex frontend.Variable
+}
+
+// IsValid method checks if the signature is valid or not using the gnark API.
+// It recomputes the message hash with the voter index, the process ID, the
+// address and the weight using poseidon, and checks the public key against
+// the census root and the EdDSA signature of the message.
+func (proof *BLS12377CSPProof) IsValid(
+	api frontend.API,
+	censusRoot, processID, address, weight frontend.Variable,
+) frontend.Variable {
+	// Initialize the poseidon hash function
+	hashFn, err := native.Poseidon(api)
+	if err != nil {
+		circuits.FrontendError(api, "failed to create poseidon hash function", err)
+		return 0
+	}
+	// Recompute the message hash with the voter index, the process ID, the
+	// address and the weight
+	msg, err := signatureMessage(hashFn, proof.VoterIndex, processID, address, weight)
+	if err != nil {
+		circuits.FrontendError(api, "failed to compose signature message", err)
+		return 0
+	}
+	valid, err := proof.isBLS12377EdDSAValid(api, hashFn, censusRoot, msg)
+	if err != nil {
+		circuits.FrontendError(api, "failed to verify bls12-377 eddsa signature", err)
+		return 0
+	}
+	return valid
+}
+
+// CensusProofToBLS12377CSPProof converts a types.CensusProof of a
+// CensusOriginCSPEdDSABLS12377V1 census to a BLS12377CSPProof to be used in
+// Gnark circuits. It decodes the public key and signature from the
+// CensusProof and converts them to gnark circuit types.
+func CensusProofToBLS12377CSPProof(censusProof *types.CensusProof) (*BLS12377CSPProof, error) {
+	if censusProof.CensusOrigin != types.CensusOriginCSPEdDSABLS12377V1 {
+		return nil, fmt.Errorf("unsupported census origin: %s", censusProof.CensusOrigin)
+	}
+	pubKey, err := cspeddsa.DecodeBLS12377PublicKey(censusProof.PublicKey)
+	if err != nil {
+		return nil, fmt.Errorf("error unmarshaling public key: %w", err)
+	}
+	signature, err := cspeddsa.DecodeBLS12377Signature(censusProof.Signature)
+	if err != nil {
+		return nil, fmt.Errorf("error decoding signature: %w", err)
+	}
+	return &BLS12377CSPProof{
+		Signature: eddsa.Signature{
+			R: twistededwards.Point{
+				X: signature.R.X.BigInt(new(big.Int)),
+				Y: signature.R.Y.BigInt(new(big.Int)),
+			},
+			S: new(big.Int).SetBytes(signature.S[:]),
+		},
+		PublicKey: eddsa.PublicKey{
+			A: twistededwards.Point{
+				X: pubKey.A.X.BigInt(new(big.Int)),
+				Y: pubKey.A.Y.BigInt(new(big.Int)),
+			},
+		},
+		VoterIndex: censusProof.VoterIndex.Uint64(),
+	}, nil
+}
+
+// bls12377Element is an element of the BLS12-377 scalar field, which is the
+// base field of the BLS12-377 twisted Edwards curve, emulated in the circuit.
+type bls12377Element = emulated.Element[emparams.BLS12377Fr]
+
+// bls12377Point is an affine point of the BLS12-377 twisted Edwards curve
+// with emulated coordinates.
+type bls12377Point struct {
+	X, Y *bls12377Element
+}
+
+// bls12377Curve provides the arithmetic of the BLS12-377 twisted Edwards
+// curve over the emulated BLS12-377 scalar field. It implements the complete
+// addition law of the curve, so it does not have exceptional cases for the
+// points on the curve, including the identity.
+type bls12377Curve struct {
+	api   frontend.API
+	field *emulated.Field[emparams.BLS12377Fr]
+	d     *big.Int
+	order *big.Int
+	// basePowers contains the multiples 2^i*Base of the curve base point,
+	// used to compute the fixed-base scalar multiplications.
+	basePowers []bls12377te.PointAffine
+}
+
+// newBLS12377Curve initializes the emulated BLS12-377 twisted Edwards curve.
+func newBLS12377Curve(api frontend.API) (*bls12377Curve, error) {
+	field, err := emulated.NewField[emparams.BLS12377Fr](api)
+	if err != nil {
+		return nil, err
+	}
+	params := bls12377te.GetEdwardsCurve()
+	basePowers := make([]bls12377te.PointAffine, params.Order.BitLen())
+	basePowers[0] = params.Base
+	for i := 1; i < len(basePowers); i++ {
+		basePowers[i].Double(&basePowers[i-1])
+	}
+	return &bls12377Curve{
+		api:        api,
+		field:      field,
+		d:          params.D.BigInt(new(big.Int)),
+		order:      new(big.Int).Set(&params.Order),
+		basePowers: basePowers,
+	}, nil
+}
+
+// element converts the native variable provided into an emulated element.
+// The variable may be bigger than the emulated modulus, so it is decomposed
+// in bits and packed into an unreduced element.
+func (c *bls12377Curve) element(v frontend.Variable) *bls12377Element {
+	return c.field.FromBits(bits.ToBinary(c.api, v)...)
+}
+
+// point converts the native coordinates of the point provided into emulated
+// ones.
+func (c *bls12377Curve) point(p twistededwards.Point) *bls12377Point {
+	return &bls12377Point{X: c.element(p.X), Y: c.element(p.Y)}
+}
+
+// constPoint returns the point provided as a constant emulated point.
+func (c *bls12377Curve) constPoint(p *bls12377te.PointAffine) *bls12377Point {
+	return &bls12377Point{
+		X: c.field.NewElement(p.X.BigInt(new(big.Int))),
+		Y: c.field.NewElement(p.Y.BigInt(new(big.Int))),
+	}
+}
+
+// identity returns the identity point of the curve, (0, 1).
+func (c *bls12377Curve) identity() *bls12377Point {
+	return &bls12377Point{X: c.field.Zero(), Y: c.field.One()}
+}
+
+// isOnCurve returns 1 if the point provided satisfies the curve equation
+// -x^2 + y^2 = 1 + d*x^2*y^2, 0 otherwise.
+func (c *bls12377Curve) isOnCurve(p *bls12377Point) frontend.Variable {
+	xx := c.field.Mul(p.X, p.X)
+	yy := c.field.Mul(p.Y, p.Y)
+	lhs := c.field.Sub(yy, xx)
+	rhs := c.field.Add(c.field.One(), c.field.MulConst(c.field.Mul(xx, yy), c.d))
+	return c.field.IsZero(c.field.Sub(lhs, rhs))
+}
+
+// add returns p + q using the complete addition law of the curve:
+//
+//	x3 = (x1*y2 + y1*x2) / (1 + d*x1*x2*y1*y2)
+//	y3 = (y1*y2 + x1*x2) / (1 - d*x1*x2*y1*y2)
+func (c *bls12377Curve) add(p, q *bls12377Point) *bls12377Point {
+	x1y2 := c.field.Mul(p.X, q.Y)
+	y1x2 := c.field.Mul(p.Y, q.X)
+	x1x2 := c.field.Mul(p.X, q.X)
+	y1y2 := c.field.Mul(p.Y, q.Y)
+	dxy := c.field.MulConst(c.field.Mul(x1x2, y1y2), c.d)
+	return &bls12377Point{
+		X: c.field.Div(c.field.Add(x1y2, y1x2), c.field.Add(c.field.One(), dxy)),
+		Y: c.field.Div(c.field.Add(y1y2, x1x2), c.field.Sub(c.field.One(), dxy)),
+	}
+}
+
+// selectPoint returns p if sel is 1, q otherwise.
+func (c *bls12377Curve) selectPoint(sel frontend.Variable, p, q *bls12377Point) *bls12377Point {
+	return &bls12377Point{
+		X: c.field.Select(sel, p.X, q.X),
+		Y: c.field.Select(sel, p.Y, q.Y),
+	}
+}
+
+// scalarMul returns [s]p, where the scalar is provided as its bits in little
+// endian order.
+func (c *bls12377Curve) scalarMul(p *bls12377Point, sBits []frontend.Variable) *bls12377Point {
+	res := c.identity()
+	for i := len(sBits) - 1; i >= 0; i-- {
+		res = c.add(res, res)
+		res = c.selectPoint(sBits[i], c.add(res, p), res)
+	}
+	return res
+}
+
+// scalarMulBase returns [s]Base, where the scalar is provided as its bits in
+// little endian order. It adds the precomputed multiples of the base point
+// selected by the scalar bits, so it does not need any doubling.
+func (c *bls12377Curve) scalarMulBase(sBits []frontend.Variable) *bls12377Point {
+	res := c.identity()
+	for i, bit := range sBits {
+		res = c.selectPoint(bit, c.add(res, c.constPoint(&c.basePowers[i])), res)
+	}
+	return res
+}
+
+// clearCofactor returns [4]p, the point multiplied by the cofactor of the
+// curve.
+func (c *bls12377Curve) clearCofactor(p *bls12377Point) *bls12377Point {
+	p = c.add(p, p)
+	return c.add(p, p)
+}
+
+// isEqual returns 1 if both points are equal, 0 otherwise.
+func (c *bls12377Curve) isEqual(p, q *bls12377Point) frontend.Variable {
+	return c.api.And(
+		c.field.IsZero(c.field.Sub(p.X, q.X)),
+		c.field.IsZero(c.field.Sub(p.Y, q.Y)),
+	)
+}
+
+// mimc returns the MiMC hash over the BLS12-377 scalar field of the inputs
+// provided, as implemented by gnark-crypto, which uses the Miyaguchi-Preneel
+// construction with the x^17 permutation.
+func (c *bls12377Curve) mimc(inputs ...*bls12377Element) *bls12377Element {
+	constants := mimc.GetConstants()
+	h := c.field.Zero()
+	for _, input := range inputs {
+		m := input
+		for i := range constants {
+			tmp := c.field.Add(c.field.Add(m, h), c.field.NewElement(&constants[i]))
+			// m = tmp^17
+			m = c.field.Mul(tmp, tmp)
+			m = c.field.Mul(m, m)
+			m = c.field.Mul(m, m)
+			m = c.field.Mul(m, m)
+			m = c.field.Mul(m, tmp)
+		}
+		h = c.field.Add(c.field.Add(m, h), c.field.Add(h, input))
+	}
+	return h
+}
+
+// isBLS12377EdDSAValid checks if the BLS12-377 EdDSA signature of the proof
+// is valid. It checks that the census root is the hash of the public key
+// coordinates and that the signature of the message provided verifies with
+// the gnark-crypto verification equation [4][S]Base = [4](R + [H]A), where
+// H is the MiMC hash of R, A and the message. Both the public key and R must
+// be points of the curve, otherwise a key out of the curve could be used to
+// forge signatures.
+func (proof *BLS12377CSPProof) isBLS12377EdDSAValid(
+	api frontend.API,
+	hashFn hash.Hash[frontend.Variable],
+	censusRoot, msg frontend.Variable,
+) (frontend.Variable, error) {
+	// Check if the census root matches the expected one
+	hashFn.Reset()
+	hashFn.Write(proof.PublicKey.A.X, proof.PublicKey.A.Y)
+	if !hashFn.WriteSucceeded() {
+		return 0, fmt.Errorf("error writing hash inputs")
+	}
+	validPubKey := hashFn.SumIsEqual(censusRoot)
+	hashFn.Reset()
+	// Convert the inputs to the emulated field of the curve
+	curve, err := newBLS12377Curve(api)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create emulated curve: %w", err)
+	}
+	pubKey := curve.point(proof.PublicKey.A)
+	r := curve.point(proof.Signature.R)
+	emulatedMsg := curve.element(msg)
+	// H = MiMC(R.X, R.Y, A.X, A.Y, M)
+	h := curve.mimc(r.X, r.Y, pubKey.X, pubKey.Y, emulatedMsg)
+	hBits := curve.field.ToBitsCanonical(h)
+	sBits := bits.ToBinary(api, proof.Signature.S, bits.WithNbDigits(curve.order.BitLen()))
+	// [4][S]Base == [4](R + [H]A)
+	lhs := curve.clearCofactor(curve.scalarMulBase(sBits))
+	rhs := curve.clearCofactor(curve.add(r, curve.scalarMul(pubKey, hBits)))
+	validPoints := api.And(curve.isOnCurve(pubKey), curve.isOnCurve(r))
+	validSignature := api.And(validPoints, curve.isEqual(lhs, rhs))
+	return api.And(validPubKey, validSignature), nil
+}
@@ -0,0 +1,126 @@
+package csp
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/std/algebra/emulated/sw_emulated"
+	"github.com/consensys/gnark/std/math/bits"
+	"github.com/consensys/gnark/std/math/emulated"
+	"github.com/consensys/gnark/std/signature/ecdsa"
+	ethcrypto "github.com/ethereum/go-ethereum/crypto"
+
+	"github.com/vocdoni/davinci-node/circuits"
+	cspecdsa "github.com/vocdoni/davinci-node/crypto/csp/ecdsa"
+	"github.com/vocdoni/davinci-node/types"
+	"github.com/vocdoni/gnark-crypto-primitives/hash"
+	"github.com/vocdoni/gnark-crypto-primitives/hash/native"
+	"github.com/vocdoni/gnark-crypto-primitives/utils"
+)
+
+// Secp256k1CSPProof struct represents a proof generated by a secp256k1 ECDSA
+// CredentialServiceProvider (CensusOriginCSPECDSASecp256k1V1) to be used in
+// the circuits. It is a standalone gadget: the state transition circuit only
+// verifies BabyJubJub CSP proofs (see CSPProof), so it must be used by a
+// separate circuit version.
+type Secp256k1CSPProof struct {
+	Signature  ecdsa.Signature[emulated.Secp256k1Fr]
+	PublicKey  ecdsa.PublicKey[emulated.Secp256k1Fp, emulated.Secp256k1Fr]
+	VoterIndex frontend.Variable
+}
+
+// IsValid method checks if the signature is valid or not using the gnark API.
+// It recomputes the message hash with the voter index, the process ID, the
+// address and the weight using poseidon, and checks the public key against
+// the census root and the ECDSA signature of the message.
+func (proof *Secp256k1CSPProof) IsValid(
+	api frontend.API,
+	censusRoot, processID, address, weight frontend.Variable,
+) frontend.Variable {
+	// Initialize the poseidon hash function
+	hashFn, err := native.Poseidon(api)
+	if err != nil {
+		circuits.FrontendError(api, "failed to create poseidon hash function", err)
+		return 0
+	}
+	// Recompute the message hash with the voter index, the process ID, the
+	// address and the weight
+	msg, err := signatureMessage(hashFn, proof.VoterIndex, processID, address, weight)
+	if err != nil {
+		circuits.FrontendError(api, "failed to compose signature message", err)
+		return 0
+	}
+	valid, err := proof.isSecp256k1ECDSAValid(api, hashFn, censusRoot, msg)
+	if err != nil {
+		circuits.FrontendError(api, "failed to verify secp256k1 ecdsa signature", err)
+		return 0
+	}
+	return valid
+}
+
+// isSecp256k1ECDSAValid checks if the secp256k1 ECDSA signature of the proof
+// is valid. It checks that the census root is the hash of the 128 bits halves
+// of the public key coordinates and that the signature of the message
+// provided, used as the signed digest, is valid for the public key.
+func (proof *Secp256k1CSPProof) isSecp256k1ECDSAValid(
+	api frontend.API,
+	hashFn hash.Hash[frontend.Variable],
+	censusRoot, msg frontend.Variable,
+) (frontend.Variable, error) {
+	baseField, err := emulated.NewField[emulated.Secp256k1Fp](api)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create emulated field: %w", err)
+	}
+	// Check if the census root matches the expected one
+	xBits := baseField.ToBitsCanonical(&proof.PublicKey.X)
+	yBits := baseField.ToBitsCanonical(&proof.PublicKey.Y)
+	hashFn.Reset()
+	hashFn.Write(
+		bits.FromBinary(api, xBits[128:]), bits.FromBinary(api, xBits[:128]),
+		bits.FromBinary(api, yBits[128:]), bits.FromBinary(api, yBits[:128]),
+	)
+	if !hashFn.WriteSucceeded() {
+		return 0, fmt.Errorf("error writing hash inputs")
+	}
+	validPubKey := hashFn.SumIsEqual(censusRoot)
+	hashFn.Reset()
+	// Check the signature of the message, which is smaller than the
+	// secp256k1 scalar field modulus
+	emulatedMsg, err := utils.UnpackVarToScalar[emulated.Secp256k1Fr](api, msg)
+	if err != nil {
+		return 0, fmt.Errorf("failed to convert message: %w", err)
+	}
+	validSignature := proof.PublicKey.IsValid(api, sw_emulated.GetCurveParams[emulated.Secp256k1Fp](),
+		emulatedMsg, &proof.Signature)
+	return api.And(validPubKey, validSignature), nil
+}
+
+// CensusProofToSecp256k1CSPProof converts a types.CensusProof of a
+// CensusOriginCSPECDSASecp256k1V1 census to a Secp256k1CSPProof to be used in
+// Gnark circuits. It decompresses the public key and decodes the signature
+// from the CensusProof and converts them to emulated gnark circuit types.
+func CensusProofToSecp256k1CSPProof(censusProof *types.CensusProof) (*Secp256k1CSPProof, error) {
+	if censusProof.CensusOrigin != types.CensusOriginCSPECDSASecp256k1V1 {
+		return nil, fmt.Errorf("unsupported census origin: %s", censusProof.CensusOrigin)
+	}
+	pubKey, err := ethcrypto.DecompressPubkey(censusProof.PublicKey)
+	if err != nil {
+		return nil, fmt.Errorf("error unmarshaling public key: %w", err)
+	}
+	signature, err := cspecdsa.DecodeSignature(censusProof.Signature)
+	if err != nil {
+		return nil, fmt.Errorf("error decoding signature: %w", err)
+	}
+	return &Secp256k1CSPProof{
+		Signature: ecdsa.Signature[emulated.Secp256k1Fr]{
+			R: emulated.ValueOf[emulated.Secp256k1Fr](new(big.Int).SetBytes(signature[:32])),
+			S: emulated.ValueOf[emulated.Secp256k1Fr](new(big.Int).SetBytes(signature[32:])),
+		},
+		PublicKey: ecdsa.PublicKey[emulated.Secp256k1Fp, emulated.Secp256k1Fr]{
+			X: emulated.ValueOf[emulated.Secp256k1Fp](pubKey.X),
+			Y: emulated.ValueOf[emulated.Secp256k1Fp](pubKey.Y),
+		},
+		VoterIndex: censusProof.VoterIndex.Uint64(),
+	}, nil
+}
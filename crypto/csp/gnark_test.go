@@ -1,6 +1,7 @@
 package csp
 
 import (
+	"math/big"
 	"os"
 	"testing"
 	"time"
@@ -11,10 +12,12 @@ import (
 	"github.com/consensys/gnark/frontend/cs/r1cs"
 	"github.com/consensys/gnark/logger"
 	"github.com/consensys/gnark/profile"
+	"github.com/consensys/gnark/std/algebra/native/twistededwards"
 	"github.com/consensys/gnark/test"
 	"github.com/ethereum/go-ethereum/common"
 	qt "github.com/frankban/quicktest"
 	"github.com/rs/zerolog"
+	cspeddsa "github.com/vocdoni/davinci-node/crypto/csp/eddsa"
 	"github.com/vocdoni/davinci-node/internal/testutil"
 	"github.com/vocdoni/davinci-node/log"
 	"github.com/vocdoni/davinci-node/spec/params"
@@ -72,3 +75,163 @@ func TestCSPProofCircuit(t *testing.T) {
 	p.Stop()
 	log.Infow("constraints", "constraints", p.NbConstraints())
 }
+
+type secp256k1CSPProofCircuit struct {
+	Proof      Secp256k1CSPProof
+	CensusRoot frontend.Variable
+	ProcessID  frontend.Variable
+	Address    frontend.Variable
+	Weight     frontend.Variable
+}
+
+func (c *secp256k1CSPProofCircuit) Define(api frontend.API) error {
+	api.AssertIsEqual(c.Proof.IsValid(api, c.CensusRoot, c.ProcessID, c.Address, c.Weight), 1)
+	return nil
+}
+
+func TestSecp256k1CSPProofCircuit(t *testing.T) {
+	c := qt.New(t)
+
+	csp, err := New(types.CensusOriginCSPECDSASecp256k1V1, nil)
+	c.Assert(err, qt.IsNil)
+
+	userAddress := common.Address(util.RandomBytes(20))
+	userWeight := new(types.BigInt).SetInt(42)
+
+	processID := testutil.RandomProcessID()
+
+	proof, err := csp.GenerateProof(processID, userAddress, userWeight)
+	c.Assert(err, qt.IsNil)
+
+	gnarkProof, err := CensusProofToSecp256k1CSPProof(proof)
+	c.Assert(err, qt.IsNil)
+
+	assignments := &secp256k1CSPProofCircuit{
+		Proof:      *gnarkProof,
+		CensusRoot: proof.Root.BigInt().MathBigInt(),
+		ProcessID:  processID.MathBigInt(),
+		Address:    userAddress.Big(),
+		Weight:     userWeight.MathBigInt(),
+	}
+	assert := test.NewAssert(t)
+	assert.SolvingSucceeded(&secp256k1CSPProofCircuit{}, assignments,
+		test.WithCurves(params.StateTransitionCurve),
+		test.WithBackends(backend.GROTH16))
+
+	// the proof is not valid for other weights
+	invalid := *assignments
+	invalid.Weight = 43
+	assert.SolvingFailed(&secp256k1CSPProofCircuit{}, &invalid,
+		test.WithCurves(params.StateTransitionCurve),
+		test.WithBackends(backend.GROTH16))
+
+	p := profile.Start()
+	_, _ = frontend.Compile(ecc.BN254.ScalarField(), r1cs.NewBuilder, &secp256k1CSPProofCircuit{})
+	p.Stop()
+	log.Infow("constraints", "constraints", p.NbConstraints())
+}
+
+type bls12377CSPProofCircuit struct {
+	Proof      BLS12377CSPProof
+	CensusRoot frontend.Variable
+	ProcessID  frontend.Variable
+	Address    frontend.Variable
+	Weight     frontend.Variable
+}
+
+func (c *bls12377CSPProofCircuit) Define(api frontend.API) error {
+	api.AssertIsEqual(c.Proof.IsValid(api, c.CensusRoot, c.ProcessID, c.Address, c.Weight), 1)
+	return nil
+}
+
+func TestBLS12377CSPProofCircuit(t *testing.T) {
+	c := qt.New(t)
+
+	csp, err := New(types.CensusOriginCSPEdDSABLS12377V1, nil)
+	c.Assert(err, qt.IsNil)
+
+	userAddress := common.Address(util.RandomBytes(20))
+	userWeight := new(types.BigInt).SetInt(42)
+
+	processID := testutil.RandomProcessID()
+
+	proof, err := csp.GenerateProof(processID, userAddress, userWeight)
+	c.Assert(err, qt.IsNil)
+
+	gnarkProof, err := CensusProofToBLS12377CSPProof(proof)
+	c.Assert(err, qt.IsNil)
+
+	assignments := &bls12377CSPProofCircuit{
+		Proof:      *gnarkProof,
+		CensusRoot: proof.Root.BigInt().MathBigInt(),
+		ProcessID:  processID.MathBigInt(),
+		Address:    userAddress.Big(),
+		Weight:     userWeight.MathBigInt(),
+	}
+	assert := test.NewAssert(t)
+	assert.SolvingSucceeded(&bls12377CSPProofCircuit{}, assignments,
+		test.WithCurves(params.StateTransitionCurve),
+		test.WithBackends(backend.GROTH16))
+
+	// the proof is not valid for other weights
+	invalid := *assignments
+	invalid.Weight = 43
+	assert.SolvingFailed(&bls12377CSPProofCircuit{}, &invalid,
+		test.WithCurves(params.StateTransitionCurve),
+		test.WithBackends(backend.GROTH16))
+
+	// the proof is not valid for a public key out of the curve, even if the
+	// census root is the hash of it
+	offCurveX := new(big.Int).Add(gnarkProof.PublicKey.A.X.(*big.Int), big.NewInt(1))
+	offCurveY := gnarkProof.PublicKey.A.Y.(*big.Int)
+	offCurveRoot, err := cspeddsa.DefaultHashFn.BigIntsSum([]*big.Int{offCurveX, offCurveY})
+	c.Assert(err, qt.IsNil)
+	invalid = *assignments
+	invalid.Proof.PublicKey.A = twistededwards.Point{X: offCurveX, Y: offCurveY}
+	invalid.CensusRoot = offCurveRoot
+	assert.SolvingFailed(&bls12377CSPProofCircuit{}, &invalid,
+		test.WithCurves(params.StateTransitionCurve),
+		test.WithBackends(backend.GROTH16))
+
+	p := profile.Start()
+	_, _ = frontend.Compile(ecc.BN254.ScalarField(), r1cs.NewBuilder, &bls12377CSPProofCircuit{})
+	p.Stop()
+	log.Infow("constraints", "constraints", p.NbConstraints())
+}
+
+type bls12377OnCurveCircuit struct {
+	Point    twistededwards.Point
+	Expected frontend.Variable
+}
+
+func (c *bls12377OnCurveCircuit) Define(api frontend.API) error {
+	curve, err := newBLS12377Curve(api)
+	if err != nil {
+		return err
+	}
+	api.AssertIsEqual(curve.isOnCurve(curve.point(c.Point)), c.Expected)
+	return nil
+}
+
+func TestBLS12377CurveIsOnCurve(t *testing.T) {
+	c := qt.New(t)
+
+	csp, err := New(types.CensusOriginCSPEdDSABLS12377V1, nil)
+	c.Assert(err, qt.IsNil)
+	proof, err := csp.GenerateProof(testutil.RandomProcessID(), common.Address(util.RandomBytes(20)), new(types.BigInt).SetInt(42))
+	c.Assert(err, qt.IsNil)
+	pubKey, err := cspeddsa.DecodeBLS12377PublicKey(proof.PublicKey)
+	c.Assert(err, qt.IsNil)
+	x := pubKey.A.X.BigInt(new(big.Int))
+	y := pubKey.A.Y.BigInt(new(big.Int))
+
+	assert := test.NewAssert(t)
+	assert.SolvingSucceeded(&bls12377OnCurveCircuit{}, &bls12377OnCurveCircuit{
+		Point:    twistededwards.Point{X: x, Y: y},
+		Expected: 1,
+	}, test.WithCurves(params.StateTransitionCurve), test.WithBackends(backend.GROTH16))
+	assert.SolvingSucceeded(&bls12377OnCurveCircuit{}, &bls12377OnCurveCircuit{
+		Point:    twistededwards.Point{X: new(big.Int).Add(x, big.NewInt(1)), Y: y},
+		Expected: 0,
+	}, test.WithCurves(params.StateTransitionCurve), test.WithBackends(backend.GROTH16))
+}
@@ -88,6 +88,42 @@ func (z *Ballot) Encrypt(message [params.FieldsPerBallot]*big.Int, publicKey ecc
 	return z, nil
 }
 
+// DecryptWithK recovers the messages of a ballot encrypted by Encrypt with
+// the randomness k, without the private key, searching each discrete log in
+// the interval [0,maxMessage]. It fails if some ciphertext was not encrypted
+// with the randomness derived from k. It is used to audit the ballots whose
+// randomness is revealed.
+func (z *Ballot) DecryptWithK(publicKey ecc.Point, k *big.Int, maxMessage uint64) ([params.FieldsPerBallot]*big.Int, error) {
+	messages := [params.FieldsPerBallot]*big.Int{}
+	if !z.Valid() || k == nil {
+		return messages, fmt.Errorf("invalid ballot or randomness")
+	}
+	lastK, err := poseidon.MultiPoseidon(k)
+	if err != nil {
+		return messages, err
+	}
+	g := publicKey.New()
+	g.SetGenerator()
+	for i, ct := range z.Ciphertexts {
+		if !CheckK(ct.C1, lastK) {
+			return messages, fmt.Errorf("ciphertext %d was not encrypted with the randomness provided", i)
+		}
+		// M = C2 - k·publicKey
+		s := publicKey.New()
+		s.ScalarMult(publicKey, lastK)
+		s.Neg(s)
+		m := ct.C2.New()
+		m.Add(ct.C2, s)
+		if messages[i], err = BabyStepGiantStepECC(m, g, maxMessage); err != nil {
+			return messages, fmt.Errorf("could not decrypt ciphertext %d: %w", i, err)
+		}
+		if lastK, err = poseidon.MultiPoseidon(lastK); err != nil {
+			return messages, err
+		}
+	}
+	return messages, nil
+}
+
 // Reencrypt reencrypts the ballot using the provided public key and k. It
 // returns the reencrypted ballot, the k used for re-encryption, or an error
 // if the re-encryption fails. The re-encryption is done by adding the
@@ -1,11 +1,14 @@
 package elgamal
 
 import (
+	"math/big"
 	"testing"
 
 	qt "github.com/frankban/quicktest"
 	bjj "github.com/vocdoni/davinci-node/crypto/ecc/bjj_gnark"
 	"github.com/vocdoni/davinci-node/crypto/ecc/curves"
+	"github.com/vocdoni/davinci-node/spec/params"
+	specutil "github.com/vocdoni/davinci-node/spec/util"
 )
 
 func TestBallotFromRTEtoTEInvalidCurve(t *testing.T) {
@@ -29,3 +32,35 @@ func TestBallotValidRejectsCiphertextsWithNilPoints(t *testing.T) {
 
 	c.Assert(ballot.Valid(), qt.IsFalse)
 }
+
+func TestBallotDecryptWithK(t *testing.T) {
+	c := qt.New(t)
+
+	publicKey, _, err := GenerateKey(curves.New(bjj.CurveType))
+	c.Assert(err, qt.IsNil)
+	k, err := specutil.RandomK()
+	c.Assert(err, qt.IsNil)
+
+	fields := [params.FieldsPerBallot]*big.Int{}
+	for i := range fields {
+		fields[i] = big.NewInt(int64(i * 3))
+	}
+	ballot, err := NewBallot(publicKey).Encrypt(fields, publicKey, k)
+	c.Assert(err, qt.IsNil)
+
+	messages, err := ballot.DecryptWithK(publicKey, k, 100)
+	c.Assert(err, qt.IsNil)
+	for i := range fields {
+		c.Assert(messages[i].Cmp(fields[i]), qt.Equals, 0)
+	}
+
+	// the messages must be in the interval
+	_, err = ballot.DecryptWithK(publicKey, k, 10)
+	c.Assert(err, qt.ErrorMatches, "could not decrypt ciphertext 4.*")
+
+	// other randomness does not decrypt the ballot
+	otherK, err := specutil.RandomK()
+	c.Assert(err, qt.IsNil)
+	_, err = ballot.DecryptWithK(publicKey, otherK, 100)
+	c.Assert(err, qt.ErrorMatches, "ciphertext 0 was not encrypted with the randomness provided")
+}
@@ -49,6 +49,13 @@ func CombinePartialDecryptions(c2 ecc.Point, partialDecryptions map[int]ecc.Poin
 	return messageScalar, nil
 }
 
+// LagrangeCoefficients computes the Lagrange coefficients at zero for the
+// given participant IDs, modulo mod. They are required to build the partial
+// responses of a combined decryption proof.
+func LagrangeCoefficients(participants []int, mod *big.Int) (map[int]*big.Int, error) {
+	return computeLagrangeCoefficients(participants, mod)
+}
+
 // computeLagrangeCoefficients computes Lagrange coefficients for given participant IDs.
 func computeLagrangeCoefficients(participants []int, mod *big.Int) (map[int]*big.Int, error) {
 	coeffs := make(map[int]*big.Int)
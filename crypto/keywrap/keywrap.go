@@ -0,0 +1,32 @@
+// Package keywrap protects secrets at rest by wrapping them with a
+// key-encryption key (KEK). The KeyWrapper interface abstracts where the KEK
+// lives, so the default local implementation, which derives the KEK from a
+// passphrase, can be replaced by HSM or KMS backed implementations.
+package keywrap
+
+import "errors"
+
+var (
+	// ErrUnwrap is returned when a wrapped secret can not be unwrapped,
+	// because it was tampered, wrapped with a different KEK or bound to a
+	// different additional data.
+	ErrUnwrap = errors.New("could not unwrap secret")
+	// ErrInvalidPassphrase is returned when the passphrase does not match
+	// the one used to create the local key file.
+	ErrInvalidPassphrase = errors.New("invalid key file passphrase")
+)
+
+// KeyWrapper wraps and unwraps secrets with a key-encryption key. The
+// additional data is authenticated but not encrypted, and it must be the same
+// to unwrap the secret, which allows binding a wrapped secret to its context
+// (for example, the public key of a wrapped private key).
+type KeyWrapper interface {
+	// ID returns a stable identifier of the key-encryption key. It is
+	// stored next to every wrapped secret to select the wrapper able to
+	// unwrap it, which allows rotating the key-encryption key.
+	ID() string
+	// Wrap encrypts the secret provided with the key-encryption key.
+	Wrap(secret, additionalData []byte) ([]byte, error)
+	// Unwrap decrypts a secret previously encrypted by Wrap.
+	Unwrap(wrapped, additionalData []byte) ([]byte, error)
+}
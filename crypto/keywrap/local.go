@@ -0,0 +1,231 @@
+package keywrap
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+const (
+	// localKeyFileVersion is the version of the local key file format.
+	localKeyFileVersion = 1
+	// Default scrypt parameters, as recommended for interactive logins in
+	// 2017 but with a higher cost since the derivation runs once at startup.
+	defaultScryptN = 1 << 17
+	defaultScryptR = 8
+	defaultScryptP = 1
+	kekSize        = 32
+	saltSize       = 32
+)
+
+// PassphraseParams are the parameters to derive a key-encryption key from a
+// passphrase with scrypt, and a MAC to detect a wrong passphrase early. They
+// are not secret, so they can be stored next to the secrets wrapped with the
+// key-encryption key.
+type PassphraseParams struct {
+	Salt  string `json:"salt"`
+	N     int    `json:"n"`
+	R     int    `json:"r"`
+	P     int    `json:"p"`
+	Check string `json:"check"`
+}
+
+// localKeyFile is the content of the key file used by LocalKeyWrapper.
+type localKeyFile struct {
+	Version int `json:"version"`
+	PassphraseParams
+}
+
+// LocalKeyWrapper is a KeyWrapper that derives the key-encryption key from a
+// passphrase with scrypt, and wraps the secrets with AES-256-GCM. The scrypt
+// parameters and salt are stored in a local key file, so the same passphrase
+// always derives the same key-encryption key for that file.
+type LocalKeyWrapper struct {
+	id   string
+	aead cipher.AEAD
+}
+
+// NewLocalKeyWrapper loads the key file at the path provided and derives the
+// key-encryption key from the passphrase. If the file does not exist, it is
+// created with a random salt. It returns ErrInvalidPassphrase if the
+// passphrase does not match the one used to create the file.
+func NewLocalKeyWrapper(path, passphrase string) (*LocalKeyWrapper, error) {
+	if passphrase == "" {
+		return nil, fmt.Errorf("empty passphrase")
+	}
+	kf, err := loadLocalKeyFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return createLocalKeyWrapper(path, passphrase)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return OpenPassphraseKeyWrapper(passphrase, &kf.PassphraseParams)
+}
+
+// NewPassphraseKeyWrapper derives a new key-encryption key from the
+// passphrase provided, with a random salt and the default scrypt parameters.
+// It returns its wrapper and the parameters to derive it again with
+// OpenPassphraseKeyWrapper.
+func NewPassphraseKeyWrapper(passphrase string) (*LocalKeyWrapper, *PassphraseParams, error) {
+	if passphrase == "" {
+		return nil, nil, fmt.Errorf("empty passphrase")
+	}
+	salt := make([]byte, saltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, nil, fmt.Errorf("could not generate salt: %w", err)
+	}
+	kek, err := scrypt.Key([]byte(passphrase), salt, defaultScryptN, defaultScryptR, defaultScryptP, kekSize)
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not derive key-encryption key: %w", err)
+	}
+	kw, err := newLocalKeyWrapper(kek)
+	if err != nil {
+		return nil, nil, err
+	}
+	return kw, &PassphraseParams{
+		Salt:  hex.EncodeToString(salt),
+		N:     defaultScryptN,
+		R:     defaultScryptR,
+		P:     defaultScryptP,
+		Check: hex.EncodeToString(kekCheck(kek)),
+	}, nil
+}
+
+// OpenPassphraseKeyWrapper derives the key-encryption key from the
+// passphrase and the parameters provided, returned by
+// NewPassphraseKeyWrapper. It returns ErrInvalidPassphrase if the passphrase
+// does not match the one used to create the parameters.
+func OpenPassphraseKeyWrapper(passphrase string, params *PassphraseParams) (*LocalKeyWrapper, error) {
+	if passphrase == "" {
+		return nil, fmt.Errorf("empty passphrase")
+	}
+	if params == nil {
+		return nil, fmt.Errorf("missing passphrase parameters")
+	}
+	salt, err := hex.DecodeString(params.Salt)
+	if err != nil {
+		return nil, fmt.Errorf("invalid salt: %w", err)
+	}
+	kek, err := scrypt.Key([]byte(passphrase), salt, params.N, params.R, params.P, kekSize)
+	if err != nil {
+		return nil, fmt.Errorf("could not derive key-encryption key: %w", err)
+	}
+	check, err := hex.DecodeString(params.Check)
+	if err != nil {
+		return nil, fmt.Errorf("invalid passphrase check: %w", err)
+	}
+	if !hmac.Equal(check, kekCheck(kek)) {
+		return nil, ErrInvalidPassphrase
+	}
+	return newLocalKeyWrapper(kek)
+}
+
+// createLocalKeyWrapper creates a new key file at the path provided with a
+// random salt and the default scrypt parameters, and returns the wrapper for
+// the key-encryption key derived from the passphrase.
+func createLocalKeyWrapper(path, passphrase string) (*LocalKeyWrapper, error) {
+	kw, params, err := NewPassphraseKeyWrapper(passphrase)
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.MarshalIndent(&localKeyFile{
+		Version:          localKeyFileVersion,
+		PassphraseParams: *params,
+	}, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, fmt.Errorf("could not create key file directory: %w", err)
+	}
+	// O_EXCL avoids overwriting a key file created concurrently, which would
+	// make the secrets wrapped with it unrecoverable.
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("could not create key file: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+	if _, err := f.Write(data); err != nil {
+		return nil, fmt.Errorf("could not write key file: %w", err)
+	}
+	return kw, nil
+}
+
+func loadLocalKeyFile(path string) (*localKeyFile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	kf := &localKeyFile{}
+	if err := json.Unmarshal(data, kf); err != nil {
+		return nil, fmt.Errorf("malformed key file: %w", err)
+	}
+	if kf.Version != localKeyFileVersion {
+		return nil, fmt.Errorf("unsupported key file version %d", kf.Version)
+	}
+	return kf, nil
+}
+
+func newLocalKeyWrapper(kek []byte) (*LocalKeyWrapper, error) {
+	block, err := aes.NewCipher(kek)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	id := sha256.Sum256(append([]byte("davinci/keywrap/id/"), kek...))
+	return &LocalKeyWrapper{
+		id:   "local:" + hex.EncodeToString(id[:8]),
+		aead: aead,
+	}, nil
+}
+
+// kekCheck returns the value stored in the key file to verify that a
+// passphrase derives the right key-encryption key.
+func kekCheck(kek []byte) []byte {
+	mac := hmac.New(sha256.New, kek)
+	mac.Write([]byte("davinci/keywrap/check"))
+	return mac.Sum(nil)
+}
+
+// ID implements KeyWrapper.
+func (w *LocalKeyWrapper) ID() string {
+	return w.id
+}
+
+// Wrap implements KeyWrapper. The result is the random nonce followed by
+// the AES-GCM sealed secret.
+func (w *LocalKeyWrapper) Wrap(secret, additionalData []byte) ([]byte, error) {
+	nonce := make([]byte, w.aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, fmt.Errorf("could not generate nonce: %w", err)
+	}
+	return w.aead.Seal(nonce, nonce, secret, additionalData), nil
+}
+
+// Unwrap implements KeyWrapper.
+func (w *LocalKeyWrapper) Unwrap(wrapped, additionalData []byte) ([]byte, error) {
+	if len(wrapped) < w.aead.NonceSize() {
+		return nil, ErrUnwrap
+	}
+	nonce, sealed := wrapped[:w.aead.NonceSize()], wrapped[w.aead.NonceSize():]
+	secret, err := w.aead.Open(nil, nonce, sealed, additionalData)
+	if err != nil {
+		return nil, ErrUnwrap
+	}
+	return secret, nil
+}
@@ -0,0 +1,86 @@
+package keywrap
+
+import (
+	"path/filepath"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+)
+
+func TestLocalKeyWrapper(t *testing.T) {
+	c := qt.New(t)
+	path := filepath.Join(t.TempDir(), "keys", "kek.json")
+
+	// The key file is created on first use
+	kw, err := NewLocalKeyWrapper(path, "passphrase")
+	c.Assert(err, qt.IsNil)
+
+	secret := []byte("private key")
+	aad := []byte("public key")
+	wrapped, err := kw.Wrap(secret, aad)
+	c.Assert(err, qt.IsNil)
+	c.Assert(wrapped, qt.Not(qt.DeepEquals), secret)
+
+	unwrapped, err := kw.Unwrap(wrapped, aad)
+	c.Assert(err, qt.IsNil)
+	c.Assert(unwrapped, qt.DeepEquals, secret)
+
+	// The wrapped secret is bound to the additional data
+	_, err = kw.Unwrap(wrapped, []byte("other public key"))
+	c.Assert(err, qt.ErrorIs, ErrUnwrap)
+
+	// Tampered secrets are rejected
+	wrapped[len(wrapped)-1] ^= 0xff
+	_, err = kw.Unwrap(wrapped, aad)
+	c.Assert(err, qt.ErrorIs, ErrUnwrap)
+	wrapped[len(wrapped)-1] ^= 0xff
+
+	// Reloading the key file with the same passphrase derives the same key
+	reloaded, err := NewLocalKeyWrapper(path, "passphrase")
+	c.Assert(err, qt.IsNil)
+	c.Assert(reloaded.ID(), qt.Equals, kw.ID())
+	unwrapped, err = reloaded.Unwrap(wrapped, aad)
+	c.Assert(err, qt.IsNil)
+	c.Assert(unwrapped, qt.DeepEquals, secret)
+
+	// A wrong passphrase is detected when loading the key file
+	_, err = NewLocalKeyWrapper(path, "wrong passphrase")
+	c.Assert(err, qt.ErrorIs, ErrInvalidPassphrase)
+
+	// A different key file derives a different key, even with the same
+	// passphrase, since the salt is random
+	other, err := NewLocalKeyWrapper(filepath.Join(t.TempDir(), "kek.json"), "passphrase")
+	c.Assert(err, qt.IsNil)
+	c.Assert(other.ID(), qt.Not(qt.Equals), kw.ID())
+	_, err = other.Unwrap(wrapped, aad)
+	c.Assert(err, qt.ErrorIs, ErrUnwrap)
+
+	_, err = NewLocalKeyWrapper(path, "")
+	c.Assert(err, qt.IsNotNil)
+}
+
+func TestPassphraseKeyWrapper(t *testing.T) {
+	c := qt.New(t)
+
+	kw, params, err := NewPassphraseKeyWrapper("passphrase")
+	c.Assert(err, qt.IsNil)
+	wrapped, err := kw.Wrap([]byte("private key"), nil)
+	c.Assert(err, qt.IsNil)
+
+	// The parameters derive the same key from the same passphrase
+	opened, err := OpenPassphraseKeyWrapper("passphrase", params)
+	c.Assert(err, qt.IsNil)
+	c.Assert(opened.ID(), qt.Equals, kw.ID())
+	unwrapped, err := opened.Unwrap(wrapped, nil)
+	c.Assert(err, qt.IsNil)
+	c.Assert(unwrapped, qt.DeepEquals, []byte("private key"))
+
+	_, err = OpenPassphraseKeyWrapper("wrong passphrase", params)
+	c.Assert(err, qt.ErrorIs, ErrInvalidPassphrase)
+	_, err = OpenPassphraseKeyWrapper("", params)
+	c.Assert(err, qt.IsNotNil)
+	_, err = OpenPassphraseKeyWrapper("passphrase", nil)
+	c.Assert(err, qt.IsNotNil)
+	_, _, err = NewPassphraseKeyWrapper("")
+	c.Assert(err, qt.IsNotNil)
+}
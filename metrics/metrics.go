@@ -0,0 +1,156 @@
+// Package metrics defines the Prometheus collectors exposed by the sequencer
+// node. Collectors are registered in a package level registry so any package
+// of the node can instrument its hot paths without wiring dependencies, and
+// the API serves the registry content through the /metrics endpoint.
+package metrics
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// namespace is the prefix used for every metric exposed by the node.
+const namespace = "davinci"
+
+// Label values used to report the outcome of an operation.
+const (
+	ResultSuccess = "success"
+	ResultFailure = "failure"
+	ResultTimeout = "timeout"
+)
+
+// proofDurationBuckets are the histogram buckets (in seconds) used for the
+// proving stages of the pipeline, which range from a few seconds for a
+// single ballot up to several minutes for aggregation on CPU.
+var proofDurationBuckets = []float64{0.5, 1, 2, 5, 10, 20, 30, 60, 120, 300, 600}
+
+// txDurationBuckets are the histogram buckets (in seconds) used to measure
+// the time it takes for a transaction to be confirmed on chain.
+var txDurationBuckets = []float64{5, 10, 15, 30, 60, 120, 300, 600, 1200}
+
+// Registry is the Prometheus registry that contains every collector of the
+// node. It also includes the default Go runtime and process collectors.
+var Registry = prometheus.NewRegistry()
+
+var (
+	// BallotVerificationDuration measures the time spent generating and
+	// verifying the vote verifier proof of a single ballot.
+	BallotVerificationDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "sequencer",
+		Name:      "ballot_verification_duration_seconds",
+		Help:      "Time spent verifying a ballot and generating its vote verifier proof.",
+		Buckets:   proofDurationBuckets,
+	}, []string{"result"})
+
+	// AggregationDuration measures the time spent building and proving an
+	// aggregator batch.
+	AggregationDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "sequencer",
+		Name:      "aggregation_duration_seconds",
+		Help:      "Time spent generating the aggregator proof of a batch of ballots.",
+		Buckets:   proofDurationBuckets,
+	}, []string{"result"})
+
+	// AggregatedBallots counts the ballots included in aggregator batches.
+	AggregatedBallots = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "sequencer",
+		Name:      "aggregated_ballots_total",
+		Help:      "Number of ballots included in successfully aggregated batches.",
+	})
+
+	// StateTransitionDuration measures the time spent generating the state
+	// transition proof of an aggregated batch.
+	StateTransitionDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "sequencer",
+		Name:      "state_transition_duration_seconds",
+		Help:      "Time spent generating the state transition proof of a batch.",
+		Buckets:   proofDurationBuckets,
+	}, []string{"result"})
+
+	// TxConfirmationDuration measures the time the transaction manager waits
+	// until a transaction is confirmed on chain.
+	TxConfirmationDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "txmanager",
+		Name:      "tx_confirmation_duration_seconds",
+		Help:      "Time spent waiting for a transaction to be confirmed on chain.",
+		Buckets:   txDurationBuckets,
+	}, []string{"chain_id", "result"})
+
+	// WorkerJobs counts the jobs completed by remote workers by outcome.
+	WorkerJobs = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "workers",
+		Name:      "jobs_total",
+		Help:      "Number of jobs completed by remote workers by outcome.",
+	}, []string{"result"})
+
+	// RPCEndpoints reports the number of web3 RPC endpoints available or
+	// disabled per chain.
+	RPCEndpoints = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "rpc",
+		Name:      "endpoints",
+		Help:      "Number of web3 RPC endpoints per chain and state (available or disabled).",
+	}, []string{"chain_id", "state"})
+
+	// RPCEndpointsDisabled counts the times a web3 RPC endpoint has been
+	// disabled because of failures.
+	RPCEndpointsDisabled = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "rpc",
+		Name:      "endpoint_disabled_total",
+		Help:      "Number of times a web3 RPC endpoint has been disabled after a failure.",
+	}, []string{"chain_id"})
+
+	// SettlementsHeld reports the number of processes whose state
+	// transitions are held because the fees are above the ceilings.
+	SettlementsHeld = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "settlement",
+		Name:      "held_processes",
+		Help:      "Number of processes whose state transitions are held by the fee ceilings.",
+	})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		BallotVerificationDuration,
+		AggregationDuration,
+		AggregatedBallots,
+		StateTransitionDuration,
+		TxConfirmationDuration,
+		WorkerJobs,
+		RPCEndpoints,
+		RPCEndpointsDisabled,
+		SettlementsHeld,
+		queues,
+	)
+}
+
+// Handler returns the HTTP handler that serves the metrics of the registry
+// in the Prometheus exposition format.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{})
+}
+
+// ObserveSince records the seconds elapsed since the start time in the
+// histogram identified by the provided label values. The result label is
+// derived from the error: ResultSuccess if nil, ResultFailure otherwise.
+func ObserveSince(h *prometheus.HistogramVec, start time.Time, err error, labels ...string) {
+	result := ResultSuccess
+	if err != nil {
+		result = ResultFailure
+	}
+	h.WithLabelValues(append(labels, result)...).Observe(time.Since(start).Seconds())
+}
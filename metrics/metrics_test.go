@@ -0,0 +1,82 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/vocdoni/davinci-node/types"
+)
+
+type testQueueSource struct {
+	pending  int
+	verified map[types.ProcessID]int
+}
+
+func (s *testQueueSource) CountPendingBallots() int { return s.pending }
+
+func (s *testQueueSource) CountVerifiedBallotsByProcess() map[types.ProcessID]int {
+	return s.verified
+}
+
+func scrape(c *qt.C) string {
+	srv := httptest.NewServer(Handler())
+	defer srv.Close()
+	resp, err := http.Get(srv.URL)
+	c.Assert(err, qt.IsNil)
+	defer func() { _ = resp.Body.Close() }()
+	c.Assert(resp.StatusCode, qt.Equals, http.StatusOK)
+	body, err := io.ReadAll(resp.Body)
+	c.Assert(err, qt.IsNil)
+	return string(body)
+}
+
+func TestQueueMetrics(t *testing.T) {
+	c := qt.New(t)
+
+	pid := types.ProcessID{0x01, 0x02}
+	SetQueueSource(&testQueueSource{
+		pending:  3,
+		verified: map[types.ProcessID]int{pid: 7},
+	})
+	defer SetQueueSource(nil)
+
+	body := scrape(c)
+	c.Assert(body, qt.Contains, "davinci_sequencer_pending_ballots 3")
+	c.Assert(body, qt.Contains, fmt.Sprintf("davinci_sequencer_verified_ballots{process_id=%q} 7", pid.String()))
+
+	// Only the deepest queues keep their own label, the rest are aggregated
+	verified := make(map[types.ProcessID]int)
+	for i := range maxVerifiedProcessLabels + 2 {
+		verified[types.ProcessID{byte(i + 1)}] = i + 1
+	}
+	SetQueueSource(&testQueueSource{verified: verified})
+	body = scrape(c)
+	c.Assert(strings.Count(body, "davinci_sequencer_verified_ballots{"), qt.Equals, maxVerifiedProcessLabels+1)
+	c.Assert(body, qt.Contains, fmt.Sprintf("davinci_sequencer_verified_ballots{process_id=%q} %d",
+		types.ProcessID{byte(maxVerifiedProcessLabels + 2)}.String(), maxVerifiedProcessLabels+2))
+	c.Assert(body, qt.Contains, fmt.Sprintf("davinci_sequencer_verified_ballots{process_id=%q} 3", otherProcessesLabel))
+
+	// Without source the queue metrics are not reported
+	SetQueueSource(nil)
+	body = scrape(c)
+	c.Assert(strings.Contains(body, "davinci_sequencer_pending_ballots"), qt.IsFalse)
+}
+
+func TestObserveSince(t *testing.T) {
+	c := qt.New(t)
+
+	ObserveSince(AggregationDuration, time.Now(), nil)
+	ObserveSince(AggregationDuration, time.Now(), fmt.Errorf("proving failed"))
+	ObserveSince(TxConfirmationDuration, time.Now(), nil, "1337")
+
+	body := scrape(c)
+	c.Assert(body, qt.Contains, `davinci_sequencer_aggregation_duration_seconds_count{result="success"} 1`)
+	c.Assert(body, qt.Contains, `davinci_sequencer_aggregation_duration_seconds_count{result="failure"} 1`)
+	c.Assert(body, qt.Contains, `davinci_txmanager_tx_confirmation_duration_seconds_count{chain_id="1337",result="success"} 1`)
+}
@@ -0,0 +1,103 @@
+package metrics
+
+import (
+	"bytes"
+	"slices"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/vocdoni/davinci-node/types"
+)
+
+// QueueSource is the subset of the storage methods required to report the
+// depth of the sequencer queues. It is implemented by *storage.Storage.
+type QueueSource interface {
+	CountPendingBallots() int
+	CountVerifiedBallotsByProcess() map[types.ProcessID]int
+}
+
+// maxVerifiedProcessLabels is the maximum number of processes reported with
+// their own process_id label in the verified ballots gauge. The ballots of
+// the remaining processes are aggregated under otherProcessesLabel.
+const maxVerifiedProcessLabels = 20
+
+// otherProcessesLabel is the process_id label value used to aggregate the
+// verified ballots of the processes beyond maxVerifiedProcessLabels.
+const otherProcessesLabel = "other"
+
+// queueCollector is a Prometheus collector that reads the depth of the
+// pending and verified ballot queues from the configured source on every
+// scrape.
+type queueCollector struct {
+	mtx      sync.RWMutex
+	src      QueueSource
+	pending  *prometheus.Desc
+	verified *prometheus.Desc
+}
+
+// queues is the collector registered in the package registry. Its source is
+// set through SetQueueSource.
+var queues = &queueCollector{
+	pending: prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "sequencer", "pending_ballots"),
+		"Number of pending ballots waiting to be verified.",
+		nil, nil,
+	),
+	verified: prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "sequencer", "verified_ballots"),
+		"Number of verified ballots waiting to be aggregated per process, limited to the processes with the deepest queues.",
+		[]string{"process_id"}, nil,
+	),
+}
+
+// SetQueueSource sets the source used to report the queue depths. Calling it
+// again replaces the previous source, and a nil source disables the queue
+// metrics.
+func SetQueueSource(src QueueSource) {
+	queues.mtx.Lock()
+	defer queues.mtx.Unlock()
+	queues.src = src
+}
+
+// Describe implements prometheus.Collector.
+func (c *queueCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.pending
+	ch <- c.verified
+}
+
+// Collect implements prometheus.Collector.
+func (c *queueCollector) Collect(ch chan<- prometheus.Metric) {
+	c.mtx.RLock()
+	src := c.src
+	c.mtx.RUnlock()
+	if src == nil {
+		return
+	}
+	ch <- prometheus.MustNewConstMetric(c.pending, prometheus.GaugeValue, float64(src.CountPendingBallots()))
+	counts := src.CountVerifiedBallotsByProcess()
+	processIDs := make([]types.ProcessID, 0, len(counts))
+	for processID := range counts {
+		processIDs = append(processIDs, processID)
+	}
+	// report the deepest queues first, sorted by processID on ties to keep
+	// the reported label set stable between scrapes
+	slices.SortFunc(processIDs, func(a, b types.ProcessID) int {
+		if counts[a] != counts[b] {
+			return counts[b] - counts[a]
+		}
+		return bytes.Compare(a[:], b[:])
+	})
+	others := 0
+	for i, processID := range processIDs {
+		if i >= maxVerifiedProcessLabels {
+			others += counts[processID]
+			continue
+		}
+		ch <- prometheus.MustNewConstMetric(c.verified, prometheus.GaugeValue,
+			float64(counts[processID]), processID.String())
+	}
+	if others > 0 {
+		ch <- prometheus.MustNewConstMetric(c.verified, prometheus.GaugeValue,
+			float64(others), otherProcessesLabel)
+	}
+}
@@ -1,8 +1,10 @@
 package sequencer
 
 import (
+	"cmp"
 	"fmt"
 	"math/big"
+	"slices"
 	"time"
 
 	"github.com/consensys/gnark/backend/groth16"
@@ -13,10 +15,13 @@ import (
 	stdgroth16 "github.com/consensys/gnark/std/recursion/groth16"
 	"github.com/vocdoni/davinci-node/circuits/aggregator"
 	"github.com/vocdoni/davinci-node/circuits/voteverifier"
+	"github.com/vocdoni/davinci-node/crypto/csp"
 	"github.com/vocdoni/davinci-node/log"
+	"github.com/vocdoni/davinci-node/metrics"
 	"github.com/vocdoni/davinci-node/spec/params"
 	"github.com/vocdoni/davinci-node/storage"
 	"github.com/vocdoni/davinci-node/types"
+	"github.com/vocdoni/davinci-node/workers"
 )
 
 type aggregationProcessState interface {
@@ -40,6 +45,7 @@ func collectAggregationBatchInputs(
 	ballots []*storage.VerifiedBallot,
 	keys [][]byte,
 	processState aggregationProcessState,
+	census *types.Census,
 	maxVotersReached bool,
 	proofToRecursion proofToRecursionFn,
 	verifyVoteVerifierProof voteVerifierProofValidatorFn,
@@ -178,6 +184,40 @@ func collectAggregationBatchInputs(
 			continue
 		}
 
+		// If the census is a CSP one, the ballot must be signed by the
+		// current CSP key, since the state transition can only prove the
+		// census proofs against the current census root. The ballots signed
+		// by a rotated key are failed, so the voter can vote again with a
+		// proof signed by the new key.
+		if census != nil && census.CensusOrigin.IsCSP() {
+			if err := csp.VerifyCensusRoot(b.CensusProof, census.CensusRoot); err != nil {
+				log.Warnw("skipping verified ballot signed by a rotated CSP key",
+					"error", err.Error(),
+					"processID", processID.String(),
+					"voteID", b.VoteID.String(),
+					"address", types.HexBytes(b.Address.Bytes()),
+					"censusRoot", census.CensusRoot.String(),
+				)
+				if err := stg.MarkVerifiedBallotsFailed(keys[i]); err != nil {
+					log.Warnw("failed to mark ballot as failed",
+						"error", err.Error(),
+						"processID", processID.String(),
+						"voteID", b.VoteID.String(),
+						"address", types.HexBytes(b.Address.Bytes()),
+					)
+					if err := stg.ReleaseVerifiedBallotReservations([][]byte{keys[i]}); err != nil {
+						log.Warnw("failed to release ballot reservation after failure marking",
+							"error", err.Error(),
+							"processID", processID.String(),
+							"voteID", b.VoteID.String(),
+							"address", types.HexBytes(b.Address.Bytes()),
+						)
+					}
+				}
+				continue
+			}
+		}
+
 		if b.Proof == nil {
 			log.Warnw("skipping verified ballot with missing vote verifier proof",
 				"processID", processID.String(),
@@ -356,9 +396,10 @@ func collectAggregationBatchInputs(
 
 // startAggregateProcessor starts a background goroutine that periodically checks
 // for batches of verified ballots that are ready to be aggregated into a single proof.
-// A batch is considered ready when either:
+// The batch policy of each process decides when its batch is ready, by default
+// when either:
 // 1. It contains at least VotesPerBatch ballots, or
-// 2. The time since the last update exceeds maxTimeWindow
+// 2. The time since the first pending ballot exceeds the batch time window
 //
 // The processor runs until the sequencer's context is canceled.
 func (s *Sequencer) startAggregateProcessor(tickerInterval time.Duration) error {
@@ -381,11 +422,24 @@ func (s *Sequencer) startAggregateProcessor(tickerInterval time.Duration) error
 	return nil
 }
 
+// pendingBatch is a batch of a process that is ready to be aggregated.
+type pendingBatch struct {
+	processID types.ProcessID
+	priority  time.Duration
+}
+
 // processPendingBatches checks all registered process IDs and aggregates
-// any batches that are ready for processing.
+// the batches that their batch policy considers ready, starting with the
+// ones with the highest priority.
 func (s *Sequencer) processPendingBatches() {
+	var ready []pendingBatch
+	now := time.Now()
 	// Process each registered process ID
 	s.processIDs.ForEach(func(processID types.ProcessID, lastUpdate time.Time) bool {
+		if s.processIDs.IsPaused(processID) {
+			log.Debugw("process paused", "processID", processID.String())
+			return true // Continue to next process ID
+		}
 		// Check if this batch is ready for processing
 		ballotCount := s.stg.CountVerifiedBallots(processID)
 
@@ -394,29 +448,42 @@ func (s *Sequencer) processPendingBatches() {
 			return true // Continue to next process ID
 		}
 
-		// If we have enough ballots for a full batch, process it regardless of time
-		if ballotCount >= params.VotesPerBatch {
-			return s.processAndUpdateBatch(processID)
+		// The time window starts when the first pending ballot is seen
+		s.processIDs.SetFirstBallotTime(processID)
+		firstBallotTime, _ := s.processIDs.GetFirstBallotTime(processID)
+
+		state := &BatchState{
+			ProcessID:      processID,
+			PendingBallots: ballotCount,
+			FirstBallot:    firstBallotTime,
+			Now:            now,
+			fees: func() (*BatchFees, error) {
+				return s.batchFees(processID)
+			},
 		}
-
-		// Otherwise, check if we have a first ballot timestamp and if enough time has passed
-		firstBallotTime, hasFirstBallot := s.processIDs.GetFirstBallotTime(processID)
-
-		// If we don't have a first ballot timestamp yet, set it now
-		if !hasFirstBallot {
-			s.processIDs.SetFirstBallotTime(processID)
-			return true // Continue to next process ID
+		if process, err := s.stg.Process(processID); err == nil && !process.StartTime.IsZero() {
+			state.ProcessEnd = process.StartTime.Add(process.Duration)
 		}
-
-		// Check if enough time has passed since the first ballot
-		timeSinceFirstBallot := time.Since(firstBallotTime)
-		if timeSinceFirstBallot <= s.batchTimeWindow {
-			return true // Continue to next process ID
+		policy := s.batchPolicy(processID)
+		if policy.Ready(state) {
+			ready = append(ready, pendingBatch{
+				processID: processID,
+				priority:  policy.Priority(state),
+			})
 		}
+		return true
+	})
 
-		// If we're here, we have some ballots and the time window has elapsed
-		return s.processAndUpdateBatch(processID)
+	// Aggregate the ready batches with the highest priority first
+	slices.SortStableFunc(ready, func(a, b pendingBatch) int {
+		return cmp.Compare(b.priority, a.priority)
 	})
+	for _, batch := range ready {
+		if s.ctx.Err() != nil {
+			return
+		}
+		s.processAndUpdateBatch(batch.processID)
+	}
 }
 
 // processAndUpdateBatch handles the processing of a batch of ballots and updates
@@ -467,6 +534,12 @@ func (s *Sequencer) aggregateBatch(processID types.ProcessID) error {
 		return fmt.Errorf("failed to check if process max voters reached: %w", err)
 	}
 
+	// Get the process census to check the census proofs of the ballots
+	process, err := s.stg.Process(processID)
+	if err != nil {
+		return fmt.Errorf("failed to get process: %w", err)
+	}
+
 	// Pull verified ballots from storage
 	ballots, keys, err := s.stg.PullVerifiedBallots(processID, params.VotesPerBatch*2) // Pull up to double the batch size, to handle skips
 	if err != nil {
@@ -524,6 +597,7 @@ func (s *Sequencer) aggregateBatch(processID types.ProcessID) error {
 		ballots,
 		keys,
 		processState,
+		process.Census,
 		maxVotersReached,
 		proofToRecursion,
 		verifyVoteVerifierProof,
@@ -575,8 +649,10 @@ func (s *Sequencer) aggregateBatch(processID types.ProcessID) error {
 	log.DebugTime("inputs ready for aggregation", startTime)
 	startTime = time.Now()
 
-	// Generate the proof for the aggregator circuit
-	proof, err := s.aggregator.ProveAndVerify(assignment)
+	// Generate the proof for the aggregator circuit, offloaded to a worker if
+	// there is one available
+	proof, err := s.prove(workers.JobTypeAggregateBatch, processID, s.aggregator, assignment)
+	metrics.ObserveSince(metrics.AggregationDuration, startTime, err)
 	if err != nil {
 		// Log detailed debug information about the failure
 		// Remove block once we have sufficient confidence in the aggregator proving
@@ -664,5 +740,6 @@ func (s *Sequencer) aggregateBatch(processID types.ProcessID) error {
 		}
 		return fmt.Errorf("failed to mark verified ballots as done: %w", err)
 	}
+	metrics.AggregatedBallots.Add(float64(len(batchInputs.AggBallots)))
 	return nil
 }
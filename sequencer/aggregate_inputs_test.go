@@ -8,7 +8,9 @@ import (
 	groth16_bls12377 "github.com/consensys/gnark/backend/groth16/bls12-377"
 	"github.com/consensys/gnark/std/algebra/native/sw_bls12377"
 	stdgroth16 "github.com/consensys/gnark/std/recursion/groth16"
+	"github.com/ethereum/go-ethereum/common"
 	qt "github.com/frankban/quicktest"
+	"github.com/vocdoni/davinci-node/crypto/csp"
 	"github.com/vocdoni/davinci-node/internal/testutil"
 	"github.com/vocdoni/davinci-node/spec/params"
 	"github.com/vocdoni/davinci-node/storage"
@@ -75,7 +77,7 @@ func TestCollectAggregationBatchInputs_SkipsDontCreateHoles(t *testing.T) {
 		return stdgroth16.Proof[sw_bls12377.G1Affine, sw_bls12377.G2Affine]{}, nil
 	}
 
-	inputs, err := collectAggregationBatchInputs(stg, processID, ballots, keys, processState, false, proofToRecursion, nil)
+	inputs, err := collectAggregationBatchInputs(stg, processID, ballots, keys, processState, nil, false, proofToRecursion, nil)
 	c.Assert(err, qt.IsNil)
 
 	c.Assert(len(inputs.AggBallots), qt.Equals, params.VotesPerBatch)
@@ -92,3 +94,59 @@ func TestCollectAggregationBatchInputs_SkipsDontCreateHoles(t *testing.T) {
 	c.Assert(inputs.ProofsInputsHashInputs[0].Cmp(ballots[1].InputsHash), qt.Equals, 0)
 	c.Assert(inputs.ProcessedKeys[len(inputs.ProcessedKeys)-1], qt.DeepEquals, keys[len(keys)-1])
 }
+
+func TestCollectAggregationBatchInputs_SkipsRotatedCSPKey(t *testing.T) {
+	c := qt.New(t)
+
+	stg := &mockAggregationStore{}
+	processState := mockAggregationState{
+		voteIDs:   make(map[string]struct{}),
+		addresses: make(map[string]struct{}),
+	}
+
+	processID := testutil.FixedProcessID()
+	oldCSP, err := csp.New(types.CensusOriginCSPEdDSABabyJubJubV1, []byte("old key"))
+	c.Assert(err, qt.IsNil)
+	newCSP, err := csp.New(types.CensusOriginCSPEdDSABabyJubJubV1, []byte("new key"))
+	c.Assert(err, qt.IsNil)
+	census := &types.Census{
+		CensusOrigin: types.CensusOriginCSPEdDSABabyJubJubV1,
+		CensusRoot:   newCSP.CensusRoot().Root,
+	}
+
+	weight := new(types.BigInt).SetUint64(testutil.Weight)
+	censusProofs := make([]*types.CensusProof, 0, 3)
+	for i, signer := range []csp.CSP{oldCSP, newCSP, oldCSP} {
+		proof, err := signer.GenerateProof(processID, common.BigToAddress(big.NewInt(int64(i+1))), weight)
+		c.Assert(err, qt.IsNil)
+		censusProofs = append(censusProofs, proof)
+	}
+	// the third proof is signed by the revoked key but claims the new root
+	censusProofs[2].Root = census.CensusRoot
+
+	ballots := make([]*storage.VerifiedBallot, 0, 3)
+	keys := make([][]byte, 0, 3)
+	for i, censusProof := range censusProofs {
+		ballots = append(ballots, &storage.VerifiedBallot{
+			VoteID:      types.VoteID(i + 1),
+			Address:     big.NewInt(int64(i + 1)),
+			Proof:       new(groth16_bls12377.Proof),
+			InputsHash:  big.NewInt(int64(1000 + i)),
+			CensusProof: censusProof,
+		})
+		keys = append(keys, []byte{0xAA, byte(i + 1)})
+	}
+
+	proofToRecursion := func(_ groth16.Proof) (stdgroth16.Proof[sw_bls12377.G1Affine, sw_bls12377.G2Affine], error) {
+		return stdgroth16.Proof[sw_bls12377.G1Affine, sw_bls12377.G2Affine]{}, nil
+	}
+
+	inputs, err := collectAggregationBatchInputs(stg, processID, ballots, keys, processState, census, false, proofToRecursion, nil)
+	c.Assert(err, qt.IsNil)
+
+	c.Assert(inputs.AggBallots, qt.HasLen, 1)
+	c.Assert(inputs.AggBallots[0].VoteID, qt.Equals, ballots[1].VoteID)
+	c.Assert(inputs.ProcessedKeys, qt.DeepEquals, [][]byte{keys[1]})
+	c.Assert(stg.failed, qt.DeepEquals, [][]byte{keys[0], keys[2]})
+	c.Assert(stg.released, qt.HasLen, 0)
+}
@@ -12,6 +12,7 @@ import (
 	ethcrypto "github.com/ethereum/go-ethereum/crypto"
 	"github.com/vocdoni/davinci-node/circuits/voteverifier"
 	"github.com/vocdoni/davinci-node/log"
+	"github.com/vocdoni/davinci-node/metrics"
 	"github.com/vocdoni/davinci-node/storage"
 	"github.com/vocdoni/davinci-node/types"
 )
@@ -91,6 +92,12 @@ func (s *Sequencer) processAvailableBallots() bool {
 			continue
 		}
 
+		// Skip processing if the process sequencing is paused
+		if s.processIDs.IsPaused(ballot.ProcessID) {
+			log.Debugw("skipping ballot, process paused", "processID", ballot.ProcessID.String())
+			continue
+		}
+
 		log.Infow("processing ballot",
 			"address", types.HexBytes(ballot.Address.Bytes()),
 			"voteID", ballot.VoteID.String(),
@@ -183,7 +190,9 @@ func (s *Sequencer) processBallot(b *storage.Ballot) (*storage.VerifiedBallot, e
 	)
 
 	log.Debugw("generating vote verification proof...", "processID", b.ProcessID.String(), "voteID", b.VoteID.String())
+	proofStartTime := time.Now()
 	proof, err := s.voteVerifier.ProveAndVerify(&assignment)
+	metrics.ObserveSince(metrics.BallotVerificationDuration, proofStartTime, err)
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate proof: %w", err)
 	}
@@ -0,0 +1,483 @@
+package sequencer
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vocdoni/davinci-node/log"
+	"github.com/vocdoni/davinci-node/spec/params"
+	"github.com/vocdoni/davinci-node/types"
+)
+
+// defaultFillMaxWaitFactor is the factor applied to the time window of a
+// fill ratio policy to get its maximum wait when it is not set, so the
+// ballots of a process that never fills the batches are still aggregated.
+const defaultFillMaxWaitFactor = 4
+
+// gwei is the number of wei in a gwei.
+var gwei = big.NewInt(1_000_000_000)
+
+// BatchFees are the current fees of the chain of a process, which a cost
+// aware policy takes into account. BlobBaseFee is nil if the chain does not
+// support blob transactions.
+type BatchFees struct {
+	GasPrice    *big.Int
+	BlobBaseFee *big.Int
+}
+
+// BatchState is the state of the pending verified ballots of a process,
+// which a BatchPolicy takes into account to decide whether they are
+// aggregated into a batch.
+type BatchState struct {
+	ProcessID      types.ProcessID
+	PendingBallots int       // Verified ballots waiting to be aggregated
+	FirstBallot    time.Time // Time at which the first pending ballot was seen
+	ProcessEnd     time.Time // End time of the process, zero if unknown
+	Now            time.Time
+
+	fees     func() (*BatchFees, error)
+	feesOnce sync.Once
+	feesVal  *BatchFees
+	feesErr  error
+}
+
+// Waiting returns the time the pending ballots have been waiting.
+func (bs *BatchState) Waiting() time.Duration {
+	return bs.Now.Sub(bs.FirstBallot)
+}
+
+// Full returns whether the pending ballots fill a batch.
+func (bs *BatchState) Full() bool {
+	return bs.PendingBallots >= params.VotesPerBatch
+}
+
+// Fees returns the current fees of the chain of the process. They are only
+// requested once, the first time they are needed.
+func (bs *BatchState) Fees() (*BatchFees, error) {
+	bs.feesOnce.Do(func() {
+		if bs.fees == nil {
+			bs.feesErr = fmt.Errorf("fees not available")
+			return
+		}
+		bs.feesVal, bs.feesErr = bs.fees()
+	})
+	return bs.feesVal, bs.feesErr
+}
+
+// BatchPolicy decides when the pending verified ballots of a process are
+// aggregated into a batch, and which batches are aggregated first when
+// several are ready. String returns the policy in the format parsed by
+// ParseBatchPolicy.
+type BatchPolicy interface {
+	// Ready returns whether the pending ballots must be aggregated now.
+	Ready(state *BatchState) bool
+	// Priority returns the priority of the batch among the ready ones,
+	// the batches with a higher priority are aggregated first.
+	Priority(state *BatchState) time.Duration
+	String() string
+}
+
+// TimeWindowPolicy aggregates the pending ballots when they fill a batch or
+// when the first of them has been waiting for the time window. The batches
+// that have been waiting longer are aggregated first, so the processes with
+// few ballots are not starved by the ones that fill batches continuously.
+type TimeWindowPolicy struct {
+	Window time.Duration
+}
+
+// Ready implements BatchPolicy.
+func (p *TimeWindowPolicy) Ready(state *BatchState) bool {
+	return state.Full() || state.Waiting() > p.Window
+}
+
+// Priority implements BatchPolicy.
+func (p *TimeWindowPolicy) Priority(state *BatchState) time.Duration {
+	return state.Waiting()
+}
+
+// String implements BatchPolicy.
+func (p *TimeWindowPolicy) String() string {
+	return "window=" + p.Window.String()
+}
+
+// FillRatioPolicy aggregates the pending ballots when they fill a batch, or
+// when the time window elapsed and they fill at least the minimum ratio of a
+// batch, so no proofs are wasted on batches that are mostly empty. Once the
+// maximum wait elapsed they are aggregated anyway.
+type FillRatioPolicy struct {
+	Window  time.Duration
+	MinFill float64 // Ratio of a batch, from 0 to 1
+	MaxWait time.Duration
+}
+
+// minBallots returns the minimum number of ballots of a batch.
+func (p *FillRatioPolicy) minBallots() int {
+	return int(math.Ceil(p.MinFill * params.VotesPerBatch))
+}
+
+// Ready implements BatchPolicy.
+func (p *FillRatioPolicy) Ready(state *BatchState) bool {
+	if state.Full() || state.Waiting() > p.MaxWait {
+		return true
+	}
+	return state.Waiting() > p.Window && state.PendingBallots >= p.minBallots()
+}
+
+// Priority implements BatchPolicy.
+func (p *FillRatioPolicy) Priority(state *BatchState) time.Duration {
+	return state.Waiting()
+}
+
+// String implements BatchPolicy.
+func (p *FillRatioPolicy) String() string {
+	return fmt.Sprintf("window=%s,minFill=%s,maxWait=%s",
+		p.Window, strconv.FormatFloat(p.MinFill, 'f', -1, 64), p.MaxWait)
+}
+
+// CostAwarePolicy delays the batches that the wrapped policy considers
+// ready while the fees of the chain are above the maximums, so they fill up
+// and fewer state transitions are settled while the fees are high. Once the
+// maximum wait elapsed, or if the fees can not be known, it does not delay
+// them. A nil maximum is not checked.
+type CostAwarePolicy struct {
+	Policy         BatchPolicy
+	MaxGasPrice    *big.Int
+	MaxBlobBaseFee *big.Int
+	MaxWait        time.Duration
+}
+
+// Ready implements BatchPolicy.
+func (p *CostAwarePolicy) Ready(state *BatchState) bool {
+	if !p.Policy.Ready(state) {
+		return false
+	}
+	if state.Waiting() > p.MaxWait {
+		return true
+	}
+	fees, err := state.Fees()
+	if err != nil {
+		log.Warnw("could not get fees for batch policy",
+			"processID", state.ProcessID.String(),
+			"error", err.Error())
+		return true
+	}
+	if p.MaxGasPrice != nil && fees.GasPrice != nil && fees.GasPrice.Cmp(p.MaxGasPrice) > 0 {
+		log.Debugw("batch delayed by gas price",
+			"processID", state.ProcessID.String(),
+			"gasPrice", fees.GasPrice.String())
+		return false
+	}
+	if p.MaxBlobBaseFee != nil && fees.BlobBaseFee != nil && fees.BlobBaseFee.Cmp(p.MaxBlobBaseFee) > 0 {
+		log.Debugw("batch delayed by blob base fee",
+			"processID", state.ProcessID.String(),
+			"blobBaseFee", fees.BlobBaseFee.String())
+		return false
+	}
+	return true
+}
+
+// Priority implements BatchPolicy.
+func (p *CostAwarePolicy) Priority(state *BatchState) time.Duration {
+	return p.Policy.Priority(state)
+}
+
+// String implements BatchPolicy.
+func (p *CostAwarePolicy) String() string {
+	s := p.Policy.String()
+	if p.MaxGasPrice != nil {
+		s += ",maxGasPrice=" + formatWei(p.MaxGasPrice)
+	}
+	if p.MaxBlobBaseFee != nil {
+		s += ",maxBlobBaseFee=" + formatWei(p.MaxBlobBaseFee)
+	}
+	return s + ",feeMaxWait=" + p.MaxWait.String()
+}
+
+// DeadlinePolicy aggregates the pending ballots as soon as the process is
+// about to end, whatever the wrapped policy decides, so they are settled
+// before the results are computed. The batches of the processes that are
+// about to end are also aggregated before the rest.
+type DeadlinePolicy struct {
+	Policy BatchPolicy
+	Margin time.Duration
+}
+
+// remaining returns the time until the process ends, and false if the end
+// of the process is unknown.
+func (p *DeadlinePolicy) remaining(state *BatchState) (time.Duration, bool) {
+	if state.ProcessEnd.IsZero() {
+		return 0, false
+	}
+	return state.ProcessEnd.Sub(state.Now), true
+}
+
+// Ready implements BatchPolicy.
+func (p *DeadlinePolicy) Ready(state *BatchState) bool {
+	if remaining, ok := p.remaining(state); ok && remaining <= p.Margin {
+		return true
+	}
+	return p.Policy.Ready(state)
+}
+
+// Priority implements BatchPolicy.
+func (p *DeadlinePolicy) Priority(state *BatchState) time.Duration {
+	priority := p.Policy.Priority(state)
+	if remaining, ok := p.remaining(state); ok && remaining <= p.Margin {
+		// the closer to the end, the higher the priority
+		priority += p.Margin - max(remaining, 0)
+	}
+	return priority
+}
+
+// String implements BatchPolicy.
+func (p *DeadlinePolicy) String() string {
+	return p.Policy.String() + ",deadline=" + p.Margin.String()
+}
+
+// ParseBatchPolicy parses a batch policy from a comma separated list of
+// key=value options:
+//
+//   - window: time the first pending ballot waits before the batch is
+//     aggregated (defaults to the window provided)
+//   - minFill: minimum ratio of a batch, from 0 to 1, filled before the
+//     window elapses
+//   - maxWait: time after which a batch is aggregated even if it does not
+//     reach the minimum fill (defaults to 4 times the window)
+//   - maxGasPrice, maxBlobBaseFee: maximum fees, in wei or with the gwei
+//     suffix, to aggregate the batches
+//   - feeMaxWait: time after which a batch is aggregated even if the fees
+//     are above the maximums (defaults to the maxWait)
+//   - deadline: time before the end of the process from which the pending
+//     ballots are aggregated right away
+//
+// An empty string returns a TimeWindowPolicy with the window provided.
+func ParseBatchPolicy(s string, window time.Duration) (BatchPolicy, error) {
+	var (
+		minFill                     float64
+		maxWait, feeMaxWait, margin time.Duration
+		maxGasPrice, maxBlobBaseFee *big.Int
+		err                         error
+	)
+	for option := range strings.SplitSeq(s, ",") {
+		option = strings.TrimSpace(option)
+		if option == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(option, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid batch policy option %q, expected key=value", option)
+		}
+		switch key {
+		case "window":
+			window, err = parsePositiveDuration(key, value)
+		case "minFill":
+			minFill, err = strconv.ParseFloat(value, 64)
+			if err == nil && (minFill <= 0 || minFill > 1) {
+				err = fmt.Errorf("minFill must be between 0 and 1, got %s", value)
+			}
+		case "maxWait":
+			maxWait, err = parsePositiveDuration(key, value)
+		case "maxGasPrice":
+			maxGasPrice, err = ParseWei(value)
+		case "maxBlobBaseFee":
+			maxBlobBaseFee, err = ParseWei(value)
+		case "feeMaxWait":
+			feeMaxWait, err = parsePositiveDuration(key, value)
+		case "deadline":
+			margin, err = parsePositiveDuration(key, value)
+		default:
+			err = fmt.Errorf("unknown batch policy option %q", key)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	if window <= 0 {
+		return nil, fmt.Errorf("batch policy window must be positive")
+	}
+	if maxWait == 0 {
+		maxWait = window * defaultFillMaxWaitFactor
+	}
+	if maxWait < window {
+		return nil, fmt.Errorf("batch policy maxWait %s is shorter than the window %s", maxWait, window)
+	}
+
+	var policy BatchPolicy = &TimeWindowPolicy{Window: window}
+	if minFill > 0 {
+		policy = &FillRatioPolicy{Window: window, MinFill: minFill, MaxWait: maxWait}
+	}
+	if maxGasPrice != nil || maxBlobBaseFee != nil {
+		if feeMaxWait == 0 {
+			feeMaxWait = maxWait
+		}
+		policy = &CostAwarePolicy{
+			Policy:         policy,
+			MaxGasPrice:    maxGasPrice,
+			MaxBlobBaseFee: maxBlobBaseFee,
+			MaxWait:        feeMaxWait,
+		}
+	}
+	if margin > 0 {
+		policy = &DeadlinePolicy{Policy: policy, Margin: margin}
+	}
+	return policy, nil
+}
+
+// parsePositiveDuration parses the duration of the batch policy option
+// provided, which must be positive.
+func parsePositiveDuration(key, value string) (time.Duration, error) {
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s: %w", key, err)
+	}
+	if d <= 0 {
+		return 0, fmt.Errorf("%s must be positive, got %s", key, value)
+	}
+	return d, nil
+}
+
+// ParseWei parses an amount in wei, or in gwei if it has the gwei suffix.
+func ParseWei(value string) (*big.Int, error) {
+	unit := big.NewInt(1)
+	if amount, ok := strings.CutSuffix(value, "gwei"); ok {
+		value, unit = amount, gwei
+	}
+	wei, ok := new(big.Int).SetString(value, 10)
+	if !ok || wei.Sign() < 0 {
+		return nil, fmt.Errorf("invalid fee %q", value)
+	}
+	return wei.Mul(wei, unit), nil
+}
+
+// formatWei formats an amount in wei as parsed by ParseWei, in gwei if it
+// is a whole number of gwei.
+func formatWei(wei *big.Int) string {
+	if gweis, rem := new(big.Int).QuoRem(wei, gwei, new(big.Int)); rem.Sign() == 0 && gweis.Sign() > 0 {
+		return gweis.String() + "gwei"
+	}
+	return wei.String()
+}
+
+// SetDefaultBatchPolicy sets the batch policy of the processes without
+// their own policy. By default, the batches are aggregated when they are
+// full or when the batch time window of the sequencer elapses.
+func (s *Sequencer) SetDefaultBatchPolicy(policy BatchPolicy) {
+	s.batchPoliciesMu.Lock()
+	defer s.batchPoliciesMu.Unlock()
+	s.defaultBatchPolicy = policy
+}
+
+// SetBatchPolicy sets the batch policy of a process. A nil policy restores
+// the default one.
+func (s *Sequencer) SetBatchPolicy(processID types.ProcessID, policy BatchPolicy) {
+	s.batchPoliciesMu.Lock()
+	defer s.batchPoliciesMu.Unlock()
+	if policy == nil {
+		delete(s.batchPolicies, processID)
+		return
+	}
+	s.batchPolicies[processID] = policy
+	log.Infow("process batch policy set", "processID", processID.String(), "policy", policy.String())
+}
+
+// batchPolicy returns the batch policy of a process.
+func (s *Sequencer) batchPolicy(processID types.ProcessID) BatchPolicy {
+	s.batchPoliciesMu.RLock()
+	defer s.batchPoliciesMu.RUnlock()
+	if policy, ok := s.batchPolicies[processID]; ok {
+		return policy
+	}
+	if s.defaultBatchPolicy != nil {
+		return s.defaultBatchPolicy
+	}
+	return &TimeWindowPolicy{Window: s.batchTimeWindow}
+}
+
+// SetProcessBatchPolicy parses the batch policy provided, in the format of
+// ParseBatchPolicy, and sets it as the batch policy of a registered
+// process. The window of the policy defaults to the batch time window of
+// the sequencer, and an empty policy restores the default one. The policy
+// is stored with the process, so it is restored when the sequencer starts.
+func (s *Sequencer) SetProcessBatchPolicy(processID types.ProcessID, policy string) error {
+	if !s.processIDs.Exists(processID) {
+		return fmt.Errorf("process %s not registered", processID.String())
+	}
+	var batchPolicy BatchPolicy
+	if strings.TrimSpace(policy) != "" {
+		var err error
+		if batchPolicy, err = ParseBatchPolicy(policy, s.batchTimeWindow); err != nil {
+			return err
+		}
+	}
+	if err := s.stg.UpdateProcess(processID, func(p *types.Process) error {
+		p.BatchPolicy = ""
+		if batchPolicy != nil {
+			p.BatchPolicy = batchPolicy.String()
+		}
+		return nil
+	}); err != nil {
+		return fmt.Errorf("could not store batch policy: %w", err)
+	}
+	s.SetBatchPolicy(processID, batchPolicy)
+	return nil
+}
+
+// loadBatchPolicies sets the batch policies stored with the processes,
+// which are lost when the sequencer stops.
+func (s *Sequencer) loadBatchPolicies() {
+	processIDs, err := s.stg.ListProcesses()
+	if err != nil {
+		log.Warnw("failed to list processes to load their batch policies", "error", err.Error())
+		return
+	}
+	for _, processID := range processIDs {
+		process, err := s.stg.Process(processID)
+		if err != nil || process.BatchPolicy == "" {
+			continue
+		}
+		policy, err := ParseBatchPolicy(process.BatchPolicy, s.batchTimeWindow)
+		if err != nil {
+			log.Warnw("invalid stored batch policy",
+				"processID", processID.String(),
+				"policy", process.BatchPolicy,
+				"error", err.Error())
+			continue
+		}
+		s.SetBatchPolicy(processID, policy)
+	}
+}
+
+// ProcessBatchPolicy returns the batch policy of a process, in the format
+// of ParseBatchPolicy. Returns false if the process is not registered.
+func (s *Sequencer) ProcessBatchPolicy(processID types.ProcessID) (string, bool) {
+	if !s.processIDs.Exists(processID) {
+		return "", false
+	}
+	return s.batchPolicy(processID).String(), true
+}
+
+// batchFees returns the current fees of the chain of a process.
+func (s *Sequencer) batchFees(processID types.ProcessID) (*BatchFees, error) {
+	contracts, err := s.contractsForProcess(processID)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(s.ctx, 10*time.Second)
+	defer cancel()
+	fees := &BatchFees{}
+	if fees.GasPrice, err = contracts.Client().SuggestGasPrice(ctx); err != nil {
+		return nil, fmt.Errorf("get gas price: %w", err)
+	}
+	if contracts.SupportBlobTxs() {
+		if fees.BlobBaseFee, err = contracts.Client().BlobBaseFee(ctx); err != nil {
+			return nil, fmt.Errorf("get blob base fee: %w", err)
+		}
+	}
+	return fees, nil
+}
@@ -0,0 +1,135 @@
+package sequencer
+
+import (
+	"fmt"
+	"math/big"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/vocdoni/davinci-node/internal/testutil"
+	"github.com/vocdoni/davinci-node/spec/params"
+	"github.com/vocdoni/davinci-node/types"
+)
+
+// batchStateForTest returns the state of a batch with the pending ballots
+// provided, waiting for the time provided, and with the gas price provided.
+func batchStateForTest(pending int, waiting time.Duration, gasPrice int64) *BatchState {
+	now := time.Now()
+	return &BatchState{
+		ProcessID:      testutil.RandomProcessID(),
+		PendingBallots: pending,
+		FirstBallot:    now.Add(-waiting),
+		Now:            now,
+		fees: func() (*BatchFees, error) {
+			return &BatchFees{GasPrice: big.NewInt(gasPrice)}, nil
+		},
+	}
+}
+
+func TestParseBatchPolicy(t *testing.T) {
+	c := qt.New(t)
+
+	policy, err := ParseBatchPolicy("", time.Minute)
+	c.Assert(err, qt.IsNil)
+	c.Assert(policy, qt.DeepEquals, BatchPolicy(&TimeWindowPolicy{Window: time.Minute}))
+
+	policy, err = ParseBatchPolicy("minFill=0.5, maxGasPrice=30gwei, deadline=10m", time.Minute)
+	c.Assert(err, qt.IsNil)
+	c.Assert(policy.String(), qt.Equals,
+		"window=1m0s,minFill=0.5,maxWait=4m0s,maxGasPrice=30gwei,feeMaxWait=4m0s,deadline=10m0s")
+	deadline, ok := policy.(*DeadlinePolicy)
+	c.Assert(ok, qt.IsTrue)
+	cost, ok := deadline.Policy.(*CostAwarePolicy)
+	c.Assert(ok, qt.IsTrue)
+	c.Assert(cost.MaxGasPrice.Cmp(big.NewInt(30_000_000_000)), qt.Equals, 0)
+	c.Assert(cost.Policy, qt.DeepEquals, BatchPolicy(&FillRatioPolicy{
+		Window:  time.Minute,
+		MinFill: 0.5,
+		MaxWait: 4 * time.Minute,
+	}))
+
+	// the string of a policy is parsed back to the same policy
+	parsed, err := ParseBatchPolicy(policy.String(), time.Hour)
+	c.Assert(err, qt.IsNil)
+	c.Assert(parsed.String(), qt.Equals, policy.String())
+
+	for _, invalid := range []string{
+		"window",
+		"window=-1m",
+		"minFill=1.5",
+		"maxWait=30s",
+		"maxGasPrice=cheap",
+		"unknown=1",
+	} {
+		_, err = ParseBatchPolicy(invalid, time.Minute)
+		c.Assert(err, qt.IsNotNil, qt.Commentf("policy %q", invalid))
+	}
+}
+
+func TestBatchPolicies(t *testing.T) {
+	c := qt.New(t)
+	half := params.VotesPerBatch / 2
+
+	window := &TimeWindowPolicy{Window: time.Minute}
+	c.Assert(window.Ready(batchStateForTest(1, 30*time.Second, 0)), qt.IsFalse)
+	c.Assert(window.Ready(batchStateForTest(1, 2*time.Minute, 0)), qt.IsTrue)
+	c.Assert(window.Ready(batchStateForTest(params.VotesPerBatch, 0, 0)), qt.IsTrue)
+
+	fill := &FillRatioPolicy{Window: time.Minute, MinFill: 0.5, MaxWait: 10 * time.Minute}
+	c.Assert(fill.Ready(batchStateForTest(half-1, 2*time.Minute, 0)), qt.IsFalse)
+	c.Assert(fill.Ready(batchStateForTest(half, 2*time.Minute, 0)), qt.IsTrue)
+	c.Assert(fill.Ready(batchStateForTest(1, 11*time.Minute, 0)), qt.IsTrue)
+
+	cost := &CostAwarePolicy{Policy: window, MaxGasPrice: big.NewInt(100), MaxWait: 10 * time.Minute}
+	c.Assert(cost.Ready(batchStateForTest(1, 2*time.Minute, 50)), qt.IsTrue)
+	c.Assert(cost.Ready(batchStateForTest(1, 2*time.Minute, 200)), qt.IsFalse)
+	c.Assert(cost.Ready(batchStateForTest(1, 11*time.Minute, 200)), qt.IsTrue)
+	// without fees the batches are not delayed
+	noFees := batchStateForTest(1, 2*time.Minute, 200)
+	noFees.fees = func() (*BatchFees, error) { return nil, fmt.Errorf("no rpc") }
+	c.Assert(cost.Ready(noFees), qt.IsTrue)
+
+	deadline := &DeadlinePolicy{Policy: window, Margin: 10 * time.Minute}
+	ending := batchStateForTest(1, 0, 0)
+	ending.ProcessEnd = ending.Now.Add(5 * time.Minute)
+	c.Assert(window.Ready(ending), qt.IsFalse)
+	c.Assert(deadline.Ready(ending), qt.IsTrue)
+	// the processes about to end have priority over the ones waiting longer
+	waiting := batchStateForTest(1, 2*time.Minute, 0)
+	c.Assert(deadline.Priority(ending) > deadline.Priority(waiting), qt.IsTrue)
+}
+
+func TestBatchPolicySurvivesRestart(t *testing.T) {
+	c := qt.New(t)
+	pid := testutil.RandomProcessID()
+	stg, seq := newTestSequencer(t, createReadyProcess(t, pid))
+	seq.batchTimeWindow = time.Minute
+	seq.batchPolicies = make(map[types.ProcessID]BatchPolicy)
+	seq.AddProcessID(pid)
+
+	c.Assert(seq.SetProcessBatchPolicy(pid, "minFill=0.5,deadline=10m"), qt.IsNil)
+	policy, _ := seq.ProcessBatchPolicy(pid)
+
+	// restart creates a new sequencer over the same storage
+	restart := func() *Sequencer {
+		restarted := &Sequencer{
+			stg:             stg,
+			processIDs:      NewProcessIDMap(),
+			batchTimeWindow: time.Minute,
+			batchPolicies:   make(map[types.ProcessID]BatchPolicy),
+		}
+		restarted.loadBatchPolicies()
+		restarted.AddProcessID(pid)
+		return restarted
+	}
+	restarted := restart()
+	restored, registered := restarted.ProcessBatchPolicy(pid)
+	c.Assert(registered, qt.IsTrue)
+	c.Assert(restored, qt.Equals, policy)
+
+	// the default policy is restored as well
+	c.Assert(restarted.SetProcessBatchPolicy(pid, ""), qt.IsNil)
+	restored, _ = restart().ProcessBatchPolicy(pid)
+	c.Assert(restored, qt.Equals, (&TimeWindowPolicy{Window: time.Minute}).String())
+}
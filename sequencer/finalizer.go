@@ -109,7 +109,8 @@ func (f *finalizer) Start(ctx context.Context, monitorInterval time.Duration) {
 					// Check if process is marked as invalid (thread-safe)
 					if _, isInvalid := f.invalidProcesses.Load(processID); !isInvalid {
 						if err := f.finalize(processID); err != nil {
-							if errors.Is(err, ErrProcessEncryptionKeysMissing) {
+							if errors.Is(err, ErrProcessEncryptionKeysMissing) ||
+								errors.Is(err, storage.ErrThresholdDecryptionPending) {
 								log.Infow(err.Error(), "processID", processID.String())
 								return
 							}
@@ -320,15 +321,24 @@ func (f *finalizer) finalize(processID types.ProcessID) error {
 		log.Debugw("state root verified against contract", "processID", processID.String(), "stateRoot", process.StateRoot.String())
 	}
 
-	// Fetch the encryption key
+	// Fetch the encryption key. If there is no private key, but the public
+	// key was generated by a DKG session, the results are decrypted by the
+	// trustees of the session.
 	encryptionPubKey, encryptionPrivKey, err := f.stg.ProcessEncryptionKeys(processID)
-	if err != nil || encryptionPubKey == nil || encryptionPrivKey == nil {
+	if err != nil || encryptionPubKey == nil {
 		setProcessInvalid()
 		if err != nil {
 			return fmt.Errorf("process %s: %w: %w", processID.String(), ErrProcessEncryptionKeysMissing, err)
 		}
 		return fmt.Errorf("process %s: %w", processID.String(), ErrProcessEncryptionKeysMissing)
 	}
+	var dkgSession *types.DKGSession
+	if encryptionPrivKey == nil {
+		if dkgSession, err = f.stg.DKGSessionByPublicKey(encryptionPubKey); err != nil {
+			setProcessInvalid()
+			return fmt.Errorf("process %s: %w: %w", processID.String(), ErrProcessEncryptionKeysMissing, err)
+		}
+	}
 
 	// Ensure the state root matches the process state root
 	stateRoot, err := st.RootAsBigInt()
@@ -354,23 +364,36 @@ func (f *finalizer) finalize(processID types.ProcessID) error {
 	resultsAccumulator := [params.FieldsPerBallot]*big.Int{}
 	accumulatorsEncrypted := [params.FieldsPerBallot]elgamal.Ciphertext{}
 	decryptionProofs := [params.FieldsPerBallot]*elgamal.DecryptionProof{}
-	maxResult := maxPossibleResult(process)
-	for i, ct := range encryptedResultsAccumulator.Ciphertexts {
-		if ct.C1 == nil || ct.C2 == nil {
-			setProcessInvalid()
-			return fmt.Errorf("invalid ciphertext for process %s: %v", processID.String(), ct)
-		}
-		_, result, err := elgamal.Decrypt(encryptionPubKey, encryptionPrivKey, ct.C1, ct.C2, maxResult)
+	if dkgSession != nil {
+		resultsAccumulator, decryptionProofs, err = f.thresholdDecrypt(process, dkgSession, encryptionPubKey, encryptedResultsAccumulator)
 		if err != nil {
-			setProcessInvalid()
-			return fmt.Errorf("could not decrypt results accumulator for process %s: %w", processID.String(), err)
+			if !errors.Is(err, storage.ErrThresholdDecryptionPending) {
+				setProcessInvalid()
+			}
+			return err
 		}
-		resultsAccumulator[i] = result
-		accumulatorsEncrypted[i] = *ct
-		decryptionProofs[i], err = elgamal.BuildDecryptionProof(encryptionPrivKey, encryptionPubKey, ct.C1, ct.C2, result)
-		if err != nil {
-			setProcessInvalid()
-			return fmt.Errorf("could not build decryption proof for results accumulator for process %s: %w", processID.String(), err)
+		for i, ct := range encryptedResultsAccumulator.Ciphertexts {
+			accumulatorsEncrypted[i] = *ct
+		}
+	} else {
+		maxResult := maxPossibleResult(process)
+		for i, ct := range encryptedResultsAccumulator.Ciphertexts {
+			if ct.C1 == nil || ct.C2 == nil {
+				setProcessInvalid()
+				return fmt.Errorf("invalid ciphertext for process %s: %v", processID.String(), ct)
+			}
+			_, result, err := elgamal.Decrypt(encryptionPubKey, encryptionPrivKey, ct.C1, ct.C2, maxResult)
+			if err != nil {
+				setProcessInvalid()
+				return fmt.Errorf("could not decrypt results accumulator for process %s: %w", processID.String(), err)
+			}
+			resultsAccumulator[i] = result
+			accumulatorsEncrypted[i] = *ct
+			decryptionProofs[i], err = elgamal.BuildDecryptionProof(encryptionPrivKey, encryptionPubKey, ct.C1, ct.C2, result)
+			if err != nil {
+				setProcessInvalid()
+				return fmt.Errorf("could not build decryption proof for results accumulator for process %s: %w", processID.String(), err)
+			}
 		}
 	}
 	log.Debugw("decrypted results accumulator", "processID", processID.String(), "duration", time.Since(startTime).String(), "result", resultsAccumulator)
@@ -411,6 +434,20 @@ func (f *finalizer) finalize(processID types.ProcessID) error {
 		return fmt.Errorf("could not store results for process %s: %w", processID.String(), err)
 	}
 
+	// Publish the decryption of the accumulator, so the results can be
+	// verified independently
+	if err := f.stg.SetResultsDecryption(
+		processID,
+		stateRootBI,
+		accumulatorsEncrypted[:],
+		resultsAccumulator[:],
+		decryptionProofs[:],
+	); err != nil {
+		log.Warnw("could not store results decryption",
+			"processID", processID.String(),
+			"error", err.Error())
+	}
+
 	return nil
 }
 
@@ -0,0 +1,100 @@
+package sequencer
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"math/big"
+	"slices"
+	"time"
+
+	"github.com/vocdoni/davinci-node/crypto/ecc"
+	"github.com/vocdoni/davinci-node/crypto/elgamal"
+	"github.com/vocdoni/davinci-node/crypto/elgamal/dkg"
+	"github.com/vocdoni/davinci-node/spec/params"
+	"github.com/vocdoni/davinci-node/storage"
+	"github.com/vocdoni/davinci-node/types"
+)
+
+// ThresholdDecryptionResponsesTimeout is the time the quorum trustees of a
+// threshold decryption have to submit their responses before the quorum is
+// formed again without the trustees that did not respond.
+var ThresholdDecryptionResponsesTimeout = 30 * time.Minute
+
+// thresholdDecrypt decrypts the results accumulator of a process whose
+// encryption key was generated by the DKG session provided. The first time
+// it is called for a process, it starts the threshold decryption of the
+// accumulator ciphertexts and returns storage.ErrThresholdDecryptionPending.
+// It keeps returning that error until the trustees have submitted their
+// partial decryptions and responses, forming the quorum again if it does not
+// respond within ThresholdDecryptionResponsesTimeout. Then, it combines the
+// quorum partial decryptions to recover the results and checks the combined
+// decryption proofs against the process encryption key, so they can be used
+// as inputs of the results verifier circuit.
+func (f *finalizer) thresholdDecrypt(
+	process *types.Process,
+	session *types.DKGSession,
+	publicKey ecc.Point,
+	encryptedResults *elgamal.Ballot,
+) ([params.FieldsPerBallot]*big.Int, [params.FieldsPerBallot]*elgamal.DecryptionProof, error) {
+	results := [params.FieldsPerBallot]*big.Int{}
+	proofs := [params.FieldsPerBallot]*elgamal.DecryptionProof{}
+	processID := *process.ID
+
+	for _, ct := range encryptedResults.Ciphertexts {
+		if ct == nil || ct.C1 == nil || ct.C2 == nil {
+			return results, proofs, fmt.Errorf("invalid ciphertext for process %s: %v", processID.String(), ct)
+		}
+	}
+
+	partials, combinedProofs, err := f.stg.ThresholdDecryptionProofs(processID)
+	if errors.Is(err, storage.ErrNotFound) {
+		ciphertexts := make([]types.DKGCiphertext, 0, len(encryptedResults.Ciphertexts))
+		for _, ct := range encryptedResults.Ciphertexts {
+			ciphertexts = append(ciphertexts, types.DKGCiphertext{
+				C1: types.DKGPointFromPoint(ct.C1),
+				C2: types.DKGPointFromPoint(ct.C2),
+			})
+		}
+		if _, err := f.stg.StartThresholdDecryption(processID, session.ID, ciphertexts); err != nil {
+			return results, proofs, fmt.Errorf("could not start threshold decryption for process %s: %w",
+				processID.String(), err)
+		}
+		return results, proofs, fmt.Errorf("process %s: %w: waiting for the trustees",
+			processID.String(), storage.ErrThresholdDecryptionPending)
+	}
+	if errors.Is(err, storage.ErrThresholdDecryptionPending) {
+		// If the quorum does not respond in time, form it again with the
+		// rest of trustees.
+		if _, err := f.stg.RestartStaleThresholdDecryption(processID, ThresholdDecryptionResponsesTimeout); err != nil {
+			return results, proofs, fmt.Errorf("could not restart threshold decryption for process %s: %w",
+				processID.String(), err)
+		}
+	}
+	if err != nil {
+		return results, proofs, fmt.Errorf("process %s: %w", processID.String(), err)
+	}
+	if len(combinedProofs) != len(encryptedResults.Ciphertexts) {
+		return results, proofs, fmt.Errorf("expected %d decryption proofs for process %s, got %d",
+			len(encryptedResults.Ciphertexts), processID.String(), len(combinedProofs))
+	}
+
+	maxResult := maxPossibleResult(process)
+	for i, ct := range encryptedResults.Ciphertexts {
+		quorum := slices.Sorted(maps.Keys(partials[i]))
+		result, err := dkg.CombinePartialDecryptions(ct.C2, partials[i], quorum, maxResult)
+		if err != nil {
+			return results, proofs, fmt.Errorf("could not combine partial decryptions for process %s: %w",
+				processID.String(), err)
+		}
+		// The proof also fails if the trustees decrypted different
+		// ciphertexts than the ones of the state.
+		if err := elgamal.VerifyDecryptionProof(publicKey, ct.C1, ct.C2, result, combinedProofs[i]); err != nil {
+			return results, proofs, fmt.Errorf("invalid combined decryption proof for process %s: %w",
+				processID.String(), err)
+		}
+		results[i] = result
+		proofs[i] = combinedProofs[i]
+	}
+	return results, proofs, nil
+}
@@ -1,6 +1,7 @@
 package sequencer
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"math/big"
@@ -12,6 +13,7 @@ import (
 	"github.com/vocdoni/davinci-node/storage"
 	"github.com/vocdoni/davinci-node/types"
 	"github.com/vocdoni/davinci-node/web3"
+	"github.com/vocdoni/davinci-node/web3/txmanager"
 )
 
 const (
@@ -64,6 +66,10 @@ func (s *Sequencer) startOnchainProcessor() error {
 func (s *Sequencer) processTransitionOnChain() {
 	// process each registered process ID
 	s.processIDs.ForEach(func(processID types.ProcessID, _ time.Time) bool {
+		if s.processIDs.IsPaused(processID) {
+			log.Debugw("process paused", "processID", processID.String())
+			return true // Continue to next process ID
+		}
 		if !s.contractsResolver.SupportsProcess(processID) {
 			log.Debugw("process not supported", "processID", processID.String())
 			return true // Continue to next process ID
@@ -83,6 +89,22 @@ func (s *Sequencer) processTransitionOnChain() {
 			}
 			return true // Continue to next process ID
 		}
+		// if the transaction of the batch was already sent, e.g. before a
+		// restart, wait for it instead of sending it again
+		if len(batch.TxID) > 0 {
+			s.waitTransitionTx(contracts, processID, batchID, batch.TxID, batch.Inputs.RootHashAfter)
+			return true // Continue to next process ID
+		}
+		// hold the batch while the fees are above the ceilings, releasing
+		// its reservation so it is picked up again on the next tick
+		if s.holdSettlement(contracts, processID) {
+			if err := s.stg.ReleaseReservation(storage.QueueStateTransitions, batchID); err != nil {
+				log.Warnw("failed to release held state transition batch",
+					"error", err.Error(),
+					"processID", processID.String())
+			}
+			return true // Continue to next process ID
+		}
 		log.Infow("state transition batch ready for on-chain upload",
 			"processID", processID.String(),
 			"batchID", fmt.Sprintf("%x", batchID))
@@ -94,6 +116,25 @@ func (s *Sequencer) processTransitionOnChain() {
 			return true // Continue to next process ID
 		}
 		localStateRoot := new(types.BigInt).SetBigInt(batch.Inputs.RootHashBefore)
+		// a batch queued again after a chain reorg may have been settled
+		// anyway, if its transaction was included in the new chain
+		if remoteStateRoot.MathBigInt().Cmp(batch.Inputs.RootHashAfter) == 0 {
+			log.Infow("state transition batch already settled",
+				"processID", processID.String(),
+				"rootHashAfter", batch.Inputs.RootHashAfter.String())
+			if err := s.stg.MarkStateTransitionBatchDone(batchID, processID); err != nil {
+				log.Errorw(err, "failed to mark settled state transition batch as done")
+			}
+			return true // Continue to next process ID
+		}
+		// a batch queued again after a chain reorg may build on another
+		// one, so it is kept reserved until the other one is settled
+		if s.stg.HasStateTransitionBatchFrom(processID, remoteStateRoot.MathBigInt()) {
+			log.Debugw("state transition batch waiting for the previous one",
+				"processID", processID.String(),
+				"rootHashBefore", batch.Inputs.RootHashBefore.String())
+			return true // Continue to next process ID
+		}
 		if remoteStateRoot.Cmp(localStateRoot) != 0 {
 			log.Errorw(fmt.Errorf("state root mismatch for processID %s: local %s != remote %s",
 				processID.String(), localStateRoot.HexBytes().String(), remoteStateRoot.HexBytes().String()), "could not push state transition to contract")
@@ -116,6 +157,10 @@ func (s *Sequencer) processTransitionOnChain() {
 		// send the proof to the contract with the public witness
 		if err := s.pushTransitionToContract(contracts, processID, batchID, solidityCommitmentProof, batch.Inputs, batch.BlobSidecar); err != nil {
 			log.Errorw(err, "failed to push to contract")
+			// keep the batch to push it again after the restart
+			if isShutdownError(err) {
+				return false
+			}
 			if err := s.stg.MarkStateTransitionBatchFailed(batchID, processID); err != nil {
 				log.Errorw(err, "failed to mark state transition batch as failed")
 			}
@@ -187,26 +232,63 @@ func (s *Sequencer) pushTransitionToContract(
 	// Submit the proof to the contract
 	log.Infow("state transition pending to be mined",
 		"processID", processID.String())
-	// Create a callback for the state transition
-	callback := s.pushStateTransitionCallback(processID, batchID, inputs.RootHashAfter)
-	if err := contracts.SetProcessTransition(
-		processID,
-		abiProof,
-		abiInputs,
-		blobSidecar,
-		transitionOnChainTimeout,
-		callback,
-	); err != nil {
+	txID, err := contracts.SendProcessTransition(processID, abiProof, abiInputs, blobSidecar)
+	if err != nil {
 		return fmt.Errorf("failed to set process transition: %w", err)
 	}
+	// Store the transaction ID, so it is waited instead of sent again if the
+	// batch is picked up after a restart
+	if err := s.stg.SetStateTransitionBatchTxID(batchID, txID); err != nil {
+		log.Warnw("failed to store state transition transaction ID",
+			"error", err.Error(),
+			"processID", processID.String(),
+			"txID", txID.String())
+	}
+	s.waitTransitionTx(contracts, processID, batchID, txID, inputs.RootHashAfter)
 	return nil
 }
 
+// waitTransitionTx waits in background for the transaction sent to settle
+// the state transition batch provided, and then runs the state transition
+// callback. It does nothing if the transaction is already being waited,
+// e.g. when the reservation of the batch expires before it is mined.
+func (s *Sequencer) waitTransitionTx(
+	contracts *web3.Contracts,
+	processID types.ProcessID,
+	batchID []byte,
+	txID types.HexBytes,
+	rootHashAfter *big.Int,
+) {
+	if _, waiting := s.transitionTxs.LoadOrStore(string(batchID), struct{}{}); waiting {
+		return
+	}
+	log.Infow("waiting for state transition transaction",
+		"processID", processID.String(),
+		"batchID", fmt.Sprintf("%x", batchID),
+		"txID", txID.String())
+	callback := s.pushStateTransitionCallback(processID, batchID, rootHashAfter)
+	if err := contracts.WaitTxByID(txID, transitionOnChainTimeout, callback); err != nil {
+		s.transitionTxs.Delete(string(batchID))
+		log.Errorw(err, "failed to wait for state transition transaction")
+	}
+}
+
 // pushStateTransitionCallback returns a callback function to be called when
 // the state transition transaction is mined or fails. It handles logging and
 // recovery of pending state transitions.
 func (s *Sequencer) pushStateTransitionCallback(processID types.ProcessID, batchID []byte, rootHashAfter *big.Int) func(err error) {
 	return func(err error) {
+		defer s.transitionTxs.Delete(string(batchID))
+		// If the wait was interrupted by a shutdown, the transaction may
+		// still be mined, so the batch, its transaction ID and the pending tx
+		// mark are kept to resume the wait after the restart
+		if isShutdownError(err) {
+			log.Infow("state transition transaction wait interrupted, it will be resumed",
+				"processID", processID.String(),
+				"batchID", fmt.Sprintf("%x", batchID),
+				"error", err.Error())
+			return
+		}
 		defer func() {
 			// Remove the pending tx mark
 			if err := s.stg.PrunePendingTx(storage.StateTransitionTx, processID); err != nil {
@@ -245,9 +327,18 @@ func (s *Sequencer) pushStateTransitionCallback(processID types.ProcessID, batch
 
 		s.processIDs.Add(processID)
 		log.Infow("state transition pushed to contract", "processID", processID.String())
+		s.stg.PublishProcessEvent(storage.ProcessEventStateTransitionSettled, processID, map[string]any{
+			"stateRoot": rootHashAfter.String(),
+		})
 	}
 }
 
+// isShutdownError returns true if the error provided is caused by the
+// sequencer or the transaction manager being stopped.
+func isShutdownError(err error) bool {
+	return errors.Is(err, txmanager.ErrStopped) || errors.Is(err, context.Canceled)
+}
+
 func (s *Sequencer) promoteCommittedStateFromTransition(processID types.ProcessID, rootHashAfter *big.Int) error {
 	if rootHashAfter == nil {
 		return fmt.Errorf("nil rootHashAfter")
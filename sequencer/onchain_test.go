@@ -1,6 +1,7 @@
 package sequencer
 
 import (
+	"context"
 	"errors"
 	"math/big"
 	"testing"
@@ -17,6 +18,7 @@ import (
 	statetest "github.com/vocdoni/davinci-node/state/testutil"
 	"github.com/vocdoni/davinci-node/storage"
 	"github.com/vocdoni/davinci-node/types"
+	"github.com/vocdoni/davinci-node/web3/txmanager"
 )
 
 func newTestSequencerStorage(t *testing.T) *storage.Storage {
@@ -269,3 +271,56 @@ func TestPushStateTransitionCallbackMarksBatchFailedOnError(t *testing.T) {
 	c.Assert(err, qt.IsNil)
 	c.Assert(status, qt.Equals, storage.VoteIDStatusError)
 }
+
+func TestPushStateTransitionCallbackKeepsBatchOnShutdown(t *testing.T) {
+	for _, shutdownErr := range []error{txmanager.ErrStopped, context.Canceled} {
+		t.Run(shutdownErr.Error(), func(t *testing.T) {
+			c := qt.New(t)
+			stg := newTestSequencerStorage(t)
+			defer stg.Close()
+
+			processID := testutil.RandomProcessID()
+			voteID := testutil.RandomVoteID()
+			ensureSequencerTestProcess(t, stg, processID)
+
+			stb := &storage.StateTransitionBatch{
+				ProcessID: processID,
+				Ballots: []*storage.AggregatorBallot{
+					testSequencerAggBallot(voteID),
+				},
+				Inputs: storage.StateTransitionBatchProofInputs{
+					RootHashBefore: big.NewInt(1),
+					RootHashAfter:  big.NewInt(2),
+					CensusRoot:     big.NewInt(3),
+				},
+			}
+			c.Assert(stg.PushStateTransitionBatch(stb), qt.IsNil)
+
+			_, batchID, err := stg.NextStateTransitionBatch(processID)
+			c.Assert(err, qt.IsNil)
+			txID := types.HexBytes{0x01, 0x02}
+			c.Assert(stg.SetStateTransitionBatchTxID(batchID, txID), qt.IsNil)
+			c.Assert(stg.SetPendingTx(storage.StateTransitionTx, processID), qt.IsNil)
+
+			seq := &Sequencer{
+				stg:        stg,
+				processIDs: NewProcessIDMap(),
+			}
+
+			// the tx manager is stopped while the transaction is pending
+			seq.pushStateTransitionCallback(processID, batchID, big.NewInt(2))(shutdownErr)
+
+			// the batch and its transaction ID are kept to resume the wait
+			c.Assert(stg.HasPendingTx(storage.StateTransitionTx, processID), qt.IsTrue)
+			c.Assert(stg.ReleaseReservation(storage.QueueStateTransitions, batchID), qt.IsNil)
+			batch, resumedBatchID, err := stg.NextStateTransitionBatch(processID)
+			c.Assert(err, qt.IsNil)
+			c.Assert(resumedBatchID, qt.DeepEquals, batchID)
+			c.Assert(batch.TxID, qt.DeepEquals, txID)
+
+			status, err := stg.VoteIDStatus(processID, voteID)
+			c.Assert(err, qt.IsNil)
+			c.Assert(status, qt.Not(qt.Equals), storage.VoteIDStatusError)
+		})
+	}
+}
@@ -13,6 +13,7 @@ import (
 type ProcessIDMap struct {
 	data             map[types.ProcessID]time.Time // Last update time for each process ID
 	firstBallotTimes map[types.ProcessID]time.Time // Timestamp of first ballot after last batch
+	paused           map[types.ProcessID]struct{}  // Process IDs whose sequencing is paused
 	mu               sync.RWMutex
 }
 
@@ -21,6 +22,7 @@ func NewProcessIDMap() *ProcessIDMap {
 	return &ProcessIDMap{
 		data:             make(map[types.ProcessID]time.Time),
 		firstBallotTimes: make(map[types.ProcessID]time.Time),
+		paused:           make(map[types.ProcessID]struct{}),
 	}
 }
 
@@ -58,6 +60,7 @@ func (p *ProcessIDMap) Remove(processID types.ProcessID) bool {
 	defer p.mu.Unlock()
 
 	delete(p.data, processID)
+	delete(p.paused, processID)
 	return true
 }
 
@@ -169,3 +172,60 @@ func (p *ProcessIDMap) ClearFirstBallotTime(processID types.ProcessID) {
 
 	delete(p.firstBallotTimes, processID)
 }
+
+// Pause pauses the sequencing of a process ID in the map. The ballots,
+// batches and state transitions of a paused process stay in the queues
+// until it is resumed. Pauses are kept in memory, so they do not survive a
+// restart. Returns false if the process ID is not in the map.
+func (p *ProcessIDMap) Pause(processID types.ProcessID) bool {
+	if !processID.IsValid() {
+		return false
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if _, exists := p.data[processID]; !exists {
+		return false
+	}
+	p.paused[processID] = struct{}{}
+	return true
+}
+
+// Resume resumes the sequencing of a paused process ID. Returns false if
+// the process ID was not paused.
+func (p *ProcessIDMap) Resume(processID types.ProcessID) bool {
+	if !processID.IsValid() {
+		return false
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if _, paused := p.paused[processID]; !paused {
+		return false
+	}
+	delete(p.paused, processID)
+	return true
+}
+
+// IsPaused checks if the sequencing of a process ID is paused.
+func (p *ProcessIDMap) IsPaused(processID types.ProcessID) bool {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	_, paused := p.paused[processID]
+	return paused
+}
+
+// Paused returns the process IDs whose sequencing is paused.
+func (p *ProcessIDMap) Paused() []types.ProcessID {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	result := make([]types.ProcessID, 0, len(p.paused))
+	for processID := range p.paused {
+		result = append(result, processID)
+	}
+	return result
+}
@@ -91,3 +91,32 @@ func TestFirstBallotTime(t *testing.T) {
 	c.Assert(exists, qt.Equals, true, qt.Commentf("Should have a first ballot time after setting it again"))
 	c.Assert(time3.After(time1), qt.Equals, true, qt.Commentf("New first ballot time should be after the original"))
 }
+
+func TestProcessIDMapPause(t *testing.T) {
+	c := qt.New(t)
+
+	pidMap := NewProcessIDMap()
+	pid1 := testutil.DeterministicProcessID(1)
+	pid2 := testutil.DeterministicProcessID(2)
+
+	// only registered process IDs can be paused
+	c.Assert(pidMap.Pause(pid1), qt.IsFalse)
+	c.Assert(pidMap.Add(pid1), qt.IsTrue)
+	c.Assert(pidMap.Add(pid2), qt.IsTrue)
+	c.Assert(pidMap.Pause(pid1), qt.IsTrue)
+	c.Assert(pidMap.IsPaused(pid1), qt.IsTrue)
+	c.Assert(pidMap.IsPaused(pid2), qt.IsFalse)
+	c.Assert(pidMap.Paused(), qt.DeepEquals, []types.ProcessID{pid1})
+
+	// resume only succeeds for paused process IDs
+	c.Assert(pidMap.Resume(pid2), qt.IsFalse)
+	c.Assert(pidMap.Resume(pid1), qt.IsTrue)
+	c.Assert(pidMap.IsPaused(pid1), qt.IsFalse)
+
+	// removing a process ID clears its pause
+	c.Assert(pidMap.Pause(pid2), qt.IsTrue)
+	c.Assert(pidMap.Remove(pid2), qt.IsTrue)
+	c.Assert(pidMap.Add(pid2), qt.IsTrue)
+	c.Assert(pidMap.IsPaused(pid2), qt.IsFalse)
+	c.Assert(pidMap.Paused(), qt.HasLen, 0)
+}
@@ -0,0 +1,102 @@
+package sequencer
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/backend/groth16"
+	"github.com/consensys/gnark/frontend"
+	"github.com/vocdoni/davinci-node/circuits"
+	"github.com/vocdoni/davinci-node/log"
+	"github.com/vocdoni/davinci-node/types"
+	"github.com/vocdoni/davinci-node/workers"
+)
+
+// SetProofJobsManager sets the jobs manager of the workers API, so the
+// aggregation and state transition proofs are offloaded to the workers that
+// take them. Without it, or when no worker takes a job, the sequencer
+// generates the proofs by itself.
+func (s *Sequencer) SetProofJobsManager(jm *workers.JobsManager) {
+	s.proofJobs = jm
+}
+
+// prove generates the proof of the assignment provided with the circuit
+// runtime provided. It is offloaded to a remote worker if there is one
+// taking the job type provided, and proven locally otherwise, or if the
+// worker fails or returns an invalid proof.
+func (s *Sequencer) prove(
+	jobType workers.JobType,
+	processID types.ProcessID,
+	runtime *circuits.CircuitRuntime,
+	assignment frontend.Circuit,
+) (groth16.Proof, error) {
+	if s.proofJobs != nil {
+		proof, err := s.proveRemotely(jobType, processID, runtime, assignment)
+		if err == nil {
+			return proof, nil
+		}
+		if !errors.Is(err, workers.ErrNoCapableWorker) {
+			log.Warnw("remote proof failed, proving locally",
+				"error", err.Error(),
+				"type", jobType,
+				"processID", processID.String())
+		}
+	}
+	return runtime.ProveAndVerify(assignment)
+}
+
+// proveRemotely sends the full witness of the assignment provided to a
+// remote worker and waits for its proof, which is verified against the
+// public witness of the assignment before it is accepted.
+func (s *Sequencer) proveRemotely(
+	jobType workers.JobType,
+	processID types.ProcessID,
+	runtime *circuits.CircuitRuntime,
+	assignment frontend.Circuit,
+) (groth16.Proof, error) {
+	fullWitness, err := frontend.NewWitness(assignment, runtime.Curve().ScalarField())
+	if err != nil {
+		return nil, fmt.Errorf("create witness: %w", err)
+	}
+	publicWitness, err := fullWitness.Public()
+	if err != nil {
+		return nil, fmt.Errorf("create public witness: %w", err)
+	}
+	witness, err := fullWitness.MarshalBinary()
+	if err != nil {
+		return nil, fmt.Errorf("encode witness: %w", err)
+	}
+	proofBytes, err := s.proofJobs.ProveRemotely(s.ctx, jobType, processID, witness, func(proofBytes []byte) error {
+		proof, err := decodeProof(runtime.Curve(), proofBytes)
+		if err != nil {
+			return err
+		}
+		return runtime.VerifyWithWitness(proof, publicWitness)
+	})
+	if err != nil {
+		return nil, err
+	}
+	log.Infow("remote proof accepted", "type", jobType, "processID", processID.String())
+	return decodeProof(runtime.Curve(), proofBytes)
+}
+
+// encodeProof returns the binary encoding of the proof provided.
+func encodeProof(proof groth16.Proof) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	if _, err := proof.WriteTo(buf); err != nil {
+		return nil, fmt.Errorf("encode proof: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// decodeProof decodes a proof of the curve provided from its binary
+// encoding.
+func decodeProof(curve ecc.ID, data []byte) (groth16.Proof, error) {
+	proof := groth16.NewProof(curve)
+	if _, err := proof.ReadFrom(bytes.NewReader(data)); err != nil {
+		return nil, fmt.Errorf("decode proof: %w", err)
+	}
+	return proof, nil
+}
@@ -13,6 +13,7 @@ import (
 	"github.com/vocdoni/davinci-node/storage"
 	"github.com/vocdoni/davinci-node/types"
 	"github.com/vocdoni/davinci-node/web3"
+	"github.com/vocdoni/davinci-node/workers"
 )
 
 var (
@@ -39,18 +40,27 @@ type Sequencer struct {
 	contractsResolver  web3.ProcessContractsResolver
 	ctx                context.Context
 	cancel             context.CancelFunc
-	processIDs         *ProcessIDMap // Maps process IDs to their last update time
-	workInProgressLock sync.RWMutex  // Lock to block new work while processing a batch or a state transition
+	processIDs         *ProcessIDMap        // Maps process IDs to their last update time
+	workInProgressLock sync.RWMutex         // Lock to block new work while processing a batch or a state transition
+	transitionTxs      sync.Map             // State transition batch IDs whose transaction is being waited
+	proofJobs          *workers.JobsManager // Offloads the aggregation and state transition proofs to the workers
 	// batchTimeWindow is the maximum time window to wait for a batch to be processed.
 	// If this time elapses, the batch will be processed even if not full.
-	batchTimeWindow time.Duration
+	batchTimeWindow    time.Duration
+	defaultBatchPolicy BatchPolicy                           // Batch policy of the processes without their own, nil for the time window
+	batchPolicies      map[types.ProcessID]BatchPolicy       // Batch policies of the processes
+	batchPoliciesMu    sync.RWMutex                          // Protects the batch policies
+	settlementConf     *SettlementConfig                     // Fee ceilings of the state transitions, nil to settle them right away
+	settlementHeld     map[types.ProcessID]*SettlementStatus // Processes whose state transitions are held by the fees
+	settlementMu       sync.Mutex                            // Protects the settlement config and statuses
 
 	// Worker mode fields
-	sequencerURL    string         // URL of sequencer node (empty for sequencer mode)
-	sequencerUUID   string         // UUID of sequencer node (empty for sequencer mode)
-	workerAddress   common.Address // Ethereum address identifying this worker
-	workerName      string         // Name of the worker for identification
-	workerAuthtoken string         // Worker auth token
+	sequencerURL    string            // URL of sequencer node (empty for sequencer mode)
+	sequencerUUID   string            // UUID of sequencer node (empty for sequencer mode)
+	workerAddress   common.Address    // Ethereum address identifying this worker
+	workerName      string            // Name of the worker for identification
+	workerAuthtoken string            // Worker auth token
+	workerJobTypes  []workers.JobType // Job types taken by the worker
 }
 
 // New creates a new Sequencer instance that processes ballots and aggregates them into batches.
@@ -77,6 +87,8 @@ func New(stg *storage.Storage, resolver web3.ProcessContractsResolver, batchTime
 		stg:               stg,
 		contractsResolver: resolver,
 		batchTimeWindow:   batchTimeWindow,
+		batchPolicies:     make(map[types.ProcessID]BatchPolicy),
+		settlementHeld:    make(map[types.ProcessID]*SettlementStatus),
 		processIDs:        NewProcessIDMap(),
 	}
 	// Load the internal circuits
@@ -171,6 +183,9 @@ func (s *Sequencer) Start(ctx context.Context) error {
 		return nil
 	}
 
+	// Restore the batch policies set before the restart
+	s.loadBatchPolicies()
+
 	// Start monitoring for new processes
 	s.monitorNewProcesses(s.ctx, NewProcessMonitorInterval)
 
@@ -333,6 +348,33 @@ func (s *Sequencer) ActiveProcessIDs() []types.ProcessID {
 	return s.processIDs.List()
 }
 
+// PauseProcessID pauses the sequencing of a registered process ID. Its
+// ballots are not verified, aggregated or settled until it is resumed.
+// Returns false if the process ID is not registered.
+func (s *Sequencer) PauseProcessID(processID types.ProcessID) bool {
+	if !s.processIDs.Pause(processID) {
+		return false
+	}
+	log.Infow("process ID sequencing paused", "processID", processID.String())
+	return true
+}
+
+// ResumeProcessID resumes the sequencing of a paused process ID. Returns
+// false if the process ID is not paused.
+func (s *Sequencer) ResumeProcessID(processID types.ProcessID) bool {
+	if !s.processIDs.Resume(processID) {
+		return false
+	}
+	log.Infow("process ID sequencing resumed", "processID", processID.String())
+	return true
+}
+
+// PausedProcessIDs returns the list of process IDs whose sequencing is
+// paused.
+func (s *Sequencer) PausedProcessIDs() []types.ProcessID {
+	return s.processIDs.Paused()
+}
+
 // WaitUntilResults waits until the process is finalized. Returns the result of the process.
 // It ensures proper timeout handling and provides detailed logging for troubleshooting.
 func (s *Sequencer) WaitUntilResults(ctx context.Context, processID types.ProcessID) ([]*types.BigInt, error) {
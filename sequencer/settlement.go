@@ -0,0 +1,165 @@
+package sequencer
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"slices"
+	"time"
+
+	"github.com/vocdoni/davinci-node/log"
+	"github.com/vocdoni/davinci-node/metrics"
+	"github.com/vocdoni/davinci-node/types"
+	"github.com/vocdoni/davinci-node/web3"
+)
+
+// settlementFeesTimeout is the time to get the fees of a chain before
+// deciding whether a state transition is held.
+const settlementFeesTimeout = 10 * time.Second
+
+// SettlementConfig defines the fee ceilings above which the state
+// transitions are held instead of being settled on chain. The transitions of
+// a process are never held past its end time minus the end margin, so its
+// results are not delayed. A nil ceiling is not checked.
+type SettlementConfig struct {
+	MaxGasTipCap   *big.Int      // Maximum suggested priority fee per gas
+	MaxBlobBaseFee *big.Int      // Maximum blob base fee, only checked on chains with blob transactions
+	EndMargin      time.Duration // Time before the end of the process from which the fees are not checked
+}
+
+// enabled returns whether the config has any fee ceiling.
+func (c *SettlementConfig) enabled() bool {
+	return c != nil && (c.MaxGasTipCap != nil || c.MaxBlobBaseFee != nil)
+}
+
+// holdReason returns the fee above its ceiling, or an empty string if the
+// fees provided allow to settle. A nil fee is not checked.
+func (c *SettlementConfig) holdReason(gasTipCap, blobBaseFee *big.Int) string {
+	switch {
+	case c.MaxGasTipCap != nil && gasTipCap != nil && gasTipCap.Cmp(c.MaxGasTipCap) > 0:
+		return fmt.Sprintf("gas tip cap %s above %s", formatWei(gasTipCap), formatWei(c.MaxGasTipCap))
+	case c.MaxBlobBaseFee != nil && blobBaseFee != nil && blobBaseFee.Cmp(c.MaxBlobBaseFee) > 0:
+		return fmt.Sprintf("blob base fee %s above %s", formatWei(blobBaseFee), formatWei(c.MaxBlobBaseFee))
+	default:
+		return ""
+	}
+}
+
+// SettlementStatus is the status of a process whose state transitions are
+// held because the fees are above the ceilings.
+type SettlementStatus struct {
+	ProcessID   types.ProcessID
+	Reason      string    // Fee above its ceiling
+	HeldSince   time.Time // Time at which the transitions started to be held
+	Deadline    time.Time // Time from which the transitions are settled whatever the fees
+	GasTipCap   *big.Int  // Last suggested priority fee per gas
+	BlobBaseFee *big.Int  // Last blob base fee, nil if the chain has no blob transactions
+}
+
+// ExpectedDelay returns the maximum time until the state transitions of the
+// process are settled, which is the time until the deadline if the fees do
+// not drop below the ceilings before.
+func (ss *SettlementStatus) ExpectedDelay(now time.Time) time.Duration {
+	return max(ss.Deadline.Sub(now), 0)
+}
+
+// SetSettlementConfig sets the fee ceilings of the state transitions. A nil
+// config, or one without ceilings, settles them as soon as they are ready.
+func (s *Sequencer) SetSettlementConfig(conf *SettlementConfig) {
+	s.settlementMu.Lock()
+	defer s.settlementMu.Unlock()
+	s.settlementConf = conf
+}
+
+// SettlementStatuses returns the status of the processes whose state
+// transitions are held, sorted by deadline.
+func (s *Sequencer) SettlementStatuses() []*SettlementStatus {
+	s.settlementMu.Lock()
+	defer s.settlementMu.Unlock()
+	statuses := make([]*SettlementStatus, 0, len(s.settlementHeld))
+	for _, status := range s.settlementHeld {
+		statuses = append(statuses, status)
+	}
+	slices.SortFunc(statuses, func(a, b *SettlementStatus) int {
+		return a.Deadline.Compare(b.Deadline)
+	})
+	return statuses
+}
+
+// releaseSettlement removes the held status of a process.
+func (s *Sequencer) releaseSettlement(processID types.ProcessID) {
+	s.settlementMu.Lock()
+	defer s.settlementMu.Unlock()
+	if _, held := s.settlementHeld[processID]; held {
+		delete(s.settlementHeld, processID)
+		metrics.SettlementsHeld.Set(float64(len(s.settlementHeld)))
+		log.Infow("state transitions released", "processID", processID.String())
+	}
+}
+
+// holdSettlement returns whether the state transitions of a process must be
+// held because the fees of its chain are above the ceilings. They are not
+// held if the end of the process is unknown or near, or if the fees can not
+// be known.
+func (s *Sequencer) holdSettlement(contracts *web3.Contracts, processID types.ProcessID) bool {
+	s.settlementMu.Lock()
+	conf := s.settlementConf
+	s.settlementMu.Unlock()
+	if !conf.enabled() {
+		return false
+	}
+
+	now := time.Now()
+	process, err := s.stg.Process(processID)
+	if err != nil || process.StartTime.IsZero() {
+		s.releaseSettlement(processID)
+		return false
+	}
+	deadline := process.StartTime.Add(process.Duration).Add(-conf.EndMargin)
+	if !now.Before(deadline) {
+		s.releaseSettlement(processID)
+		return false
+	}
+
+	ctx, cancel := context.WithTimeout(s.ctx, settlementFeesTimeout)
+	defer cancel()
+	status := &SettlementStatus{
+		ProcessID: processID,
+		HeldSince: now,
+		Deadline:  deadline,
+	}
+	if status.GasTipCap, err = contracts.Client().SuggestGasTipCap(ctx); err != nil {
+		log.Warnw("could not get gas tip cap, settling state transition",
+			"processID", processID.String(),
+			"error", err.Error())
+		s.releaseSettlement(processID)
+		return false
+	}
+	if conf.MaxBlobBaseFee != nil && contracts.SupportBlobTxs() {
+		if status.BlobBaseFee, err = contracts.Client().BlobBaseFee(ctx); err != nil {
+			log.Warnw("could not get blob base fee, settling state transition",
+				"processID", processID.String(),
+				"error", err.Error())
+			s.releaseSettlement(processID)
+			return false
+		}
+	}
+	if status.Reason = conf.holdReason(status.GasTipCap, status.BlobBaseFee); status.Reason == "" {
+		s.releaseSettlement(processID)
+		return false
+	}
+
+	s.settlementMu.Lock()
+	defer s.settlementMu.Unlock()
+	if previous, held := s.settlementHeld[processID]; held {
+		status.HeldSince = previous.HeldSince
+	} else {
+		log.Infow("state transitions held by fees",
+			"processID", processID.String(),
+			"reason", status.Reason,
+			"deadline", deadline.Format(time.RFC3339))
+	}
+	s.settlementHeld[processID] = status
+	metrics.SettlementsHeld.Set(float64(len(s.settlementHeld)))
+	return true
+}
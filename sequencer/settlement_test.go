@@ -0,0 +1,52 @@
+package sequencer
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/vocdoni/davinci-node/internal/testutil"
+	"github.com/vocdoni/davinci-node/types"
+)
+
+func TestSettlementHoldReason(t *testing.T) {
+	c := qt.New(t)
+
+	var disabled *SettlementConfig
+	c.Assert(disabled.enabled(), qt.IsFalse)
+	c.Assert((&SettlementConfig{EndMargin: time.Minute}).enabled(), qt.IsFalse)
+
+	conf := &SettlementConfig{
+		MaxGasTipCap:   big.NewInt(2_000_000_000),
+		MaxBlobBaseFee: big.NewInt(100),
+	}
+	c.Assert(conf.enabled(), qt.IsTrue)
+	c.Assert(conf.holdReason(big.NewInt(1_000_000_000), big.NewInt(50)), qt.Equals, "")
+	c.Assert(conf.holdReason(big.NewInt(5_000_000_000), big.NewInt(50)), qt.Equals,
+		"gas tip cap 5gwei above 2gwei")
+	c.Assert(conf.holdReason(big.NewInt(1_000_000_000), big.NewInt(150)), qt.Equals,
+		"blob base fee 150 above 100")
+	// the chains without blob transactions are not held by the blob fee
+	c.Assert(conf.holdReason(big.NewInt(1_000_000_000), nil), qt.Equals, "")
+	// the fees without ceiling are not checked
+	c.Assert((&SettlementConfig{MaxBlobBaseFee: big.NewInt(100)}).holdReason(big.NewInt(5_000_000_000), nil), qt.Equals, "")
+}
+
+func TestSettlementStatuses(t *testing.T) {
+	c := qt.New(t)
+	now := time.Now()
+	s := &Sequencer{settlementHeld: make(map[types.ProcessID]*SettlementStatus)}
+
+	late := &SettlementStatus{ProcessID: testutil.RandomProcessID(), Deadline: now.Add(time.Hour)}
+	soon := &SettlementStatus{ProcessID: testutil.RandomProcessID(), Deadline: now.Add(time.Minute)}
+	s.settlementHeld[late.ProcessID] = late
+	s.settlementHeld[soon.ProcessID] = soon
+	c.Assert(s.SettlementStatuses(), qt.DeepEquals, []*SettlementStatus{soon, late})
+
+	c.Assert(soon.ExpectedDelay(now), qt.Equals, time.Minute)
+	c.Assert(soon.ExpectedDelay(now.Add(time.Hour)), qt.Equals, time.Duration(0))
+
+	s.releaseSettlement(soon.ProcessID)
+	c.Assert(s.SettlementStatuses(), qt.DeepEquals, []*SettlementStatus{late})
+}
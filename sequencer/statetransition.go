@@ -15,11 +15,13 @@ import (
 	"github.com/vocdoni/davinci-node/crypto/csp"
 	"github.com/vocdoni/davinci-node/crypto/elgamal"
 	"github.com/vocdoni/davinci-node/log"
+	"github.com/vocdoni/davinci-node/metrics"
 	"github.com/vocdoni/davinci-node/spec/params"
 	specutil "github.com/vocdoni/davinci-node/spec/util"
 	"github.com/vocdoni/davinci-node/state"
 	"github.com/vocdoni/davinci-node/storage"
 	"github.com/vocdoni/davinci-node/types"
+	"github.com/vocdoni/davinci-node/workers"
 	imtcircuit "github.com/vocdoni/lean-imt-go/circuit"
 )
 
@@ -48,6 +50,10 @@ func (s *Sequencer) startStateTransitionProcessor() error {
 func (s *Sequencer) processPendingTransitions() {
 	// Process each registered process ID
 	s.processIDs.ForEach(func(processID types.ProcessID, _ time.Time) bool {
+		if s.processIDs.IsPaused(processID) {
+			log.Debugw("process paused", "processID", processID.String())
+			return true // Continue to next process ID
+		}
 		if !s.contractsResolver.SupportsProcess(processID) {
 			log.Debugw("process not supported", "processID", processID.String())
 			return true // Continue to next process ID
@@ -244,8 +250,15 @@ func (s *Sequencer) processStateTransitionBatch(
 		"censusRoot", censusRoot.String(),
 	)
 
-	// Generate the proof
-	proof, err := s.stateTransition.ProveAndVerify(assignment)
+	processID, err := types.BigIntToProcessID(processState.ProcessID())
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid process ID: %w", err)
+	}
+
+	// Generate the proof, offloaded to a worker if there is one available
+	proofStartTime := time.Now()
+	proof, err := s.prove(workers.JobTypeStateTransition, processID, s.stateTransition, assignment)
+	metrics.ObserveSince(metrics.StateTransitionDuration, proofStartTime, err)
 	if err != nil {
 		s.logStateTransitionDebugInfo(processState, votes, censusRoot, assignment, err)
 		return nil, nil, fmt.Errorf("failed to generate proof: %w", err)
@@ -419,10 +432,18 @@ func (s *Sequencer) processCensusProofs(
 			cspProofs[i] = statetransition.DummyCSPProof()
 		}
 	case process.Census.CensusOrigin.IsCSP():
+		if !csp.StateTransitionSupported(process.Census.CensusOrigin) {
+			return nil, nil, fmt.Errorf("census origin %s not supported by the state transition circuit", process.Census.CensusOrigin)
+		}
 		// iterate over the votes to get the CSP proofs
 		root = process.Census.CensusRoot.BigInt().MathBigInt()
 		for i := range params.VotesPerBatch {
 			if i < len(votes) {
+				// the proofs signed by a rotated CSP key cannot be proven
+				// against the current census root
+				if err := csp.VerifyCensusRoot(censusProofs[i], process.Census.CensusRoot); err != nil {
+					return nil, nil, fmt.Errorf("census proof for address %s is not signed by the current CSP key: %w", common.BigToAddress(votes[i].Address).Hex(), err)
+				}
 				proof, err := csp.CensusProofToCSPProof(process.Census.CensusOrigin.CurveID(), censusProofs[i])
 				if err != nil {
 					return nil, nil, fmt.Errorf("error transforming census proof for address %s: %w", common.BigToAddress(votes[i].Address).Hex(), err)
@@ -9,10 +9,16 @@ import (
 	"io"
 	"net/http"
 	"regexp"
+	"slices"
 	"strings"
 	"time"
 
+	"github.com/consensys/gnark/backend/groth16"
+	"github.com/consensys/gnark/backend/witness"
 	"github.com/vocdoni/davinci-node/api"
+	"github.com/vocdoni/davinci-node/circuits"
+	"github.com/vocdoni/davinci-node/circuits/aggregator"
+	"github.com/vocdoni/davinci-node/circuits/statetransition"
 	"github.com/vocdoni/davinci-node/circuits/voteverifier"
 	"github.com/vocdoni/davinci-node/log"
 	"github.com/vocdoni/davinci-node/storage"
@@ -32,7 +38,7 @@ var (
 )
 
 // NewWorker creates a Sequencer instance configured for worker mode.
-// Only loads the necessary artifacts for ballot processing (vote verifier).
+// Only loads the necessary artifacts for the job types taken by the worker.
 //
 // Parameters:
 //   - stg: Storage instance for accessing ballots and other data
@@ -41,10 +47,11 @@ var (
 //   - workerAddr: Ethereum address identifying this worker
 //   - workerToken: Hex-encoded authentication token for the worker
 //   - workerName: Name of the worker (optional)
+//   - jobTypes: Job types taken by the worker, ballot jobs only if empty
 //
 // Returns a configured Sequencer instance for worker mode or an error if
 // initialization fails.
-func NewWorker(stg *storage.Storage, rawSequencerURL, workerAddr, workerToken, workerName string) (*Sequencer, error) {
+func NewWorker(stg *storage.Storage, rawSequencerURL, workerAddr, workerToken, workerName string, jobTypes []workers.JobType) (*Sequencer, error) {
 	if stg == nil {
 		return nil, fmt.Errorf("storage cannot be nil")
 	}
@@ -77,6 +84,9 @@ func NewWorker(stg *storage.Storage, rawSequencerURL, workerAddr, workerToken, w
 	if workerToken == "" {
 		return nil, fmt.Errorf("hexSignature cannot be empty for worker mode")
 	}
+	if len(jobTypes) == 0 {
+		jobTypes = []workers.JobType{workers.JobTypeBallot}
+	}
 
 	startTime := time.Now()
 	s := &Sequencer{
@@ -89,6 +99,7 @@ func NewWorker(stg *storage.Storage, rawSequencerURL, workerAddr, workerToken, w
 		workerAddress:     wAddr,
 		workerName:        workerName,
 		workerAuthtoken:   workerToken,
+		workerJobTypes:    jobTypes,
 	}
 
 	s.internalCircuits = new(internalCircuits)
@@ -97,11 +108,24 @@ func NewWorker(stg *storage.Storage, rawSequencerURL, workerAddr, workerToken, w
 	if err != nil {
 		return nil, fmt.Errorf("failed to load vote verifier artifacts: %w", err)
 	}
+	if slices.Contains(jobTypes, workers.JobTypeAggregateBatch) {
+		s.aggregator, err = aggregator.Artifacts.LoadOrDownload(context.Background())
+		if err != nil {
+			return nil, fmt.Errorf("failed to load aggregator artifacts: %w", err)
+		}
+	}
+	if slices.Contains(jobTypes, workers.JobTypeStateTransition) {
+		s.stateTransition, err = statetransition.Artifacts.LoadOrDownload(context.Background())
+		if err != nil {
+			return nil, fmt.Errorf("failed to load statetransition artifacts: %w", err)
+		}
+	}
 
 	log.DebugTime("worker sequencer initialized", startTime,
 		"sequencerURL", sequencerURL,
 		"workerAddress", workerAddr,
 		"workerName", workerName,
+		"jobTypes", workers.JoinJobTypes(jobTypes),
 	)
 
 	return s, nil
@@ -181,10 +205,13 @@ func (s *Sequencer) startWorkerModeServices() error {
 // processWorkerJob fetches a job from master, processes it, and returns the result
 func (s *Sequencer) processWorkerJob() error {
 	// GET job from master
-	ballot, err := s.fetchJobFromMaster()
+	ballot, proofJob, err := s.fetchJobFromMaster()
 	if err != nil {
 		return err
 	}
+	if proofJob != nil {
+		return s.processProofJob(proofJob)
+	}
 
 	log.Debugw("processing worker job", "voteID", fmt.Sprintf("%x", ballot.VoteID))
 
@@ -251,32 +278,45 @@ func (s *Sequencer) fetchProcessFromMaster(processID types.ProcessID) error {
 	return nil
 }
 
-// fetchJobFromMaster performs GET request to master
-func (s *Sequencer) fetchJobFromMaster() (*storage.Ballot, error) {
+// fetchJobFromMaster performs GET request to master. It returns either a
+// ballot to verify or a proof job, depending on the job type returned.
+func (s *Sequencer) fetchJobFromMaster() (*storage.Ballot, *api.WorkerProofJob, error) {
 	uri := api.EndpointWithParam(api.WorkerJobEndpoint, api.SequencerUUIDURLParam, s.sequencerUUID)
 	uri = api.EndpointWithParam(uri, api.WorkerAddressQueryParam, s.workerAddress.String())
 	uri = api.EndpointWithParam(uri, api.WorkerTokenQueryParam, s.workerAuthtoken)
 	uri = api.EndpointWithParam(uri, api.WorkerNameQueryParam, s.workerName)
+	uri = api.EndpointWithParam(uri, api.WorkerJobsQueryParam, workers.JoinJobTypes(s.workerJobTypes))
 	seqUrl := fmt.Sprintf("%s%s", s.sequencerURL, uri)
 
 	client := &http.Client{Timeout: 20 * time.Second}
 	resp, err := client.Get(seqUrl)
 	if err != nil {
-		return nil, fmt.Errorf("failed to fetch job: %w", err)
+		return nil, nil, fmt.Errorf("failed to fetch job: %w", err)
 	}
 	defer func() { _ = resp.Body.Close() }()
 
 	switch resp.StatusCode {
 	case http.StatusNoContent:
-		return nil, ErrNoJobAvailable
+		return nil, nil, ErrNoJobAvailable
 	case http.StatusOK:
-		var ballot storage.Ballot
 		data, err := io.ReadAll(resp.Body) // Read the body to ensure it's consumed
 		if err != nil {
-			return nil, fmt.Errorf("failed to read response body: %w", err)
+			return nil, nil, fmt.Errorf("failed to read response body: %w", err)
+		}
+		if jobType := resp.Header.Get(api.WorkerJobTypeHeader); jobType != "" {
+			proofJob := &api.WorkerProofJob{}
+			if err := storage.DecodeArtifact(data, proofJob); err != nil {
+				return nil, nil, fmt.Errorf("failed to decode proof job: %w", err)
+			}
+			log.Debugw("fetched proof job from master",
+				"jobID", proofJob.ID.String(),
+				"type", proofJob.Type,
+				"processID", proofJob.ProcessID.String())
+			return nil, proofJob, nil
 		}
+		var ballot storage.Ballot
 		if err := storage.DecodeArtifact(data, &ballot); err != nil {
-			return nil, fmt.Errorf("failed to decode ballot: %w", err)
+			return nil, nil, fmt.Errorf("failed to decode ballot: %w", err)
 		}
 
 		// Register the process ID locally for ExistsProcessID check
@@ -286,24 +326,24 @@ func (s *Sequencer) fetchJobFromMaster() (*storage.Ballot, error) {
 			"voteID", fmt.Sprintf("%x", ballot.VoteID),
 			"processID", ballot.ProcessID.String())
 
-		return &ballot, nil
+		return &ballot, nil, nil
 	case http.StatusUnauthorized:
-		return nil, fmt.Errorf("unauthorized: invalid worker authentication")
+		return nil, nil, fmt.Errorf("unauthorized: invalid worker authentication")
 	case http.StatusForbidden:
-		return nil, fmt.Errorf("forbidden: worker is banned")
+		return nil, nil, fmt.Errorf("forbidden: worker is banned")
 	default:
 		body, err := io.ReadAll(resp.Body)
 		if err != nil {
-			return nil, fmt.Errorf("failed to read error response body: %w", err)
+			return nil, nil, fmt.Errorf("failed to read error response body: %w", err)
 		}
 		originalErr := &api.Error{}
 		if jsonErr := json.Unmarshal(body, originalErr); jsonErr != nil {
 			log.Debugw("failed to unmarshal error response", "error", jsonErr.Error())
 		}
 		if originalErr.Code == api.ErrWorkerNotAvailable.Code {
-			return nil, ErrNoJobAvailable
+			return nil, nil, ErrNoJobAvailable
 		}
-		return nil, fmt.Errorf("unexpected status code %d: %s", resp.StatusCode, originalErr.Err)
+		return nil, nil, fmt.Errorf("unexpected status code %d: %s", resp.StatusCode, originalErr.Err)
 	}
 }
 
@@ -348,3 +388,101 @@ func (s *Sequencer) submitJobToMaster(vb *storage.VerifiedBallot) error {
 
 	return nil
 }
+
+// processProofJob generates the proof of the witness of the proof job
+// provided and returns it to the master. If the proof can not be generated,
+// the error is returned to the master instead, so it does not wait for the
+// job timeout.
+func (s *Sequencer) processProofJob(job *api.WorkerProofJob) error {
+	log.Debugw("processing worker proof job", "jobID", job.ID.String(), "type", job.Type)
+	startTime := time.Now()
+	result := &api.WorkerProofJobResult{ID: job.ID}
+	proof, err := s.proveWitness(workers.JobType(job.Type), job.Witness)
+	if err != nil {
+		log.Warnw("failed to process proof job in worker mode",
+			"error", err.Error(),
+			"jobID", job.ID.String(),
+			"type", job.Type)
+		result.Error = err.Error()
+	} else {
+		log.InfoTime("worker proof generated", startTime,
+			"jobID", job.ID.String(),
+			"type", job.Type,
+			"processID", job.ProcessID.String())
+		if result.Proof, err = encodeProof(proof); err != nil {
+			result.Error = err.Error()
+		}
+	}
+	if err := s.submitProofJobToMaster(result); err != nil {
+		return err
+	}
+	if result.Error != "" {
+		return fmt.Errorf("failed to process proof job: %s", result.Error)
+	}
+	return nil
+}
+
+// proveWitness generates and verifies the proof of the binary encoded full
+// witness provided, with the circuit of the job type provided.
+func (s *Sequencer) proveWitness(jobType workers.JobType, data []byte) (groth16.Proof, error) {
+	if !slices.Contains(s.workerJobTypes, jobType) {
+		return nil, fmt.Errorf("job type %q not taken by this worker", jobType)
+	}
+	var runtime *circuits.CircuitRuntime
+	switch jobType {
+	case workers.JobTypeAggregateBatch:
+		runtime = s.aggregator
+	case workers.JobTypeStateTransition:
+		runtime = s.stateTransition
+	default:
+		return nil, fmt.Errorf("job type %q is not a proof job", jobType)
+	}
+	fullWitness, err := witness.New(runtime.Curve().ScalarField())
+	if err != nil {
+		return nil, fmt.Errorf("failed to create witness: %w", err)
+	}
+	if err := fullWitness.UnmarshalBinary(data); err != nil {
+		return nil, fmt.Errorf("failed to decode witness: %w", err)
+	}
+	return runtime.ProveAndVerifyWithWitness(fullWitness)
+}
+
+// submitProofJobToMaster performs POST request to master with the result of
+// a proof job
+func (s *Sequencer) submitProofJobToMaster(result *api.WorkerProofJobResult) error {
+	uri := api.EndpointWithParam(api.WorkerProofJobEndpoint, api.SequencerUUIDURLParam, s.sequencerUUID)
+	uri = api.EndpointWithParam(uri, api.WorkerAddressQueryParam, s.workerAddress.String())
+	uri = api.EndpointWithParam(uri, api.WorkerTokenQueryParam, s.workerAuthtoken)
+	uri = api.EndpointWithParam(uri, api.WorkerNameQueryParam, s.workerName)
+	seqUrl := fmt.Sprintf("%s%s", s.sequencerURL, uri)
+
+	body, err := storage.EncodeArtifact(result)
+	if err != nil {
+		return fmt.Errorf("failed to marshal proof job result: %w", err)
+	}
+
+	client := &http.Client{Timeout: 20 * time.Second}
+	resp, err := client.Post(seqUrl, "application/octet-stream", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to submit proof job: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("failed to submit proof job, status %d: %s", resp.StatusCode, body)
+	}
+
+	workerResponse := &api.WorkerProofJobResponse{}
+	if err := json.NewDecoder(resp.Body).Decode(workerResponse); err != nil {
+		return fmt.Errorf("failed to decode worker response: %w", err)
+	}
+
+	log.Infow("submitted proof job to master",
+		"jobID", result.ID.String(),
+		"type", workerResponse.Type,
+		"success", workerResponse.SuccessCount,
+		"failed", workerResponse.FailedCount,
+	)
+	return nil
+}
@@ -11,6 +11,7 @@ import (
 	"github.com/vocdoni/davinci-node/metadata"
 	"github.com/vocdoni/davinci-node/storage"
 	"github.com/vocdoni/davinci-node/web3"
+	"github.com/vocdoni/davinci-node/webhooks"
 	"github.com/vocdoni/davinci-node/workers"
 )
 
@@ -27,7 +28,15 @@ type APIService struct {
 	sequencerWorkersSeed       string
 	workersAuthtokenExpiration time.Duration
 	workersJobTimeout          time.Duration
+	workersProofJobTimeout     time.Duration
 	workersBanRules            *workers.WorkerBanRules // Custom ban rules for workers
+	webhooks                   *webhooks.Manager       // Webhook subscriptions manager
+	webhooksToken              string                  // Bearer token of the webhook subscription endpoints
+	dkgToken                   string                  // Bearer token required to create DKG sessions
+	adminToken                 string                  // Bearer token required by the admin endpoints
+	censusToken                string                  // Bearer token required by the census builder endpoints
+	rateLimits                 *api.RateLimitConfig    // Rate limits of the requests, nil if disabled
+	readOnly                   bool                    // Rejects the requests that write to the node
 }
 
 // NewAPI creates a new APIService instance.
@@ -63,6 +72,63 @@ func (as *APIService) SetWorkerConfig(seed string, tokenExpiration, timeout time
 	as.workersBanRules = banRules
 }
 
+// SetWorkerProofJobTimeout sets the time the workers have to return the
+// proof of an aggregation or state transition job.
+func (as *APIService) SetWorkerProofJobTimeout(timeout time.Duration) {
+	as.mu.Lock()
+	defer as.mu.Unlock()
+	as.workersProofJobTimeout = timeout
+}
+
+// SetWebhooks enables the webhook subscription endpoints of the API, which
+// require the bearer token provided.
+func (as *APIService) SetWebhooks(manager *webhooks.Manager, token string) {
+	as.mu.Lock()
+	defer as.mu.Unlock()
+	as.webhooks = manager
+	as.webhooksToken = token
+}
+
+// SetDKGToken enables the creation of DKG sessions through the API, which
+// requires the bearer token provided.
+func (as *APIService) SetDKGToken(token string) {
+	as.mu.Lock()
+	defer as.mu.Unlock()
+	as.dkgToken = token
+}
+
+// SetAdminToken enables the admin endpoints of the API, which require the
+// bearer token provided.
+func (as *APIService) SetAdminToken(token string) {
+	as.mu.Lock()
+	defer as.mu.Unlock()
+	as.adminToken = token
+}
+
+// SetCensusToken enables the census builder endpoints of the API, which
+// require the bearer token provided.
+func (as *APIService) SetCensusToken(token string) {
+	as.mu.Lock()
+	defer as.mu.Unlock()
+	as.censusToken = token
+}
+
+// SetRateLimits enables the rate limiting of the API requests with the
+// budgets provided.
+func (as *APIService) SetRateLimits(conf *api.RateLimitConfig) {
+	as.mu.Lock()
+	defer as.mu.Unlock()
+	as.rateLimits = conf
+}
+
+// SetReadOnly makes the API reject the requests that write to the node,
+// like the vote submissions, as observer nodes do.
+func (as *APIService) SetReadOnly(readOnly bool) {
+	as.mu.Lock()
+	defer as.mu.Unlock()
+	as.readOnly = readOnly
+}
+
 // Start begins the API server. It returns an error if the service
 // is already running or if it fails to start.
 func (as *APIService) Start(ctx context.Context) error {
@@ -85,8 +151,16 @@ func (as *APIService) Start(ctx context.Context) error {
 		SequencerWorkersSeed:       as.sequencerWorkersSeed,
 		WorkersAuthtokenExpiration: as.workersAuthtokenExpiration,
 		WorkerJobTimeout:           as.workersJobTimeout,
+		WorkerProofJobTimeout:      as.workersProofJobTimeout,
 		WorkerBanRules:             as.workersBanRules,
 		PinataConfig:               as.pinataConfig,
+		Webhooks:                   as.webhooks,
+		WebhooksToken:              as.webhooksToken,
+		DKGToken:                   as.dkgToken,
+		AdminToken:                 as.adminToken,
+		CensusToken:                as.censusToken,
+		RateLimits:                 as.rateLimits,
+		ReadOnly:                   as.readOnly,
 	})
 	if err != nil {
 		as.cancel = nil
@@ -2,6 +2,7 @@ package service
 
 import (
 	"context"
+	"slices"
 
 	"github.com/vocdoni/davinci-node/circuits"
 	"github.com/vocdoni/davinci-node/circuits/aggregator"
@@ -9,6 +10,7 @@ import (
 	"github.com/vocdoni/davinci-node/circuits/results"
 	"github.com/vocdoni/davinci-node/circuits/statetransition"
 	"github.com/vocdoni/davinci-node/circuits/voteverifier"
+	"github.com/vocdoni/davinci-node/workers"
 	"golang.org/x/sync/errgroup"
 )
 
@@ -26,12 +28,20 @@ func DownloadArtifacts(ctx context.Context, dataDir string) error {
 	return g.Wait()
 }
 
-func DownloadWorkerArtifacts(ctx context.Context, dataDir string) error {
+// DownloadWorkerArtifacts downloads the circuit artifacts required by a
+// worker to take the job types provided concurrently.
+func DownloadWorkerArtifacts(ctx context.Context, dataDir string, jobTypes []workers.JobType) error {
 	if dataDir != "" {
 		circuits.BaseDir = dataDir
 	}
 	g, ctx := errgroup.WithContext(ctx)
 	g.Go(func() error { return voteverifier.Artifacts.Download(ctx) })
 	g.Go(func() error { return ballotproof.Artifacts.Download(ctx) })
+	if slices.Contains(jobTypes, workers.JobTypeAggregateBatch) {
+		g.Go(func() error { return aggregator.Artifacts.Download(ctx) })
+	}
+	if slices.Contains(jobTypes, workers.JobTypeStateTransition) {
+		g.Go(func() error { return statetransition.Artifacts.Download(ctx) })
+	}
 	return g.Wait()
 }
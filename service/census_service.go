@@ -3,7 +3,9 @@ package service
 import (
 	"context"
 	"encoding/binary"
+	"errors"
 	"fmt"
+	"io/fs"
 	"strings"
 	"sync"
 	"time"
@@ -14,6 +16,7 @@ import (
 	"github.com/vocdoni/davinci-node/storage"
 	"github.com/vocdoni/davinci-node/types"
 	"github.com/vocdoni/davinci-node/web3"
+	"github.com/vocdoni/davinci-node/web3/rpc"
 )
 
 // CensusDownloaderConfig holds the configuration for the CensusDownloader. It
@@ -25,6 +28,8 @@ import (
 //   - Cooldown: time duration to wait before retrying a failed census download.
 //   - AttemptTimeout: maximum time allowed for a single download/import attempt.
 //   - Attempts: maximum number of attempts to download and import a census.
+//   - FileDir: directory from which the CSV and Parquet censuses can be
+//     imported with file:// URIs (disabled if empty).
 type CensusDownloaderConfig struct {
 	CleanUpInterval      time.Duration
 	OnchainCheckInterval time.Duration
@@ -33,6 +38,11 @@ type CensusDownloaderConfig struct {
 	AttemptTimeout       time.Duration
 	Attempts             int
 	ConcurrentDownloads  int
+	FileDir              string
+	// ChainClient returns the web3 client of the chain with the ID provided,
+	// used to import the censuses of ERC-20 and ERC-721 token holders. If it
+	// is not set, those censuses cannot be imported.
+	ChainClient func(chainID uint64) (*rpc.Client, error)
 }
 
 // DefaultCensusDownloaderConfig provides default values for the CensusDownloaderConfig.
@@ -96,14 +106,25 @@ func NewCensusDownloader(
 	if config.AttemptTimeout <= 0 {
 		config.AttemptTimeout = DefaultCensusDownloaderConfig.AttemptTimeout
 	}
+	tokenConfig := &census.TokenImporterConfig{}
+	if config.ChainClient != nil {
+		tokenConfig.Clients = func(chainID uint64) (census.TokenLogsClient, error) {
+			return config.ChainClient(chainID)
+		}
+	}
 	return &CensusDownloader{
 		queue:             make(chan internalCensus, 100),
 		contractsResolver: contractsResolver,
 		storage:           stg,
 		importer: census.NewCensusImporter(
 			stg,
+			// the CSV and Parquet importers go first, since the JSON
+			// importer takes any HTTP URI
+			census.CSVImporter(&census.CSVImporterConfig{FileDir: config.FileDir}),
+			census.ParquetImporter(&census.ParquetImporterConfig{FileDir: config.FileDir}),
 			census.JSONImporter(),
 			census.GraphQLImporter(nil),
+			census.TokenImporter(tokenConfig),
 		),
 		censusStatus:    make(map[string]DownloadStatus),
 		onchainCensuses: sync.Map{},
@@ -337,6 +358,11 @@ func (cd *CensusDownloader) processCensusDownload(ctx context.Context, census in
 					"origin", census.CensusOrigin.String(),
 					"address", census.ContractAddress.String())
 			}
+			cd.storage.PublishProcessEvent(storage.ProcessEventCensusDownloaded, census.ProcessID, map[string]any{
+				"censusRoot":   census.CensusRoot.String(),
+				"censusOrigin": census.CensusOrigin.String(),
+				"elements":     census.ProcessedElements,
+			})
 			return nil
 		}
 		if isTerminalDownloadError(importErr) {
@@ -546,6 +572,11 @@ func isTerminalDownloadError(err error) bool {
 	if err == nil {
 		return false
 	}
+	// retrying does not fix missing census files nor their invalid rows
+	var invalidRows *census.InvalidRowsError
+	if errors.As(err, &invalidRows) || errors.Is(err, fs.ErrNotExist) {
+		return true
+	}
 	errMsg := err.Error()
 	return strings.Contains(errMsg, "status code 404") ||
 		strings.Contains(errMsg, "non-200 response: 404")
@@ -28,6 +28,9 @@ type MockContracts struct {
 	registeredKnownIDs []types.ProcessID
 	processLookups     []types.ProcessID
 	chanPWC            chan *types.ProcessWithChanges
+	currentBlock       uint64
+	blockHashes        map[uint64]common.Hash
+	rewoundBlock       uint64
 	mu                 sync.Mutex
 }
 
@@ -37,6 +40,7 @@ func NewMockContracts() *MockContracts {
 		latestProcesses: make(map[types.ProcessID]*types.Process),
 		blobs:           make(map[common.Hash]*types.Blob),
 		chanPWC:         make(chan *types.ProcessWithChanges),
+		blockHashes:     make(map[uint64]common.Hash),
 	}
 }
 
@@ -53,6 +57,44 @@ func (m *MockContracts) MonitorProcessUpdates(
 	return m.chanPWC, nil
 }
 
+func (m *MockContracts) RewindProcessUpdates(block uint64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.rewoundBlock = block
+}
+
+// RewoundBlock returns the last block the process updates were rewound to.
+func (m *MockContracts) RewoundBlock() uint64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.rewoundBlock
+}
+
+func (m *MockContracts) CurrentBlock() uint64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.currentBlock
+}
+
+func (m *MockContracts) BlockHash(_ context.Context, number uint64) (common.Hash, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	hash, ok := m.blockHashes[number]
+	if !ok {
+		return common.Hash{}, fmt.Errorf("block %d not found", number)
+	}
+	return hash, nil
+}
+
+// SetBlock sets the canonical hash of a block, advancing the current block
+// if needed.
+func (m *MockContracts) SetBlock(number uint64, hash common.Hash) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.blockHashes[number] = hash
+	m.currentBlock = max(m.currentBlock, number)
+}
+
 func (m *MockContracts) CreateProcess(process *types.Process) (types.ProcessID, *common.Hash, error) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
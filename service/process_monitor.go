@@ -22,6 +22,7 @@ type ProcessMonitor struct {
 	censusDownloader *CensusDownloader
 	statesync        *StateSync
 	interval         time.Duration
+	reorgDepth       uint64 // Blocks after which the applied state transitions are final
 	mu               sync.Mutex
 	cancel           context.CancelFunc
 }
@@ -30,6 +31,9 @@ type ProcessMonitor struct {
 type ContractsService interface {
 	MonitorProcessUpdates(ctx context.Context, interval time.Duration, retries int, filters ...types.Web3FilterFn) (<-chan *types.ProcessWithChanges, error)
 	ProcessUpdatesFilters() []types.Web3FilterFn
+	RewindProcessUpdates(block uint64)
+	CurrentBlock() uint64
+	BlockHash(ctx context.Context, number uint64) (common.Hash, error)
 	CreateProcess(process *types.Process) (types.ProcessID, *common.Hash, error)
 	Process(processID types.ProcessID) (*types.Process, error)
 	ValidVersion(processID types.ProcessID) bool
@@ -60,6 +64,7 @@ func NewProcessMonitor(
 		censusDownloader: censusDownloader,
 		statesync:        stateSync,
 		interval:         interval,
+		reorgDepth:       DefaultReorgDepth,
 	}
 }
 
@@ -234,10 +239,14 @@ func (pm *ProcessMonitor) monitorProcesses(
 	ctx context.Context,
 	updatedProcChan <-chan *types.ProcessWithChanges,
 ) {
+	reorgTicker := time.NewTicker(pm.interval)
+	defer reorgTicker.Stop()
 	for {
 		select {
 		case <-ctx.Done():
 			return
+		case <-reorgTicker.C:
+			pm.checkReorgs(ctx)
 		case update, ok := <-updatedProcChan:
 			if !ok {
 				log.Warn("process updates channel closed")
@@ -330,6 +339,12 @@ func (pm *ProcessMonitor) newProcessCallback(ctx context.Context, update *types.
 			"processID", p.ID.String(),
 			"stateRoot", p.StateRoot.HexBytes().String(),
 			"censusRoot", p.Census.CensusRoot.String())
+		pm.storage.PublishProcessEvent(storage.ProcessEventCreated, *p.ID, map[string]any{
+			"status":     p.Status.String(),
+			"startTime":  p.StartTime.Unix(),
+			"endTime":    p.StartTime.Add(p.Duration).Unix(),
+			"censusRoot": p.Census.CensusRoot.String(),
+		})
 	}
 
 	// If the process is active, download census and import it first, then store
@@ -404,6 +419,9 @@ func (pm *ProcessMonitor) statusChangeCallback(update *types.ProcessWithChanges)
 				"error", err.Error())
 			return
 		}
+		pm.storage.PublishProcessEvent(storage.ProcessEventResults, update.ProcessID, map[string]any{
+			"result": process.Result,
+		})
 		// Clean up any stale votes
 		if err := pm.storage.CleanProcessStaleVotes(update.ProcessID); err != nil {
 			log.Warnw("failed to clean stale votes after process finalization",
@@ -428,7 +446,24 @@ func (pm *ProcessMonitor) stateRootChangeCallback(update *types.ProcessWithChang
 		"newStateRoot", update.NewStateRoot.String(),
 		"newVotersCount", update.NewVotersCount.String(),
 		"newOverwrittenVotesCount", update.NewOverwrittenVotesCount.String())
-	if err := pm.storage.UpdateProcess(update.ProcessID, storage.ProcessUpdateCallbackSetStateRoot(
+	// keep the previous values to roll them back if the block of the
+	// transition is reorganized out of the chain
+	applied := &storage.AppliedStateTransition{
+		ProcessID:    update.ProcessID,
+		BlockNumber:  update.BlockNumber,
+		BlockHash:    update.BlockHash.Bytes(),
+		LogIndex:     update.LogIndex,
+		OldStateRoot: update.OldStateRoot,
+		NewStateRoot: update.NewStateRoot,
+	}
+	if update.TxHash != nil {
+		applied.TxHash = update.TxHash.Bytes()
+	}
+	if err := pm.storage.UpdateProcess(update.ProcessID, func(p *types.Process) error {
+		applied.OldVotersCount = p.VotersCount
+		applied.OldOverwrittenVotesCount = p.OverwrittenVotesCount
+		return nil
+	}, storage.ProcessUpdateCallbackSetStateRoot(
 		update.NewStateRoot,
 		update.NewVotersCount,
 		update.NewOverwrittenVotesCount,
@@ -436,6 +471,7 @@ func (pm *ProcessMonitor) stateRootChangeCallback(update *types.ProcessWithChang
 		log.Errorw(err, fmt.Sprintf("failed to update process %s state root", update.ProcessID.String()))
 		return
 	}
+	pm.trackStateTransition(applied)
 	// Notify StateSync service for blob fetching and state reconstruction (non-blocking)
 	if pm.statesync != nil {
 		pm.statesync.Notify(update)
@@ -470,6 +506,32 @@ func (pm *ProcessMonitor) censusRootChangeCallback(ctx context.Context, update *
 		"processID", update.ProcessID.String(),
 		"newCensusRoot", update.NewCensusRoot.String(),
 		"newCensusURI", update.NewCensusURI)
+	// CSP censuses have nothing to download, their census root is the hash
+	// of the CSP public key, so updating it rotates the key of the process.
+	// From then on, only the proofs signed by the new key are accepted, and
+	// the ballots already settled with the previous key remain untouched.
+	// Keeping the pending ballots of the previous key is not supported: the
+	// state transition proves every census proof against the current census
+	// root, so they are always invalidated by the aggregator. Note that the
+	// current ProcessRegistry contracts reject census updates of CSP
+	// processes, so this path is only reached with updated contracts.
+	if process.Census.CensusOrigin.IsCSP() {
+		if err := pm.storage.UpdateProcess(update.ProcessID, storage.ProcessUpdateCallbackSetCensusRoot(
+			update.NewCensusRoot,
+			update.NewCensusURI,
+		)); err != nil {
+			log.Warnw("failed to rotate process CSP key",
+				"processID", update.ProcessID.String(),
+				"error", err.Error())
+			return
+		}
+		log.Infow("process CSP key rotated",
+			"processID", update.ProcessID.String(),
+			"oldCensusRoot", process.Census.CensusRoot.String(),
+			"censusRoot", update.NewCensusRoot.String(),
+			"censusURI", update.NewCensusURI)
+		return
+	}
 	newCensus := &types.Census{
 		CensusOrigin:    process.Census.CensusOrigin,
 		CensusRoot:      update.NewCensusRoot,
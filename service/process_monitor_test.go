@@ -342,6 +342,38 @@ func TestProcessMonitorIgnoresForeignRuntimeEvents(t *testing.T) {
 	c.Assert(storedForeignProcess.OverwrittenVotesCount, qt.DeepEquals, existingForeignProcess.OverwrittenVotesCount)
 }
 
+func TestProcessMonitorRotatesCSPKey(t *testing.T) {
+	c := qt.New(t)
+
+	store := storage.New(memdb.New())
+	c.Cleanup(store.Close)
+
+	contracts := NewMockContracts()
+	monitor := NewProcessMonitor(contracts, defaultMockProcessIDVersion, store, nil, nil, time.Second)
+
+	processID := testMonitorProcessID(defaultMockProcessIDVersion, 8)
+	process := testutil.RandomProcess(processID)
+	process.Census = testutil.RandomCensus(types.CensusOriginCSPEdDSABabyJubJubV1)
+	c.Assert(store.NewProcess(process), qt.IsNil)
+
+	// the census downloader is not set, so the rotation must not try to
+	// download anything
+	newRoot := types.HexBytes(testutil.RandomCensusRoot().Bytes())
+	monitor.censusRootChangeCallback(context.Background(), &types.ProcessWithChanges{
+		ProcessID: processID,
+		CensusRootChange: &types.CensusRootChange{
+			NewCensusRoot: newRoot,
+			NewCensusURI:  "https://csp.example.org/new",
+		},
+	})
+
+	stored, err := store.Process(processID)
+	c.Assert(err, qt.IsNil)
+	c.Assert(stored.Census.CensusOrigin, qt.Equals, types.CensusOriginCSPEdDSABabyJubJubV1)
+	c.Assert(stored.Census.CensusRoot, qt.DeepEquals, newRoot)
+	c.Assert(stored.Census.CensusURI, qt.Equals, "https://csp.example.org/new")
+}
+
 func testMonitorProcessID(version [4]byte, nonce uint64) types.ProcessID {
 	return types.NewProcessID(testutil.DeterministicAddress(nonce), version, nonce)
 }
@@ -0,0 +1,196 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/vocdoni/davinci-node/db"
+	"github.com/vocdoni/davinci-node/log"
+	"github.com/vocdoni/davinci-node/state"
+	"github.com/vocdoni/davinci-node/storage"
+	"github.com/vocdoni/davinci-node/types"
+)
+
+// DefaultReorgDepth is the default number of blocks on top of the block of an
+// applied state transition after which it is considered final and its block
+// is not checked anymore.
+const DefaultReorgDepth = 64
+
+// reorgQueryTimeout is the timeout to get the hash of a block.
+const reorgQueryTimeout = 10 * time.Second
+
+// SetReorgDepth sets the number of blocks on top of the block of an applied
+// state transition after which it is considered final. Until then, the
+// block is checked to be still canonical, and the transition is rolled back
+// if it is not. Zero disables the reorg detection.
+func (pm *ProcessMonitor) SetReorgDepth(depth uint64) {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+	pm.reorgDepth = depth
+}
+
+// trackStateTransition tracks a state transition applied from a chain event
+// until its block is final.
+func (pm *ProcessMonitor) trackStateTransition(applied *storage.AppliedStateTransition) {
+	pm.mu.Lock()
+	depth := pm.reorgDepth
+	pm.mu.Unlock()
+	if depth == 0 || applied.BlockNumber == 0 {
+		return
+	}
+	if err := pm.storage.SetAppliedStateTransition(applied); err != nil {
+		log.Warnw("failed to track applied state transition",
+			"processID", applied.ProcessID.String(),
+			"block", applied.BlockNumber,
+			"error", err.Error())
+	}
+}
+
+// checkReorgs checks that the blocks of the tracked state transitions are
+// still canonical, rolling back the processes whose transitions were
+// reorganized out of the chain, and stops tracking the final ones.
+func (pm *ProcessMonitor) checkReorgs(ctx context.Context) {
+	pm.mu.Lock()
+	depth := pm.reorgDepth
+	pm.mu.Unlock()
+	if depth == 0 {
+		return
+	}
+	tracked, err := pm.storage.AppliedStateTransitions()
+	if err != nil {
+		log.Warnw("failed to list applied state transitions", "error", err.Error())
+		return
+	}
+	current := pm.contracts.CurrentBlock()
+	hashes := make(map[uint64]common.Hash)
+	for processID, applied := range tracked {
+		if !pm.ownsProcess(processID) {
+			continue
+		}
+		for i, t := range applied {
+			hash, ok := hashes[t.BlockNumber]
+			if !ok {
+				ctxQuery, cancel := context.WithTimeout(ctx, reorgQueryTimeout)
+				hash, err = pm.contracts.BlockHash(ctxQuery, t.BlockNumber)
+				cancel()
+				if err != nil {
+					log.Warnw("failed to check applied state transition block",
+						"processID", processID.String(),
+						"block", t.BlockNumber,
+						"error", err.Error())
+					break
+				}
+				hashes[t.BlockNumber] = hash
+			}
+			if !bytes.Equal(hash.Bytes(), t.BlockHash) {
+				pm.rollbackStateTransitions(processID, applied[i:])
+				break
+			}
+			if t.BlockNumber+depth <= current {
+				if err := pm.storage.DeleteAppliedStateTransition(t); err != nil {
+					log.Warnw("failed to stop tracking final state transition",
+						"processID", processID.String(),
+						"block", t.BlockNumber,
+						"error", err.Error())
+				}
+			}
+		}
+	}
+}
+
+// rollbackStateTransitions rolls back the state transitions of a process
+// provided, whose blocks were reorganized out of the chain, to the last
+// canonical state root. The transitions settled by this node are queued
+// again to be settled, and the process updates are filtered again from the
+// first reorganized block, so the events of the new canonical blocks are
+// applied.
+func (pm *ProcessMonitor) rollbackStateTransitions(processID types.ProcessID, reorged []*storage.AppliedStateTransition) {
+	canonical := reorged[0]
+	log.Warnw("chain reorg detected, rolling back state transitions",
+		"processID", processID.String(),
+		"block", canonical.BlockNumber,
+		"reorgedTransitions", len(reorged),
+		"canonicalStateRoot", canonical.OldStateRoot.String())
+
+	// Queue again the batches settled by this node. The state of the node
+	// already includes them, so it is only rolled back if some transition
+	// was settled by another sequencer.
+	requeued := 0
+	discardedBlocks := make([]common.Hash, 0, len(reorged))
+	for _, t := range reorged {
+		discardedBlocks = append(discardedBlocks, common.BytesToHash(t.BlockHash))
+		err := pm.storage.RequeueSettledStateTransition(processID, t.NewStateRoot.MathBigInt())
+		switch {
+		case err == nil:
+			requeued++
+		case !errors.Is(err, storage.ErrNotFound):
+			log.Warnw("failed to requeue reorged state transition batch",
+				"processID", processID.String(),
+				"newStateRoot", t.NewStateRoot.String(),
+				"error", err.Error())
+		}
+	}
+	if requeued < len(reorged) {
+		var err error
+		if pm.statesync != nil {
+			err = pm.statesync.Rollback(processID, canonical.OldStateRoot.MathBigInt(), discardedBlocks...)
+		} else {
+			err = rollbackStateRoot(pm.storage.StateDB(), processID, canonical.OldStateRoot.MathBigInt())
+		}
+		if err != nil {
+			log.Errorw(err, fmt.Sprintf("failed to roll back state of process %s", processID.String()))
+		}
+	}
+
+	// Restore the on-chain values of the process before the reorged
+	// transitions and stop tracking them
+	if err := pm.storage.UpdateProcess(processID, storage.ProcessUpdateCallbackSetStateRoot(
+		canonical.OldStateRoot,
+		canonical.OldVotersCount,
+		canonical.OldOverwrittenVotesCount,
+	)); err != nil {
+		log.Errorw(err, fmt.Sprintf("failed to roll back process %s state root", processID.String()))
+		return
+	}
+	for _, t := range reorged {
+		if err := pm.storage.DeleteAppliedStateTransition(t); err != nil {
+			log.Warnw("failed to stop tracking reorged state transition",
+				"processID", processID.String(),
+				"block", t.BlockNumber,
+				"error", err.Error())
+		}
+	}
+	pm.contracts.RewindProcessUpdates(canonical.BlockNumber)
+
+	pm.storage.PublishProcessEvent(storage.ProcessEventStateTransitionReorged, processID, map[string]any{
+		"block":              canonical.BlockNumber,
+		"stateRoot":          canonical.OldStateRoot.String(),
+		"reorgedTransitions": len(reorged),
+		"requeuedBatches":    requeued,
+	})
+	log.Infow("state transitions rolled back",
+		"processID", processID.String(),
+		"stateRoot", canonical.OldStateRoot.String(),
+		"requeuedBatches", requeued)
+}
+
+// rollbackStateRoot moves the state of a process back to the root provided,
+// which must exist in the local state.
+func rollbackStateRoot(stateDB db.Database, processID types.ProcessID, root *big.Int) error {
+	if _, err := state.LoadSnapshotOnRoot(stateDB, processID, root); err != nil {
+		return fmt.Errorf("failed to load state at root %s: %w", root.String(), err)
+	}
+	st, err := state.New(stateDB, processID)
+	if err != nil {
+		return fmt.Errorf("failed to open state for process %s: %w", processID.String(), err)
+	}
+	if err := st.SetRootAsBigInt(root); err != nil {
+		return fmt.Errorf("failed to set state root %s: %w", root.String(), err)
+	}
+	return nil
+}
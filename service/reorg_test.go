@@ -0,0 +1,99 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	qt "github.com/frankban/quicktest"
+	"github.com/vocdoni/arbo/memdb"
+	"github.com/vocdoni/davinci-node/internal/testutil"
+	"github.com/vocdoni/davinci-node/state"
+	"github.com/vocdoni/davinci-node/storage"
+	"github.com/vocdoni/davinci-node/types"
+)
+
+func TestProcessMonitorReorgRollback(t *testing.T) {
+	c := qt.New(t)
+	ctx := context.Background()
+
+	store := storage.New(memdb.New())
+	c.Cleanup(store.Close)
+
+	contracts := NewMockContracts()
+	monitor := NewProcessMonitor(contracts, defaultMockProcessIDVersion, store, nil, nil, time.Second)
+
+	processID := testMonitorProcessID(defaultMockProcessIDVersion, 6)
+	process := testutil.RandomProcess(processID)
+	process.VotersCount = types.NewInt(0)
+	process.OverwrittenVotesCount = types.NewInt(0)
+	c.Assert(store.NewProcess(process), qt.IsNil)
+	canonicalRoot := process.StateRoot
+
+	// a state transition is applied from block 10
+	txHash := common.HexToHash("0x01")
+	contracts.SetBlock(10, common.HexToHash("0xa1"))
+	monitor.stateRootChangeCallback(&types.ProcessWithChanges{
+		ProcessID: processID,
+		StateRootChange: &types.StateRootChange{
+			OldStateRoot:             canonicalRoot,
+			NewStateRoot:             testutil.DeterministicStateRoot(20),
+			NewVotersCount:           types.NewInt(5),
+			NewOverwrittenVotesCount: types.NewInt(1),
+			TxHash:                   &txHash,
+			BlockNumber:              10,
+			BlockHash:                common.HexToHash("0xa1"),
+		},
+	})
+	stored, err := store.Process(processID)
+	c.Assert(err, qt.IsNil)
+	c.Assert(stored.StateRoot, qt.DeepEquals, testutil.DeterministicStateRoot(20))
+
+	// while the block is canonical nothing changes
+	monitor.checkReorgs(ctx)
+	tracked, err := store.AppliedStateTransitions()
+	c.Assert(err, qt.IsNil)
+	c.Assert(tracked[processID], qt.HasLen, 1)
+
+	// the block is reorganized out, so the process is rolled back
+	contracts.SetBlock(10, common.HexToHash("0xb1"))
+	monitor.checkReorgs(ctx)
+	stored, err = store.Process(processID)
+	c.Assert(err, qt.IsNil)
+	c.Assert(stored.StateRoot, qt.DeepEquals, canonicalRoot)
+	c.Assert(stored.VotersCount, qt.DeepEquals, types.NewInt(0))
+	c.Assert(stored.OverwrittenVotesCount, qt.DeepEquals, types.NewInt(0))
+	st, err := state.New(store.StateDB(), processID)
+	c.Assert(err, qt.IsNil)
+	root, err := st.RootAsBigInt()
+	c.Assert(err, qt.IsNil)
+	c.Assert(root.Cmp(canonicalRoot.MathBigInt()), qt.Equals, 0)
+	c.Assert(contracts.RewoundBlock(), qt.Equals, uint64(10))
+	tracked, err = store.AppliedStateTransitions()
+	c.Assert(err, qt.IsNil)
+	c.Assert(tracked[processID], qt.HasLen, 0)
+
+	// the transitions deep enough are final and not tracked anymore
+	contracts.SetBlock(11, common.HexToHash("0xa2"))
+	monitor.stateRootChangeCallback(&types.ProcessWithChanges{
+		ProcessID: processID,
+		StateRootChange: &types.StateRootChange{
+			OldStateRoot:             canonicalRoot,
+			NewStateRoot:             testutil.DeterministicStateRoot(21),
+			NewVotersCount:           types.NewInt(5),
+			NewOverwrittenVotesCount: types.NewInt(1),
+			TxHash:                   &txHash,
+			BlockNumber:              11,
+			BlockHash:                common.HexToHash("0xa2"),
+		},
+	})
+	contracts.SetBlock(11+DefaultReorgDepth, common.HexToHash("0xa3"))
+	monitor.checkReorgs(ctx)
+	tracked, err = store.AppliedStateTransitions()
+	c.Assert(err, qt.IsNil)
+	c.Assert(tracked[processID], qt.HasLen, 0)
+	stored, err = store.Process(processID)
+	c.Assert(err, qt.IsNil)
+	c.Assert(stored.StateRoot, qt.DeepEquals, testutil.DeterministicStateRoot(21))
+}
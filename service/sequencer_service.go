@@ -12,12 +12,16 @@ import (
 	"github.com/vocdoni/davinci-node/log"
 	"github.com/vocdoni/davinci-node/sequencer"
 	"github.com/vocdoni/davinci-node/storage"
+	"github.com/vocdoni/davinci-node/types"
 	"github.com/vocdoni/davinci-node/web3"
 )
 
 const (
 	// SequencerStatsEndpoint is the API endpoint for retrieving sequencer statistics.
 	SequencerStatsEndpoint = "/sequencer/stats"
+	// SequencerSettlementEndpoint is the API endpoint for retrieving the
+	// processes whose state transitions are held by the fees.
+	SequencerSettlementEndpoint = "/sequencer/settlement"
 )
 
 // StatsMonitorInterval is the interval at which process statistics are logged.
@@ -61,6 +65,14 @@ func (ss *SequencerService) Start(ctx context.Context) error {
 	if ss.api != nil {
 		ss.api.Router().Get(SequencerStatsEndpoint, ss.statsHandler)
 		log.Infow("register handler", "endpoint", SequencerStatsEndpoint, "method", "GET")
+		ss.api.Router().Get(SequencerSettlementEndpoint, ss.settlementHandler)
+		log.Infow("register handler", "endpoint", SequencerSettlementEndpoint, "method", "GET")
+		// Let the admin endpoints pause and resume the sequencing of processes
+		ss.api.SetSequencingController(ss.Sequencer)
+		// Offload the aggregation and state transition proofs to the workers
+		if jm := ss.api.JobsManager(); jm != nil {
+			ss.Sequencer.SetProofJobsManager(jm)
+		}
 	}
 
 	return nil
@@ -184,3 +196,41 @@ func (ss *SequencerService) statsHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 }
+
+// settlementHandler is an HTTP handler that returns the processes whose state
+// transitions are held by the fees, with the expected settlement delay.
+func (ss *SequencerService) settlementHandler(w http.ResponseWriter, r *http.Request) {
+	now := time.Now()
+	resp := &api.SequencerSettlementResponse{Processes: []*api.ProcessSettlement{}}
+	for _, status := range ss.Sequencer.SettlementStatuses() {
+		settlement := &api.ProcessSettlement{
+			ProcessID:     status.ProcessID,
+			Reason:        status.Reason,
+			HeldSince:     status.HeldSince,
+			Deadline:      status.Deadline,
+			ExpectedDelay: int64(status.ExpectedDelay(now).Seconds()),
+		}
+		if status.GasTipCap != nil {
+			settlement.GasTipCap = new(types.BigInt).SetBigInt(status.GasTipCap)
+		}
+		if status.BlobBaseFee != nil {
+			settlement.BlobBaseFee = new(types.BigInt).SetBigInt(status.BlobBaseFee)
+		}
+		resp.Processes = append(resp.Processes, settlement)
+	}
+	jdata, err := json.Marshal(resp)
+	if err != nil {
+		api.ErrMarshalingServerJSONFailed.WithErr(err).Write(w)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if _, err = w.Write(jdata); err != nil {
+		log.Warnw("failed to write http response", "error", err)
+		return
+	}
+	if _, err := w.Write([]byte("\n")); err != nil {
+		log.Warnw("failed to write on response", "error", err)
+		return
+	}
+}
@@ -3,8 +3,10 @@ package service
 import (
 	"context"
 	"fmt"
+	"math/big"
 	"sync"
 
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/vocdoni/davinci-node/log"
 	"github.com/vocdoni/davinci-node/state"
 	"github.com/vocdoni/davinci-node/storage"
@@ -24,8 +26,10 @@ type StateSync struct {
 }
 
 type stateSyncWorker struct {
-	queue   chan *types.ProcessWithChanges
-	applyFn func(context.Context, *types.ProcessWithChanges) error
+	queue     chan *types.ProcessWithChanges
+	applyFn   func(context.Context, *types.ProcessWithChanges) error
+	mu        sync.Mutex               // Serializes the syncs and the rollbacks
+	discarded map[common.Hash]struct{} // Blocks reorganized out of the chain
 }
 
 // NewStateSync creates a new StateSync service.
@@ -76,6 +80,21 @@ func (ss *StateSync) Notify(process *types.ProcessWithChanges) {
 	}
 }
 
+// Rollback moves the state of the process back to the root provided, after
+// the blocks provided were reorganized out of the chain. The pending syncs of
+// the state transitions of those blocks are discarded.
+func (ss *StateSync) Rollback(processID types.ProcessID, root *big.Int, blocks ...common.Hash) error {
+	if v, ok := ss.workers.Load(processID); ok {
+		ssw := v.(*stateSyncWorker)
+		ssw.mu.Lock()
+		defer ssw.mu.Unlock()
+		for _, block := range blocks {
+			ssw.discarded[block] = struct{}{}
+		}
+	}
+	return rollbackStateRoot(ss.storage.StateDB(), processID, root)
+}
+
 // consumeQueue listens for state transition notifications and dispatches goroutines for each of them.
 func (ss *StateSync) consumeQueue(ctx context.Context) {
 	for {
@@ -159,6 +178,15 @@ func (ss *StateSync) fetchBlobAndApply(ctx context.Context, process *types.Proce
 			process.NewStateRoot.String(), newRoot.String())
 	}
 
+	// Record the receipts of the votes settled by the transaction, so their
+	// voters can verify them on this node too
+	if err := ss.storage.SetVoteReceipts(process.ProcessID, newRoot, process.TxHash.Bytes(), sidecar); err != nil {
+		log.Warnw("failed to record vote receipts",
+			"processID", process.ProcessID.String(),
+			"txHash", process.TxHash.String(),
+			"error", err.Error())
+	}
+
 	log.Debugw("successfully synced state from blob",
 		"processID", process.ProcessID.String(),
 		"txHash", process.TxHash.String(),
@@ -199,8 +227,9 @@ func (ss *StateSync) getOrCreateWorker(ctx context.Context, processID types.Proc
 
 func newStateSyncWorker(applyFn func(context.Context, *types.ProcessWithChanges) error) *stateSyncWorker {
 	return &stateSyncWorker{
-		queue:   make(chan *types.ProcessWithChanges, 100),
-		applyFn: applyFn,
+		queue:     make(chan *types.ProcessWithChanges, 100),
+		applyFn:   applyFn,
+		discarded: make(map[common.Hash]struct{}),
 	}
 }
 
@@ -225,18 +254,33 @@ func (ssw *stateSyncWorker) run(ctx context.Context) {
 		case <-ctx.Done():
 			return
 		case process := <-ssw.queue:
-			if err := ssw.applyFn(ctx, process); err != nil {
-				log.Warnw("statesync failed",
-					"error", err,
-					"processID", process.ProcessID.String(),
-					"txHash", process.TxHash.String(),
-					"oldStateRoot", process.OldStateRoot.String(),
-					"newStateRoot", process.NewStateRoot.String())
-			}
+			ssw.apply(ctx, process)
 		}
 	}
 }
 
+// apply syncs the state transition provided, unless its block was
+// reorganized out of the chain.
+func (ssw *stateSyncWorker) apply(ctx context.Context, process *types.ProcessWithChanges) {
+	ssw.mu.Lock()
+	defer ssw.mu.Unlock()
+	if _, discarded := ssw.discarded[process.BlockHash]; discarded {
+		log.Debugw("statesync discarded, block reorganized out",
+			"processID", process.ProcessID.String(),
+			"block", process.BlockNumber,
+			"newStateRoot", process.NewStateRoot.String())
+		return
+	}
+	if err := ssw.applyFn(ctx, process); err != nil {
+		log.Warnw("statesync failed",
+			"error", err,
+			"processID", process.ProcessID.String(),
+			"txHash", process.TxHash.String(),
+			"oldStateRoot", process.OldStateRoot.String(),
+			"newStateRoot", process.NewStateRoot.String())
+	}
+}
+
 func (ssw *stateSyncWorker) enqueue(process *types.ProcessWithChanges) error {
 	select {
 	case ssw.queue <- process:
@@ -0,0 +1,249 @@
+package storage
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/vocdoni/davinci-node/db"
+	"github.com/vocdoni/davinci-node/db/prefixeddb"
+	"github.com/vocdoni/davinci-node/types"
+)
+
+// Queue identifies one of the queues of the sequencing pipeline.
+type Queue string
+
+const (
+	// QueuePending is the queue of ballots waiting to be verified.
+	QueuePending Queue = "pending"
+	// QueueVerified is the queue of verified ballots waiting to be aggregated.
+	QueueVerified Queue = "verified"
+	// QueueAggregated is the queue of aggregated batches waiting for the
+	// state transition.
+	QueueAggregated Queue = "aggregated"
+	// QueueStateTransitions is the queue of state transition batches waiting
+	// to be settled on chain.
+	QueueStateTransitions Queue = "stateTransitions"
+)
+
+// Queues is the list of the queues of the sequencing pipeline, in the order
+// the items go through them.
+var Queues = []Queue{QueuePending, QueueVerified, QueueAggregated, QueueStateTransitions}
+
+// ErrUnknownQueue is returned when the queue provided is not one of Queues.
+var ErrUnknownQueue = errors.New("unknown queue")
+
+// prefix returns the storage prefix of the queue.
+func (q Queue) prefix() ([]byte, error) {
+	switch q {
+	case QueuePending:
+		return ballotPrefix, nil
+	case QueueVerified:
+		return verifiedBallotPrefix, nil
+	case QueueAggregated:
+		return aggregBatchPrefix, nil
+	case QueueStateTransitions:
+		return stateTransitionPrefix, nil
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnknownQueue, q)
+	}
+}
+
+// QueueSize contains the number of items of a queue and how many of them
+// are reserved by a running task.
+type QueueSize struct {
+	Total    int `json:"total"`
+	Reserved int `json:"reserved"`
+}
+
+// QueueItem describes an item of a queue without its proofs and ballots,
+// which are not useful to inspect the state of the pipeline.
+type QueueItem struct {
+	Key        types.HexBytes  `json:"key"`
+	ProcessID  types.ProcessID `json:"processId"`
+	VoteIDs    []types.VoteID  `json:"voteIds"`
+	Reserved   bool            `json:"reserved"`
+	ReservedAt time.Time       `json:"reservedAt,omitzero"`
+	Attempts   int             `json:"attempts,omitempty"`
+}
+
+// QueueSizes returns the size of every queue of the sequencing pipeline.
+func (s *Storage) QueueSizes() (map[Queue]QueueSize, error) {
+	s.globalLock.Lock()
+	defer s.globalLock.Unlock()
+
+	sizes := make(map[Queue]QueueSize, len(Queues))
+	for _, queue := range Queues {
+		prefix, _ := queue.prefix()
+		size := QueueSize{}
+		if err := prefixeddb.NewPrefixedReader(s.db, prefix).Iterate(nil, func(k, _ []byte) bool {
+			size.Total++
+			if s.isReserved(prefix, k) {
+				size.Reserved++
+			}
+			return true
+		}); err != nil {
+			return nil, fmt.Errorf("iterate %s queue: %w", queue, err)
+		}
+		sizes[queue] = size
+	}
+	return sizes, nil
+}
+
+// QueueItems returns up to limit items of the queue provided. If processID
+// is not nil, only the items of that process are returned. A limit lower
+// than 1 returns all the items.
+func (s *Storage) QueueItems(queue Queue, processID *types.ProcessID, limit int) ([]QueueItem, error) {
+	prefix, err := queue.prefix()
+	if err != nil {
+		return nil, err
+	}
+
+	s.globalLock.Lock()
+	defer s.globalLock.Unlock()
+
+	items := []QueueItem{}
+	var decodeErr error
+	if err := prefixeddb.NewPrefixedReader(s.db, prefix).Iterate(nil, func(k, v []byte) bool {
+		// the keys of the verified, aggregated and state transition queues
+		// start with the process ID, so they can be filtered without decoding
+		if processID != nil && queue != QueuePending && !bytes.HasPrefix(k, processID.Bytes()) {
+			return true
+		}
+		item, err := s.queueItem(queue, prefix, bytes.Clone(k), v)
+		if err != nil {
+			decodeErr = err
+			return false
+		}
+		if processID != nil && item.ProcessID != *processID {
+			return true
+		}
+		items = append(items, *item)
+		return limit < 1 || len(items) < limit
+	}); err != nil {
+		return nil, fmt.Errorf("iterate %s queue: %w", queue, err)
+	}
+	if decodeErr != nil {
+		return nil, decodeErr
+	}
+	return items, nil
+}
+
+// QueueItem returns the item of the queue provided with the key provided.
+// It returns ErrNotFound if the item does not exist.
+func (s *Storage) QueueItem(queue Queue, key []byte) (*QueueItem, error) {
+	prefix, err := queue.prefix()
+	if err != nil {
+		return nil, err
+	}
+
+	s.globalLock.Lock()
+	defer s.globalLock.Unlock()
+
+	val, err := prefixeddb.NewPrefixedReader(s.db, prefix).Get(key)
+	if err != nil {
+		if errors.Is(err, db.ErrKeyNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("get %s queue item: %w", queue, err)
+	}
+	return s.queueItem(queue, prefix, key, val)
+}
+
+// queueItem decodes the value of a queue item and returns its description.
+// It assumes the caller already holds the globalLock.
+func (s *Storage) queueItem(queue Queue, prefix, key, val []byte) (*QueueItem, error) {
+	item := &QueueItem{Key: key}
+	switch queue {
+	case QueuePending:
+		var b Ballot
+		if err := DecodeArtifact(val, &b); err != nil {
+			return nil, fmt.Errorf("decode ballot: %w", err)
+		}
+		item.ProcessID = b.ProcessID
+		item.VoteIDs = []types.VoteID{b.VoteID}
+	case QueueVerified:
+		var vb VerifiedBallot
+		if err := DecodeArtifact(val, &vb); err != nil {
+			return nil, fmt.Errorf("decode verified ballot: %w", err)
+		}
+		item.ProcessID = vb.ProcessID
+		item.VoteIDs = []types.VoteID{vb.VoteID}
+	case QueueAggregated:
+		var abb AggregatorBallotBatch
+		if err := DecodeArtifact(val, &abb); err != nil {
+			return nil, fmt.Errorf("decode agg batch: %w", err)
+		}
+		item.ProcessID = abb.ProcessID
+		item.Attempts = abb.Attempts
+		for _, b := range abb.Ballots {
+			item.VoteIDs = append(item.VoteIDs, b.VoteID)
+		}
+	case QueueStateTransitions:
+		var stb StateTransitionBatch
+		if err := DecodeArtifact(val, &stb); err != nil {
+			return nil, fmt.Errorf("decode state transition batch: %w", err)
+		}
+		item.ProcessID = stb.ProcessID
+		for _, b := range stb.Ballots {
+			item.VoteIDs = append(item.VoteIDs, b.VoteID)
+		}
+	}
+	if rawReservation, err := s.reservationReader(prefix).Get(key); err == nil {
+		item.Reserved = true
+		r := &reservationRecord{}
+		if err := DecodeArtifact(rawReservation, r); err == nil {
+			item.ReservedAt = time.Unix(r.Timestamp, 0)
+		}
+	}
+	return item, nil
+}
+
+// ReleaseReservation removes the reservation of the item of the queue
+// provided, so it can be picked up again by the sequencer. It returns
+// ErrNotFound if the item is not reserved.
+func (s *Storage) ReleaseReservation(queue Queue, key []byte) error {
+	prefix, err := queue.prefix()
+	if err != nil {
+		return err
+	}
+
+	s.globalLock.Lock()
+	defer s.globalLock.Unlock()
+
+	if !s.isReserved(prefix, key) {
+		return ErrNotFound
+	}
+	if err := s.deleteReservation(prefix, key); err != nil {
+		return fmt.Errorf("delete reservation: %w", err)
+	}
+	return nil
+}
+
+// MarkBatchFailed marks the aggregated or state transition batch provided
+// as failed using MarkAggregatorBatchFailed or
+// MarkStateTransitionBatchFailed, which return its ballots to the previous
+// stage when possible. It returns ErrNotFound if the batch does not exist.
+func (s *Storage) MarkBatchFailed(queue Queue, key []byte) error {
+	if queue != QueueAggregated && queue != QueueStateTransitions {
+		return fmt.Errorf("%w: %s has no batches", ErrUnknownQueue, queue)
+	}
+	item, err := s.QueueItem(queue, key)
+	if err != nil {
+		return err
+	}
+	if queue == QueueAggregated {
+		return s.MarkAggregatorBatchFailed(key)
+	}
+	return s.MarkStateTransitionBatchFailed(key, item.ProcessID)
+}
+
+// CleanupProcess removes all the ballots, batches and state transitions of
+// the process provided from the queues, and marks its undone votes as
+// timeout. It returns the number of votes marked as timeout. It is the same
+// cleanup done when a process ends, and it can be used to unblock the
+// pipeline of a process without waiting for it to end.
+func (s *Storage) CleanupProcess(processID types.ProcessID) (int, error) {
+	return s.cleanupEndedProcess(processID)
+}
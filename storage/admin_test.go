@@ -0,0 +1,95 @@
+package storage
+
+import (
+	"errors"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/vocdoni/davinci-node/internal/testutil"
+	"github.com/vocdoni/davinci-node/types"
+)
+
+func TestQueueInspection(t *testing.T) {
+	c := qt.New(t)
+	stg := newTestStorage(t)
+	defer stg.Close()
+
+	pid1 := testutil.RandomProcessID()
+	pid2 := testutil.RandomProcessID()
+	ensureProcess(t, stg, pid1)
+	ensureProcess(t, stg, pid2)
+
+	voteID1 := testutil.RandomVoteID()
+	voteID2 := testutil.RandomVoteID()
+	c.Assert(stg.PushPendingBallot(mkBallot(pid1, voteID1)), qt.IsNil)
+	c.Assert(stg.PushPendingBallot(mkBallot(pid2, voteID2)), qt.IsNil)
+	batchVoteIDs := []types.VoteID{testutil.RandomVoteID(), testutil.RandomVoteID()}
+	c.Assert(stg.PushAggregatorBatch(&AggregatorBallotBatch{
+		ProcessID: pid1,
+		Ballots:   []*AggregatorBallot{mkAggBallot(batchVoteIDs[0]), mkAggBallot(batchVoteIDs[1])},
+	}), qt.IsNil)
+
+	// reserve one pending ballot and the aggregated batch
+	_, _, err := stg.NextPendingBallot()
+	c.Assert(err, qt.IsNil)
+	_, batchKey, err := stg.NextAggregatorBatch(pid1)
+	c.Assert(err, qt.IsNil)
+
+	sizes, err := stg.QueueSizes()
+	c.Assert(err, qt.IsNil)
+	c.Assert(sizes[QueuePending], qt.Equals, QueueSize{Total: 2, Reserved: 1})
+	c.Assert(sizes[QueueVerified], qt.Equals, QueueSize{})
+	c.Assert(sizes[QueueAggregated], qt.Equals, QueueSize{Total: 1, Reserved: 1})
+	c.Assert(sizes[QueueStateTransitions], qt.Equals, QueueSize{})
+
+	// the items can be filtered by process and limited
+	items, err := stg.QueueItems(QueuePending, &pid2, 0)
+	c.Assert(err, qt.IsNil)
+	c.Assert(items, qt.HasLen, 1)
+	c.Assert(items[0].ProcessID, qt.Equals, pid2)
+	c.Assert(items[0].VoteIDs, qt.DeepEquals, []types.VoteID{voteID2})
+	items, err = stg.QueueItems(QueuePending, nil, 1)
+	c.Assert(err, qt.IsNil)
+	c.Assert(items, qt.HasLen, 1)
+	items, err = stg.QueueItems(QueueAggregated, &pid2, 0)
+	c.Assert(err, qt.IsNil)
+	c.Assert(items, qt.HasLen, 0)
+
+	item, err := stg.QueueItem(QueueAggregated, batchKey)
+	c.Assert(err, qt.IsNil)
+	c.Assert(item.ProcessID, qt.Equals, pid1)
+	c.Assert(item.VoteIDs, qt.DeepEquals, batchVoteIDs)
+	c.Assert(item.Reserved, qt.IsTrue)
+	c.Assert(item.ReservedAt.IsZero(), qt.IsFalse)
+
+	_, err = stg.QueueItem(QueueVerified, batchKey)
+	c.Assert(errors.Is(err, ErrNotFound), qt.IsTrue)
+	_, err = stg.QueueItems(Queue("unknown"), nil, 0)
+	c.Assert(errors.Is(err, ErrUnknownQueue), qt.IsTrue)
+
+	// the released batch can be picked up again
+	c.Assert(stg.ReleaseReservation(QueueAggregated, batchKey), qt.IsNil)
+	c.Assert(errors.Is(stg.ReleaseReservation(QueueAggregated, batchKey), ErrNotFound), qt.IsTrue)
+	_, key, err := stg.NextAggregatorBatch(pid1)
+	c.Assert(err, qt.IsNil)
+	c.Assert(key, qt.DeepEquals, batchKey)
+
+	// the failed batch is removed and its votes are marked as error
+	c.Assert(errors.Is(stg.MarkBatchFailed(QueuePending, batchKey), ErrUnknownQueue), qt.IsTrue)
+	c.Assert(stg.MarkBatchFailed(QueueAggregated, batchKey), qt.IsNil)
+	c.Assert(errors.Is(stg.MarkBatchFailed(QueueAggregated, batchKey), ErrNotFound), qt.IsTrue)
+	for _, voteID := range batchVoteIDs {
+		status, err := stg.VoteIDStatus(pid1, voteID)
+		c.Assert(err, qt.IsNil)
+		c.Assert(status, qt.Equals, VoteIDStatusError)
+	}
+
+	// the cleanup only removes the items of the process provided
+	timedOut, err := stg.CleanupProcess(pid1)
+	c.Assert(err, qt.IsNil)
+	c.Assert(timedOut, qt.Equals, 1+len(batchVoteIDs))
+	items, err = stg.QueueItems(QueuePending, nil, 0)
+	c.Assert(err, qt.IsNil)
+	c.Assert(items, qt.HasLen, 1)
+	c.Assert(items[0].ProcessID, qt.Equals, pid2)
+}
@@ -51,6 +51,11 @@ func (s *Storage) PushPendingBallot(b *Ballot) error {
 		return ErrNullifierProcessing
 	}
 
+	// The spoiled ballots are never tallied
+	if s.isBallotSpoiledUnsafe(b.ProcessID, b.VoteID) {
+		return ErrBallotSpoiled
+	}
+
 	// Atomically lock the address BEFORE writing to database
 	// This uses LoadOrStore to ensure only one request succeeds
 	if !s.lockAddress(b.ProcessID, b.Address) {
@@ -423,6 +428,32 @@ func (s *Storage) CountVerifiedBallots(processID types.ProcessID) int {
 	return count
 }
 
+// CountVerifiedBallotsByProcess returns the number of verified ballots which
+// are not reserved, grouped by processID. Only the processes with queued
+// verified ballots are included. The whole queue is read in a single pass.
+func (s *Storage) CountVerifiedBallotsByProcess() map[types.ProcessID]int {
+	s.globalLock.Lock()
+	defer s.globalLock.Unlock()
+	rd := prefixeddb.NewPrefixedReader(s.db, verifiedBallotPrefix)
+	counts := make(map[types.ProcessID]int)
+	if err := rd.Iterate(nil, func(k, _ []byte) bool {
+		if len(k) < types.ProcessIDLen {
+			return true
+		}
+		// Skip if already reserved
+		if s.isReserved(verifiedBallotPrefix, k) {
+			return true
+		}
+		var processID types.ProcessID
+		copy(processID[:], k[:types.ProcessIDLen])
+		counts[processID]++
+		return true
+	}); err != nil {
+		log.Warnw("failed to count verified ballots", "error", err.Error())
+	}
+	return counts
+}
+
 // RemoveVerifiedBallotsByProcess removes all verified ballots for a given
 // processID.
 func (s *Storage) RemoveVerifiedBallotsByProcess(processID types.ProcessID) error {
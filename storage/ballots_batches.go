@@ -5,6 +5,7 @@ import (
 	"encoding/hex"
 	"errors"
 	"fmt"
+	"math/big"
 	"slices"
 	"time"
 
@@ -401,6 +402,42 @@ func (s *Storage) NextStateTransitionBatch(processID types.ProcessID) (*StateTra
 	return &stb, chosenKey, nil
 }
 
+// HasStateTransitionBatchFrom returns whether a state transition batch of
+// the process provided, starting at the root provided, is queued.
+func (s *Storage) HasStateTransitionBatchFrom(processID types.ProcessID, rootBefore *big.Int) bool {
+	s.globalLock.Lock()
+	defer s.globalLock.Unlock()
+
+	found := false
+	pr := prefixeddb.NewPrefixedReader(s.db, stateTransitionPrefix)
+	if err := pr.Iterate(processID.Bytes(), func(_, v []byte) bool {
+		var stb StateTransitionBatch
+		if err := DecodeArtifact(v, &stb); err != nil {
+			return true
+		}
+		found = stb.Inputs.RootHashBefore != nil && stb.Inputs.RootHashBefore.Cmp(rootBefore) == 0
+		return !found
+	}); err != nil {
+		log.Warnw("failed to iterate state transition batches", "error", err.Error())
+	}
+	return found
+}
+
+// SetStateTransitionBatchTxID stores the ID of the transaction sent to settle
+// the state transition batch with the key provided, so the transaction is
+// waited instead of sent again if the batch is picked up after a restart.
+func (s *Storage) SetStateTransitionBatchTxID(key []byte, txID types.HexBytes) error {
+	s.globalLock.Lock()
+	defer s.globalLock.Unlock()
+
+	var stb StateTransitionBatch
+	if err := s.getArtifact(stateTransitionPrefix, key, &stb); err != nil {
+		return fmt.Errorf("get state transition batch: %w", err)
+	}
+	stb.TxID = txID
+	return s.setArtifact(stateTransitionPrefix, key, &stb)
+}
+
 func (s *Storage) MarkStateTransitionBatchDone(k []byte, processID types.ProcessID) error {
 	s.globalLock.Lock()
 	defer s.globalLock.Unlock()
@@ -431,6 +468,27 @@ func (s *Storage) MarkStateTransitionBatchDone(k []byte, processID types.Process
 				s.releaseVoteID(ballot.VoteID)
 			}
 
+			// Keep the batch until its block is final, to settle it again if
+			// the block is reorganized out of the chain
+			if err := s.retainSettledStateTransition(&stb); err != nil {
+				log.Warnw("failed to retain settled state transition batch",
+					"error", err.Error(),
+					"processID", processID.String(),
+				)
+			}
+
+			// Record the receipts of the votes settled by the transaction.
+			// The batches marked done without a transaction of their own
+			// keep the receipts recorded when they were settled.
+			if len(stb.TxID) > 0 && stb.BlobSidecar != nil && stb.Inputs.RootHashAfter != nil {
+				if err := s.setVoteReceiptsUnsafe(processID, stb.Inputs.RootHashAfter, stb.TxID, stb.BlobSidecar); err != nil {
+					log.Warnw("failed to record vote receipts",
+						"error", err.Error(),
+						"processID", processID.String(),
+					)
+				}
+			}
+
 			// Mark all vote IDs in the batch as done
 			if err := s.markVoteIDsDone(processID, voteIDs); err != nil {
 				log.Warnw("failed to mark vote IDs as done",
@@ -515,3 +515,30 @@ func TestMarkStateTransitionOutdatedWithCorruptedData(t *testing.T) {
 	isReserved := stg.isReserved(stateTransitionPrefix, corruptedKey)
 	c.Assert(isReserved, qt.IsFalse, qt.Commentf("reservation should be removed"))
 }
+
+func TestSetStateTransitionBatchTxID(t *testing.T) {
+	c := qt.New(t)
+	stg := newTestStorage(t)
+	defer stg.Close()
+
+	pid := testutil.RandomProcessID()
+	ensureProcess(t, stg, pid)
+	c.Assert(stg.PushStateTransitionBatch(&StateTransitionBatch{
+		ProcessID: pid,
+		Ballots:   []*AggregatorBallot{mkAggBallot(testutil.RandomVoteID())},
+	}), qt.IsNil)
+	_, key, err := stg.NextStateTransitionBatch(pid)
+	c.Assert(err, qt.IsNil)
+
+	txID := types.HexBytes{0x01, 0x02}
+	c.Assert(stg.SetStateTransitionBatchTxID(key, txID), qt.IsNil)
+
+	// the batch keeps its key, and the tx ID survives the reservation release
+	c.Assert(stg.ReleaseReservation(QueueStateTransitions, key), qt.IsNil)
+	stb, sameKey, err := stg.NextStateTransitionBatch(pid)
+	c.Assert(err, qt.IsNil)
+	c.Assert(sameKey, qt.DeepEquals, key)
+	c.Assert(stb.TxID, qt.DeepEquals, txID)
+
+	c.Assert(errors.Is(stg.SetStateTransitionBatchTxID([]byte("missing"), txID), ErrNotFound), qt.IsTrue)
+}
@@ -274,6 +274,7 @@ func TestBallotQueue_PullVerifiedBallots_ReservationsAndLimits(t *testing.T) {
 
 	// count excludes reserved
 	c.Assert(stg.CountVerifiedBallots(pid), qt.Equals, 1)
+	c.Assert(stg.CountVerifiedBallotsByProcess(), qt.DeepEquals, map[types.ProcessID]int{pid: 1})
 
 	// subsequent pull should return the remaining one
 	vbs2, keys2, err := stg.PullVerifiedBallots(pid, 5)
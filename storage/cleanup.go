@@ -25,9 +25,11 @@ import (
 // 5. Verified results
 // 6. Vote ID statuses (marked as timeout, not deleted)
 //
+// It returns the number of vote IDs marked as timeout.
+//
 // Important: This method does NOT clean process metadata, encryption keys,
 // or statistics as they serve as historical records.
-func (s *Storage) cleanupEndedProcess(processID types.ProcessID) error {
+func (s *Storage) cleanupEndedProcess(processID types.ProcessID) (int, error) {
 	s.globalLock.Lock()
 	defer s.globalLock.Unlock()
 
@@ -58,19 +60,25 @@ func (s *Storage) cleanupEndedProcess(processID types.ProcessID) error {
 		errs = append(errs, fmt.Errorf("state transition artifacts: %w", err))
 	}
 
+	// Clean the reorg tracking of the applied and settled state transitions
+	if err := s.cleanReorgTrackingForProcess(processID); err != nil {
+		errs = append(errs, fmt.Errorf("reorg tracking: %w", err))
+	}
+
 	// Mark undone vote IDs as timeout (preserve vote status records for voters)
-	if count, err := s.markProcessVoteIDsTimeout(processID); err != nil {
+	timedOut, err := s.markProcessVoteIDsTimeout(processID)
+	if err != nil {
 		errs = append(errs, fmt.Errorf("vote ID timeout marking: %w", err))
 	} else {
-		log.Debugw("marked vote IDs as timeout", "processID", processID.String(), "count", count)
+		log.Debugw("marked vote IDs as timeout", "processID", processID.String(), "count", timedOut)
 	}
 
 	if len(errs) > 0 {
-		return fmt.Errorf("cleanup errors: %v", errs)
+		return timedOut, fmt.Errorf("cleanup errors: %v", errs)
 	}
 
 	log.Debugw("completed cleanup for ended process", "processID", processID.String())
-	return nil
+	return timedOut, nil
 }
 
 // CleanAllPending removes all pending verified votes, all pending aggregated
@@ -0,0 +1,307 @@
+package storage
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/vocdoni/davinci-node/crypto/ecc"
+	"github.com/vocdoni/davinci-node/crypto/ecc/curves"
+	"github.com/vocdoni/davinci-node/log"
+	"github.com/vocdoni/davinci-node/types"
+)
+
+// dkgSessionIDLen is the length in bytes of the randomly generated DKG
+// session identifiers.
+const dkgSessionIDLen = 16
+
+var (
+	ErrDKGInvalidSession     = errors.New("invalid DKG session")
+	ErrDKGUnexpectedRound    = errors.New("DKG session is not in the expected round")
+	ErrDKGAlreadySubmitted   = errors.New("trustee already submitted for this round")
+	ErrDKGUnknownTrustee     = errors.New("unknown DKG trustee")
+	ErrDKGInvalidSubmission  = errors.New("invalid DKG submission")
+	ErrDKGSessionNotFinished = errors.New("DKG session is not finished yet")
+)
+
+// NewDKGSession creates and stores a new DKG session for the trustees and
+// threshold provided. The trustees are indexed from 1 to n in the order
+// provided, ignoring any index they may contain, because the index zero
+// can not be used to evaluate the secret polynomials. It returns the
+// session created.
+func (s *Storage) NewDKGSession(threshold int, trustees []types.DKGTrustee) (*types.DKGSession, error) {
+	if len(trustees) == 0 {
+		return nil, fmt.Errorf("%w: no trustees provided", ErrDKGInvalidSession)
+	}
+	if threshold < 1 || threshold > len(trustees) {
+		return nil, fmt.Errorf("%w: threshold must be between 1 and %d", ErrDKGInvalidSession, len(trustees))
+	}
+	session := &types.DKGSession{
+		ID:          make(types.HexBytes, dkgSessionIDLen),
+		Threshold:   threshold,
+		Trustees:    make([]types.DKGTrustee, 0, len(trustees)),
+		Status:      types.DKGSessionStatusCommitments,
+		Commitments: make(map[int][]types.DKGPoint),
+		Shares:      make(map[int][]types.DKGEncryptedShare),
+		CreatedAt:   time.Now(),
+	}
+	for i, t := range trustees {
+		if !t.SharesPubKey.Valid() {
+			return nil, fmt.Errorf("%w: trustee %s has no shares public key", ErrDKGInvalidSession, t.Address.Hex())
+		}
+		if slices.ContainsFunc(session.Trustees, func(o types.DKGTrustee) bool {
+			return o.Address == t.Address
+		}) {
+			return nil, fmt.Errorf("%w: duplicated trustee %s", ErrDKGInvalidSession, t.Address.Hex())
+		}
+		t.Index = i + 1
+		session.Trustees = append(session.Trustees, t)
+	}
+	if _, err := rand.Read(session.ID); err != nil {
+		return nil, fmt.Errorf("could not generate DKG session ID: %w", err)
+	}
+
+	s.globalLock.Lock()
+	defer s.globalLock.Unlock()
+	if err := s.setArtifact(dkgSessionPrefix, session.ID, session); err != nil {
+		return nil, fmt.Errorf("could not store DKG session: %w", err)
+	}
+	log.Infow("new DKG session created",
+		"sessionID", session.ID.String(),
+		"threshold", threshold,
+		"trustees", len(session.Trustees))
+	return session, nil
+}
+
+// DKGSession retrieves the DKG session identified by the ID provided. It
+// returns ErrNotFound if the session does not exist.
+func (s *Storage) DKGSession(sessionID types.HexBytes) (*types.DKGSession, error) {
+	s.globalLock.Lock()
+	defer s.globalLock.Unlock()
+	return s.dkgSession(sessionID)
+}
+
+// dkgSession retrieves a DKG session without acquiring the global lock. It
+// assumes the caller already holds the lock.
+func (s *Storage) dkgSession(sessionID types.HexBytes) (*types.DKGSession, error) {
+	session := &types.DKGSession{}
+	if err := s.getArtifact(dkgSessionPrefix, sessionID, session); err != nil {
+		return nil, err
+	}
+	if session.Commitments == nil {
+		session.Commitments = make(map[int][]types.DKGPoint)
+	}
+	if session.Shares == nil {
+		session.Shares = make(map[int][]types.DKGEncryptedShare)
+	}
+	if session.Complaints == nil {
+		session.Complaints = make(map[int][]int)
+	}
+	return session, nil
+}
+
+// SetDKGCommitments stores the public coefficients of the secret polynomial
+// of the trustee provided. Every trustee must submit exactly threshold
+// coefficients once. When the commitments of every trustee are received, the
+// session moves to the shares round and the public key of the session is
+// computed by adding the constant term commitment of every trustee.
+func (s *Storage) SetDKGCommitments(sessionID types.HexBytes, trusteeIndex int, coefficients []types.DKGPoint) error {
+	s.globalLock.Lock()
+	defer s.globalLock.Unlock()
+
+	session, err := s.dkgSession(sessionID)
+	if err != nil {
+		return err
+	}
+	if session.Status != types.DKGSessionStatusCommitments {
+		return fmt.Errorf("%w: %s", ErrDKGUnexpectedRound, session.Status)
+	}
+	if _, ok := session.Trustee(trusteeIndex); !ok {
+		return fmt.Errorf("%w: %d", ErrDKGUnknownTrustee, trusteeIndex)
+	}
+	if _, ok := session.Commitments[trusteeIndex]; ok {
+		return ErrDKGAlreadySubmitted
+	}
+	if len(coefficients) != session.Threshold {
+		return fmt.Errorf("%w: expected %d coefficients, got %d",
+			ErrDKGInvalidSubmission, session.Threshold, len(coefficients))
+	}
+	for _, c := range coefficients {
+		if !c.Valid() {
+			return fmt.Errorf("%w: malformed coefficient commitment", ErrDKGInvalidSubmission)
+		}
+	}
+	session.Commitments[trusteeIndex] = coefficients
+
+	// If every trustee has submitted its commitments, compute the public key
+	// and move to the next round.
+	if len(session.Commitments) == len(session.Trustees) {
+		publicKey := curves.New(encKeyCurveType).New()
+		publicKey.SetZero()
+		for _, index := range session.Participants() {
+			publicKey.Add(publicKey, session.Commitments[index][0].ToPoint(publicKey))
+		}
+		encKey := types.EncryptionKeyFromPoint(publicKey)
+		session.PublicKey = &encKey
+		session.Status = types.DKGSessionStatusShares
+		log.Infow("DKG session commitments completed",
+			"sessionID", session.ID.String(),
+			"publicKey", publicKey.String())
+	}
+	return s.setArtifact(dkgSessionPrefix, session.ID, session)
+}
+
+// SetDKGShares stores the encrypted shares that the trustee provided sends
+// to the rest of trustees. It expects exactly one share for every other
+// trustee of the session. When the shares of every trustee are received, the
+// session moves to the verification round, where every trustee checks the
+// shares it received.
+func (s *Storage) SetDKGShares(sessionID types.HexBytes, trusteeIndex int, shares []types.DKGEncryptedShare) error {
+	s.globalLock.Lock()
+	defer s.globalLock.Unlock()
+
+	session, err := s.dkgSession(sessionID)
+	if err != nil {
+		return err
+	}
+	if session.Status != types.DKGSessionStatusShares {
+		return fmt.Errorf("%w: %s", ErrDKGUnexpectedRound, session.Status)
+	}
+	if _, ok := session.Trustee(trusteeIndex); !ok {
+		return fmt.Errorf("%w: %d", ErrDKGUnknownTrustee, trusteeIndex)
+	}
+	if _, ok := session.Shares[trusteeIndex]; ok {
+		return ErrDKGAlreadySubmitted
+	}
+	if len(shares) != len(session.Trustees)-1 {
+		return fmt.Errorf("%w: expected %d shares, got %d",
+			ErrDKGInvalidSubmission, len(session.Trustees)-1, len(shares))
+	}
+	recipients := make(map[int]struct{}, len(shares))
+	for _, share := range shares {
+		if share.From != trusteeIndex {
+			return fmt.Errorf("%w: share sender %d does not match trustee %d",
+				ErrDKGInvalidSubmission, share.From, trusteeIndex)
+		}
+		if share.To == trusteeIndex {
+			return fmt.Errorf("%w: trustee can not send a share to itself", ErrDKGInvalidSubmission)
+		}
+		if _, ok := session.Trustee(share.To); !ok {
+			return fmt.Errorf("%w: unknown recipient %d", ErrDKGInvalidSubmission, share.To)
+		}
+		if _, ok := recipients[share.To]; ok {
+			return fmt.Errorf("%w: duplicated recipient %d", ErrDKGInvalidSubmission, share.To)
+		}
+		if share.Ciphertext == nil || len(share.Ephemeral) == 0 {
+			return fmt.Errorf("%w: malformed share for recipient %d", ErrDKGInvalidSubmission, share.To)
+		}
+		recipients[share.To] = struct{}{}
+	}
+	session.Shares[trusteeIndex] = shares
+
+	// If every trustee has submitted its shares, move to the verification
+	// round.
+	if len(session.Shares) == len(session.Trustees) {
+		session.Status = types.DKGSessionStatusVerification
+		log.Infow("DKG session shares completed",
+			"sessionID", session.ID.String())
+	}
+	return s.setArtifact(dkgSessionPrefix, session.ID, session)
+}
+
+// SetDKGVerification stores the result of the verification of the shares
+// received by the trustee provided, which lists the senders of the shares
+// that could not be decrypted or do not match the commitments of their
+// senders. A complaint fails the session, since the shares are encrypted
+// and the sequencer can not check who is wrong, so a new session must be
+// created. When every trustee has accepted its shares, the session is
+// marked as ready and its public key is stored as an encryption key
+// (without private key), so it can be used to create new processes.
+func (s *Storage) SetDKGVerification(sessionID types.HexBytes, trusteeIndex int, complaints []int) error {
+	s.globalLock.Lock()
+	defer s.globalLock.Unlock()
+
+	session, err := s.dkgSession(sessionID)
+	if err != nil {
+		return err
+	}
+	if session.Status != types.DKGSessionStatusVerification {
+		return fmt.Errorf("%w: %s", ErrDKGUnexpectedRound, session.Status)
+	}
+	if _, ok := session.Trustee(trusteeIndex); !ok {
+		return fmt.Errorf("%w: %d", ErrDKGUnknownTrustee, trusteeIndex)
+	}
+	if _, ok := session.Complaints[trusteeIndex]; ok || slices.Contains(session.Verified, trusteeIndex) {
+		return ErrDKGAlreadySubmitted
+	}
+	for i, from := range complaints {
+		if from == trusteeIndex {
+			return fmt.Errorf("%w: trustee can not complain about itself", ErrDKGInvalidSubmission)
+		}
+		if _, ok := session.Trustee(from); !ok {
+			return fmt.Errorf("%w: unknown accused trustee %d", ErrDKGInvalidSubmission, from)
+		}
+		if slices.Contains(complaints[:i], from) {
+			return fmt.Errorf("%w: duplicated accused trustee %d", ErrDKGInvalidSubmission, from)
+		}
+	}
+
+	if len(complaints) > 0 {
+		session.Complaints[trusteeIndex] = complaints
+		session.Status = types.DKGSessionStatusFailed
+		log.Warnw("DKG session failed, invalid shares received",
+			"sessionID", session.ID.String(),
+			"trustee", trusteeIndex,
+			"accused", complaints)
+		return s.setArtifact(dkgSessionPrefix, session.ID, session)
+	}
+	session.Verified = append(session.Verified, trusteeIndex)
+
+	// If every trustee has accepted its shares, the session is ready. Store
+	// the public key as a known encryption key and index it to be able to
+	// find the session from the process encryption key.
+	if len(session.Verified) == len(session.Trustees) {
+		publicKey := ProcessEncryptionKeyToPoint(session.PublicKey)
+		if err := s.setEncryptionPubKeyUnsafe(publicKey); err != nil {
+			return fmt.Errorf("could not store DKG public key: %w", err)
+		}
+		if err := s.setArtifact(dkgPublicKeyPrefix, publicKey.Marshal(), session.ID); err != nil {
+			return fmt.Errorf("could not index DKG public key: %w", err)
+		}
+		session.Status = types.DKGSessionStatusReady
+		log.Infow("DKG session ready",
+			"sessionID", session.ID.String(),
+			"publicKey", publicKey.String())
+	}
+	return s.setArtifact(dkgSessionPrefix, session.ID, session)
+}
+
+// DKGSessionPublicKey returns the public key derived by a finished DKG
+// session. It returns ErrDKGSessionNotFinished if the session has not
+// received every share yet.
+func (s *Storage) DKGSessionPublicKey(sessionID types.HexBytes) (ecc.Point, error) {
+	session, err := s.DKGSession(sessionID)
+	if err != nil {
+		return nil, err
+	}
+	if session.Status != types.DKGSessionStatusReady || session.PublicKey == nil {
+		return nil, fmt.Errorf("%w: %s", ErrDKGSessionNotFinished, session.Status)
+	}
+	return ProcessEncryptionKeyToPoint(session.PublicKey), nil
+}
+
+// DKGSessionByPublicKey returns the finished DKG session that derived the
+// public key provided. It returns ErrNotFound if the key was not generated
+// by a DKG session.
+func (s *Storage) DKGSessionByPublicKey(publicKey ecc.Point) (*types.DKGSession, error) {
+	s.globalLock.Lock()
+	defer s.globalLock.Unlock()
+
+	var sessionID types.HexBytes
+	if err := s.getArtifact(dkgPublicKeyPrefix, publicKey.Marshal(), &sessionID); err != nil {
+		return nil, err
+	}
+	return s.dkgSession(sessionID)
+}
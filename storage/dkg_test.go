@@ -0,0 +1,152 @@
+package storage
+
+import (
+	"math/big"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	qt "github.com/frankban/quicktest"
+	bjj "github.com/vocdoni/davinci-node/crypto/ecc/bjj_gnark"
+	"github.com/vocdoni/davinci-node/db"
+	"github.com/vocdoni/davinci-node/db/metadb"
+	"github.com/vocdoni/davinci-node/types"
+)
+
+func TestDKGSession(t *testing.T) {
+	c := qt.New(t)
+
+	testDB, err := metadb.New(db.TypePebble, filepath.Join(t.TempDir(), "db"))
+	c.Assert(err, qt.IsNil)
+	st := New(testDB)
+	defer st.Close()
+
+	// build two trustees with deterministic secrets, so the expected public
+	// key can be computed
+	secrets := []*big.Int{big.NewInt(11), big.NewInt(22)}
+	trustees := make([]types.DKGTrustee, len(secrets))
+	for i, secret := range secrets {
+		pubKey := bjj.New()
+		pubKey.ScalarBaseMult(secret)
+		trustees[i] = types.DKGTrustee{
+			Address:      common.BigToAddress(big.NewInt(int64(i + 1))),
+			SharesPubKey: types.DKGPointFromPoint(pubKey),
+		}
+	}
+
+	// invalid sessions
+	_, err = st.NewDKGSession(3, trustees)
+	c.Assert(err, qt.ErrorIs, ErrDKGInvalidSession)
+	_, err = st.NewDKGSession(1, []types.DKGTrustee{trustees[0], trustees[0]})
+	c.Assert(err, qt.ErrorIs, ErrDKGInvalidSession)
+	_, err = st.NewDKGSession(1, []types.DKGTrustee{{Address: trustees[0].Address}})
+	c.Assert(err, qt.ErrorIs, ErrDKGInvalidSession)
+
+	session, err := st.NewDKGSession(1, trustees)
+	c.Assert(err, qt.IsNil)
+	c.Assert(session.Participants(), qt.DeepEquals, []int{1, 2})
+
+	_, err = st.DKGSessionPublicKey(session.ID)
+	c.Assert(err, qt.ErrorIs, ErrDKGSessionNotFinished)
+	c.Assert(st.SetDKGShares(session.ID, 1, nil), qt.ErrorIs, ErrDKGUnexpectedRound)
+
+	// commitments round
+	commitment := func(secret *big.Int) []types.DKGPoint {
+		p := bjj.New()
+		p.ScalarBaseMult(secret)
+		return []types.DKGPoint{types.DKGPointFromPoint(p)}
+	}
+	c.Assert(st.SetDKGCommitments(session.ID, 3, commitment(secrets[0])), qt.ErrorIs, ErrDKGUnknownTrustee)
+	c.Assert(st.SetDKGCommitments(session.ID, 1, nil), qt.ErrorIs, ErrDKGInvalidSubmission)
+	c.Assert(st.SetDKGCommitments(session.ID, 1, commitment(secrets[0])), qt.IsNil)
+	c.Assert(st.SetDKGCommitments(session.ID, 1, commitment(secrets[0])), qt.ErrorIs, ErrDKGAlreadySubmitted)
+	c.Assert(st.SetDKGCommitments(session.ID, 2, commitment(secrets[1])), qt.IsNil)
+
+	session, err = st.DKGSession(session.ID)
+	c.Assert(err, qt.IsNil)
+	c.Assert(session.Status, qt.Equals, types.DKGSessionStatusShares)
+	expected := bjj.New()
+	expected.ScalarBaseMult(big.NewInt(33))
+	c.Assert(ProcessEncryptionKeyToPoint(session.PublicKey).Equal(expected), qt.IsTrue)
+
+	// shares round
+	share := func(from, to int) types.DKGEncryptedShare {
+		return types.DKGEncryptedShare{
+			From:       from,
+			To:         to,
+			Ciphertext: types.BigIntConverter(big.NewInt(1)),
+			Ephemeral:  []byte{1},
+		}
+	}
+	c.Assert(st.SetDKGShares(session.ID, 1, []types.DKGEncryptedShare{share(1, 1)}), qt.ErrorIs, ErrDKGInvalidSubmission)
+	c.Assert(st.SetDKGShares(session.ID, 1, []types.DKGEncryptedShare{share(2, 2)}), qt.ErrorIs, ErrDKGInvalidSubmission)
+	c.Assert(st.SetDKGShares(session.ID, 1, []types.DKGEncryptedShare{share(1, 2)}), qt.IsNil)
+	c.Assert(st.SetDKGShares(session.ID, 2, []types.DKGEncryptedShare{share(2, 1)}), qt.IsNil)
+
+	// verification round, the key is not ready until every trustee accepts
+	// its shares
+	_, err = st.DKGSessionPublicKey(session.ID)
+	c.Assert(err, qt.ErrorIs, ErrDKGSessionNotFinished)
+	c.Assert(st.SetDKGVerification(session.ID, 1, []int{1}), qt.ErrorIs, ErrDKGInvalidSubmission)
+	c.Assert(st.SetDKGVerification(session.ID, 1, []int{3}), qt.ErrorIs, ErrDKGInvalidSubmission)
+	c.Assert(st.SetDKGVerification(session.ID, 1, nil), qt.IsNil)
+	c.Assert(st.SetDKGVerification(session.ID, 1, nil), qt.ErrorIs, ErrDKGAlreadySubmitted)
+	_, err = st.DKGSessionPublicKey(session.ID)
+	c.Assert(err, qt.ErrorIs, ErrDKGSessionNotFinished)
+	c.Assert(st.SetDKGVerification(session.ID, 2, []int{}), qt.IsNil)
+
+	// the session public key is ready and indexed
+	publicKey, err := st.DKGSessionPublicKey(session.ID)
+	c.Assert(err, qt.IsNil)
+	c.Assert(publicKey.Equal(expected), qt.IsTrue)
+	byKey, err := st.DKGSessionByPublicKey(publicKey)
+	c.Assert(err, qt.IsNil)
+	c.Assert(byKey.ID, qt.DeepEquals, session.ID)
+	c.Assert(byKey.SharesFor(1), qt.HasLen, 1)
+}
+
+func TestDKGSessionComplaint(t *testing.T) {
+	c := qt.New(t)
+
+	testDB, err := metadb.New(db.TypePebble, filepath.Join(t.TempDir(), "db"))
+	c.Assert(err, qt.IsNil)
+	st := New(testDB)
+	defer st.Close()
+
+	trustees := make([]types.DKGTrustee, 2)
+	for i := range trustees {
+		secret := big.NewInt(int64(i + 1))
+		pubKey := bjj.New()
+		pubKey.ScalarBaseMult(secret)
+		trustees[i] = types.DKGTrustee{
+			Address:      common.BigToAddress(big.NewInt(int64(i + 1))),
+			SharesPubKey: types.DKGPointFromPoint(pubKey),
+		}
+	}
+	session, err := st.NewDKGSession(1, trustees)
+	c.Assert(err, qt.IsNil)
+	for _, index := range session.Participants() {
+		p := bjj.New()
+		p.ScalarBaseMult(big.NewInt(int64(index)))
+		c.Assert(st.SetDKGCommitments(session.ID, index, []types.DKGPoint{types.DKGPointFromPoint(p)}), qt.IsNil)
+	}
+	for _, index := range session.Participants() {
+		c.Assert(st.SetDKGShares(session.ID, index, []types.DKGEncryptedShare{{
+			From:       index,
+			To:         3 - index,
+			Ciphertext: types.BigIntConverter(big.NewInt(1)),
+			Ephemeral:  []byte{1},
+		}}), qt.IsNil)
+	}
+
+	// a complaint fails the session, so its key can not be used
+	c.Assert(st.SetDKGVerification(session.ID, 1, nil), qt.IsNil)
+	c.Assert(st.SetDKGVerification(session.ID, 2, []int{1}), qt.IsNil)
+	session, err = st.DKGSession(session.ID)
+	c.Assert(err, qt.IsNil)
+	c.Assert(session.Status, qt.Equals, types.DKGSessionStatusFailed)
+	c.Assert(session.Complaints, qt.DeepEquals, map[int][]int{2: {1}})
+	_, err = st.DKGSessionPublicKey(session.ID)
+	c.Assert(err, qt.ErrorIs, ErrDKGSessionNotFinished)
+	c.Assert(st.SetDKGVerification(session.ID, 1, nil), qt.ErrorIs, ErrDKGUnexpectedRound)
+}
@@ -0,0 +1,456 @@
+package storage
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"time"
+
+	"github.com/vocdoni/davinci-node/crypto/keywrap"
+	"github.com/vocdoni/davinci-node/db"
+	"github.com/vocdoni/davinci-node/db/prefixeddb"
+	"github.com/vocdoni/davinci-node/log"
+	"github.com/vocdoni/davinci-node/state"
+	"github.com/vocdoni/davinci-node/types"
+)
+
+// ProcessExportVersion is the version of the process export format.
+const ProcessExportVersion = 1
+
+// ErrInvalidProcessExport is returned when a process export can not be
+// imported because it is malformed or does not match the on-chain process.
+var ErrInvalidProcessExport = errors.New("invalid process export")
+
+// processExportSections are the storage prefixes with process data included
+// in the exports, by section name. Except for the pending ballots, which are
+// keyed by vote ID, every key of these prefixes starts with the process ID.
+// The reservations and the pending txs are not exported, they belong to the
+// node that created them.
+var processExportSections = map[string][]byte{
+	"pendingBallots":         ballotPrefix,
+	"verifiedBallots":        verifiedBallotPrefix,
+	"aggregatorBatches":      aggregBatchPrefix,
+	"pendingAggregatorBatch": pendingAggregBatchPrefix,
+	"stateTransitionBatches": stateTransitionPrefix,
+	"transitionArtifacts":    stateTransitionArtifactPrefix,
+	"voteIDStatuses":         voteIDStatusPrefix,
+	"verifiedResults":        verifiedResultPrefix,
+}
+
+// ExportEntry is a raw key-value pair of the storage included in a process
+// export.
+type ExportEntry struct {
+	Key   types.HexBytes `json:"key"`
+	Value types.HexBytes `json:"value"`
+}
+
+// CensusExport contains the dump of the census tree of a process, in the
+// JSONL format imported by the census database.
+type CensusExport struct {
+	Root types.HexBytes `json:"root"`
+	Dump []byte         `json:"dump"`
+}
+
+// ProcessExport bundles everything a node needs to continue sequencing a
+// process: the process record, the state tree, the census tree, the
+// encryption keys, the queues and the state transition artifacts. The
+// private key is wrapped with a key-encryption key derived from the export
+// passphrase, bound to the process ID, and the parameters to derive it
+// again are included in the export.
+type ProcessExport struct {
+	Version           int                       `json:"version"`
+	ProcessID         types.ProcessID           `json:"processId"`
+	StateRoot         *types.BigInt             `json:"stateRoot"`
+	ExportedAt        time.Time                 `json:"exportedAt"`
+	Process           types.HexBytes            `json:"process"`
+	KeyDerivation     *keywrap.PassphraseParams `json:"keyDerivation,omitempty"`
+	WrappedPrivateKey types.HexBytes            `json:"wrappedPrivateKey,omitempty"`
+	Census            *CensusExport             `json:"census,omitempty"`
+	State             []ExportEntry             `json:"state"`
+	Sections          map[string][]ExportEntry  `json:"sections"`
+}
+
+// Write writes the export to the writer provided as gzip compressed JSON.
+func (e *ProcessExport) Write(w io.Writer) error {
+	zw := gzip.NewWriter(w)
+	if err := json.NewEncoder(zw).Encode(e); err != nil {
+		return fmt.Errorf("encode process export: %w", err)
+	}
+	return zw.Close()
+}
+
+// ReadProcessExport reads an export written with ProcessExport.Write.
+func ReadProcessExport(r io.Reader) (*ProcessExport, error) {
+	zr, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidProcessExport, err)
+	}
+	defer func() {
+		if err := zr.Close(); err != nil {
+			log.Warnw("failed to close process export reader", "error", err.Error())
+		}
+	}()
+	e := &ProcessExport{}
+	if err := json.NewDecoder(zr).Decode(e); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidProcessExport, err)
+	}
+	return e, nil
+}
+
+// privateKey returns the process private key of the export, unwrapped with
+// the key-encryption key derived from the passphrase provided. It returns
+// nil if the export has no private key.
+func (e *ProcessExport) privateKey(passphrase string) (*big.Int, error) {
+	if e.WrappedPrivateKey == nil {
+		return nil, nil
+	}
+	kw, err := keywrap.OpenPassphraseKeyWrapper(passphrase, e.KeyDerivation)
+	if err != nil {
+		return nil, fmt.Errorf("derive export key: %w", err)
+	}
+	secret, err := kw.Unwrap(e.WrappedPrivateKey, e.ProcessID.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("%w: unwrap private key: %v", ErrInvalidProcessExport, err)
+	}
+	return new(big.Int).SetBytes(secret), nil
+}
+
+// ExportProcess exports the process provided. The process private key is
+// wrapped with a key-encryption key derived from the passphrase provided,
+// which is required to import it. The chain ID is only required to find the
+// census of the processes with a dynamic on-chain census.
+func (s *Storage) ExportProcess(processID types.ProcessID, chainID uint64, passphrase string) (*ProcessExport, error) {
+	s.globalLock.Lock()
+	defer s.globalLock.Unlock()
+
+	process, err := s.process(processID)
+	if err != nil {
+		return nil, fmt.Errorf("get process: %w", err)
+	}
+	if process.StateRoot == nil {
+		return nil, fmt.Errorf("process %s has no state root", processID.String())
+	}
+	rawProcess, err := EncodeArtifact(process)
+	if err != nil {
+		return nil, fmt.Errorf("encode process: %w", err)
+	}
+	e := &ProcessExport{
+		Version:    ProcessExportVersion,
+		ProcessID:  processID,
+		StateRoot:  process.StateRoot,
+		ExportedAt: time.Now(),
+		Process:    rawProcess,
+		Sections:   make(map[string][]ExportEntry, len(processExportSections)),
+	}
+
+	// The private key is unwrapped from the node key-encryption key and
+	// wrapped with the export one, the importer wraps it with its own key
+	if process.EncryptionKey != nil {
+		_, privateKey, err := s.encryptionKeysUnsafe(ProcessEncryptionKeyToPoint(process.EncryptionKey))
+		if err != nil && !errors.Is(err, ErrNotFound) {
+			return nil, fmt.Errorf("get encryption keys: %w", err)
+		}
+		if privateKey != nil {
+			kw, params, err := keywrap.NewPassphraseKeyWrapper(passphrase)
+			if err != nil {
+				return nil, fmt.Errorf("derive export key: %w", err)
+			}
+			if e.WrappedPrivateKey, err = kw.Wrap(privateKey.Bytes(), processID.Bytes()); err != nil {
+				return nil, fmt.Errorf("wrap private key: %w", err)
+			}
+			e.KeyDerivation = params
+		}
+	}
+
+	// The CSP censuses are not stored locally
+	if process.Census != nil && !process.Census.CensusOrigin.IsCSP() {
+		ref, err := s.LoadCensus(chainID, process.Census)
+		if err != nil {
+			return nil, fmt.Errorf("load census: %w", err)
+		}
+		dump, err := io.ReadAll(ref.Tree().Dump())
+		if err != nil {
+			return nil, fmt.Errorf("dump census: %w", err)
+		}
+		e.Census = &CensusExport{Root: ref.Root(), Dump: dump}
+	}
+
+	// The whole state tree of the process is exported, including the roots
+	// of the state transitions not settled yet
+	if err := prefixeddb.NewPrefixedReader(s.stateDB, processID.Bytes()).Iterate(nil, func(k, v []byte) bool {
+		e.State = append(e.State, ExportEntry{Key: bytes.Clone(k), Value: bytes.Clone(v)})
+		return true
+	}); err != nil {
+		return nil, fmt.Errorf("iterate state: %w", err)
+	}
+
+	for name, prefix := range processExportSections {
+		var iterErr error
+		if err := prefixeddb.NewPrefixedReader(s.db, prefix).Iterate(nil, func(k, v []byte) bool {
+			belongs, err := entryBelongsToProcess(prefix, processID, k, v)
+			if err != nil {
+				iterErr = err
+				return false
+			}
+			if belongs {
+				e.Sections[name] = append(e.Sections[name], ExportEntry{Key: bytes.Clone(k), Value: bytes.Clone(v)})
+			}
+			return true
+		}); err != nil {
+			return nil, fmt.Errorf("iterate %s: %w", name, err)
+		}
+		if iterErr != nil {
+			return nil, fmt.Errorf("export %s: %w", name, iterErr)
+		}
+	}
+	return e, nil
+}
+
+// ImportProcess imports a process exported with ExportProcess, unwrapping
+// its private key with the passphrase used to export it. The process must
+// not exist in the storage. The state root of the export must match the
+// state root of the on-chain process provided, and the state tree and the
+// census tree must contain their roots. The encryption keys, the sections
+// and the process record are stored in a single write transaction once the
+// state tree and the census are imported, and if anything fails the state
+// tree and the census are removed, so a failed import leaves nothing behind.
+// The chain ID is only required to store the census of the processes with a
+// dynamic on-chain census.
+func (s *Storage) ImportProcess(e *ProcessExport, passphrase string, chainID uint64, onchain *types.Process) error {
+	if e.Version != ProcessExportVersion {
+		return fmt.Errorf("%w: unsupported version %d", ErrInvalidProcessExport, e.Version)
+	}
+	process := &types.Process{}
+	if err := DecodeArtifact(e.Process, process); err != nil {
+		return fmt.Errorf("%w: decode process: %v", ErrInvalidProcessExport, err)
+	}
+	if process.ID == nil || *process.ID != e.ProcessID {
+		return fmt.Errorf("%w: process record does not match process ID %s", ErrInvalidProcessExport, e.ProcessID.String())
+	}
+	if process.StateRoot == nil || process.Census == nil {
+		return fmt.Errorf("%w: process has no state root or census", ErrInvalidProcessExport)
+	}
+	if onchain == nil || onchain.StateRoot == nil {
+		return fmt.Errorf("%w: missing on-chain state root", ErrInvalidProcessExport)
+	}
+	if onchain.StateRoot.MathBigInt().Cmp(process.StateRoot.MathBigInt()) != 0 {
+		return fmt.Errorf("%w: state root %s does not match the on-chain state root %s, export the process again",
+			ErrInvalidProcessExport, process.StateRoot.String(), onchain.StateRoot.String())
+	}
+	if !process.Census.CensusOrigin.IsCSP() {
+		if e.Census == nil {
+			return fmt.Errorf("%w: missing census", ErrInvalidProcessExport)
+		}
+		if process.Census.CensusOrigin != types.CensusOriginMerkleTreeOnchainDynamicV1 &&
+			e.Census.Root.BigInt().MathBigInt().Cmp(process.Census.CensusRoot.BigInt().MathBigInt()) != 0 {
+			return fmt.Errorf("%w: census root does not match the process census root", ErrInvalidProcessExport)
+		}
+	}
+	privateKey, err := e.privateKey(passphrase)
+	if err != nil {
+		return err
+	}
+	entries, err := e.sectionEntries()
+	if err != nil {
+		return err
+	}
+
+	s.globalLock.Lock()
+	defer s.globalLock.Unlock()
+
+	if exists, err := s.processExists(e.ProcessID); err != nil {
+		return fmt.Errorf("check process: %w", err)
+	} else if exists {
+		return fmt.Errorf("process %s already exists", e.ProcessID.String())
+	}
+
+	if err := s.importProcessState(e.ProcessID, process.StateRoot.MathBigInt(), e.State); err != nil {
+		return err
+	}
+	removeCensus, err := s.importProcessCensus(e.Census, process.Census, chainID)
+	if err != nil {
+		s.removeProcessState(e.ProcessID, e.State)
+		return err
+	}
+
+	wTx := s.db.WriteTx()
+	defer wTx.Discard()
+	if err := s.writeImportedProcessUnsafe(wTx, e.ProcessID, process, privateKey, entries); err == nil {
+		err = wTx.Commit()
+	}
+	if err != nil {
+		removeCensus()
+		s.removeProcessState(e.ProcessID, e.State)
+		return fmt.Errorf("store process: %w", err)
+	}
+	log.Infow("process imported",
+		"processID", e.ProcessID.String(),
+		"stateRoot", process.StateRoot.String(),
+		"exportedAt", e.ExportedAt)
+	return nil
+}
+
+// importEntry is an entry of a process export section, ready to be stored
+// under its storage prefix.
+type importEntry struct {
+	prefix []byte
+	key    []byte
+	value  []byte
+}
+
+// sectionEntries checks that every entry of the export sections belongs to
+// the process, and returns them ready to be stored. The transaction IDs of
+// the state transition batches are removed, since the transactions were
+// sent by the exporting node, so they are sent again if not settled yet.
+func (e *ProcessExport) sectionEntries() ([]importEntry, error) {
+	var entries []importEntry
+	for name, sectionEntries := range e.Sections {
+		prefix, ok := processExportSections[name]
+		if !ok {
+			return nil, fmt.Errorf("%w: unknown section %s", ErrInvalidProcessExport, name)
+		}
+		for _, entry := range sectionEntries {
+			belongs, err := entryBelongsToProcess(prefix, e.ProcessID, entry.Key, entry.Value)
+			if err != nil || !belongs {
+				return nil, fmt.Errorf("%w: %s entry %s does not belong to the process", ErrInvalidProcessExport, name, entry.Key.String())
+			}
+			value := entry.Value
+			if bytes.Equal(prefix, stateTransitionPrefix) {
+				if value, err = withoutTxID(value); err != nil {
+					return nil, fmt.Errorf("%w: %s entry %s: %v", ErrInvalidProcessExport, name, entry.Key.String(), err)
+				}
+			}
+			entries = append(entries, importEntry{prefix: prefix, key: entry.Key, value: value})
+		}
+	}
+	return entries, nil
+}
+
+// writeImportedProcessUnsafe writes the encryption keys, the section entries
+// and the record of an imported process in the write transaction provided.
+// It assumes the caller already holds the globalLock.
+func (s *Storage) writeImportedProcessUnsafe(wTx db.WriteTx, processID types.ProcessID, process *types.Process,
+	privateKey *big.Int, entries []importEntry,
+) error {
+	if process.EncryptionKey != nil {
+		publicKey := ProcessEncryptionKeyToPoint(process.EncryptionKey)
+		// the existing encryption keys are not overwritten by a public key
+		err := s.getArtifact(encryptionKeyPrefix, publicKey.Marshal(), new(EncryptionKeys))
+		if err != nil && !errors.Is(err, ErrNotFound) {
+			return fmt.Errorf("check encryption keys: %w", err)
+		}
+		if privateKey != nil || err != nil {
+			if err := s.writeEncryptionKeysUnsafe(wTx, publicKey, privateKey); err != nil {
+				return fmt.Errorf("store encryption keys: %w", err)
+			}
+		}
+	}
+	for _, entry := range entries {
+		if err := prefixeddb.NewPrefixedWriteTx(wTx, entry.prefix).Set(entry.key, entry.value); err != nil {
+			return fmt.Errorf("import entry %x: %w", entry.key, err)
+		}
+	}
+	return s.writeProcessUnsafe(wTx, processID, process)
+}
+
+// importProcessCensus imports the census of the export provided, if any. It
+// returns a function that removes the census if it was created by the
+// import, to roll back the import if it fails afterwards.
+func (s *Storage) importProcessCensus(ce *CensusExport, census *types.Census, chainID uint64) (func(), error) {
+	if ce == nil {
+		return func() {}, nil
+	}
+	var (
+		existed bool
+		err     error
+		remove  func() error
+	)
+	dump := bytes.NewReader(ce.Dump)
+	if census.CensusOrigin == types.CensusOriginMerkleTreeOnchainDynamicV1 {
+		existed = s.CensusDB().ExistsByScopedAddress(chainID, census.ContractAddress)
+		_, err = s.CensusDB().ImportByScopedAddress(chainID, census.ContractAddress, ce.Root, dump)
+		remove = func() error { return s.CensusDB().DelByScopedAddress(chainID, census.ContractAddress) }
+	} else {
+		existed = s.CensusDB().ExistsByRoot(ce.Root)
+		_, err = s.CensusDB().Import(ce.Root, dump)
+		remove = func() error { return s.CensusDB().DelByRoot(ce.Root) }
+	}
+	if err != nil {
+		return nil, fmt.Errorf("import census: %w", err)
+	}
+	return func() {
+		if existed {
+			return
+		}
+		if err := remove(); err != nil {
+			log.Warnw("failed to remove imported census", "root", ce.Root.String(), "error", err.Error())
+		}
+	}, nil
+}
+
+// importProcessState writes the state tree entries of a process and checks
+// that the tree contains the root provided. If it does not, the entries are
+// removed. It assumes the caller already holds the globalLock.
+func (s *Storage) importProcessState(processID types.ProcessID, root *big.Int, entries []ExportEntry) error {
+	wTx := prefixeddb.NewPrefixedDatabase(s.stateDB, processID.Bytes()).WriteTx()
+	for _, entry := range entries {
+		if err := wTx.Set(entry.Key, entry.Value); err != nil {
+			wTx.Discard()
+			return fmt.Errorf("import state: %w", err)
+		}
+	}
+	if err := wTx.Commit(); err != nil {
+		return fmt.Errorf("import state: %w", err)
+	}
+	if err := state.RootExists(s.stateDB, processID, root); err != nil {
+		s.removeProcessState(processID, entries)
+		return fmt.Errorf("%w: state does not contain the state root: %v", ErrInvalidProcessExport, err)
+	}
+	return nil
+}
+
+// removeProcessState removes the state tree entries of a process written by
+// importProcessState. It assumes the caller already holds the globalLock.
+func (s *Storage) removeProcessState(processID types.ProcessID, entries []ExportEntry) {
+	wTx := prefixeddb.NewPrefixedDatabase(s.stateDB, processID.Bytes()).WriteTx()
+	defer wTx.Discard()
+	for _, entry := range entries {
+		if err := wTx.Delete(entry.Key); err != nil {
+			log.Warnw("failed to remove imported state entry", "error", err.Error())
+		}
+	}
+	if err := wTx.Commit(); err != nil {
+		log.Warnw("failed to remove imported state", "error", err.Error())
+	}
+}
+
+// withoutTxID removes the transaction ID of the encoded state transition
+// batch provided.
+func withoutTxID(value []byte) ([]byte, error) {
+	var stb StateTransitionBatch
+	if err := DecodeArtifact(value, &stb); err != nil {
+		return nil, fmt.Errorf("decode state transition batch: %w", err)
+	}
+	if len(stb.TxID) == 0 {
+		return value, nil
+	}
+	stb.TxID = nil
+	return EncodeArtifact(&stb)
+}
+
+// entryBelongsToProcess reports whether the entry of the prefix provided
+// belongs to the process provided.
+func entryBelongsToProcess(prefix []byte, processID types.ProcessID, key, value []byte) (bool, error) {
+	if !bytes.Equal(prefix, ballotPrefix) {
+		return bytes.HasPrefix(key, processID.Bytes()), nil
+	}
+	var b Ballot
+	if err := DecodeArtifact(value, &b); err != nil {
+		return false, fmt.Errorf("decode ballot: %w", err)
+	}
+	return b.ProcessID == processID && bytes.Equal(key, b.VoteID.Bytes()), nil
+}
@@ -0,0 +1,156 @@
+package storage
+
+import (
+	"bytes"
+	"errors"
+	"math/big"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/google/uuid"
+	"github.com/vocdoni/davinci-node/crypto/keywrap"
+	"github.com/vocdoni/davinci-node/db/prefixeddb"
+	"github.com/vocdoni/davinci-node/internal/testutil"
+	"github.com/vocdoni/davinci-node/types"
+)
+
+const testExportPassphrase = "export passphrase"
+
+func TestProcessExportImport(t *testing.T) {
+	c := qt.New(t)
+	src := newTestStorage(t)
+	defer src.Close()
+	dst := newTestStorage(t)
+	defer dst.Close()
+
+	pid := testutil.RandomProcessID()
+	otherPID := testutil.RandomProcessID()
+	ensureProcess(t, src, pid)
+	ensureProcess(t, src, otherPID)
+
+	// publish a census with one voter and use it as the process census
+	ref, err := src.CensusDB().New(uuid.New())
+	c.Assert(err, qt.IsNil)
+	c.Assert(ref.Insert(testutil.DeterministicAddress(1).Bytes(), types.HexBytes{1}), qt.IsNil)
+	censusRoot := ref.Root()
+	_, err = src.CensusDB().Import(censusRoot, ref.Tree().Dump())
+	c.Assert(err, qt.IsNil)
+	c.Assert(src.UpdateProcess(pid, func(p *types.Process) error {
+		p.Census.CensusRoot = censusRoot
+		return nil
+	}), qt.IsNil)
+
+	voteID := testutil.RandomVoteID()
+	c.Assert(src.PushPendingBallot(mkBallot(pid, voteID)), qt.IsNil)
+	c.Assert(src.PushPendingBallot(mkBallot(otherPID, testutil.RandomVoteID())), qt.IsNil)
+	c.Assert(src.PushAggregatorBatch(&AggregatorBallotBatch{
+		ProcessID: pid,
+		Ballots:   []*AggregatorBallot{mkAggBallot(testutil.RandomVoteID())},
+	}), qt.IsNil)
+
+	// store the private key of the process encryption key
+	process, err := src.Process(pid)
+	c.Assert(err, qt.IsNil)
+	privateKey := big.NewInt(42)
+	c.Assert(src.setEncryptionKeysUnsafe(ProcessEncryptionKeyToPoint(process.EncryptionKey), privateKey), qt.IsNil)
+
+	_, err = src.ExportProcess(pid, 0, "")
+	c.Assert(err, qt.IsNotNil)
+	export, err := src.ExportProcess(pid, 0, testExportPassphrase)
+	c.Assert(err, qt.IsNil)
+	c.Assert(export.KeyDerivation, qt.IsNotNil)
+	c.Assert(export.WrappedPrivateKey, qt.Not(qt.HasLen), 0)
+	c.Assert(export.State, qt.Not(qt.HasLen), 0)
+	c.Assert(export.Census, qt.IsNotNil)
+	c.Assert(export.Sections["pendingBallots"], qt.HasLen, 1)
+	c.Assert(export.Sections["aggregatorBatches"], qt.HasLen, 1)
+
+	// the export survives the serialization
+	buf := &bytes.Buffer{}
+	c.Assert(export.Write(buf), qt.IsNil)
+	export, err = ReadProcessExport(buf)
+	c.Assert(err, qt.IsNil)
+
+	// a stale export is rejected and nothing is imported
+	stale := &types.Process{StateRoot: (*types.BigInt)(big.NewInt(1))}
+	err = dst.ImportProcess(export, testExportPassphrase, 0, stale)
+	c.Assert(errors.Is(err, ErrInvalidProcessExport), qt.IsTrue)
+	_, err = dst.Process(pid)
+	c.Assert(errors.Is(err, ErrNotFound), qt.IsTrue)
+
+	// the private key can not be unwrapped without the export passphrase
+	err = dst.ImportProcess(export, "wrong passphrase", 0, process)
+	c.Assert(err, qt.ErrorIs, keywrap.ErrInvalidPassphrase)
+	c.Assert(dst.ImportProcess(export, "", 0, process), qt.IsNotNil)
+	_, err = dst.Process(pid)
+	c.Assert(errors.Is(err, ErrNotFound), qt.IsTrue)
+
+	c.Assert(dst.ImportProcess(export, testExportPassphrase, 0, process), qt.IsNil)
+	imported, err := dst.Process(pid)
+	c.Assert(err, qt.IsNil)
+	c.Assert(imported.StateRoot.String(), qt.Equals, process.StateRoot.String())
+	c.Assert(dst.CountPendingBallots(), qt.Equals, 1)
+	b, _, err := dst.NextPendingBallot()
+	c.Assert(err, qt.IsNil)
+	c.Assert(b.VoteID, qt.Equals, voteID)
+	_, _, err = dst.NextAggregatorBatch(pid)
+	c.Assert(err, qt.IsNil)
+	_, importedKey, err := dst.ProcessEncryptionKeys(pid)
+	c.Assert(err, qt.IsNil)
+	c.Assert(importedKey.Cmp(privateKey), qt.Equals, 0)
+	importedCensus, err := dst.LoadCensus(0, imported.Census)
+	c.Assert(err, qt.IsNil)
+	c.Assert(importedCensus.Root(), qt.DeepEquals, censusRoot)
+
+	// the process can not be imported twice
+	c.Assert(dst.ImportProcess(export, testExportPassphrase, 0, process), qt.IsNotNil)
+}
+
+func TestProcessImportRollback(t *testing.T) {
+	c := qt.New(t)
+	src := newTestStorage(t)
+	defer src.Close()
+	dst := newTestStorage(t)
+	defer dst.Close()
+
+	pid := testutil.RandomProcessID()
+	ensureProcess(t, src, pid)
+	ref, err := src.CensusDB().New(uuid.New())
+	c.Assert(err, qt.IsNil)
+	c.Assert(ref.Insert(testutil.DeterministicAddress(1).Bytes(), types.HexBytes{1}), qt.IsNil)
+	censusRoot := ref.Root()
+	_, err = src.CensusDB().Import(censusRoot, ref.Tree().Dump())
+	c.Assert(err, qt.IsNil)
+	c.Assert(src.UpdateProcess(pid, func(p *types.Process) error {
+		p.Census.CensusRoot = censusRoot
+		return nil
+	}), qt.IsNil)
+	c.Assert(src.PushPendingBallot(mkBallot(pid, testutil.RandomVoteID())), qt.IsNil)
+	process, err := src.Process(pid)
+	c.Assert(err, qt.IsNil)
+
+	export, err := src.ExportProcess(pid, 0, testExportPassphrase)
+	c.Assert(err, qt.IsNil)
+
+	// the census import fails once the state tree is imported, so the
+	// state tree is removed and nothing else is stored
+	broken := *export
+	broken.Census = &CensusExport{Root: export.Census.Root}
+	err = dst.ImportProcess(&broken, testExportPassphrase, 0, process)
+	c.Assert(err, qt.ErrorMatches, "import census: .*")
+	_, err = dst.Process(pid)
+	c.Assert(errors.Is(err, ErrNotFound), qt.IsTrue)
+	c.Assert(dst.CensusDB().ExistsByRoot(censusRoot), qt.IsFalse)
+	c.Assert(dst.CountPendingBallots(), qt.Equals, 0)
+	stateEntries := 0
+	c.Assert(prefixeddb.NewPrefixedReader(dst.stateDB, pid.Bytes()).Iterate(nil, func(_, _ []byte) bool {
+		stateEntries++
+		return true
+	}), qt.IsNil)
+	c.Assert(stateEntries, qt.Equals, 0)
+
+	// the failed import does not prevent importing the process again
+	c.Assert(dst.ImportProcess(export, testExportPassphrase, 0, process), qt.IsNil)
+	c.Assert(dst.CountPendingBallots(), qt.Equals, 1)
+	c.Assert(dst.CensusDB().ExistsByRoot(censusRoot), qt.IsTrue)
+}
@@ -1,6 +1,7 @@
 package storage
 
 import (
+	"errors"
 	"fmt"
 	"math/big"
 
@@ -8,6 +9,8 @@ import (
 	bjj "github.com/vocdoni/davinci-node/crypto/ecc/bjj_gnark"
 	"github.com/vocdoni/davinci-node/crypto/ecc/curves"
 	"github.com/vocdoni/davinci-node/crypto/elgamal"
+	"github.com/vocdoni/davinci-node/db"
+	"github.com/vocdoni/davinci-node/db/prefixeddb"
 	"github.com/vocdoni/davinci-node/types"
 )
 
@@ -42,39 +45,70 @@ func (s *Storage) GenerateProcessEncryptionKeys() (ecc.Point, *big.Int, error) {
 
 // setEncryptionKeysUnsafe stores the given encryption public and private keys,
 // without locking the storage and using the public key as the key in storage.
+// If a key wrapper is configured, the private key is stored wrapped with it.
 func (s *Storage) setEncryptionKeysUnsafe(publicKey ecc.Point, privateKey *big.Int) error {
+	wTx := s.db.WriteTx()
+	defer wTx.Discard()
+	if err := s.writeEncryptionKeysUnsafe(wTx, publicKey, privateKey); err != nil {
+		return err
+	}
+	return wTx.Commit()
+}
+
+// writeEncryptionKeysUnsafe is like setEncryptionKeysUnsafe, but it writes
+// the keys in the write transaction provided, which is committed by the
+// caller.
+func (s *Storage) writeEncryptionKeysUnsafe(wTx db.WriteTx, publicKey ecc.Point, privateKey *big.Int) error {
 	x, y := publicKey.Point()
 	eks := &EncryptionKeys{
-		X:          x,
-		Y:          y,
-		PrivateKey: privateKey,
+		X: x,
+		Y: y,
 	}
-	return s.setArtifact(encryptionKeyPrefix, publicKey.Marshal(), eks)
+	key := publicKey.Marshal()
+	if err := s.wrapPrivateKeyUnsafe(key, eks, privateKey); err != nil {
+		return fmt.Errorf("could not wrap private key: %w", err)
+	}
+	data, err := EncodeArtifact(eks)
+	if err != nil {
+		return err
+	}
+	return prefixeddb.NewPrefixedWriteTx(wTx, encryptionKeyPrefix).Set(key, data)
 }
 
 // setEncryptionPubKeyUnsafe stores the given encryption public key, without
 // locking the storage and using the public key as the key and nil private key
-// as the value in storage.
+// as the value in storage. The existing encryption keys are never
+// overwritten, even if their private key can not be unwrapped with the
+// configured key wrappers.
 func (s *Storage) setEncryptionPubKeyUnsafe(publicKey ecc.Point) error {
 	// Check if the encryption keys already exist
-	if _, _, err := s.encryptionKeysUnsafe(publicKey); err == nil {
+	err := s.getArtifact(encryptionKeyPrefix, publicKey.Marshal(), new(EncryptionKeys))
+	if err == nil {
 		return nil
 	}
+	if !errors.Is(err, ErrNotFound) {
+		return fmt.Errorf("could not check encryption keys: %w", err)
+	}
 	// If not, create them but just with the public key
 	return s.setEncryptionKeysUnsafe(publicKey, nil)
 }
 
 // encryptionKeysUnsafe loads the encryption keys for the given public key,
-// without locking the storage.
+// without locking the storage. The private key is unwrapped if needed.
 func (s *Storage) encryptionKeysUnsafe(publicKey ecc.Point) (ecc.Point, *big.Int, error) {
 	eks := new(EncryptionKeys)
-	if err := s.getArtifact(encryptionKeyPrefix, publicKey.Marshal(), eks); err != nil {
+	key := publicKey.Marshal()
+	if err := s.getArtifact(encryptionKeyPrefix, key, eks); err != nil {
 		return nil, nil, err
 	}
 	if eks.X == nil || eks.Y == nil {
 		return nil, nil, fmt.Errorf("not found or malformed encryption keys")
 	}
-	return eks.Point(), eks.PrivateKey, nil
+	privateKey, err := s.unwrapPrivateKeyUnsafe(key, eks)
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not unwrap private key: %w", err)
+	}
+	return eks.Point(), privateKey, nil
 }
 
 // generateEncryptionKeysUnsafe generates a new encryption key pair, using
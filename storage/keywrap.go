@@ -0,0 +1,144 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"slices"
+
+	"github.com/vocdoni/davinci-node/crypto/keywrap"
+	"github.com/vocdoni/davinci-node/log"
+)
+
+// ErrKeyWrapperNotFound is returned when a process private key is wrapped
+// with a key-encryption key that is not configured in the storage.
+var ErrKeyWrapperNotFound = errors.New("key wrapper not found for encryption keys")
+
+// SetKeyWrapper configures the key wrapper used to encrypt the process
+// private keys at rest. The previous key wrappers are only used to unwrap
+// the keys wrapped with them. Every stored private key that is in the clear
+// (databases created before key wrapping) or wrapped with a previous key
+// wrapper is rewrapped with the current one, so rotating the key-encryption
+// key only requires providing the old one as previous once. It returns the
+// number of rewrapped keys.
+func (s *Storage) SetKeyWrapper(current keywrap.KeyWrapper, previous ...keywrap.KeyWrapper) (int, error) {
+	if current == nil {
+		return 0, fmt.Errorf("nil key wrapper")
+	}
+	s.globalLock.Lock()
+	defer s.globalLock.Unlock()
+	s.keyWrapper = current
+	s.keyUnwrappers = map[string]keywrap.KeyWrapper{current.ID(): current}
+	for _, kw := range previous {
+		if kw != nil {
+			if _, ok := s.keyUnwrappers[kw.ID()]; !ok {
+				s.keyUnwrappers[kw.ID()] = kw
+			}
+		}
+	}
+	return s.rewrapEncryptionKeysUnsafe()
+}
+
+// MissingKeyWrappers returns the IDs of the key wrappers used to wrap stored
+// private keys that are not configured in the storage, so they can not be
+// unwrapped. It must be empty before the results of any process can be
+// decrypted.
+func (s *Storage) MissingKeyWrappers() ([]string, error) {
+	s.globalLock.Lock()
+	defer s.globalLock.Unlock()
+	keys, err := s.listArtifacts(encryptionKeyPrefix)
+	if err != nil {
+		return nil, fmt.Errorf("could not list encryption keys: %w", err)
+	}
+	var missing []string
+	for _, key := range keys {
+		eks := new(EncryptionKeys)
+		if err := s.getArtifact(encryptionKeyPrefix, key, eks); err != nil {
+			return nil, fmt.Errorf("could not get encryption keys %x: %w", key, err)
+		}
+		if eks.WrappedPrivateKey == nil || slices.Contains(missing, eks.KeyWrapperID) {
+			continue
+		}
+		if _, ok := s.keyUnwrappers[eks.KeyWrapperID]; !ok {
+			missing = append(missing, eks.KeyWrapperID)
+		}
+	}
+	return missing, nil
+}
+
+// rewrapEncryptionKeysUnsafe wraps with the current key wrapper every stored
+// private key that is in the clear or wrapped with another key wrapper,
+// without locking the storage. It returns the number of rewrapped keys.
+func (s *Storage) rewrapEncryptionKeysUnsafe() (int, error) {
+	keys, err := s.listArtifacts(encryptionKeyPrefix)
+	if err != nil {
+		return 0, fmt.Errorf("could not list encryption keys: %w", err)
+	}
+	rewrapped := 0
+	for _, key := range keys {
+		eks := new(EncryptionKeys)
+		if err := s.getArtifact(encryptionKeyPrefix, key, eks); err != nil {
+			return rewrapped, fmt.Errorf("could not get encryption keys %x: %w", key, err)
+		}
+		if eks.PrivateKey == nil && (eks.WrappedPrivateKey == nil || eks.KeyWrapperID == s.keyWrapper.ID()) {
+			continue
+		}
+		privateKey, err := s.unwrapPrivateKeyUnsafe(key, eks)
+		if err != nil {
+			return rewrapped, fmt.Errorf("could not unwrap encryption keys %x: %w", key, err)
+		}
+		if err := s.wrapPrivateKeyUnsafe(key, eks, privateKey); err != nil {
+			return rewrapped, fmt.Errorf("could not wrap encryption keys %x: %w", key, err)
+		}
+		if err := s.setArtifact(encryptionKeyPrefix, key, eks); err != nil {
+			return rewrapped, fmt.Errorf("could not store encryption keys %x: %w", key, err)
+		}
+		rewrapped++
+	}
+	if rewrapped > 0 {
+		log.Infow("process private keys rewrapped",
+			"keys", rewrapped,
+			"keyWrapper", s.keyWrapper.ID())
+	}
+	return rewrapped, nil
+}
+
+// wrapPrivateKeyUnsafe sets the private key into the encryption keys
+// provided, wrapped with the current key wrapper and bound to the storage key
+// (the marshaled public key). If no key wrapper is configured, the private
+// key is kept in the clear.
+func (s *Storage) wrapPrivateKeyUnsafe(key []byte, eks *EncryptionKeys, privateKey *big.Int) error {
+	eks.PrivateKey, eks.WrappedPrivateKey, eks.KeyWrapperID = nil, nil, ""
+	if privateKey == nil {
+		return nil
+	}
+	if s.keyWrapper == nil {
+		eks.PrivateKey = privateKey
+		return nil
+	}
+	wrapped, err := s.keyWrapper.Wrap(privateKey.Bytes(), key)
+	if err != nil {
+		return err
+	}
+	eks.WrappedPrivateKey = wrapped
+	eks.KeyWrapperID = s.keyWrapper.ID()
+	return nil
+}
+
+// unwrapPrivateKeyUnsafe returns the private key of the encryption keys
+// provided, unwrapping it with the key wrapper that wrapped it. It returns
+// nil if the encryption keys have no private key.
+func (s *Storage) unwrapPrivateKeyUnsafe(key []byte, eks *EncryptionKeys) (*big.Int, error) {
+	if eks.WrappedPrivateKey == nil {
+		return eks.PrivateKey, nil
+	}
+	kw, ok := s.keyUnwrappers[eks.KeyWrapperID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrKeyWrapperNotFound, eks.KeyWrapperID)
+	}
+	secret, err := kw.Unwrap(eks.WrappedPrivateKey, key)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(secret), nil
+}
@@ -0,0 +1,84 @@
+package storage
+
+import (
+	"path/filepath"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/vocdoni/davinci-node/crypto/keywrap"
+	"github.com/vocdoni/davinci-node/db"
+	"github.com/vocdoni/davinci-node/db/metadb"
+)
+
+func TestKeyWrapperMigrationAndRotation(t *testing.T) {
+	c := qt.New(t)
+
+	dir := t.TempDir()
+	testDB, err := metadb.New(db.TypePebble, filepath.Join(dir, "db"))
+	c.Assert(err, qt.IsNil)
+	st := New(testDB)
+	defer st.Close()
+
+	// Generate a key pair without key wrapper, as older databases did
+	publicKey, privateKey, err := st.GenerateProcessEncryptionKeys()
+	c.Assert(err, qt.IsNil)
+	stored := new(EncryptionKeys)
+	c.Assert(st.getArtifact(encryptionKeyPrefix, publicKey.Marshal(), stored), qt.IsNil)
+	c.Assert(stored.PrivateKey, qt.DeepEquals, privateKey)
+
+	// Configuring a key wrapper wraps the existing private keys
+	kw1, err := keywrap.NewLocalKeyWrapper(filepath.Join(dir, "kek1.json"), "first")
+	c.Assert(err, qt.IsNil)
+	rewrapped, err := st.SetKeyWrapper(kw1)
+	c.Assert(err, qt.IsNil)
+	c.Assert(rewrapped, qt.Equals, 1)
+	stored = new(EncryptionKeys)
+	c.Assert(st.getArtifact(encryptionKeyPrefix, publicKey.Marshal(), stored), qt.IsNil)
+	c.Assert(stored.PrivateKey, qt.IsNil)
+	c.Assert(stored.KeyWrapperID, qt.Equals, kw1.ID())
+	_, got, err := st.encryptionKeysUnsafe(publicKey)
+	c.Assert(err, qt.IsNil)
+	c.Assert(got, qt.DeepEquals, privateKey)
+
+	// New keys are stored wrapped
+	publicKey2, privateKey2, err := st.GenerateProcessEncryptionKeys()
+	c.Assert(err, qt.IsNil)
+	stored = new(EncryptionKeys)
+	c.Assert(st.getArtifact(encryptionKeyPrefix, publicKey2.Marshal(), stored), qt.IsNil)
+	c.Assert(stored.PrivateKey, qt.IsNil)
+	c.Assert(stored.WrappedPrivateKey, qt.Not(qt.HasLen), 0)
+
+	// Without the previous key wrapper, the keys can not be unwrapped
+	kw2, err := keywrap.NewLocalKeyWrapper(filepath.Join(dir, "kek2.json"), "second")
+	c.Assert(err, qt.IsNil)
+	_, err = st.SetKeyWrapper(kw2)
+	c.Assert(err, qt.ErrorIs, ErrKeyWrapperNotFound)
+	missing, err := st.MissingKeyWrappers()
+	c.Assert(err, qt.IsNil)
+	c.Assert(missing, qt.DeepEquals, []string{kw1.ID()})
+
+	// Storing the public key again (i.e. the process is registered again)
+	// must not overwrite the keys that can not be unwrapped
+	c.Assert(st.setEncryptionPubKeyUnsafe(publicKey), qt.IsNil)
+	stored = new(EncryptionKeys)
+	c.Assert(st.getArtifact(encryptionKeyPrefix, publicKey.Marshal(), stored), qt.IsNil)
+	c.Assert(stored.KeyWrapperID, qt.Equals, kw1.ID())
+	c.Assert(stored.WrappedPrivateKey, qt.Not(qt.HasLen), 0)
+
+	// Rotating the key wrapper rewraps every key with the new one
+	rewrapped, err = st.SetKeyWrapper(kw2, kw1)
+	c.Assert(err, qt.IsNil)
+	c.Assert(rewrapped, qt.Equals, 2)
+	rewrapped, err = st.SetKeyWrapper(kw2)
+	c.Assert(err, qt.IsNil)
+	c.Assert(rewrapped, qt.Equals, 0)
+	_, got, err = st.encryptionKeysUnsafe(publicKey)
+	c.Assert(err, qt.IsNil)
+	c.Assert(got, qt.DeepEquals, privateKey)
+	_, got, err = st.encryptionKeysUnsafe(publicKey2)
+	c.Assert(err, qt.IsNil)
+	c.Assert(got, qt.DeepEquals, privateKey2)
+	missing, err = st.MissingKeyWrappers()
+	c.Assert(err, qt.IsNil)
+	c.Assert(missing, qt.HasLen, 0)
+}
@@ -1,7 +1,9 @@
 package storage
 
 import (
+	"errors"
 	"fmt"
+	"math/big"
 	"time"
 
 	"github.com/vocdoni/davinci-node/log"
@@ -105,7 +107,7 @@ func (s *Storage) NewProcess(process *types.Process) error {
 	if err := pState.SetRootAsBigInt(process.StateRoot.MathBigInt()); err != nil {
 		return fmt.Errorf("failed to set process state root: %w", err)
 	}
-	return s.setArtifact(processPrefix, process.ID.Bytes(), process)
+	return s.setProcessUnsafe(*process.ID, process)
 }
 
 // UpdateProcess performs an atomic read-modify-write operation on a process.
@@ -129,6 +131,10 @@ func (s *Storage) UpdateProcess(processID types.ProcessID, updateFunc ...func(*t
 	}
 
 	// Apply the update functions, each of which can modify the process state
+	var prevStateRoot *big.Int
+	if p.StateRoot != nil {
+		prevStateRoot = new(big.Int).Set(p.StateRoot.MathBigInt())
+	}
 	for _, f := range updateFunc {
 		if err := f(p); err != nil {
 			return fmt.Errorf("update function failed: %w", err)
@@ -136,10 +142,15 @@ func (s *Storage) UpdateProcess(processID types.ProcessID, updateFunc ...func(*t
 	}
 
 	// Write back atomically
-	if err := s.setArtifact(processPrefix, processID.Bytes(), p); err != nil {
+	if err := s.setProcessUnsafe(processID, p); err != nil {
 		return fmt.Errorf("failed to save updated process: %w", err)
 	}
 
+	// The done vote IDs included in the new state are settled now
+	if p.StateRoot != nil && (prevStateRoot == nil || prevStateRoot.Cmp(p.StateRoot.MathBigInt()) != 0) {
+		s.publishSettledVoteIDsUnsafe(processID)
+	}
+
 	return nil
 }
 
@@ -333,8 +344,21 @@ func (s *Storage) listProcessesWithEncryptionKeys() ([]types.ProcessID, error) {
 		if process.EncryptionKey == nil {
 			continue
 		}
-		if _, _, err := s.encryptionKeysUnsafe(ProcessEncryptionKeyToPoint(process.EncryptionKey)); err != nil {
-			continue
+		// Check the stored keys without unwrapping the private key, which is
+		// only needed to decrypt the results
+		eks := new(EncryptionKeys)
+		if err := s.getArtifact(encryptionKeyPrefix, ProcessEncryptionKeyToPoint(process.EncryptionKey).Marshal(), eks); err != nil {
+			if errors.Is(err, ErrNotFound) {
+				continue
+			}
+			return nil, fmt.Errorf("error retrieving encryption keys of process %x: %w", processID, err)
+		}
+		if eks.WrappedPrivateKey != nil {
+			if _, ok := s.keyUnwrappers[eks.KeyWrapperID]; !ok {
+				log.Errorw(fmt.Errorf("%w: %s", ErrKeyWrapperNotFound, eks.KeyWrapperID),
+					"encryption keys of process "+processID.String()+" can not be unwrapped")
+				continue
+			}
 		}
 
 		processIDs = append(processIDs, processID)
@@ -392,9 +416,21 @@ func (s *Storage) checkAndUpdateEndedProcesses() {
 				}
 				log.Infow("process status updated to ended", "processID", processID.String())
 				// Cleanup ended process data
-				if err := s.cleanupEndedProcess(processID); err != nil {
+				timedOut, err := s.cleanupEndedProcess(processID)
+				if err != nil {
 					log.Errorw(err, "failed to cleanup ended process "+processID.String())
 				}
+				endTime := p.StartTime.Add(p.Duration).Unix()
+				s.PublishProcessEvent(ProcessEventEnded, processID, map[string]any{
+					"endTime": endTime,
+				})
+				// Votes still in the pipeline when the process ended are timed out
+				if timedOut > 0 {
+					s.PublishProcessEvent(ProcessEventTimeout, processID, map[string]any{
+						"endTime":       endTime,
+						"timedOutVotes": timedOut,
+					})
+				}
 			}
 		}
 	}
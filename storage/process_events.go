@@ -0,0 +1,84 @@
+package storage
+
+import (
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/vocdoni/davinci-node/types"
+)
+
+// Process lifecycle event types.
+const (
+	ProcessEventCreated                = "process.created"
+	ProcessEventCensusDownloaded       = "census.downloaded"
+	ProcessEventStateTransitionSettled = "stateTransition.settled"
+	ProcessEventStateTransitionReorged = "stateTransition.reorged"
+	ProcessEventResults                = "process.results"
+	ProcessEventEnded                  = "process.ended"
+	ProcessEventTimeout                = "process.timeout"
+)
+
+// ProcessEventTypes lists every process lifecycle event type.
+var ProcessEventTypes = []string{
+	ProcessEventCreated,
+	ProcessEventCensusDownloaded,
+	ProcessEventStateTransitionSettled,
+	ProcessEventStateTransitionReorged,
+	ProcessEventResults,
+	ProcessEventEnded,
+	ProcessEventTimeout,
+}
+
+// ProcessEvent is something that happened on the lifecycle of a process, as
+// notified to the process event listeners.
+type ProcessEvent struct {
+	Type           string          `json:"type"`
+	ProcessID      types.ProcessID `json:"processId"`
+	OrganizationID common.Address  `json:"organizationId"`
+	Time           time.Time       `json:"time"`
+	Data           map[string]any  `json:"data,omitempty"`
+}
+
+// ProcessEventListener is a function called for every process event. It is
+// called synchronously by the component that publishes the event, so it must
+// not block.
+type ProcessEventListener func(event *ProcessEvent)
+
+// processEventListeners keeps the process event listeners with their own
+// lock, so events can be published from any component.
+type processEventListeners struct {
+	lock      sync.RWMutex
+	listeners []ProcessEventListener
+}
+
+// AddProcessEventListener registers a listener for the process lifecycle
+// events.
+func (s *Storage) AddProcessEventListener(listener ProcessEventListener) {
+	s.processEvents.lock.Lock()
+	defer s.processEvents.lock.Unlock()
+	s.processEvents.listeners = append(s.processEvents.listeners, listener)
+}
+
+// PublishProcessEvent notifies a process lifecycle event to the listeners.
+// The organization of the process is resolved from storage if the process is
+// known. It must not be called while holding the globalLock.
+func (s *Storage) PublishProcessEvent(eventType string, processID types.ProcessID, data map[string]any) {
+	s.processEvents.lock.RLock()
+	defer s.processEvents.lock.RUnlock()
+	if len(s.processEvents.listeners) == 0 {
+		return
+	}
+	event := &ProcessEvent{
+		Type:      eventType,
+		ProcessID: processID,
+		Time:      time.Now(),
+		Data:      data,
+	}
+	if p, err := s.Process(processID); err == nil {
+		event.OrganizationID = p.OrganizationID
+	}
+	for _, listener := range s.processEvents.listeners {
+		listener(event)
+	}
+}
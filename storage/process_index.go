@@ -0,0 +1,403 @@
+package storage
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/vocdoni/davinci-node/db"
+	"github.com/vocdoni/davinci-node/db/prefixeddb"
+	"github.com/vocdoni/davinci-node/log"
+	"github.com/vocdoni/davinci-node/types"
+)
+
+const (
+	// DefaultProcessListLimit is the number of processes returned by
+	// ListProcessSummaries when no limit is provided.
+	DefaultProcessListLimit = 50
+	// MaxProcessListLimit is the maximum number of processes returned by a
+	// single ListProcessSummaries call.
+	MaxProcessListLimit = 500
+)
+
+// ErrInvalidCursor is returned when the pagination cursor provided to
+// ListProcessSummaries can not be decoded.
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// ProcessSummary is a lightweight view of a process stored next to the
+// process itself. It contains the fields used to filter the process list,
+// so listing processes does not require decoding every process.
+type ProcessSummary struct {
+	ID             types.ProcessID     `json:"id"             cbor:"0,keyasint,omitempty"`
+	Status         types.ProcessStatus `json:"status"         cbor:"1,keyasint,omitempty"`
+	OrganizationID common.Address      `json:"organizationId" cbor:"2,keyasint,omitempty"`
+	CensusOrigin   types.CensusOrigin  `json:"censusOrigin"   cbor:"3,keyasint,omitempty"`
+	StartTime      time.Time           `json:"startTime"      cbor:"4,keyasint,omitempty"`
+	EndTime        time.Time           `json:"endTime"        cbor:"5,keyasint,omitempty"`
+	MetadataURI    string              `json:"metadataURI"    cbor:"6,keyasint,omitempty"`
+	VotersCount    *types.BigInt       `json:"votersCount"    cbor:"7,keyasint,omitempty"`
+	HasResults     bool                `json:"hasResults"     cbor:"8,keyasint,omitempty"`
+}
+
+// NewProcessSummary builds the summary of the process provided.
+func NewProcessSummary(p *types.Process) *ProcessSummary {
+	summary := &ProcessSummary{
+		Status:         p.Status,
+		OrganizationID: p.OrganizationID,
+		StartTime:      p.StartTime,
+		EndTime:        p.StartTime.Add(p.Duration),
+		MetadataURI:    p.MetadataURI,
+		VotersCount:    p.VotersCount,
+		HasResults:     len(p.Result) > 0,
+	}
+	if p.ID != nil {
+		summary.ID = *p.ID
+	}
+	if p.Census != nil {
+		summary.CensusOrigin = p.Census.CensusOrigin
+	}
+	return summary
+}
+
+// ProcessFilter defines the filters and pagination parameters accepted by
+// ListProcessSummaries. Nil or zero fields are ignored.
+type ProcessFilter struct {
+	Status         *types.ProcessStatus
+	OrganizationID *common.Address
+	CensusOrigin   *types.CensusOrigin
+	HasResults     *bool
+	// ProcessIDVersion keeps only the processes whose ID has this version,
+	// which identifies the chain and contract that created them.
+	ProcessIDVersion *[4]byte
+	// From and To define a time window, only the processes whose voting
+	// period overlaps it are returned.
+	From time.Time
+	To   time.Time
+	// Cursor is the opaque value returned by a previous call to continue
+	// the listing from the last returned process.
+	Cursor string
+	Limit  int
+}
+
+// processIndex defines a secondary index over the process summaries. The
+// index keys are the index value followed by the process ID, so iterating
+// over an index value returns the processes sorted by ID.
+type processIndex struct {
+	prefix []byte
+	value  func(*ProcessSummary) ([]byte, bool)
+}
+
+var (
+	processSummaryPrefix     = []byte("psum/")
+	processStatusIndex       = []byte("pis/")
+	processOrganizationIndex = []byte("pio/")
+	processCensusOriginIndex = []byte("pic/")
+	processResultsIndex      = []byte("pir/")
+	processStartTimeIndex    = []byte("pit/")
+	processEndTimeIndex      = []byte("pie/")
+)
+
+var processIndexes = []processIndex{
+	{processStatusIndex, func(s *ProcessSummary) ([]byte, bool) {
+		return []byte{byte(s.Status)}, true
+	}},
+	{processOrganizationIndex, func(s *ProcessSummary) ([]byte, bool) {
+		return s.OrganizationID.Bytes(), true
+	}},
+	{processCensusOriginIndex, func(s *ProcessSummary) ([]byte, bool) {
+		return []byte{byte(s.CensusOrigin)}, true
+	}},
+	{processResultsIndex, func(s *ProcessSummary) ([]byte, bool) {
+		return nil, s.HasResults
+	}},
+	{processStartTimeIndex, func(s *ProcessSummary) ([]byte, bool) {
+		return encodeIndexTime(s.StartTime), true
+	}},
+	{processEndTimeIndex, func(s *ProcessSummary) ([]byte, bool) {
+		return encodeIndexTimeDesc(s.EndTime), true
+	}},
+}
+
+// encodeIndexTime encodes a time as a big endian unix timestamp, so the
+// lexicographic order of the keys follows the chronological order. Times
+// before the unix epoch are stored as zero.
+func encodeIndexTime(t time.Time) []byte {
+	ts := max(t.Unix(), 0)
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, uint64(ts))
+	return b
+}
+
+// encodeIndexTimeDesc encodes a time like encodeIndexTime but with its bits
+// inverted, so the lexicographic order of the keys follows the reverse
+// chronological order.
+func encodeIndexTimeDesc(t time.Time) []byte {
+	b := encodeIndexTime(t)
+	for i := range b {
+		b[i] = ^b[i]
+	}
+	return b
+}
+
+// processIndexKey returns the full key of the index entry of the summary.
+func processIndexKey(idx processIndex, summary *ProcessSummary) ([]byte, bool) {
+	value, ok := idx.value(summary)
+	if !ok {
+		return nil, false
+	}
+	key := append([]byte{}, idx.prefix...)
+	key = append(key, value...)
+	return append(key, summary.ID.Bytes()...), true
+}
+
+// setProcessUnsafe stores the process together with its summary and updates
+// the secondary indexes in a single write transaction. The index entries of
+// the previous summary are removed if they changed. It assumes the caller
+// already holds the globalLock.
+func (s *Storage) setProcessUnsafe(processID types.ProcessID, p *types.Process) error {
+	wTx := s.db.WriteTx()
+	defer wTx.Discard()
+	if err := s.writeProcessUnsafe(wTx, processID, p); err != nil {
+		return err
+	}
+	return wTx.Commit()
+}
+
+// writeProcessUnsafe is like setProcessUnsafe, but it writes the process in
+// the write transaction provided, which is committed by the caller.
+func (s *Storage) writeProcessUnsafe(wTx db.WriteTx, processID types.ProcessID, p *types.Process) error {
+	if p == nil {
+		return fmt.Errorf("nil process data")
+	}
+	if p.ID == nil {
+		p.ID = &processID
+	}
+	data, err := EncodeArtifact(p)
+	if err != nil {
+		return err
+	}
+	summary := NewProcessSummary(p)
+	summaryData, err := EncodeArtifact(summary)
+	if err != nil {
+		return err
+	}
+	prev := &ProcessSummary{}
+	hasPrev := s.getArtifact(processSummaryPrefix, processID.Bytes(), prev) == nil
+
+	if err := prefixeddb.NewPrefixedWriteTx(wTx, processPrefix).Set(processID.Bytes(), data); err != nil {
+		return err
+	}
+	return writeProcessIndexes(wTx, summary, summaryData, prev, hasPrev)
+}
+
+// writeProcessIndexes stores the summary and its index entries in the write
+// transaction provided, removing the stale entries of the previous summary.
+func writeProcessIndexes(wTx db.WriteTx, summary *ProcessSummary, summaryData []byte, prev *ProcessSummary, hasPrev bool) error {
+	if err := prefixeddb.NewPrefixedWriteTx(wTx, processSummaryPrefix).Set(summary.ID.Bytes(), summaryData); err != nil {
+		return err
+	}
+	for _, idx := range processIndexes {
+		newKey, hasNew := processIndexKey(idx, summary)
+		if hasPrev {
+			if oldKey, hasOld := processIndexKey(idx, prev); hasOld && (!hasNew || !bytes.Equal(oldKey, newKey)) {
+				if err := wTx.Delete(oldKey); err != nil {
+					return fmt.Errorf("delete process index entry: %w", err)
+				}
+			}
+		}
+		if hasNew {
+			if err := wTx.Set(newKey, []byte{}); err != nil {
+				return fmt.Errorf("set process index entry: %w", err)
+			}
+		}
+	}
+	return nil
+}
+
+// ProcessSummary returns the summary of the process identified by the ID
+// provided. It returns ErrNotFound if the process does not exist.
+func (s *Storage) ProcessSummary(processID types.ProcessID) (*ProcessSummary, error) {
+	s.globalLock.Lock()
+	defer s.globalLock.Unlock()
+
+	summary := &ProcessSummary{}
+	if err := s.getArtifact(processSummaryPrefix, processID.Bytes(), summary); err != nil {
+		return nil, err
+	}
+	return summary, nil
+}
+
+// ListProcessSummaries returns the summaries of the processes that match the
+// filter provided, sorted by the secondary index used to resolve the query,
+// and a cursor to get the next page. The cursor is empty when there are no
+// more results. The most selective filter is used to drive the iteration
+// over its index, while the rest of filters are checked against the
+// summaries of the candidates as the index is iterated, so the iteration
+// ends as soon as the page is complete.
+func (s *Storage) ListProcessSummaries(filter *ProcessFilter) ([]*ProcessSummary, string, error) {
+	if filter == nil {
+		filter = &ProcessFilter{}
+	}
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = DefaultProcessListLimit
+	}
+	limit = min(limit, MaxProcessListLimit)
+
+	var cursor []byte
+	if filter.Cursor != "" {
+		var err error
+		if cursor, err = hex.DecodeString(filter.Cursor); err != nil {
+			return nil, "", fmt.Errorf("%w: %v", ErrInvalidCursor, err)
+		}
+	}
+
+	// Select the index that drives the iteration. The stop function is used
+	// to end the iteration early over the time indexes once the keys are
+	// out of the requested window: the start time index is iterated until
+	// the start of the window end, and the end time index, sorted from the
+	// latest end time, until the end time reaches the window start.
+	var prefix []byte
+	var stop func(key []byte) bool
+	switch {
+	case filter.OrganizationID != nil:
+		prefix = append(append([]byte{}, processOrganizationIndex...), filter.OrganizationID.Bytes()...)
+	case filter.HasResults != nil && *filter.HasResults:
+		prefix = processResultsIndex
+	case filter.Status != nil:
+		prefix = append(append([]byte{}, processStatusIndex...), byte(*filter.Status))
+	case filter.CensusOrigin != nil:
+		prefix = append(append([]byte{}, processCensusOriginIndex...), byte(*filter.CensusOrigin))
+	case !filter.To.IsZero():
+		prefix = processStartTimeIndex
+		to := encodeIndexTime(filter.To)
+		stop = func(key []byte) bool {
+			return len(key) >= len(to) && bytes.Compare(key[:len(to)], to) > 0
+		}
+	case !filter.From.IsZero():
+		prefix = processEndTimeIndex
+		from := encodeIndexTimeDesc(filter.From)
+		stop = func(key []byte) bool {
+			return len(key) >= len(from) && bytes.Compare(key[:len(from)], from) > 0
+		}
+	default:
+		prefix = processSummaryPrefix
+	}
+
+	s.globalLock.Lock()
+	defer s.globalLock.Unlock()
+
+	drivenBySummaries := bytes.Equal(prefix, processSummaryPrefix)
+	var summaries []*ProcessSummary
+	var lastKey []byte
+	more := false
+	var iterErr error
+	if err := prefixeddb.NewPrefixedReader(s.db, prefix).Iterate(nil, func(k, v []byte) bool {
+		if cursor != nil && bytes.Compare(k, cursor) <= 0 {
+			return true
+		}
+		if stop != nil && stop(k) {
+			return false
+		}
+		if len(k) < types.ProcessIDLen {
+			return true
+		}
+		summary := &ProcessSummary{}
+		if drivenBySummaries {
+			if iterErr = DecodeArtifact(v, summary); iterErr != nil {
+				return false
+			}
+		} else {
+			pid := k[len(k)-types.ProcessIDLen:]
+			if err := s.getArtifact(processSummaryPrefix, pid, summary); err != nil {
+				log.Warnw("process index entry without summary", "processID", hex.EncodeToString(pid))
+				return true
+			}
+		}
+		if !filter.match(summary) {
+			return true
+		}
+		// one extra match is required to know if there are more pages
+		if len(summaries) == limit {
+			more = true
+			return false
+		}
+		summaries = append(summaries, summary)
+		lastKey = bytes.Clone(k)
+		return true
+	}); err != nil {
+		return nil, "", err
+	}
+	if iterErr != nil {
+		return nil, "", fmt.Errorf("could not decode process summary: %w", iterErr)
+	}
+	if !more {
+		return summaries, "", nil
+	}
+	return summaries, hex.EncodeToString(lastKey), nil
+}
+
+// match returns true if the summary matches every filter.
+func (f *ProcessFilter) match(s *ProcessSummary) bool {
+	if f.Status != nil && s.Status != *f.Status {
+		return false
+	}
+	if f.OrganizationID != nil && s.OrganizationID != *f.OrganizationID {
+		return false
+	}
+	if f.CensusOrigin != nil && s.CensusOrigin != *f.CensusOrigin {
+		return false
+	}
+	if f.HasResults != nil && s.HasResults != *f.HasResults {
+		return false
+	}
+	if f.ProcessIDVersion != nil && s.ID.Version() != *f.ProcessIDVersion {
+		return false
+	}
+	if !f.From.IsZero() && !s.EndTime.After(f.From) {
+		return false
+	}
+	if !f.To.IsZero() && !s.StartTime.Before(f.To) {
+		return false
+	}
+	return true
+}
+
+// reindexProcesses builds the summary and secondary indexes of the stored
+// processes that do not have one yet, which is the case of the databases
+// created before the indexes were introduced.
+func (s *Storage) reindexProcesses() error {
+	s.globalLock.Lock()
+	defer s.globalLock.Unlock()
+
+	pidsBytes, err := s.listArtifacts(processPrefix)
+	if err != nil {
+		return err
+	}
+	reindexed := 0
+	for _, pid := range pidsBytes {
+		if err := s.getArtifact(processSummaryPrefix, pid, &ProcessSummary{}); err == nil {
+			continue
+		}
+		processID, err := types.BytesToProcessID(pid)
+		if err != nil {
+			return err
+		}
+		p := &types.Process{}
+		if err := s.getArtifact(processPrefix, pid, p); err != nil {
+			return fmt.Errorf("could not get process %x: %w", pid, err)
+		}
+		if err := s.setProcessUnsafe(processID, p); err != nil {
+			return fmt.Errorf("could not index process %x: %w", pid, err)
+		}
+		reindexed++
+	}
+	if reindexed > 0 {
+		log.Infow("process indexes rebuilt", "processes", reindexed)
+	}
+	return nil
+}
@@ -0,0 +1,143 @@
+package storage
+
+import (
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/vocdoni/davinci-node/db"
+	"github.com/vocdoni/davinci-node/db/metadb"
+	"github.com/vocdoni/davinci-node/internal/testutil"
+	"github.com/vocdoni/davinci-node/types"
+)
+
+func TestListProcessSummaries(t *testing.T) {
+	c := qt.New(t)
+
+	testDB, err := metadb.New(db.TypePebble, t.TempDir())
+	c.Assert(err, qt.IsNil)
+	st := New(testDB)
+	defer st.Close()
+
+	orgA := testutil.DeterministicAddress(1)
+	orgB := testutil.DeterministicAddress(2)
+	now := time.Now().Truncate(time.Second)
+
+	// Create 10 processes, the even ones owned by orgA and the odd ones by
+	// orgB, each one starting one hour after the previous one.
+	pids := make([]types.ProcessID, 10)
+	for i := range pids {
+		pids[i] = testutil.DeterministicProcessID(uint64(i + 1))
+		p := testutil.RandomProcess(pids[i])
+		p.OrganizationID = orgA
+		if i%2 == 1 {
+			p.OrganizationID = orgB
+		}
+		p.StartTime = now.Add(time.Duration(i) * time.Hour)
+		p.Duration = 30 * time.Minute
+		c.Assert(st.NewProcess(p), qt.IsNil)
+	}
+
+	// Paginate over every process
+	var listed []*ProcessSummary
+	cursor := ""
+	pages := 0
+	for {
+		page, next, err := st.ListProcessSummaries(&ProcessFilter{Limit: 3, Cursor: cursor})
+		c.Assert(err, qt.IsNil)
+		listed = append(listed, page...)
+		pages++
+		if next == "" {
+			break
+		}
+		cursor = next
+	}
+	c.Assert(listed, qt.HasLen, len(pids))
+	c.Assert(pages, qt.Equals, 4)
+
+	// Filter by organization
+	summaries, next, err := st.ListProcessSummaries(&ProcessFilter{OrganizationID: &orgB})
+	c.Assert(err, qt.IsNil)
+	c.Assert(next, qt.Equals, "")
+	c.Assert(summaries, qt.HasLen, 5)
+	for _, s := range summaries {
+		c.Assert(s.OrganizationID, qt.Equals, orgB)
+	}
+
+	// Update the status of a process, the status index must follow it
+	c.Assert(st.UpdateProcess(pids[3], ProcessUpdateCallbackSetStatus(types.ProcessStatusEnded)), qt.IsNil)
+	ended := types.ProcessStatusEnded
+	summaries, _, err = st.ListProcessSummaries(&ProcessFilter{Status: &ended})
+	c.Assert(err, qt.IsNil)
+	c.Assert(summaries, qt.HasLen, 1)
+	c.Assert(summaries[0].ID, qt.Equals, pids[3])
+	ready := types.ProcessStatusReady
+	summaries, _, err = st.ListProcessSummaries(&ProcessFilter{Status: &ready, OrganizationID: &orgB})
+	c.Assert(err, qt.IsNil)
+	c.Assert(summaries, qt.HasLen, 4)
+
+	// Filter by results
+	c.Assert(st.UpdateProcess(pids[4], func(p *types.Process) error {
+		p.Result = []*types.BigInt{types.NewInt(1)}
+		return nil
+	}), qt.IsNil)
+	hasResults := true
+	summaries, _, err = st.ListProcessSummaries(&ProcessFilter{HasResults: &hasResults})
+	c.Assert(err, qt.IsNil)
+	c.Assert(summaries, qt.HasLen, 1)
+	c.Assert(summaries[0].ID, qt.Equals, pids[4])
+
+	// Filter by time window, it overlaps the processes 2, 3 and 4
+	summaries, _, err = st.ListProcessSummaries(&ProcessFilter{
+		From: now.Add(2*time.Hour + 10*time.Minute),
+		To:   now.Add(4*time.Hour + 10*time.Minute),
+	})
+	c.Assert(err, qt.IsNil)
+	c.Assert(summaries, qt.HasLen, 3)
+	c.Assert(summaries[0].ID, qt.Equals, pids[2])
+	c.Assert(summaries[2].ID, qt.Equals, pids[4])
+
+	// Filter only by the window start, the processes are sorted from the
+	// latest end time
+	summaries, _, err = st.ListProcessSummaries(&ProcessFilter{From: now.Add(7*time.Hour + 10*time.Minute)})
+	c.Assert(err, qt.IsNil)
+	c.Assert(summaries, qt.HasLen, 3)
+	c.Assert(summaries[0].ID, qt.Equals, pids[9])
+	c.Assert(summaries[2].ID, qt.Equals, pids[7])
+
+	// Paginate over a filtered index
+	summaries, next, err = st.ListProcessSummaries(&ProcessFilter{Status: &ready, OrganizationID: &orgB, Limit: 3})
+	c.Assert(err, qt.IsNil)
+	c.Assert(summaries, qt.HasLen, 3)
+	c.Assert(next, qt.Not(qt.Equals), "")
+	summaries, next, err = st.ListProcessSummaries(&ProcessFilter{Status: &ready, OrganizationID: &orgB, Limit: 3, Cursor: next})
+	c.Assert(err, qt.IsNil)
+	c.Assert(summaries, qt.HasLen, 1)
+	c.Assert(next, qt.Equals, "")
+
+	// Invalid cursor
+	_, _, err = st.ListProcessSummaries(&ProcessFilter{Cursor: "not-hex"})
+	c.Assert(err, qt.ErrorIs, ErrInvalidCursor)
+}
+
+func TestReindexProcesses(t *testing.T) {
+	c := qt.New(t)
+
+	testDB, err := metadb.New(db.TypePebble, t.TempDir())
+	c.Assert(err, qt.IsNil)
+	st := New(testDB)
+	defer st.Close()
+
+	// Store a process without summary, as older databases did
+	pid := testutil.DeterministicProcessID(1)
+	c.Assert(st.setArtifact(processPrefix, pid.Bytes(), testutil.RandomProcess(pid)), qt.IsNil)
+	summaries, _, err := st.ListProcessSummaries(nil)
+	c.Assert(err, qt.IsNil)
+	c.Assert(summaries, qt.HasLen, 0)
+
+	c.Assert(st.reindexProcesses(), qt.IsNil)
+	summaries, _, err = st.ListProcessSummaries(nil)
+	c.Assert(err, qt.IsNil)
+	c.Assert(summaries, qt.HasLen, 1)
+	c.Assert(summaries[0].ID, qt.Equals, pid)
+}
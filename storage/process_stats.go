@@ -103,7 +103,7 @@ func (s *Storage) updateProcessStats(processID types.ProcessID, updates []Proces
 		}
 	}
 
-	if err := s.setArtifact(processPrefix, processID.Bytes(), p); err != nil {
+	if err := s.setProcessUnsafe(processID, p); err != nil {
 		return fmt.Errorf("failed to save process after stats update: %w", err)
 	}
 	if err := s.setArtifact(statsPrefix, totalStatsStorageKey, totalStats); err != nil {
@@ -0,0 +1,176 @@
+package storage
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/vocdoni/davinci-node/db/prefixeddb"
+	"github.com/vocdoni/davinci-node/log"
+	"github.com/vocdoni/davinci-node/types"
+)
+
+// AppliedStateTransition is a state transition of a process applied from a
+// chain event. It is tracked until its block is deep enough to be final, so
+// the transition can be rolled back if the block is reorganized out of the
+// chain.
+type AppliedStateTransition struct {
+	ProcessID                types.ProcessID `json:"processId" cbor:"0,keyasint,omitempty"`
+	BlockNumber              uint64          `json:"blockNumber" cbor:"1,keyasint,omitempty"`
+	BlockHash                types.HexBytes  `json:"blockHash" cbor:"2,keyasint,omitempty"`
+	LogIndex                 uint            `json:"logIndex" cbor:"3,keyasint,omitempty"`
+	TxHash                   types.HexBytes  `json:"txHash" cbor:"4,keyasint,omitempty"`
+	OldStateRoot             *types.BigInt   `json:"oldStateRoot" cbor:"5,keyasint,omitempty"`
+	NewStateRoot             *types.BigInt   `json:"newStateRoot" cbor:"6,keyasint,omitempty"`
+	OldVotersCount           *types.BigInt   `json:"oldVotersCount" cbor:"7,keyasint,omitempty"`
+	OldOverwrittenVotesCount *types.BigInt   `json:"oldOverwrittenVotesCount" cbor:"8,keyasint,omitempty"`
+}
+
+// SetAppliedStateTransition tracks a state transition applied from a chain
+// event until it is final.
+func (s *Storage) SetAppliedStateTransition(t *AppliedStateTransition) error {
+	if t == nil || !t.ProcessID.IsValid() {
+		return fmt.Errorf("invalid applied state transition")
+	}
+	s.globalLock.Lock()
+	defer s.globalLock.Unlock()
+	return s.setArtifact(appliedTransitionPrefix, appliedStateTransitionKey(t), t)
+}
+
+// AppliedStateTransitions returns the tracked state transitions of every
+// process, grouped by process and sorted in chain order.
+func (s *Storage) AppliedStateTransitions() (map[types.ProcessID][]*AppliedStateTransition, error) {
+	s.globalLock.Lock()
+	defer s.globalLock.Unlock()
+
+	transitions := make(map[types.ProcessID][]*AppliedStateTransition)
+	var decodeErr error
+	if err := prefixeddb.NewPrefixedReader(s.db, appliedTransitionPrefix).Iterate(nil, func(_, v []byte) bool {
+		t := &AppliedStateTransition{}
+		if decodeErr = DecodeArtifact(v, t); decodeErr != nil {
+			return false
+		}
+		transitions[t.ProcessID] = append(transitions[t.ProcessID], t)
+		return true
+	}); err != nil {
+		return nil, fmt.Errorf("iterate applied state transitions: %w", err)
+	}
+	if decodeErr != nil {
+		return nil, fmt.Errorf("decode applied state transition: %w", decodeErr)
+	}
+	return transitions, nil
+}
+
+// DeleteAppliedStateTransition stops tracking a state transition, together
+// with the batch retained to settle it again, because it is final or it was
+// rolled back.
+func (s *Storage) DeleteAppliedStateTransition(t *AppliedStateTransition) error {
+	s.globalLock.Lock()
+	defer s.globalLock.Unlock()
+
+	if err := s.deleteArtifact(appliedTransitionPrefix, appliedStateTransitionKey(t)); err != nil && !errors.Is(err, ErrNotFound) {
+		return fmt.Errorf("delete applied state transition: %w", err)
+	}
+	if t.NewStateRoot != nil {
+		key := stateTransitionArtifactKey(t.ProcessID, t.NewStateRoot.MathBigInt())
+		if err := s.deleteArtifact(settledTransitionPrefix, key); err != nil && !errors.Is(err, ErrNotFound) {
+			return fmt.Errorf("delete settled state transition batch: %w", err)
+		}
+	}
+	return nil
+}
+
+// retainSettledStateTransition keeps a copy of a settled state transition
+// batch, without its transaction, so it can be settled again if its block is
+// reorganized out of the chain. It assumes the caller holds the global lock.
+func (s *Storage) retainSettledStateTransition(stb *StateTransitionBatch) error {
+	if stb.Inputs.RootHashAfter == nil {
+		return fmt.Errorf("state transition batch has no rootHashAfter")
+	}
+	retained := *stb
+	retained.TxID = nil
+	key := stateTransitionArtifactKey(stb.ProcessID, stb.Inputs.RootHashAfter)
+	return s.setArtifact(settledTransitionPrefix, key, &retained)
+}
+
+// RequeueSettledStateTransition pushes back to the state transitions queue
+// the retained batch that settled the root provided, whose block was
+// reorganized out of the chain, so it is settled again. It returns
+// ErrNotFound if the batch was not settled by this node.
+func (s *Storage) RequeueSettledStateTransition(processID types.ProcessID, rootAfter *big.Int) error {
+	s.globalLock.Lock()
+	defer s.globalLock.Unlock()
+
+	key := stateTransitionArtifactKey(processID, rootAfter)
+	stb := &StateTransitionBatch{}
+	if err := s.getArtifact(settledTransitionPrefix, key, stb); err != nil {
+		return err
+	}
+	val, err := EncodeArtifact(stb)
+	if err != nil {
+		return fmt.Errorf("encode state transition batch: %w", err)
+	}
+	wTx := prefixeddb.NewPrefixedWriteTx(s.db.WriteTx(), stateTransitionPrefix)
+	if err := wTx.Set(append(bytes.Clone(processID.Bytes()), hashKey(val)...), val); err != nil {
+		wTx.Discard()
+		return fmt.Errorf("requeue state transition batch: %w", err)
+	}
+	if err := wTx.Commit(); err != nil {
+		return fmt.Errorf("requeue state transition batch: %w", err)
+	}
+	if err := s.deleteArtifact(settledTransitionPrefix, key); err != nil {
+		log.Warnw("failed to delete requeued state transition batch",
+			"processID", processID.String(),
+			"rootAfter", rootAfter.String(),
+			"error", err.Error())
+	}
+
+	// The batch is not settled anymore
+	if err := s.updateProcessStats(processID, []ProcessStatsUpdate{
+		{TypeStats: types.TypeStatsSettledStateTransitions, Delta: -1},
+	}); err != nil {
+		log.Warnw("failed to update process stats after requeuing state transition batch",
+			"processID", processID.String(),
+			"error", err.Error())
+	}
+	for _, ballot := range stb.Ballots {
+		if err := s.setVoteIDStatus(processID, ballot.VoteID, VoteIDStatusProcessed); err != nil {
+			log.Warnw("failed to set vote ID status to processed", "error", err.Error())
+		}
+	}
+	return nil
+}
+
+// cleanReorgTrackingForProcess removes the applied state transitions and the
+// retained settled batches of a process.
+func (s *Storage) cleanReorgTrackingForProcess(processID types.ProcessID) error {
+	for _, prefix := range [][]byte{appliedTransitionPrefix, settledTransitionPrefix} {
+		var keys [][]byte
+		if err := prefixeddb.NewPrefixedReader(s.db, prefix).Iterate(processID.Bytes(), func(k, _ []byte) bool {
+			// Ensure key has processID prefix
+			if !bytes.HasPrefix(k, processID.Bytes()) {
+				k = append(bytes.Clone(processID.Bytes()), k...)
+			}
+			keys = append(keys, bytes.Clone(k))
+			return true
+		}); err != nil {
+			return fmt.Errorf("iterate reorg tracking: %w", err)
+		}
+		for _, key := range keys {
+			if err := s.deleteArtifact(prefix, key); err != nil && !errors.Is(err, ErrNotFound) {
+				return fmt.Errorf("delete reorg tracking: %w", err)
+			}
+		}
+	}
+	return nil
+}
+
+// appliedStateTransitionKey returns the key of an applied state transition,
+// which sorts the transitions of a process in chain order.
+func appliedStateTransitionKey(t *AppliedStateTransition) []byte {
+	key := bytes.Clone(t.ProcessID.Bytes())
+	key = binary.BigEndian.AppendUint64(key, t.BlockNumber)
+	return binary.BigEndian.AppendUint32(key, uint32(t.LogIndex))
+}
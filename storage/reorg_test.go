@@ -0,0 +1,50 @@
+package storage
+
+import (
+	"path/filepath"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/vocdoni/davinci-node/db"
+	"github.com/vocdoni/davinci-node/db/metadb"
+	"github.com/vocdoni/davinci-node/internal/testutil"
+)
+
+func TestAppliedStateTransitions(t *testing.T) {
+	c := qt.New(t)
+	testDB, err := metadb.New(db.TypePebble, filepath.Join(t.TempDir(), "db"))
+	c.Assert(err, qt.IsNil)
+	st := New(testDB)
+	defer st.Close()
+
+	processID := testutil.RandomProcessID()
+	other := testutil.RandomProcessID()
+	for _, applied := range []*AppliedStateTransition{
+		{ProcessID: processID, BlockNumber: 12, LogIndex: 0, NewStateRoot: testutil.DeterministicStateRoot(3)},
+		{ProcessID: processID, BlockNumber: 10, LogIndex: 4, NewStateRoot: testutil.DeterministicStateRoot(2)},
+		{ProcessID: processID, BlockNumber: 10, LogIndex: 1, NewStateRoot: testutil.DeterministicStateRoot(1)},
+		{ProcessID: other, BlockNumber: 11, NewStateRoot: testutil.DeterministicStateRoot(4)},
+	} {
+		c.Assert(st.SetAppliedStateTransition(applied), qt.IsNil)
+	}
+
+	// the transitions are grouped by process in chain order
+	tracked, err := st.AppliedStateTransitions()
+	c.Assert(err, qt.IsNil)
+	c.Assert(tracked, qt.HasLen, 2)
+	c.Assert(tracked[processID], qt.HasLen, 3)
+	for i, applied := range tracked[processID] {
+		c.Assert(applied.NewStateRoot, qt.DeepEquals, testutil.DeterministicStateRoot(uint64(i+1)))
+	}
+
+	c.Assert(st.DeleteAppliedStateTransition(tracked[processID][0]), qt.IsNil)
+	c.Assert(st.cleanReorgTrackingForProcess(other), qt.IsNil)
+	tracked, err = st.AppliedStateTransitions()
+	c.Assert(err, qt.IsNil)
+	c.Assert(tracked, qt.HasLen, 1)
+	c.Assert(tracked[processID], qt.HasLen, 2)
+
+	// a batch not settled by this node can not be requeued
+	err = st.RequeueSettledStateTransition(processID, testutil.DeterministicStateRoot(1).MathBigInt())
+	c.Assert(err, qt.ErrorIs, ErrNotFound)
+}
@@ -0,0 +1,64 @@
+package storage
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/vocdoni/davinci-node/crypto/elgamal"
+	"github.com/vocdoni/davinci-node/types"
+)
+
+// SetResultsDecryption stores the decryption of the results accumulator of a
+// process at the state root provided, with the proof of correct decryption
+// of every ciphertext, so it can be published. Ciphertexts, results and
+// proofs must follow the order of the accumulator fields.
+func (s *Storage) SetResultsDecryption(
+	processID types.ProcessID,
+	stateRoot *big.Int,
+	ciphertexts []elgamal.Ciphertext,
+	results []*big.Int,
+	proofs []*elgamal.DecryptionProof,
+) error {
+	if len(ciphertexts) != len(results) || len(ciphertexts) != len(proofs) {
+		return fmt.Errorf("mismatched results decryption: %d ciphertexts, %d results, %d proofs",
+			len(ciphertexts), len(results), len(proofs))
+	}
+	rd := &types.ResultsDecryption{
+		ProcessID:   processID,
+		StateRoot:   types.BigIntConverter(stateRoot),
+		Ciphertexts: make([]types.DKGCiphertext, len(ciphertexts)),
+		Results:     make([]*types.BigInt, len(results)),
+		Proofs:      make([]types.DKGProof, len(proofs)),
+	}
+	for i := range ciphertexts {
+		if ciphertexts[i].C1 == nil || ciphertexts[i].C2 == nil || results[i] == nil || proofs[i] == nil {
+			return fmt.Errorf("incomplete results decryption for field %d", i)
+		}
+		rd.Ciphertexts[i] = types.DKGCiphertext{
+			C1: types.DKGPointFromPoint(ciphertexts[i].C1),
+			C2: types.DKGPointFromPoint(ciphertexts[i].C2),
+		}
+		rd.Results[i] = types.BigIntConverter(results[i])
+		rd.Proofs[i] = types.DKGProof{
+			A1: types.DKGPointFromPoint(proofs[i].A1),
+			A2: types.DKGPointFromPoint(proofs[i].A2),
+			Z:  types.BigIntConverter(proofs[i].Z),
+		}
+	}
+
+	s.globalLock.Lock()
+	defer s.globalLock.Unlock()
+	return s.setArtifact(resultsDecryptionPrefix, processID.Bytes(), rd)
+}
+
+// ResultsDecryption retrieves the published decryption of the results of a
+// process. It returns ErrNotFound if the results are not computed yet.
+func (s *Storage) ResultsDecryption(processID types.ProcessID) (*types.ResultsDecryption, error) {
+	s.globalLock.Lock()
+	defer s.globalLock.Unlock()
+	rd := &types.ResultsDecryption{}
+	if err := s.getArtifact(resultsDecryptionPrefix, processID.Bytes(), rd); err != nil {
+		return nil, err
+	}
+	return rd, nil
+}
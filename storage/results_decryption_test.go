@@ -0,0 +1,69 @@
+package storage
+
+import (
+	"errors"
+	"math/big"
+	"path/filepath"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/vocdoni/davinci-node/crypto/ecc/curves"
+	"github.com/vocdoni/davinci-node/crypto/elgamal"
+	"github.com/vocdoni/davinci-node/db"
+	"github.com/vocdoni/davinci-node/db/metadb"
+	"github.com/vocdoni/davinci-node/internal/testutil"
+	specutil "github.com/vocdoni/davinci-node/spec/util"
+)
+
+func TestResultsDecryption(t *testing.T) {
+	c := qt.New(t)
+	testDB, err := metadb.New(db.TypePebble, filepath.Join(t.TempDir(), "db"))
+	c.Assert(err, qt.IsNil)
+	st := New(testDB)
+	defer st.Close()
+
+	processID := testutil.RandomProcessID()
+	_, err = st.ResultsDecryption(processID)
+	c.Assert(errors.Is(err, ErrNotFound), qt.IsTrue)
+
+	publicKey, privateKey, err := elgamal.GenerateKey(curves.New(encKeyCurveType))
+	c.Assert(err, qt.IsNil)
+	var (
+		ciphertexts []elgamal.Ciphertext
+		results     []*big.Int
+		proofs      []*elgamal.DecryptionProof
+	)
+	for i := range 3 {
+		result := big.NewInt(int64(i * 5))
+		k, err := specutil.RandomK()
+		c.Assert(err, qt.IsNil)
+		ct, err := elgamal.NewCiphertext(publicKey).Encrypt(result, publicKey, k)
+		c.Assert(err, qt.IsNil)
+		proof, err := elgamal.BuildDecryptionProof(privateKey, publicKey, ct.C1, ct.C2, result)
+		c.Assert(err, qt.IsNil)
+		ciphertexts = append(ciphertexts, *ct)
+		results = append(results, result)
+		proofs = append(proofs, proof)
+	}
+
+	// every result needs its ciphertext and proof
+	c.Assert(st.SetResultsDecryption(processID, big.NewInt(1), ciphertexts, results[:2], proofs), qt.IsNotNil)
+	c.Assert(st.SetResultsDecryption(processID, big.NewInt(1), ciphertexts, results, proofs), qt.IsNil)
+
+	rd, err := st.ResultsDecryption(processID)
+	c.Assert(err, qt.IsNil)
+	c.Assert(rd.ProcessID, qt.Equals, processID)
+	c.Assert(rd.StateRoot.MathBigInt().Int64(), qt.Equals, int64(1))
+	c.Assert(rd.Results, qt.HasLen, 3)
+	c.Assert(rd.Proofs, qt.HasLen, 3)
+	for i := range results {
+		c.Assert(rd.Results[i].MathBigInt().Cmp(results[i]), qt.Equals, 0)
+		// the stored proofs are still valid for the stored ciphertexts
+		proof, err := dkgProofToDecryptionProof(rd.Proofs[i])
+		c.Assert(err, qt.IsNil)
+		c.Assert(elgamal.VerifyDecryptionProof(publicKey,
+			rd.Ciphertexts[i].C1.ToPoint(publicKey),
+			rd.Ciphertexts[i].C2.ToPoint(publicKey),
+			results[i], proof), qt.IsNil)
+	}
+}
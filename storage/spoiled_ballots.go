@@ -0,0 +1,73 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/vocdoni/davinci-node/crypto/elgamal"
+	"github.com/vocdoni/davinci-node/db/prefixeddb"
+	"github.com/vocdoni/davinci-node/types"
+)
+
+// ErrBallotSpoiled is returned when a vote of a spoiled ballot is pushed,
+// since the spoiled ballots are never tallied.
+var ErrBallotSpoiled = errors.New("ballot is spoiled")
+
+// SpoiledBallot is a ballot spoiled by its voter to audit its encryption
+// (a Benaloh challenge). It reveals the randomness that encrypts the ballot
+// and the field values that the client claimed to encrypt, and records
+// whether they match. It is recorded, but never tallied.
+type SpoiledBallot struct {
+	ProcessID   types.ProcessID `json:"processId" cbor:"0,keyasint,omitempty"`
+	VoteID      types.VoteID    `json:"voteId" cbor:"1,keyasint,omitempty"`
+	Address     types.HexBytes  `json:"address" cbor:"2,keyasint,omitempty"`
+	Ballot      *elgamal.Ballot `json:"ballot" cbor:"3,keyasint,omitempty"`
+	K           *types.BigInt   `json:"k" cbor:"4,keyasint,omitempty"`
+	FieldValues []*types.BigInt `json:"fieldValues" cbor:"5,keyasint,omitempty"`
+	Verified    bool            `json:"verified" cbor:"6,keyasint,omitempty"`
+	SpoiledAt   time.Time       `json:"spoiledAt" cbor:"7,keyasint,omitempty"`
+}
+
+// SetSpoiledBallot records a spoiled ballot, so its vote can not be cast
+// anymore. It returns ErroBallotAlreadyExists if the vote was already
+// submitted, because a cast ballot can not be spoiled.
+func (s *Storage) SetSpoiledBallot(sb *SpoiledBallot) error {
+	if sb == nil || !sb.ProcessID.IsValid() {
+		return fmt.Errorf("invalid spoiled ballot")
+	}
+	s.globalLock.Lock()
+	defer s.globalLock.Unlock()
+
+	if s.IsVoteIDProcessing(sb.VoteID) {
+		return ErroBallotAlreadyExists
+	}
+	statuses := prefixeddb.NewPrefixedReader(s.db, voteIDStatusPrefix)
+	if _, err := statuses.Get(createVoteIDStatusKey(sb.ProcessID, sb.VoteID)); err == nil {
+		return ErroBallotAlreadyExists
+	}
+	if sb.SpoiledAt.IsZero() {
+		sb.SpoiledAt = time.Now()
+	}
+	return s.setArtifact(spoiledBallotPrefix, createVoteIDStatusKey(sb.ProcessID, sb.VoteID), sb)
+}
+
+// SpoiledBallot returns the spoiled ballot of a vote. It returns ErrNotFound
+// if the ballot of the vote was not spoiled.
+func (s *Storage) SpoiledBallot(processID types.ProcessID, voteID types.VoteID) (*SpoiledBallot, error) {
+	s.globalLock.Lock()
+	defer s.globalLock.Unlock()
+	sb := &SpoiledBallot{}
+	if err := s.getArtifact(spoiledBallotPrefix, createVoteIDStatusKey(processID, voteID), sb); err != nil {
+		return nil, err
+	}
+	return sb, nil
+}
+
+// isBallotSpoiledUnsafe reports whether the ballot of a vote was spoiled. It
+// assumes the caller holds the global lock.
+func (s *Storage) isBallotSpoiledUnsafe(processID types.ProcessID, voteID types.VoteID) bool {
+	pr := prefixeddb.NewPrefixedReader(s.db, spoiledBallotPrefix)
+	_, err := pr.Get(createVoteIDStatusKey(processID, voteID))
+	return err == nil
+}
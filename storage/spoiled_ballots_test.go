@@ -0,0 +1,43 @@
+package storage
+
+import (
+	"errors"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/vocdoni/davinci-node/internal/testutil"
+	"github.com/vocdoni/davinci-node/types"
+)
+
+func TestSpoiledBallots(t *testing.T) {
+	c := qt.New(t)
+	stg := newTestStorage(t)
+	defer stg.Close()
+
+	pid := testutil.RandomProcessID()
+	ensureProcess(t, stg, pid)
+	spoiledVoteID := testutil.RandomVoteID()
+	_, err := stg.SpoiledBallot(pid, spoiledVoteID)
+	c.Assert(errors.Is(err, ErrNotFound), qt.IsTrue)
+
+	c.Assert(stg.SetSpoiledBallot(&SpoiledBallot{
+		ProcessID:   pid,
+		VoteID:      spoiledVoteID,
+		K:           types.NewInt(7),
+		FieldValues: []*types.BigInt{types.NewInt(1)},
+		Verified:    true,
+	}), qt.IsNil)
+	sb, err := stg.SpoiledBallot(pid, spoiledVoteID)
+	c.Assert(err, qt.IsNil)
+	c.Assert(sb.Verified, qt.IsTrue)
+	c.Assert(sb.K.MathBigInt().Int64(), qt.Equals, int64(7))
+	c.Assert(sb.SpoiledAt.IsZero(), qt.IsFalse)
+
+	// the vote of a spoiled ballot can not be cast
+	c.Assert(stg.PushPendingBallot(mkBallot(pid, spoiledVoteID)), qt.ErrorIs, ErrBallotSpoiled)
+
+	// and a cast ballot can not be spoiled
+	castVoteID := testutil.RandomVoteID()
+	c.Assert(stg.PushPendingBallot(mkBallot(pid, castVoteID)), qt.IsNil)
+	c.Assert(stg.SetSpoiledBallot(&SpoiledBallot{ProcessID: pid, VoteID: castVoteID}), qt.ErrorIs, ErroBallotAlreadyExists)
+}
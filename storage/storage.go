@@ -7,7 +7,14 @@ The storage uses a key-value database with prefixed namespaces to organize diffe
 
 ## Process Management
 - p/  : processID → Process metadata (status, times, ballot mode, census info)
-- ek/ : processID → Encryption keys (public and private keys for ballot encryption)
+- psum/ : processID → ProcessSummary (fields used to filter and list processes)
+- pis/  : status + processID → nil (status index)
+- pio/  : organizationID + processID → nil (organization index)
+- pic/  : censusOrigin + processID → nil (census origin index)
+- pir/  : processID → nil (processes with results index)
+- pit/  : startTime + processID → nil (start time index)
+- pie/  : inverted endTime + processID → nil (end time index, latest first)
+- ek/ : publicKey → Encryption keys (public and private keys for ballot encryption, the private key wrapped with the configured key wrapper)
 
 ## Ballot Processing Pipeline
 
@@ -36,6 +43,9 @@ The ballot processing follows these stages:
 ## Vote Tracking
   - vs/ : processID + voteID → status byte
     Status values: 0=pending, 1=verified, 2=aggregated, 3=processed, 4=settled, 5=error
+  - rcpt/ : processID + voteID → VoteReceipt (state transition and blob that settled the vote)
+  - rcpb/ : processID + ballotIndex → voteID (last vote settled on the ballot index)
+  - spl/  : processID + voteID → SpoiledBallot (ballots spoiled to audit their encryption, never tallied)
 
 ## Statistics
 - s/  : various keys for process and global statistics
@@ -49,6 +59,16 @@ The ballot processing follows these stages:
 ## Pending OnChain Transactions
 - ptx/: ProcessID → nil (tracks if there are pending on-chain tx for a process)
 
+## Distributed Key Generation
+- dkg/ : sessionID → DKGSession (trustees, commitments, encrypted shares and derived public key)
+- dkgk/: publicKey → sessionID (finds the DKG session that derived a process encryption key)
+- tdec/: ProcessID → ThresholdDecryption (trustees partial decryptions, responses and combined proofs)
+
+## Webhooks (owned by the webhooks package)
+- whs/: subscriptionID → Subscription
+- whd/: subscriptionID + deliveryID → Delivery (delivery log)
+- whq/: deliveryID → subscriptionID (pending deliveries queue)
+
 ## Separate Databases
 - cs_ : prefix for census database (merkle trees for voter eligibility)
 - st_ : prefix for state database (merkle trees for vote state)
@@ -68,6 +88,7 @@ import (
 	lru "github.com/hashicorp/golang-lru/v2"
 
 	"github.com/vocdoni/davinci-node/census/censusdb"
+	"github.com/vocdoni/davinci-node/crypto/keywrap"
 	"github.com/vocdoni/davinci-node/db"
 	"github.com/vocdoni/davinci-node/db/prefixeddb"
 	"github.com/vocdoni/davinci-node/log"
@@ -97,6 +118,15 @@ var (
 	censusDBprefix                = []byte("cs_")
 	stateDBprefix                 = []byte("st_")
 	pendingTxPrefix               = []byte("ptx/")
+	dkgSessionPrefix              = []byte("dkg/")
+	dkgPublicKeyPrefix            = []byte("dkgk/")
+	thresholdDecryptionPrefix     = []byte("tdec/")
+	appliedTransitionPrefix       = []byte("atr/")
+	settledTransitionPrefix       = []byte("sst/")
+	resultsDecryptionPrefix       = []byte("rdec/")
+	voteReceiptPrefix             = []byte("rcpt/")
+	voteReceiptBallotPrefix       = []byte("rcpb/")
+	spoiledBallotPrefix           = []byte("spl/")
 
 	maxKeySize = 12
 )
@@ -128,12 +158,16 @@ type Storage struct {
 	cancel              context.CancelFunc
 	censusDB            *censusdb.CensusDB
 	stateDB             db.Database
-	globalLock          sync.Mutex              // Lock for global operations
-	workersLock         sync.Mutex              // Lock for worker-related operations
-	cache               *lru.Cache[string, any] // Cache for artifacts
-	processingVoteIDs   sync.Map                // Map to track voteIDs being processed
-	processingAddresses sync.Map                // Map to track addresses being processed: "processID:address" → struct{}
-	voteIDToAddress     sync.Map                // Map from voteID string → addressInfo for lock release
+	globalLock          sync.Mutex                    // Lock for global operations
+	workersLock         sync.Mutex                    // Lock for worker-related operations
+	cache               *lru.Cache[string, any]       // Cache for artifacts
+	processingVoteIDs   sync.Map                      // Map to track voteIDs being processed
+	processingAddresses sync.Map                      // Map to track addresses being processed: "processID:address" → struct{}
+	voteIDToAddress     sync.Map                      // Map from voteID string → addressInfo for lock release
+	keyWrapper          keywrap.KeyWrapper            // Wraps the process private keys at rest (nil keeps them in the clear)
+	keyUnwrappers       map[string]keywrap.KeyWrapper // Current and previous key wrappers by ID, to unwrap private keys
+	voteIDStatusEvents  voteIDStatusBroker            // Subscriptions to the vote ID status transitions
+	processEvents       processEventListeners         // Listeners of the process lifecycle events
 }
 
 // New creates a new Storage instance.
@@ -157,6 +191,11 @@ func New(db db.Database) *Storage {
 		log.Errorw(err, "failed to clear stale reservations")
 	}
 
+	// build the process indexes missing in databases created before them
+	if err := s.reindexProcesses(); err != nil {
+		log.Errorw(err, "failed to rebuild process indexes")
+	}
+
 	// start monitoring for ended processes
 	s.monitorEndedProcesses()
 
@@ -0,0 +1,461 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"math/big"
+	"slices"
+	"time"
+
+	"github.com/vocdoni/davinci-node/crypto/ecc"
+	"github.com/vocdoni/davinci-node/crypto/ecc/curves"
+	"github.com/vocdoni/davinci-node/crypto/elgamal"
+	"github.com/vocdoni/davinci-node/crypto/elgamal/dkg"
+	"github.com/vocdoni/davinci-node/log"
+	"github.com/vocdoni/davinci-node/types"
+)
+
+var (
+	ErrThresholdDecryptionRound    = errors.New("threshold decryption is not in the expected round")
+	ErrThresholdDecryptionPending  = errors.New("threshold decryption not finished yet")
+	ErrThresholdDecryptionExcluded = errors.New("trustee excluded from the threshold decryption")
+)
+
+// StartThresholdDecryption stores a new threshold decryption of the
+// ciphertexts provided for the process, to be performed by the trustees of
+// the DKG session provided. If the process already has a threshold
+// decryption, it is returned unchanged.
+func (s *Storage) StartThresholdDecryption(
+	processID types.ProcessID,
+	sessionID types.HexBytes,
+	ciphertexts []types.DKGCiphertext,
+) (*types.ThresholdDecryption, error) {
+	s.globalLock.Lock()
+	defer s.globalLock.Unlock()
+
+	if td, err := s.thresholdDecryption(processID); err == nil {
+		return td, nil
+	} else if !errors.Is(err, ErrNotFound) {
+		return nil, err
+	}
+	session, err := s.dkgSession(sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("could not load DKG session: %w", err)
+	}
+	if session.Status != types.DKGSessionStatusReady {
+		return nil, fmt.Errorf("%w: %s", ErrDKGSessionNotFinished, session.Status)
+	}
+	if len(ciphertexts) == 0 {
+		return nil, fmt.Errorf("no ciphertexts to decrypt")
+	}
+	td := &types.ThresholdDecryption{
+		ProcessID:   processID,
+		SessionID:   session.ID,
+		Status:      types.ThresholdDecryptionStatusPartials,
+		Ciphertexts: ciphertexts,
+		Partials:    make(map[int][]types.DKGPartialDecryption),
+		Responses:   make(map[int][]*types.BigInt),
+		CreatedAt:   time.Now(),
+	}
+	if err := s.setArtifact(thresholdDecryptionPrefix, processID.Bytes(), td); err != nil {
+		return nil, fmt.Errorf("could not store threshold decryption: %w", err)
+	}
+	log.Infow("threshold decryption started",
+		"processID", processID.String(),
+		"sessionID", session.ID.String(),
+		"ciphertexts", len(ciphertexts))
+	return td, nil
+}
+
+// ThresholdDecryption retrieves the threshold decryption of the process
+// provided. It returns ErrNotFound if it has not been started.
+func (s *Storage) ThresholdDecryption(processID types.ProcessID) (*types.ThresholdDecryption, error) {
+	s.globalLock.Lock()
+	defer s.globalLock.Unlock()
+	return s.thresholdDecryption(processID)
+}
+
+// thresholdDecryption retrieves a threshold decryption without acquiring the
+// global lock. It assumes the caller already holds the lock.
+func (s *Storage) thresholdDecryption(processID types.ProcessID) (*types.ThresholdDecryption, error) {
+	td := &types.ThresholdDecryption{}
+	if err := s.getArtifact(thresholdDecryptionPrefix, processID.Bytes(), td); err != nil {
+		return nil, err
+	}
+	if td.Partials == nil {
+		td.Partials = make(map[int][]types.DKGPartialDecryption)
+	}
+	if td.Responses == nil {
+		td.Responses = make(map[int][]*types.BigInt)
+	}
+	return td, nil
+}
+
+// SetPartialDecryptions stores the partial decryptions of the trustee
+// provided, one per ciphertext. The Chaum-Pedersen proof of every partial
+// decryption is verified against the verification key of the trustee. When
+// threshold trustees have submitted them, they become the quorum and the
+// challenge of the combined decryption proof of every ciphertext is computed.
+// The partial decryptions submitted after the quorum is formed are stored
+// too, so their trustees can replace the unresponsive quorum trustees if the
+// quorum is formed again by RestartStaleThresholdDecryption.
+func (s *Storage) SetPartialDecryptions(
+	processID types.ProcessID,
+	trusteeIndex int,
+	partials []types.DKGPartialDecryption,
+) error {
+	s.globalLock.Lock()
+	defer s.globalLock.Unlock()
+
+	td, err := s.thresholdDecryption(processID)
+	if err != nil {
+		return err
+	}
+	if td.Status == types.ThresholdDecryptionStatusDone {
+		return fmt.Errorf("%w: %s", ErrThresholdDecryptionRound, td.Status)
+	}
+	session, err := s.dkgSession(td.SessionID)
+	if err != nil {
+		return fmt.Errorf("could not load DKG session: %w", err)
+	}
+	if _, ok := session.Trustee(trusteeIndex); !ok {
+		return fmt.Errorf("%w: %d", ErrDKGUnknownTrustee, trusteeIndex)
+	}
+	if slices.Contains(td.Excluded, trusteeIndex) {
+		return fmt.Errorf("%w: %d", ErrThresholdDecryptionExcluded, trusteeIndex)
+	}
+	if _, ok := td.Partials[trusteeIndex]; ok {
+		return ErrDKGAlreadySubmitted
+	}
+	if len(partials) != len(td.Ciphertexts) {
+		return fmt.Errorf("%w: expected %d partial decryptions, got %d",
+			ErrDKGInvalidSubmission, len(td.Ciphertexts), len(partials))
+	}
+	if slices.ContainsFunc(td.Discarded[trusteeIndex], partials[0].Commitment.Equal) {
+		return fmt.Errorf("%w: commitments already used in a previous attempt", ErrDKGInvalidSubmission)
+	}
+	curve := curves.New(encKeyCurveType)
+	verificationKey := dkgVerificationKey(session, trusteeIndex)
+	for i, partial := range partials {
+		if !partial.Share.Valid() || !partial.Commitment.A1.Valid() || !partial.Commitment.A2.Valid() {
+			return fmt.Errorf("%w: malformed partial decryption %d", ErrDKGInvalidSubmission, i)
+		}
+		proof, err := dkgProofToDecryptionProof(partial.Proof)
+		if err != nil {
+			return fmt.Errorf("%w: partial decryption %d: %w", ErrDKGInvalidSubmission, i, err)
+		}
+		// The share is verified as the decryption of (C1, share) with
+		// plaintext zero, which proves log_G(verificationKey) == log_C1(share)
+		c1 := td.Ciphertexts[i].C1.ToPoint(curve)
+		share := partial.Share.ToPoint(curve)
+		if err := elgamal.VerifyDecryptionProof(verificationKey, c1, share, big.NewInt(0), proof); err != nil {
+			return fmt.Errorf("%w: partial decryption %d: %w", ErrDKGInvalidSubmission, i, err)
+		}
+	}
+	td.Partials[trusteeIndex] = partials
+
+	if td.Status == types.ThresholdDecryptionStatusPartials {
+		if err := formThresholdDecryptionQuorum(session, td); err != nil {
+			return err
+		}
+	}
+	return s.setArtifact(thresholdDecryptionPrefix, processID.Bytes(), td)
+}
+
+// RestartStaleThresholdDecryption forms the quorum of the threshold
+// decryption of the process provided again if the quorum trustees have not
+// responded within the timeout provided. The quorum trustees that did not
+// respond are excluded, and the partial decryptions of the ones that
+// responded are discarded, so they must submit new ones with fresh
+// commitments. The new quorum is formed as soon as threshold trustees have
+// partial decryptions, which may be immediately if enough trustees submitted
+// them after the previous quorum was formed. It returns true if the
+// threshold decryption was restarted. If excluding the unresponsive trustees
+// would leave less than threshold trustees, the current quorum is kept.
+func (s *Storage) RestartStaleThresholdDecryption(processID types.ProcessID, timeout time.Duration) (bool, error) {
+	s.globalLock.Lock()
+	defer s.globalLock.Unlock()
+
+	td, err := s.thresholdDecryption(processID)
+	if err != nil {
+		return false, err
+	}
+	if td.Status != types.ThresholdDecryptionStatusResponses || time.Since(td.QuorumAt) < timeout {
+		return false, nil
+	}
+	session, err := s.dkgSession(td.SessionID)
+	if err != nil {
+		return false, fmt.Errorf("could not load DKG session: %w", err)
+	}
+	excluded := slices.Clone(td.Excluded)
+	for _, index := range td.Quorum {
+		if _, ok := td.Responses[index]; !ok {
+			excluded = append(excluded, index)
+		}
+	}
+	slices.Sort(excluded)
+	eligible := 0
+	for _, index := range session.Participants() {
+		if !slices.Contains(excluded, index) {
+			eligible++
+		}
+	}
+	if eligible < session.Threshold {
+		log.Warnw("threshold decryption quorum not responding, not enough trustees to replace it",
+			"processID", processID.String(),
+			"quorum", td.Quorum,
+			"excluded", excluded)
+		return false, nil
+	}
+	if td.Discarded == nil {
+		td.Discarded = make(map[int][]types.DKGCommitment)
+	}
+	for index, partials := range td.Partials {
+		if _, responded := td.Responses[index]; responded {
+			td.Discarded[index] = append(td.Discarded[index], partials[0].Commitment)
+			delete(td.Partials, index)
+		} else if slices.Contains(excluded, index) {
+			delete(td.Partials, index)
+		}
+	}
+	td.Excluded = excluded
+	td.Attempt++
+	td.Status = types.ThresholdDecryptionStatusPartials
+	td.Quorum = nil
+	td.Challenges = nil
+	td.Responses = make(map[int][]*types.BigInt)
+	td.QuorumAt = time.Time{}
+	log.Warnw("threshold decryption quorum not responding, restarting",
+		"processID", processID.String(),
+		"attempt", td.Attempt,
+		"excluded", td.Excluded)
+	if err := formThresholdDecryptionQuorum(session, td); err != nil {
+		return false, err
+	}
+	if err := s.setArtifact(thresholdDecryptionPrefix, processID.Bytes(), td); err != nil {
+		return false, fmt.Errorf("could not store threshold decryption: %w", err)
+	}
+	return true, nil
+}
+
+// formThresholdDecryptionQuorum forms the quorum of the threshold decryption
+// provided if threshold trustees have submitted their partial decryptions,
+// taking the ones with the lowest indexes, and computes the challenges of
+// the combined decryption proofs. Otherwise, it does nothing.
+func formThresholdDecryptionQuorum(session *types.DKGSession, td *types.ThresholdDecryption) error {
+	if len(td.Partials) < session.Threshold {
+		return nil
+	}
+	quorum := slices.Sorted(maps.Keys(td.Partials))
+	td.Quorum = quorum[:session.Threshold]
+	var err error
+	if td.Challenges, err = thresholdDecryptionChallenges(session, td); err != nil {
+		return fmt.Errorf("could not compute decryption challenges: %w", err)
+	}
+	td.Status = types.ThresholdDecryptionStatusResponses
+	td.QuorumAt = time.Now()
+	log.Infow("threshold decryption quorum reached",
+		"processID", td.ProcessID.String(),
+		"attempt", td.Attempt,
+		"quorum", td.Quorum)
+	return nil
+}
+
+// SetDecryptionResponses stores the responses of a quorum trustee to the
+// challenges of the combined decryption proofs, one per ciphertext. Every
+// response is verified against the commitment and partial decryption of the
+// trustee. When every quorum trustee has responded, the combined decryption
+// proofs are assembled and the threshold decryption is done.
+func (s *Storage) SetDecryptionResponses(
+	processID types.ProcessID,
+	trusteeIndex int,
+	responses []*types.BigInt,
+) error {
+	s.globalLock.Lock()
+	defer s.globalLock.Unlock()
+
+	td, err := s.thresholdDecryption(processID)
+	if err != nil {
+		return err
+	}
+	if td.Status != types.ThresholdDecryptionStatusResponses {
+		return fmt.Errorf("%w: %s", ErrThresholdDecryptionRound, td.Status)
+	}
+	if !slices.Contains(td.Quorum, trusteeIndex) {
+		return fmt.Errorf("%w: trustee %d is not part of the quorum", ErrDKGUnknownTrustee, trusteeIndex)
+	}
+	if _, ok := td.Responses[trusteeIndex]; ok {
+		return ErrDKGAlreadySubmitted
+	}
+	if len(responses) != len(td.Ciphertexts) {
+		return fmt.Errorf("%w: expected %d responses, got %d",
+			ErrDKGInvalidSubmission, len(td.Ciphertexts), len(responses))
+	}
+	session, err := s.dkgSession(td.SessionID)
+	if err != nil {
+		return fmt.Errorf("could not load DKG session: %w", err)
+	}
+	curve := curves.New(encKeyCurveType)
+	order := curve.Order()
+	lambdas, err := dkg.LagrangeCoefficients(td.Quorum, order)
+	if err != nil {
+		return err
+	}
+	verificationKey := dkgVerificationKey(session, trusteeIndex)
+	for i, z := range responses {
+		if z == nil {
+			return fmt.Errorf("%w: missing response %d", ErrDKGInvalidSubmission, i)
+		}
+		partial := td.Partials[trusteeIndex][i]
+		// w = e·λ_i, then check z·G == A1 + w·P_i and z·C1 == A2 + w·S_i
+		w := new(big.Int).Mul(td.Challenges[i].MathBigInt(), lambdas[trusteeIndex])
+		w.Mod(w, order)
+		zG := curve.New()
+		zG.ScalarBaseMult(z.MathBigInt())
+		zC1 := curve.New()
+		zC1.ScalarMult(td.Ciphertexts[i].C1.ToPoint(curve), z.MathBigInt())
+		if !verifySchnorrResponse(zG, w, partial.Commitment.A1.ToPoint(curve), verificationKey) ||
+			!verifySchnorrResponse(zC1, w, partial.Commitment.A2.ToPoint(curve), partial.Share.ToPoint(curve)) {
+			return fmt.Errorf("%w: invalid response %d", ErrDKGInvalidSubmission, i)
+		}
+	}
+	td.Responses[trusteeIndex] = responses
+
+	// If every quorum trustee has responded, assemble the combined proofs.
+	if len(td.Responses) == len(td.Quorum) {
+		publicKey := ProcessEncryptionKeyToPoint(session.PublicKey)
+		td.Proofs = make([]types.DKGProof, 0, len(td.Ciphertexts))
+		for i, ct := range td.Ciphertexts {
+			commitments := make(map[int]dkg.CPCommitment, len(td.Quorum))
+			partZ := make(map[int]*big.Int, len(td.Quorum))
+			for _, index := range td.Quorum {
+				commitment := td.Partials[index][i].Commitment
+				commitments[index] = dkg.CPCommitment{
+					A1: commitment.A1.ToPoint(curve),
+					A2: commitment.A2.ToPoint(curve),
+				}
+				partZ[index] = td.Responses[index][i].MathBigInt()
+			}
+			proof, err := dkg.AssembleDecryptionProof(publicKey, ct.C1.ToPoint(curve), ct.C2.ToPoint(curve),
+				nil, commitments, partZ)
+			if err != nil {
+				return fmt.Errorf("could not assemble decryption proof %d: %w", i, err)
+			}
+			td.Proofs = append(td.Proofs, types.DKGProof{
+				A1: types.DKGPointFromPoint(proof.A1),
+				A2: types.DKGPointFromPoint(proof.A2),
+				Z:  types.BigIntConverter(proof.Z),
+			})
+		}
+		td.Status = types.ThresholdDecryptionStatusDone
+		log.Infow("threshold decryption done",
+			"processID", processID.String(),
+			"quorum", td.Quorum)
+	}
+	return s.setArtifact(thresholdDecryptionPrefix, processID.Bytes(), td)
+}
+
+// ThresholdDecryptionProofs returns the quorum partial decryptions (indexed
+// by trustee) and the combined decryption proof of every ciphertext of a done
+// threshold decryption. It returns ErrThresholdDecryptionPending if the
+// trustees have not finished yet.
+func (s *Storage) ThresholdDecryptionProofs(processID types.ProcessID) (
+	[]map[int]ecc.Point, []*elgamal.DecryptionProof, error,
+) {
+	td, err := s.ThresholdDecryption(processID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if td.Status != types.ThresholdDecryptionStatusDone {
+		return nil, nil, fmt.Errorf("%w: %s", ErrThresholdDecryptionPending, td.Status)
+	}
+	curve := curves.New(encKeyCurveType)
+	partials := make([]map[int]ecc.Point, len(td.Ciphertexts))
+	proofs := make([]*elgamal.DecryptionProof, len(td.Ciphertexts))
+	for i := range td.Ciphertexts {
+		partials[i] = make(map[int]ecc.Point, len(td.Quorum))
+		for _, index := range td.Quorum {
+			partials[i][index] = td.Partials[index][i].Share.ToPoint(curve)
+		}
+		if proofs[i], err = dkgProofToDecryptionProof(td.Proofs[i]); err != nil {
+			return nil, nil, fmt.Errorf("malformed decryption proof %d: %w", i, err)
+		}
+	}
+	return partials, proofs, nil
+}
+
+// thresholdDecryptionChallenges computes the Fiat-Shamir challenge of the
+// combined decryption proof of every ciphertext, following the transcript
+// of elgamal.BuildDecryptionProof: e = H(P, P, C1, D, ΣA1, ΣA2), where D is
+// the Lagrange combination of the quorum partial decryptions.
+func thresholdDecryptionChallenges(session *types.DKGSession, td *types.ThresholdDecryption) ([]*types.BigInt, error) {
+	curve := curves.New(encKeyCurveType)
+	lambdas, err := dkg.LagrangeCoefficients(td.Quorum, curve.Order())
+	if err != nil {
+		return nil, err
+	}
+	publicKey := ProcessEncryptionKeyToPoint(session.PublicKey)
+	challenges := make([]*types.BigInt, 0, len(td.Ciphertexts))
+	for i, ct := range td.Ciphertexts {
+		d, sumA1, sumA2 := curve.New(), curve.New(), curve.New()
+		for _, index := range td.Quorum {
+			partial := td.Partials[index][i]
+			term := curve.New()
+			term.ScalarMult(partial.Share.ToPoint(curve), lambdas[index])
+			d.Add(d, term)
+			sumA1.Add(sumA1, partial.Commitment.A1.ToPoint(curve))
+			sumA2.Add(sumA2, partial.Commitment.A2.ToPoint(curve))
+		}
+		e, err := elgamal.HashPointsToScalar(publicKey, publicKey, ct.C1.ToPoint(curve), d, sumA1, sumA2)
+		if err != nil {
+			return nil, err
+		}
+		challenges = append(challenges, types.BigIntConverter(e))
+	}
+	return challenges, nil
+}
+
+// dkgVerificationKey returns the verification key (d_i·G) of the trustee
+// provided, computed from the commitments of every trustee of the session
+// as Σ_k Σ_j C_{k,j}·i^j.
+func dkgVerificationKey(session *types.DKGSession, trusteeIndex int) ecc.Point {
+	curve := curves.New(encKeyCurveType)
+	order := curve.Order()
+	x := big.NewInt(int64(trusteeIndex))
+	key := curve.New()
+	for _, index := range session.Participants() {
+		xPower := big.NewInt(1)
+		for _, c := range session.Commitments[index] {
+			term := curve.New()
+			term.ScalarMult(c.ToPoint(curve), xPower)
+			key.Add(key, term)
+			xPower.Mul(xPower, x)
+			xPower.Mod(xPower, order)
+		}
+	}
+	return key
+}
+
+// verifySchnorrResponse checks that the response z multiplied by the base
+// of the proof (zBase) matches commitment + w·key.
+func verifySchnorrResponse(zBase ecc.Point, w *big.Int, commitment, key ecc.Point) bool {
+	right := zBase.New()
+	right.ScalarMult(key, w)
+	right.Add(right, commitment)
+	return zBase.Equal(right)
+}
+
+// dkgProofToDecryptionProof converts a DKGProof into an elgamal decryption
+// proof over the encryption keys curve.
+func dkgProofToDecryptionProof(p types.DKGProof) (*elgamal.DecryptionProof, error) {
+	if !p.A1.Valid() || !p.A2.Valid() || p.Z == nil {
+		return nil, fmt.Errorf("malformed decryption proof")
+	}
+	curve := curves.New(encKeyCurveType)
+	return &elgamal.DecryptionProof{
+		A1: p.A1.ToPoint(curve),
+		A2: p.A2.ToPoint(curve),
+		Z:  p.Z.MathBigInt(),
+	}, nil
+}
@@ -40,11 +40,15 @@ type Process struct {
 
 // EncryptionKeys is the struct that contains the public key used to encrypt
 // the ballots. The public key is a point on the elliptic curve. It also
-// contains the private key, but it is not exported in the JSON.
+// contains the private key, but it is not exported in the JSON. When a key
+// wrapper is configured, the private key is only stored wrapped, along with
+// the ID of the key wrapper used, and PrivateKey is nil.
 type EncryptionKeys struct {
-	X          *big.Int `json:"publicKeyX" cbor:"0,keyasint,omitempty"`
-	Y          *big.Int `json:"publicKeyY" cbor:"1,keyasint,omitempty"`
-	PrivateKey *big.Int `json:"-" cbor:"2,keyasint,omitempty"`
+	X                 *big.Int `json:"publicKeyX" cbor:"0,keyasint,omitempty"`
+	Y                 *big.Int `json:"publicKeyY" cbor:"1,keyasint,omitempty"`
+	PrivateKey        *big.Int `json:"-" cbor:"2,keyasint,omitempty"`
+	WrappedPrivateKey []byte   `json:"-" cbor:"3,keyasint,omitempty"`
+	KeyWrapperID      string   `json:"-" cbor:"4,keyasint,omitempty"`
 }
 
 // Point returns the public key as a point on the elliptic curve.
@@ -179,6 +183,7 @@ type StateTransitionBatch struct {
 	BlobVersionHash common.Hash                     `json:"blobVersionHash"`
 	BlobSidecar     *types.BlobTxSidecar            `json:"blobSidecar"`
 	BatchID         []byte                          `json:"batchId"`
+	TxID            types.HexBytes                  `json:"txId,omitempty"` // Transaction sent to settle the batch, if any
 }
 
 // StateTransitionBatchProofInputs is the struct that contains the inputs
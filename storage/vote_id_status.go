@@ -66,20 +66,11 @@ func (s *Storage) voteIDStatus(processID types.ProcessID, voteID types.VoteID) (
 	// If the vote has reached done status in the sequencer, it could be
 	// already settled, but only if it is in the current state
 	if status == VoteIDStatusDone {
-		// Get the current process state root
-		process, err := s.process(processID)
+		settled, err := s.settledVoteIDs(processID, voteID)
 		if err != nil {
 			return 0, err
 		}
-
-		// Load the state of the process
-		pState, err := state.LoadSnapshotOnRoot(s.stateDB, processID, process.StateRoot.MathBigInt())
-		if err != nil {
-			return status, nil
-		}
-
-		// If the vote ID is in the state, it is settled
-		if pState.ContainsVoteID(voteID) {
+		if settled[0] {
 			return VoteIDStatusSettled, nil
 		}
 	}
@@ -88,6 +79,31 @@ func (s *Storage) voteIDStatus(processID types.ProcessID, voteID types.VoteID) (
 	return status, nil
 }
 
+// settledVoteIDs returns whether each of the vote IDs provided is in the
+// current state of the process, so a vote ID with done status is settled.
+// This method assumes the caller already holds the globalLock.
+func (s *Storage) settledVoteIDs(processID types.ProcessID, voteIDs ...types.VoteID) ([]bool, error) {
+	settled := make([]bool, len(voteIDs))
+	// Get the current process state root
+	process, err := s.process(processID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Load the state of the process, if it is not available no vote is
+	// settled yet
+	pState, err := state.LoadSnapshotOnRoot(s.stateDB, processID, process.StateRoot.MathBigInt())
+	if err != nil {
+		return settled, nil
+	}
+
+	// If the vote ID is in the state, it is settled
+	for i, voteID := range voteIDs {
+		settled[i] = pState.ContainsVoteID(voteID)
+	}
+	return settled, nil
+}
+
 // VoteIDStatusName returns the human-readable name of a vote ID status.
 func VoteIDStatusName(status int) string {
 	if name, ok := voteIDStatusNames[status]; ok {
@@ -96,7 +112,7 @@ func VoteIDStatusName(status int) string {
 	return "unknown_status_" + strconv.Itoa(status)
 }
 
-// MarkVoteIDsDone marks a list of vote IDs as settled for a given processID.
+// MarkVoteIDsDone marks a list of vote IDs as done for a given processID.
 // This function is called after a state transition batch is confirmed on the
 // blockchain.
 func (s *Storage) MarkVoteIDsDone(processID types.ProcessID, voteIDs []types.VoteID) error {
@@ -105,7 +121,7 @@ func (s *Storage) MarkVoteIDsDone(processID types.ProcessID, voteIDs []types.Vot
 	return s.markVoteIDsDone(processID, voteIDs)
 }
 
-// markVoteIDsDone marks a list of vote IDs as settled without acquiring locks.
+// markVoteIDsDone marks a list of vote IDs as done without acquiring locks.
 // This method assumes the caller already holds the globalLock.
 func (s *Storage) markVoteIDsDone(processID types.ProcessID, voteIDs []types.VoteID) error {
 	// Use a transaction for better atomicity
@@ -124,7 +140,77 @@ func (s *Storage) markVoteIDsDone(processID types.ProcessID, voteIDs []types.Vot
 		// to allow overwrites after aggregation, not after settlement
 	}
 
-	return wTx.Commit()
+	if err := wTx.Commit(); err != nil {
+		return err
+	}
+	s.publishVoteIDsDoneUnsafe(processID, voteIDs)
+	return nil
+}
+
+// publishVoteIDsDoneUnsafe publishes the status of the vote IDs provided,
+// which have done status, following the same rule as VoteIDStatus: settled
+// if they are in the current state of the process, done otherwise. This
+// method assumes the caller already holds the globalLock.
+func (s *Storage) publishVoteIDsDoneUnsafe(processID types.ProcessID, voteIDs []types.VoteID) {
+	if len(voteIDs) == 0 || !s.hasVoteIDStatusSubscribers(processID) {
+		return
+	}
+	settled, err := s.settledVoteIDs(processID, voteIDs...)
+	if err != nil {
+		log.Warnw("failed to check settled vote IDs",
+			"processID", processID.String(),
+			"error", err)
+		return
+	}
+	var settledIDs, doneIDs []types.VoteID
+	for i, voteID := range voteIDs {
+		if settled[i] {
+			settledIDs = append(settledIDs, voteID)
+		} else {
+			doneIDs = append(doneIDs, voteID)
+		}
+	}
+	s.publishVoteIDStatus(processID, VoteIDStatusSettled, settledIDs...)
+	s.publishVoteIDStatus(processID, VoteIDStatusDone, doneIDs...)
+}
+
+// publishSettledVoteIDsUnsafe publishes the settled status of the vote IDs
+// of the process with done status that are in its current state, once the
+// state root of the process changes. This method assumes the caller already
+// holds the globalLock.
+func (s *Storage) publishSettledVoteIDsUnsafe(processID types.ProcessID) {
+	if !s.hasVoteIDStatusSubscribers(processID) {
+		return
+	}
+	var doneIDs []types.VoteID
+	if err := prefixeddb.NewPrefixedReader(s.db, voteIDStatusPrefix).Iterate(processID.Bytes(), func(k, v []byte) bool {
+		if status, err := bytesToInt(v); err == nil && status == VoteIDStatusDone && len(k) == 8 {
+			doneIDs = append(doneIDs, types.VoteID(binary.BigEndian.Uint64(k)))
+		}
+		return true
+	}); err != nil {
+		log.Warnw("failed to list done vote IDs",
+			"processID", processID.String(),
+			"error", err)
+		return
+	}
+	if len(doneIDs) == 0 {
+		return
+	}
+	settled, err := s.settledVoteIDs(processID, doneIDs...)
+	if err != nil {
+		log.Warnw("failed to check settled vote IDs",
+			"processID", processID.String(),
+			"error", err)
+		return
+	}
+	var settledIDs []types.VoteID
+	for i, voteID := range doneIDs {
+		if settled[i] {
+			settledIDs = append(settledIDs, voteID)
+		}
+	}
+	s.publishVoteIDStatus(processID, VoteIDStatusSettled, settledIDs...)
 }
 
 // MarkProcessVoteIDsTimeout marks all unsettled vote IDs for a process as timeout.
@@ -144,6 +230,7 @@ func (s *Storage) markProcessVoteIDsTimeout(processID types.ProcessID) (int, err
 	defer wTx.Discard()
 
 	var updatedCount int
+	var updatedVoteIDs []types.VoteID
 
 	// Iterate through all vote IDs for this process
 	if err := prefixedDB.Iterate(processID.Bytes(), func(k, v []byte) bool {
@@ -167,6 +254,9 @@ func (s *Storage) markProcessVoteIDsTimeout(processID types.ProcessID) (int, err
 				return true
 			}
 			updatedCount++
+			if len(k) == 8 {
+				updatedVoteIDs = append(updatedVoteIDs, types.VoteID(binary.BigEndian.Uint64(k)))
+			}
 		}
 		return true
 	}); err != nil {
@@ -176,6 +266,7 @@ func (s *Storage) markProcessVoteIDsTimeout(processID types.ProcessID) (int, err
 	if err := wTx.Commit(); err != nil {
 		return 0, fmt.Errorf("error committing timeout status updates: %w", err)
 	}
+	s.publishVoteIDStatus(processID, VoteIDStatusTimeout, updatedVoteIDs...)
 
 	log.Debugw("marked vote IDs as timeout", "processID", processID.String(), "count", updatedCount)
 	return updatedCount, nil
@@ -224,7 +315,11 @@ func (s *Storage) setVoteIDStatus(processID types.ProcessID, voteID types.VoteID
 		return fmt.Errorf("set vote ID status: %w", err)
 	}
 
-	return wTx.Commit()
+	if err := wTx.Commit(); err != nil {
+		return err
+	}
+	s.publishVoteIDStatus(processID, status, voteID)
+	return nil
 }
 
 // isValidStatusTransition checks if a status transition is valid.
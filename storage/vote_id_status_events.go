@@ -0,0 +1,123 @@
+package storage
+
+import (
+	"sync"
+	"time"
+
+	"github.com/vocdoni/davinci-node/log"
+	"github.com/vocdoni/davinci-node/types"
+)
+
+// voteIDStatusEventsBuffer is the number of events buffered for every
+// subscription. If a subscriber does not consume them fast enough, its
+// subscription is cancelled.
+const voteIDStatusEventsBuffer = 64
+
+// VoteIDStatusEvent is a transition of the status of a vote ID, published to
+// the subscribers once it is stored.
+type VoteIDStatusEvent struct {
+	ProcessID types.ProcessID
+	VoteID    types.VoteID
+	Status    int
+	Time      time.Time
+}
+
+// voteIDStatusSubscription is a subscription to the vote ID status events of
+// a process, or of a single vote ID of a process if voteID is not nil.
+type voteIDStatusSubscription struct {
+	processID types.ProcessID
+	voteID    *types.VoteID
+	ch        chan *VoteIDStatusEvent
+}
+
+// voteIDStatusBroker keeps the subscriptions to the vote ID status events. It
+// has its own lock, since events are published while holding the globalLock.
+type voteIDStatusBroker struct {
+	lock sync.Mutex
+	subs map[*voteIDStatusSubscription]struct{}
+}
+
+// SubscribeVoteIDStatus subscribes to the status transitions of the vote IDs
+// of the process provided, or only to the transitions of the vote ID
+// provided if it is not nil. It returns the channel where the events are
+// received and a function to cancel the subscription, which closes the
+// channel. The events are delivered in order. If the subscriber does not
+// consume them fast enough and the channel buffer fills up, the subscription
+// is cancelled instead of dropping events, so the subscriber can read the
+// current status again and subscribe again.
+func (s *Storage) SubscribeVoteIDStatus(processID types.ProcessID, voteID *types.VoteID) (<-chan *VoteIDStatusEvent, func()) {
+	sub := &voteIDStatusSubscription{
+		processID: processID,
+		voteID:    voteID,
+		ch:        make(chan *VoteIDStatusEvent, voteIDStatusEventsBuffer),
+	}
+	s.voteIDStatusEvents.lock.Lock()
+	if s.voteIDStatusEvents.subs == nil {
+		s.voteIDStatusEvents.subs = make(map[*voteIDStatusSubscription]struct{})
+	}
+	s.voteIDStatusEvents.subs[sub] = struct{}{}
+	s.voteIDStatusEvents.lock.Unlock()
+
+	return sub.ch, func() {
+		s.voteIDStatusEvents.lock.Lock()
+		defer s.voteIDStatusEvents.lock.Unlock()
+		s.voteIDStatusEvents.unsubscribeUnsafe(sub)
+	}
+}
+
+// unsubscribeUnsafe removes the subscription and closes its channel, if it
+// was not removed yet. The caller must hold the broker lock.
+func (b *voteIDStatusBroker) unsubscribeUnsafe(sub *voteIDStatusSubscription) {
+	if _, ok := b.subs[sub]; !ok {
+		return
+	}
+	delete(b.subs, sub)
+	close(sub.ch)
+}
+
+// hasVoteIDStatusSubscribers returns whether there is any subscription to
+// the vote ID status events of the process provided.
+func (s *Storage) hasVoteIDStatusSubscribers(processID types.ProcessID) bool {
+	s.voteIDStatusEvents.lock.Lock()
+	defer s.voteIDStatusEvents.lock.Unlock()
+	for sub := range s.voteIDStatusEvents.subs {
+		if sub.processID == processID {
+			return true
+		}
+	}
+	return false
+}
+
+// publishVoteIDStatus sends the status transition of the vote IDs provided
+// to the matching subscribers without blocking. The subscriptions whose
+// buffer is full are cancelled.
+func (s *Storage) publishVoteIDStatus(processID types.ProcessID, status int, voteIDs ...types.VoteID) {
+	s.voteIDStatusEvents.lock.Lock()
+	defer s.voteIDStatusEvents.lock.Unlock()
+	if len(s.voteIDStatusEvents.subs) == 0 {
+		return
+	}
+	now := time.Now()
+	for _, voteID := range voteIDs {
+		event := &VoteIDStatusEvent{
+			ProcessID: processID,
+			VoteID:    voteID,
+			Status:    status,
+			Time:      now,
+		}
+		for sub := range s.voteIDStatusEvents.subs {
+			if sub.processID != processID || (sub.voteID != nil && *sub.voteID != voteID) {
+				continue
+			}
+			select {
+			case sub.ch <- event:
+			default:
+				log.Debugw("vote ID status subscription cancelled, subscriber too slow",
+					"processID", processID.String(),
+					"voteID", voteID.String(),
+					"status", VoteIDStatusName(status))
+				s.voteIDStatusEvents.unsubscribeUnsafe(sub)
+			}
+		}
+	}
+}
@@ -262,3 +262,88 @@ func TestVoteIDStatusName(t *testing.T) {
 	c.Assert(VoteIDStatusName(VoteIDStatusTimeout), qt.Equals, "timeout")
 	c.Assert(VoteIDStatusName(999), qt.Equals, "unknown_status_999")
 }
+
+func TestSubscribeVoteIDStatus(t *testing.T) {
+	c := qt.New(t)
+
+	st := New(metadb.NewTest(t))
+	defer st.Close()
+
+	pid := testutil.DeterministicProcessID(1)
+	c.Assert(st.NewProcess(testutil.RandomProcess(pid)), qt.IsNil)
+	voteID1 := testutil.RandomVoteID()
+	voteID2 := testutil.RandomVoteID()
+
+	processEvents, cancelProcess := st.SubscribeVoteIDStatus(pid, nil)
+	defer cancelProcess()
+	voteEvents, cancelVote := st.SubscribeVoteIDStatus(pid, &voteID1)
+
+	c.Assert(st.setVoteIDStatus(pid, voteID1, VoteIDStatusPending), qt.IsNil)
+	c.Assert(st.setVoteIDStatus(pid, voteID2, VoteIDStatusPending), qt.IsNil)
+	c.Assert(st.setVoteIDStatus(pid, voteID1, VoteIDStatusVerified), qt.IsNil)
+
+	// The process subscription receives every transition
+	for _, want := range []struct {
+		voteID types.VoteID
+		status int
+	}{
+		{voteID1, VoteIDStatusPending},
+		{voteID2, VoteIDStatusPending},
+		{voteID1, VoteIDStatusVerified},
+	} {
+		event := <-processEvents
+		c.Assert(event.ProcessID, qt.Equals, pid)
+		c.Assert(event.VoteID, qt.Equals, want.voteID)
+		c.Assert(event.Status, qt.Equals, want.status)
+	}
+
+	// The vote subscription only receives the transitions of its vote ID
+	c.Assert((<-voteEvents).Status, qt.Equals, VoteIDStatusPending)
+	c.Assert((<-voteEvents).Status, qt.Equals, VoteIDStatusVerified)
+
+	// Done vote IDs are published with the status reported by VoteIDStatus,
+	// they are not settled until they are in the process state
+	c.Assert(st.markVoteIDsDone(pid, []types.VoteID{voteID1}), qt.IsNil)
+	status, err := st.VoteIDStatus(pid, voteID1)
+	c.Assert(err, qt.IsNil)
+	event := <-processEvents
+	c.Assert(event.VoteID, qt.Equals, voteID1)
+	c.Assert(event.Status, qt.Equals, status)
+	c.Assert((<-voteEvents).Status, qt.Equals, status)
+
+	_, err = st.MarkProcessVoteIDsTimeout(pid)
+	c.Assert(err, qt.IsNil)
+	event = <-processEvents
+	c.Assert(event.VoteID, qt.Equals, voteID2)
+	c.Assert(event.Status, qt.Equals, VoteIDStatusTimeout)
+
+	// Cancelling the subscription closes the channel
+	cancelVote()
+	cancelVote()
+	_, ok := <-voteEvents
+	c.Assert(ok, qt.IsFalse)
+}
+
+func TestSubscribeVoteIDStatusLagging(t *testing.T) {
+	c := qt.New(t)
+
+	st := New(metadb.NewTest(t))
+	defer st.Close()
+
+	pid := testutil.DeterministicProcessID(1)
+	voteID := testutil.RandomVoteID()
+	events, cancel := st.SubscribeVoteIDStatus(pid, &voteID)
+	defer cancel()
+
+	// A subscriber that does not consume the events is cancelled instead of
+	// losing events silently
+	for range voteIDStatusEventsBuffer + 1 {
+		st.publishVoteIDStatus(pid, VoteIDStatusPending, voteID)
+	}
+	received := 0
+	for range events {
+		received++
+	}
+	c.Assert(received, qt.Equals, voteIDStatusEventsBuffer)
+	c.Assert(st.hasVoteIDStatusSubscribers(pid), qt.IsFalse)
+}
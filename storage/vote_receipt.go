@@ -0,0 +1,117 @@
+package storage
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math/big"
+
+	"github.com/vocdoni/davinci-node/db/prefixeddb"
+	"github.com/vocdoni/davinci-node/state"
+	"github.com/vocdoni/davinci-node/types"
+)
+
+// VoteReceipt records where a vote was settled on chain: the state
+// transition that included it and the blob of its transaction that carries
+// the ballot, so the voter can verify the inclusion independently.
+type VoteReceipt struct {
+	ProcessID   types.ProcessID   `json:"processId" cbor:"0,keyasint,omitempty"`
+	VoteID      types.VoteID      `json:"voteId" cbor:"1,keyasint,omitempty"`
+	BallotIndex types.BallotIndex `json:"ballotIndex" cbor:"2,keyasint,omitempty"`
+	StateRoot   *types.BigInt     `json:"stateRoot" cbor:"3,keyasint,omitempty"`
+	TxHash      types.HexBytes    `json:"txHash" cbor:"4,keyasint,omitempty"`
+	BlobIndex   int               `json:"blobIndex" cbor:"5,keyasint"`
+}
+
+// SetVoteReceipts records the receipt of every vote carried by the blobs of
+// a state transition settled on chain, which leads to the state root
+// provided.
+func (s *Storage) SetVoteReceipts(
+	processID types.ProcessID,
+	stateRoot *big.Int,
+	txHash types.HexBytes,
+	sidecar *types.BlobTxSidecar,
+) error {
+	s.globalLock.Lock()
+	defer s.globalLock.Unlock()
+	return s.setVoteReceiptsUnsafe(processID, stateRoot, txHash, sidecar)
+}
+
+// setVoteReceiptsUnsafe records the receipts of the votes of a state
+// transition and points their ballot indexes to them, so a later overwrite
+// of the ballot can be detected. It assumes the caller holds the global
+// lock.
+func (s *Storage) setVoteReceiptsUnsafe(
+	processID types.ProcessID,
+	stateRoot *big.Int,
+	txHash types.HexBytes,
+	sidecar *types.BlobTxSidecar,
+) error {
+	if stateRoot == nil || len(txHash) == 0 || sidecar == nil {
+		return fmt.Errorf("incomplete state transition")
+	}
+	wTx := s.db.WriteTx()
+	defer wTx.Discard()
+	receiptsTx := prefixeddb.NewPrefixedWriteTx(wTx, voteReceiptPrefix)
+	ballotsTx := prefixeddb.NewPrefixedWriteTx(wTx, voteReceiptBallotPrefix)
+	for i, blob := range sidecar.Blobs {
+		if blob == nil {
+			continue
+		}
+		blobData, err := state.ParseBlobData(blob.Bytes())
+		if err != nil {
+			return fmt.Errorf("parse blob %d: %w", i, err)
+		}
+		for _, vote := range blobData.Votes {
+			data, err := EncodeArtifact(&VoteReceipt{
+				ProcessID:   processID,
+				VoteID:      vote.VoteID,
+				BallotIndex: vote.BallotIndex,
+				StateRoot:   types.BigIntConverter(stateRoot),
+				TxHash:      txHash,
+				BlobIndex:   i,
+			})
+			if err != nil {
+				return fmt.Errorf("encode vote receipt: %w", err)
+			}
+			if err := receiptsTx.Set(voteReceiptKey(processID, vote.VoteID.Bytes()), data); err != nil {
+				return fmt.Errorf("set vote receipt: %w", err)
+			}
+			if err := ballotsTx.Set(voteReceiptKey(processID, vote.BallotIndex.Bytes()), vote.VoteID.Bytes()); err != nil {
+				return fmt.Errorf("set ballot vote ID: %w", err)
+			}
+		}
+	}
+	return wTx.Commit()
+}
+
+// VoteReceipt returns the receipt of a vote settled on chain. It returns
+// ErrNotFound if the vote is not settled yet.
+func (s *Storage) VoteReceipt(processID types.ProcessID, voteID types.VoteID) (*VoteReceipt, error) {
+	s.globalLock.Lock()
+	defer s.globalLock.Unlock()
+	receipt := &VoteReceipt{}
+	if err := s.getArtifact(voteReceiptPrefix, voteReceiptKey(processID, voteID.Bytes()), receipt); err != nil {
+		return nil, err
+	}
+	return receipt, nil
+}
+
+// BallotVoteID returns the ID of the last vote settled on the ballot index
+// provided, which differs from the vote ID of a receipt when the ballot was
+// overwritten by a later vote. It returns ErrNotFound if no vote was settled
+// on the ballot index.
+func (s *Storage) BallotVoteID(processID types.ProcessID, ballotIndex types.BallotIndex) (types.VoteID, error) {
+	s.globalLock.Lock()
+	defer s.globalLock.Unlock()
+	pr := prefixeddb.NewPrefixedReader(s.db, voteReceiptBallotPrefix)
+	data, err := pr.Get(voteReceiptKey(processID, ballotIndex.Bytes()))
+	if err != nil || len(data) != 8 {
+		return 0, ErrNotFound
+	}
+	return types.VoteID(binary.BigEndian.Uint64(data)), nil
+}
+
+// voteReceiptKey returns the key of a vote receipt entry of a process.
+func voteReceiptKey(processID types.ProcessID, key []byte) []byte {
+	return append(processID.Bytes(), key...)
+}
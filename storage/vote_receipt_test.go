@@ -0,0 +1,83 @@
+package storage
+
+import (
+	"errors"
+	"math/big"
+	"path/filepath"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/vocdoni/davinci-node/crypto/elgamal"
+	"github.com/vocdoni/davinci-node/db"
+	"github.com/vocdoni/davinci-node/db/metadb"
+	"github.com/vocdoni/davinci-node/internal/testutil"
+	"github.com/vocdoni/davinci-node/state"
+	statetest "github.com/vocdoni/davinci-node/state/testutil"
+	"github.com/vocdoni/davinci-node/types"
+)
+
+func TestVoteReceipts(t *testing.T) {
+	c := qt.New(t)
+	testDB, err := metadb.New(db.TypePebble, filepath.Join(t.TempDir(), "db"))
+	c.Assert(err, qt.IsNil)
+	st := New(testDB)
+	defer st.Close()
+
+	processID := testutil.RandomProcessID()
+	publicKey, _, err := elgamal.GenerateKey(state.Curve)
+	c.Assert(err, qt.IsNil)
+	processState := statetest.NewStateForTest(t, processID, testutil.BallotModePacked(),
+		types.CensusOriginMerkleTreeOffchainStaticV1, types.EncryptionKeyFromPoint(publicKey))
+
+	// settles the votes provided and records their receipts
+	settle := func(txHash types.HexBytes, votes ...*state.Vote) *big.Int {
+		batch, err := processState.PrepareVotesBatch(votes)
+		c.Assert(err, qt.IsNil)
+		root, err := batch.RootAsBigInt()
+		c.Assert(err, qt.IsNil)
+		sidecar := batch.BlobEvalData().TxSidecar()
+		c.Assert(batch.Commit(), qt.IsNil)
+		c.Assert(st.SetVoteReceipts(processID, root, txHash, sidecar), qt.IsNil)
+		return root
+	}
+
+	first := statetest.NewVoteForTest(publicKey, 1, 2)
+	second := statetest.NewVoteForTest(publicKey, 2, 3)
+	_, err = st.VoteReceipt(processID, first.VoteID)
+	c.Assert(errors.Is(err, ErrNotFound), qt.IsTrue)
+	c.Assert(st.SetVoteReceipts(processID, nil, types.HexBytes{0x01}, &types.BlobTxSidecar{}), qt.IsNotNil)
+
+	root := settle(types.HexBytes{0x01}, first, second)
+	for _, vote := range []*state.Vote{first, second} {
+		receipt, err := st.VoteReceipt(processID, vote.VoteID)
+		c.Assert(err, qt.IsNil)
+		c.Assert(receipt.ProcessID, qt.Equals, processID)
+		c.Assert(receipt.VoteID, qt.Equals, vote.VoteID)
+		c.Assert(receipt.BallotIndex, qt.Equals, vote.BallotIndex)
+		c.Assert(receipt.StateRoot.MathBigInt().Cmp(root), qt.Equals, 0)
+		c.Assert(receipt.TxHash, qt.DeepEquals, types.HexBytes{0x01})
+		c.Assert(receipt.BlobIndex, qt.Equals, 0)
+
+		voteID, err := st.BallotVoteID(processID, vote.BallotIndex)
+		c.Assert(err, qt.IsNil)
+		c.Assert(voteID, qt.Equals, vote.VoteID)
+	}
+
+	// an overwrite of the first voter points its ballot index to the new
+	// vote, while the receipt of the overwritten vote is kept
+	overwrite := statetest.NewVoteForTest(publicKey, 1, 4)
+	settle(types.HexBytes{0x02}, overwrite)
+	voteID, err := st.BallotVoteID(processID, first.BallotIndex)
+	c.Assert(err, qt.IsNil)
+	c.Assert(voteID, qt.Equals, overwrite.VoteID)
+	receipt, err := st.VoteReceipt(processID, first.VoteID)
+	c.Assert(err, qt.IsNil)
+	c.Assert(receipt.TxHash, qt.DeepEquals, types.HexBytes{0x01})
+	receipt, err = st.VoteReceipt(processID, overwrite.VoteID)
+	c.Assert(err, qt.IsNil)
+	c.Assert(receipt.TxHash, qt.DeepEquals, types.HexBytes{0x02})
+
+	// receipts are scoped to their process
+	_, err = st.BallotVoteID(testutil.RandomProcessID(), first.BallotIndex)
+	c.Assert(errors.Is(err, ErrNotFound), qt.IsTrue)
+}
@@ -98,7 +98,7 @@ func NewCensusWithVoters(ctx context.Context, origin types.CensusOrigin, signers
 func CreateCensusProof(origin types.CensusOrigin, pid types.ProcessID, address common.Address) (types.CensusProof, error) {
 	if origin.IsCSP() {
 		weight := new(types.BigInt).SetUint64(testutil.Weight)
-		eddsaCSP, err := csp.New(types.CensusOriginCSPEdDSABabyJubJubV1, []byte(LocalCSPSeed))
+		eddsaCSP, err := csp.New(origin, []byte(LocalCSPSeed))
 		if err != nil {
 			return types.CensusProof{}, fmt.Errorf("failed to create CSP: %w", err)
 		}
@@ -0,0 +1,125 @@
+package trustee
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+	"slices"
+
+	"github.com/vocdoni/davinci-node/crypto/ecc/curves"
+	"github.com/vocdoni/davinci-node/crypto/elgamal"
+	"github.com/vocdoni/davinci-node/crypto/elgamal/dkg"
+	"github.com/vocdoni/davinci-node/types"
+)
+
+// PartialDecryptions computes the partial decryption of every ciphertext of
+// the threshold decryption provided, with the Chaum-Pedersen proof that it
+// was computed with the private share of the trustee and the commitment for
+// the combined decryption proof. The secret nonces of the commitments are
+// kept by the trustee (and included in its State), calling it again returns
+// the same commitments. Once the trustee has answered the challenges of an
+// attempt, new nonces are generated for the later attempts, since answering
+// different challenges with the same nonces would reveal its private share.
+func (t *Trustee) PartialDecryptions(td *types.ThresholdDecryption) ([]types.DKGPartialDecryption, error) {
+	if err := t.checkDecryption(td); err != nil {
+		return nil, err
+	}
+	curve := curves.New(CurveType)
+	privateShare := t.PrivateShare()
+	verificationKey := curve.New()
+	verificationKey.ScalarBaseMult(privateShare)
+
+	if attempt, ok := t.answered[td.ProcessID]; ok && attempt != td.Attempt {
+		delete(t.nonces, td.ProcessID)
+		delete(t.answered, td.ProcessID)
+	}
+	nonces, reuseNonces := t.nonces[td.ProcessID]
+	if reuseNonces && len(nonces) != len(td.Ciphertexts) {
+		return nil, fmt.Errorf("stored nonces do not match the ciphertexts of process %s", td.ProcessID.String())
+	}
+	partials := make([]types.DKGPartialDecryption, 0, len(td.Ciphertexts))
+	for i, ct := range td.Ciphertexts {
+		c1 := ct.C1.ToPoint(curve)
+		share := t.participant.ComputePartialDecryption(c1)
+		// prove log_G(verificationKey) == log_C1(share) as the decryption of
+		// (C1, share) with plaintext zero
+		proof, err := elgamal.BuildDecryptionProof(privateShare, verificationKey, c1, share, big.NewInt(0))
+		if err != nil {
+			return nil, fmt.Errorf("could not build partial decryption proof %d: %w", i, err)
+		}
+		var commitment dkg.CPCommitment
+		if reuseNonces {
+			commitment.A1 = curve.New()
+			commitment.A1.ScalarBaseMult(nonces[i])
+			commitment.A2 = curve.New()
+			commitment.A2.ScalarMult(c1, nonces[i])
+		} else {
+			var r *big.Int
+			if commitment, r, err = t.participant.BuildCommitment(c1); err != nil {
+				return nil, fmt.Errorf("could not build decryption commitment %d: %w", i, err)
+			}
+			nonces = append(nonces, r)
+		}
+		partials = append(partials, types.DKGPartialDecryption{
+			Share: types.DKGPointFromPoint(share),
+			Proof: types.DKGProof{
+				A1: types.DKGPointFromPoint(proof.A1),
+				A2: types.DKGPointFromPoint(proof.A2),
+				Z:  types.BigIntConverter(proof.Z),
+			},
+			Commitment: types.DKGCommitment{
+				A1: types.DKGPointFromPoint(commitment.A1),
+				A2: types.DKGPointFromPoint(commitment.A2),
+			},
+		})
+	}
+	t.nonces[td.ProcessID] = nonces
+	return partials, nil
+}
+
+// DecryptionResponses computes the responses of the trustee to the challenges
+// of the combined decryption proofs of the threshold decryption provided. The
+// trustee must be part of the quorum, so it must have computed its partial
+// decryptions before.
+func (t *Trustee) DecryptionResponses(td *types.ThresholdDecryption) ([]*types.BigInt, error) {
+	if err := t.checkDecryption(td); err != nil {
+		return nil, err
+	}
+	if td.Status != types.ThresholdDecryptionStatusResponses {
+		return nil, fmt.Errorf("threshold decryption is not waiting for responses: %s", td.Status)
+	}
+	if !slices.Contains(td.Quorum, t.participant.ID) {
+		return nil, fmt.Errorf("trustee %d is not part of the quorum", t.participant.ID)
+	}
+	nonces, ok := t.nonces[td.ProcessID]
+	if !ok || len(nonces) != len(td.Ciphertexts) || len(td.Challenges) != len(td.Ciphertexts) {
+		return nil, fmt.Errorf("no decryption nonces found for process %s", td.ProcessID.String())
+	}
+	if attempt, ok := t.answered[td.ProcessID]; ok && attempt != td.Attempt {
+		return nil, fmt.Errorf("decryption nonces of process %s already used in attempt %d", td.ProcessID.String(), attempt)
+	}
+	order := curves.New(CurveType).Order()
+	lambdas, err := dkg.LagrangeCoefficients(td.Quorum, order)
+	if err != nil {
+		return nil, err
+	}
+	responses := make([]*types.BigInt, 0, len(td.Ciphertexts))
+	for i, e := range td.Challenges {
+		z := t.participant.BuildPartialResponse(nonces[i], lambdas[t.participant.ID], e.MathBigInt(), order)
+		responses = append(responses, types.BigIntConverter(z))
+	}
+	t.answered[td.ProcessID] = td.Attempt
+	return responses, nil
+}
+
+// checkDecryption checks that the trustee can take part in the threshold
+// decryption provided.
+func (t *Trustee) checkDecryption(td *types.ThresholdDecryption) error {
+	if t.participant == nil || !t.hasShare {
+		return fmt.Errorf("trustee has no private share")
+	}
+	if td == nil || !bytes.Equal(td.SessionID, t.sessionID) {
+		return fmt.Errorf("threshold decryption does not belong to the trustee session")
+	}
+	return nil
+}
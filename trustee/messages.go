@@ -0,0 +1,163 @@
+package trustee
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/vocdoni/davinci-node/crypto/signatures/ethereum"
+	"github.com/vocdoni/davinci-node/types"
+)
+
+// trusteeSignMessage is the message signed by a trustee to authenticate its
+// submissions to the sequencer. It includes the session, the round, the
+// index of the trustee and the hash of the submitted payload.
+const trusteeSignMessage = "DKG session %x, round '%s', trustee %d, payload %x"
+
+// trusteeDecryptionSignMessage is the message signed by a trustee to
+// authenticate its submissions for the threshold decryption of the results
+// of a process. It also includes the process, so the submissions can not be
+// replayed between processes that share the same session key.
+const trusteeDecryptionSignMessage = "DKG session %x, process %x, round '%s', trustee %d, payload %x"
+
+// Round identifies the step of the DKG protocol that a trustee submission
+// belongs to.
+type Round string
+
+const (
+	// RoundCommitments is the round where trustees publish the public
+	// coefficients of their secret polynomials.
+	RoundCommitments Round = "commitments"
+	// RoundShares is the round where trustees send the encrypted shares to
+	// the rest of trustees.
+	RoundShares Round = "shares"
+	// RoundPartialDecryptions is the round where trustees publish the
+	// partial decryptions of the results of a process.
+	RoundPartialDecryptions Round = "partials"
+	// RoundDecryptionResponses is the round where the quorum trustees send
+	// their responses to the challenges of the combined decryption proofs.
+	RoundDecryptionResponses Round = "responses"
+	// RoundVerification is the round where trustees report the senders of
+	// the invalid shares they received, if any.
+	RoundVerification Round = "verification"
+)
+
+// SignMessage returns the message that the trustee with the index provided
+// must sign to submit the payload provided in the round and session
+// provided. The payload is hashed from its JSON representation.
+func SignMessage(sessionID types.HexBytes, round Round, trusteeIndex int, payload any) ([]byte, error) {
+	payloadHash, err := hashPayload(payload)
+	if err != nil {
+		return nil, err
+	}
+	return fmt.Appendf(nil, trusteeSignMessage, []byte(sessionID), round, trusteeIndex, payloadHash), nil
+}
+
+// DecryptionSignMessage returns the message that the trustee with the index
+// provided must sign to submit the payload provided in a threshold
+// decryption round of the process provided.
+func DecryptionSignMessage(
+	sessionID types.HexBytes,
+	processID types.ProcessID,
+	round Round,
+	trusteeIndex int,
+	payload any,
+) ([]byte, error) {
+	payloadHash, err := hashPayload(payload)
+	if err != nil {
+		return nil, err
+	}
+	return fmt.Appendf(nil, trusteeDecryptionSignMessage, []byte(sessionID), processID.Bytes(), round, trusteeIndex, payloadHash), nil
+}
+
+// Sign signs the payload provided for the round, session and trustee index
+// with the signer provided and returns the signature bytes.
+func Sign(signer *ethereum.Signer, sessionID types.HexBytes, round Round, trusteeIndex int, payload any) (types.HexBytes, error) {
+	msg, err := SignMessage(sessionID, round, trusteeIndex, payload)
+	if err != nil {
+		return nil, err
+	}
+	return signMessage(signer, msg)
+}
+
+// SignDecryption signs the payload provided for the threshold decryption
+// round of the process provided with the signer provided and returns the
+// signature bytes.
+func SignDecryption(
+	signer *ethereum.Signer,
+	sessionID types.HexBytes,
+	processID types.ProcessID,
+	round Round,
+	trusteeIndex int,
+	payload any,
+) (types.HexBytes, error) {
+	msg, err := DecryptionSignMessage(sessionID, processID, round, trusteeIndex, payload)
+	if err != nil {
+		return nil, err
+	}
+	return signMessage(signer, msg)
+}
+
+// VerifySignature checks that the signature provided has been produced by
+// the address provided over the payload, round, session and trustee index
+// provided. It returns an error if the signature is not valid.
+func VerifySignature(signature types.HexBytes, address common.Address, sessionID types.HexBytes, round Round, trusteeIndex int, payload any) error {
+	msg, err := SignMessage(sessionID, round, trusteeIndex, payload)
+	if err != nil {
+		return err
+	}
+	return verifyMessage(signature, address, msg)
+}
+
+// VerifyDecryptionSignature checks that the signature provided has been
+// produced by the address provided over the payload of a threshold
+// decryption round of the process provided. It returns an error if the
+// signature is not valid.
+func VerifyDecryptionSignature(
+	signature types.HexBytes,
+	address common.Address,
+	sessionID types.HexBytes,
+	processID types.ProcessID,
+	round Round,
+	trusteeIndex int,
+	payload any,
+) error {
+	msg, err := DecryptionSignMessage(sessionID, processID, round, trusteeIndex, payload)
+	if err != nil {
+		return err
+	}
+	return verifyMessage(signature, address, msg)
+}
+
+// hashPayload returns the sha256 hash of the JSON representation of the
+// payload provided.
+func hashPayload(payload any) ([32]byte, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return [32]byte{}, fmt.Errorf("could not encode payload: %w", err)
+	}
+	return sha256.Sum256(data), nil
+}
+
+// signMessage signs the message provided with the signer provided.
+func signMessage(signer *ethereum.Signer, msg []byte) (types.HexBytes, error) {
+	signature, err := signer.Sign(msg)
+	if err != nil {
+		return nil, fmt.Errorf("could not sign trustee message: %w", err)
+	}
+	return signature.Bytes(), nil
+}
+
+// verifyMessage checks that the signature provided over the message provided
+// has been produced by the address provided.
+func verifyMessage(signature types.HexBytes, address common.Address, msg []byte) error {
+	sig, err := ethereum.BytesToSignature(signature)
+	if err != nil {
+		return fmt.Errorf("malformed signature: %w", err)
+	}
+	if valid, _ := sig.Verify(msg, address); !valid {
+		return fmt.Errorf("signature does not match trustee %s", address.Hex())
+	}
+	return nil
+}
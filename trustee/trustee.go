@@ -0,0 +1,337 @@
+// Package trustee implements the client side of the distributed key
+// generation used to create the encryption keys of the processes. Every
+// trustee of a committee runs its own instance, publishes its polynomial
+// commitments and encrypted shares through the sequencer API and keeps its
+// private share to decrypt the results of the processes once they end.
+package trustee
+
+import (
+	"fmt"
+	"math/big"
+	"slices"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/vocdoni/davinci-node/crypto/ecc"
+	bjj "github.com/vocdoni/davinci-node/crypto/ecc/bjj_gnark"
+	"github.com/vocdoni/davinci-node/crypto/ecc/curves"
+	"github.com/vocdoni/davinci-node/crypto/elgamal/dkg"
+	"github.com/vocdoni/davinci-node/crypto/elgamal/dkg/secies"
+	"github.com/vocdoni/davinci-node/crypto/signatures/ethereum"
+	"github.com/vocdoni/davinci-node/types"
+)
+
+// CurveType is the curve used by the trustees, it must match the curve of
+// the process encryption keys.
+const CurveType = bjj.CurveType
+
+// Trustee holds the keys of a member of a DKG committee and, once it joins
+// a session, its secret polynomial and the resulting private share.
+type Trustee struct {
+	signer      *ethereum.Signer
+	sharesKey   *secies.ScalarECIES
+	sessionID   types.HexBytes
+	participant *dkg.Participant
+	hasShare    bool
+	nonces      map[types.ProcessID][]*big.Int
+	answered    map[types.ProcessID]int
+}
+
+// State contains the secrets of a trustee for a DKG session. It must be
+// persisted by the trustee between rounds and kept until the results of
+// the processes that use the session key are decrypted.
+type State struct {
+	SessionID     types.HexBytes  `json:"sessionId"`
+	Index         int             `json:"index"`
+	Threshold     int             `json:"threshold"`
+	Participants  []int           `json:"participants"`
+	SharesPrivKey *types.BigInt   `json:"sharesPrivKey"`
+	SecretCoeffs  []*types.BigInt `json:"secretCoeffs"`
+	PrivateShare  *types.BigInt   `json:"privateShare,omitempty"`
+	// DecryptionNonces are the secret nonces of the decryption proof
+	// commitments of the trustee, indexed by process ID.
+	DecryptionNonces map[string][]*types.BigInt `json:"decryptionNonces,omitempty"`
+	// DecryptionAnswered are the threshold decryption attempts whose
+	// challenges were answered with the current nonces, indexed by process
+	// ID.
+	DecryptionAnswered map[string]int `json:"decryptionAnswered,omitempty"`
+}
+
+// New creates a new trustee with the signer provided to authenticate its
+// messages. If sharesPrivKey is nil, a new key to receive the encrypted
+// shares of the rest of trustees is generated.
+func New(signer *ethereum.Signer, sharesPrivKey *big.Int) (*Trustee, error) {
+	if signer == nil {
+		return nil, fmt.Errorf("trustee signer is required")
+	}
+	sharesKey, err := secies.New(sharesPrivKey, curves.New(CurveType), nil)
+	if err != nil {
+		return nil, fmt.Errorf("could not initialize trustee shares key: %w", err)
+	}
+	return &Trustee{
+		signer:    signer,
+		sharesKey: sharesKey,
+		nonces:    make(map[types.ProcessID][]*big.Int),
+		answered:  make(map[types.ProcessID]int),
+	}, nil
+}
+
+// Address returns the address used by the trustee to sign its messages.
+func (t *Trustee) Address() common.Address {
+	return t.signer.Address()
+}
+
+// Signer returns the signer of the trustee.
+func (t *Trustee) Signer() *ethereum.Signer {
+	return t.signer
+}
+
+// Info returns the public information of the trustee required to include it
+// in a new DKG session.
+func (t *Trustee) Info() types.DKGTrustee {
+	pubKey := curves.New(CurveType).New()
+	pubKey.ScalarBaseMult(t.sharesKey.GetPrivateKey())
+	return types.DKGTrustee{
+		Address:      t.Address(),
+		SharesPubKey: types.DKGPointFromPoint(pubKey),
+	}
+}
+
+// Index returns the index of the trustee in the session that it joined, or
+// zero if it has not joined any session yet.
+func (t *Trustee) Index() int {
+	if t.participant == nil {
+		return 0
+	}
+	return t.participant.ID
+}
+
+// Join looks for the trustee in the session provided by its address and
+// generates a new secret polynomial for it.
+func (t *Trustee) Join(session *types.DKGSession) error {
+	index := 0
+	for _, st := range session.Trustees {
+		if st.Address == t.Address() {
+			index = st.Index
+			break
+		}
+	}
+	if index == 0 {
+		return fmt.Errorf("trustee %s is not part of session %s", t.Address().Hex(), session.ID.String())
+	}
+	t.sessionID = session.ID
+	t.participant = dkg.NewParticipant(index, session.Threshold, session.Participants(), curves.New(CurveType))
+	t.hasShare = false
+	t.participant.GenerateSecretPolynomial()
+	t.participant.ComputeShares()
+	return nil
+}
+
+// Commitments returns the public coefficients of the secret polynomial of
+// the trustee, to be published in the commitments round.
+func (t *Trustee) Commitments() ([]types.DKGPoint, error) {
+	if t.participant == nil {
+		return nil, fmt.Errorf("trustee has not joined any session")
+	}
+	commitments := make([]types.DKGPoint, 0, len(t.participant.PublicCoeffs))
+	for _, c := range t.participant.PublicCoeffs {
+		commitments = append(commitments, types.DKGPointFromPoint(c))
+	}
+	return commitments, nil
+}
+
+// EncryptedShares returns the shares of the trustee secret polynomial for
+// the rest of trustees of the session, each one encrypted with the shares
+// public key of its recipient.
+func (t *Trustee) EncryptedShares(session *types.DKGSession) ([]types.DKGEncryptedShare, error) {
+	if t.participant == nil {
+		return nil, fmt.Errorf("trustee has not joined any session")
+	}
+	shares := []types.DKGEncryptedShare{}
+	for _, recipient := range session.Trustees {
+		if recipient.Index == t.participant.ID {
+			continue
+		}
+		share, ok := t.participant.SecretShares[recipient.Index]
+		if !ok {
+			return nil, fmt.Errorf("no share computed for trustee %d", recipient.Index)
+		}
+		recipientKey := recipient.SharesPubKey.ToPoint(curves.New(CurveType))
+		ciphertext, ephemeral, err := t.sharesKey.Encrypt(new(big.Int).Set(share), recipientKey)
+		if err != nil {
+			return nil, fmt.Errorf("could not encrypt share for trustee %d: %w", recipient.Index, err)
+		}
+		shares = append(shares, types.DKGEncryptedShare{
+			From:       t.participant.ID,
+			To:         recipient.Index,
+			Ciphertext: types.BigIntConverter(ciphertext),
+			Ephemeral:  ephemeral,
+		})
+	}
+	return shares, nil
+}
+
+// ReceiveShares decrypts the shares addressed to the trustee, verifies them
+// against the commitments of their senders and aggregates them into the
+// private share of the trustee. It also checks that the public key derived
+// locally matches the public key of the session. It fails if any share is
+// invalid, use VerifyShares to get the senders to complain about.
+func (t *Trustee) ReceiveShares(session *types.DKGSession, shares []types.DKGEncryptedShare) error {
+	complaints, err := t.VerifyShares(session, shares)
+	if err != nil {
+		return err
+	}
+	if len(complaints) > 0 {
+		return fmt.Errorf("invalid shares from trustees %v", complaints)
+	}
+	return nil
+}
+
+// VerifyShares decrypts the shares addressed to the trustee and verifies
+// them against the commitments of their senders. It returns the indexes of
+// the senders whose share could not be decrypted or is not valid, which
+// must be submitted in the verification round. If every share is valid, it
+// returns an empty list and aggregates them into the private share of the
+// trustee, checking that the public key derived locally matches the public
+// key of the session.
+func (t *Trustee) VerifyShares(session *types.DKGSession, shares []types.DKGEncryptedShare) ([]int, error) {
+	if t.participant == nil {
+		return nil, fmt.Errorf("trustee has not joined any session")
+	}
+	if len(shares) != len(session.Trustees)-1 {
+		return nil, fmt.Errorf("expected %d shares, got %d", len(session.Trustees)-1, len(shares))
+	}
+	allPublicCoeffs := make(map[int][]ecc.Point, len(session.Commitments))
+	for index, commitments := range session.Commitments {
+		coeffs := make([]ecc.Point, 0, len(commitments))
+		for _, c := range commitments {
+			coeffs = append(coeffs, c.ToPoint(curves.New(CurveType)))
+		}
+		allPublicCoeffs[index] = coeffs
+	}
+	complaints := []int{}
+	for _, share := range shares {
+		if share.To != t.participant.ID {
+			return nil, fmt.Errorf("share from trustee %d is not addressed to this trustee", share.From)
+		}
+		coeffs, ok := allPublicCoeffs[share.From]
+		if !ok {
+			return nil, fmt.Errorf("no commitments found for trustee %d", share.From)
+		}
+		value, err := t.sharesKey.Decrypt(share.Ciphertext.MathBigInt(), share.Ephemeral)
+		if err != nil {
+			complaints = append(complaints, share.From)
+			continue
+		}
+		if err := t.participant.ReceiveShare(share.From, value, coeffs); err != nil {
+			complaints = append(complaints, share.From)
+		}
+	}
+	if len(complaints) > 0 {
+		slices.Sort(complaints)
+		return complaints, nil
+	}
+	t.participant.AggregateShares()
+	t.participant.AggregatePublicKey(allPublicCoeffs)
+	t.hasShare = true
+	if session.PublicKey != nil {
+		sessionKey := curves.New(CurveType).New().SetPoint(session.PublicKey.X.MathBigInt(), session.PublicKey.Y.MathBigInt())
+		if !sessionKey.Equal(t.participant.PublicKey) {
+			return nil, fmt.Errorf("session public key does not match the one derived from the commitments")
+		}
+	}
+	return complaints, nil
+}
+
+// PrivateShare returns the private key share of the trustee, or nil if the
+// shares of the session have not been received yet.
+func (t *Trustee) PrivateShare() *big.Int {
+	if t.participant == nil || !t.hasShare {
+		return nil
+	}
+	return new(big.Int).Set(t.participant.PrivateShare)
+}
+
+// State returns the secrets of the trustee for the session it joined, so
+// they can be persisted and restored later with Restore.
+func (t *Trustee) State() (*State, error) {
+	if t.participant == nil {
+		return nil, fmt.Errorf("trustee has not joined any session")
+	}
+	st := &State{
+		SessionID:     t.sessionID,
+		Index:         t.participant.ID,
+		Threshold:     t.participant.Threshold,
+		Participants:  t.participant.Participants,
+		SharesPrivKey: types.BigIntConverter(t.sharesKey.GetPrivateKey()),
+	}
+	for _, c := range t.participant.SecretCoeffs {
+		st.SecretCoeffs = append(st.SecretCoeffs, types.BigIntConverter(c))
+	}
+	if share := t.PrivateShare(); share != nil {
+		st.PrivateShare = types.BigIntConverter(share)
+	}
+	if len(t.nonces) > 0 {
+		st.DecryptionNonces = make(map[string][]*types.BigInt, len(t.nonces))
+		for processID, nonces := range t.nonces {
+			for _, r := range nonces {
+				st.DecryptionNonces[processID.String()] = append(st.DecryptionNonces[processID.String()], types.BigIntConverter(r))
+			}
+		}
+	}
+	if len(t.answered) > 0 {
+		st.DecryptionAnswered = make(map[string]int, len(t.answered))
+		for processID, attempt := range t.answered {
+			st.DecryptionAnswered[processID.String()] = attempt
+		}
+	}
+	return st, nil
+}
+
+// Restore loads the secrets of a previously persisted state into the
+// trustee. The shares private key of the state must match the one of the
+// trustee.
+func (t *Trustee) Restore(st *State) error {
+	if st == nil || st.SharesPrivKey == nil || len(st.SecretCoeffs) != st.Threshold {
+		return fmt.Errorf("invalid trustee state")
+	}
+	if st.SharesPrivKey.MathBigInt().Cmp(t.sharesKey.GetPrivateKey()) != 0 {
+		return fmt.Errorf("trustee state belongs to a different shares key")
+	}
+	curve := curves.New(CurveType)
+	p := dkg.NewParticipant(st.Index, st.Threshold, st.Participants, curve)
+	for _, c := range st.SecretCoeffs {
+		coeff := c.MathBigInt()
+		commitment := curve.New()
+		commitment.ScalarBaseMult(coeff)
+		p.SecretCoeffs = append(p.SecretCoeffs, coeff)
+		p.PublicCoeffs = append(p.PublicCoeffs, commitment)
+	}
+	p.ComputeShares()
+	if st.PrivateShare != nil {
+		p.PrivateShare.Set(st.PrivateShare.MathBigInt())
+	}
+	nonces := make(map[types.ProcessID][]*big.Int, len(st.DecryptionNonces))
+	for strProcessID, stNonces := range st.DecryptionNonces {
+		processID, err := types.HexStringToProcessID(strProcessID)
+		if err != nil {
+			return fmt.Errorf("invalid decryption nonces process ID: %w", err)
+		}
+		for _, r := range stNonces {
+			nonces[processID] = append(nonces[processID], r.MathBigInt())
+		}
+	}
+	answered := make(map[types.ProcessID]int, len(st.DecryptionAnswered))
+	for strProcessID, attempt := range st.DecryptionAnswered {
+		processID, err := types.HexStringToProcessID(strProcessID)
+		if err != nil {
+			return fmt.Errorf("invalid decryption attempt process ID: %w", err)
+		}
+		answered[processID] = attempt
+	}
+	t.nonces = nonces
+	t.answered = answered
+	t.sessionID = st.SessionID
+	t.participant = p
+	t.hasShare = st.PrivateShare != nil
+	return nil
+}
@@ -0,0 +1,191 @@
+package trustee
+
+import (
+	"maps"
+	"math/big"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/vocdoni/davinci-node/crypto/elgamal"
+	"github.com/vocdoni/davinci-node/crypto/elgamal/dkg"
+	"github.com/vocdoni/davinci-node/crypto/signatures/ethereum"
+	"github.com/vocdoni/davinci-node/db"
+	"github.com/vocdoni/davinci-node/db/metadb"
+	"github.com/vocdoni/davinci-node/internal/testutil"
+	"github.com/vocdoni/davinci-node/storage"
+	"github.com/vocdoni/davinci-node/types"
+)
+
+func TestThresholdDecryption(t *testing.T) {
+	c := qt.New(t)
+
+	testDB, err := metadb.New(db.TypePebble, filepath.Join(t.TempDir(), "db"))
+	c.Assert(err, qt.IsNil)
+	stg := storage.New(testDB)
+	defer stg.Close()
+
+	// run the DKG between three trustees with threshold two
+	const n, threshold = 3, 2
+	trustees := make([]*Trustee, n)
+	infos := make([]types.DKGTrustee, n)
+	for i := range trustees {
+		signer, err := ethereum.NewSigner()
+		c.Assert(err, qt.IsNil)
+		trustees[i], err = New(signer, nil)
+		c.Assert(err, qt.IsNil)
+		infos[i] = trustees[i].Info()
+	}
+	session, err := stg.NewDKGSession(threshold, infos)
+	c.Assert(err, qt.IsNil)
+	for _, tr := range trustees {
+		c.Assert(tr.Join(session), qt.IsNil)
+		commitments, err := tr.Commitments()
+		c.Assert(err, qt.IsNil)
+		c.Assert(stg.SetDKGCommitments(session.ID, tr.Index(), commitments), qt.IsNil)
+	}
+	session, err = stg.DKGSession(session.ID)
+	c.Assert(err, qt.IsNil)
+	for _, tr := range trustees {
+		shares, err := tr.EncryptedShares(session)
+		c.Assert(err, qt.IsNil)
+		c.Assert(stg.SetDKGShares(session.ID, tr.Index(), shares), qt.IsNil)
+	}
+	session, err = stg.DKGSession(session.ID)
+	c.Assert(err, qt.IsNil)
+	_, err = stg.DKGSessionPublicKey(session.ID)
+	c.Assert(err, qt.ErrorIs, storage.ErrDKGSessionNotFinished)
+
+	// a tampered share is reported in the verification round
+	tampered := slices.Clone(session.SharesFor(trustees[0].Index()))
+	tampered[0].Ciphertext = new(types.BigInt).Add(tampered[0].Ciphertext, types.NewInt(1))
+	complaints, err := trustees[0].VerifyShares(session, tampered)
+	c.Assert(err, qt.IsNil)
+	c.Assert(complaints, qt.DeepEquals, []int{tampered[0].From})
+	c.Assert(trustees[0].PrivateShare(), qt.IsNil)
+
+	for _, tr := range trustees {
+		complaints, err := tr.VerifyShares(session, session.SharesFor(tr.Index()))
+		c.Assert(err, qt.IsNil)
+		c.Assert(complaints, qt.HasLen, 0)
+		c.Assert(stg.SetDKGVerification(session.ID, tr.Index(), complaints), qt.IsNil)
+	}
+	publicKey, err := stg.DKGSessionPublicKey(session.ID)
+	c.Assert(err, qt.IsNil)
+
+	// encrypt the messages to decrypt
+	messages := []*big.Int{big.NewInt(0), big.NewInt(7), big.NewInt(42)}
+	ciphertexts := make([]types.DKGCiphertext, 0, len(messages))
+	for _, m := range messages {
+		c1, c2, _, err := elgamal.Encrypt(publicKey, m)
+		c.Assert(err, qt.IsNil)
+		ciphertexts = append(ciphertexts, types.DKGCiphertext{
+			C1: types.DKGPointFromPoint(c1),
+			C2: types.DKGPointFromPoint(c2),
+		})
+	}
+	processID := testutil.DeterministicProcessID(1)
+	td, err := stg.StartThresholdDecryption(processID, session.ID, ciphertexts)
+	c.Assert(err, qt.IsNil)
+	c.Assert(td.Status, qt.Equals, types.ThresholdDecryptionStatusPartials)
+
+	// the last two trustees submit their partial decryptions and become the
+	// quorum
+	for _, tr := range trustees[1:] {
+		partials, err := tr.PartialDecryptions(td)
+		c.Assert(err, qt.IsNil)
+		c.Assert(stg.SetPartialDecryptions(processID, tr.Index(), partials), qt.IsNil)
+	}
+	// the partial decryptions submitted after the quorum is formed are
+	// stored, but the quorum does not change
+	partials, err := trustees[0].PartialDecryptions(td)
+	c.Assert(err, qt.IsNil)
+	c.Assert(stg.SetPartialDecryptions(processID, trustees[0].Index(), partials), qt.IsNil)
+	c.Assert(stg.SetPartialDecryptions(processID, trustees[0].Index(), partials), qt.ErrorIs, storage.ErrDKGAlreadySubmitted)
+
+	td, err = stg.ThresholdDecryption(processID)
+	c.Assert(err, qt.IsNil)
+	c.Assert(td.Status, qt.Equals, types.ThresholdDecryptionStatusResponses)
+	c.Assert(td.Quorum, qt.DeepEquals, []int{trustees[1].Index(), trustees[2].Index()})
+	c.Assert(td.Partials, qt.HasLen, n)
+	prevPartials := td.Partials[trustees[1].Index()]
+	_, err = trustees[0].DecryptionResponses(td)
+	c.Assert(err, qt.IsNotNil)
+
+	// the quorum is not formed again before the timeout
+	restarted, err := stg.RestartStaleThresholdDecryption(processID, time.Hour)
+	c.Assert(err, qt.IsNil)
+	c.Assert(restarted, qt.IsFalse)
+
+	// only the first quorum trustee responds, so once the timeout expires
+	// the second one is excluded and the quorum is formed again with the
+	// late trustee and the responsive one, which needs fresh commitments
+	responses, err := trustees[1].DecryptionResponses(td)
+	c.Assert(err, qt.IsNil)
+	c.Assert(stg.SetDecryptionResponses(processID, trustees[1].Index(), responses), qt.IsNil)
+	restarted, err = stg.RestartStaleThresholdDecryption(processID, 0)
+	c.Assert(err, qt.IsNil)
+	c.Assert(restarted, qt.IsTrue)
+	td, err = stg.ThresholdDecryption(processID)
+	c.Assert(err, qt.IsNil)
+	c.Assert(td.Status, qt.Equals, types.ThresholdDecryptionStatusPartials)
+	c.Assert(td.Attempt, qt.Equals, 1)
+	c.Assert(td.Excluded, qt.DeepEquals, []int{trustees[2].Index()})
+	c.Assert(td.Partials, qt.HasLen, 1)
+	_, err = trustees[1].DecryptionResponses(td)
+	c.Assert(err, qt.IsNotNil)
+
+	// the excluded trustee can not take part anymore
+	partials, err = trustees[2].PartialDecryptions(td)
+	c.Assert(err, qt.IsNil)
+	c.Assert(stg.SetPartialDecryptions(processID, trustees[2].Index(), partials), qt.ErrorIs, storage.ErrThresholdDecryptionExcluded)
+
+	// the responsive trustee can not submit its answered commitments again,
+	// it submits new partial decryptions which form the new quorum
+	c.Assert(stg.SetPartialDecryptions(processID, trustees[1].Index(), prevPartials), qt.ErrorIs, storage.ErrDKGInvalidSubmission)
+	partials, err = trustees[1].PartialDecryptions(td)
+	c.Assert(err, qt.IsNil)
+	c.Assert(partials[0].Commitment.Equal(prevPartials[0].Commitment), qt.IsFalse)
+	c.Assert(stg.SetPartialDecryptions(processID, trustees[1].Index(), partials), qt.IsNil)
+	td, err = stg.ThresholdDecryption(processID)
+	c.Assert(err, qt.IsNil)
+	c.Assert(td.Status, qt.Equals, types.ThresholdDecryptionStatusResponses)
+	c.Assert(td.Quorum, qt.DeepEquals, []int{trustees[0].Index(), trustees[1].Index()})
+
+	// the decryption nonces survive a restore of the trustee state
+	st, err := trustees[1].State()
+	c.Assert(err, qt.IsNil)
+	restored, err := New(trustees[1].Signer(), st.SharesPrivKey.MathBigInt())
+	c.Assert(err, qt.IsNil)
+	c.Assert(restored.Restore(st), qt.IsNil)
+	trustees[1] = restored
+
+	// a wrong response is rejected
+	responses, err = trustees[1].DecryptionResponses(td)
+	c.Assert(err, qt.IsNil)
+	wrong := slices.Clone(responses)
+	wrong[0] = types.BigIntConverter(big.NewInt(1))
+	c.Assert(stg.SetDecryptionResponses(processID, trustees[1].Index(), wrong), qt.ErrorIs, storage.ErrDKGInvalidSubmission)
+
+	for _, tr := range trustees[:2] {
+		responses, err := tr.DecryptionResponses(td)
+		c.Assert(err, qt.IsNil)
+		c.Assert(stg.SetDecryptionResponses(processID, tr.Index(), responses), qt.IsNil)
+	}
+
+	// combine the partial decryptions and verify the combined proofs against
+	// the session public key
+	quorumPartials, proofs, err := stg.ThresholdDecryptionProofs(processID)
+	c.Assert(err, qt.IsNil)
+	for i, ct := range ciphertexts {
+		c1 := ct.C1.ToPoint(publicKey)
+		c2 := ct.C2.ToPoint(publicKey)
+		quorum := slices.Sorted(maps.Keys(quorumPartials[i]))
+		result, err := dkg.CombinePartialDecryptions(c2, quorumPartials[i], quorum, 100)
+		c.Assert(err, qt.IsNil)
+		c.Assert(result.Cmp(messages[i]), qt.Equals, 0)
+		c.Assert(elgamal.VerifyDecryptionProof(publicKey, c1, c2, result, proofs[i]), qt.IsNil)
+	}
+}
@@ -19,12 +19,16 @@ const (
 	CensusOriginMerkleTreeOffchainDynamicV1
 	CensusOriginMerkleTreeOnchainDynamicV1
 	CensusOriginCSPEdDSABabyJubJubV1
+	CensusOriginCSPECDSASecp256k1V1
+	CensusOriginCSPEdDSABLS12377V1
 
 	CensusOriginNameUnknown                     = "unknown"
 	CensusOriginNameMerkleTreeOffchainStaticV1  = "merkle_tree_offchain_static_v1"
 	CensusOriginNameMerkleTreeOffchainDynamicV1 = "merkle_tree_offchain_dynamic_v1"
 	CensusOriginNameMerkleTreeOnchainDynamicV1  = "merkle_tree_onchain_dynamic_v1"
 	CensusOriginNameCSPEdDSABabyJubJubV1        = "csp_eddsa_babyjubjub_v1"
+	CensusOriginNameCSPECDSASecp256k1V1         = "csp_ecdsa_secp256k1_v1"
+	CensusOriginNameCSPEdDSABLS12377V1          = "csp_eddsa_bls12377_v1"
 
 	// CensusRootLength defines the length in bytes of the census root.
 	CensusRootLength = 32
@@ -35,14 +39,19 @@ var supportedCensusOrigins = map[CensusOrigin]string{
 	CensusOriginMerkleTreeOffchainDynamicV1: CensusOriginNameMerkleTreeOffchainDynamicV1,
 	CensusOriginMerkleTreeOnchainDynamicV1:  CensusOriginNameMerkleTreeOnchainDynamicV1,
 	CensusOriginCSPEdDSABabyJubJubV1:        CensusOriginNameCSPEdDSABabyJubJubV1,
+	CensusOriginCSPECDSASecp256k1V1:         CensusOriginNameCSPECDSASecp256k1V1,
+	CensusOriginCSPEdDSABLS12377V1:          CensusOriginNameCSPEdDSABLS12377V1,
 }
 
 // CurveID returns the twistededwards.ID associated with the CensusOrigin. Only
-// CSP origins have an associated curve, the rest return UNKNOWN.
+// EdDSA CSP origins have an associated twisted Edwards curve, the rest return
+// UNKNOWN.
 func (co CensusOrigin) CurveID() twistededwards.ID {
 	switch co {
 	case CensusOriginCSPEdDSABabyJubJubV1:
 		return twistededwards.BN254
+	case CensusOriginCSPEdDSABLS12377V1:
+		return twistededwards.BLS12_377
 	default:
 		return twistededwards.UNKNOWN
 	}
@@ -85,7 +94,9 @@ func (co CensusOrigin) IsMerkleTree() bool {
 // IsCSP checks if the CensusOrigin corresponds to a CSP-based census.
 func (co CensusOrigin) IsCSP() bool {
 	switch co {
-	case CensusOriginCSPEdDSABabyJubJubV1:
+	case CensusOriginCSPEdDSABabyJubJubV1,
+		CensusOriginCSPECDSASecp256k1V1,
+		CensusOriginCSPEdDSABLS12377V1:
 		return true
 	default:
 		return false
@@ -121,6 +132,9 @@ type Census struct {
 	//  - CensusOriginMerkleTreeOffchainDynamicV1: Merkle Root (could change
 	// 	  via tx).
 	//  - CensusOriginCSPEdDSABN254V1: MiMC7 of CSP PubKey (fixed).
+	//  - CensusOriginCSPECDSASecp256k1V1: Poseidon of the 128 bits halves of
+	//    the CSP PubKey coordinates (fixed).
+	//  - CensusOriginCSPEdDSABLS12377V1: Poseidon of CSP PubKey (fixed).
 	//  - CensusOriginMerkleTreeOnchainV1: Merkle Root (could change).
 	CensusRoot HexBytes `json:"censusRoot" cbor:"2,keyasint,omitempty"`
 	// CensusURI contains the following information depending on the CensusOrigin:
@@ -0,0 +1,155 @@
+package types
+
+import (
+	"slices"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/vocdoni/davinci-node/crypto/ecc"
+)
+
+type DKGSessionStatus uint8
+
+const (
+	DKGSessionStatusCommitments  = DKGSessionStatus(iota) // Waiting for the trustees polynomial commitments
+	DKGSessionStatusShares                                // Waiting for the trustees encrypted shares
+	DKGSessionStatusReady                                 // Public key derived and ready to be used by a process
+	DKGSessionStatusVerification                          // Waiting for the trustees to verify the shares received
+	DKGSessionStatusFailed                                // A trustee complained about the shares received
+
+	DKGSessionStatusCommitmentsName  = "commitments"
+	DKGSessionStatusSharesName       = "shares"
+	DKGSessionStatusReadyName        = "ready"
+	DKGSessionStatusVerificationName = "verification"
+	DKGSessionStatusFailedName       = "failed"
+)
+
+func (s DKGSessionStatus) String() string {
+	switch s {
+	case DKGSessionStatusCommitments:
+		return DKGSessionStatusCommitmentsName
+	case DKGSessionStatusShares:
+		return DKGSessionStatusSharesName
+	case DKGSessionStatusReady:
+		return DKGSessionStatusReadyName
+	case DKGSessionStatusVerification:
+		return DKGSessionStatusVerificationName
+	case DKGSessionStatusFailed:
+		return DKGSessionStatusFailedName
+	default:
+		return "unknown"
+	}
+}
+
+// DKGPoint is the affine representation of a curve point exchanged between
+// the trustees of a DKG session (polynomial commitments, share encryption
+// keys, partial decryptions...).
+type DKGPoint struct {
+	X *BigInt `json:"x" cbor:"0,keyasint,omitempty"`
+	Y *BigInt `json:"y" cbor:"1,keyasint,omitempty"`
+}
+
+// DKGPointFromPoint returns a DKGPoint with the X and Y coordinates of the
+// elliptic curve element provided.
+func DKGPointFromPoint(p ecc.Point) DKGPoint {
+	x, y := p.Point()
+	return DKGPoint{
+		X: BigIntConverter(x),
+		Y: BigIntConverter(y),
+	}
+}
+
+// Valid returns true if both coordinates of the point are defined.
+func (p DKGPoint) Valid() bool {
+	return p.X != nil && p.Y != nil
+}
+
+// Equal returns true if both points have the same coordinates.
+func (p DKGPoint) Equal(q DKGPoint) bool {
+	return p.X.Equal(q.X) && p.Y.Equal(q.Y)
+}
+
+// ToPoint returns the point as an elliptic curve element of the same type as
+// the curve provided.
+func (p DKGPoint) ToPoint(curve ecc.Point) ecc.Point {
+	return curve.New().SetPoint(p.X.MathBigInt(), p.Y.MathBigInt())
+}
+
+// DKGTrustee is a member of the committee that generates the encryption key
+// of a process. The index is the x coordinate used to evaluate the secret
+// polynomials, the address identifies the trustee when it signs its
+// messages and the shares public key is used by the rest of trustees to
+// encrypt the secret shares sent to it.
+type DKGTrustee struct {
+	Index        int            `json:"index"        cbor:"0,keyasint,omitempty"`
+	Address      common.Address `json:"address"      cbor:"1,keyasint,omitempty"`
+	SharesPubKey DKGPoint       `json:"sharesPubKey" cbor:"2,keyasint,omitempty"`
+}
+
+// DKGEncryptedShare is the secret share that the trustee identified by From
+// sends to the trustee identified by To. The share is encrypted with the
+// shares public key of the recipient, so it can be relayed by the sequencer
+// without leaking it.
+type DKGEncryptedShare struct {
+	From       int      `json:"from"       cbor:"0,keyasint,omitempty"`
+	To         int      `json:"to"         cbor:"1,keyasint,omitempty"`
+	Ciphertext *BigInt  `json:"ciphertext" cbor:"2,keyasint,omitempty"`
+	Ephemeral  HexBytes `json:"ephemeral"  cbor:"3,keyasint,omitempty"`
+}
+
+// DKGSession contains the state of a distributed key generation between a
+// committee of trustees. The commitments are indexed by trustee index and
+// contain the public coefficients of each trustee secret polynomial. The
+// shares are indexed by sender trustee index and are not exported in the
+// JSON, every trustee should retrieve just the shares addressed to it. Once
+// every share is sent, each trustee verifies the ones it received against
+// the commitments of their senders: Verified contains the trustees that
+// accepted them, and Complaints the senders of the invalid shares indexed by
+// the trustee that received them.
+type DKGSession struct {
+	ID          HexBytes                    `json:"id"                   cbor:"0,keyasint,omitempty"`
+	Threshold   int                         `json:"threshold"            cbor:"1,keyasint,omitempty"`
+	Trustees    []DKGTrustee                `json:"trustees"             cbor:"2,keyasint,omitempty"`
+	Status      DKGSessionStatus            `json:"status"               cbor:"3,keyasint,omitempty"`
+	Commitments map[int][]DKGPoint          `json:"commitments"          cbor:"4,keyasint,omitempty"`
+	Shares      map[int][]DKGEncryptedShare `json:"-"                    cbor:"5,keyasint,omitempty"`
+	PublicKey   *EncryptionKey              `json:"publicKey,omitempty"  cbor:"6,keyasint,omitempty"`
+	CreatedAt   time.Time                   `json:"createdAt"            cbor:"7,keyasint,omitempty"`
+	Verified    []int                       `json:"verified,omitempty"   cbor:"8,keyasint,omitempty"`
+	Complaints  map[int][]int               `json:"complaints,omitempty" cbor:"9,keyasint,omitempty"`
+}
+
+// Trustee returns the trustee of the session with the index provided and
+// true, or false if there is no trustee with that index.
+func (s *DKGSession) Trustee(index int) (*DKGTrustee, bool) {
+	for i := range s.Trustees {
+		if s.Trustees[i].Index == index {
+			return &s.Trustees[i], true
+		}
+	}
+	return nil, false
+}
+
+// Participants returns the sorted list of trustee indexes of the session.
+func (s *DKGSession) Participants() []int {
+	indexes := make([]int, 0, len(s.Trustees))
+	for _, t := range s.Trustees {
+		indexes = append(indexes, t.Index)
+	}
+	slices.Sort(indexes)
+	return indexes
+}
+
+// SharesFor returns the encrypted shares addressed to the trustee with the
+// index provided, sorted by sender.
+func (s *DKGSession) SharesFor(index int) []DKGEncryptedShare {
+	shares := []DKGEncryptedShare{}
+	for _, from := range s.Participants() {
+		for _, share := range s.Shares[from] {
+			if share.To == index {
+				shares = append(shares, share)
+			}
+		}
+	}
+	return shares
+}
@@ -47,6 +47,23 @@ func (s ProcessStatus) String() string {
 	}
 }
 
+// ProcessStatusFromString returns the ProcessStatus that matches the name
+// provided. The second return value is false if the name is unknown.
+func ProcessStatusFromString(name string) (ProcessStatus, bool) {
+	for _, s := range []ProcessStatus{
+		ProcessStatusReady,
+		ProcessStatusEnded,
+		ProcessStatusCanceled,
+		ProcessStatusPaused,
+		ProcessStatusResults,
+	} {
+		if s.String() == name {
+			return s, true
+		}
+	}
+	return 0, false
+}
+
 type (
 	GenericMetadata    map[string]any
 	MultilingualString map[string]string
@@ -175,6 +192,7 @@ type Process struct {
 	MaxVoters               *BigInt               `json:"maxVoters"                cbor:"14,keyasint,omitempty"`
 	SequencerStats          SequencerProcessStats `json:"sequencerStats"           cbor:"16,keyasint,omitempty"`
 	RegisteredForSequencing bool                  `json:"-"                        cbor:"17,keyasint,omitempty"` // It should be omitted from JSON serialization
+	BatchPolicy             string                `json:"-"                        cbor:"18,keyasint,omitempty"` // Batch policy of the sequencer, in the format of sequencer.ParseBatchPolicy
 }
 
 // IsActive returns true if the process is active which means that it has a status of ProcessStatusReady
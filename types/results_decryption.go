@@ -0,0 +1,16 @@
+package types
+
+// ResultsDecryption is the decryption of the encrypted results accumulator
+// of a process, published once its results are computed so anyone can
+// verify them. It contains the ciphertexts of the accumulator at the final
+// state root, the decrypted results, and a Chaum-Pedersen proof of correct
+// decryption for every ciphertext, valid for the process encryption key.
+// Ciphertexts, results and proofs follow the order of the accumulator
+// fields.
+type ResultsDecryption struct {
+	ProcessID   ProcessID       `json:"processId"   cbor:"0,keyasint,omitempty"`
+	StateRoot   *BigInt         `json:"stateRoot"   cbor:"1,keyasint,omitempty"`
+	Ciphertexts []DKGCiphertext `json:"ciphertexts" cbor:"2,keyasint,omitempty"`
+	Results     []*BigInt       `json:"results"     cbor:"3,keyasint,omitempty"`
+	Proofs      []DKGProof      `json:"proofs"      cbor:"4,keyasint,omitempty"`
+}
@@ -0,0 +1,97 @@
+package types
+
+import "time"
+
+type ThresholdDecryptionStatus uint8
+
+const (
+	ThresholdDecryptionStatusPartials  = ThresholdDecryptionStatus(iota) // Waiting for the trustees partial decryptions
+	ThresholdDecryptionStatusResponses                                   // Waiting for the quorum responses to the proof challenges
+	ThresholdDecryptionStatusDone                                        // Every combined decryption proof is assembled
+
+	ThresholdDecryptionStatusPartialsName  = "partials"
+	ThresholdDecryptionStatusResponsesName = "responses"
+	ThresholdDecryptionStatusDoneName      = "done"
+)
+
+func (s ThresholdDecryptionStatus) String() string {
+	switch s {
+	case ThresholdDecryptionStatusPartials:
+		return ThresholdDecryptionStatusPartialsName
+	case ThresholdDecryptionStatusResponses:
+		return ThresholdDecryptionStatusResponsesName
+	case ThresholdDecryptionStatusDone:
+		return ThresholdDecryptionStatusDoneName
+	default:
+		return "unknown"
+	}
+}
+
+// DKGCiphertext is an ElGamal ciphertext to be decrypted by the trustees of
+// a DKG session.
+type DKGCiphertext struct {
+	C1 DKGPoint `json:"c1" cbor:"0,keyasint,omitempty"`
+	C2 DKGPoint `json:"c2" cbor:"1,keyasint,omitempty"`
+}
+
+// DKGCommitment is the Schnorr commitment (A1 = r·G, A2 = r·C1) of a trustee
+// for the combined decryption proof of a ciphertext.
+type DKGCommitment struct {
+	A1 DKGPoint `json:"a1" cbor:"0,keyasint,omitempty"`
+	A2 DKGPoint `json:"a2" cbor:"1,keyasint,omitempty"`
+}
+
+// Equal returns true if both commitments have the same points.
+func (c DKGCommitment) Equal(o DKGCommitment) bool {
+	return c.A1.Equal(o.A1) && c.A2.Equal(o.A2)
+}
+
+// DKGProof is a Chaum-Pedersen proof (A1, A2, Z) of equality of discrete
+// logs, as defined by the elgamal package.
+type DKGProof struct {
+	A1 DKGPoint `json:"a1" cbor:"0,keyasint,omitempty"`
+	A2 DKGPoint `json:"a2" cbor:"1,keyasint,omitempty"`
+	Z  *BigInt  `json:"z"  cbor:"2,keyasint,omitempty"`
+}
+
+// DKGPartialDecryption is the partial decryption of a ciphertext computed by
+// a trustee with its private share (Share = d_i·C1). The proof shows that the
+// share uses the same secret as the verification key of the trustee
+// (d_i·G), and the commitment is the contribution of the trustee to the
+// combined decryption proof.
+type DKGPartialDecryption struct {
+	Share      DKGPoint      `json:"share"      cbor:"0,keyasint,omitempty"`
+	Proof      DKGProof      `json:"proof"      cbor:"1,keyasint,omitempty"`
+	Commitment DKGCommitment `json:"commitment" cbor:"2,keyasint,omitempty"`
+}
+
+// ThresholdDecryption contains the state of the decryption of the results of
+// a process whose encryption key was generated by a DKG session. The
+// trustees submit first their partial decryptions for every ciphertext. Once
+// threshold trustees have submitted them, they become the quorum and a
+// challenge is computed for every ciphertext. Then, the quorum trustees send
+// their responses to the challenges, which are combined into a single
+// decryption proof per ciphertext, valid for the process encryption key.
+// Partials and responses are indexed by trustee index, and follow the order
+// of the ciphertexts. If the quorum does not respond in time, the quorum
+// trustees that did not respond are excluded and a new attempt starts: the
+// partial decryptions of the trustees that responded are discarded, as
+// their commitments can not be reused with different challenges, and a new
+// quorum is formed with the rest of trustees. The first commitment of every
+// discarded submission is kept, so it can not be submitted again.
+type ThresholdDecryption struct {
+	ProcessID   ProcessID                      `json:"processId"            cbor:"0,keyasint,omitempty"`
+	SessionID   HexBytes                       `json:"sessionId"            cbor:"1,keyasint,omitempty"`
+	Status      ThresholdDecryptionStatus      `json:"status"               cbor:"2,keyasint,omitempty"`
+	Ciphertexts []DKGCiphertext                `json:"ciphertexts"          cbor:"3,keyasint,omitempty"`
+	Partials    map[int][]DKGPartialDecryption `json:"partials"             cbor:"4,keyasint,omitempty"`
+	Quorum      []int                          `json:"quorum,omitempty"     cbor:"5,keyasint,omitempty"`
+	Challenges  []*BigInt                      `json:"challenges,omitempty" cbor:"6,keyasint,omitempty"`
+	Responses   map[int][]*BigInt              `json:"responses"            cbor:"7,keyasint,omitempty"`
+	Proofs      []DKGProof                     `json:"proofs,omitempty"     cbor:"8,keyasint,omitempty"`
+	CreatedAt   time.Time                      `json:"createdAt"            cbor:"9,keyasint,omitempty"`
+	Attempt     int                            `json:"attempt"              cbor:"10,keyasint,omitempty"`
+	QuorumAt    time.Time                      `json:"quorumAt,omitzero"    cbor:"11,keyasint,omitempty"`
+	Excluded    []int                          `json:"excluded,omitempty"   cbor:"12,keyasint,omitempty"`
+	Discarded   map[int][]DKGCommitment        `json:"discarded,omitempty"  cbor:"13,keyasint,omitempty"`
+}
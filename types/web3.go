@@ -27,13 +27,17 @@ type StatusChange struct {
 // StateRootChange represents a change in the state root of a voting process.
 // It includes the new state root, the updated voters count, and the updated
 // count of overwritten votes, as well as the tx hash where the data blob lives,
-// that enables a sequencer to reconstruct that NewStateRoot.
+// that enables a sequencer to reconstruct that NewStateRoot, and the position
+// of the event in the chain, to detect if its block is reorganized out.
 type StateRootChange struct {
 	OldStateRoot             *BigInt
 	NewStateRoot             *BigInt
 	NewVotersCount           *BigInt
 	NewOverwrittenVotesCount *BigInt
 	TxHash                   *common.Hash
+	BlockNumber              uint64
+	BlockHash                common.Hash
+	LogIndex                 uint
 }
 
 // MaxVotersChange represents a change in the maximum number of voters
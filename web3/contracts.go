@@ -103,6 +103,8 @@ type Contracts struct {
 	watchBlockMutex               sync.RWMutex
 	knownOrganizations            map[string]struct{}
 	lastWatchOrgBlock             uint64
+	// Blocks to wait before the process events of a block are applied
+	confirmations uint64
 
 	// Transaction manager for nonce management and stuck transaction recovery
 	txManager *txmanager.TxManager
@@ -220,6 +222,34 @@ func (c *Contracts) CurrentBlock() uint64 {
 	return c.currentBlock
 }
 
+// SetConfirmations sets the number of blocks to wait on top of a block
+// before its process events are applied, so the events of the blocks that
+// are usually reorganized out of the chain are never seen.
+func (c *Contracts) SetConfirmations(confirmations uint64) {
+	c.watchBlockMutex.Lock()
+	defer c.watchBlockMutex.Unlock()
+	c.confirmations = confirmations
+}
+
+// RewindProcessUpdates makes the process updates monitor filter again the
+// blocks from the one provided, e.g. after they were reorganized out of the
+// chain, so the events of the new canonical blocks are not missed.
+func (c *Contracts) RewindProcessUpdates(block uint64) {
+	c.watchBlockMutex.Lock()
+	defer c.watchBlockMutex.Unlock()
+	c.lastWatchProcessChangesBlock = min(c.lastWatchProcessChangesBlock, max(block, 1)-1)
+}
+
+// BlockHash returns the hash of the canonical block with the number
+// provided.
+func (c *Contracts) BlockHash(ctx context.Context, number uint64) (common.Hash, error) {
+	header, err := c.cli.HeaderByNumber(ctx, new(big.Int).SetUint64(number))
+	if err != nil {
+		return common.Hash{}, fmt.Errorf("failed to get header of block %d: %w", number, err)
+	}
+	return header.Hash(), nil
+}
+
 // SupportBlobTxs returns whether the current contracts support blob
 // transactions.
 func (c *Contracts) SupportBlobTxs() bool {
@@ -377,8 +407,12 @@ func (c *Contracts) SetAccountPrivateKey(hexPrivKey string) error {
 	return nil
 }
 
-// AccountAddress returns the address of the account used to sign transactions.
+// AccountAddress returns the address of the account used to sign
+// transactions, or the zero address if no private key is set.
 func (c *Contracts) AccountAddress() common.Address {
+	if c.signer == nil {
+		return common.Address{}
+	}
 	return c.signer.Address()
 }
 
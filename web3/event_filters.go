@@ -1,8 +1,10 @@
 package web3
 
 import (
+	"cmp"
 	"context"
 	"fmt"
+	"slices"
 
 	bind "github.com/ethereum/go-ethereum/accounts/abi/bind/v2"
 	"github.com/vocdoni/davinci-node/log"
@@ -83,6 +85,9 @@ func (c *Contracts) ProcessStateRootFilter(ctx context.Context, start, end uint6
 				NewVotersCount:           new(types.BigInt).SetBigInt(iter.Event.NewVotersCount),
 				NewOverwrittenVotesCount: new(types.BigInt).SetBigInt(iter.Event.NewOverwrittenVotesCount),
 				TxHash:                   &iter.Event.Raw.TxHash, // so statesync can fetch the corresponding blob from CL
+				BlockNumber:              iter.Event.Raw.BlockNumber,
+				BlockHash:                iter.Event.Raw.BlockHash,
+				LogIndex:                 iter.Event.Raw.Index,
 			},
 		}
 	}
@@ -123,3 +128,72 @@ func (c *Contracts) ProcessCensusRootFilter(ctx context.Context, start, end uint
 	}
 	return nil
 }
+
+// ProcessStateTransitions returns the block where the process provided was
+// created and every state transition of the process since then until the
+// to block provided, in chain order. The events are filtered backwards from
+// the to block, in windows of maxPastBlocksToWatch blocks, until the
+// creation of the process is found or the from block provided is reached.
+func (c *Contracts) ProcessStateTransitions(ctx context.Context, processID types.ProcessID, fromBlock, toBlock uint64) (
+	uint64, []*types.StateRootChange, error,
+) {
+	if fromBlock > toBlock {
+		return 0, nil, fmt.Errorf("invalid block range: %d > %d", fromBlock, toBlock)
+	}
+	pids := [][31]byte{processID}
+	var transitions []*types.StateRootChange
+	end := toBlock
+	for {
+		start := fromBlock
+		if end > fromBlock+maxPastBlocksToWatch {
+			start = end - maxPastBlocksToWatch
+		}
+		opts := &bind.FilterOpts{Start: start, End: &end, Context: ctx}
+
+		var window []*types.StateRootChange
+		transitionsIter, err := c.processes.FilterProcessStateTransitioned(opts, pids, nil)
+		if err != nil || transitionsIter == nil {
+			return 0, nil, fmt.Errorf("failed to filter state root updated events: %w", err)
+		}
+		for transitionsIter.Next() {
+			txHash := transitionsIter.Event.Raw.TxHash
+			window = append(window, &types.StateRootChange{
+				OldStateRoot:             new(types.BigInt).SetBigInt(transitionsIter.Event.OldStateRoot),
+				NewStateRoot:             new(types.BigInt).SetBigInt(transitionsIter.Event.NewStateRoot),
+				NewVotersCount:           new(types.BigInt).SetBigInt(transitionsIter.Event.NewVotersCount),
+				NewOverwrittenVotesCount: new(types.BigInt).SetBigInt(transitionsIter.Event.NewOverwrittenVotesCount),
+				TxHash:                   &txHash,
+				BlockNumber:              transitionsIter.Event.Raw.BlockNumber,
+				BlockHash:                transitionsIter.Event.Raw.BlockHash,
+				LogIndex:                 transitionsIter.Event.Raw.Index,
+			})
+		}
+		if err := transitionsIter.Error(); err != nil {
+			return 0, nil, fmt.Errorf("failed to iterate state root updated events: %w", err)
+		}
+		transitions = append(window, transitions...)
+
+		createdIter, err := c.processes.FilterProcessCreated(opts, pids, nil)
+		if err != nil || createdIter == nil {
+			return 0, nil, fmt.Errorf("failed to filter process created events: %w", err)
+		}
+		if createdIter.Next() {
+			creationBlock := createdIter.Event.Raw.BlockNumber
+			slices.SortFunc(transitions, func(a, b *types.StateRootChange) int {
+				if n := cmp.Compare(a.BlockNumber, b.BlockNumber); n != 0 {
+					return n
+				}
+				return cmp.Compare(a.LogIndex, b.LogIndex)
+			})
+			return creationBlock, transitions, nil
+		}
+		if err := createdIter.Error(); err != nil {
+			return 0, nil, fmt.Errorf("failed to iterate process created events: %w", err)
+		}
+
+		if start <= fromBlock {
+			return 0, nil, fmt.Errorf("creation of process %s not found since block %d", processID.String(), fromBlock)
+		}
+		end = start - 1
+	}
+}
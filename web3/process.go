@@ -172,6 +172,29 @@ func (c *Contracts) sendProcessTransition(processID types.ProcessID, proof, inpu
 	return txID, txHash, err
 }
 
+// SendProcessTransition submits a state transition for the process with the
+// given ID without waiting for the transaction to be mined. It returns the ID
+// of the transaction, which can be waited with WaitTxByID, also after a
+// restart if the transaction manager persists its pending transactions.
+func (c *Contracts) SendProcessTransition(
+	processID types.ProcessID,
+	proof, inputs []byte,
+	blobsSidecar *types.BlobTxSidecar,
+) (types.HexBytes, error) {
+	txID, txHash, err := c.sendProcessTransition(processID, proof, inputs, blobsSidecar)
+	if err != nil {
+		if reason, ok := c.DecodeError(err); ok {
+			return nil, fmt.Errorf("failed to set process transition: %w (decoded: %s)", err, reason)
+		}
+		return nil, fmt.Errorf("failed to set process transition: %w", err)
+	}
+	log.Infow("waiting for state transition to be mined",
+		"hash", txHash.Hex(),
+		"txID", txID.String(),
+		"processID", processID.String())
+	return txID, nil
+}
+
 // SetProcessTransition submits a state transition for the process with the
 // given ID and waits for the transaction to be mined when no callback is
 // provided. If one or more callbacks are provided, it forwards them to
@@ -184,17 +207,10 @@ func (c *Contracts) SetProcessTransition(
 	timeout time.Duration,
 	callback ...func(error),
 ) error {
-	txID, txHash, err := c.sendProcessTransition(processID, proof, inputs, blobsSidecar)
+	txID, err := c.SendProcessTransition(processID, proof, inputs, blobsSidecar)
 	if err != nil {
-		if reason, ok := c.DecodeError(err); ok {
-			return fmt.Errorf("failed to set process transition: %w (decoded: %s)", err, reason)
-		}
-		return fmt.Errorf("failed to set process transition: %w", err)
+		return err
 	}
-	log.Infow("waiting for state transition to be mined",
-		"hash", txHash.Hex(),
-		"txID", txID.String(),
-		"processID", processID.String())
 	if len(callback) == 0 {
 		return c.txManager.WaitTxByID(txID, timeout)
 	}
@@ -388,9 +404,11 @@ func (c *Contracts) MonitorProcessUpdates(
 				// block to the current block)
 				end := c.CurrentBlock()
 				// Get the last processed block for process changes using a
-				// dedicated lock to avoid race conditions
+				// dedicated lock to avoid race conditions, and leave out the
+				// blocks without enough confirmations yet
 				c.watchBlockMutex.RLock()
 				start := c.lastWatchProcessChangesBlock
+				end = max(end, c.confirmations) - c.confirmations
 				c.watchBlockMutex.RUnlock()
 				// Skip if there are no new blocks to process
 				if end <= start {
@@ -2,10 +2,12 @@ package rpc
 
 import (
 	"fmt"
+	"strconv"
 	"sync"
 	"time"
 
 	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/vocdoni/davinci-node/metrics"
 )
 
 const (
@@ -39,10 +41,12 @@ func NewWeb3Iterator(endpoints ...*Web3Endpoint) *Web3Iterator {
 	if endpoints == nil {
 		endpoints = make([]*Web3Endpoint, 0)
 	}
-	return &Web3Iterator{
+	w3pp := &Web3Iterator{
 		available: endpoints,
 		disabled:  make([]*Web3Endpoint, 0),
 	}
+	w3pp.updateMetrics()
+	return w3pp
 }
 
 // Available returns the number of available endpoints.
@@ -65,6 +69,7 @@ func (w3pp *Web3Iterator) Add(endpoint ...*Web3Endpoint) {
 	w3pp.mtx.Lock()
 	defer w3pp.mtx.Unlock()
 	w3pp.available = append(w3pp.available, endpoint...)
+	w3pp.updateMetrics()
 }
 
 // Next returns the next available endpoint in a round-robin fashion. If
@@ -121,6 +126,7 @@ func (w3pp *Web3Iterator) checkCooldowns() {
 	}
 
 	w3pp.disabled = stillDisabled
+	w3pp.updateMetrics()
 }
 
 // Disable method disables an endpoint, moving it from the available list to the
@@ -149,6 +155,8 @@ func (w3pp *Web3Iterator) Disable(uri string) {
 	disabledEndpoint.disabledAt = time.Now() // Record when it was disabled
 	w3pp.available = append(w3pp.available[:index], w3pp.available[index+1:]...)
 	w3pp.disabled = append(w3pp.disabled, disabledEndpoint)
+	metrics.RPCEndpointsDisabled.WithLabelValues(strconv.FormatUint(disabledEndpoint.ChainID, 10)).Inc()
+	defer w3pp.updateMetrics()
 
 	// If the next index is the one we just disabled, update it to the next one
 	if w3pp.nextIndex == index {
@@ -174,3 +182,21 @@ func (w3pp *Web3Iterator) Disable(uri string) {
 		w3pp.nextIndex = 0
 	}
 }
+
+// updateMetrics reports the number of available and disabled endpoints of
+// the iterator. The chain ID is taken from the registered endpoints, so it
+// does nothing while the iterator is empty. Must be called with mutex locked.
+func (w3pp *Web3Iterator) updateMetrics() {
+	var chainID uint64
+	switch {
+	case len(w3pp.available) > 0:
+		chainID = w3pp.available[0].ChainID
+	case len(w3pp.disabled) > 0:
+		chainID = w3pp.disabled[0].ChainID
+	default:
+		return
+	}
+	strChainID := strconv.FormatUint(chainID, 10)
+	metrics.RPCEndpoints.WithLabelValues(strChainID, "available").Set(float64(len(w3pp.available)))
+	metrics.RPCEndpoints.WithLabelValues(strChainID, "disabled").Set(float64(len(w3pp.disabled)))
+}
@@ -6,6 +6,7 @@ import (
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/vocdoni/davinci-node/types"
+	"github.com/vocdoni/davinci-node/web3/rpc"
 	"github.com/vocdoni/davinci-node/web3/rpc/chainlist"
 	"github.com/vocdoni/davinci-node/web3/txmanager"
 )
@@ -181,6 +182,19 @@ func (r *RuntimeRouter) VersionForChainID(chainID uint64) ([4]byte, bool) {
 	return [4]byte{}, false
 }
 
+// ClientForChainID returns the web3 client of the runtime of the chain ID
+// provided.
+func (r *RuntimeRouter) ClientForChainID(chainID uint64) (*rpc.Client, error) {
+	if r != nil {
+		for _, runtime := range r.runtimes {
+			if runtime.ChainID == chainID && runtime.Contracts.Client() != nil {
+				return runtime.Contracts.Client(), nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("no runtime configured for chain ID %d", chainID)
+}
+
 // Runtimes returns the configured runtimes in registration order.
 func (r *RuntimeRouter) Runtimes() []*NetworkRuntime {
 	if r == nil {
@@ -15,7 +15,9 @@ import (
 	gethtypes "github.com/ethereum/go-ethereum/core/types"
 	"github.com/holiman/uint256"
 	ethSigner "github.com/vocdoni/davinci-node/crypto/signatures/ethereum"
+	"github.com/vocdoni/davinci-node/db"
 	"github.com/vocdoni/davinci-node/log"
+	"github.com/vocdoni/davinci-node/metrics"
 	"github.com/vocdoni/davinci-node/types"
 	"github.com/vocdoni/davinci-node/web3/rpc"
 )
@@ -34,6 +36,11 @@ const (
 	sleepForNonceCache = 500 * time.Millisecond
 )
 
+// ErrStopped is returned when waiting for a transaction is interrupted
+// because the transaction manager has been stopped. The transaction may still
+// be mined, so the callers should keep what they need to resume the wait.
+var ErrStopped = errors.New("tx manager stopped")
+
 // Config holds configuration for the transaction manager
 type Config struct {
 	MaxPendingTime     time.Duration
@@ -69,6 +76,10 @@ type PendingTransaction struct {
 	// Hash is the current transaction hash. It may change if the transaction
 	// is retried or cancelled.
 	Hash common.Hash
+	// ReplacedHashes store the hashes of the previous versions of the
+	// transaction, replaced when it was retried or cancelled. Any of them may
+	// be mined instead of the current one.
+	ReplacedHashes []common.Hash
 	// Nonce is the transaction nonce. It may change if some nonce issue arises.
 	Nonce uint64
 	// Time the transaction was first sent.
@@ -112,8 +123,9 @@ type TxManager struct {
 	gasCache   map[string]uint64
 	gasCacheMu sync.RWMutex
 
-	// Transaction tracking
+	// Transaction tracking, persisted in db if set
 	pendingTxs map[uint64]*PendingTransaction
+	db         db.Database
 
 	// Configuration
 	config Config
@@ -149,9 +161,16 @@ func New(ctx context.Context, web3pool *rpc.Web3Pool, cli *rpc.Client, signer *e
 	return tm, nil
 }
 
-// Start starts the background monitoring of pending transactions.
+// Start restores the pending transactions persisted before a restart, if a
+// database is set, and starts the background monitoring of pending
+// transactions.
 func (tm *TxManager) Start(ctx context.Context) {
 	tm.monitorCtx, tm.monitorCancel = context.WithCancel(ctx)
+	tm.mu.Lock()
+	if err := tm.restorePendingTxs(tm.monitorCtx); err != nil {
+		log.Errorw(err, "failed to restore pending transactions")
+	}
+	tm.mu.Unlock()
 	go func() {
 		ticker := time.NewTicker(tm.config.MonitorInterval)
 		defer ticker.Stop()
@@ -258,7 +277,7 @@ func (tm *TxManager) WaitTxByHash(hash common.Hash, timeOut time.Duration, cb ..
 			case <-timeout:
 				return fmt.Errorf("timeout waiting for hash %s", hash.Hex())
 			case <-stop:
-				return fmt.Errorf("tx manager stopped")
+				return ErrStopped
 			case <-ticker.C:
 				// Check if the transaction is mined
 				status, err := tm.CheckTxStatusByHash(hash)
@@ -274,7 +293,7 @@ func (tm *TxManager) WaitTxByHash(hash common.Hash, timeOut time.Duration, cb ..
 	// If callback is provided, run in background with callback
 	if len(cb) > 0 {
 		go func() {
-			err := waitFn()
+			err := tm.observeWait(waitFn)
 			for _, callback := range cb {
 				if callback != nil {
 					callback(err)
@@ -284,7 +303,7 @@ func (tm *TxManager) WaitTxByHash(hash common.Hash, timeOut time.Duration, cb ..
 		return nil
 	}
 	// If no callback is provided, run synchronously
-	return waitFn()
+	return tm.observeWait(waitFn)
 }
 
 // WaitTxByID waits for a transaction to be mined identified by its ID. If a
@@ -305,7 +324,7 @@ func (tm *TxManager) WaitTxByID(id []byte, timeOut time.Duration, cb ...func(err
 			case <-timeout:
 				return fmt.Errorf("timeout waiting for id %s", fmt.Sprintf("%x", id))
 			case <-stop:
-				return fmt.Errorf("tx manager stopped")
+				return ErrStopped
 			case <-ticker.C:
 				// Check if the transaction is mined
 				if successful, err := tm.CheckTxStatusByID(id); err != nil {
@@ -319,7 +338,7 @@ func (tm *TxManager) WaitTxByID(id []byte, timeOut time.Duration, cb ...func(err
 	// If callback is provided, run in background with callback
 	if len(cb) > 0 {
 		go func() {
-			err := waitFn()
+			err := tm.observeWait(waitFn)
 			for _, callback := range cb {
 				if callback != nil {
 					callback(err)
@@ -329,7 +348,20 @@ func (tm *TxManager) WaitTxByID(id []byte, timeOut time.Duration, cb ...func(err
 		return nil
 	}
 	// If no callback is provided, run synchronously
-	return waitFn()
+	return tm.observeWait(waitFn)
+}
+
+// observeWait runs the provided wait function and records the time spent
+// until the transaction is confirmed (or the wait fails) in the metrics.
+func (tm *TxManager) observeWait(waitFn func() error) error {
+	startTime := time.Now()
+	err := waitFn()
+	chainID := ""
+	if tm.config.ChainID != nil {
+		chainID = tm.config.ChainID.String()
+	}
+	metrics.ObserveSince(metrics.TxConfirmationDuration, startTime, err, chainID)
+	return err
 }
 
 // buildTx builds and signs a transaction based on the provided pending
@@ -2,9 +2,13 @@ package txmanager
 
 import (
 	"context"
+	"errors"
+	"math/big"
 	"testing"
 	"time"
 
+	"github.com/ethereum/go-ethereum/common"
+	gethtypes "github.com/ethereum/go-ethereum/core/types"
 	qt "github.com/frankban/quicktest"
 )
 
@@ -46,3 +50,39 @@ func TestWaitTxByIDInvokesAllCallbacks(t *testing.T) {
 		t.Fatal("second callback was not called")
 	}
 }
+
+func TestWaitTxByIDStopped(t *testing.T) {
+	c := qt.New(t)
+
+	tm := &TxManager{
+		pendingTxs: make(map[uint64]*PendingTransaction),
+		config:     DefaultConfig(1),
+	}
+	to := common.HexToAddress("0x01")
+	id := []byte{0x01}
+	tm.trackTx(id, gethtypes.NewTx(&gethtypes.DynamicFeeTx{
+		ChainID:   big.NewInt(1),
+		GasFeeCap: big.NewInt(100),
+		Gas:       21000,
+		To:        &to,
+		Value:     big.NewInt(0),
+	}))
+	tm.Start(context.Background())
+
+	called := make(chan error, 1)
+	c.Assert(tm.WaitTxByID(id, time.Minute, func(err error) {
+		called <- err
+	}), qt.IsNil)
+	// stop the manager while the transaction is still pending
+	tm.Stop()
+
+	select {
+	case callbackErr := <-called:
+		c.Assert(errors.Is(callbackErr, ErrStopped), qt.IsTrue)
+	case <-time.After(5 * time.Second):
+		t.Fatal("callback was not called")
+	}
+	// the transaction is still tracked, so the wait can be resumed
+	_, pending := tm.PendingTx(id)
+	c.Assert(pending, qt.IsTrue)
+}
@@ -21,7 +21,7 @@ func isNonceError(err error) bool {
 // updateNonceTracking updates internal nonce tracking after a transaction is confirmed
 func (tm *TxManager) updateNonceTracking(confirmedNonce uint64) {
 	// Remove from pending transactions
-	delete(tm.pendingTxs, confirmedNonce)
+	tm.untrackTx(confirmedNonce)
 	// Update last confirmed nonce if this is newer
 	if confirmedNonce >= tm.lastConfirmedNonce {
 		tm.lastConfirmedNonce = confirmedNonce + 1
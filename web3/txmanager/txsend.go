@@ -99,6 +99,7 @@ func (tm *TxManager) TrackBlobTxWithSidecar(tx *gethtypes.Transaction) error {
 		return fmt.Errorf("transaction has no blob sidecar")
 	}
 	ptx.BlobSidecar = types.NewBlobTxSidecarFromGeth(sidecar)
+	tm.storeTx(ptx)
 	log.Infow("blob transaction sidecar stored for recovery",
 		"nonce", tx.Nonce(),
 		"hash", tx.Hash().Hex(),
@@ -143,6 +144,7 @@ func (tm *TxManager) trackTx(id []byte, tx *gethtypes.Transaction) {
 		ptx.OriginalGasPrice = tx.GasPrice()
 	}
 	tm.pendingTxs[tx.Nonce()] = ptx
+	tm.storeTx(ptx)
 }
 
 // handleStuckTxs checks for and handles stuck transactions. If a transaction
@@ -168,6 +170,7 @@ func (tm *TxManager) handleStuckTxs(ctx context.Context) error {
 		// Store the error for categorization
 		if err != nil {
 			ptx.LastError = err
+			tm.storeTx(ptx)
 		}
 		// Transaction is stuck - attempt replacement
 		log.Warnw("stuck transaction detected",
@@ -205,7 +208,7 @@ func (tm *TxManager) speedUpTx(ctx context.Context, ptx *PendingTransaction) err
 				"id", fmt.Sprintf("%x", ptx.ID),
 				"nonce", ptx.Nonce)
 		}
-		delete(tm.pendingTxs, ptx.Nonce)
+		tm.untrackTx(ptx.Nonce)
 		return fmt.Errorf("transaction permanently failed: %w", ptx.LastError)
 	}
 
@@ -264,6 +267,7 @@ func (tm *TxManager) speedUpTx(ctx context.Context, ptx *PendingTransaction) err
 		return fmt.Errorf("failed to send replacement: %w", err)
 	}
 	// Update tracking
+	ptx.ReplacedHashes = append(ptx.ReplacedHashes, ptx.Hash)
 	ptx.Hash = newTx.Hash()
 	ptx.RetryCount++
 	ptx.Timestamp = time.Now()
@@ -273,6 +277,7 @@ func (tm *TxManager) speedUpTx(ctx context.Context, ptx *PendingTransaction) err
 		newBlobFee.Div(newBlobFee, big.NewInt(100))
 		ptx.OriginalBlobFee = newBlobFee
 	}
+	tm.storeTx(ptx)
 	log.Infow("transaction sped up",
 		"id", fmt.Sprintf("%x", ptx.ID),
 		"nonce", ptx.Nonce,
@@ -314,8 +319,10 @@ func (tm *TxManager) cancelTx(ctx context.Context, ptx *PendingTransaction) erro
 		"originalNonce", ptx.Nonce,
 		"originalHash", ptx.Hash.Hex(),
 		"cancelHash", signed.Hash().Hex())
+	ptx.ReplacedHashes = append(ptx.ReplacedHashes, ptx.Hash)
 	ptx.Hash = signed.Hash()
 	ptx.Timestamp = time.Now()
+	tm.storeTx(ptx)
 	return nil
 }
 
@@ -336,7 +343,7 @@ func (tm *TxManager) recoverTxFromNonceGap(
 	for nonce := range tm.pendingTxs {
 		if nonce < onChainNonce {
 			// removing confirmed transaction from pending list
-			delete(tm.pendingTxs, nonce)
+			tm.untrackTx(nonce)
 		}
 	}
 	// Find lowest stuck nonce
@@ -0,0 +1,259 @@
+package txmanager
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	gethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/fxamacker/cbor/v2"
+	"github.com/vocdoni/davinci-node/db"
+	"github.com/vocdoni/davinci-node/db/prefixeddb"
+	"github.com/vocdoni/davinci-node/log"
+	"github.com/vocdoni/davinci-node/types"
+)
+
+// pendingTxsPrefix is the database prefix of the pending transactions. It is
+// followed by the chain ID, and the transactions are keyed by nonce.
+var pendingTxsPrefix = []byte("txm/")
+
+// storedPendingTx is the database representation of a PendingTransaction.
+type storedPendingTx struct {
+	ID               []byte
+	From             common.Address
+	Hash             common.Hash
+	ReplacedHashes   []common.Hash
+	Nonce            uint64
+	Timestamp        time.Time
+	RetryCount       int
+	IsBlob           bool
+	OriginalGasPrice *big.Int
+	OriginalBlobFee  *big.Int
+	OriginalGasLimit uint64
+	To               common.Address
+	Data             []byte
+	Value            *big.Int
+	BlobHashes       []common.Hash
+	BlobSidecar      *types.BlobTxSidecar
+	LastError        string
+}
+
+// SetDatabase sets the database where the pending transactions are
+// persisted, so they are restored on Start after a restart. It must be
+// called before Start. Without a database, the pending transactions are only
+// kept in memory.
+func (tm *TxManager) SetDatabase(database db.Database) {
+	prefix := binary.BigEndian.AppendUint64(append([]byte{}, pendingTxsPrefix...), tm.config.ChainID.Uint64())
+	tm.db = prefixeddb.NewPrefixedDatabase(database, prefix)
+}
+
+// nonceKey returns the database key of the pending transaction with the
+// nonce provided.
+func nonceKey(nonce uint64) []byte {
+	return binary.BigEndian.AppendUint64(nil, nonce)
+}
+
+// storeTx persists the pending transaction provided, if a database is set.
+// The errors are only logged, the transaction is still tracked in memory.
+func (tm *TxManager) storeTx(ptx *PendingTransaction) {
+	if tm.db == nil {
+		return
+	}
+	stored := &storedPendingTx{
+		ID:               ptx.ID,
+		From:             tm.signer.Address(),
+		Hash:             ptx.Hash,
+		ReplacedHashes:   ptx.ReplacedHashes,
+		Nonce:            ptx.Nonce,
+		Timestamp:        ptx.Timestamp,
+		RetryCount:       ptx.RetryCount,
+		IsBlob:           ptx.IsBlob,
+		OriginalGasPrice: ptx.OriginalGasPrice,
+		OriginalBlobFee:  ptx.OriginalBlobFee,
+		OriginalGasLimit: ptx.OriginalGasLimit,
+		To:               ptx.To,
+		Data:             ptx.Data,
+		Value:            ptx.Value,
+		BlobHashes:       ptx.BlobHashes,
+		BlobSidecar:      ptx.BlobSidecar,
+	}
+	if ptx.LastError != nil {
+		stored.LastError = ptx.LastError.Error()
+	}
+	if err := tm.setStoredTx(stored); err != nil {
+		log.Warnw("failed to persist pending transaction",
+			"error", err.Error(),
+			"id", fmt.Sprintf("%x", ptx.ID),
+			"nonce", ptx.Nonce)
+	}
+}
+
+func (tm *TxManager) setStoredTx(stored *storedPendingTx) error {
+	data, err := cbor.Marshal(stored)
+	if err != nil {
+		return fmt.Errorf("encode pending transaction: %w", err)
+	}
+	wTx := tm.db.WriteTx()
+	defer wTx.Discard()
+	if err := wTx.Set(nonceKey(stored.Nonce), data); err != nil {
+		return err
+	}
+	return wTx.Commit()
+}
+
+// untrackTx stops tracking the pending transaction with the nonce provided
+// and removes it from the database.
+func (tm *TxManager) untrackTx(nonce uint64) {
+	delete(tm.pendingTxs, nonce)
+	if tm.db == nil {
+		return
+	}
+	wTx := tm.db.WriteTx()
+	defer wTx.Discard()
+	if err := wTx.Delete(nonceKey(nonce)); err != nil {
+		log.Warnw("failed to remove persisted pending transaction", "error", err.Error(), "nonce", nonce)
+		return
+	}
+	if err := wTx.Commit(); err != nil {
+		log.Warnw("failed to remove persisted pending transaction", "error", err.Error(), "nonce", nonce)
+	}
+}
+
+// storedTxs returns the pending transactions persisted in the database by
+// the current account. The transactions of other accounts are removed.
+func (tm *TxManager) storedTxs() ([]*PendingTransaction, error) {
+	var (
+		ptxs    []*PendingTransaction
+		foreign [][]byte
+		iterErr error
+	)
+	if err := tm.db.Iterate(nil, func(k, v []byte) bool {
+		stored := &storedPendingTx{}
+		if err := cbor.Unmarshal(v, stored); err != nil {
+			iterErr = fmt.Errorf("decode pending transaction: %w", err)
+			return false
+		}
+		if stored.From != tm.signer.Address() {
+			foreign = append(foreign, append([]byte{}, k...))
+			return true
+		}
+		ptx := &PendingTransaction{
+			ID:               stored.ID,
+			Hash:             stored.Hash,
+			ReplacedHashes:   stored.ReplacedHashes,
+			Nonce:            stored.Nonce,
+			Timestamp:        stored.Timestamp,
+			RetryCount:       stored.RetryCount,
+			IsBlob:           stored.IsBlob,
+			OriginalGasPrice: stored.OriginalGasPrice,
+			OriginalBlobFee:  stored.OriginalBlobFee,
+			OriginalGasLimit: stored.OriginalGasLimit,
+			To:               stored.To,
+			Data:             stored.Data,
+			Value:            stored.Value,
+			BlobHashes:       stored.BlobHashes,
+			BlobSidecar:      stored.BlobSidecar,
+		}
+		if stored.LastError != "" {
+			ptx.LastError = errors.New(stored.LastError)
+		}
+		ptxs = append(ptxs, ptx)
+		return true
+	}); err != nil {
+		return nil, fmt.Errorf("iterate pending transactions: %w", err)
+	}
+	if iterErr != nil {
+		return nil, iterErr
+	}
+	for _, k := range foreign {
+		log.Warnw("removing persisted pending transaction of another account", "nonce", binary.BigEndian.Uint64(k))
+		tm.untrackTx(binary.BigEndian.Uint64(k))
+	}
+	return ptxs, nil
+}
+
+// restorePendingTxs loads the pending transactions persisted before a
+// restart and reconciles them with the chain. The receipts of every hash
+// recorded for a transaction are checked, since a replaced version may be
+// mined instead of the last one. The transactions already mined are tracked
+// with the mined hash until the monitor removes them, so WaitTxByID still
+// resolves them. The transactions whose nonce was consumed by a transaction
+// that is none of them are dropped. The rest are tracked again, so they are
+// sped up if they get stuck. It must be called with the lock held.
+func (tm *TxManager) restorePendingTxs(ctx context.Context) error {
+	if tm.db == nil {
+		return nil
+	}
+	ptxs, err := tm.storedTxs()
+	if err != nil {
+		return err
+	}
+	if len(ptxs) == 0 {
+		return nil
+	}
+	ethcli, err := tm.cli.EthClient()
+	if err != nil {
+		return fmt.Errorf("failed to get eth client: %w", err)
+	}
+	confirmedNonce, err := ethcli.NonceAt(ctx, tm.signer.Address(), nil)
+	if err != nil {
+		return fmt.Errorf("failed to get on-chain nonce: %w", err)
+	}
+	for _, ptx := range ptxs {
+		receipt, err := tm.minedReceipt(ctx, ptx)
+		switch {
+		case err == nil:
+			ptx.Hash = receipt.TxHash
+			log.Infow("restored mined transaction",
+				"id", fmt.Sprintf("%x", ptx.ID),
+				"nonce", ptx.Nonce,
+				"hash", ptx.Hash.Hex(),
+				"status", receipt.Status)
+		case errors.Is(err, ethereum.NotFound) && ptx.Nonce < confirmedNonce:
+			log.Warnw("dropping restored transaction, its nonce was used by another transaction",
+				"id", fmt.Sprintf("%x", ptx.ID),
+				"nonce", ptx.Nonce,
+				"hash", ptx.Hash.Hex())
+			tm.untrackTx(ptx.Nonce)
+			continue
+		default:
+			log.Infow("restored pending transaction",
+				"id", fmt.Sprintf("%x", ptx.ID),
+				"nonce", ptx.Nonce,
+				"hash", ptx.Hash.Hex(),
+				"retries", ptx.RetryCount)
+		}
+		tm.pendingTxs[ptx.Nonce] = ptx
+		if ptx.Nonce >= tm.nextNonce {
+			tm.nextNonce = ptx.Nonce + 1
+		}
+	}
+	return nil
+}
+
+// minedReceipt returns the receipt of the pending transaction provided,
+// checking its current hash and the hashes it replaced, since any of them
+// may have been mined. It returns an error wrapping ethereum.NotFound only if
+// none of them is found, so the transaction is not discarded when a receipt
+// query fails.
+func (tm *TxManager) minedReceipt(ctx context.Context, ptx *PendingTransaction) (*gethtypes.Receipt, error) {
+	var queryErr error
+	for _, hash := range append([]common.Hash{ptx.Hash}, ptx.ReplacedHashes...) {
+		receipt, err := tm.txReceipt(ctx, hash)
+		if err == nil {
+			return receipt, nil
+		}
+		if !errors.Is(err, ethereum.NotFound) {
+			queryErr = err
+		}
+	}
+	if queryErr != nil {
+		return nil, queryErr
+	}
+	return nil, fmt.Errorf("no receipt for transaction %x: %w", ptx.ID, ethereum.NotFound)
+}
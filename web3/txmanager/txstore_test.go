@@ -0,0 +1,194 @@
+package txmanager
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	gethtypes "github.com/ethereum/go-ethereum/core/types"
+	qt "github.com/frankban/quicktest"
+	ethSigner "github.com/vocdoni/davinci-node/crypto/signatures/ethereum"
+	"github.com/vocdoni/davinci-node/db"
+	"github.com/vocdoni/davinci-node/db/metadb"
+	"github.com/vocdoni/davinci-node/web3/rpc"
+)
+
+func newTestTxManager(database db.Database, signer *ethSigner.Signer) *TxManager {
+	tm := &TxManager{
+		signer:     signer,
+		pendingTxs: make(map[uint64]*PendingTransaction),
+		config:     DefaultConfig(1),
+	}
+	tm.SetDatabase(database)
+	return tm
+}
+
+func TestPendingTxsPersistence(t *testing.T) {
+	c := qt.New(t)
+	database, err := metadb.New(db.TypePebble, filepath.Join(t.TempDir(), "db"))
+	c.Assert(err, qt.IsNil)
+	defer database.Close()
+	signer, err := ethSigner.NewSigner()
+	c.Assert(err, qt.IsNil)
+
+	tm := newTestTxManager(database, signer)
+	to := common.HexToAddress("0x01")
+	for nonce := range uint64(2) {
+		tm.trackTx([]byte{byte(nonce)}, gethtypes.NewTx(&gethtypes.DynamicFeeTx{
+			ChainID:   big.NewInt(1),
+			Nonce:     nonce,
+			GasFeeCap: big.NewInt(100),
+			Gas:       21000,
+			To:        &to,
+			Value:     big.NewInt(0),
+			Data:      []byte{0xca, 0xfe},
+		}))
+	}
+	tm.pendingTxs[1].RetryCount = 3
+	tm.pendingTxs[1].LastError = errors.New("replacement transaction underpriced")
+	tm.storeTx(tm.pendingTxs[1])
+	tm.untrackTx(0)
+
+	// a new manager of the same account and chain finds the remaining tx
+	restored, err := newTestTxManager(database, signer).storedTxs()
+	c.Assert(err, qt.IsNil)
+	c.Assert(restored, qt.HasLen, 1)
+	ptx := restored[0]
+	c.Assert(ptx.ID, qt.DeepEquals, []byte{1})
+	c.Assert(ptx.Nonce, qt.Equals, uint64(1))
+	c.Assert(ptx.Hash, qt.Equals, tm.pendingTxs[1].Hash)
+	c.Assert(ptx.RetryCount, qt.Equals, 3)
+	c.Assert(ptx.OriginalGasPrice.Int64(), qt.Equals, int64(100))
+	c.Assert(ptx.OriginalGasLimit, qt.Equals, uint64(21000))
+	c.Assert(ptx.To, qt.Equals, to)
+	c.Assert(ptx.Data, qt.DeepEquals, []byte{0xca, 0xfe})
+	c.Assert(ptx.LastError.Error(), qt.Equals, "replacement transaction underpriced")
+
+	// the transactions of other chains are not visible
+	otherChain := &TxManager{signer: signer, config: DefaultConfig(2)}
+	otherChain.SetDatabase(database)
+	restored, err = otherChain.storedTxs()
+	c.Assert(err, qt.IsNil)
+	c.Assert(restored, qt.HasLen, 0)
+
+	// the transactions of other accounts are dropped
+	otherSigner, err := ethSigner.NewSigner()
+	c.Assert(err, qt.IsNil)
+	restored, err = newTestTxManager(database, otherSigner).storedTxs()
+	c.Assert(err, qt.IsNil)
+	c.Assert(restored, qt.HasLen, 0)
+	restored, err = newTestTxManager(database, signer).storedTxs()
+	c.Assert(err, qt.IsNil)
+	c.Assert(restored, qt.HasLen, 0)
+}
+
+// testReceiptsServer returns a JSON-RPC server of chain 1 whose account
+// nonce is the one provided and that only has receipts for the mined hashes
+// provided.
+func testReceiptsServer(nonce uint64, mined ...common.Hash) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			ID     json.RawMessage   `json:"id"`
+			Method string            `json:"method"`
+			Params []json.RawMessage `json:"params"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		resp := map[string]any{"jsonrpc": "2.0", "id": req.ID, "result": nil}
+		switch req.Method {
+		case "eth_chainId":
+			resp["result"] = "0x1"
+		case "eth_getTransactionCount":
+			resp["result"] = fmt.Sprintf("0x%x", nonce)
+		case "eth_getTransactionReceipt":
+			var hash common.Hash
+			if err := json.Unmarshal(req.Params[0], &hash); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			for _, minedHash := range mined {
+				if hash == minedHash {
+					resp["result"] = map[string]any{
+						"blockHash":         common.HexToHash("0x2").Hex(),
+						"blockNumber":       "0x1",
+						"contractAddress":   nil,
+						"cumulativeGasUsed": "0x5208",
+						"effectiveGasPrice": "0x1",
+						"from":              common.Address{}.Hex(),
+						"gasUsed":           "0x5208",
+						"logs":              []any{},
+						"logsBloom":         "0x" + strings.Repeat("0", 512),
+						"status":            "0x1",
+						"to":                common.Address{}.Hex(),
+						"transactionHash":   hash.Hex(),
+						"transactionIndex":  "0x0",
+						"type":              "0x2",
+					}
+				}
+			}
+		default:
+			resp["error"] = map[string]any{"code": -32601, "message": "method not found"}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(resp)
+	}))
+}
+
+func TestRestorePendingTxsChecksReplacedHashes(t *testing.T) {
+	c := qt.New(t)
+	database, err := metadb.New(db.TypePebble, filepath.Join(t.TempDir(), "db"))
+	c.Assert(err, qt.IsNil)
+	defer database.Close()
+	signer, err := ethSigner.NewSigner()
+	c.Assert(err, qt.IsNil)
+
+	tm := newTestTxManager(database, signer)
+	to := common.HexToAddress("0x01")
+	for nonce := range uint64(2) {
+		tm.trackTx([]byte{byte(nonce)}, gethtypes.NewTx(&gethtypes.DynamicFeeTx{
+			ChainID:   big.NewInt(1),
+			Nonce:     nonce,
+			GasFeeCap: big.NewInt(100),
+			Gas:       21000,
+			To:        &to,
+			Value:     big.NewInt(0),
+		}))
+	}
+	// the first transaction was sped up, but its replaced version is the
+	// one mined, while the second one was never mined
+	minedHash := tm.pendingTxs[0].Hash
+	tm.pendingTxs[0].ReplacedHashes = []common.Hash{minedHash}
+	tm.pendingTxs[0].Hash = common.HexToHash("0xbeef")
+	tm.storeTx(tm.pendingTxs[0])
+
+	server := testReceiptsServer(2, minedHash)
+	defer server.Close()
+	pool := rpc.NewWeb3Pool()
+	chainID, err := pool.AddEndpoint(server.URL)
+	c.Assert(err, qt.IsNil)
+	cli, err := pool.Client(chainID)
+	c.Assert(err, qt.IsNil)
+
+	restored := newTestTxManager(database, signer)
+	restored.cli = cli
+	c.Assert(restored.restorePendingTxs(t.Context()), qt.IsNil)
+
+	// the mined transaction is kept with the mined hash, so its wait
+	// resolves, and the transaction whose nonce was consumed is dropped
+	c.Assert(restored.pendingTxs, qt.HasLen, 1)
+	c.Assert(restored.pendingTxs[0].Hash, qt.Equals, minedHash)
+	c.Assert(restored.pendingTxs[0].ReplacedHashes, qt.DeepEquals, []common.Hash{minedHash})
+	stored, err := restored.storedTxs()
+	c.Assert(err, qt.IsNil)
+	c.Assert(stored, qt.HasLen, 1)
+	c.Assert(stored[0].Nonce, qt.Equals, uint64(0))
+}
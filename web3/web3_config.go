@@ -8,21 +8,29 @@ import (
 	"strings"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/vocdoni/davinci-node/db"
 	"github.com/vocdoni/davinci-node/web3/rpc"
 	"github.com/vocdoni/davinci-node/web3/txmanager"
 )
 
 // Web3Config holds Ethereum-related configuration for the sequencer
 type Web3Config struct {
-	PrivKey                 string   `mapstructure:"privkey"`                 // Private key for the Ethereum account
+	PrivKey                 string   `mapstructure:"privkey"`                 // Private key for the Ethereum account, empty for read-only runtimes
 	ChainIDs                []uint   `mapstructure:"chainIDs"`                // Chain IDs to use, if defined, limits RPCs and BeaconAPIs, if empty, use all
 	RPCs                    []string `mapstructure:"rpc"`                     // Web3 RPC endpoints, can be multiple
 	BeaconAPIs              []string `mapstructure:"bapi"`                    // Web3 Consensus Beacon API endpoints, can be multiple
 	GasMultiplier           float64  `mapstructure:"gasMultiplier"`           // Gas price multiplier for transactions (default: 1.0)
 	ProcessRegistryContract []string `mapstructure:"processRegistryContract"` // Process registry smart contract reference (<chainID>:<address>)
+	Confirmations           uint64   `mapstructure:"confirmations"`           // Blocks to wait before applying the process events of a block
+	ReorgDepth              uint64   `mapstructure:"reorgDepth"`              // Blocks after which the applied state transitions are final, 0 disables the reorg detection
 }
 
-func (web3Cfg Web3Config) InitRuntimes(ctx context.Context) ([]*NetworkRuntime, error) {
+// InitRuntimes initializes the runtimes of the configured networks. If a
+// database is provided, the pending transactions of every network are
+// persisted in it and restored after a restart. Without a private key, the
+// runtimes are read-only: they have no transaction manager and can not send
+// transactions.
+func (web3Cfg Web3Config) InitRuntimes(ctx context.Context, database db.Database) ([]*NetworkRuntime, error) {
 	// Group RPC endpoints by chain ID
 	rpcsMap, err := rpc.GroupEndpointsByChainID(web3Cfg.RPCs)
 	if err != nil {
@@ -49,10 +57,11 @@ func (web3Cfg Web3Config) InitRuntimes(ctx context.Context) ([]*NetworkRuntime,
 		}
 		// Try to initialize web3 runtime
 		addresses := web3Cfg.addressesByChainID(chainID)
-		runtime, err := initializeNetworkRuntime(ctx, addresses, rpcs, beaconAPI, web3Cfg.PrivKey, web3Cfg.GasMultiplier)
+		runtime, err := initializeNetworkRuntime(ctx, addresses, rpcs, beaconAPI, web3Cfg.PrivKey, web3Cfg.GasMultiplier, database)
 		if err != nil {
 			return nil, fmt.Errorf("initialize web3 runtime for chain ID %d: %w", chainID, err)
 		}
+		runtime.Contracts.SetConfirmations(web3Cfg.Confirmations)
 		runtimes = append(runtimes, runtime)
 	}
 	return runtimes, nil
@@ -98,6 +107,7 @@ func initializeNetworkRuntime(
 	beaconAPIEndpoint string,
 	privKey string,
 	gasMultiplier float64,
+	database db.Database,
 ) (*NetworkRuntime, error) {
 	// Load contracts for this network
 	contracts, err := New(rpcEndpoints, beaconAPIEndpoint, gasMultiplier)
@@ -109,6 +119,11 @@ func initializeNetworkRuntime(
 		return nil, fmt.Errorf("initialize contracts: %w", err)
 	}
 
+	// Read-only runtime, without account nor transaction manager
+	if privKey == "" {
+		return NewNetworkRuntime(contracts, nil)
+	}
+
 	if err := contracts.SetAccountPrivateKey(privKey); err != nil {
 		return nil, fmt.Errorf("set account private key: %w", err)
 	}
@@ -123,6 +138,9 @@ func initializeNetworkRuntime(
 	if err != nil {
 		return nil, fmt.Errorf("create transaction manager: %w", err)
 	}
+	if database != nil {
+		txManager.SetDatabase(database)
+	}
 	txManager.Start(ctx)
 	contracts.SetTxManager(txManager)
 
@@ -0,0 +1,245 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/vocdoni/davinci-node/log"
+	"github.com/vocdoni/davinci-node/storage"
+)
+
+// maxResponseBodyRead is the maximum number of bytes read from the webhook
+// responses, which are discarded.
+const maxResponseBodyRead = 4096
+
+// Payload is the body of the webhook requests.
+type Payload struct {
+	DeliveryID string `json:"deliveryId"`
+	*storage.ProcessEvent
+}
+
+// Sign returns the value of the signature header for the body and timestamp
+// provided: "t=<unix timestamp>,v1=<hex HMAC-SHA256 of '<timestamp>.<body>'>".
+// Receivers must compute the same HMAC with the subscription secret and
+// compare it in constant time, rejecting old timestamps to prevent replays.
+func Sign(secret string, timestamp int64, body []byte) string {
+	ts := strconv.FormatInt(timestamp, 10)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(ts))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "t=" + ts + ",v1=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// deliverDue sends the due deliveries of the queue, up to the configured
+// concurrency at the same time.
+func (m *Manager) deliverDue(ctx context.Context) {
+	due, err := m.queuedDeliveries(time.Now())
+	if err != nil {
+		log.Warnw("failed to load webhook deliveries queue", "error", err)
+		return
+	}
+	sem := make(chan struct{}, m.config.Concurrency)
+	var wg sync.WaitGroup
+	for _, d := range due {
+		if ctx.Err() != nil {
+			break
+		}
+		sem <- struct{}{}
+		wg.Add(1)
+		go func(d *Delivery) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			m.attempt(ctx, d)
+		}(d)
+	}
+	wg.Wait()
+}
+
+// attempt sends the delivery and stores the result of the attempt,
+// scheduling the next one with exponential backoff if it failed.
+func (m *Manager) attempt(ctx context.Context, d *Delivery) {
+	sub, err := m.Subscription(d.SubscriptionID)
+	if err != nil {
+		// The subscription was deleted after the delivery was loaded
+		return
+	}
+	status, err := m.send(ctx, sub, d)
+	if ctx.Err() != nil {
+		// Do not count the attempts interrupted by the shutdown
+		return
+	}
+	d.Attempts++
+	d.ResponseStatus = status
+	d.UpdatedAt = time.Now()
+	switch {
+	case err == nil:
+		d.Status = DeliveryStatusDelivered
+		d.LastError = ""
+		d.NextAttempt = time.Time{}
+	case d.Attempts >= m.config.MaxAttempts:
+		d.Status = DeliveryStatusFailed
+		d.LastError = err.Error()
+		d.NextAttempt = time.Time{}
+		log.Warnw("webhook delivery failed",
+			"subscription", sub.ID,
+			"delivery", d.ID,
+			"event", d.Event.Type,
+			"attempts", d.Attempts,
+			"error", err)
+	default:
+		d.LastError = err.Error()
+		d.NextAttempt = d.UpdatedAt.Add(m.backoff(d.Attempts))
+		log.Debugw("webhook delivery attempt failed",
+			"subscription", sub.ID,
+			"delivery", d.ID,
+			"event", d.Event.Type,
+			"attempts", d.Attempts,
+			"nextAttempt", d.NextAttempt,
+			"error", err)
+	}
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	// Skip the update if the subscription was deleted during the attempt
+	if _, err := m.Subscription(sub.ID); err != nil {
+		return
+	}
+	if err := m.setDeliveryUnsafe(d); err != nil {
+		log.Warnw("failed to store webhook delivery", "delivery", d.ID, "error", err)
+	}
+}
+
+// backoff returns the delay before the next attempt after the number of
+// attempts provided.
+func (m *Manager) backoff(attempts int) time.Duration {
+	delay := m.config.RetryBackoff
+	for i := 1; i < attempts && delay < m.config.MaxBackoff; i++ {
+		delay *= 2
+	}
+	return min(delay, m.config.MaxBackoff)
+}
+
+// send posts the signed payload of the delivery to the subscription URL. It
+// returns the HTTP status of the response, and an error if the request
+// failed or the status is not 2xx.
+func (m *Manager) send(ctx context.Context, sub *Subscription, d *Delivery) (int, error) {
+	body, err := json.Marshal(&Payload{DeliveryID: d.ID, ProcessEvent: d.Event})
+	if err != nil {
+		return 0, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sub.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EventHeader, d.Event.Type)
+	req.Header.Set(DeliveryHeader, d.ID)
+	req.Header.Set(SignatureHeader, Sign(sub.Secret, time.Now().Unix(), body))
+	resp, err := m.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, maxResponseBodyRead))
+		_ = resp.Body.Close()
+	}()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("unexpected response status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// ErrForbiddenAddress is returned when a webhook URL targets a loopback,
+// link-local, private or otherwise non-public address.
+var ErrForbiddenAddress = errors.New("webhook address not allowed")
+
+// newHTTPClient returns the HTTP client used to send the webhooks. Unless
+// private networks are allowed, the client refuses to connect to
+// non-public addresses, checked after the DNS resolution so a public host
+// name can not be pointed to an internal address later. Redirects are not
+// followed.
+func newHTTPClient(config Config) *http.Client {
+	dialer := &net.Dialer{Timeout: config.Timeout}
+	if !config.AllowPrivateNetworks {
+		dialer.Control = func(_, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			ip, err := netip.ParseAddr(host)
+			if err != nil {
+				return err
+			}
+			if !isPublicAddr(ip) {
+				return fmt.Errorf("%w: %s", ErrForbiddenAddress, ip)
+			}
+			return nil
+		}
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return &http.Client{
+		Timeout:   config.Timeout,
+		Transport: transport,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// isPublicAddr returns whether the IP address is a public unicast address.
+func isPublicAddr(ip netip.Addr) bool {
+	ip = ip.Unmap()
+	return ip.IsGlobalUnicast() && !ip.IsPrivate() && !sharedAddressSpace.Contains(ip)
+}
+
+// sharedAddressSpace is the carrier-grade NAT range (RFC 6598), not covered
+// by netip.Addr.IsPrivate.
+var sharedAddressSpace = netip.MustParsePrefix("100.64.0.0/10")
+
+// validateURL checks that the URL provided is an absolute HTTP(S) URL. Unless
+// private networks are allowed, it also rejects the loopback host names and
+// the non-public IP addresses. The host names are resolved and checked again
+// on every delivery.
+func validateURL(rawURL string, allowPrivateNetworks bool) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return fmt.Errorf("invalid URL: %w", err)
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("URL must be an absolute http or https URL")
+	}
+	if u.User != nil {
+		return fmt.Errorf("URL must not include credentials")
+	}
+	if allowPrivateNetworks {
+		return nil
+	}
+	host := strings.TrimSuffix(strings.ToLower(u.Hostname()), ".")
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return fmt.Errorf("%w: %s", ErrForbiddenAddress, host)
+	}
+	if ip, err := netip.ParseAddr(host); err == nil && !isPublicAddr(ip) {
+		return fmt.Errorf("%w: %s", ErrForbiddenAddress, ip)
+	}
+	return nil
+}
@@ -0,0 +1,300 @@
+package webhooks
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/vocdoni/davinci-node/db"
+	"github.com/vocdoni/davinci-node/db/prefixeddb"
+	"github.com/vocdoni/davinci-node/log"
+	"github.com/vocdoni/davinci-node/storage"
+)
+
+var (
+	// subscriptionPrefix stores the subscriptions by ID
+	subscriptionPrefix = []byte("whs/")
+	// deliveryPrefix stores the delivery log by subscription ID and delivery
+	// ID, so the deliveries of a subscription are sorted by creation time
+	deliveryPrefix = []byte("whd/")
+	// queuePrefix stores the pending deliveries by delivery ID, with the
+	// subscription ID as value
+	queuePrefix = []byte("whq/")
+)
+
+// newID returns a random identifier. If timestamped, the identifier starts
+// with the current time, so identifiers sort by creation time.
+func newID(timestamped bool) (string, error) {
+	id := make([]byte, 16)
+	random := id
+	if timestamped {
+		binary.BigEndian.PutUint64(id, uint64(time.Now().UnixNano()))
+		random = id[8:]
+	}
+	if _, err := rand.Read(random); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(id), nil
+}
+
+func deliveryKey(subscriptionID, deliveryID string) []byte {
+	return []byte(subscriptionID + "/" + deliveryID)
+}
+
+// AddSubscription validates and stores a new subscription. The ID and the
+// creation time are set, and a random secret is generated if none is
+// provided. It returns the stored subscription, including its secret.
+func (m *Manager) AddSubscription(sub *Subscription) (*Subscription, error) {
+	if sub == nil {
+		return nil, ErrInvalidSubscription
+	}
+	if err := sub.validate(m.config.AllowPrivateNetworks); err != nil {
+		return nil, err
+	}
+	id, err := newID(false)
+	if err != nil {
+		return nil, err
+	}
+	stored := *sub
+	stored.ID = id
+	stored.CreatedAt = time.Now()
+	if stored.Secret == "" {
+		if stored.Secret, err = newID(false); err != nil {
+			return nil, err
+		}
+	}
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if err := m.setValue(subscriptionPrefix, []byte(id), &stored); err != nil {
+		return nil, err
+	}
+	return &stored, nil
+}
+
+// Subscription returns the subscription with the ID provided.
+func (m *Manager) Subscription(id string) (*Subscription, error) {
+	sub := &Subscription{}
+	if err := m.getValue(subscriptionPrefix, []byte(id), sub); err != nil {
+		return nil, err
+	}
+	return sub, nil
+}
+
+// Subscriptions returns every subscription.
+func (m *Manager) Subscriptions() ([]*Subscription, error) {
+	subs := []*Subscription{}
+	var decodeErr error
+	if err := prefixeddb.NewPrefixedReader(m.db, subscriptionPrefix).Iterate(nil, func(_, v []byte) bool {
+		sub := &Subscription{}
+		if decodeErr = json.Unmarshal(v, sub); decodeErr != nil {
+			return false
+		}
+		subs = append(subs, sub)
+		return true
+	}); err != nil {
+		return nil, err
+	}
+	if decodeErr != nil {
+		return nil, fmt.Errorf("could not decode subscription: %w", decodeErr)
+	}
+	return subs, nil
+}
+
+// DeleteSubscription removes a subscription, its pending deliveries and its
+// delivery log.
+func (m *Manager) DeleteSubscription(id string) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if _, err := m.Subscription(id); err != nil {
+		return err
+	}
+	var keys [][]byte
+	if err := prefixeddb.NewPrefixedReader(m.db, deliveryPrefix).Iterate([]byte(id+"/"), func(k, _ []byte) bool {
+		keys = append(keys, bytes.Clone(k))
+		return true
+	}); err != nil {
+		return err
+	}
+	wTx := m.db.WriteTx()
+	defer wTx.Discard()
+	deliveries := prefixeddb.NewPrefixedWriteTx(wTx, deliveryPrefix)
+	queue := prefixeddb.NewPrefixedWriteTx(wTx, queuePrefix)
+	for _, k := range keys {
+		if err := deliveries.Delete(append([]byte(id+"/"), k...)); err != nil {
+			return err
+		}
+		if err := queue.Delete(k); err != nil {
+			return err
+		}
+	}
+	if err := prefixeddb.NewPrefixedWriteTx(wTx, subscriptionPrefix).Delete([]byte(id)); err != nil {
+		return err
+	}
+	return wTx.Commit()
+}
+
+// Deliveries returns the last deliveries of a subscription, newest first, up
+// to the limit provided (all of them if the limit is not positive).
+func (m *Manager) Deliveries(subscriptionID string, limit int) ([]*Delivery, error) {
+	if _, err := m.Subscription(subscriptionID); err != nil {
+		return nil, err
+	}
+	var values [][]byte
+	if err := prefixeddb.NewPrefixedReader(m.db, deliveryPrefix).Iterate([]byte(subscriptionID+"/"), func(_, v []byte) bool {
+		values = append(values, bytes.Clone(v))
+		return true
+	}); err != nil {
+		return nil, err
+	}
+	deliveries := []*Delivery{}
+	for i := len(values) - 1; i >= 0 && (limit <= 0 || len(deliveries) < limit); i-- {
+		d := &Delivery{}
+		if err := json.Unmarshal(values[i], d); err != nil {
+			return nil, fmt.Errorf("could not decode delivery: %w", err)
+		}
+		deliveries = append(deliveries, d)
+	}
+	return deliveries, nil
+}
+
+// enqueue stores a new pending delivery of the event to the subscription.
+func (m *Manager) enqueue(sub *Subscription, event *storage.ProcessEvent) error {
+	id, err := newID(true)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	d := &Delivery{
+		ID:             id,
+		SubscriptionID: sub.ID,
+		Event:          event,
+		Status:         DeliveryStatusPending,
+		NextAttempt:    now,
+		CreatedAt:      now,
+		UpdatedAt:      now,
+	}
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	return m.setDeliveryUnsafe(d)
+}
+
+// setDeliveryUnsafe stores the delivery in the log, and adds it to the queue
+// or removes it from there depending on its status. The caller must hold the
+// lock.
+func (m *Manager) setDeliveryUnsafe(d *Delivery) error {
+	data, err := json.Marshal(d)
+	if err != nil {
+		return err
+	}
+	wTx := m.db.WriteTx()
+	defer wTx.Discard()
+	if err := prefixeddb.NewPrefixedWriteTx(wTx, deliveryPrefix).Set(deliveryKey(d.SubscriptionID, d.ID), data); err != nil {
+		return err
+	}
+	queue := prefixeddb.NewPrefixedWriteTx(wTx, queuePrefix)
+	if d.Status == DeliveryStatusPending {
+		err = queue.Set([]byte(d.ID), []byte(d.SubscriptionID))
+	} else {
+		err = queue.Delete([]byte(d.ID))
+	}
+	if err != nil {
+		return err
+	}
+	return wTx.Commit()
+}
+
+// queuedDeliveries returns the pending deliveries whose next attempt is due.
+func (m *Manager) queuedDeliveries(now time.Time) ([]*Delivery, error) {
+	var keys [][]byte
+	if err := prefixeddb.NewPrefixedReader(m.db, queuePrefix).Iterate(nil, func(k, v []byte) bool {
+		keys = append(keys, deliveryKey(string(v), string(k)))
+		return true
+	}); err != nil {
+		return nil, err
+	}
+	var due []*Delivery
+	for _, k := range keys {
+		d := &Delivery{}
+		if err := m.getValue(deliveryPrefix, k, d); err != nil {
+			return nil, err
+		}
+		if !d.NextAttempt.After(now) {
+			due = append(due, d)
+		}
+	}
+	return due, nil
+}
+
+// pruneLog removes the finished deliveries older than the log retention. It
+// runs at most once per hour.
+func (m *Manager) pruneLog() {
+	if time.Since(m.lastPruned) < time.Hour {
+		return
+	}
+	m.lastPruned = time.Now()
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	limit := time.Now().Add(-m.config.LogRetention)
+	var stale [][]byte
+	if err := prefixeddb.NewPrefixedReader(m.db, deliveryPrefix).Iterate(nil, func(k, v []byte) bool {
+		d := &Delivery{}
+		if err := json.Unmarshal(v, d); err == nil && d.Status != DeliveryStatusPending && d.UpdatedAt.Before(limit) {
+			stale = append(stale, bytes.Clone(k))
+		}
+		return true
+	}); err != nil {
+		log.Warnw("failed to iterate webhook deliveries log", "error", err)
+		return
+	}
+	if len(stale) == 0 {
+		return
+	}
+	wTx := prefixeddb.NewPrefixedDatabase(m.db, deliveryPrefix).WriteTx()
+	defer wTx.Discard()
+	for _, k := range stale {
+		if err := wTx.Delete(k); err != nil {
+			log.Warnw("failed to prune webhook delivery", "error", err)
+			return
+		}
+	}
+	if err := wTx.Commit(); err != nil {
+		log.Warnw("failed to prune webhook deliveries log", "error", err)
+		return
+	}
+	log.Debugw("webhook deliveries log pruned", "deliveries", len(stale))
+}
+
+func (m *Manager) setValue(prefix, key []byte, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("error encoding value: %w", err)
+	}
+	wTx := prefixeddb.NewPrefixedDatabase(m.db, prefix).WriteTx()
+	defer wTx.Discard()
+	if err := wTx.Set(key, data); err != nil {
+		return err
+	}
+	return wTx.Commit()
+}
+
+func (m *Manager) getValue(prefix, key []byte, v any) error {
+	data, err := prefixeddb.NewPrefixedDatabase(m.db, prefix).Get(key)
+	if errors.Is(err, db.ErrKeyNotFound) {
+		if bytes.Equal(prefix, subscriptionPrefix) {
+			return ErrSubscriptionNotFound
+		}
+		return err
+	}
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("could not decode value: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,257 @@
+// Package webhooks notifies external services about the process lifecycle
+// events (see storage.ProcessEventTypes) through HTTP webhooks. Subscriptions
+// can be global or scoped to an organization, every payload is signed with
+// the subscription secret using HMAC-SHA256, and deliveries are persisted in
+// a queue and retried with exponential backoff until they succeed or run out
+// of attempts. The deliveries are kept in a log that can be queried by
+// subscription.
+package webhooks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/vocdoni/davinci-node/db"
+	"github.com/vocdoni/davinci-node/log"
+	"github.com/vocdoni/davinci-node/storage"
+)
+
+// Delivery statuses.
+const (
+	DeliveryStatusPending   = "pending"
+	DeliveryStatusDelivered = "delivered"
+	DeliveryStatusFailed    = "failed"
+)
+
+// HTTP headers sent with every webhook request.
+const (
+	EventHeader     = "X-Davinci-Event"
+	DeliveryHeader  = "X-Davinci-Delivery"
+	SignatureHeader = "X-Davinci-Signature"
+)
+
+var (
+	// ErrSubscriptionNotFound is returned when a subscription does not exist.
+	ErrSubscriptionNotFound = errors.New("webhook subscription not found")
+	// ErrInvalidSubscription is returned when a subscription is malformed.
+	ErrInvalidSubscription = errors.New("invalid webhook subscription")
+)
+
+// Config holds the webhooks delivery configuration.
+type Config struct {
+	PollInterval time.Duration // Interval to check the queue for due deliveries
+	Timeout      time.Duration // Timeout of every delivery attempt
+	MaxAttempts  int           // Attempts before a delivery is marked as failed
+	RetryBackoff time.Duration // Delay before the first retry, doubled on every attempt
+	MaxBackoff   time.Duration // Maximum delay between retries
+	LogRetention time.Duration // Time the finished deliveries are kept in the log
+	Concurrency  int           // Maximum number of deliveries sent at the same time
+	// AllowPrivateNetworks allows webhook URLs that resolve to loopback,
+	// link-local or private addresses. It must only be enabled if the users
+	// that manage the subscriptions are trusted, since it exposes the
+	// internal network of the sequencer host.
+	AllowPrivateNetworks bool
+}
+
+// DefaultConfig is the default webhooks delivery configuration.
+var DefaultConfig = Config{
+	PollInterval: 2 * time.Second,
+	Timeout:      10 * time.Second,
+	MaxAttempts:  8,
+	RetryBackoff: 10 * time.Second,
+	MaxBackoff:   time.Hour,
+	LogRetention: 7 * 24 * time.Hour,
+	Concurrency:  4,
+}
+
+// Subscription is a webhook subscription. If OrganizationID is nil, it
+// receives the events of every process, otherwise only the events of the
+// processes of the organization. If Events is empty, it receives every event
+// type.
+type Subscription struct {
+	ID             string          `json:"id"`
+	URL            string          `json:"url"`
+	Secret         string          `json:"secret,omitempty"`
+	OrganizationID *common.Address `json:"organizationId,omitempty"`
+	Events         []string        `json:"events,omitempty"`
+	CreatedAt      time.Time       `json:"createdAt"`
+}
+
+// matches returns whether the subscription receives the event provided.
+func (s *Subscription) matches(event *storage.ProcessEvent) bool {
+	if s.OrganizationID != nil && *s.OrganizationID != event.OrganizationID {
+		return false
+	}
+	return len(s.Events) == 0 || slices.Contains(s.Events, event.Type)
+}
+
+// Delivery is the delivery of an event to a subscription, with the result of
+// its last attempt.
+type Delivery struct {
+	ID             string                `json:"id"`
+	SubscriptionID string                `json:"subscriptionId"`
+	Event          *storage.ProcessEvent `json:"event"`
+	Status         string                `json:"status"`
+	Attempts       int                   `json:"attempts"`
+	NextAttempt    time.Time             `json:"nextAttempt,omitzero"`
+	ResponseStatus int                   `json:"responseStatus,omitempty"`
+	LastError      string                `json:"lastError,omitempty"`
+	CreatedAt      time.Time             `json:"createdAt"`
+	UpdatedAt      time.Time             `json:"updatedAt"`
+}
+
+// Manager keeps the webhook subscriptions and delivers the process events to
+// them.
+type Manager struct {
+	db         db.Database
+	config     Config
+	client     *http.Client
+	lock       sync.Mutex
+	cancel     context.CancelFunc
+	wg         sync.WaitGroup
+	lastPruned time.Time
+
+	// events notified and not yet queued for delivery, so the publishers
+	// never wait for the database
+	eventsLock sync.Mutex
+	events     []*storage.ProcessEvent
+	newEvents  chan struct{}
+}
+
+// New creates a webhooks manager that persists its data in the database
+// provided. The zero values of the config are replaced by the defaults.
+func New(database db.Database, config Config) *Manager {
+	if config.PollInterval <= 0 {
+		config.PollInterval = DefaultConfig.PollInterval
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = DefaultConfig.Timeout
+	}
+	if config.MaxAttempts <= 0 {
+		config.MaxAttempts = DefaultConfig.MaxAttempts
+	}
+	if config.RetryBackoff <= 0 {
+		config.RetryBackoff = DefaultConfig.RetryBackoff
+	}
+	if config.MaxBackoff <= 0 {
+		config.MaxBackoff = DefaultConfig.MaxBackoff
+	}
+	if config.LogRetention <= 0 {
+		config.LogRetention = DefaultConfig.LogRetention
+	}
+	if config.Concurrency <= 0 {
+		config.Concurrency = DefaultConfig.Concurrency
+	}
+	return &Manager{
+		db:        database,
+		config:    config,
+		client:    newHTTPClient(config),
+		newEvents: make(chan struct{}, 1),
+	}
+}
+
+// Start starts queuing the notified events and delivering them in
+// background.
+func (m *Manager) Start(ctx context.Context) {
+	ctx, m.cancel = context.WithCancel(ctx)
+	m.wg.Add(2)
+	go func() {
+		defer m.wg.Done()
+		for {
+			select {
+			case <-ctx.Done():
+				// Persist the remaining events, they are delivered on the
+				// next start
+				m.queueEvents()
+				return
+			case <-m.newEvents:
+				m.queueEvents()
+			}
+		}
+	}()
+	go func() {
+		defer m.wg.Done()
+		ticker := time.NewTicker(m.config.PollInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				m.deliverDue(ctx)
+				m.pruneLog()
+			}
+		}
+	}()
+}
+
+// Stop stops delivering events and waits for the ongoing deliveries.
+func (m *Manager) Stop() {
+	if m.cancel != nil {
+		m.cancel()
+	}
+	m.wg.Wait()
+}
+
+// Notify adds the event to the in-memory queue of the manager, which stores
+// a delivery for every matching subscription in background. It never blocks,
+// so it is meant to be registered as a storage.ProcessEventListener.
+func (m *Manager) Notify(event *storage.ProcessEvent) {
+	m.eventsLock.Lock()
+	m.events = append(m.events, event)
+	m.eventsLock.Unlock()
+	select {
+	case m.newEvents <- struct{}{}:
+	default:
+	}
+}
+
+// queueEvents stores a delivery of the notified events for every matching
+// subscription.
+func (m *Manager) queueEvents() {
+	m.eventsLock.Lock()
+	events := m.events
+	m.events = nil
+	m.eventsLock.Unlock()
+	if len(events) == 0 {
+		return
+	}
+	subs, err := m.Subscriptions()
+	if err != nil {
+		log.Warnw("failed to list webhook subscriptions", "error", err, "droppedEvents", len(events))
+		return
+	}
+	for _, event := range events {
+		for _, sub := range subs {
+			if !sub.matches(event) {
+				continue
+			}
+			if err := m.enqueue(sub, event); err != nil {
+				log.Warnw("failed to queue webhook delivery",
+					"subscription", sub.ID,
+					"event", event.Type,
+					"processID", event.ProcessID.String(),
+					"error", err)
+			}
+		}
+	}
+}
+
+// validate checks the subscription fields provided by the user.
+func (s *Subscription) validate(allowPrivateNetworks bool) error {
+	if err := validateURL(s.URL, allowPrivateNetworks); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidSubscription, err)
+	}
+	for _, e := range s.Events {
+		if !slices.Contains(storage.ProcessEventTypes, e) {
+			return fmt.Errorf("%w: unknown event type %q", ErrInvalidSubscription, e)
+		}
+	}
+	return nil
+}
@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/vocdoni/davinci-node/log"
+	"github.com/vocdoni/davinci-node/metrics"
 	"github.com/vocdoni/davinci-node/storage"
 	"github.com/vocdoni/davinci-node/types"
 )
@@ -24,10 +25,13 @@ var (
 // when creating a new JobsManager instance.
 const defaultTickerInterval = 10 * time.Second
 
-// WorkerJob represents a job assigned to a worker. It contains the vote ID,
-// worker address, timestamp, and expiration time.
+// WorkerJob represents a job assigned to a worker. It contains the job type,
+// the vote ID for ballot jobs or the proof job for aggregation and state
+// transition jobs, worker address, timestamp, and expiration time.
 type WorkerJob struct {
+	Type       JobType
 	VoteID     types.VoteID
+	ProofJob   *ProofJob
 	Address    string
 	Timestamp  time.Time
 	Expiration time.Time // When the job should expire
@@ -44,9 +48,15 @@ type JobsManager struct {
 	tickerInterval time.Duration // Configurable ticker interval for timeout checking
 	closeOnce      sync.Once     // Ensures channel is closed only once
 
-	FailedJobs    chan *WorkerJob // Channel to handle failed jobs
-	JobTimeout    time.Duration   // Duration after which a job is considered timed out
-	WorkerManager *WorkerManager  // Reference to the worker manager for job tracking
+	proofMtx       sync.Mutex
+	proofQueue     []*ProofJob                // Proof jobs waiting for a worker
+	proofPending   map[string]*WorkerJob      // Proof jobs assigned to a worker, by job ID
+	workerJobTypes map[string]*workerJobTypes // Job types advertised by each worker
+
+	FailedJobs      chan *WorkerJob // Channel to handle failed ballot jobs
+	JobTimeout      time.Duration   // Duration after which a ballot job is considered timed out
+	ProofJobTimeout time.Duration   // Duration after which a proof job is considered timed out
+	WorkerManager   *WorkerManager  // Reference to the worker manager for job tracking
 }
 
 // NewJobsManager creates a new jobs manager with the specified job timeout
@@ -58,11 +68,14 @@ func NewJobsManager(storage *storage.Storage, jobTimeout time.Duration, banRules
 		interval = tickerInterval[0]
 	}
 	return &JobsManager{
-		pending:        make(map[types.VoteID]*WorkerJob),
-		WorkerManager:  NewWorkerManager(storage, banRules),
-		FailedJobs:     make(chan *WorkerJob), // Unbuffered channel for failed jobs
-		JobTimeout:     jobTimeout,
-		tickerInterval: interval,
+		pending:         make(map[types.VoteID]*WorkerJob),
+		proofPending:    make(map[string]*WorkerJob),
+		workerJobTypes:  make(map[string]*workerJobTypes),
+		WorkerManager:   NewWorkerManager(storage, banRules),
+		FailedJobs:      make(chan *WorkerJob), // Unbuffered channel for failed jobs
+		JobTimeout:      jobTimeout,
+		ProofJobTimeout: defaultProofJobTimeout,
+		tickerInterval:  interval,
 	}
 }
 
@@ -106,6 +119,14 @@ func (jm *JobsManager) Stop() {
 	jm.pending = make(map[types.VoteID]*WorkerJob) // Clear all pending jobs
 	jm.WorkerManager.Stop()                        // Stop the worker manager
 
+	// Fail the assigned proof jobs, so their sequencer stops waiting
+	jm.proofMtx.Lock()
+	for key, job := range jm.proofPending {
+		job.ProofJob.result <- proofJobResult{err: fmt.Errorf("jobs manager stopped")}
+		delete(jm.proofPending, key)
+	}
+	jm.proofMtx.Unlock()
+
 	// Close the failed jobs channel safely using sync.Once
 	jm.closeOnce.Do(func() {
 		close(jm.FailedJobs)
@@ -118,6 +139,10 @@ func (jm *JobsManager) Stop() {
 // the expired job from the pending jobs map. This function is called
 // periodically by a ticker to ensure timely handling of job timeouts.
 func (jm *JobsManager) checkTimeouts() {
+	jm.proofMtx.Lock()
+	jm.checkProofTimeouts()
+	jm.proofMtx.Unlock()
+
 	jm.pendingMtx.Lock()
 	defer jm.pendingMtx.Unlock()
 
@@ -130,6 +155,7 @@ func (jm *JobsManager) checkTimeouts() {
 					"voteID", job.VoteID.String(),
 					"error", err)
 			}
+			metrics.WorkerJobs.WithLabelValues(metrics.ResultTimeout).Inc()
 			jm.FailedJobs <- job    // Send to failed jobs channel (blocking)
 			delete(jm.pending, key) // Remove expired job
 		}
@@ -155,6 +181,13 @@ func (jm *JobsManager) IsWorkerAvailable(workerAddr string) (bool, error) {
 			return false, ErrWorkerBusy // Worker has pending jobs
 		}
 	}
+	jm.proofMtx.Lock()
+	defer jm.proofMtx.Unlock()
+	for _, job := range jm.proofPending {
+		if job.Address == worker.Address {
+			return false, ErrWorkerBusy // Worker has pending proof jobs
+		}
+	}
 	return true, nil // Worker is available
 }
 
@@ -176,6 +209,7 @@ func (jm *JobsManager) RegisterJob(workerAddr string, voteID types.VoteID) (*Wor
 		return nil, ErrWorkerBanned // Worker is banned
 	}
 	job := &WorkerJob{
+		Type:       JobTypeBallot,
 		VoteID:     voteID,
 		Address:    worker.Address,
 		Timestamp:  time.Now(),
@@ -216,7 +250,10 @@ func (jm *JobsManager) CompleteJob(voteID types.VoteID, success bool) *WorkerJob
 		return nil // Job not found
 	}
 	if !success {
+		metrics.WorkerJobs.WithLabelValues(metrics.ResultFailure).Inc()
 		jm.FailedJobs <- job // Send to failed jobs channel (blocking)
+	} else {
+		metrics.WorkerJobs.WithLabelValues(metrics.ResultSuccess).Inc()
 	}
 	// Notify worker manager
 	if err := jm.WorkerManager.WorkerResult(job.Address, success); err == nil {
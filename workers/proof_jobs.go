@@ -0,0 +1,309 @@
+package workers
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/vocdoni/davinci-node/log"
+	"github.com/vocdoni/davinci-node/metrics"
+	"github.com/vocdoni/davinci-node/types"
+	"github.com/vocdoni/davinci-node/util"
+)
+
+var (
+	ErrNoCapableWorker = fmt.Errorf("no worker available for the job type")
+	ErrInvalidJobType  = fmt.Errorf("invalid job type")
+	ErrInvalidProof    = fmt.Errorf("invalid proof")
+)
+
+// JobType identifies the kind of work that a worker can take from the
+// sequencer.
+type JobType string
+
+const (
+	JobTypeBallot          JobType = "ballot"          // Verification of a single ballot
+	JobTypeAggregateBatch  JobType = "aggregateBatch"  // Aggregation proof of a batch of verified ballots
+	JobTypeStateTransition JobType = "stateTransition" // State transition proof of an aggregated batch
+)
+
+// JobTypes are all the job types supported.
+var JobTypes = []JobType{JobTypeStateTransition, JobTypeAggregateBatch, JobTypeBallot}
+
+const (
+	// defaultProofJobTimeout is the default time a worker has to return the
+	// proof of an aggregation or state transition job.
+	defaultProofJobTimeout = 10 * time.Minute
+	// proofJobPickupTimeout is the time a proof job waits in the queue to be
+	// taken by a worker before the sequencer proves it by itself.
+	proofJobPickupTimeout = 30 * time.Second
+	// workerJobTypesTTL is the time the job types advertised by a worker are
+	// taken into account after its last request.
+	workerJobTypesTTL = time.Minute
+)
+
+// ParseJobTypes parses a comma separated list of job types. An empty list
+// means that the worker only takes ballot jobs, which is the behaviour of
+// the workers that do not advertise their job types.
+func ParseJobTypes(s string) ([]JobType, error) {
+	if strings.TrimSpace(s) == "" {
+		return []JobType{JobTypeBallot}, nil
+	}
+	var jobTypes []JobType
+	for part := range strings.SplitSeq(s, ",") {
+		jobType := JobType(strings.TrimSpace(part))
+		if !slices.Contains(JobTypes, jobType) {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidJobType, jobType)
+		}
+		if !slices.Contains(jobTypes, jobType) {
+			jobTypes = append(jobTypes, jobType)
+		}
+	}
+	return jobTypes, nil
+}
+
+// JoinJobTypes returns the comma separated list of the job types provided,
+// as parsed by ParseJobTypes.
+func JoinJobTypes(jobTypes []JobType) string {
+	strs := make([]string, len(jobTypes))
+	for i, jobType := range jobTypes {
+		strs[i] = string(jobType)
+	}
+	return strings.Join(strs, ",")
+}
+
+// ProofJob is a proving job offloaded to a remote worker. The witness is the
+// binary encoded full witness of the circuit of the job type, and the worker
+// returns the binary encoded proof, which is verified by the sequencer
+// before it is accepted.
+type ProofJob struct {
+	ID        types.HexBytes
+	Type      JobType
+	ProcessID types.ProcessID
+	Witness   []byte
+
+	verify func(proof []byte) error
+	result chan proofJobResult
+}
+
+// proofJobResult is the outcome of a proof job, delivered to the sequencer
+// waiting for it.
+type proofJobResult struct {
+	proof []byte
+	err   error
+}
+
+// workerJobTypes are the job types advertised by a worker in its last
+// request.
+type workerJobTypes struct {
+	jobTypes []JobType
+	lastSeen time.Time
+}
+
+// SetWorkerJobTypes records the job types advertised by a worker. It is
+// called on every worker request, so the proof jobs are only queued while
+// there are workers able to take them.
+func (jm *JobsManager) SetWorkerJobTypes(workerAddr string, jobTypes []JobType) {
+	jm.proofMtx.Lock()
+	defer jm.proofMtx.Unlock()
+	jm.workerJobTypes[workerAddr] = &workerJobTypes{
+		jobTypes: jobTypes,
+		lastSeen: time.Now(),
+	}
+}
+
+// hasCapableWorker returns whether a worker that is not banned advertised
+// the job type provided recently. It must be called with the proof lock
+// held.
+func (jm *JobsManager) hasCapableWorker(jobType JobType) bool {
+	for addr, wjt := range jm.workerJobTypes {
+		if time.Since(wjt.lastSeen) > workerJobTypesTTL || !slices.Contains(wjt.jobTypes, jobType) {
+			continue
+		}
+		if worker, ok := jm.WorkerManager.GetWorker(addr); ok && worker.IsBanned(jm.WorkerManager.rules) {
+			continue
+		}
+		return true
+	}
+	return false
+}
+
+// ProveRemotely queues a proof job with the witness provided and waits
+// until a worker returns a proof that passes the verify function provided.
+// It returns ErrNoCapableWorker right away if no worker advertised the job
+// type, or if no worker takes the job in time, so the caller can prove it
+// by itself. It also returns an error if the worker fails, returns an
+// invalid proof or does not return it before the proof job timeout.
+func (jm *JobsManager) ProveRemotely(
+	ctx context.Context,
+	jobType JobType,
+	processID types.ProcessID,
+	witness []byte,
+	verify func(proof []byte) error,
+) ([]byte, error) {
+	job := &ProofJob{
+		ID:        util.RandomBytes(16),
+		Type:      jobType,
+		ProcessID: processID,
+		Witness:   witness,
+		verify:    verify,
+		result:    make(chan proofJobResult, 1),
+	}
+
+	jm.proofMtx.Lock()
+	if !jm.hasCapableWorker(jobType) {
+		jm.proofMtx.Unlock()
+		return nil, ErrNoCapableWorker
+	}
+	jm.proofQueue = append(jm.proofQueue, job)
+	jm.proofMtx.Unlock()
+	log.Debugw("proof job queued",
+		"jobID", job.ID.String(),
+		"type", jobType,
+		"processID", processID.String())
+
+	pickup := time.NewTimer(proofJobPickupTimeout)
+	defer pickup.Stop()
+	for {
+		select {
+		case res := <-job.result:
+			return res.proof, res.err
+		case <-pickup.C:
+			if jm.dequeueProofJob(job) {
+				return nil, ErrNoCapableWorker
+			}
+		case <-ctx.Done():
+			jm.dequeueProofJob(job)
+			jm.proofMtx.Lock()
+			delete(jm.proofPending, job.ID.String())
+			jm.proofMtx.Unlock()
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// dequeueProofJob removes the proof job provided from the queue. It returns
+// false if the job is not queued, because a worker took it.
+func (jm *JobsManager) dequeueProofJob(job *ProofJob) bool {
+	jm.proofMtx.Lock()
+	defer jm.proofMtx.Unlock()
+	for i, queued := range jm.proofQueue {
+		if queued == job {
+			jm.proofQueue = slices.Delete(jm.proofQueue, i, i+1)
+			return true
+		}
+	}
+	return false
+}
+
+// NextProofJob assigns the first queued proof job of the job types provided
+// to the worker. It returns nil if there is no proof job of those types.
+func (jm *JobsManager) NextProofJob(workerAddr string, jobTypes []JobType) (*ProofJob, error) {
+	worker, ok := jm.WorkerManager.GetWorker(workerAddr)
+	if !ok {
+		return nil, ErrWorkerNotFound
+	}
+	if worker.IsBanned(jm.WorkerManager.rules) {
+		return nil, ErrWorkerBanned
+	}
+	jm.proofMtx.Lock()
+	defer jm.proofMtx.Unlock()
+	for i, job := range jm.proofQueue {
+		if !slices.Contains(jobTypes, job.Type) {
+			continue
+		}
+		jm.proofQueue = slices.Delete(jm.proofQueue, i, i+1)
+		jm.proofPending[job.ID.String()] = &WorkerJob{
+			Type:       job.Type,
+			ProofJob:   job,
+			Address:    worker.Address,
+			Timestamp:  time.Now(),
+			Expiration: time.Now().Add(jm.ProofJobTimeout),
+		}
+		return job, nil
+	}
+	return nil, nil
+}
+
+// CompleteProofJob verifies the proof returned by a worker for the proof
+// job provided and delivers it to the sequencer waiting for it. The worker
+// result is accounted for the ban rules, and an invalid proof fails the job.
+func (jm *JobsManager) CompleteProofJob(workerAddr string, jobID types.HexBytes, proof []byte) (*WorkerJob, error) {
+	jm.proofMtx.Lock()
+	job, exists := jm.proofPending[jobID.String()]
+	if !exists {
+		jm.proofMtx.Unlock()
+		return nil, ErrJobNotFound
+	}
+	if job.Address != workerAddr {
+		jm.proofMtx.Unlock()
+		return nil, ErrWorkerJobMismatch
+	}
+	delete(jm.proofPending, jobID.String())
+	jm.proofMtx.Unlock()
+
+	err := job.ProofJob.verify(proof)
+	if err != nil {
+		err = fmt.Errorf("%w: %w", ErrInvalidProof, err)
+		metrics.WorkerJobs.WithLabelValues(metrics.ResultFailure).Inc()
+		job.ProofJob.result <- proofJobResult{err: err}
+	} else {
+		metrics.WorkerJobs.WithLabelValues(metrics.ResultSuccess).Inc()
+		job.ProofJob.result <- proofJobResult{proof: proof}
+	}
+	if wErr := jm.WorkerManager.WorkerResult(job.Address, err == nil); wErr == nil {
+		log.Debugw("proof job completed",
+			"jobID", jobID.String(),
+			"type", job.Type,
+			"success", err == nil)
+	}
+	return job, err
+}
+
+// FailProofJob fails the proof job provided, reported as failed by the
+// worker that took it.
+func (jm *JobsManager) FailProofJob(workerAddr string, jobID types.HexBytes, reason error) (*WorkerJob, error) {
+	jm.proofMtx.Lock()
+	job, exists := jm.proofPending[jobID.String()]
+	if !exists {
+		jm.proofMtx.Unlock()
+		return nil, ErrJobNotFound
+	}
+	if job.Address != workerAddr {
+		jm.proofMtx.Unlock()
+		return nil, ErrWorkerJobMismatch
+	}
+	delete(jm.proofPending, jobID.String())
+	jm.proofMtx.Unlock()
+
+	metrics.WorkerJobs.WithLabelValues(metrics.ResultFailure).Inc()
+	job.ProofJob.result <- proofJobResult{err: fmt.Errorf("worker failed the job: %w", reason)}
+	if err := jm.WorkerManager.WorkerResult(job.Address, false); err != nil {
+		log.Warnw("failed to notify worker manager for proof job",
+			"jobID", jobID.String(),
+			"error", err)
+	}
+	return job, nil
+}
+
+// checkProofTimeouts fails the proof jobs whose worker did not return the
+// proof in time. It must be called with the proof lock held.
+func (jm *JobsManager) checkProofTimeouts() {
+	now := time.Now()
+	for key, job := range jm.proofPending {
+		if !now.After(job.Expiration) {
+			continue
+		}
+		log.Debugw("proof job has expired", "jobID", key, "type", job.Type)
+		if err := jm.WorkerManager.WorkerResult(job.Address, false); err != nil {
+			log.Warnw("failed to notify worker manager for proof job",
+				"jobID", key,
+				"error", err)
+		}
+		metrics.WorkerJobs.WithLabelValues(metrics.ResultTimeout).Inc()
+		job.ProofJob.result <- proofJobResult{err: fmt.Errorf("proof job timed out")}
+		delete(jm.proofPending, key)
+	}
+}
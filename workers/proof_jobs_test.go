@@ -0,0 +1,154 @@
+package workers
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/vocdoni/davinci-node/internal/testutil"
+)
+
+// queueProofJob starts a remote proof in the background that accepts the
+// valid proof provided, and waits until its job is queued.
+func queueProofJob(t *testing.T, jm *JobsManager, jobType JobType, validProof []byte) chan error {
+	done := make(chan error, 1)
+	go func() {
+		_, err := jm.ProveRemotely(t.Context(), jobType, testutil.RandomProcessID(), []byte{1}, func(proof []byte) error {
+			if !bytes.Equal(proof, validProof) {
+				return errors.New("wrong proof")
+			}
+			return nil
+		})
+		done <- err
+	}()
+	for range 100 {
+		jm.proofMtx.Lock()
+		queued := len(jm.proofQueue)
+		jm.proofMtx.Unlock()
+		if queued > 0 {
+			return done
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("proof job not queued")
+	return nil
+}
+
+func TestParseJobTypes(t *testing.T) {
+	c := qt.New(t)
+
+	jobTypes, err := ParseJobTypes("")
+	c.Assert(err, qt.IsNil)
+	c.Assert(jobTypes, qt.DeepEquals, []JobType{JobTypeBallot})
+
+	jobTypes, err = ParseJobTypes("aggregateBatch, stateTransition,aggregateBatch")
+	c.Assert(err, qt.IsNil)
+	c.Assert(jobTypes, qt.DeepEquals, []JobType{JobTypeAggregateBatch, JobTypeStateTransition})
+	c.Assert(JoinJobTypes(jobTypes), qt.Equals, "aggregateBatch,stateTransition")
+
+	_, err = ParseJobTypes("ballot,results")
+	c.Assert(err, qt.ErrorIs, ErrInvalidJobType)
+}
+
+func TestJobsManagerProofJobs(t *testing.T) {
+	c := qt.New(t)
+	validProof := []byte("proof")
+
+	t.Run("No capable worker", func(t *testing.T) {
+		jm := NewJobsManager(storageForTest(t), time.Minute, nil)
+		jm.WorkerManager.AddWorker(testWorkerAddr, testWorkerName)
+		jm.SetWorkerJobTypes(testWorkerAddr, []JobType{JobTypeBallot})
+
+		_, err := jm.ProveRemotely(t.Context(), JobTypeAggregateBatch, testutil.RandomProcessID(), []byte{1}, nil)
+		c.Assert(err, qt.ErrorIs, ErrNoCapableWorker)
+	})
+
+	t.Run("Valid proof", func(t *testing.T) {
+		jm := NewJobsManager(storageForTest(t), time.Minute, nil)
+		jm.WorkerManager.AddWorker(testWorkerAddr, testWorkerName)
+		jm.SetWorkerJobTypes(testWorkerAddr, []JobType{JobTypeBallot, JobTypeAggregateBatch})
+		done := queueProofJob(t, jm, JobTypeAggregateBatch, validProof)
+
+		// the job is only offered to the workers that take its type
+		job, err := jm.NextProofJob(testWorkerAddr, []JobType{JobTypeStateTransition})
+		c.Assert(err, qt.IsNil)
+		c.Assert(job, qt.IsNil)
+		job, err = jm.NextProofJob(testWorkerAddr, []JobType{JobTypeAggregateBatch})
+		c.Assert(err, qt.IsNil)
+		c.Assert(job, qt.IsNotNil)
+		c.Assert(job.Type, qt.Equals, JobTypeAggregateBatch)
+
+		// the worker is busy until the proof is returned
+		available, err := jm.IsWorkerAvailable(testWorkerAddr)
+		c.Assert(available, qt.IsFalse)
+		c.Assert(err, qt.ErrorIs, ErrWorkerBusy)
+
+		_, err = jm.CompleteProofJob("other-worker", job.ID, validProof)
+		c.Assert(err, qt.ErrorIs, ErrWorkerJobMismatch)
+		_, err = jm.CompleteProofJob(testWorkerAddr, job.ID, validProof)
+		c.Assert(err, qt.IsNil)
+		c.Assert(<-done, qt.IsNil)
+
+		stats, err := jm.WorkerManager.WorkerStats(testWorkerAddr)
+		c.Assert(err, qt.IsNil)
+		c.Assert(stats.SuccessCount, qt.Equals, int64(1))
+	})
+
+	t.Run("Invalid proof", func(t *testing.T) {
+		jm := NewJobsManager(storageForTest(t), time.Minute, nil)
+		jm.WorkerManager.AddWorker(testWorkerAddr, testWorkerName)
+		jm.SetWorkerJobTypes(testWorkerAddr, []JobType{JobTypeStateTransition})
+		done := queueProofJob(t, jm, JobTypeStateTransition, validProof)
+
+		job, err := jm.NextProofJob(testWorkerAddr, []JobType{JobTypeStateTransition})
+		c.Assert(err, qt.IsNil)
+		_, err = jm.CompleteProofJob(testWorkerAddr, job.ID, []byte("forged"))
+		c.Assert(err, qt.ErrorIs, ErrInvalidProof)
+		c.Assert(<-done, qt.ErrorIs, ErrInvalidProof)
+
+		// the job can not be completed twice
+		_, err = jm.CompleteProofJob(testWorkerAddr, job.ID, validProof)
+		c.Assert(err, qt.ErrorIs, ErrJobNotFound)
+
+		stats, err := jm.WorkerManager.WorkerStats(testWorkerAddr)
+		c.Assert(err, qt.IsNil)
+		c.Assert(stats.FailedCount, qt.Equals, int64(1))
+	})
+
+	t.Run("Timeout", func(t *testing.T) {
+		jm := NewJobsManager(storageForTest(t), time.Minute, nil)
+		jm.ProofJobTimeout = time.Millisecond
+		jm.WorkerManager.AddWorker(testWorkerAddr, testWorkerName)
+		jm.SetWorkerJobTypes(testWorkerAddr, []JobType{JobTypeAggregateBatch})
+		done := queueProofJob(t, jm, JobTypeAggregateBatch, validProof)
+
+		job, err := jm.NextProofJob(testWorkerAddr, []JobType{JobTypeAggregateBatch})
+		c.Assert(err, qt.IsNil)
+		time.Sleep(5 * time.Millisecond)
+		jm.checkTimeouts()
+		c.Assert(<-done, qt.IsNotNil)
+		_, err = jm.CompleteProofJob(testWorkerAddr, job.ID, validProof)
+		c.Assert(err, qt.ErrorIs, ErrJobNotFound)
+
+		stats, err := jm.WorkerManager.WorkerStats(testWorkerAddr)
+		c.Assert(err, qt.IsNil)
+		c.Assert(stats.FailedCount, qt.Equals, int64(1))
+	})
+
+	t.Run("Banned worker", func(t *testing.T) {
+		jm := NewJobsManager(storageForTest(t), time.Minute, nil)
+		jm.WorkerManager.AddWorker(testWorkerAddr, testWorkerName)
+		for range 15 { // Exceed default ban threshold
+			c.Assert(jm.WorkerManager.WorkerResult(testWorkerAddr, false), qt.IsNil)
+		}
+		jm.SetWorkerJobTypes(testWorkerAddr, []JobType{JobTypeAggregateBatch})
+
+		_, err := jm.ProveRemotely(context.Background(), JobTypeAggregateBatch, testutil.RandomProcessID(), []byte{1}, nil)
+		c.Assert(err, qt.ErrorIs, ErrNoCapableWorker)
+		_, err = jm.NextProofJob(testWorkerAddr, []JobType{JobTypeAggregateBatch})
+		c.Assert(err, qt.ErrorIs, ErrWorkerBanned)
+	})
+}